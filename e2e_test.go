@@ -0,0 +1,304 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/harness"
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// testFailure is go test output reporting a failed test
+const testFailure = "Ran the tests.\n--- FAIL: TestSum (0.00s)\n    sum_test.go:9: got 3, want 4\nFAIL\nFAIL\texample.com/app\t0.01s\n"
+
+// e2ePlan is a plan with two untested features
+func e2ePlan() []plan.Plan {
+	return []plan.Plan{
+		{ID: 1, Category: "chore", Description: "Add sum helper", Steps: []string{"Write sum.go", "Test it"}},
+		{ID: 2, Category: "chore", Description: "Add product helper", Steps: []string{"Write product.go", "Test it"}},
+	}
+}
+
+// runRalph validates the configuration and runs the main loop, as main does
+func runRalph(t *testing.T, cfg *config.Config) error {
+	t.Helper()
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("invalid configuration: %v", err)
+	}
+	return runIterations(cfg)
+}
+
+// recordedRun returns the run history record of the only run in the repository
+func recordedRun(t *testing.T, cfg *config.Config) *history.Run {
+	t.Helper()
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("run history = %v, %v; want one run", runs, err)
+	}
+	return runs[0]
+}
+
+func TestEndToEndCompletesPlan(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: "Added sum.", Files: map[string]string{"sum.go": "package app\n"}, Tested: []int{1}},
+		harness.Reply{Output: "Added product.", Files: map[string]string{"product.go": "package app\n"}, Tested: []int{2}, Complete: true},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 5
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if fake.Calls() != 2 {
+		t.Errorf("agent called %d times, want 2 (run should stop at the completion signal)", fake.Calls())
+	}
+	for _, id := range []int{1, 2} {
+		if !repo.Feature(id).Tested {
+			t.Errorf("feature #%d not marked tested", id)
+		}
+	}
+	if repo.ReadFile("sum.go") == "" || repo.ReadFile("product.go") == "" {
+		t.Error("files written by the agent are missing")
+	}
+	if !strings.Contains(fake.Prompts()[0], "Add sum helper") {
+		t.Error("first prompt does not include the plan")
+	}
+	if run := recordedRun(t, cfg); len(run.FeaturesCompleted) != 2 || !run.Completed {
+		t.Errorf("recorded run = %+v, want 2 features completed", run)
+	}
+}
+
+func TestEndToEndRetriesAfterTestFailure(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: testFailure, Files: map[string]string{"sum.go": "package app\n"}},
+		harness.Reply{Output: "Fixed the sum.", Tested: []int{1, 2}, Complete: true},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 5
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(repo.Progress(), "FAILURE [test_failure]") {
+		t.Errorf("test failure not logged to progress:\n%s", repo.Progress())
+	}
+	prompts := fake.Prompts()
+	if len(prompts) != 2 {
+		t.Fatalf("agent called %d times, want 2", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "previous attempt failed due to test failures") {
+		t.Errorf("retry prompt carries no failure guidance:\n%s", prompts[1])
+	}
+	if !repo.Feature(1).Tested {
+		t.Error("feature #1 not marked tested after the retry")
+	}
+}
+
+func TestEndToEndRetryNamesFailedTests(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	goJSON := `{"Action":"run","Package":"example.com/app","Test":"TestSum"}
+{"Action":"output","Package":"example.com/app","Test":"TestSum","Output":"    sum_test.go:9: got 3, want 4\n"}
+{"Action":"output","Package":"example.com/app","Test":"TestSum","Output":"--- FAIL: TestSum (0.00s)\n"}
+{"Action":"fail","Package":"example.com/app","Test":"TestSum","Elapsed":0}
+{"Action":"output","Package":"example.com/app","Output":"FAIL\n"}
+{"Action":"fail","Package":"example.com/app","Elapsed":0.01}`
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: "Ran go test -json ./...\n" + goJSON},
+		harness.Reply{Output: "Fixed the sum.", Tested: []int{1, 2}, Complete: true},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 5
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	prompts := fake.Prompts()
+	if len(prompts) != 2 {
+		t.Fatalf("agent called %d times, want 2", len(prompts))
+	}
+	for _, want := range []string{"1 test(s) failed (0 passed):", "- example.com/app: TestSum", "sum_test.go:9: got 3, want 4"} {
+		if !strings.Contains(prompts[1], want) {
+			t.Errorf("retry prompt missing %q:\n%s", want, prompts[1])
+		}
+	}
+}
+
+func TestEndToEndCoverageGateBlocksTested(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: "Added sum.", Files: map[string]string{"coverage.txt": "Total coverage: 70.0%\n"}, Tested: []int{1}},
+		harness.Reply{Output: "Added tests.", Files: map[string]string{"coverage.txt": "Total coverage: 85.0%\n"}, Tested: []int{1}},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 2
+	cfg.CoverageGate = "80%"
+	cfg.CoverageCmd = "cat coverage.txt"
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(repo.Progress(), "COVERAGE: feature(s) #1 not marked tested: total coverage 70.0% is below the 80.0% gate") {
+		t.Errorf("blocked feature not logged to progress:\n%s", repo.Progress())
+	}
+	prompts := fake.Prompts()
+	if len(prompts) != 2 {
+		t.Fatalf("agent called %d times, want 2", len(prompts))
+	}
+	if !strings.Contains(prompts[1], "Blocked by the coverage gate") {
+		t.Errorf("retry prompt carries no coverage guidance:\n%s", prompts[1])
+	}
+	if !repo.Feature(1).Tested {
+		t.Error("feature #1 not marked tested once coverage passed the gate")
+	}
+}
+
+func TestEndToEndRetriesAfterLintFailure(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	repo.WriteFile("lint.sh", "if grep -n TODO sum.go; then echo 'sum.go:1:1: TODO comment left in code (godox)'; exit 1; fi\n")
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: "Added sum.", Files: map[string]string{"sum.go": "// TODO: document\npackage app\n"}},
+		harness.Reply{Output: "Cleaned up sum.", Files: map[string]string{"sum.go": "package app\n"}, Tested: []int{1, 2}, Complete: true},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 5
+	cfg.LintCmd = "sh lint.sh"
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(repo.Progress(), "FAILURE [lint_failure]") {
+		t.Errorf("lint failure not logged to progress:\n%s", repo.Progress())
+	}
+	prompts := fake.Prompts()
+	if len(prompts) != 2 {
+		t.Fatalf("agent called %d times, want 2", len(prompts))
+	}
+	if !strings.Contains(prompts[0], "the linter passes via sh lint.sh") {
+		t.Errorf("prompt does not ask for the linter to pass:\n%s", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "failed the lint check") || !strings.Contains(prompts[1], "TODO comment left in code") {
+		t.Errorf("retry prompt carries no lint guidance:\n%s", prompts[1])
+	}
+}
+
+func TestEndToEndSkipsFeatureAfterMaxRetries(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: testFailure},
+		harness.Reply{Output: "Looked at the next feature."},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 2
+	cfg.MaxRetries = 1
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(repo.Progress(), "FAILURE [test_failure]") {
+		t.Errorf("test failure not logged to progress:\n%s", repo.Progress())
+	}
+	if prompts := fake.Prompts(); len(prompts) != 2 || strings.Contains(prompts[1], "previous attempt failed") {
+		t.Errorf("feature was retried instead of skipped; prompts: %q", prompts)
+	}
+	if run := recordedRun(t, cfg); run.FeaturesSkipped != 1 || run.Failures != 1 {
+		t.Errorf("recorded run = %+v, want 1 failure and 1 feature skipped", run)
+	}
+}
+
+func TestEndToEndReplansAfterConsecutiveFailures(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewScriptedAgent(t, func(int, string) harness.Reply {
+		return harness.Reply{Output: testFailure}
+	})
+	cfg := repo.Config()
+	cfg.Iterations = 2
+	cfg.AutoReplan = true
+	cfg.ReplanThreshold = 2
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if !strings.Contains(repo.Progress(), "REPLAN: ") {
+		t.Errorf("replan not logged to progress:\n%s", repo.Progress())
+	}
+	if backups, _ := filepath.Glob(filepath.Join(config.DefaultPlanVersionDir, "plan.bak.*.json")); len(backups) != 1 {
+		t.Errorf("plan backups = %v, want one", backups)
+	}
+}
+
+func TestEndToEndDefersFeatureOverScopeLimit(t *testing.T) {
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewScriptedAgent(t, func(int, string) harness.Reply {
+		return harness.Reply{Output: "Still working on it."}
+	})
+	cfg := repo.Config()
+	cfg.Iterations = 3
+	cfg.ScopeLimit = 1
+	fake.Configure(cfg)
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if f := repo.Feature(1); !f.Deferred || f.DeferReason == "" {
+		t.Errorf("feature #1 = %+v, want it deferred", f)
+	}
+	if !strings.Contains(repo.Progress(), "DEFERRED: Feature #1") {
+		t.Errorf("deferral not logged to progress:\n%s", repo.Progress())
+	}
+}
+
+func TestEndToEndRunsHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks not supported")
+	}
+	repo := harness.NewRepo(t, e2ePlan()...)
+	fake := harness.NewAgent(t,
+		harness.Reply{Output: testFailure, Files: map[string]string{"sum.go": "package app\n"}},
+		harness.Reply{Output: "Fixed the sum.", Tested: []int{1, 2}, Complete: true},
+	)
+	cfg := repo.Config()
+	cfg.Iterations = 5
+	fake.Configure(cfg)
+	hookLog := filepath.Join(t.TempDir(), "hooks.log")
+	cfg.Hooks = config.Hooks{
+		PreIteration:  []string{`echo "pre $RALPH_ITERATION feature=$RALPH_FEATURE_ID" >> ` + hookLog},
+		PostIteration: []string{`echo "post $RALPH_ITERATION $RALPH_RESULT" >> ` + hookLog},
+		OnFailure:     []string{`grep -q '"event":"on_failure"' && echo "failure $RALPH_ITERATION" >> ` + hookLog},
+		OnComplete:    []string{`echo complete >> ` + hookLog, "exit 1"},
+	}
+
+	if err := runRalph(t, cfg); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(hookLog)
+	want := "pre 1 feature=1\nfailure 1\npost 1 failure\npre 2 feature=1\npost 2 success\ncomplete\n"
+	if string(data) != want {
+		t.Errorf("hooks ran:\n%s\nwant:\n%s", data, want)
+	}
+	if !strings.Contains(repo.Progress(), `HOOK: on_complete hook "exit 1" failed`) {
+		t.Errorf("failed hook not logged to progress:\n%s", repo.Progress())
+	}
+}
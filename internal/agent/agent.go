@@ -2,14 +2,33 @@
 package agent
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/proc"
 )
 
+// killGracePeriod is how long to wait for the agent's output pipes to close
+// after it has been killed
+const killGracePeriod = 5 * time.Second
+
+// ErrTimeout is returned when the agent exceeds the configured iteration timeout
+var ErrTimeout = errors.New("agent timed out")
+
+// ErrInterrupted is returned when Ralph is interrupted (Ctrl+C) while the
+// agent runs. The agent has been killed; callers should stop and clean up.
+var ErrInterrupted = errors.New("interrupted")
+
 // IsCursorAgent checks if the agent command is cursor-agent
 // This detects cursor-agent, cursor, or any command containing "cursor-agent"
 func IsCursorAgent(agentCmd string) bool {
@@ -20,78 +39,159 @@ func IsCursorAgent(agentCmd string) bool {
 
 // Execute runs the AI agent with the given prompt and returns the output
 func Execute(cfg *config.Config, prompt string) (string, error) {
+	return ExecuteStream(cfg, prompt, nil, nil)
+}
+
+// ExecuteStream runs the AI agent like Execute, additionally copying the agent's
+// stdout and stderr to the given writers as they are produced. Either writer may
+// be nil. The full output is still captured and returned.
+//
+// If an iteration timeout is configured, the agent is killed (together with any
+// processes it spawned) when it expires, and an error wrapping ErrTimeout is
+// returned along with the output produced so far.
+func ExecuteStream(cfg *config.Config, prompt string, stdoutW, stderrW io.Writer) (string, error) {
+	return ExecuteContext(context.Background(), cfg, prompt, stdoutW, stderrW)
+}
+
+// ExecuteContext runs the AI agent like ExecuteStream, stopping it when ctx is
+// done. Secrets are masked in the prompt before it is sent, and the prompt and
+// response are recorded in the transcript, if one is set.
+func ExecuteContext(ctx context.Context, cfg *config.Config, prompt string, stdoutW, stderrW io.Writer) (string, error) {
+	start := time.Now()
+	prompt = redactPrompt(prompt)
+	output, err := execute(ctx, cfg, prompt, stdoutW, stderrW)
+	recordTranscript(cfg, prompt, output, err, start)
+	return output, err
+}
+
+// execute runs the AI agent for ExecuteContext
+func execute(ctx context.Context, cfg *config.Config, prompt string, stdoutW, stderrW io.Writer) (string, error) {
+	timeout, err := cfg.IterationTimeoutDuration()
+	if err != nil {
+		return "", fmt.Errorf("invalid iteration timeout: %w", err)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if cfg.UsesAPIBackend() {
+		output, err := executeAPI(ctx, cfg, prompt)
+		if timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+			return output, fmt.Errorf("%w after %s", ErrTimeout, timeout)
+		}
+		if err == nil && stdoutW != nil {
+			io.WriteString(stdoutW, output+"\n")
+		}
+		return output, err
+	}
+
 	// Construct the command based on the agent type
 	var cmd *exec.Cmd
 	if IsCursorAgent(cfg.AgentCmd) {
 		// cursor-agent uses --print --force and prompt as positional argument
-		cmd = exec.Command(cfg.AgentCmd, "--print", "--force", prompt)
+		cmd = exec.CommandContext(ctx, cfg.AgentCmd, "--print", "--force", prompt)
 	} else {
 		// claude uses --permission-mode acceptEdits -p format
-		cmd = exec.Command(cfg.AgentCmd, "--permission-mode", "acceptEdits", "-p", prompt)
+		cmd = exec.CommandContext(ctx, cfg.AgentCmd, "--permission-mode", "acceptEdits", "-p", prompt)
+	}
+
+	interrupted := func() bool { return false }
+	if ctx.Done() != nil {
+		// Run the agent in its own process group so a hung agent can be killed
+		// together with everything it started
+		proc.SetGroup(cmd)
+		// Don't wait forever for output from orphaned processes holding the pipes open
+		cmd.WaitDelay = killGracePeriod
+
+		// The agent no longer receives terminal interrupts directly, so relay them
+		var stop func()
+		interrupted, stop = relayInterrupts(cmd)
+		defer stop()
+	}
+
+	// Per-agent environment variables are only visible to the agent process
+	if len(cfg.AgentEnv) > 0 {
+		vars, err := ResolveEnv(cfg.AgentEnv)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve agent environment: %w", err)
+		}
+		cmd.Env = mergeEnv(os.Environ(), vars)
 	}
 
 	if cfg.Verbose {
 		fmt.Printf("Command: %s %v\n", cmd.Path, cmd.Args)
+		if len(cfg.AgentEnv) > 0 {
+			fmt.Printf("Agent environment: %s\n", strings.Join(EnvNames(cfg.AgentEnv), ", "))
+		}
 	}
 
-	// Capture stdout and stderr
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stdout pipe: %w", err)
+	// Capture stdout and stderr, copying them to the stream writers if given
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+	if stdoutW != nil {
+		cmd.Stdout = io.MultiWriter(&stdoutBuf, stdoutW)
 	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stderr pipe: %w", err)
+	if stderrW != nil {
+		cmd.Stderr = io.MultiWriter(&stderrBuf, stderrW)
 	}
 
 	// Start the command
-	if err := cmd.Start(); err != nil {
+	if err := proc.Start(cmd); err != nil {
 		return "", fmt.Errorf("failed to start agent command: %w", err)
 	}
 
-	// Read stdout and stderr concurrently
-	var stdoutBytes, stderrBytes []byte
-	stdoutDone := make(chan error, 1)
-	stderrDone := make(chan error, 1)
-
-	go func() {
-		var err error
-		stdoutBytes, err = io.ReadAll(stdout)
-		stdoutDone <- err
-	}()
-
-	go func() {
-		var err error
-		stderrBytes, err = io.ReadAll(stderr)
-		stderrDone <- err
-	}()
-
-	// Wait for both to complete
-	if err := <-stdoutDone; err != nil {
-		cmd.Process.Kill()
-		return "", fmt.Errorf("failed to read stdout: %w", err)
+	// Combine stdout and stderr for output
+	waitErr := cmd.Wait()
+	proc.Release(cmd.Process.Pid)
+	output := strings.TrimSpace(stdoutBuf.String())
+	if stderrBuf.Len() > 0 {
+		output += "\n" + strings.TrimSpace(stderrBuf.String())
 	}
 
-	if err := <-stderrDone; err != nil {
-		cmd.Process.Kill()
-		return "", fmt.Errorf("failed to read stderr: %w", err)
+	if interrupted() {
+		return output, ErrInterrupted
+	}
+	if timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("%w after %s", ErrTimeout, timeout)
 	}
 
-	// Wait for command to finish
-	if err := cmd.Wait(); err != nil {
+	if waitErr != nil {
 		// Include stderr in error message if available
-		if len(stderrBytes) > 0 {
-			return "", fmt.Errorf("agent command failed: %w\nstderr: %s", err, string(stderrBytes))
+		if stderrBuf.Len() > 0 {
+			return "", fmt.Errorf("agent command failed: %w\nstderr: %s", waitErr, stderrBuf.String())
 		}
-		return "", fmt.Errorf("agent command failed: %w", err)
-	}
-
-	// Combine stdout and stderr for output
-	output := strings.TrimSpace(string(stdoutBytes))
-	if len(stderrBytes) > 0 {
-		output += "\n" + strings.TrimSpace(string(stderrBytes))
+		return "", fmt.Errorf("agent command failed: %w", waitErr)
 	}
 
 	return output, nil
 }
+
+// relayInterrupts kills the agent's process group when Ralph receives an
+// interrupt, so that Ralph can return ErrInterrupted and clean up. It returns
+// a function reporting whether an interrupt was received, and a function
+// that stops relaying.
+func relayInterrupts(cmd *exec.Cmd) (interrupted func() bool, stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	done := make(chan struct{})
+	var received atomic.Bool
+
+	go func() {
+		select {
+		case <-sigCh:
+			received.Store(true)
+			if cmd.Cancel != nil && cmd.Process != nil {
+				cmd.Cancel()
+			}
+		case <-done:
+		}
+	}()
+
+	return received.Load, func() {
+		signal.Stop(sigCh)
+		close(done)
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/config"
+)
+
+// writeFakeAgent creates an executable shell script that stands in for an agent CLI
+func writeFakeAgent(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts not supported")
+	}
+	path := filepath.Join(t.TempDir(), "fake-agent")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake agent: %v", err)
+	}
+	return path
+}
+
+func TestIsCursorAgent(t *testing.T) {
+	tests := map[string]bool{
+		"cursor-agent":         true,
+		"/usr/bin/cursor":      true,
+		"claude":               false,
+		"cursor-claude-bridge": false,
+	}
+	for cmd, want := range tests {
+		if got := IsCursorAgent(cmd); got != want {
+			t.Errorf("IsCursorAgent(%q) = %v, want %v", cmd, got, want)
+		}
+	}
+}
+
+func TestExecute_CapturesOutput(t *testing.T) {
+	cfg := config.New()
+	cfg.AgentCmd = writeFakeAgent(t, `echo "done"; echo "warning" >&2`)
+
+	output, err := Execute(cfg, "prompt")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if output != "done\nwarning" {
+		t.Errorf("output = %q", output)
+	}
+}
+
+func TestExecute_Timeout(t *testing.T) {
+	cfg := config.New()
+	// The agent starts a child that would keep running after the agent is killed
+	cfg.AgentCmd = writeFakeAgent(t, `echo "started"; sleep 30 & sleep 30`)
+	cfg.IterationTimeout = "500ms"
+
+	start := time.Now()
+	output, err := Execute(cfg, "prompt")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("agent was not killed promptly (took %s)", elapsed)
+	}
+	if !strings.Contains(output, "started") {
+		t.Errorf("expected partial output to be returned, got %q", output)
+	}
+}
+
+func TestExecute_Interrupted(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "started")
+	cfg := config.New()
+	cfg.AgentCmd = writeFakeAgent(t, `sleep 30 & touch "`+marker+`"; wait`)
+	cfg.IterationTimeout = "1m"
+
+	// Interrupt Ralph once the agent runs, and with it the relay
+	go func() {
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+			if _, err := os.Stat(marker); err == nil {
+				p, _ := os.FindProcess(os.Getpid())
+				p.Signal(os.Interrupt)
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	if _, err := Execute(cfg, "prompt"); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Execute() = %v, want ErrInterrupted", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("interrupt did not stop the agent promptly (took %s)", elapsed)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	cfg := config.New()
+	cfg.AgentCmd = writeFakeAgent(t, `echo OK`)
+	if err := HealthCheck(context.Background(), cfg, "Reply with OK"); err != nil {
+		t.Errorf("HealthCheck() = %v", err)
+	}
+
+	cfg.AgentCmd = writeFakeAgent(t, `echo "Not logged in" >&2; exit 1`)
+	if err := HealthCheck(context.Background(), cfg, "Reply with OK"); err == nil || !strings.Contains(err.Error(), "Not logged in") {
+		t.Errorf("HealthCheck() of a failing agent = %v, want its stderr", err)
+	}
+
+	cfg.AgentCmd = writeFakeAgent(t, `exit 0`)
+	if err := HealthCheck(context.Background(), cfg, "Reply with OK"); err == nil {
+		t.Error("HealthCheck() accepted an empty answer")
+	}
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/llm"
+)
+
+// APISystemPrompt instructs API models how to make changes, since they cannot
+// edit files directly the way agent CLIs do
+const APISystemPrompt = "You are an autonomous software engineer working in a repository. " +
+	"You cannot run commands or edit files directly. Referenced files are included below the task. " +
+	"To create or modify a file, output its complete new contents in a block of the form " +
+	"<file path=\"relative/path\">\n...contents...\n</file>. " +
+	"Only files emitted this way will be written; paths must be relative to the repository root. " +
+	"Follow the task instructions exactly, including any completion markers."
+
+var (
+	apiMu      sync.Mutex
+	apiClients = make(map[apiSettings]*llm.Client)
+)
+
+// apiSettings identifies the API client to use for a configuration
+type apiSettings struct {
+	backend, baseURL, model, keyEnv string
+	maxTokens                       int
+}
+
+// apiClientFor returns the shared API client for the configured backend
+// settings, creating it on first use
+func apiClientFor(cfg *config.Config) (*llm.Client, error) {
+	apiMu.Lock()
+	defer apiMu.Unlock()
+
+	settings := apiSettings{cfg.AgentBackend, cfg.APIBaseURL, cfg.APIModel, cfg.APIKeyEnv, cfg.APIMaxTokens}
+	if client, ok := apiClients[settings]; ok {
+		return client, nil
+	}
+
+	provider, err := llm.ParseProvider(cfg.AgentBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := ResolveEnv(cfg.AgentEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve agent environment: %w", err)
+	}
+
+	client, err := llm.NewClient(llm.Config{
+		Provider:  provider,
+		BaseURL:   cfg.APIBaseURL,
+		Model:     cfg.APIModel,
+		APIKeyEnv: cfg.APIKeyEnv,
+		MaxTokens: cfg.APIMaxTokens,
+		Env:       env,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	apiClients[settings] = client
+	return client, nil
+}
+
+// CheckAPIBackend verifies that the API backend is usable (model set, key present)
+func CheckAPIBackend(cfg *config.Config) error {
+	_, err := apiClientFor(cfg)
+	return err
+}
+
+// executeAPI sends the prompt to the configured HTTP API and applies any file
+// blocks in the response to the working directory
+func executeAPI(ctx context.Context, cfg *config.Config, prompt string) (string, error) {
+	client, err := apiClientFor(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Verbose {
+		apiCfg := client.Config()
+		fmt.Printf("API request: %s %s (model %s)\n", apiCfg.Provider, apiCfg.BaseURL, apiCfg.Model)
+	}
+
+	// Files referenced by the prompt are sent too, so they are redacted as well
+	resp, err := client.Complete(ctx, APISystemPrompt, redactPrompt(llm.ExpandFileReferences(prompt)))
+	if err != nil {
+		return "", fmt.Errorf("agent API request failed: %w", err)
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	written, err := llm.ApplyFileBlocks(resp.Content, root)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply agent changes: %w", err)
+	}
+
+	output := strings.TrimSpace(resp.Content)
+	if len(written) > 0 {
+		output += "\nFiles written: " + strings.Join(written, ", ")
+	}
+
+	return output, nil
+}
+
+// TokenUsage returns the tokens consumed by the API backend so far in this
+// process. It is zero when the CLI backend is used.
+func TokenUsage() llm.Usage {
+	apiMu.Lock()
+	defer apiMu.Unlock()
+
+	var usage llm.Usage
+	for _, client := range apiClients {
+		usage = usage.Add(client.TotalUsage())
+	}
+	return usage
+}
+
+// EstimatedCost returns the cost of the tokens consumed so far, based on the
+// configured per-million-token prices
+func EstimatedCost(cfg *config.Config) float64 {
+	return llm.EstimateCost(TokenUsage(), cfg.APIInputCost, cfg.APIOutputCost)
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// envRefPrefix marks a value that is read from Ralph's own environment
+	envRefPrefix = "env:"
+	// fileRefPrefix marks a value that is read from a file (e.g., a mounted secret)
+	fileRefPrefix = "file:"
+)
+
+// ResolveEnv resolves per-agent environment variables. Values may be literal,
+// or credential references: "env:NAME" reads NAME from Ralph's environment and
+// "file:PATH" reads the (trimmed) contents of PATH. References are resolved at
+// execution time so secrets never need to be written into config files.
+func ResolveEnv(vars map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if err := ValidateEnvName(name); err != nil {
+			return nil, err
+		}
+		v, err := ResolveValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		resolved[name] = v
+	}
+	return resolved, nil
+}
+
+// ValidateEnvName checks that name can be used as an environment variable name
+func ValidateEnvName(name string) error {
+	if name == "" {
+		return fmt.Errorf("environment variable name cannot be empty")
+	}
+	if strings.ContainsAny(name, "= \t\n\x00") {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	return nil
+}
+
+// ResolveValue expands a single credential reference (env:NAME or file:PATH),
+// returning other values as they are
+func ResolveValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envRefPrefix):
+		name := strings.TrimPrefix(value, envRefPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("referenced environment variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(value, fileRefPrefix):
+		path := strings.TrimPrefix(value, fileRefPrefix)
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read credential file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return value, nil
+	}
+}
+
+// mergeEnv returns base with the given variables added or overridden, in a
+// stable order. The process environment itself is never modified.
+func mergeEnv(base []string, vars map[string]string) []string {
+	merged := make([]string, 0, len(base)+len(vars))
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, overridden := vars[name]; overridden {
+			continue
+		}
+		merged = append(merged, kv)
+	}
+
+	for _, name := range EnvNames(vars) {
+		merged = append(merged, name+"="+vars[name])
+	}
+	return merged
+}
+
+// EnvNames returns the sorted variable names, for display without leaking values
+func EnvNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+)
+
+func TestResolveEnv(t *testing.T) {
+	t.Setenv("RALPH_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(secretFile, []byte("from-file\n"), 0600)
+
+	got, err := ResolveEnv(map[string]string{
+		"LITERAL":   "value",
+		"FROM_ENV":  "env:RALPH_TEST_SECRET",
+		"FROM_FILE": "file:" + secretFile,
+	})
+	if err != nil {
+		t.Fatalf("ResolveEnv failed: %v", err)
+	}
+	want := map[string]string{"LITERAL": "value", "FROM_ENV": "from-env", "FROM_FILE": "from-file"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveEnv = %v, want %v", got, want)
+	}
+
+	errorCases := []map[string]string{
+		{"KEY": "env:RALPH_TEST_UNSET_VARIABLE"},
+		{"KEY": "file:" + filepath.Join(t.TempDir(), "missing")},
+		{"BAD=NAME": "value"},
+		{"": "value"},
+	}
+	for _, vars := range errorCases {
+		if _, err := ResolveEnv(vars); err == nil {
+			t.Errorf("ResolveEnv(%v) should fail", vars)
+		}
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	base := []string{"PATH=/bin", "API_KEY=global", "HOME=/root"}
+	got := mergeEnv(base, map[string]string{"API_KEY": "agent", "MODEL": "fast"})
+	want := []string{"PATH=/bin", "HOME=/root", "API_KEY=agent", "MODEL=fast"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnv = %v, want %v", got, want)
+	}
+}
+
+func TestExecute_AgentEnvIsScopedToAgent(t *testing.T) {
+	t.Setenv("RALPH_TEST_GLOBAL_KEY", "global")
+	cfg := config.New()
+	cfg.AgentCmd = writeFakeAgent(t, `echo "$RALPH_TEST_AGENT_KEY $RALPH_TEST_GLOBAL_KEY"`)
+	cfg.AgentEnv = map[string]string{"RALPH_TEST_AGENT_KEY": "env:RALPH_TEST_GLOBAL_KEY"}
+
+	output, err := Execute(cfg, "prompt")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if output != "global global" {
+		t.Errorf("output = %q, want agent env to be set", output)
+	}
+	if _, ok := os.LookupEnv("RALPH_TEST_AGENT_KEY"); ok {
+		t.Error("agent environment must not leak into Ralph's environment")
+	}
+}
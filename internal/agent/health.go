@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/config"
+)
+
+// HealthCheckTimeout bounds how long the agent may take to answer the health
+// check
+const HealthCheckTimeout = 2 * time.Minute
+
+// HealthCheck sends the agent a cheap prompt and checks that it answers,
+// verifying that the agent is installed, authenticated and can reach its API.
+// The exchange is not recorded in the transcript.
+func HealthCheck(ctx context.Context, cfg *config.Config, prompt string) error {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	output, err := execute(ctx, cfg, prompt, nil, nil)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("no answer within %s", HealthCheckTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(output) == "" {
+		return fmt.Errorf("empty answer")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"github.com/logimos/ralph/internal/config"
+)
+
+// Pool is the agents an iteration can be served by: the configured agent
+// followed by the fallback agents (-fallback-agents), tried in order when an
+// agent errors or times out. With rotation (-rotate-agents), each iteration
+// starts at the next agent of the pool, spreading rate limits across them.
+type Pool struct {
+	fallbacks []string
+	rotate    bool
+	next      int // Agent the next iteration starts at, with rotation
+}
+
+// NewPool creates a pool of the configured agent and the fallback agent
+// commands in cfg
+func NewPool(cfg *config.Config) *Pool {
+	return &Pool{fallbacks: cfg.FallbackAgents, rotate: cfg.RotateAgents}
+}
+
+// Size returns the number of agents in the pool, the configured agent
+// included
+func (p *Pool) Size() int {
+	return 1 + len(p.fallbacks)
+}
+
+// Agents returns the agents of the pool. The configured agent is cfg itself;
+// fallback agents are copies of cfg that run the fallback command with the
+// CLI backend.
+func (p *Pool) Agents(cfg *config.Config) []*config.Config {
+	agents := []*config.Config{cfg}
+	for _, cmd := range p.fallbacks {
+		fallback := *cfg
+		fallback.AgentCmd = cmd
+		fallback.AgentBackend = config.DefaultAgentBackend
+		agents = append(agents, &fallback)
+	}
+	return agents
+}
+
+// Order returns the agents to try for the next iteration, in order, and
+// advances the rotation
+func (p *Pool) Order(cfg *config.Config) []*config.Config {
+	agents := p.Agents(cfg)
+	if !p.rotate {
+		return agents
+	}
+
+	start := p.next % len(agents)
+	p.next++
+	return append(agents[start:], agents[:start]...)
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+)
+
+// commands returns the agent command of each config
+func commands(cfgs []*config.Config) []string {
+	cmds := make([]string, len(cfgs))
+	for i, c := range cfgs {
+		cmds[i] = c.AgentCmd
+	}
+	return cmds
+}
+
+func TestPoolOrder(t *testing.T) {
+	cfg := config.New()
+	cfg.AgentCmd = "claude"
+	cfg.AgentBackend = "anthropic"
+	cfg.FallbackAgents = []string{"cursor-agent", "aider"}
+
+	pool := NewPool(cfg)
+	if pool.Size() != 3 {
+		t.Errorf("Size() = %d, want 3", pool.Size())
+	}
+	for i := 0; i < 2; i++ {
+		agents := pool.Order(cfg)
+		if got := commands(agents); len(got) != 3 || got[0] != "claude" || got[1] != "cursor-agent" || got[2] != "aider" {
+			t.Fatalf("Order() = %v", got)
+		}
+		if agents[0] != cfg || agents[1].AgentBackend != config.DefaultAgentBackend {
+			t.Error("the configured agent should be used as is, fallbacks with the CLI backend")
+		}
+	}
+
+	cfg.RotateAgents = true
+	pool = NewPool(cfg)
+	for _, first := range []string{"claude", "cursor-agent", "aider", "claude"} {
+		got := commands(pool.Order(cfg))
+		if got[0] != first || len(got) != 3 {
+			t.Errorf("Order() = %v, want %s first", got, first)
+		}
+	}
+
+	if got := commands(NewPool(config.New()).Order(config.New())); len(got) != 1 {
+		t.Errorf("Order() without fallbacks = %v", got)
+	}
+}
@@ -0,0 +1,24 @@
+package agent
+
+import "regexp"
+
+// rateLimitPattern matches the messages agent CLIs and provider APIs give when
+// requests are throttled (HTTP 429)
+var rateLimitPattern = regexp.MustCompile(`(?i)\b(?:http|status|code|error)[ :=]*429\b|too many requests|rate[ _-]?limit|usage limit|quota exceeded|resource_exhausted`)
+
+// rateLimitOutputMax is the longest output of a successful agent run that is
+// checked for rate limit messages. Longer output is real work, which may well
+// mention rate limits.
+const rateLimitOutputMax = 500
+
+// IsRateLimited reports whether the agent was throttled by its provider,
+// judging by its error, or by its output if the agent only gave a short reply
+func IsRateLimited(output string, err error) bool {
+	if err != nil && rateLimitPattern.MatchString(err.Error()) {
+		return true
+	}
+	if err == nil && len(output) > rateLimitOutputMax {
+		return false
+	}
+	return rateLimitPattern.MatchString(output)
+}
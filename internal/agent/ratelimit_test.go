@@ -0,0 +1,29 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIsRateLimited(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		output string
+		err    error
+		want   bool
+	}{
+		{"api 429", "", errors.New("agent API request failed: API returned status 429: slow down"), true},
+		{"cli stderr", "", errors.New("agent command failed: exit status 1\nstderr: Error: Too Many Requests"), true},
+		{"short reply", "Claude usage limit reached. Your limit will reset at 5pm.", nil, true},
+		{"quota", "", errors.New("RESOURCE_EXHAUSTED: quota exceeded"), true},
+		{"other failure", "", errors.New("agent command failed: exit status 1\nstderr: not logged in"), false},
+		{"line number", "", errors.New("main.go:429: undefined: x"), false},
+		{"work", "Added a rate limiter to the API client.\n" + strings.Repeat("Implemented the feature. ", 30), nil, false},
+		{"success", "done", nil, false},
+	} {
+		if got := IsRateLimited(tt.output, tt.err); got != tt.want {
+			t.Errorf("%s: IsRateLimited() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
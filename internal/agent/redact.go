@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/logimos/ralph/internal/redact"
+)
+
+var (
+	redactMu       sync.Mutex
+	promptRedactor *redact.Redactor
+)
+
+// SetRedactor masks secrets in every prompt before it is sent to the agent.
+// A nil redactor sends prompts unchanged (-no-redact).
+func SetRedactor(r *redact.Redactor) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	promptRedactor = r
+}
+
+// redactPrompt masks secrets in a prompt with the redactor, if one is set
+func redactPrompt(prompt string) string {
+	redactMu.Lock()
+	r := promptRedactor
+	redactMu.Unlock()
+	return r.Redact(prompt)
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/transcript"
+)
+
+var (
+	transcriptMu       sync.Mutex
+	transcriptRecorder *transcript.Recorder
+)
+
+// SetTranscript records the prompt and response of every agent execution with
+// r (-transcript). A nil recorder stops recording.
+func SetTranscript(r *transcript.Recorder) {
+	transcriptMu.Lock()
+	defer transcriptMu.Unlock()
+	transcriptRecorder = r
+}
+
+// recordTranscript records an agent execution in the transcript, if one is set
+func recordTranscript(cfg *config.Config, prompt, output string, err error, start time.Time) {
+	transcriptMu.Lock()
+	r := transcriptRecorder
+	transcriptMu.Unlock()
+	if r == nil {
+		return
+	}
+
+	e := transcript.Entry{
+		Time:     start,
+		Agent:    cfg.AgentCmd,
+		Prompt:   prompt,
+		Response: output,
+		Duration: time.Since(start),
+	}
+	if cfg.UsesAPIBackend() {
+		e.Agent = cfg.AgentBackend + ":" + cfg.APIModel
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := r.Record(e); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record transcript: %v\n", err)
+	}
+}
@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/transcript"
+)
+
+func TestExecute_RecordsTranscript(t *testing.T) {
+	cfg := config.New()
+	cfg.AgentCmd = writeFakeAgent(t, `echo "done"`)
+	dir := t.TempDir()
+	r := transcript.NewRecorder(dir, "run-1")
+	r.SetIteration(3, 2)
+	SetTranscript(r)
+	defer SetTranscript(nil)
+
+	if _, err := Execute(cfg, "implement feature 2"); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	failing := *cfg
+	failing.AgentCmd = writeFakeAgent(t, `echo "boom" >&2; exit 1`)
+	Execute(&failing, "try again")
+
+	entries, err := transcript.NewStore(dir).Load("run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("transcript has %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Prompt != "implement feature 2" || e.Response != "done" || e.Iteration != 3 || e.FeatureID != 2 || e.Agent != cfg.AgentCmd {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := entries[1]; e.Prompt != "try again" || !strings.Contains(e.Error, "boom") {
+		t.Errorf("second entry = %+v", e)
+	}
+}
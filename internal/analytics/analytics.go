@@ -0,0 +1,347 @@
+// Package analytics aggregates the run history into statistics per feature
+// category and complexity, to follow how the agent performs over time.
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// Uncategorized is the category of features whose category is unknown
+const Uncategorized = "uncategorized"
+
+// complexityOrder is the order complexity levels are reported in; other
+// levels follow in alphabetical order
+var complexityOrder = []string{"low", "medium", "high"}
+
+// CategoryStats aggregates the work on the features of a category
+type CategoryStats struct {
+	Category           string             `json:"category"`
+	Features           int                `json:"features"`           // Distinct features worked on
+	Completed          int                `json:"completed"`          // Distinct features completed
+	Iterations         int                `json:"iterations"`         // Iterations spent on the features
+	TrackedIterations  int                `json:"tracked_iterations"` // Iterations of the runs that record failures
+	Failures           int                `json:"failures"`
+	FailureRate        float64            `json:"failure_rate"`       // Failures per tracked iteration
+	AverageIterations  float64            `json:"average_iterations"` // Iterations per completed feature
+	ByComplexity       map[string]float64 `json:"average_iterations_by_complexity,omitempty"`
+	ValidationsPassed  int                `json:"validations_passed"`
+	ValidationsFailed  int                `json:"validations_failed"`
+	ValidationPassRate float64            `json:"validation_pass_rate"` // 0 if nothing was validated
+}
+
+// categoryTally collects the statistics of a category while runs are read
+type categoryTally struct {
+	CategoryStats
+	features  map[int]bool
+	completed map[int]bool
+	done      map[string]int // Completed features by complexity
+	doneIters map[string]int // Iterations of the completed features by complexity
+}
+
+// Count is the number of occurrences of a failure type or deferral cause
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Report holds the statistics of the runs of the history
+type Report struct {
+	Runs               int                `json:"runs"`
+	From               time.Time          `json:"from"` // Start of the first run
+	To                 time.Time          `json:"to"`   // Start of the last run
+	Iterations         int                `json:"iterations"`
+	TrackedIterations  int                `json:"tracked_iterations"`
+	Failures           int                `json:"failures"`
+	FailureRate        float64            `json:"failure_rate"`
+	ValidationsPassed  int                `json:"validations_passed"`
+	ValidationsFailed  int                `json:"validations_failed"`
+	ValidationPassRate float64            `json:"validation_pass_rate"`
+	Categories         []CategoryStats    `json:"categories"`
+	Complexities       []string           `json:"complexities"` // Complexity levels of the completed features
+	ByComplexity       map[string]float64 `json:"average_iterations_by_complexity,omitempty"`
+	FailureTypes       []Count            `json:"failure_types"`   // Most frequent first
+	DeferralCauses     []Count            `json:"deferral_causes"` // Most frequent first
+}
+
+// Build aggregates runs. The category of a feature is the one recorded by
+// the run, or else its category in plans. Failures, failure types,
+// validations and deferrals are only known for runs that record them, and
+// failure rates are computed over the iterations of those runs.
+// Features deferred in plans but by none of the runs (e.g., by hand) count
+// towards the deferral causes.
+func Build(runs []*history.Run, plans []plan.Plan) *Report {
+	report := &Report{
+		Runs:           len(runs),
+		Categories:     []CategoryStats{},
+		Complexities:   []string{},
+		FailureTypes:   []Count{},
+		DeferralCauses: []Count{},
+	}
+	planCategories := make(map[int]string)
+	for _, p := range plans {
+		planCategories[p.ID] = p.Category
+	}
+
+	categories := make(map[string]*categoryTally)
+	stats := func(r *history.Run, id int) *categoryTally {
+		category := r.FeatureCategories[id]
+		if category == "" {
+			category = planCategories[id]
+		}
+		if category == "" {
+			category = Uncategorized
+		}
+		s := categories[category]
+		if s == nil {
+			s = &categoryTally{
+				CategoryStats: CategoryStats{Category: category},
+				features:      make(map[int]bool),
+				completed:     make(map[int]bool),
+				done:          make(map[string]int),
+				doneIters:     make(map[string]int),
+			}
+			categories[category] = s
+		}
+		s.features[id] = true
+		return s
+	}
+
+	failureTypes := make(map[string]int)
+	deferrals := make(map[string]int)
+	deferred := make(map[int]bool)
+	for i, r := range runs {
+		if i == 0 || r.StartTime.Before(report.From) {
+			report.From = r.StartTime
+		}
+		if r.StartTime.After(report.To) {
+			report.To = r.StartTime
+		}
+		// Runs recording failures record the category of every feature
+		tracks := len(r.FeatureCategories) > 0
+
+		for id, n := range r.IterationsPerFeature {
+			if id <= 0 {
+				continue
+			}
+			s := stats(r, id)
+			s.Iterations += n
+			report.Iterations += n
+			if tracks {
+				s.TrackedIterations += n
+				report.TrackedIterations += n
+			}
+		}
+		for _, id := range r.FeaturesCompleted {
+			s := stats(r, id)
+			s.completed[id] = true
+			if n := r.IterationsPerFeature[id]; n > 0 {
+				complexity := r.FeatureComplexity[id]
+				if complexity == "" {
+					complexity = "unknown"
+				}
+				s.done[complexity]++
+				s.doneIters[complexity] += n
+			}
+		}
+		for id, n := range r.FeatureFailures {
+			stats(r, id).Failures += n
+			report.Failures += n
+		}
+		for t, n := range r.FailuresByType {
+			failureTypes[t] += n
+		}
+		for id, v := range r.Validations {
+			s := stats(r, id)
+			s.ValidationsPassed += v.Passed
+			s.ValidationsFailed += v.Failed
+			report.ValidationsPassed += v.Passed
+			report.ValidationsFailed += v.Failed
+		}
+		for id, reason := range r.Deferrals {
+			stats(r, id)
+			deferrals[deferralCause(reason)]++
+			deferred[id] = true
+		}
+	}
+	for _, p := range plans {
+		if p.Deferred && !deferred[p.ID] {
+			deferrals[deferralCause(p.DeferReason)]++
+		}
+	}
+
+	complexities := make(map[string]bool)
+	done := make(map[string]int)
+	doneIters := make(map[string]int)
+	for _, s := range categories {
+		s.Features = len(s.features)
+		s.Completed = len(s.completed)
+		s.FailureRate = ratio(s.Failures, s.TrackedIterations)
+		s.ValidationPassRate = ratio(s.ValidationsPassed, s.ValidationsPassed+s.ValidationsFailed)
+		completedIters, completed := 0, 0
+		for complexity, n := range s.done {
+			if s.ByComplexity == nil {
+				s.ByComplexity = make(map[string]float64)
+			}
+			s.ByComplexity[complexity] = ratio(s.doneIters[complexity], n)
+			complexities[complexity] = true
+			done[complexity] += n
+			doneIters[complexity] += s.doneIters[complexity]
+			completed += n
+			completedIters += s.doneIters[complexity]
+		}
+		s.AverageIterations = ratio(completedIters, completed)
+		report.Categories = append(report.Categories, s.CategoryStats)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		a, b := report.Categories[i], report.Categories[j]
+		if a.Iterations != b.Iterations {
+			return a.Iterations > b.Iterations
+		}
+		return a.Category < b.Category
+	})
+
+	for complexity := range complexities {
+		report.Complexities = append(report.Complexities, complexity)
+		if report.ByComplexity == nil {
+			report.ByComplexity = make(map[string]float64)
+		}
+		report.ByComplexity[complexity] = ratio(doneIters[complexity], done[complexity])
+	}
+	sort.Slice(report.Complexities, func(i, j int) bool {
+		a, b := complexityRank(report.Complexities[i]), complexityRank(report.Complexities[j])
+		if a != b {
+			return a < b
+		}
+		return report.Complexities[i] < report.Complexities[j]
+	})
+
+	report.FailureRate = ratio(report.Failures, report.TrackedIterations)
+	report.ValidationPassRate = ratio(report.ValidationsPassed, report.ValidationsPassed+report.ValidationsFailed)
+	report.FailureTypes = sortedCounts(failureTypes)
+	report.DeferralCauses = sortedCounts(deferrals)
+	return report
+}
+
+// Format returns the report as text
+func (r *Report) Format() string {
+	var sb strings.Builder
+	if r.Runs == 0 {
+		return "No runs recorded yet.\n"
+	}
+	fmt.Fprintf(&sb, "=== Analytics: %d run(s), %s to %s ===\n", r.Runs, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+	fmt.Fprintf(&sb, "Iterations: %d, failures: %d (%s of iterations), validations: %d/%d passed (%s)\n",
+		r.Iterations, r.Failures, percent(r.FailureRate, r.TrackedIterations > 0),
+		r.ValidationsPassed, r.ValidationsPassed+r.ValidationsFailed,
+		percent(r.ValidationPassRate, r.ValidationsPassed+r.ValidationsFailed > 0))
+
+	if len(r.Categories) > 0 {
+		sb.WriteString("\nCategories:\n")
+		fmt.Fprintf(&sb, "  %-20s %8s %6s %10s %9s %12s %11s\n", "Category", "Features", "Done", "Iterations", "Avg iter", "Failure rate", "Validations")
+		for _, c := range r.Categories {
+			avg := "-"
+			if c.Completed > 0 && c.AverageIterations > 0 {
+				avg = fmt.Sprintf("%.1f", c.AverageIterations)
+			}
+			fmt.Fprintf(&sb, "  %-20s %8d %6d %10d %9s %12s %11s\n", c.Category, c.Features, c.Completed, c.Iterations, avg,
+				percent(c.FailureRate, c.TrackedIterations > 0), percent(c.ValidationPassRate, c.ValidationsPassed+c.ValidationsFailed > 0))
+		}
+	}
+
+	if len(r.Complexities) > 0 {
+		sb.WriteString("\nAverage iterations per completed feature:\n")
+		fmt.Fprintf(&sb, "  %-20s", "Category")
+		for _, complexity := range r.Complexities {
+			fmt.Fprintf(&sb, " %8s", complexity)
+		}
+		sb.WriteString("\n")
+		for _, c := range r.Categories {
+			if len(c.ByComplexity) > 0 {
+				writeComplexityRow(&sb, c.Category, r.Complexities, c.ByComplexity)
+			}
+		}
+		writeComplexityRow(&sb, "all", r.Complexities, r.ByComplexity)
+	}
+
+	if len(r.FailureTypes) > 0 {
+		sb.WriteString("\nFailure types:\n")
+		for _, c := range r.FailureTypes {
+			fmt.Fprintf(&sb, "  %-20s %d\n", c.Name, c.Count)
+		}
+	}
+	if len(r.DeferralCauses) > 0 {
+		sb.WriteString("\nDeferral causes:\n")
+		for _, c := range r.DeferralCauses {
+			fmt.Fprintf(&sb, "  %-20s %d\n", c.Name, c.Count)
+		}
+	}
+	return sb.String()
+}
+
+// writeComplexityRow writes the average iterations of a category for each
+// complexity level
+func writeComplexityRow(sb *strings.Builder, name string, complexities []string, averages map[string]float64) {
+	fmt.Fprintf(sb, "  %-20s", name)
+	for _, complexity := range complexities {
+		if avg, ok := averages[complexity]; ok {
+			fmt.Fprintf(sb, " %8.1f", avg)
+		} else {
+			fmt.Fprintf(sb, " %8s", "-")
+		}
+	}
+	sb.WriteString("\n")
+}
+
+// deferralCause names the cause of a deferral
+func deferralCause(reason string) string {
+	if reason == "" {
+		return "unspecified"
+	}
+	return reason
+}
+
+// complexityRank returns the position of a complexity level in the report
+func complexityRank(complexity string) int {
+	for i, c := range complexityOrder {
+		if c == complexity {
+			return i
+		}
+	}
+	return len(complexityOrder)
+}
+
+// sortedCounts returns counts, most frequent first
+func sortedCounts(counts map[string]int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for name, n := range counts {
+		sorted = append(sorted, Count{Name: name, Count: n})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// ratio returns n/total, or 0 if total is 0
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// percent formats a rate as a percentage, or "-" if it is not known
+func percent(rate float64, known bool) string {
+	if !known {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestBuild(t *testing.T) {
+	start := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	runs := []*history.Run{
+		{
+			// Recorded before outcomes were: categories come from the plan
+			StartTime:            start,
+			FeaturesCompleted:    []int{1},
+			IterationsPerFeature: map[int]int{1: 2, 2: 3},
+			FeatureComplexity:    map[int]string{1: "low", 2: "high"},
+		},
+		{
+			StartTime:            start.Add(48 * time.Hour),
+			FeaturesCompleted:    []int{2, 3},
+			IterationsPerFeature: map[int]int{2: 5, 3: 4},
+			FeatureComplexity:    map[int]string{2: "high", 3: "low"},
+			FeatureCategories:    map[int]string{2: "feature", 3: "fix"},
+			FeatureFailures:      map[int]int{2: 2, 3: 1},
+			FailuresByType:       map[string]int{"test_failure": 2, "lint_failure": 1},
+			Validations:          map[int]history.ValidationCount{2: {Passed: 3, Failed: 1}},
+			Deferrals:            map[int]string{4: "iteration_limit"},
+		},
+	}
+	plans := []plan.Plan{
+		{ID: 1, Category: "feature", Tested: true},
+		{ID: 2, Category: "feature", Tested: true},
+		{ID: 3, Category: "fix", Tested: true},
+		{ID: 4, Category: "fix", Deferred: true, DeferReason: "iteration_limit"},
+		{ID: 5, Category: "feature", Deferred: true},
+	}
+
+	r := Build(runs, plans)
+	if r.Runs != 2 || !r.From.Equal(start) || !r.To.Equal(start.Add(48*time.Hour)) {
+		t.Errorf("Runs, From, To = %d, %v, %v", r.Runs, r.From, r.To)
+	}
+	if r.Iterations != 14 || r.TrackedIterations != 9 || r.Failures != 3 {
+		t.Errorf("Iterations, TrackedIterations, Failures = %d, %d, %d, want 14, 9, 3", r.Iterations, r.TrackedIterations, r.Failures)
+	}
+	if r.ValidationPassRate != 0.75 {
+		t.Errorf("ValidationPassRate = %v, want 0.75", r.ValidationPassRate)
+	}
+
+	if len(r.Categories) != 2 {
+		t.Fatalf("Categories = %+v, want feature and fix", r.Categories)
+	}
+	feature := r.Categories[0]
+	want := CategoryStats{
+		Category:           "feature",
+		Features:           2,
+		Completed:          2,
+		Iterations:         10,
+		TrackedIterations:  5,
+		Failures:           2,
+		FailureRate:        0.4,
+		AverageIterations:  3.5,
+		ByComplexity:       map[string]float64{"low": 2, "high": 5},
+		ValidationsPassed:  3,
+		ValidationsFailed:  1,
+		ValidationPassRate: 0.75,
+	}
+	if !reflect.DeepEqual(feature, want) {
+		t.Errorf("feature stats =\n%+v\nwant\n%+v", feature, want)
+	}
+	if fix := r.Categories[1]; fix.Category != "fix" || fix.Features != 2 || fix.Completed != 1 || fix.FailureRate != 0.25 {
+		t.Errorf("fix stats = %+v", fix)
+	}
+
+	if want := []string{"low", "high"}; !reflect.DeepEqual(r.Complexities, want) {
+		t.Errorf("Complexities = %v, want %v", r.Complexities, want)
+	}
+	if want := map[string]float64{"low": 3, "high": 5}; !reflect.DeepEqual(r.ByComplexity, want) {
+		t.Errorf("ByComplexity = %v, want %v", r.ByComplexity, want)
+	}
+	if want := []Count{{"test_failure", 2}, {"lint_failure", 1}}; !reflect.DeepEqual(r.FailureTypes, want) {
+		t.Errorf("FailureTypes = %v, want %v", r.FailureTypes, want)
+	}
+	// Feature 4 was deferred by a run, feature 5 by hand
+	if want := []Count{{"iteration_limit", 1}, {"unspecified", 1}}; !reflect.DeepEqual(r.DeferralCauses, want) {
+		t.Errorf("DeferralCauses = %v, want %v", r.DeferralCauses, want)
+	}
+}
+
+func TestReportFormat(t *testing.T) {
+	if got := Build(nil, nil).Format(); got != "No runs recorded yet.\n" {
+		t.Errorf("Format() of no runs = %q", got)
+	}
+
+	runs := []*history.Run{{
+		StartTime:            time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC),
+		FeaturesCompleted:    []int{1},
+		IterationsPerFeature: map[int]int{1: 4},
+		FeatureComplexity:    map[int]string{1: "medium"},
+		FeatureCategories:    map[int]string{1: "feature"},
+		FeatureFailures:      map[int]int{1: 1},
+		FailuresByType:       map[string]int{"test_failure": 1},
+	}}
+	out := Build(runs, nil).Format()
+	for _, want := range []string{
+		"=== Analytics: 1 run(s), 2026-09-01 to 2026-09-01 ===",
+		"Iterations: 4, failures: 1 (25% of iterations), validations: 0/0 passed (-)",
+		"feature                     1      1          4       4.0          25%           -",
+		"medium",
+		"test_failure         1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Deferral causes") {
+		t.Errorf("Format() lists deferral causes without deferrals:\n%s", out)
+	}
+}
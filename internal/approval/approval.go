@@ -0,0 +1,137 @@
+// Package approval implements the interactive gate that lets a human review
+// the changes of each agent iteration before Ralph moves on.
+package approval
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Decision is the reviewer's verdict on an iteration
+type Decision string
+
+const (
+	// Approve keeps the iteration's changes
+	Approve Decision = "approve"
+	// Reject rolls the iteration's changes back
+	Reject Decision = "reject"
+)
+
+// ErrNoInput is returned when the reviewer's input ends before a decision is made
+var ErrNoInput = errors.New("no input available for approval")
+
+// Changes provides the diff shown to the reviewer
+type Changes interface {
+	DiffStat() (string, error)
+	Diff() (string, error)
+}
+
+// Result holds the reviewer's decision
+type Result struct {
+	Decision Decision
+	Reason   string // Optional reason given for a rejection, passed on to the agent
+}
+
+// Reviewer asks a human to approve each iteration
+type Reviewer struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewReviewer creates a reviewer reading answers from in and writing to out
+func NewReviewer(in io.Reader, out io.Writer) *Reviewer {
+	return &Reviewer{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Review shows the iteration's diff stat and asks until the reviewer approves
+// or rejects it. The reviewer can also view the full diff, or edit files
+// and have the diff stat shown again.
+func (r *Reviewer) Review(iteration int, changes Changes) (Result, error) {
+	r.showStat(iteration, changes)
+
+	for {
+		fmt.Fprint(r.out, "Approve changes? [y]es, [n]o (roll back), [d]iff, [e]dit: ")
+		answer, err := r.readLine()
+		if err != nil {
+			return Result{}, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return Result{Decision: Approve}, nil
+		case "n", "no":
+			fmt.Fprint(r.out, "Reason for the agent (optional): ")
+			reason, err := r.readLine()
+			if err != nil && !errors.Is(err, ErrNoInput) {
+				return Result{}, err
+			}
+			return Result{Decision: Reject, Reason: reason}, nil
+		case "d", "diff":
+			diff, err := changes.Diff()
+			if err != nil {
+				fmt.Fprintf(r.out, "Failed to get diff: %v\n", err)
+				continue
+			}
+			fmt.Fprintln(r.out, diff)
+		case "e", "edit":
+			fmt.Fprint(r.out, "Edit the files, then press Enter to review again: ")
+			if _, err := r.readLine(); err != nil {
+				return Result{}, err
+			}
+			r.showStat(iteration, changes)
+		default:
+			fmt.Fprintln(r.out, "Please answer y, n, d or e.")
+		}
+	}
+}
+
+// Confirm asks a yes/no question. Only yes confirms; an empty answer is no.
+func (r *Reviewer) Confirm(question string) (bool, error) {
+	for {
+		fmt.Fprintf(r.out, "%s [y/N]: ", question)
+		answer, err := r.readLine()
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "", "n", "no":
+			return false, nil
+		default:
+			fmt.Fprintln(r.out, "Please answer y or n.")
+		}
+	}
+}
+
+// showStat prints the diff stat of the iteration's changes
+func (r *Reviewer) showStat(iteration int, changes Changes) {
+	stat, err := changes.DiffStat()
+	switch {
+	case err != nil:
+		fmt.Fprintf(r.out, "Failed to get changes of iteration %d: %v\n", iteration, err)
+	case stat == "":
+		fmt.Fprintf(r.out, "Iteration %d made no file changes.\n", iteration)
+	default:
+		fmt.Fprintf(r.out, "Changes in iteration %d:\n%s\n", iteration, stat)
+	}
+}
+
+// readLine reads one trimmed line of input
+func (r *Reviewer) readLine() (string, error) {
+	line, err := r.in.ReadString('\n')
+	if err != nil && (line == "" || err != io.EOF) {
+		if err == io.EOF {
+			return "", ErrNoInput
+		}
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
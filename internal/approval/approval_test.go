@@ -0,0 +1,131 @@
+package approval
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeChanges is a fixed set of changes
+type fakeChanges struct {
+	stat, diff string
+	statCalls  int
+}
+
+func (f *fakeChanges) DiffStat() (string, error) {
+	f.statCalls++
+	return f.stat, nil
+}
+
+func (f *fakeChanges) Diff() (string, error) {
+	return f.diff, nil
+}
+
+func TestReview(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantDecision Decision
+		wantReason   string
+		wantOutput   string
+		wantStats    int
+	}{
+		{
+			name:         "approve",
+			input:        "y\n",
+			wantDecision: Approve,
+			wantOutput:   " main.go | 2 +-",
+			wantStats:    1,
+		},
+		{
+			name:         "reject with reason",
+			input:        "n\ndon't touch main.go\n",
+			wantDecision: Reject,
+			wantReason:   "don't touch main.go",
+			wantStats:    1,
+		},
+		{
+			name:         "reject at end of input",
+			input:        "no",
+			wantDecision: Reject,
+			wantStats:    1,
+		},
+		{
+			name:         "show diff then approve",
+			input:        "d\nYES\n",
+			wantDecision: Approve,
+			wantOutput:   "+package main // agent",
+			wantStats:    1,
+		},
+		{
+			name:         "edit shows the stat again",
+			input:        "e\n\ny\n",
+			wantDecision: Approve,
+			wantStats:    2,
+		},
+		{
+			name:         "unknown answer asks again",
+			input:        "maybe\ny\n",
+			wantDecision: Approve,
+			wantOutput:   "Please answer y, n, d or e.",
+			wantStats:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := &fakeChanges{stat: " main.go | 2 +-", diff: "+package main // agent"}
+			var out strings.Builder
+			result, err := NewReviewer(strings.NewReader(tt.input), &out).Review(1, changes)
+			if err != nil {
+				t.Fatalf("Review failed: %v", err)
+			}
+			if result.Decision != tt.wantDecision {
+				t.Errorf("decision = %s, want %s", result.Decision, tt.wantDecision)
+			}
+			if result.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", result.Reason, tt.wantReason)
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("output missing %q:\n%s", tt.wantOutput, out.String())
+			}
+			if changes.statCalls != tt.wantStats {
+				t.Errorf("diff stat shown %d time(s), want %d", changes.statCalls, tt.wantStats)
+			}
+		})
+	}
+}
+
+func TestReview_NoInput(t *testing.T) {
+	_, err := NewReviewer(strings.NewReader(""), &strings.Builder{}).Review(1, &fakeChanges{})
+	if !errors.Is(err, ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"maybe\ny\n", true},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		got, err := NewReviewer(strings.NewReader(tt.input), &out).Confirm("Apply?")
+		if err != nil || got != tt.want {
+			t.Errorf("Confirm(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+		if !strings.HasPrefix(out.String(), "Apply? [y/N]: ") {
+			t.Errorf("Confirm(%q) output = %q", tt.input, out.String())
+		}
+	}
+
+	if _, err := NewReviewer(strings.NewReader(""), &strings.Builder{}).Confirm("Apply?"); !errors.Is(err, ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+}
@@ -38,6 +38,7 @@ type FileInfo struct {
 	Size         int64    `json:"size"`
 	LineCount    int      `json:"line_count,omitempty"`
 	LastModified time.Time `json:"last_modified"`
+	Hash         string   `json:"hash,omitempty"` // SHA-256 of the content, to detect drift
 }
 
 // PackageInfo represents a package or module in the codebase
@@ -81,6 +82,9 @@ type Baseline struct {
 	TotalLines    int               `json:"total_lines"`
 	Conventions   []string          `json:"conventions,omitempty"`
 	Patterns      []string          `json:"patterns,omitempty"`
+	Hotspots      []Hotspot         `json:"hotspots,omitempty"`    // Files changed most often by recent commits
+	CodeOwners    []OwnerRule       `json:"code_owners,omitempty"` // Rules of the CODEOWNERS file
+	OwnersFile    string            `json:"owners_file,omitempty"` // CODEOWNERS file the rules were read from
 }
 
 // Scanner handles codebase scanning and analysis
@@ -158,6 +162,10 @@ func (s *Scanner) Scan() (*Baseline, error) {
 	baseline.Conventions = s.detectConventions(files)
 	baseline.Patterns = s.detectPatterns(files)
 
+	// Detect ownership and the files changed most often
+	baseline.OwnersFile, baseline.CodeOwners = s.loadCodeOwners()
+	baseline.Hotspots = s.detectHotspots(files, baseline.CodeOwners)
+
 	return baseline, nil
 }
 
@@ -207,6 +215,10 @@ func (s *Scanner) scanFiles() ([]FileInfo, error) {
 			}
 		}
 
+		if hash, err := hashFile(path); err == nil {
+			fileInfo.Hash = hash
+		}
+
 		files = append(files, fileInfo)
 		return nil
 	})
@@ -546,7 +558,7 @@ func (s *Scanner) detectPackages(files []FileInfo) []PackageInfo {
 					Path: dir,
 					Type: "go",
 				}
-				// Could parse go.mod for module name and dependencies
+				s.parseManifest(&pkg, f.Path)
 				packages = append(packages, pkg)
 				pkgSet[dir] = true
 			}
@@ -556,6 +568,7 @@ func (s *Scanner) detectPackages(files []FileInfo) []PackageInfo {
 					Path: dir,
 					Type: "npm",
 				}
+				s.parseManifest(&pkg, f.Path)
 				packages = append(packages, pkg)
 				pkgSet[dir] = true
 			}
@@ -565,6 +578,7 @@ func (s *Scanner) detectPackages(files []FileInfo) []PackageInfo {
 					Path: dir,
 					Type: "cargo",
 				}
+				s.parseManifest(&pkg, f.Path)
 				packages = append(packages, pkg)
 				pkgSet[dir] = true
 			}
@@ -574,6 +588,7 @@ func (s *Scanner) detectPackages(files []FileInfo) []PackageInfo {
 					Path: dir,
 					Type: "python",
 				}
+				s.parseManifest(&pkg, f.Path)
 				packages = append(packages, pkg)
 				pkgSet[dir] = true
 			}
@@ -782,6 +797,34 @@ func (b *Baseline) Summary() string {
 		for _, p := range b.Patterns {
 			sb.WriteString(fmt.Sprintf("  - %s\n", p))
 		}
+		sb.WriteString("\n")
+	}
+
+	// Dependencies
+	if hasDependencies(b.Structure.Packages) {
+		sb.WriteString("Dependencies:\n")
+		for _, p := range b.Structure.Packages {
+			if len(p.Dependencies) > 0 {
+				sb.WriteString(fmt.Sprintf("  - %s: %d direct\n", p.label(), len(p.Dependencies)))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	// Hotspots and ownership
+	if len(b.Hotspots) > 0 {
+		sb.WriteString(fmt.Sprintf("Hotspots (changed in %d+ of the last %d commits):\n", MinHotspotChanges, HistoryCommits))
+		for _, h := range b.Hotspots {
+			sb.WriteString(fmt.Sprintf("  - %s: %d changes by %d author(s)", h.Path, h.Changes, h.Authors))
+			if len(h.Owners) > 0 {
+				sb.WriteString(fmt.Sprintf(", owned by %s", strings.Join(h.Owners, " ")))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	if b.OwnersFile != "" {
+		sb.WriteString(fmt.Sprintf("Code owners: %d rule(s) in %s\n", len(b.CodeOwners), b.OwnersFile))
 	}
 
 	return sb.String()
@@ -836,6 +879,37 @@ func (b *Baseline) BuildPromptContext() string {
 		sb.WriteString("\n")
 	}
 
+	// Libraries already available, so that they are not added again
+	if hasDependencies(b.Structure.Packages) {
+		sb.WriteString("Available libraries (already dependencies - use them instead of adding new ones):\n")
+		for _, p := range b.Structure.Packages {
+			if len(p.Dependencies) == 0 {
+				continue
+			}
+			deps := p.Dependencies
+			more := ""
+			if len(deps) > maxPromptDependencies {
+				more = fmt.Sprintf(" and %d more", len(deps)-maxPromptDependencies)
+				deps = deps[:maxPromptDependencies]
+			}
+			sb.WriteString(fmt.Sprintf("- %s: %s%s\n", p.label(), strings.Join(deps, ", "), more))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Files changed often are riskier to modify
+	if len(b.Hotspots) > 0 {
+		sb.WriteString("High-churn files (changed often; riskier to modify - keep changes to them minimal and run their tests):\n")
+		for _, h := range b.Hotspots {
+			sb.WriteString(fmt.Sprintf("- %s (%d recent changes", h.Path, h.Changes))
+			if len(h.Owners) > 0 {
+				sb.WriteString(fmt.Sprintf(", owned by %s", strings.Join(h.Owners, " ")))
+			}
+			sb.WriteString(")\n")
+		}
+		sb.WriteString("\n")
+	}
+
 	// Key directories (first 10)
 	if len(b.Structure.Directories) > 0 {
 		sb.WriteString("Key directories:\n")
@@ -0,0 +1,300 @@
+package baseline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxPromptDependencies is the number of dependencies of a package listed in
+// the prompt context
+const maxPromptDependencies = 30
+
+// tomlVersion extracts the version of an inline table dependency such as
+// { version = "1.0", features = ["derive"] }
+var tomlVersion = regexp.MustCompile(`\bversion\s*=\s*"([^"]*)"`)
+
+// tomlQuoted matches the strings of a TOML value
+var tomlQuoted = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+
+// pep508Name extracts the distribution name of a Python requirement
+var pep508Name = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+
+// parseManifest reads the name and direct dependencies of a package from its
+// manifest: go.mod, package.json, Cargo.toml or pyproject.toml. Other
+// manifests, and manifests that cannot be read, leave the package unchanged.
+// Dependencies are recorded as name@version, or as the requirement itself for
+// Python.
+func (s *Scanner) parseManifest(pkg *PackageInfo, path string) {
+	data, err := os.ReadFile(filepath.Join(s.rootPath, path))
+	if err != nil {
+		return
+	}
+	switch strings.ToLower(filepath.Base(path)) {
+	case "go.mod":
+		pkg.Name, pkg.Dependencies = parseGoMod(string(data))
+	case "package.json":
+		pkg.Name, pkg.Dependencies = parsePackageJSON(data)
+	case "cargo.toml":
+		pkg.Name, pkg.Dependencies = parseCargoToml(string(data))
+	case "pyproject.toml":
+		pkg.Name, pkg.Dependencies = parsePyproject(string(data))
+	}
+}
+
+// hasDependencies reports whether any of the packages has dependencies
+func hasDependencies(packages []PackageInfo) bool {
+	for _, p := range packages {
+		if len(p.Dependencies) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// label names a package for listings: its name, or its directory when the
+// manifest has no name, with its type
+func (p PackageInfo) label() string {
+	name := p.Name
+	if name == "" {
+		name = p.Path
+		if name == "" {
+			name = "."
+		}
+	}
+	return fmt.Sprintf("%s (%s)", name, p.Type)
+}
+
+// parseGoMod returns the module path and the direct requirements of a go.mod
+// file; requirements marked "// indirect" are left out
+func parseGoMod(data string) (string, []string) {
+	module := ""
+	var deps []string
+	inRequire := false
+	lines := bufio.NewScanner(strings.NewReader(data))
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		indirect := strings.Contains(line, "// indirect")
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inRequire:
+			if fields[0] == ")" {
+				inRequire = false
+				continue
+			}
+		case fields[0] == "module" && len(fields) > 1:
+			module = strings.Trim(fields[1], `"`)
+			continue
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inRequire = true
+			continue
+		case fields[0] == "require":
+			fields = fields[1:]
+		default:
+			continue
+		}
+		if len(fields) >= 2 && !indirect {
+			deps = append(deps, strings.Trim(fields[0], `"`)+"@"+fields[1])
+		}
+	}
+	return module, deps
+}
+
+// parsePackageJSON returns the name and the dependencies and
+// devDependencies of a package.json file
+func parsePackageJSON(data []byte) (string, []string) {
+	var manifest struct {
+		Name            string            `json:"name"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", nil
+	}
+	var deps []string
+	for _, group := range []map[string]string{manifest.Dependencies, manifest.DevDependencies} {
+		var names []string
+		for name := range group {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, name+"@"+group[name])
+		}
+	}
+	return manifest.Name, deps
+}
+
+// parseCargoToml returns the package name and the dependencies,
+// dev-dependencies and build-dependencies of a Cargo.toml file
+func parseCargoToml(data string) (string, []string) {
+	name := ""
+	var deps []string
+	for _, e := range parseTOML(data) {
+		switch {
+		case e.section == "package" && e.key == "name":
+			name = tomlString(e.value)
+		case isCargoDependencySection(e.section) && e.key != "":
+			deps = append(deps, withVersion(e.key, e.value))
+		case e.key == "" && strings.Contains(e.section, "dependencies."):
+			// [dependencies.serde] tables
+			i := strings.LastIndex(e.section, "dependencies.")
+			if isCargoDependencySection(e.section[:i+len("dependencies")]) {
+				deps = append(deps, e.section[i+len("dependencies."):])
+			}
+		}
+	}
+	return name, deps
+}
+
+// isCargoDependencySection reports whether a Cargo.toml table lists
+// dependencies, including target-specific ones
+func isCargoDependencySection(section string) bool {
+	for _, s := range []string{"dependencies", "dev-dependencies", "build-dependencies"} {
+		if section == s || strings.HasSuffix(section, "."+s) {
+			return !strings.HasPrefix(section, "workspace.")
+		}
+	}
+	return false
+}
+
+// parsePyproject returns the project name and dependencies of a
+// pyproject.toml file, from its [project] table or, for Poetry projects, its
+// [tool.poetry] tables
+func parsePyproject(data string) (string, []string) {
+	name := ""
+	var deps []string
+	for _, e := range parseTOML(data) {
+		switch {
+		case (e.section == "project" || e.section == "tool.poetry") && e.key == "name":
+			if name == "" {
+				name = tomlString(e.value)
+			}
+		case e.section == "project" && e.key == "dependencies":
+			for _, req := range tomlStrings(e.value) {
+				if pep508Name.MatchString(req) {
+					deps = append(deps, strings.Join(strings.Fields(req), ""))
+				}
+			}
+		case (e.section == "tool.poetry.dependencies" || e.section == "tool.poetry.dev-dependencies") &&
+			e.key != "" && e.key != "python":
+			deps = append(deps, withVersion(e.key, e.value))
+		}
+	}
+	return name, deps
+}
+
+// withVersion formats a TOML dependency entry as name@version, or name when
+// it has no version
+func withVersion(name, value string) string {
+	version := tomlString(value)
+	if strings.HasPrefix(value, "{") {
+		version = ""
+		if m := tomlVersion.FindStringSubmatch(value); m != nil {
+			version = m[1]
+		}
+	}
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// tomlEntry is a key of a TOML table, or a table header when key is empty
+type tomlEntry struct {
+	section string
+	key     string
+	value   string
+}
+
+// parseTOML reads the keys of the tables of a TOML document. It is not a full
+// TOML parser: it reads what dependency manifests use, including multi-line
+// arrays, and leaves values unparsed.
+func parseTOML(data string) []tomlEntry {
+	var entries []tomlEntry
+	section := ""
+	var pending *tomlEntry
+	depth := 0
+	lines := bufio.NewScanner(strings.NewReader(data))
+	for lines.Scan() {
+		line := strings.TrimSpace(stripTOMLComment(lines.Text()))
+		if pending != nil {
+			pending.value += " " + line
+			depth += strings.Count(line, "[") - strings.Count(line, "]")
+			if depth <= 0 {
+				entries = append(entries, *pending)
+				pending = nil
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			entries = append(entries, tomlEntry{section: section})
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		entry := tomlEntry{section: section, key: strings.Trim(strings.TrimSpace(key), `"'`), value: strings.TrimSpace(value)}
+		if depth = strings.Count(entry.value, "[") - strings.Count(entry.value, "]"); depth > 0 {
+			pending = &entry
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if pending != nil {
+		entries = append(entries, *pending)
+	}
+	return entries
+}
+
+// stripTOMLComment removes a comment from a TOML line, leaving "#" inside
+// strings alone
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote == 0 && r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlString returns the content of a TOML string value, or "" if value is
+// not a string
+func tomlString(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return ""
+}
+
+// tomlStrings returns the strings of a TOML array value
+func tomlStrings(value string) []string {
+	var values []string
+	for _, m := range tomlQuoted.FindAllString(value, -1) {
+		if s := tomlString(m); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
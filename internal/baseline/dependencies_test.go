@@ -0,0 +1,174 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGoMod(t *testing.T) {
+	data := `module github.com/example/app // the app
+
+go 1.24
+
+require github.com/lib/pq v1.10.9
+
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	golang.org/x/sys v0.40.0 // indirect
+)
+
+replace github.com/lib/pq => ../pq
+`
+	name, deps := parseGoMod(data)
+	if name != "github.com/example/app" {
+		t.Errorf("module = %q", name)
+	}
+	if want := []string{"github.com/lib/pq@v1.10.9", "gopkg.in/yaml.v3@v3.0.1"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("dependencies = %v, want %v", deps, want)
+	}
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	data := []byte(`{
+  "name": "@example/web",
+  "dependencies": {"react": "^18.2.0", "axios": "1.6.0"},
+  "devDependencies": {"vitest": "^1.0.0"}
+}`)
+	name, deps := parsePackageJSON(data)
+	if name != "@example/web" {
+		t.Errorf("name = %q", name)
+	}
+	if want := []string{"axios@1.6.0", "react@^18.2.0", "vitest@^1.0.0"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("dependencies = %v, want %v", deps, want)
+	}
+	if name, deps := parsePackageJSON([]byte("{not json")); name != "" || deps != nil {
+		t.Errorf("parsePackageJSON() of invalid JSON = %q, %v", name, deps)
+	}
+}
+
+func TestParseCargoToml(t *testing.T) {
+	data := `[package]
+name = "example" # the crate
+version = "0.1.0"
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+tokio = "1"
+local = { path = "../local" }
+
+[dependencies.regex]
+version = "1.10"
+
+[dev-dependencies]
+proptest = "1.4"
+
+[target.'cfg(unix)'.dependencies]
+libc = "0.2"
+
+[workspace.dependencies]
+shared = "2"
+`
+	name, deps := parseCargoToml(data)
+	if name != "example" {
+		t.Errorf("name = %q", name)
+	}
+	want := []string{"serde@1.0", "tokio@1", "local", "regex", "proptest@1.4", "libc@0.2"}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("dependencies = %v, want %v", deps, want)
+	}
+}
+
+func TestParsePyproject(t *testing.T) {
+	data := `[project]
+name = "example"
+dependencies = [
+    "requests >= 2.31, < 3",  # HTTP
+    "pydantic[email]>=2",
+    'click',
+]
+
+[project.optional-dependencies]
+dev = ["pytest"]
+`
+	name, deps := parsePyproject(data)
+	if name != "example" {
+		t.Errorf("name = %q", name)
+	}
+	if want := []string{"requests>=2.31,<3", "pydantic[email]>=2", "click"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("dependencies = %v, want %v", deps, want)
+	}
+
+	poetry := `[tool.poetry]
+name = "legacy"
+
+[tool.poetry.dependencies]
+python = "^3.11"
+fastapi = "^0.110"
+sqlalchemy = { version = "^2.0", extras = ["asyncio"] }
+`
+	name, deps = parsePyproject(poetry)
+	if name != "legacy" {
+		t.Errorf("Poetry name = %q", name)
+	}
+	if want := []string{"fastapi@^0.110", "sqlalchemy@^2.0"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("Poetry dependencies = %v, want %v", deps, want)
+	}
+}
+
+func TestScanDependencies(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module example.com/svc\n\ngo 1.24\n\nrequire github.com/lib/pq v1.10.9\n",
+		"main.go":          "package main\n",
+		"web/package.json": `{"dependencies": {"react": "^18.2.0"}}`,
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := NewScanner(dir).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PackageInfo{
+		{Name: "example.com/svc", Path: "", Type: "go", Dependencies: []string{"github.com/lib/pq@v1.10.9"}},
+		{Name: "", Path: "web", Type: "npm", Dependencies: []string{"react@^18.2.0"}},
+	}
+	if !reflect.DeepEqual(b.Structure.Packages, want) {
+		t.Errorf("Packages = %+v, want %+v", b.Structure.Packages, want)
+	}
+
+	ctx := b.BuildPromptContext()
+	for _, line := range []string{
+		"Available libraries (already dependencies - use them instead of adding new ones):",
+		"- example.com/svc (go): github.com/lib/pq@v1.10.9\n",
+		"- web (npm): react@^18.2.0\n",
+	} {
+		if !strings.Contains(ctx, line) {
+			t.Errorf("BuildPromptContext() is missing %q:\n%s", line, ctx)
+		}
+	}
+	if s := b.Summary(); !strings.Contains(s, "Dependencies:\n  - example.com/svc (go): 1 direct\n  - web (npm): 1 direct\n") {
+		t.Errorf("Summary() is missing the dependencies:\n%s", s)
+	}
+}
+
+func TestPromptDependencyLimit(t *testing.T) {
+	var deps []string
+	for i := 0; i < maxPromptDependencies+5; i++ {
+		deps = append(deps, "dep")
+	}
+	b := &Baseline{Structure: CodebaseStructure{Packages: []PackageInfo{{Path: "", Type: "go", Dependencies: deps}}}}
+	if ctx := b.BuildPromptContext(); !strings.Contains(ctx, "- . (go): dep, dep") || !strings.Contains(ctx, " and 5 more\n") {
+		t.Errorf("BuildPromptContext() does not shorten long dependency lists:\n%s", ctx)
+	}
+}
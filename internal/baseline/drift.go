@@ -0,0 +1,101 @@
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Drift lists the files that differ between a saved baseline and a fresh
+// scan of the tree
+type Drift struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Drift compares the baseline with a fresh scan of the same tree. Files are
+// compared by content hash; files recorded without a hash (by baselines made
+// before hashes were stored) are compared by size.
+func (b *Baseline) Drift(current *Baseline) Drift {
+	drift := Drift{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	saved := make(map[string]FileInfo, len(b.Files))
+	for _, f := range b.Files {
+		saved[filepath.ToSlash(f.Path)] = f
+	}
+
+	for _, f := range current.Files {
+		path := filepath.ToSlash(f.Path)
+		old, ok := saved[path]
+		delete(saved, path)
+		switch {
+		case !ok:
+			drift.Added = append(drift.Added, path)
+		case old.Hash != "" && f.Hash != "":
+			if old.Hash != f.Hash {
+				drift.Changed = append(drift.Changed, path)
+			}
+		case old.Size != f.Size:
+			drift.Changed = append(drift.Changed, path)
+		}
+	}
+	for path := range saved {
+		drift.Removed = append(drift.Removed, path)
+	}
+
+	sort.Strings(drift.Added)
+	sort.Strings(drift.Removed)
+	sort.Strings(drift.Changed)
+	return drift
+}
+
+// Total returns the number of drifted files
+func (d Drift) Total() int {
+	return len(d.Added) + len(d.Removed) + len(d.Changed)
+}
+
+// Exclude returns the drift without the given paths and the files below the
+// given directories (paths ending with "/")
+func (d Drift) Exclude(paths []string) Drift {
+	skip := func(path string) bool {
+		for _, p := range paths {
+			p = filepath.ToSlash(p)
+			if strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+				return true
+			}
+			if path == strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "./") {
+				return true
+			}
+		}
+		return false
+	}
+	filter := func(files []string) []string {
+		kept := []string{}
+		for _, f := range files {
+			if !skip(f) {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+	return Drift{Added: filter(d.Added), Removed: filter(d.Removed), Changed: filter(d.Changed)}
+}
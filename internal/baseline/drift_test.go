@@ -0,0 +1,74 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	saved := &Baseline{Files: []FileInfo{
+		{Path: "main.go", Hash: "aaa", Size: 10},
+		{Path: "util.go", Hash: "bbb", Size: 10},
+		{Path: "gone.go", Hash: "ccc", Size: 10},
+		{Path: "legacy.go", Size: 10}, // Saved before hashes were stored
+		{Path: "resized.go", Size: 10},
+	}}
+	current := &Baseline{Files: []FileInfo{
+		{Path: "main.go", Hash: "aaa", Size: 10},
+		{Path: "util.go", Hash: "bbx", Size: 10},
+		{Path: "legacy.go", Hash: "ddd", Size: 10},
+		{Path: "resized.go", Hash: "eee", Size: 12},
+		{Path: "new.go", Hash: "fff", Size: 1},
+		{Path: ".ralph/diffs/run/iteration-001.patch", Hash: "ggg", Size: 1},
+	}}
+
+	drift := saved.Drift(current)
+	want := Drift{
+		Added:   []string{".ralph/diffs/run/iteration-001.patch", "new.go"},
+		Removed: []string{"gone.go"},
+		Changed: []string{"resized.go", "util.go"},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Fatalf("Drift() = %+v, want %+v", drift, want)
+	}
+	if drift.Total() != 5 {
+		t.Errorf("Total() = %d, want 5", drift.Total())
+	}
+
+	want = Drift{Added: []string{"new.go"}, Removed: []string{"gone.go"}, Changed: []string{"resized.go"}}
+	if got := drift.Exclude([]string{".ralph/", "./util.go"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Exclude() = %+v, want %+v", got, want)
+	}
+
+	if got := saved.Drift(saved); got.Total() != 0 {
+		t.Errorf("Drift() of the same baseline = %+v", got)
+	}
+}
+
+func TestScanHashesFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := NewScanner(dir).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Files) != 1 || len(first.Files[0].Hash) != 64 {
+		t.Fatalf("Files = %+v, want one file with a SHA-256 hash", first.Files)
+	}
+
+	// Same size, different content
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package niam\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewScanner(dir).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := first.Drift(second); !reflect.DeepEqual(got.Changed, []string{"main.go"}) {
+		t.Errorf("Drift() = %+v, want main.go changed", got)
+	}
+}
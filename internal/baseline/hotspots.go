@@ -0,0 +1,199 @@
+package baseline
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// HistoryCommits is the number of recent commits read to measure churn
+	HistoryCommits = 1000
+	// MinHotspotChanges is how many of the recent commits must change a file
+	// for it to be a hotspot
+	MinHotspotChanges = 5
+	// maxHotspots is the number of hotspots kept in the baseline
+	maxHotspots = 10
+)
+
+// CodeOwnersFiles are where CODEOWNERS files are looked for, in the order
+// GitHub and GitLab use them
+var CodeOwnersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// Hotspot is a file changed often in the recent history: changes to it are
+// riskier, as other work depends on it
+type Hotspot struct {
+	Path    string   `json:"path"`
+	Changes int      `json:"changes"` // Recent commits changing the file
+	Authors int      `json:"authors"` // Distinct authors of those commits
+	Owners  []string `json:"owners,omitempty"`
+}
+
+// OwnerRule is a CODEOWNERS rule: the owners of the files matching a pattern
+type OwnerRule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+}
+
+// churn counts the recent changes of a file
+type churn struct {
+	changes int
+	authors map[string]bool
+}
+
+// detectHotspots finds the files of the baseline changed most often by the
+// recent commits of the git repository, and their owners. Outside a git
+// repository there are no hotspots.
+func (s *Scanner) detectHotspots(files []FileInfo, owners []OwnerRule) []Hotspot {
+	out, err := exec.Command("git", "-C", s.rootPath, "log", "--no-merges", fmt.Sprintf("--max-count=%d", HistoryCommits),
+		"--name-only", "--relative", "--format=%x00%aN", "--", ".").Output()
+	if err != nil {
+		return nil
+	}
+	return hotspots(parseGitLog(string(out)), files, owners)
+}
+
+// parseGitLog counts the changes and authors of each file in the output of
+// git log --name-only --format=%x00%aN
+func parseGitLog(out string) map[string]*churn {
+	counts := make(map[string]*churn)
+	author := ""
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "\x00") {
+			author = strings.TrimPrefix(line, "\x00")
+			continue
+		}
+		path := strings.TrimSpace(line)
+		if path == "" {
+			continue
+		}
+		c := counts[path]
+		if c == nil {
+			c = &churn{authors: make(map[string]bool)}
+			counts[path] = c
+		}
+		c.changes++
+		c.authors[author] = true
+	}
+	return counts
+}
+
+// hotspots returns the files changed at least MinHotspotChanges times, most
+// changed first
+func hotspots(counts map[string]*churn, files []FileInfo, owners []OwnerRule) []Hotspot {
+	var spots []Hotspot
+	for _, f := range files {
+		path := filepath.ToSlash(f.Path)
+		c := counts[path]
+		if c == nil || c.changes < MinHotspotChanges {
+			continue
+		}
+		spots = append(spots, Hotspot{Path: path, Changes: c.changes, Authors: len(c.authors), Owners: OwnersOf(owners, path)})
+	}
+	sort.SliceStable(spots, func(i, j int) bool {
+		if spots[i].Changes != spots[j].Changes {
+			return spots[i].Changes > spots[j].Changes
+		}
+		return spots[i].Path < spots[j].Path
+	})
+	if len(spots) > maxHotspots {
+		spots = spots[:maxHotspots]
+	}
+	return spots
+}
+
+// loadCodeOwners reads the first CODEOWNERS file found under the root and
+// returns its path, relative to the root, and its rules
+func (s *Scanner) loadCodeOwners() (string, []OwnerRule) {
+	for _, name := range CodeOwnersFiles {
+		f, err := os.Open(filepath.Join(s.rootPath, name))
+		if err != nil {
+			continue
+		}
+		rules := ParseCodeOwners(bufio.NewScanner(f))
+		f.Close()
+		return name, rules
+	}
+	return "", nil
+}
+
+// ParseCodeOwners parses the lines of a CODEOWNERS file. Comments, section
+// headers (GitLab's "[Section]") and patterns without owners are skipped.
+func ParseCodeOwners(lines *bufio.Scanner) []OwnerRule {
+	var rules []OwnerRule
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		rules = append(rules, OwnerRule{Pattern: fields[0], Owners: fields[1:]})
+	}
+	return rules
+}
+
+// OwnersOf returns the owners of a file: those of the last rule matching
+// it, as in CODEOWNERS files
+func OwnersOf(rules []OwnerRule, path string) []string {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	for i := len(rules) - 1; i >= 0; i-- {
+		if matchOwnerPattern(rules[i].Pattern, path) {
+			return rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// matchOwnerPattern reports whether a CODEOWNERS pattern matches a path.
+// Patterns follow gitignore rules: a pattern with a leading or inner slash
+// is relative to the root and others match at any depth, "*" matches within
+// a path segment and "**" across segments, and a pattern matching a
+// directory matches all files below it, except for "dir/*", which only
+// matches the files directly in dir.
+func matchOwnerPattern(pattern, path string) bool {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			re.WriteString("[^/]*")
+		case pattern[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if !strings.HasSuffix(pattern, "/*") {
+		re.WriteString("(?:/.*)?")
+	}
+	re.WriteString("$")
+
+	matched, err := regexp.MatchString(re.String(), path)
+	return err == nil && matched
+}
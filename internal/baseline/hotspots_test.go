@@ -0,0 +1,155 @@
+package baseline
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitLog(t *testing.T) {
+	out := "\x00Alice\n\nmain.go\nutil.go\n\x00Bob\n\nmain.go\n\x00Alice\n\nmain.go\n"
+	counts := parseGitLog(out)
+	if c := counts["main.go"]; c == nil || c.changes != 3 || len(c.authors) != 2 {
+		t.Errorf("main.go churn = %+v, want 3 changes by 2 authors", c)
+	}
+	if c := counts["util.go"]; c == nil || c.changes != 1 || len(c.authors) != 1 {
+		t.Errorf("util.go churn = %+v, want 1 change by 1 author", c)
+	}
+}
+
+func TestHotspots(t *testing.T) {
+	counts := map[string]*churn{
+		"api/server.go": {changes: 9, authors: map[string]bool{"a": true, "b": true}},
+		"api/routes.go": {changes: MinHotspotChanges, authors: map[string]bool{"a": true}},
+		"README.md":     {changes: MinHotspotChanges - 1, authors: map[string]bool{"a": true}},
+		"deleted.go":    {changes: 20, authors: map[string]bool{"a": true}},
+	}
+	files := []FileInfo{{Path: "api/routes.go"}, {Path: "api/server.go"}, {Path: "README.md"}}
+	owners := []OwnerRule{{Pattern: "*", Owners: []string{"@team"}}, {Pattern: "/api/", Owners: []string{"@api-team"}}}
+
+	want := []Hotspot{
+		{Path: "api/server.go", Changes: 9, Authors: 2, Owners: []string{"@api-team"}},
+		{Path: "api/routes.go", Changes: MinHotspotChanges, Authors: 1, Owners: []string{"@api-team"}},
+	}
+	if got := hotspots(counts, files, owners); !reflect.DeepEqual(got, want) {
+		t.Errorf("hotspots() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseCodeOwners(t *testing.T) {
+	content := `# Default owners
+*       @org/everyone
+
+[Backend]
+/api/   @org/backend @alice # Inline comment
+docs/*.md @org/writers
+orphan
+`
+	rules := ParseCodeOwners(bufio.NewScanner(strings.NewReader(content)))
+	want := []OwnerRule{
+		{Pattern: "*", Owners: []string{"@org/everyone"}},
+		{Pattern: "/api/", Owners: []string{"@org/backend", "@alice"}},
+		{Pattern: "docs/*.md", Owners: []string{"@org/writers"}},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("ParseCodeOwners() = %+v, want %+v", rules, want)
+	}
+
+	for path, owners := range map[string][]string{
+		"main.go":             {"@org/everyone"},
+		"api/v1/handler.go":   {"@org/backend", "@alice"},
+		"web/api/client.ts":   {"@org/everyone"}, // /api/ is anchored at the root
+		"docs/guide.md":       {"@org/writers"},
+		"docs/images/arch.md": {"@org/everyone"}, // * does not cross directories
+	} {
+		if got := OwnersOf(rules, path); !reflect.DeepEqual(got, owners) {
+			t.Errorf("OwnersOf(%q) = %v, want %v", path, got, owners)
+		}
+	}
+	if got := OwnersOf(nil, "main.go"); got != nil {
+		t.Errorf("OwnersOf() without rules = %v", got)
+	}
+}
+
+func TestMatchOwnerPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.js", "web/app.js", true},
+		{"*.js", "web/app.ts", false},
+		{"build/", "tools/build/out.txt", true},
+		{"/build/", "tools/build/out.txt", false},
+		{"apps/web", "apps/web/index.ts", true},
+		{"**/logs", "var/app/logs/today.log", true},
+		{"/src/**/test_?.py", "src/a/b/test_x.py", true},
+		{"/src/**/test_?.py", "src/a/b/test_xy.py", false},
+		{"docs/*", "docs/api/index.md", false},
+	}
+	for _, tt := range tests {
+		if got := matchOwnerPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchOwnerPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestScanHotspotsAndOwners(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Dev", "-c", "user.email=dev@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("*.go @go-team\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MinHotspotChanges; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(fmt.Sprintf("package main\n// %d\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", fmt.Sprintf("change %d", i))
+	}
+
+	b, err := NewScanner(dir).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Hotspot{{Path: "main.go", Changes: MinHotspotChanges, Authors: 1, Owners: []string{"@go-team"}}}
+	if !reflect.DeepEqual(b.Hotspots, want) {
+		t.Errorf("Hotspots = %+v, want %+v", b.Hotspots, want)
+	}
+	if b.OwnersFile != ".github/CODEOWNERS" || len(b.CodeOwners) != 1 {
+		t.Errorf("OwnersFile, CodeOwners = %q, %+v", b.OwnersFile, b.CodeOwners)
+	}
+	if s := b.Summary(); !strings.Contains(s, "main.go: 5 changes by 1 author(s), owned by @go-team") || !strings.Contains(s, "Code owners: 1 rule(s) in .github/CODEOWNERS") {
+		t.Errorf("Summary() is missing the hotspots or owners:\n%s", s)
+	}
+	if ctx := b.BuildPromptContext(); !strings.Contains(ctx, "High-churn files") || !strings.Contains(ctx, "- main.go (5 recent changes, owned by @go-team)") {
+		t.Errorf("BuildPromptContext() is missing the hotspots:\n%s", ctx)
+	}
+
+	// Outside a git repository there are no hotspots
+	plain := t.TempDir()
+	if err := os.WriteFile(filepath.Join(plain, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := NewScanner(plain).Scan(); err != nil || len(b.Hotspots) != 0 || b.OwnersFile != "" {
+		t.Errorf("Scan() outside git = %+v, %v", b, err)
+	}
+}
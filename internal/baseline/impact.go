@@ -0,0 +1,292 @@
+package baseline
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultImpactFiles is the number of files in the impact shortlist
+const DefaultImpactFiles = 10
+
+// maxImpactDirectories is the number of directories listed with the shortlist
+const maxImpactDirectories = 5
+
+// impactStopWords are words of feature descriptions that say nothing about
+// where the code is
+var impactStopWords = map[string]bool{
+	"a": true, "able": true, "add": true, "after": true, "all": true, "allow": true, "also": true, "an": true,
+	"and": true, "any": true, "are": true, "as": true, "be": true, "before": true, "but": true, "by": true,
+	"can": true, "create": true, "each": true, "ensure": true, "feature": true, "for": true, "from": true,
+	"has": true, "have": true, "if": true, "implement": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "make": true, "must": true, "new": true, "not": true, "of": true, "on": true, "or": true,
+	"should": true, "so": true, "support": true, "test": true, "tests": true, "that": true, "the": true,
+	"their": true, "them": true, "then": true, "there": true, "they": true, "this": true, "to": true,
+	"update": true, "use": true, "using": true, "via": true, "when": true, "which": true, "will": true,
+	"with": true, "without": true,
+}
+
+// ImpactFile is a file a feature will likely touch
+type ImpactFile struct {
+	Path  string `json:"path"`
+	Score int    `json:"score"` // Strength of the match with the feature
+}
+
+// Impact lists the files a feature will likely touch, predicted by matching
+// the words of its description and steps with the paths of the baseline
+// files
+type Impact struct {
+	Keywords    []string     `json:"keywords"`
+	Files       []ImpactFile `json:"files"`       // Most likely first
+	Directories []string     `json:"directories"` // Directories of the matching files, best match first
+}
+
+// ImpactResult compares the predicted files with the files an iteration
+// changed
+type ImpactResult struct {
+	Predicted   int      `json:"predicted"`   // Files in the shortlist
+	Changed     []string `json:"changed"`     // Predicted files that were changed
+	Unpredicted []string `json:"unpredicted"` // Changed files that were not predicted
+}
+
+// PredictImpact returns the files a feature will likely touch: files whose
+// name or directories match the words of text (the feature's description
+// and steps), and files the text mentions by path. It returns nil if no
+// file matches. At most limit files are kept.
+func (b *Baseline) PredictImpact(text string, limit int) *Impact {
+	mentions, rest := splitMentions(text)
+	keywords := impactKeywords(rest)
+	if len(keywords) == 0 && len(mentions) == 0 {
+		return nil
+	}
+
+	var files []ImpactFile
+	dirScores := make(map[string]int)
+	for _, f := range b.Files {
+		if f.Type == FileTypeAsset || f.Type == FileTypeOther {
+			continue
+		}
+		score := scorePath(f.Path, keywords, mentions)
+		if score == 0 {
+			continue
+		}
+		files = append(files, ImpactFile{Path: f.Path, Score: score})
+		if dir := filepath.Dir(f.Path); dir != "." {
+			dirScores[dir] += score
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Score != files[j].Score {
+			return files[i].Score > files[j].Score
+		}
+		return files[i].Path < files[j].Path
+	})
+	if limit > 0 && len(files) > limit {
+		files = files[:limit]
+	}
+
+	var dirs []string
+	for dir := range dirScores {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirScores[dirs[i]] != dirScores[dirs[j]] {
+			return dirScores[dirs[i]] > dirScores[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	if len(dirs) > maxImpactDirectories {
+		dirs = dirs[:maxImpactDirectories]
+	}
+
+	return &Impact{Keywords: keywords, Files: files, Directories: dirs}
+}
+
+// BuildPromptContext creates the shortlist of likely affected files to
+// inject into prompts, or an empty string if there is none
+func (i *Impact) BuildPromptContext() string {
+	if i == nil || len(i.Files) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n[LIKELY AFFECTED FILES - Predicted from the codebase baseline for the current feature:]\n\n")
+	sb.WriteString("You will probably need to modify these files:\n")
+	for _, f := range i.Files {
+		sb.WriteString(fmt.Sprintf("- %s\n", f.Path))
+	}
+	if len(i.Directories) > 0 {
+		dirs := make([]string, len(i.Directories))
+		for n, d := range i.Directories {
+			dirs[n] = d + "/"
+		}
+		sb.WriteString(fmt.Sprintf("\nRelated directories: %s\n", strings.Join(dirs, ", ")))
+	}
+	sb.WriteString("\nThis list is a guess from file names; look around before relying on it.\n")
+	sb.WriteString("\n[END LIKELY AFFECTED FILES]\n")
+	return sb.String()
+}
+
+// Compare returns which predicted files are among the changed files, and
+// which changed files were not predicted
+func (i *Impact) Compare(changed []string) ImpactResult {
+	result := ImpactResult{Changed: []string{}, Unpredicted: []string{}}
+	predicted := make(map[string]bool)
+	if i != nil {
+		result.Predicted = len(i.Files)
+		for _, f := range i.Files {
+			predicted[filepath.ToSlash(filepath.Clean(f.Path))] = true
+		}
+	}
+	for _, path := range changed {
+		if predicted[filepath.ToSlash(filepath.Clean(path))] {
+			result.Changed = append(result.Changed, path)
+		} else {
+			result.Unpredicted = append(result.Unpredicted, path)
+		}
+	}
+	return result
+}
+
+// scorePath scores how well a file path matches the keywords and path
+// mentions of a feature. A keyword matching the file name counts more than
+// one matching a directory, and an exact match more than a prefix.
+func scorePath(path string, keywords []string, mentions []string) int {
+	slashed := filepath.ToSlash(path)
+	score := 0
+	for _, m := range mentions {
+		if slashed == m || strings.HasSuffix(slashed, "/"+m) || strings.HasPrefix(slashed, strings.TrimSuffix(m, "/")+"/") {
+			score += 10
+		}
+	}
+
+	dir, name := filepath.Split(slashed)
+	nameTokens := pathTokens(strings.TrimSuffix(name, filepath.Ext(name)))
+	dirTokens := pathTokens(dir)
+	for _, k := range keywords {
+		switch {
+		case containsToken(nameTokens, k, true):
+			score += 3
+		case containsToken(nameTokens, k, false):
+			score += 2
+		case containsToken(dirTokens, k, true):
+			score += 2
+		case containsToken(dirTokens, k, false):
+			score++
+		}
+	}
+	return score
+}
+
+// containsToken reports whether tokens contain keyword, or, unless exact is
+// set, a word of at least four letters that keyword starts with or that
+// starts with keyword ("auth" and "authentication")
+func containsToken(tokens []string, keyword string, exact bool) bool {
+	for _, t := range tokens {
+		if t == keyword {
+			return true
+		}
+		if !exact && len(t) >= 4 && len(keyword) >= 4 && (strings.HasPrefix(t, keyword) || strings.HasPrefix(keyword, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// impactKeywords returns the distinct words of text that may name code,
+// stemmed and in order of appearance
+func impactKeywords(text string) []string {
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, word := range splitWords(text) {
+		if len(word) < 3 || impactStopWords[word] {
+			continue
+		}
+		word = stem(word)
+		if !seen[word] {
+			seen[word] = true
+			keywords = append(keywords, word)
+		}
+	}
+	return keywords
+}
+
+// splitMentions returns the words of text that look like file paths (they
+// contain a slash or end with a file extension), and the rest of the text
+func splitMentions(text string) ([]string, string) {
+	var mentions, rest []string
+	for _, field := range strings.Fields(text) {
+		path := strings.Trim(field, "`'\"()[]{},;:!?")
+		path = strings.TrimSuffix(strings.TrimPrefix(path, "./"), ".")
+		ext := filepath.Ext(path)
+		if !strings.Contains(path, "://") && (strings.Contains(path, "/") || (len(ext) > 1 && len(ext) <= 5 && len(path) > len(ext))) {
+			mentions = append(mentions, filepath.ToSlash(path))
+		} else {
+			rest = append(rest, field)
+		}
+	}
+	return mentions, strings.Join(rest, " ")
+}
+
+// pathTokens splits a path into stemmed lowercase words, at separators and
+// camelCase boundaries
+func pathTokens(path string) []string {
+	var tokens []string
+	for _, word := range splitWords(path) {
+		if len(word) >= 3 {
+			tokens = append(tokens, stem(word))
+		}
+	}
+	return tokens
+}
+
+// splitWords splits s into lowercase words at non-alphanumeric characters and
+// camelCase boundaries
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+	}
+	runes := []rune(s)
+	for n, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && n > 0 && (unicode.IsLower(runes[n-1]) ||
+			(n+1 < len(runes) && unicode.IsUpper(runes[n-1]) && unicode.IsLower(runes[n+1]))):
+			flush()
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// stem strips common English plural and verb suffixes, so that "handlers"
+// matches "handler" and "categories" matches "category"
+func stem(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "es") && len(word) > 4 && strings.ContainsAny(word[len(word)-3:len(word)-2], "sxz"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+		return strings.TrimSuffix(word, "s")
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		return strings.TrimSuffix(word, "ing")
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		return strings.TrimSuffix(word, "ed")
+	}
+	return word
+}
@@ -0,0 +1,105 @@
+package baseline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func impactBaseline() *Baseline {
+	var files []FileInfo
+	for _, f := range []struct {
+		path string
+		typ  FileType
+	}{
+		{"cmd/server/main.go", FileTypeSource},
+		{"internal/auth/login.go", FileTypeSource},
+		{"internal/auth/login_test.go", FileTypeTest},
+		{"internal/auth/session.go", FileTypeSource},
+		{"internal/billing/invoice.go", FileTypeSource},
+		{"internal/users/UserRepository.go", FileTypeSource},
+		{"web/static/login.png", FileTypeAsset},
+		{"docs/authentication.md", FileTypeDocs},
+	} {
+		files = append(files, FileInfo{Path: f.path, Type: f.typ})
+	}
+	return &Baseline{Files: files}
+}
+
+func TestPredictImpact(t *testing.T) {
+	b := impactBaseline()
+
+	impact := b.PredictImpact("Add login sessions for users Store the user in internal/users", DefaultImpactFiles)
+	if impact == nil {
+		t.Fatal("PredictImpact() = nil")
+	}
+	if want := []string{"login", "session", "user", "store"}; !reflect.DeepEqual(impact.Keywords, want) {
+		t.Errorf("Keywords = %v, want %v", impact.Keywords, want)
+	}
+	var paths []string
+	for _, f := range impact.Files {
+		paths = append(paths, f.Path)
+	}
+	// The mentioned directory comes first, then matching file names; assets
+	// are left out
+	want := []string{
+		"internal/users/UserRepository.go",
+		"internal/auth/login.go",
+		"internal/auth/login_test.go",
+		"internal/auth/session.go",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Files = %v, want %v", paths, want)
+	}
+	if want := []string{"internal/users", "internal/auth"}; !reflect.DeepEqual(impact.Directories, want) {
+		t.Errorf("Directories = %v, want %v", impact.Directories, want)
+	}
+
+	if got := b.PredictImpact("Add login sessions for users", 2); len(got.Files) != 2 {
+		t.Errorf("PredictImpact() kept %d files, want 2", len(got.Files))
+	}
+	if got := b.PredictImpact("Authentication docs", DefaultImpactFiles); got == nil || got.Files[0].Path != "docs/authentication.md" {
+		t.Errorf("PredictImpact() of a prefix match = %+v", got)
+	}
+	if got := b.PredictImpact("Add support for the new thing", DefaultImpactFiles); got != nil {
+		t.Errorf("PredictImpact() without matches = %+v, want nil", got)
+	}
+}
+
+func TestImpactPromptContextAndCompare(t *testing.T) {
+	impact := impactBaseline().PredictImpact("Invoice PDF export in billing", DefaultImpactFiles)
+	ctx := impact.BuildPromptContext()
+	for _, want := range []string{"You will probably need to modify these files:\n- internal/billing/invoice.go\n", "Related directories: internal/billing/"} {
+		if !strings.Contains(ctx, want) {
+			t.Errorf("BuildPromptContext() is missing %q:\n%s", want, ctx)
+		}
+	}
+
+	result := impact.Compare([]string{"internal/billing/invoice.go", "internal/billing/pdf.go"})
+	want := ImpactResult{Predicted: 1, Changed: []string{"internal/billing/invoice.go"}, Unpredicted: []string{"internal/billing/pdf.go"}}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Compare() = %+v, want %+v", result, want)
+	}
+
+	var none *Impact
+	if none.BuildPromptContext() != "" {
+		t.Error("BuildPromptContext() of no impact is not empty")
+	}
+	if got := none.Compare([]string{"a.go"}); got.Predicted != 0 || len(got.Unpredicted) != 1 {
+		t.Errorf("Compare() of no impact = %+v", got)
+	}
+}
+
+func TestSplitWordsAndStem(t *testing.T) {
+	if got, want := splitWords("UserRepository_v2/HTTPServer-config"), []string{"user", "repository", "v2", "http", "server", "config"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitWords() = %v, want %v", got, want)
+	}
+	for word, want := range map[string]string{
+		"handlers": "handler", "categories": "category", "boxes": "box", "matches": "match",
+		"files": "file", "class": "class", "parsing": "pars", "logged": "logg", "bus": "bus",
+	} {
+		if got := stem(word); got != want {
+			t.Errorf("stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Package changelog turns the completed features of a plan into a changelog,
+// grouped by milestone and category and dated from the progress log.
+package changelog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/internal/progress"
+	"github.com/logimos/ralph/pkg/milestone"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// Changelog formats
+const (
+	// FormatMarkdown lists the features of each milestone by category
+	FormatMarkdown = "markdown"
+	// FormatKeepAChangelog follows https://keepachangelog.com: completed
+	// milestones are releases and the rest is unreleased
+	FormatKeepAChangelog = "keepachangelog"
+)
+
+// Formats lists the supported changelog formats
+var Formats = []string{FormatMarkdown, FormatKeepAChangelog}
+
+// EventType is the type of the progress events recording that a feature was
+// completed ("TESTED: feature #N ...")
+const EventType = "tested"
+
+// dateLayout is how dates are written in changelogs
+const dateLayout = "2006-01-02"
+
+// changeTypes are the Keep a Changelog types of change, in the order they
+// are listed
+var changeTypes = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// Entry is a completed feature
+type Entry struct {
+	ID          int       `json:"id"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Completed   time.Time `json:"completed,omitzero"` // Zero if no completion was recorded
+}
+
+// Section holds the completed features of a milestone
+type Section struct {
+	Milestone string    `json:"milestone,omitempty"` // Empty for features of no milestone
+	Complete  bool      `json:"complete"`            // Whether all features of the milestone are tested
+	Date      time.Time `json:"date,omitzero"`       // Latest completion of its features
+	Entries   []Entry   `json:"entries"`
+}
+
+// Changelog holds the completed features of a plan. Sections of milestones
+// in progress come first in milestone order, then the features of no
+// milestone, then completed milestones, most recent first.
+type Changelog struct {
+	Sections []Section `json:"sections"`
+}
+
+// ValidFormat reports whether format is a supported changelog format
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// CompletionDates returns when each feature was completed: the time of its
+// latest completion event, or else the end of the latest run that completed
+// it
+func CompletionDates(events []progress.Event, runs []*history.Run) map[int]time.Time {
+	dates := make(map[int]time.Time)
+	for _, e := range events {
+		if e.Type == EventType && e.FeatureID > 0 && e.Time.After(dates[e.FeatureID]) {
+			dates[e.FeatureID] = e.Time
+		}
+	}
+	fromRuns := make(map[int]time.Time)
+	for _, r := range runs {
+		end := r.EndTime
+		if end.IsZero() {
+			end = r.StartTime
+		}
+		for _, id := range r.FeaturesCompleted {
+			if end.After(fromRuns[id]) {
+				fromRuns[id] = end
+			}
+		}
+	}
+	for id, t := range fromRuns {
+		if _, ok := dates[id]; !ok {
+			dates[id] = t
+		}
+	}
+	return dates
+}
+
+// New builds the changelog of the tested features of plans, grouped by the
+// milestones of progress (as returned by milestone.Manager.CalculateAllProgress)
+func New(plans []plan.Plan, milestones []*milestone.Progress, dates map[int]time.Time) *Changelog {
+	c := &Changelog{Sections: []Section{}}
+	assigned := make(map[int]bool)
+	var inProgress, complete []Section
+	for _, p := range milestones {
+		s := Section{Milestone: p.Milestone.Name, Complete: p.Status == milestone.StatusComplete}
+		for _, f := range p.Features {
+			assigned[f.ID] = true
+			if f.Tested && !f.IsQuestion() {
+				s.Entries = append(s.Entries, newEntry(f, dates))
+			}
+		}
+		if len(s.Entries) == 0 {
+			continue
+		}
+		s.finish()
+		if s.Complete {
+			complete = append(complete, s)
+		} else {
+			inProgress = append(inProgress, s)
+		}
+	}
+
+	other := Section{}
+	for _, f := range plans {
+		if f.Tested && !f.IsQuestion() && !assigned[f.ID] {
+			other.Entries = append(other.Entries, newEntry(f, dates))
+		}
+	}
+	if len(other.Entries) > 0 {
+		other.finish()
+		inProgress = append(inProgress, other)
+	}
+
+	sort.SliceStable(complete, func(i, j int) bool {
+		return complete[i].Date.After(complete[j].Date)
+	})
+	c.Sections = append(append(c.Sections, inProgress...), complete...)
+	return c
+}
+
+// newEntry returns the changelog entry of a feature
+func newEntry(f plan.Plan, dates map[int]time.Time) Entry {
+	category := f.Category
+	if category == "" {
+		category = "feature"
+	}
+	return Entry{ID: f.ID, Description: f.Description, Category: category, Completed: dates[f.ID]}
+}
+
+// finish sorts the entries of the section by completion and sets its date
+func (s *Section) finish() {
+	sort.SliceStable(s.Entries, func(i, j int) bool {
+		a, b := s.Entries[i], s.Entries[j]
+		if !a.Completed.Equal(b.Completed) {
+			return a.Completed.Before(b.Completed)
+		}
+		return a.ID < b.ID
+	})
+	for _, e := range s.Entries {
+		if e.Completed.After(s.Date) {
+			s.Date = e.Completed
+		}
+	}
+}
+
+// Render returns the changelog in the given format
+func (c *Changelog) Render(format string) (string, error) {
+	switch format {
+	case FormatMarkdown, "":
+		return c.markdown(), nil
+	case FormatKeepAChangelog:
+		return c.keepAChangelog(), nil
+	default:
+		return "", fmt.Errorf("unknown changelog format %q (valid: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// markdown renders each milestone as a section with a subsection per
+// category and the completion date of each feature
+func (c *Changelog) markdown() string {
+	var sb strings.Builder
+	if len(c.Sections) == 0 {
+		return "## Changelog\n\nNo features completed yet.\n"
+	}
+	for i, s := range c.Sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		switch {
+		case s.Milestone == "":
+			sb.WriteString("## Other features\n")
+		case !s.Complete:
+			fmt.Fprintf(&sb, "## %s (in progress)\n", s.Milestone)
+		case s.Date.IsZero():
+			fmt.Fprintf(&sb, "## %s\n", s.Milestone)
+		default:
+			fmt.Fprintf(&sb, "## %s - %s\n", s.Milestone, s.Date.Format(dateLayout))
+		}
+		for _, g := range groupEntries(s.Entries, func(e Entry) string { return categoryTitle(e.Category) }, nil) {
+			fmt.Fprintf(&sb, "\n### %s\n\n", g.name)
+			for _, e := range g.entries {
+				if e.Completed.IsZero() {
+					fmt.Fprintf(&sb, "- %s (#%d)\n", e.Description, e.ID)
+				} else {
+					fmt.Fprintf(&sb, "- %s (#%d, %s)\n", e.Description, e.ID, e.Completed.Format(dateLayout))
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// keepAChangelog renders completed milestones as releases, and the features
+// of the other sections as unreleased, grouped by type of change
+func (c *Changelog) keepAChangelog() string {
+	var sb strings.Builder
+	sb.WriteString("# Changelog\n\n")
+	sb.WriteString("All notable changes to this project will be documented in this file.\n\n")
+	sb.WriteString("The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).\n")
+
+	var unreleased []Entry
+	var releases []Section
+	for _, s := range c.Sections {
+		if s.Complete {
+			releases = append(releases, s)
+		} else {
+			unreleased = append(unreleased, s.Entries...)
+		}
+	}
+
+	sb.WriteString("\n## [Unreleased]\n")
+	writeChanges(&sb, unreleased)
+	for _, s := range releases {
+		if s.Date.IsZero() {
+			fmt.Fprintf(&sb, "\n## [%s]\n", s.Milestone)
+		} else {
+			fmt.Fprintf(&sb, "\n## [%s] - %s\n", s.Milestone, s.Date.Format(dateLayout))
+		}
+		writeChanges(&sb, s.Entries)
+	}
+	return sb.String()
+}
+
+// writeChanges writes entries grouped by type of change
+func writeChanges(sb *strings.Builder, entries []Entry) {
+	for _, g := range groupEntries(entries, func(e Entry) string { return ChangeType(e.Category) }, changeTypes) {
+		fmt.Fprintf(sb, "\n### %s\n\n", g.name)
+		for _, e := range g.entries {
+			fmt.Fprintf(sb, "- %s (#%d)\n", e.Description, e.ID)
+		}
+	}
+}
+
+// entryGroup is entries with the same category or type of change
+type entryGroup struct {
+	name    string
+	entries []Entry
+}
+
+// groupEntries groups entries by key, in the order of keys if given and in
+// alphabetical order otherwise
+func groupEntries(entries []Entry, key func(Entry) string, keys []string) []entryGroup {
+	grouped := make(map[string][]Entry)
+	for _, e := range entries {
+		grouped[key(e)] = append(grouped[key(e)], e)
+	}
+	if keys == nil {
+		for k := range grouped {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	var groups []entryGroup
+	for _, k := range keys {
+		if len(grouped[k]) > 0 {
+			groups = append(groups, entryGroup{name: k, entries: grouped[k]})
+		}
+	}
+	return groups
+}
+
+// ChangeType returns the Keep a Changelog type of change of a feature
+// category: fixes, security, deprecations, removals and refactorings are
+// recognized by name, and everything else counts as added
+func ChangeType(category string) string {
+	c := strings.ToLower(category)
+	switch {
+	case strings.Contains(c, "security"):
+		return "Security"
+	case strings.Contains(c, "fix") || strings.Contains(c, "bug"):
+		return "Fixed"
+	case strings.Contains(c, "deprecat"):
+		return "Deprecated"
+	case strings.Contains(c, "remov"):
+		return "Removed"
+	case strings.Contains(c, "refactor") || strings.Contains(c, "change") || strings.Contains(c, "improve") ||
+		strings.Contains(c, "perf") || strings.Contains(c, "chore") || strings.Contains(c, "update"):
+		return "Changed"
+	default:
+		return "Added"
+	}
+}
+
+// categoryTitle returns a category as a heading ("user-preferences" ->
+// "User preferences")
+func categoryTitle(category string) string {
+	title := strings.ReplaceAll(strings.ReplaceAll(category, "-", " "), "_", " ")
+	if title == "" {
+		return "Feature"
+	}
+	return strings.ToUpper(title[:1]) + title[1:]
+}
@@ -0,0 +1,145 @@
+package changelog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/internal/progress"
+	"github.com/logimos/ralph/pkg/milestone"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestCompletionDates(t *testing.T) {
+	events := []progress.Event{
+		{Time: day(1), Type: EventType, FeatureID: 1},
+		{Time: day(3), Type: EventType, FeatureID: 1}, // Tested again later
+		{Time: day(4), Type: "edit", FeatureID: 2},
+	}
+	runs := []*history.Run{
+		{StartTime: day(1), EndTime: day(2), FeaturesCompleted: []int{1, 2}},
+		{StartTime: day(5), FeaturesCompleted: []int{3}}, // Not finished
+	}
+	want := map[int]time.Time{1: day(3), 2: day(2), 3: day(5)}
+	if got := CompletionDates(events, runs); !reflect.DeepEqual(got, want) {
+		t.Errorf("CompletionDates() = %v, want %v", got, want)
+	}
+}
+
+// testChangelog has a completed milestone, one in progress and a feature of
+// no milestone
+func testChangelog() *Changelog {
+	plans := []plan.Plan{
+		{ID: 1, Category: "feature", Description: "Login", Milestone: "Alpha", Tested: true},
+		{ID: 2, Category: "fix", Description: "Session timeout", Milestone: "Alpha", Tested: true},
+		{ID: 3, Category: "feature", Description: "Search", Milestone: "Beta", Tested: true},
+		{ID: 4, Category: "feature", Description: "Filters", Milestone: "Beta"},
+		{ID: 5, Category: "user-preferences", Description: "Dark mode", Tested: true},
+		{ID: 6, Description: "Which database?", Type: plan.TypeQuestion, Tested: true},
+	}
+	dates := map[int]time.Time{1: day(2), 2: day(1), 3: day(5)}
+	return New(plans, milestone.NewManager(plans).CalculateAllProgress(), dates)
+}
+
+func TestNew(t *testing.T) {
+	c := testChangelog()
+	if len(c.Sections) != 3 {
+		t.Fatalf("Sections = %+v, want Beta, other and Alpha", c.Sections)
+	}
+	beta, other, alpha := c.Sections[0], c.Sections[1], c.Sections[2]
+	if beta.Milestone != "Beta" || beta.Complete || len(beta.Entries) != 1 || !beta.Date.Equal(day(5)) {
+		t.Errorf("Beta = %+v", beta)
+	}
+	if other.Milestone != "" || len(other.Entries) != 1 || other.Entries[0].Category != "user-preferences" {
+		t.Errorf("other = %+v", other)
+	}
+	if alpha.Milestone != "Alpha" || !alpha.Complete || !alpha.Date.Equal(day(2)) {
+		t.Errorf("Alpha = %+v", alpha)
+	}
+	// Entries are in completion order
+	if len(alpha.Entries) != 2 || alpha.Entries[0].ID != 2 || alpha.Entries[1].ID != 1 {
+		t.Errorf("Alpha entries = %+v", alpha.Entries)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	got, err := testChangelog().Render(FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `## Beta (in progress)
+
+### Feature
+
+- Search (#3, 2026-10-05)
+
+## Other features
+
+### User preferences
+
+- Dark mode (#5)
+
+## Alpha - 2026-10-02
+
+### Feature
+
+- Login (#1, 2026-10-02)
+
+### Fix
+
+- Session timeout (#2, 2026-10-01)
+`
+	if got != want {
+		t.Errorf("Render(markdown) =\n%s\nwant\n%s", got, want)
+	}
+
+	if got, _ := New(nil, nil, nil).Render(FormatMarkdown); !strings.Contains(got, "No features completed yet.") {
+		t.Errorf("Render() of no features = %q", got)
+	}
+}
+
+func TestRenderKeepAChangelog(t *testing.T) {
+	got, err := testChangelog().Render(FormatKeepAChangelog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Changelog\n",
+		"[Keep a Changelog](https://keepachangelog.com/en/1.1.0/)",
+		"## [Unreleased]\n\n### Added\n\n- Search (#3)\n- Dark mode (#5)\n",
+		"## [Alpha] - 2026-10-02\n\n### Added\n\n- Login (#1)\n\n### Fixed\n\n- Session timeout (#2)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render(keepachangelog) is missing %q:\n%s", want, got)
+		}
+	}
+
+	if _, err := testChangelog().Render("rst"); err == nil {
+		t.Error("Render() of an unknown format returned no error")
+	}
+}
+
+func TestChangeType(t *testing.T) {
+	tests := map[string]string{
+		"feature":     "Added",
+		"bugfix":      "Fixed",
+		"Fix":         "Fixed",
+		"security":    "Security",
+		"refactor":    "Changed",
+		"performance": "Changed",
+		"removal":     "Removed",
+		"deprecation": "Deprecated",
+		"ui":          "Added",
+	}
+	for category, want := range tests {
+		if got := ChangeType(category); got != want {
+			t.Errorf("ChangeType(%q) = %q, want %q", category, got, want)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+// Package checkpoint provides named snapshots of Ralph's state (plan, progress,
+// memory, nudges, ...) together with the git state of the repository, so a run
+// can be rolled back to a known point such as "before the refactor".
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/recovery"
+)
+
+const (
+	// DefaultCheckpointDir is the default directory where checkpoints are stored
+	DefaultCheckpointDir = ".ralph/checkpoints"
+
+	// metadataFile is the name of the metadata file inside each checkpoint directory
+	metadataFile = "checkpoint.json"
+	// filesDir is the subdirectory holding copies of the state files
+	filesDir = "files"
+	// refPrefix is the git ref namespace used to keep checkpoint commits reachable
+	refPrefix = "refs/ralph/checkpoints/"
+	// idTimeFormat is the timestamp layout used when generating checkpoint IDs
+	idTimeFormat = "20060102-150405"
+)
+
+// ReservedLabels are the words that cannot be used as checkpoint labels:
+// the checkpoint subcommands and the aliases of Find
+var ReservedLabels = []string{"list", "restore", "delete", "latest", "last"}
+
+// Checkpoint describes a saved snapshot
+type Checkpoint struct {
+	ID          string    `json:"id"`
+	Label       string    `json:"label"`
+	CreatedAt   time.Time `json:"created_at"`
+	Source      string    `json:"source,omitempty"`       // What created it: cli, nudge, restore
+	Iteration   int       `json:"iteration,omitempty"`    // Iteration number when created mid-run
+	GitHead     string    `json:"git_head,omitempty"`     // Commit checked out when the checkpoint was taken
+	GitSnapshot bool      `json:"git_snapshot,omitempty"` // The checkpoint ref holds the working tree, tracked and untracked files
+	Uncommitted bool      `json:"uncommitted,omitempty"`  // The working tree differed from GitHead
+	Exclude     []string  `json:"exclude,omitempty"`      // Paths left out of the working tree snapshot
+	GitStash    string    `json:"git_stash,omitempty"`    // Stash commit of uncommitted tracked changes (checkpoints of older versions)
+	Files       []string  `json:"files"`                  // State files captured
+	Missing     []string  `json:"missing,omitempty"`      // State files that did not exist
+}
+
+// HasGit reports whether the checkpoint captured git state
+func (c *Checkpoint) HasGit() bool {
+	return c.GitHead != "" || c.GitSnapshot
+}
+
+// Options controls how a checkpoint is created
+type Options struct {
+	Source    string
+	Iteration int
+	Exclude   []string // Paths left out of the working tree snapshot and its restore, e.g. Ralph's own state directories
+}
+
+// Store manages checkpoints on disk
+type Store struct {
+	dir string
+}
+
+// NewStore creates a checkpoint store rooted at dir
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = DefaultCheckpointDir
+	}
+	return &Store{dir: dir}
+}
+
+// Dir returns the checkpoint directory
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Create snapshots the given state files and the repository's git state. The
+// label cannot be one of ReservedLabels.
+func (s *Store) Create(label string, files []string, opts Options) (*Checkpoint, error) {
+	for _, reserved := range ReservedLabels {
+		if strings.EqualFold(strings.TrimSpace(label), reserved) {
+			return nil, fmt.Errorf("%q cannot be used as a checkpoint label", label)
+		}
+	}
+
+	now := time.Now()
+	id := s.uniqueID("cp-" + now.Format(idTimeFormat))
+
+	cp := &Checkpoint{
+		ID:        id,
+		Label:     label,
+		CreatedAt: now,
+		Source:    opts.Source,
+		Iteration: opts.Iteration,
+		Files:     []string{},
+	}
+
+	cpDir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(filepath.Join(cpDir, filesDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	for _, file := range dedupe(files) {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			cp.Missing = append(cp.Missing, file)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if err := os.WriteFile(filepath.Join(cpDir, filesDir, storedName(len(cp.Files), file)), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to save %s: %w", file, err)
+		}
+		cp.Files = append(cp.Files, file)
+	}
+
+	// The snapshot records tracked and untracked files without touching the
+	// working tree; its ref keeps it from being garbage collected
+	cp.Exclude = append(dedupe(opts.Exclude), s.dir)
+	if snap, err := recovery.TakeSnapshot(cp.Exclude...); err == nil {
+		if err := snap.Save(refPrefix+id, "ralph checkpoint: "+label); err != nil {
+			return nil, err
+		}
+		cp.GitSnapshot = true
+		cp.GitHead, _ = git("rev-parse", "--verify", "-q", "HEAD")
+		tree, _ := git("rev-parse", refPrefix+id+"^{tree}")
+		headTree, _ := git("rev-parse", "--verify", "-q", "HEAD^{tree}")
+		cp.Uncommitted = tree != headTree
+	}
+
+	if err := s.writeMetadata(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// List returns all checkpoints sorted by creation time (oldest first)
+func (s *Store) List() ([]*Checkpoint, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []*Checkpoint{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint directory: %w", err)
+	}
+
+	var cps []*Checkpoint
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name(), metadataFile))
+		if err != nil {
+			continue
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			continue
+		}
+		cps = append(cps, &cp)
+	}
+
+	sort.Slice(cps, func(i, j int) bool {
+		return cps[i].CreatedAt.Before(cps[j].CreatedAt)
+	})
+	return cps, nil
+}
+
+// Find locates a checkpoint by ID, label (most recent wins), unique ID prefix, or "latest"
+func (s *Store) Find(ref string) (*Checkpoint, error) {
+	cps, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(cps) == 0 {
+		return nil, fmt.Errorf("no checkpoints found in %s", s.dir)
+	}
+
+	if ref == "latest" || ref == "last" {
+		return cps[len(cps)-1], nil
+	}
+
+	for _, cp := range cps {
+		if cp.ID == ref {
+			return cp, nil
+		}
+	}
+
+	for i := len(cps) - 1; i >= 0; i-- {
+		if cps[i].Label == ref {
+			return cps[i], nil
+		}
+	}
+
+	var matches []*Checkpoint
+	for _, cp := range cps {
+		if strings.HasPrefix(cp.ID, ref) {
+			matches = append(matches, cp)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("checkpoint not found: %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("checkpoint reference %q is ambiguous (%d matches)", ref, len(matches))
+	}
+}
+
+// Restore rolls the repository and state files back to the checkpoint. HEAD
+// is reset to the checkpoint's commit and the working tree to its contents:
+// changed and deleted files are restored, including untracked ones, and files
+// created since are removed (ignored files are left alone). Then the captured
+// state files are written back. State files that did not exist at checkpoint
+// time are removed.
+//
+// Checkpoints of older versions only recorded tracked files: restoring them
+// leaves untracked files as they are.
+func (s *Store) Restore(cp *Checkpoint) error {
+	if cp.GitSnapshot {
+		snap, err := recovery.LoadSnapshot(refPrefix+cp.ID, cp.Exclude...)
+		if err != nil {
+			return err
+		}
+		if err := snap.Rollback(); err != nil {
+			return fmt.Errorf("failed to restore the working tree: %w", err)
+		}
+	} else if cp.HasGit() {
+		if _, err := git("reset", "--hard", cp.GitHead); err != nil {
+			return fmt.Errorf("failed to reset to %s: %w", shortSHA(cp.GitHead), err)
+		}
+		if cp.GitStash != "" {
+			if _, err := git("stash", "apply", cp.GitStash); err != nil {
+				return fmt.Errorf("failed to re-apply uncommitted changes from %s: %w", shortSHA(cp.GitStash), err)
+			}
+		}
+	}
+
+	cpDir := filepath.Join(s.dir, cp.ID)
+	for i, file := range cp.Files {
+		data, err := os.ReadFile(filepath.Join(cpDir, filesDir, storedName(i, file)))
+		if err != nil {
+			return fmt.Errorf("checkpoint copy of %s is missing: %w", file, err)
+		}
+		if dir := filepath.Dir(file); dir != "." {
+			os.MkdirAll(dir, 0755)
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", file, err)
+		}
+	}
+	for _, file := range cp.Missing {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// Delete removes a checkpoint and its git ref
+func (s *Store) Delete(cp *Checkpoint) error {
+	git("update-ref", "-d", refPrefix+cp.ID)
+	if err := os.RemoveAll(filepath.Join(s.dir, cp.ID)); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// writeMetadata saves the checkpoint metadata file
+func (s *Store) writeMetadata(cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, cp.ID, metadataFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// uniqueID appends a numeric suffix if a checkpoint with the same ID already exists
+func (s *Store) uniqueID(base string) string {
+	id := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(s.dir, id)); os.IsNotExist(err) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// FormatList returns a human-readable table of checkpoints
+func FormatList(cps []*Checkpoint) string {
+	if len(cps) == 0 {
+		return "No checkpoints"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-24s %-19s %-10s %-9s %s\n", "ID", "Created", "Git", "Source", "Label"))
+	for _, cp := range cps {
+		gitRef := "-"
+		if cp.HasGit() {
+			gitRef = shortSHA(cp.GitHead)
+			if cp.GitStash != "" || cp.Uncommitted {
+				gitRef += "*"
+			}
+		}
+		source := cp.Source
+		if cp.Iteration > 0 {
+			source = fmt.Sprintf("%s@%d", source, cp.Iteration)
+		}
+		sb.WriteString(fmt.Sprintf("%-24s %-19s %-10s %-9s %s\n",
+			cp.ID, cp.CreatedAt.Format("2006-01-02 15:04:05"), gitRef, source, cp.Label))
+	}
+	sb.WriteString("\n* = includes uncommitted changes")
+	return sb.String()
+}
+
+// storedName returns the file name used for the i-th captured state file
+func storedName(i int, file string) string {
+	return fmt.Sprintf("%02d-%s", i, filepath.Base(file))
+}
+
+// dedupe removes empty and duplicate paths while keeping order
+func dedupe(files []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, f := range files {
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		out = append(out, f)
+	}
+	return out
+}
+
+// shortSHA abbreviates a commit hash for display
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// git runs a git command in the current directory and returns trimmed stdout
+func git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,209 @@
+package checkpoint
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupRepo creates a git repository in a temp directory and changes into it
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	for _, args := range [][]string{{"init", "-q"}, {"commit", "-q", "--allow-empty", "-m", "initial"}} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func gitRun(t *testing.T, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestNewStore(t *testing.T) {
+	if NewStore("").Dir() != DefaultCheckpointDir {
+		t.Errorf("expected default dir %q", DefaultCheckpointDir)
+	}
+	if NewStore("custom").Dir() != "custom" {
+		t.Error("expected custom dir")
+	}
+}
+
+func TestStore_CreateListFind(t *testing.T) {
+	t.Chdir(t.TempDir()) // Not a git repository
+	store := NewStore(".ralph/checkpoints")
+
+	os.WriteFile("plan.json", []byte(`[{"id":1}]`), 0644)
+
+	first, err := store.Create("before refactor", []string{"plan.json", "progress.txt", "plan.json"}, Options{Source: "cli"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(first.Files) != 1 || len(first.Missing) != 1 {
+		t.Errorf("expected 1 captured and 1 missing file, got %v / %v", first.Files, first.Missing)
+	}
+	if first.HasGit() {
+		t.Error("checkpoint outside a git repository should not have git state")
+	}
+
+	second, err := store.Create("after refactor", []string{"plan.json"}, Options{Source: "nudge", Iteration: 3})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Error("checkpoint IDs should be unique")
+	}
+
+	cps, err := store.List()
+	if err != nil || len(cps) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d (%v)", len(cps), err)
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"latest", second.ID, false},
+		{first.ID, first.ID, false},
+		{"before refactor", first.ID, false},
+		{"cp-", "", true}, // ambiguous
+		{"nope", "", true},
+	}
+	for _, tt := range tests {
+		got, err := store.Find(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Find(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.ID != tt.want {
+			t.Errorf("Find(%q) = %s, want %s", tt.ref, got.ID, tt.want)
+		}
+	}
+
+	list := FormatList(cps)
+	if !strings.Contains(list, "before refactor") || !strings.Contains(list, "nudge@3") {
+		t.Errorf("unexpected list output:\n%s", list)
+	}
+	if FormatList(nil) != "No checkpoints" {
+		t.Error("expected empty list message")
+	}
+}
+
+func TestStore_RestoreStateFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store := NewStore(".ralph/checkpoints")
+
+	os.WriteFile("plan.json", []byte("original"), 0644)
+	cp, err := store.Create("snap", []string{"plan.json", "nudges.json"}, Options{})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	os.WriteFile("plan.json", []byte("changed"), 0644)
+	os.WriteFile("nudges.json", []byte("{}"), 0644)
+
+	if err := store.Restore(cp); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := readFile(t, "plan.json"); got != "original" {
+		t.Errorf("plan.json = %q, want original", got)
+	}
+	if _, err := os.Stat("nudges.json"); !os.IsNotExist(err) {
+		t.Error("files missing at checkpoint time should be removed on restore")
+	}
+}
+
+func TestStore_RestoreGitState(t *testing.T) {
+	setupRepo(t)
+	store := NewStore(filepath.Join(t.TempDir(), "checkpoints"))
+
+	os.WriteFile("main.go", []byte("v1"), 0644)
+	gitRun(t, "add", "main.go")
+	gitRun(t, "commit", "-q", "-m", "v1")
+
+	// Uncommitted work at checkpoint time, including a new file
+	os.WriteFile("main.go", []byte("v1-wip"), 0644)
+	os.WriteFile("notes.md", []byte("draft"), 0644)
+
+	cp, err := store.Create("before refactor", nil, Options{Source: "cli"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !cp.HasGit() || !cp.GitSnapshot || !cp.Uncommitted {
+		t.Fatalf("expected git head and working tree snapshot to be recorded: %+v", cp)
+	}
+	if got := readFile(t, "main.go"); got != "v1-wip" {
+		t.Errorf("creating a checkpoint must not touch the working tree, got %q", got)
+	}
+
+	// The agent commits a refactor, deletes the untracked file and creates another
+	os.WriteFile("main.go", []byte("v2"), 0644)
+	gitRun(t, "commit", "-q", "-am", "refactor")
+	os.Remove("notes.md")
+	os.WriteFile("scratch.go", []byte("package main"), 0644)
+
+	if err := store.Restore(cp); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := readFile(t, "main.go"); got != "v1-wip" {
+		t.Errorf("main.go = %q, want v1-wip", got)
+	}
+	if got := readFile(t, "notes.md"); got != "draft" {
+		t.Errorf("untracked notes.md = %q, want draft", got)
+	}
+	if _, err := os.Stat("scratch.go"); !os.IsNotExist(err) {
+		t.Error("file created after the checkpoint should be removed")
+	}
+
+	out, _ := exec.Command("git", "show-ref", refPrefix+cp.ID).Output()
+	if len(out) == 0 {
+		t.Error("checkpoint ref should exist")
+	}
+	if err := store.Delete(cp); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	out, _ = exec.Command("git", "show-ref", refPrefix+cp.ID).Output()
+	if len(out) != 0 {
+		t.Error("checkpoint ref should be removed on delete")
+	}
+}
+
+func TestStore_CreateRejectsReservedLabels(t *testing.T) {
+	t.Chdir(t.TempDir())
+	store := NewStore("checkpoints")
+	for _, label := range []string{"list", "restore", "delete", "latest", "Last"} {
+		if _, err := store.Create(label, nil, Options{}); err == nil {
+			t.Errorf("Create(%q) should fail", label)
+		}
+	}
+	if _, err := store.Create("list of fixes", nil, Options{}); err != nil {
+		t.Errorf("Create() of a label starting with a subcommand failed: %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 // Package config provides configuration management for Ralph.
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 const (
 	// DefaultPlanFile is the default path for the plan file
 	DefaultPlanFile = "plan.json"
@@ -12,6 +17,16 @@ const (
 	DefaultMaxRetries = 3
 	// DefaultRecoveryStrategy is the default recovery strategy
 	DefaultRecoveryStrategy = "retry"
+	// DefaultRetryBackoffMultiplier is the default factor applied to the retry delay after each retry
+	DefaultRetryBackoffMultiplier = 2.0
+	// DefaultRetryBackoffMax is the default upper bound of the retry delay
+	DefaultRetryBackoffMax = "5m"
+	// DefaultRetryJitter is the default fraction of the retry delay randomly added or removed
+	DefaultRetryJitter = 0.2
+	// DefaultRateLimitBackoff is the default wait before retrying a rate-limited agent
+	DefaultRateLimitBackoff = "30s"
+	// DefaultRateLimitRetries is the default number of times a rate-limited agent is retried
+	DefaultRateLimitRetries = 5
 	// DefaultLogLevel is the default logging level
 	DefaultLogLevel = "info"
 	// DefaultMemoryFile is the default path for the memory file
@@ -36,6 +51,36 @@ const (
 	DefaultParallelAgents = 2
 	// DefaultBaselineFile is the default path for the baseline file
 	DefaultBaselineFile = "baseline.json"
+	// DefaultContextMaxAge is the default age in days after which the baseline and memories count as stale
+	DefaultContextMaxAge = 14
+	// DefaultContextMaxChanges is the default number of files changed after which the baseline and memories count as stale
+	DefaultContextMaxChanges = 50
+	// DefaultHistoryDir is the default directory for run history records
+	DefaultHistoryDir = ".ralph/history"
+	// DefaultDiffDir is the default directory for per-iteration patches
+	DefaultDiffDir = ".ralph/diffs"
+	// DefaultChangelogFormat is the default format of -changelog
+	DefaultChangelogFormat = "markdown"
+	// DefaultTelemetryFile is the default path of the local telemetry aggregate
+	DefaultTelemetryFile = ".ralph/telemetry.json"
+	// DefaultFlakyFile is the default path of the flaky test store
+	DefaultFlakyFile = ".ralph/flaky.json"
+	// DefaultReportDir is the default directory for validation reports
+	DefaultReportDir = ".ralph/reports"
+	// DefaultTranscriptDir is the default directory for prompt/response transcripts
+	DefaultTranscriptDir = ".ralph/transcripts"
+	// DefaultAgentBackend is the default agent backend (shell out to the agent CLI)
+	DefaultAgentBackend = "cli"
+	// DefaultMaxPromptSteps is the default number of steps of a feature included in prompts
+	DefaultMaxPromptSteps = 25
+	// DefaultCheckpointDir is the default directory for named checkpoints
+	DefaultCheckpointDir = ".ralph/checkpoints"
+	// DefaultPlanVersionDir is the default directory for plan backups
+	DefaultPlanVersionDir = ".ralph/plan-versions"
+	// DefaultExperimentSplit is the default way iterations are split between experiment variants
+	DefaultExperimentSplit = "alternate"
+	// DefaultHealthCheckPrompt is the default prompt of the agent health check
+	DefaultHealthCheckPrompt = "Reply with OK and nothing else."
 )
 
 // Config holds the application configuration
@@ -46,20 +91,53 @@ type Config struct {
 	AgentCmd         string
 	TypeCheckCmd     string
 	TestCmd          string
+	Lint             bool   // Run the build system's lint command after each iteration
+	LintCmd          string // Lint command (overrides the build-system preset; enables the lint step)
 	BuildSystem      string
 	Verbose          bool
 	ShowVersion      bool
-	ListAll          bool // List all features (tested and untested)
-	ListStatus       bool // Deprecated: Use ListAll instead
+	ListAll          bool   // List all features (tested and untested)
+	ShowStatus       bool   // Show the status dashboard
+	ShowFeature      int    // Print the full record of a feature by ID
+	MarkTested       int    // Mark a feature tested by ID
+	MarkUntested     int    // Mark a feature untested (and no longer deferred) by ID
+	DeferFeature     int    // Defer a feature by ID
+	DeferReason      string // Reason recorded with -defer
+	DeleteFeature    int    // Remove a feature from the plan by ID
+	AddFeature       string // Description of a feature to append to the plan
+	FeatureCategory  string // Category of the feature added with -add-feature
+	FeatureSteps     string // Steps of the feature added with -add-feature, separated by ";"
+	FeatureMilestone string // Milestone of the feature added with -add-feature
 	ListTested       bool
 	ListUntested     bool
 	GeneratePlan     bool
 	NotesFile        string
+	FromIssue        string // GitHub issue or pull request (owner/repo#123 or URL) to generate the plan from instead of notes
+	NoAgent          bool   // Generate the plan from the structure of the notes, without the agent
 	OutputPlanFile   string
+	WorkDir          string // Directory to change to before anything else (-C), like git -C
 	ConfigFile       string // Path to config file (if specified via -config flag)
+	Profile          string // Config file profile overlaid on the other settings
+	ExplainConfig    bool   // Print every effective config value with its source
 	MaxRetries       int    // Maximum retries per feature before recovery escalation
 	RecoveryStrategy string // Recovery strategy: retry, skip, rollback
 	Environment      string // Environment override (local, github-actions, gitlab-ci, etc.)
+	IterationTimeout string // Maximum duration of a single agent execution (e.g., "15m"); empty = no limit
+	TimeoutRetry     bool   // Re-run a timed-out iteration once with guidance to be concise
+	FailurePatterns  []FailurePattern // Custom failure detection rules (config file only)
+	// Retry backoff configuration
+	RetryBackoff           string  // Delay before the first retry of a feature (e.g., "30s"); empty = retry immediately
+	RetryBackoffMultiplier float64 // Factor applied to the delay after each retry (default: 2)
+	RetryBackoffMax        string  // Upper bound of the retry delay (default: 5m)
+	RetryJitter            float64 // Fraction of the delay randomly added or removed (default: 0.2)
+	RetryBudget            int     // Maximum retries per run across all features (0 = unlimited)
+	// Rate limit configuration
+	MinIterationInterval string // Minimum time between the starts of agent runs (e.g., "30s"); empty = no pause
+	RateLimitBackoff     string // Wait before retrying a rate-limited agent, doubled for each retry (default: 30s)
+	RateLimitRetries     int    // Times a rate-limited agent is retried before the iteration fails (0 = treat as a failure)
+	// Restore point configuration
+	RollbackFeature   int // Restore the working tree to before this feature was started
+	RollbackIteration int // Restore the working tree to before this iteration of the latest run
 	// UI-related configuration
 	NoColor    bool   // Disable colored output
 	Quiet      bool   // Minimal output (errors only)
@@ -71,51 +149,261 @@ type Config struct {
 	ClearMemory     bool   // Clear all memories
 	AddMemory       string // Add a manual memory entry (format: "type:content")
 	MemoryRetention int    // Number of days to retain memories (default: 90)
+	ExportMemory    string // Write all memories to this file
+	ImportMemory    string // Read memories from this file, replacing the stored ones unless MergeMemory is set
+	MergeMemory     bool   // Merge imported memories with the stored ones (-merge)
+	DeleteMemory    string // Delete the memory with this ID
+	EditMemory      string // Replace a memory's content (format: "id:content")
+	PinMemory       string // Exempt the memory with this ID from retention pruning
+	UnpinMemory     string // Make the memory with this ID subject to retention pruning again
+	GlobalMemory    bool   // Memory commands target the user-level store (-global)
+	NoGlobalMemory  bool   // Do not layer the user-level store ($HOME/.ralph/memory.json) under the project's
 	// Milestone-related configuration
-	ListMilestones  bool   // List all milestones with progress
-	ShowMilestone   string // Show features for a specific milestone
+	ListMilestones bool   // List all milestones with progress
+	ShowMilestone  string // Show features for a specific milestone
 	// Nudge-related configuration
-	NudgeFile    string // Path to nudge file (default: nudges.json)
-	Nudge        string // One-time inline nudge (format: "type:content")
-	ClearNudges  bool   // Clear all nudges
-	ShowNudges   bool   // Display current nudges
+	NudgeFile      string // Path to nudge file (default: nudges.json)
+	Nudge          string // One-time inline nudge (format: "type:content")
+	ClearNudges    bool   // Clear all nudges
+	ShowNudges     bool   // Display current nudges
+	NudgeSticky    bool   // Keep the -nudge nudge active after it is injected
+	NudgeTTL       string // Expiry of the -nudge nudge: a duration or a number of iterations
+	NudgeList      bool   // List active nudges with their remaining lifetime
+	NoNudgeConsole bool   // Do not read nudges typed into the terminal during a run
 	// Scope control configuration
-	ScopeLimit   int    // Max iterations per feature (0 = unlimited)
-	Deadline     string // Deadline duration (e.g., "1h", "30m", "2h30m")
-	ListDeferred bool   // List deferred features
+	ScopeLimit         int    // Max iterations per feature (0 = unlimited)
+	Deadline           string // Deadline duration (e.g., "1h", "30m", "2h30m")
+	FeatureDeadline    string // Time budget per feature (e.g., "20m"); empty = no limit
+	ListDeferred       bool   // List deferred features
+	NoScopeCalibration bool   // Use the built-in iteration estimates instead of calibrating them from run history
+	Forecast           bool   // Estimate the iterations and time left for the plan and each milestone
 	// Replanning configuration
-	AutoReplan       bool   // Enable automatic replanning when triggers fire
-	Replan           bool   // Manually trigger replanning
-	ReplanStrategy   string // Replanning strategy: incremental, agent
-	ReplanThreshold  int    // Number of consecutive failures before replanning
-	ListVersions     bool   // List plan versions
-	RestoreVersion   int    // Restore a specific plan version
+	AutoReplan      bool   // Enable automatic replanning when triggers fire
+	Replan          bool   // Manually trigger replanning
+	ReplanStrategy  string // Replanning strategy: incremental, agent
+	ReplanThreshold int    // Number of consecutive failures before replanning
+	ListVersions    bool   // List plan versions
+	RestoreVersion  int    // Restore a specific plan version
+	ReplanConfirm   bool   // Show the diff of a replan and ask before it overwrites the plan
+	// Plan backup configuration
+	PlanVersionDir       string // Directory for plan backups (default: .ralph/plan-versions)
+	MaxPlanVersions      int    // Number of plan backups to keep (0 = all)
+	CompressPlanVersions bool   // Gzip plan backups
+	// Plan merging
+	MergePlan            string  // Plan file whose features are merged into the plan
+	MergeMilestonePrefix string  // Prepended to the milestones of merged features
+	MergeSimilarity      float64 // Skip merged features at least this similar to an existing one (0-1, 0 = merge all)
 	// Validation configuration
-	Validate        bool // Run validations for all completed features
-	ValidateFeature int  // Validate a specific feature by ID
+	Validate           bool   // Run validations for all completed features
+	CIGate             bool   // Exit non-zero if the plan regressed since the last recorded run
+	ValidateFeature    int    // Validate a specific feature by ID
+	ValidateOnComplete bool   // Run a feature's validations when the agent marks it tested, unmarking it if they fail
+	CoverageGate       string // Minimum coverage for marking features tested (e.g., "80%"); empty = no gate
+	CoverageCmd        string // Command measuring test coverage (default depends on the build system)
+	ReportDir          string // Directory for validation reports (default: .ralph/reports)
+	ReportHTML         bool   // Also write an HTML validation report
+	EnvironmentReports bool   // Write GitLab CI / Jenkins reports (JUnit, dotenv, properties) to ReportDir at the end of a run
 	// Goal-oriented configuration
-	GoalsFile     string // Path to goals file (default: goals.json)
-	Goal          string // Single goal to add and decompose
-	GoalPriority  int    // Priority for the goal (when using -goal)
-	ShowGoals     bool   // Show all goals with progress (unified view)
-	GoalStatus    bool   // Deprecated: Use ShowGoals instead
-	ListGoals     bool   // Deprecated: Use ShowGoals instead
-	DecomposeGoal string // Decompose a specific goal by ID
-	DecomposeAll  bool   // Decompose all pending goals
+	GoalsFile       string // Path to goals file (default: goals.json)
+	Goal            string // Single goal to add and decompose
+	GoalPriority    int    // Priority for the goal (when using -goal)
+	ShowGoals       bool   // Show all goals with progress (unified view)
+	GoalStatus      bool   // Deprecated: Use ShowGoals instead
+	ListGoals       bool   // Deprecated: Use ShowGoals instead
+	DecomposeGoal   string // Decompose a specific goal by ID
+	DecomposeAll    bool   // Decompose all pending goals
+	RedecomposeGoal string // Regenerate the plan items of the goal with this ID
+	Feedback        string // Feedback on the previous plan items, for -redecompose-goal
+	RemoveGoal      string // Remove the goal with this ID
+	EditGoal        string // Edit the goal with this ID as JSON in $VISUAL or $EDITOR
+	SetGoalPriority string // Change a goal's priority (format: "id:priority")
+	CompleteGoal    string // Mark the goal with this ID complete
 	// Multi-agent configuration
-	AgentsFile       string // Path to multi-agent configuration file
-	ParallelAgents   int    // Maximum number of agents to run in parallel
-	ListAgents       bool   // List configured agents
-	EnableMultiAgent bool   // Enable multi-agent mode
+	AgentsFile       string           // Path to multi-agent configuration file
+	ParallelAgents   int              // Maximum number of agents to run in parallel
+	ListAgents       bool             // List configured agents
+	EnableMultiAgent bool             // Enable multi-agent mode
+	ReviewGate       bool             // Only keep features tested once the reviewer agents approve the iteration
+	Agents           []map[string]any // Agents defined in the config file (agents section), used instead of AgentsFile
 	// Plan analysis configuration
 	AnalyzePlan bool // Analyze plan for refinement suggestions (read-only, writes preview to plan.refined.json)
 	RefinePlan  bool // Apply plan refinement by splitting complex features (writes to plan.json)
-	DryRun      bool // Show what changes would be made without writing (for -refine-plan)
+	DryRun      bool // Show what a run (or -refine-plan) would do without invoking the agent or writing changes
+	// Prompt configuration
+	MaxPromptSteps          int    // Steps of a feature included in prompts; longer step lists are truncated (0 = no limit)
+	IterationPromptTemplate string // Go text/template file replacing the built-in iteration prompt
+	PlanPromptTemplate      string // Go text/template file replacing the built-in plan generation prompt
+	GoalPromptTemplate      string // Go text/template file replacing the built-in goal decomposition prompt
+	ProgressTail            int    // Recent progress file entries included in iteration prompts (0 = none)
+	FeaturePrompt           bool   // Prompt with only the current feature's details instead of the whole plan
 	// Baseline configuration
 	Baseline         bool   // Run baseline analysis of the codebase
 	BaselineFile     string // Path to baseline file (default: baseline.json)
 	ShowBaseline     bool   // Display current baseline summary
+	BaselineDrift    bool   // Compare the saved baseline with the current tree
 	UseBaseline      bool   // Use baseline context in prompts (default: true when baseline.json exists)
+	NoImpactAnalysis bool   // Don't list the files the current feature will likely touch in prompts
+	// Context staleness configuration
+	ContextMaxAge       int  // Days after which the baseline and memories count as stale (0 = never)
+	ContextMaxChanges   int  // Files changed after which the baseline and memories count as stale (0 = never)
+	RefreshStaleContext bool // Re-scan a stale baseline at the start of a run instead of only warning
+	// Run history configuration
+	HistoryDir  string // Directory for run history records (default: .ralph/history)
+	RunLabel    string // Optional label recorded with this run (e.g., "claude-opus")
+	JUnitOutput string // JUnit XML file for the iterations of a run, or the results of -validate
+	// Markdown summary of the run (e.g., for PR descriptions)
+	SummaryMarkdown string // Markdown file for the end-of-run summary
+	// Progress log configuration
+	ProgressQuery string // Print the structured progress log events matching these filters
+	// Analytics configuration
+	Analytics bool // Print statistics per feature category aggregated across the run history
+	// Changelog configuration
+	Changelog       bool   // Print the changelog of the completed features
+	ChangelogFormat string // Changelog format: markdown or keepachangelog
+	ChangelogFile   string // File the changelog is written to, and updated in when a milestone completes during a run
+	// Iteration diff configuration
+	DiffDir           string // Directory for per-iteration patches (default: .ralph/diffs)
+	ShowIterationDiff int    // Print the patch of this iteration of the latest run
+	// Transcript configuration
+	Transcript       bool     // Record every prompt and agent response in an append-only transcript
+	TranscriptDir    string   // Directory for transcripts (default: .ralph/transcripts)
+	TranscriptRedact []string // Regular expressions whose matches are redacted from transcripts (config file only)
+	ShowTranscript   string   // Print the transcript of this run ("latest", run ID or prefix)
+	// Telemetry configuration
+	Telemetry     bool   // Aggregate anonymized usage statistics locally (opt-in)
+	TelemetryFile string // Path of the telemetry aggregate (default: .ralph/telemetry.json)
+	// Checkpoint configuration
+	CheckpointDir string // Directory for named checkpoints (default: .ralph/checkpoints)
+	// Flaky test configuration
+	FlakyFile string // Path of the flaky test store (default: .ralph/flaky.json)
+	// Test report configuration
+	TestReport string // Test report naming failed tests in retry guidance (default: the build system's report directory, if any)
+	// API backend configuration
+	AgentBackend  string  // Agent backend: cli, openai, anthropic
+	APIBaseURL    string  // Base URL for the API backend (default depends on provider)
+	APIModel      string  // Model name for the API backend
+	APIKeyEnv     string  // Environment variable holding the API key
+	APIMaxTokens  int     // Maximum tokens to generate per API request
+	APIInputCost  float64 // Cost per million input tokens (for cost accounting)
+	APIOutputCost float64 // Cost per million output tokens (for cost accounting)
+	// A/B experiment configuration
+	Experiment        bool   // Alternate between two agent/prompt variants and compare results
+	ExperimentAgentB  string // Agent command for variant B (variant A uses -agent)
+	ExperimentPromptA string // Prompt template file for variant A
+	ExperimentPromptB string // Prompt template file for variant B
+	ExperimentSplit   string // How iterations are split: alternate, halves
+	// Safety configuration
+	NoPathGuard      bool     // Disable the guard against modifying files outside the repository
+	GuardPaths       string   // Additional comma-separated paths outside the repository to watch
+	IsolatedWorktree bool     // Run in a temporary git worktree and merge back only after validation passes
+	Approve          bool     // Ask a human to approve each iteration's changes before moving on
+	Yes              bool     // Answer yes to confirmation prompts, e.g. of -replan-confirm
+	PolicyFile       string   // Path to the policy file (default: ralph-policy.yaml if present)
+	Force            bool     // Take over locks on the plan and progress files held by other processes
+	NoRedact         bool     // Send and persist text without masking secrets (API keys, tokens, passwords)
+	RedactPatterns   []string // Additional regular expressions for secrets to mask (config file only)
+	// Streaming configuration
+	Stream bool // Stream agent output to the terminal live instead of after the iteration
+	// Notification configuration
+	Notify bool // Show desktop notifications when the run finishes, a milestone completes, or approval is needed (not in CI)
+	// Agent environment configuration
+	AgentEnv map[string]string // Extra environment variables for the agent process only; values may be env:NAME or file:PATH references
+	// Agent fallback configuration
+	FallbackAgents []string // Agent commands tried in order when the agent errors or times out
+	RotateAgents   bool     // Start each iteration at the next agent, spreading rate limits across the agents
+	// Agent health check configuration
+	HealthCheck       bool   // Ping the agents before the first iteration to verify authentication and connectivity
+	HealthCheckPrompt string // Prompt of the health check
+	// Parallel feature configuration
+	Parallel int // Independent features worked on at the same time, each in its own git worktree (0 or 1 = one at a time)
+	// Lifecycle hooks
+	Hooks Hooks // Commands run before and after iterations, on failures and on completion (config file only)
+	// Issue tracker sync
+	SyncTracker bool     // Import the tracker's open issues as features and close the issues of tested features, then exit
+	Tracker     *Tracker // Issue tracker to sync with (config file only)
+}
+
+// UsesAPIBackend reports whether the agent is reached over an HTTP API
+// instead of by shelling out to an agent CLI
+func (c *Config) UsesAPIBackend() bool {
+	return c.AgentBackend != "" && c.AgentBackend != DefaultAgentBackend
+}
+
+// IterationTimeoutDuration returns the parsed per-iteration timeout, or 0 if
+// none is set
+func (c *Config) IterationTimeoutDuration() (time.Duration, error) {
+	return ParseIterationTimeout(c.IterationTimeout)
+}
+
+// ParseIterationTimeout parses a per-iteration timeout, a positive duration
+// such as "15m". An empty value means no limit and returns 0.
+func ParseIterationTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// FeatureDeadlineDuration returns the parsed per-feature time budget, or 0 if
+// none is set or the value is invalid
+func (c *Config) FeatureDeadlineDuration() time.Duration {
+	if c.FeatureDeadline == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(c.FeatureDeadline)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// RetryBackoffDuration returns the parsed delay before the first retry, or 0
+// if retries are not delayed
+func (c *Config) RetryBackoffDuration() time.Duration {
+	return parsePositiveDuration(c.RetryBackoff)
+}
+
+// MinIterationIntervalDuration returns the parsed minimum time between agent
+// runs, or 0 if runs are not spaced out
+func (c *Config) MinIterationIntervalDuration() time.Duration {
+	return parsePositiveDuration(c.MinIterationInterval)
+}
+
+// RateLimitBackoffDuration returns the parsed wait before retrying a
+// rate-limited agent, or 0 if it is retried immediately
+func (c *Config) RateLimitBackoffDuration() time.Duration {
+	return parsePositiveDuration(c.RateLimitBackoff)
+}
+
+// RetryBackoffMaxDuration returns the parsed upper bound of the retry delay,
+// or 0 if it is unbounded
+func (c *Config) RetryBackoffMaxDuration() time.Duration {
+	return parsePositiveDuration(c.RetryBackoffMax)
+}
+
+// HookTimeoutDuration returns the parsed limit of a hook's run time, or 0 for
+// the default
+func (c *Config) HookTimeoutDuration() time.Duration {
+	return parsePositiveDuration(c.Hooks.Timeout)
+}
+
+// parsePositiveDuration parses a duration, returning 0 if it is empty or invalid
+func parsePositiveDuration(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
 }
 
 // New creates a new Config with default values
@@ -140,5 +428,26 @@ func New() *Config {
 		ParallelAgents:   DefaultParallelAgents,
 		BaselineFile:     DefaultBaselineFile,
 		UseBaseline:      true, // Auto-use baseline if file exists
+		HistoryDir:       DefaultHistoryDir,
+		DiffDir:          DefaultDiffDir,
+		ChangelogFormat:  DefaultChangelogFormat,
+		TranscriptDir:    DefaultTranscriptDir,
+		TelemetryFile:    DefaultTelemetryFile,
+		CheckpointDir:    DefaultCheckpointDir,
+		PlanVersionDir:   DefaultPlanVersionDir,
+		FlakyFile:        DefaultFlakyFile,
+		ReportDir:        DefaultReportDir,
+		MaxPromptSteps:   DefaultMaxPromptSteps,
+		AgentBackend:     DefaultAgentBackend,
+		ExperimentSplit:  DefaultExperimentSplit,
+
+		HealthCheckPrompt:      DefaultHealthCheckPrompt,
+		RetryBackoffMultiplier: DefaultRetryBackoffMultiplier,
+		RetryBackoffMax:        DefaultRetryBackoffMax,
+		RetryJitter:            DefaultRetryJitter,
+		RateLimitBackoff:       DefaultRateLimitBackoff,
+		RateLimitRetries:       DefaultRateLimitRetries,
+		ContextMaxAge:          DefaultContextMaxAge,
+		ContextMaxChanges:      DefaultContextMaxChanges,
 	}
 }
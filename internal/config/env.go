@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables that set config keys
+const EnvPrefix = "RALPH_"
+
+// reservedEnv are RALPH_* variables that mean something else: settings read
+// outside the config file, and the variables Ralph sets for hooks and in CI
+// reports, which a hook or a later CI job running Ralph inherits. Config keys
+// with these names (agent, iterations) cannot be set from the environment.
+var reservedEnv = map[string]bool{
+	"RALPH_PROFILE":            true,
+	"RALPH_SERVE_TOKEN":        true,
+	"RALPH_HARNESS_API_KEY":    true,
+	"RALPH_EVENT":              true,
+	"RALPH_RUN_ID":             true,
+	"RALPH_ITERATION":          true,
+	"RALPH_ITERATIONS":         true,
+	"RALPH_FEATURE_ID":         true,
+	"RALPH_FEATURE":            true,
+	"RALPH_AGENT":              true,
+	"RALPH_RESULT":             true,
+	"RALPH_ERROR":              true,
+	"RALPH_MILESTONE":          true,
+	"RALPH_STATUS":             true,
+	"RALPH_FEATURES_COMPLETED": true,
+	"RALPH_FEATURES_TESTED":    true,
+	"RALPH_FEATURES_TOTAL":     true,
+	"RALPH_FAILURES":           true,
+}
+
+// EnvVarName returns the environment variable that sets a config key, e.g.
+// RALPH_SCOPE_LIMIT for scope_limit
+func EnvVarName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// OverlayEnv returns a copy of base (which may be nil) with the keys set by
+// RALPH_* environment variables replaced, and the keys that were set. String
+// values are used as they are; other values are parsed as YAML, e.g.
+// RALPH_SCOPE_LIMIT=5, RALPH_LINT=true or RALPH_REDACT_PATTERNS='["token-[0-9]+"]'.
+// Keys whose variable is reserved for another purpose are never set.
+func OverlayEnv(base *FileConfig) (*FileConfig, []string, error) {
+	merged := FileConfig{}
+	if base != nil {
+		merged = *base
+	}
+	v := reflect.ValueOf(&merged).Elem()
+	var keys []string
+	for i := 0; i < v.NumField(); i++ {
+		key := fieldKey(v.Type().Field(i))
+		if key == "" || reservedEnv[EnvVarName(key)] {
+			continue
+		}
+		raw, ok := os.LookupEnv(EnvVarName(key))
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		value := reflect.New(field.Type())
+		if field.Kind() == reflect.String {
+			value.Elem().SetString(raw)
+		} else if err := yaml.Unmarshal([]byte(raw), value.Interface()); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", EnvVarName(key), err)
+		}
+		field.Set(value.Elem())
+		keys = append(keys, key)
+	}
+	return &merged, keys, nil
+}
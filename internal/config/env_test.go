@@ -0,0 +1,84 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvVarName(t *testing.T) {
+	if got := EnvVarName("scope_limit"); got != "RALPH_SCOPE_LIMIT" {
+		t.Errorf("EnvVarName() = %s", got)
+	}
+}
+
+func TestOverlayEnv(t *testing.T) {
+	t.Setenv("RALPH_BUILD_SYSTEM", "cargo")
+	t.Setenv("RALPH_SCOPE_LIMIT", "7")
+	t.Setenv("RALPH_LINT", "false")
+	t.Setenv("RALPH_TEST", "go test ./... -run 'A: B'")
+	t.Setenv("RALPH_REDACT_PATTERNS", `["tok-[0-9]+", "sk-\\w+"]`)
+
+	base := &FileConfig{Agent: "claude", ScopeLimit: 4, Lint: true, Plan: "tasks.json"}
+	merged, keys, err := OverlayEnv(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FileConfig{
+		Agent:          "claude",
+		BuildSystem:    "cargo",
+		ScopeLimit:     7,
+		Test:           "go test ./... -run 'A: B'",
+		Plan:           "tasks.json",
+		RedactPatterns: []string{"tok-[0-9]+", `sk-\w+`},
+	}
+	if !reflect.DeepEqual(*merged, want) {
+		t.Errorf("OverlayEnv() = %+v, want %+v", *merged, want)
+	}
+	if base.BuildSystem != "" {
+		t.Error("OverlayEnv() modified the base config")
+	}
+	if want := []string{"build_system", "test", "lint", "scope_limit", "redact_patterns"}; !sameKeys(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+
+	// Without a config file, the environment is the whole config
+	if merged, _, err := OverlayEnv(nil); err != nil || merged.BuildSystem != "cargo" {
+		t.Errorf("OverlayEnv(nil) = %+v, %v", merged, err)
+	}
+
+	t.Setenv("RALPH_MAX_RETRIES", "many")
+	if _, _, err := OverlayEnv(base); err == nil {
+		t.Error("OverlayEnv() accepted a non-numeric RALPH_MAX_RETRIES")
+	}
+}
+
+func TestOverlayEnvSkipsReservedVariables(t *testing.T) {
+	// Ralph sets these for hooks, so a hook running Ralph must not inherit them
+	t.Setenv("RALPH_AGENT", "aider")
+	t.Setenv("RALPH_ITERATIONS", "6")
+
+	merged, keys, err := OverlayEnv(&FileConfig{Agent: "claude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Agent != "claude" || merged.Iterations != 0 || len(keys) != 0 {
+		t.Errorf("OverlayEnv() = %+v, keys %v; want reserved variables skipped", *merged, keys)
+	}
+}
+
+// sameKeys reports whether a and b hold the same keys in any order
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, k := range a {
+		seen[k] = true
+	}
+	for _, k := range b {
+		if !seen[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Source is where an effective config value comes from
+type Source string
+
+const (
+	// SourceDefault is Ralph's built-in default
+	SourceDefault Source = "default"
+	// SourceFlag is a command-line flag
+	SourceFlag Source = "flag"
+	// SourceEnv is a RALPH_* environment variable
+	SourceEnv Source = "env"
+	// SourceProjectConfig is a config file outside the home directory
+	SourceProjectConfig Source = "project config"
+	// SourceHomeConfig is a config file in the home directory
+	SourceHomeConfig Source = "home config"
+	// SourceDerived is a value Ralph set from other settings, such as the
+	// commands of the build system preset
+	SourceDerived Source = "derived"
+)
+
+// ProfileSource returns the source of values set by a config file profile
+func ProfileSource(fileSource Source, profile string) Source {
+	return Source(fmt.Sprintf("%s, profile %s", fileSource, profile))
+}
+
+// Setting is an effective config value and where it comes from
+type Setting struct {
+	Key    string // Config file key
+	Value  string
+	Source Source
+}
+
+// FileConfigSource returns the source of the config file at path: home
+// config for a file in the home directory, project config otherwise
+func FileConfigSource(path string) Source {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return SourceProjectConfig
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err == nil && dir == filepath.Clean(home) {
+		return SourceHomeConfig
+	}
+	return SourceProjectConfig
+}
+
+// FileConfigKeys returns the keys a config file can set, in the order of the
+// FileConfig fields
+func FileConfigKeys() []string {
+	t := reflect.TypeOf(FileConfig{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := fieldKey(t.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// fieldKey returns the config file key of a FileConfig field, or "" if the
+// field is not a setting
+func fieldKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || name == profilesKey {
+		return ""
+	}
+	return name
+}
+
+// FileConfigValues returns the values a config file sets, by key. Values are
+// formatted as JSON, except strings, which are not quoted.
+func FileConfigValues(f *FileConfig) (map[string]string, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode config file: %w", err)
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			values[key] = s
+		} else {
+			values[key] = string(value)
+		}
+	}
+	return values, nil
+}
+
+// FormatSettings formats settings as aligned lines of key, source and value
+func FormatSettings(settings []Setting) string {
+	keyWidth, sourceWidth := 0, 0
+	for _, s := range settings {
+		keyWidth = max(keyWidth, len(s.Key))
+		sourceWidth = max(sourceWidth, len(s.Source))
+	}
+	var b strings.Builder
+	for _, s := range settings {
+		b.WriteString(fmt.Sprintf("%-*s  %-*s  %s\n", keyWidth, s.Key, sourceWidth, s.Source, displayValue(s.Value)))
+	}
+	return b.String()
+}
+
+// displayValue shows empty values explicitly
+func displayValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
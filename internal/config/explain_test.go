@@ -0,0 +1,58 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileConfigKeys(t *testing.T) {
+	keys := FileConfigKeys()
+	if len(keys) == 0 || keys[0] != "agent" {
+		t.Fatalf("FileConfigKeys() = %v, want agent first", keys)
+	}
+	for _, key := range keys {
+		if strings.Contains(key, ",") || key == "" {
+			t.Errorf("invalid key %q", key)
+		}
+	}
+}
+
+func TestFileConfigValues(t *testing.T) {
+	limit := 0
+	values, err := FileConfigValues(&FileConfig{Agent: "claude", ScopeLimit: 4, Lint: true, MaxPromptSteps: &limit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"agent": "claude", "scope_limit": "4", "lint": "true", "max_prompt_steps": "0"}
+	if len(values) != len(want) {
+		t.Errorf("FileConfigValues() = %v, want %v", values, want)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %q, want %q", key, values[key], value)
+		}
+	}
+}
+
+func TestFileConfigSource(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if got := FileConfigSource(filepath.Join(home, ".ralph.yaml")); got != SourceHomeConfig {
+		t.Errorf("source of a home config file = %s", got)
+	}
+	if got := FileConfigSource(filepath.Join(t.TempDir(), ".ralph.yaml")); got != SourceProjectConfig {
+		t.Errorf("source of a project config file = %s", got)
+	}
+}
+
+func TestFormatSettings(t *testing.T) {
+	got := FormatSettings([]Setting{
+		{Key: "agent", Value: "claude", Source: SourceFlag},
+		{Key: "scope_limit", Value: "", Source: SourceDefault},
+	})
+	want := "agent        flag     claude\nscope_limit  default  \"\"\n"
+	if got != want {
+		t.Errorf("FormatSettings() = %q, want %q", got, want)
+	}
+}
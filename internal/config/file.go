@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -25,7 +27,14 @@ var ConfigFileNames = []string{
 // Fields use pointers to distinguish between "not set" and "set to zero/empty value".
 type FileConfig struct {
 	// Agent configuration
-	Agent string `json:"agent,omitempty" yaml:"agent,omitempty"`
+	Agent          string            `json:"agent,omitempty" yaml:"agent,omitempty"`
+	AgentEnv       map[string]string `json:"agent_env,omitempty" yaml:"agent_env,omitempty"`             // Environment variables for the agent only (env:NAME / file:PATH references allowed)
+	FallbackAgents []string          `json:"fallback_agents,omitempty" yaml:"fallback_agents,omitempty"` // Agent commands tried in order when the agent errors or times out
+	RotateAgents   bool              `json:"rotate_agents,omitempty" yaml:"rotate_agents,omitempty"`     // Start each iteration at the next agent
+
+	// Agent health check settings
+	HealthCheck       bool   `json:"health_check,omitempty" yaml:"health_check,omitempty"`               // Ping the agents before the first iteration
+	HealthCheckPrompt string `json:"health_check_prompt,omitempty" yaml:"health_check_prompt,omitempty"` // Prompt of the health check
 
 	// Build system preset (pnpm, npm, yarn, gradle, maven, cargo, go, python, auto)
 	BuildSystem string `json:"build_system,omitempty" yaml:"build_system,omitempty"`
@@ -33,6 +42,22 @@ type FileConfig struct {
 	// Custom commands (override build system preset)
 	TypeCheck string `json:"typecheck,omitempty" yaml:"typecheck,omitempty"`
 	Test      string `json:"test,omitempty" yaml:"test,omitempty"`
+	Lint      bool   `json:"lint,omitempty" yaml:"lint,omitempty"`
+	LintCmd   string `json:"lint_cmd,omitempty" yaml:"lint_cmd,omitempty"`
+
+	// Test report read after test failures (JUnit XML file or directory, Jest JSON, go test -json output)
+	TestReport string `json:"test_report,omitempty" yaml:"test_report,omitempty"`
+
+	// Coverage gate
+	CoverageGate string `json:"coverage_gate,omitempty" yaml:"coverage_gate,omitempty"`
+	CoverageCmd  string `json:"coverage_cmd,omitempty" yaml:"coverage_cmd,omitempty"`
+
+	// Validations of features the agent marks tested during a run
+	ValidateOnComplete bool `json:"validate_on_complete,omitempty" yaml:"validate_on_complete,omitempty"`
+
+	// Validation reports
+	ReportDir  string `json:"report_dir,omitempty" yaml:"report_dir,omitempty"`   // Directory for validation reports
+	ReportHTML bool   `json:"report_html,omitempty" yaml:"report_html,omitempty"` // Also write an HTML report
 
 	// File paths
 	Plan     string `json:"plan,omitempty" yaml:"plan,omitempty"`
@@ -45,39 +70,234 @@ type FileConfig struct {
 	// Recovery settings
 	MaxRetries       int    `json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
 	RecoveryStrategy string `json:"recovery_strategy,omitempty" yaml:"recovery_strategy,omitempty"`
+	IterationTimeout string `json:"iteration_timeout,omitempty" yaml:"iteration_timeout,omitempty"` // Per-iteration agent timeout (e.g., "15m")
+	TimeoutRetry     bool   `json:"timeout_retry,omitempty" yaml:"timeout_retry,omitempty"`         // Retry timed-out iterations once
+
+	// Retry backoff settings
+	RetryBackoff           string   `json:"retry_backoff,omitempty" yaml:"retry_backoff,omitempty"`                       // Delay before the first retry (e.g., "30s")
+	RetryBackoffMultiplier float64  `json:"retry_backoff_multiplier,omitempty" yaml:"retry_backoff_multiplier,omitempty"` // Factor applied to the delay after each retry
+	RetryBackoffMax        string   `json:"retry_backoff_max,omitempty" yaml:"retry_backoff_max,omitempty"`               // Upper bound of the retry delay
+	RetryJitter            *float64 `json:"retry_jitter,omitempty" yaml:"retry_jitter,omitempty"`                         // Fraction of the delay randomly added or removed (0 allowed)
+	RetryBudget            int      `json:"retry_budget,omitempty" yaml:"retry_budget,omitempty"`                         // Maximum retries per run (0 = unlimited)
+
+	// Rate limit settings
+	MinIterationInterval string `json:"min_iteration_interval,omitempty" yaml:"min_iteration_interval,omitempty"` // Minimum time between agent runs (e.g., "30s")
+	RateLimitBackoff     string `json:"rate_limit_backoff,omitempty" yaml:"rate_limit_backoff,omitempty"`         // Wait before retrying a rate-limited agent
+	RateLimitRetries     *int   `json:"rate_limit_retries,omitempty" yaml:"rate_limit_retries,omitempty"`         // Retries of a rate-limited agent (0 = treat as a failure)
+
+	// Custom failure detection rules, checked before the built-in ones
+	FailurePatterns []FailurePattern `json:"failure_patterns,omitempty" yaml:"failure_patterns,omitempty"`
 
 	// Environment settings
-	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+	Environment        string `json:"environment,omitempty" yaml:"environment,omitempty"`
+	EnvironmentReports bool   `json:"environment_reports,omitempty" yaml:"environment_reports,omitempty"` // GitLab CI / Jenkins reports at the end of a run
 
 	// UI settings
 	NoColor    bool   `json:"no_color,omitempty" yaml:"no_color,omitempty"`
 	Quiet      bool   `json:"quiet,omitempty" yaml:"quiet,omitempty"`
 	JSONOutput bool   `json:"json_output,omitempty" yaml:"json_output,omitempty"`
 	LogLevel   string `json:"log_level,omitempty" yaml:"log_level,omitempty"`
+	Stream     bool   `json:"stream,omitempty" yaml:"stream,omitempty"` // Stream agent output live
+	Notify     bool   `json:"notify,omitempty" yaml:"notify,omitempty"` // Desktop notifications for local runs
 
 	// Memory settings
 	MemoryFile      string `json:"memory_file,omitempty" yaml:"memory_file,omitempty"`
+	NoGlobalMemory  bool   `json:"no_global_memory,omitempty" yaml:"no_global_memory,omitempty"` // Ignore $HOME/.ralph/memory.json
 	MemoryRetention int    `json:"memory_retention,omitempty" yaml:"memory_retention,omitempty"`
 
 	// Nudge settings
-	NudgeFile string `json:"nudge_file,omitempty" yaml:"nudge_file,omitempty"`
+	NudgeFile      string `json:"nudge_file,omitempty" yaml:"nudge_file,omitempty"`
+	NoNudgeConsole bool   `json:"no_nudge_console,omitempty" yaml:"no_nudge_console,omitempty"` // Do not read nudges typed during a run
 
 	// Scope control settings
-	ScopeLimit int    `json:"scope_limit,omitempty" yaml:"scope_limit,omitempty"` // Max iterations per feature
-	Deadline   string `json:"deadline,omitempty" yaml:"deadline,omitempty"`       // Deadline duration (e.g., "1h", "30m")
+	ScopeLimit         int    `json:"scope_limit,omitempty" yaml:"scope_limit,omitempty"`                   // Max iterations per feature
+	Deadline           string `json:"deadline,omitempty" yaml:"deadline,omitempty"`                         // Deadline duration (e.g., "1h", "30m")
+	FeatureDeadline    string `json:"feature_deadline,omitempty" yaml:"feature_deadline,omitempty"`         // Time budget per feature (e.g., "20m")
+	NoScopeCalibration bool   `json:"no_scope_calibration,omitempty" yaml:"no_scope_calibration,omitempty"` // Use the built-in iteration estimates
+
+	// Prompt settings
+	MaxPromptSteps          *int   `json:"max_prompt_steps,omitempty" yaml:"max_prompt_steps,omitempty"`                   // Steps of a feature included in prompts (0 = no limit)
+	IterationPromptTemplate string `json:"iteration_prompt_template,omitempty" yaml:"iteration_prompt_template,omitempty"` // Template file for the iteration prompt
+	PlanPromptTemplate      string `json:"plan_prompt_template,omitempty" yaml:"plan_prompt_template,omitempty"`           // Template file for the plan generation prompt
+	GoalPromptTemplate      string `json:"goal_prompt_template,omitempty" yaml:"goal_prompt_template,omitempty"`           // Template file for the goal decomposition prompt
+	ProgressTail            int    `json:"progress_tail,omitempty" yaml:"progress_tail,omitempty"`                         // Recent progress entries included in iteration prompts
+	FeaturePrompt           bool   `json:"feature_prompt,omitempty" yaml:"feature_prompt,omitempty"`                       // Prompt with only the current feature
 
 	// Replanning settings
 	AutoReplan      bool   `json:"auto_replan,omitempty" yaml:"auto_replan,omitempty"`           // Enable automatic replanning
 	ReplanStrategy  string `json:"replan_strategy,omitempty" yaml:"replan_strategy,omitempty"`   // Replanning strategy: incremental, agent
 	ReplanThreshold int    `json:"replan_threshold,omitempty" yaml:"replan_threshold,omitempty"` // Consecutive failures before replanning
+	ReplanConfirm   bool   `json:"replan_confirm,omitempty" yaml:"replan_confirm,omitempty"`     // Ask before a replan overwrites the plan
+
+	// Plan backup settings
+	PlanVersionDir       string `json:"plan_version_dir,omitempty" yaml:"plan_version_dir,omitempty"`             // Directory for plan backups
+	MaxPlanVersions      int    `json:"max_plan_versions,omitempty" yaml:"max_plan_versions,omitempty"`           // Number of plan backups to keep
+	CompressPlanVersions bool   `json:"compress_plan_versions,omitempty" yaml:"compress_plan_versions,omitempty"` // Gzip plan backups
 
 	// Goal settings
 	GoalsFile string `json:"goals_file,omitempty" yaml:"goals_file,omitempty"` // Path to goals file
 
 	// Multi-agent settings
-	AgentsFile       string `json:"agents_file,omitempty" yaml:"agents_file,omitempty"`             // Path to multi-agent config file
-	ParallelAgents   int    `json:"parallel_agents,omitempty" yaml:"parallel_agents,omitempty"`     // Max parallel agents
+	AgentsFile       string `json:"agents_file,omitempty" yaml:"agents_file,omitempty"`               // Path to multi-agent config file
+	ParallelAgents   int    `json:"parallel_agents,omitempty" yaml:"parallel_agents,omitempty"`       // Max parallel agents
 	EnableMultiAgent bool   `json:"enable_multi_agent,omitempty" yaml:"enable_multi_agent,omitempty"` // Enable multi-agent mode
+	// Agents defines the multi-agent agents in the config file, used instead of
+	// agents_file. Entries have the fields of the agents of an agents file.
+	Agents     []map[string]any `json:"agents,omitempty" yaml:"agents,omitempty"`
+	ReviewGate bool             `json:"review_gate,omitempty" yaml:"review_gate,omitempty"` // Require reviewer approval before features stay tested
+
+	// Context staleness settings
+	ContextMaxAge       *int `json:"context_max_age,omitempty" yaml:"context_max_age,omitempty"`             // Days after which the baseline and memories are stale (0 = never)
+	ContextMaxChanges   *int `json:"context_max_changes,omitempty" yaml:"context_max_changes,omitempty"`     // Files changed after which they are stale (0 = never)
+	RefreshStaleContext bool `json:"refresh_stale_context,omitempty" yaml:"refresh_stale_context,omitempty"` // Re-scan a stale baseline at the start of a run
+	NoImpactAnalysis    bool `json:"no_impact_analysis,omitempty" yaml:"no_impact_analysis,omitempty"`       // Don't list the files the current feature will likely touch
+
+	// Run history settings
+	HistoryDir  string `json:"history_dir,omitempty" yaml:"history_dir,omitempty"`   // Directory for run history records
+	JUnitOutput string `json:"junit_output,omitempty" yaml:"junit_output,omitempty"` // JUnit XML file for CI test summaries
+
+	// Markdown summary of the run
+	SummaryMarkdown string `json:"summary_markdown,omitempty" yaml:"summary_markdown,omitempty"` // Markdown file for the end-of-run summary
+
+	// Changelog settings
+	ChangelogFile   string `json:"changelog_file,omitempty" yaml:"changelog_file,omitempty"`     // Changelog updated when a milestone completes
+	ChangelogFormat string `json:"changelog_format,omitempty" yaml:"changelog_format,omitempty"` // markdown or keepachangelog
+
+	// Iteration diff settings
+	DiffDir string `json:"diff_dir,omitempty" yaml:"diff_dir,omitempty"` // Directory for per-iteration patches
+
+	// Transcript settings
+	Transcript       bool     `json:"transcript,omitempty" yaml:"transcript,omitempty"`               // Record prompts and responses
+	TranscriptDir    string   `json:"transcript_dir,omitempty" yaml:"transcript_dir,omitempty"`       // Directory for transcripts
+	TranscriptRedact []string `json:"transcript_redact,omitempty" yaml:"transcript_redact,omitempty"` // Patterns redacted from transcripts
+
+	// Telemetry settings
+	Telemetry     bool   `json:"telemetry,omitempty" yaml:"telemetry,omitempty"`           // Aggregate anonymized usage statistics locally
+	TelemetryFile string `json:"telemetry_file,omitempty" yaml:"telemetry_file,omitempty"` // Path of the telemetry aggregate
+
+	// Checkpoint settings
+	CheckpointDir string `json:"checkpoint_dir,omitempty" yaml:"checkpoint_dir,omitempty"` // Directory for named checkpoints
+
+	// Flaky test settings
+	FlakyFile string `json:"flaky_file,omitempty" yaml:"flaky_file,omitempty"` // Path of the flaky test store
+
+	// API backend settings
+	Backend       string  `json:"backend,omitempty" yaml:"backend,omitempty"`                 // Agent backend: cli, openai, anthropic
+	APIBaseURL    string  `json:"api_base_url,omitempty" yaml:"api_base_url,omitempty"`       // Base URL for the API backend
+	APIModel      string  `json:"api_model,omitempty" yaml:"api_model,omitempty"`             // Model name for the API backend
+	APIKeyEnv     string  `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"`         // Environment variable holding the API key
+	APIMaxTokens  int     `json:"api_max_tokens,omitempty" yaml:"api_max_tokens,omitempty"`   // Max tokens per API request
+	APIInputCost  float64 `json:"api_input_cost,omitempty" yaml:"api_input_cost,omitempty"`   // Cost per million input tokens
+	APIOutputCost float64 `json:"api_output_cost,omitempty" yaml:"api_output_cost,omitempty"` // Cost per million output tokens
+
+	// A/B experiment settings
+	Experiment        bool   `json:"experiment,omitempty" yaml:"experiment,omitempty"`                   // Enable A/B experiment mode
+	ExperimentAgentB  string `json:"experiment_agent_b,omitempty" yaml:"experiment_agent_b,omitempty"`   // Agent command for variant B
+	ExperimentPromptA string `json:"experiment_prompt_a,omitempty" yaml:"experiment_prompt_a,omitempty"` // Prompt template file for variant A
+	ExperimentPromptB string `json:"experiment_prompt_b,omitempty" yaml:"experiment_prompt_b,omitempty"` // Prompt template file for variant B
+	ExperimentSplit   string `json:"experiment_split,omitempty" yaml:"experiment_split,omitempty"`       // alternate or halves
+
+	// Safety settings
+	NoPathGuard      bool     `json:"no_path_guard,omitempty" yaml:"no_path_guard,omitempty"`         // Disable the outside-repository guard
+	GuardPaths       []string `json:"guard_paths,omitempty" yaml:"guard_paths,omitempty"`             // Additional paths to watch
+	IsolatedWorktree bool     `json:"isolated_worktree,omitempty" yaml:"isolated_worktree,omitempty"` // Run in a temporary git worktree
+	Approve          bool     `json:"approve,omitempty" yaml:"approve,omitempty"`                     // Require human approval per iteration
+	PolicyFile       string   `json:"policy_file,omitempty" yaml:"policy_file,omitempty"`             // Path to the policy file
+	NoRedact         bool     `json:"no_redact,omitempty" yaml:"no_redact,omitempty"`                 // Do not mask secrets
+	RedactPatterns   []string `json:"redact_patterns,omitempty" yaml:"redact_patterns,omitempty"`     // Additional secret patterns to mask
+
+	// Parallel settings
+	Parallel int `json:"parallel,omitempty" yaml:"parallel,omitempty"` // Independent features worked on at the same time
+
+	// Commands run at points of a run
+	Hooks *Hooks `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+
+	// Issue tracker synced with -sync-tracker
+	Tracker *Tracker `json:"tracker,omitempty" yaml:"tracker,omitempty"`
+
+	// Named sets of settings overlaid on the others with -profile
+	Profiles map[string]map[string]any `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// FailurePattern is a custom rule for detecting failures in agent and check output
+type FailurePattern struct {
+	Name         string   `json:"name,omitempty" yaml:"name,omitempty"`                   // Name shown when the pattern matches
+	Pattern      string   `json:"pattern" yaml:"pattern"`                                 // Regular expression matched per line
+	Type         string   `json:"type,omitempty" yaml:"type,omitempty"`                   // test, typecheck, lint, agent or timeout
+	Severity     string   `json:"severity,omitempty" yaml:"severity,omitempty"`           // warning, error (default) or fatal
+	BuildSystems []string `json:"build_systems,omitempty" yaml:"build_systems,omitempty"` // Build systems it applies to (default: all)
+}
+
+// Hooks are shell commands run at points of a run, with the context of the
+// event as RALPH_* environment variables and as JSON on stdin
+type Hooks struct {
+	PreIteration  []string `json:"pre_iteration,omitempty" yaml:"pre_iteration,omitempty"`   // Before the agent runs
+	PostIteration []string `json:"post_iteration,omitempty" yaml:"post_iteration,omitempty"` // After an iteration was checked
+	OnFailure     []string `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`         // When an iteration fails
+	OnComplete    []string `json:"on_complete,omitempty" yaml:"on_complete,omitempty"`       // When the plan is complete
+	Timeout       string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`               // Limit of each hook's run time (default: 1m)
+	// When all features of a milestone are tested
+	OnMilestoneComplete []string `json:"on_milestone_complete,omitempty" yaml:"on_milestone_complete,omitempty"`
+}
+
+// IsEmpty reports whether no hooks are configured
+func (h Hooks) IsEmpty() bool {
+	return len(h.PreIteration)+len(h.PostIteration)+len(h.OnFailure)+len(h.OnComplete)+len(h.OnMilestoneComplete) == 0 && h.Timeout == ""
+}
+
+// validate checks that the hooks have commands and a valid timeout
+func (h *Hooks) validate() error {
+	if h == nil {
+		return nil
+	}
+	for name, commands := range map[string][]string{"pre_iteration": h.PreIteration, "post_iteration": h.PostIteration,
+		"on_failure": h.OnFailure, "on_complete": h.OnComplete, "on_milestone_complete": h.OnMilestoneComplete} {
+		for i, command := range commands {
+			if strings.TrimSpace(command) == "" {
+				return fmt.Errorf("hooks.%s[%d]: command is empty", name, i)
+			}
+		}
+	}
+	if h.Timeout != "" {
+		d, err := parseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid hooks.timeout format %q: %w", h.Timeout, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("hooks.timeout must be positive")
+		}
+	}
+	return nil
+}
+
+// Tracker is the issue tracker whose issues -sync-tracker imports as features
+// and closes once their feature is tested
+type Tracker struct {
+	Provider   string            `json:"provider" yaml:"provider"`                         // github, jira or linear
+	Project    string            `json:"project" yaml:"project"`                           // owner/repo, Jira project key or Linear team key
+	URL        string            `json:"url,omitempty" yaml:"url,omitempty"`               // API base URL (required for Jira)
+	Token      string            `json:"token,omitempty" yaml:"token,omitempty"`           // Token, or an env:NAME or file:PATH reference
+	Labels     []string          `json:"labels,omitempty" yaml:"labels,omitempty"`         // Only import issues with these labels
+	Categories map[string]string `json:"categories,omitempty" yaml:"categories,omitempty"` // Issue label -> feature category
+}
+
+// validate checks that the tracker has a known provider and a project
+func (t *Tracker) validate() error {
+	if t == nil {
+		return nil
+	}
+	switch strings.ToLower(t.Provider) {
+	case "github", "jira", "linear":
+	default:
+		return fmt.Errorf("invalid tracker.provider %q: must be github, jira or linear", t.Provider)
+	}
+	if strings.TrimSpace(t.Project) == "" {
+		return fmt.Errorf("tracker.project is required")
+	}
+	if strings.EqualFold(t.Provider, "jira") && t.URL == "" {
+		return fmt.Errorf("tracker.url is required for jira")
+	}
+	return nil
 }
 
 // DiscoverConfigFile searches for a configuration file in the current directory
@@ -193,6 +413,89 @@ func ValidateFileConfig(cfg *FileConfig) error {
 		return fmt.Errorf("invalid recovery_strategy %q: must be one of retry, skip, or rollback", cfg.RecoveryStrategy)
 	}
 
+	// Validate retry backoff settings
+	for name, value := range map[string]string{"retry_backoff": cfg.RetryBackoff, "retry_backoff_max": cfg.RetryBackoffMax} {
+		if value == "" {
+			continue
+		}
+		d, err := parseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s format %q: %w", name, value, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if cfg.RetryBackoffMultiplier < 0 {
+		return fmt.Errorf("retry_backoff_multiplier cannot be negative")
+	}
+	if cfg.RetryJitter != nil && (*cfg.RetryJitter < 0 || *cfg.RetryJitter > 1) {
+		return fmt.Errorf("retry_jitter must be between 0 and 1")
+	}
+	if cfg.RetryBudget < 0 {
+		return fmt.Errorf("retry_budget cannot be negative")
+	}
+
+	// Validate rate limit settings
+	for name, value := range map[string]string{"min_iteration_interval": cfg.MinIterationInterval, "rate_limit_backoff": cfg.RateLimitBackoff} {
+		if value == "" {
+			continue
+		}
+		d, err := parseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s format %q: %w", name, value, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if cfg.RateLimitRetries != nil && *cfg.RateLimitRetries < 0 {
+		return fmt.Errorf("rate_limit_retries cannot be negative")
+	}
+	if cfg.Parallel < 0 {
+		return fmt.Errorf("parallel cannot be negative")
+	}
+
+	// Validate hooks
+	if err := cfg.Hooks.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Tracker.validate(); err != nil {
+		return err
+	}
+
+	// Validate custom failure patterns
+	validFailureTypes := map[string]bool{"": true, "test": true, "typecheck": true, "lint": true, "agent": true, "timeout": true}
+	validSeverities := map[string]bool{"": true, "warning": true, "error": true, "fatal": true}
+	for i, fp := range cfg.FailurePatterns {
+		if fp.Pattern == "" {
+			return fmt.Errorf("failure_patterns[%d]: pattern is required", i)
+		}
+		if _, err := regexp.Compile(fp.Pattern); err != nil {
+			return fmt.Errorf("failure_patterns[%d]: invalid pattern %q: %w", i, fp.Pattern, err)
+		}
+		if !validFailureTypes[fp.Type] {
+			return fmt.Errorf("failure_patterns[%d]: invalid type %q: must be one of test, typecheck, lint, agent, or timeout", i, fp.Type)
+		}
+		if !validSeverities[fp.Severity] {
+			return fmt.Errorf("failure_patterns[%d]: invalid severity %q: must be one of warning, error, or fatal", i, fp.Severity)
+		}
+	}
+
+	// Validate secret redaction patterns
+	for i, pattern := range cfg.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("redact_patterns[%d]: invalid pattern %q: %w", i, pattern, err)
+		}
+	}
+
+	// Validate transcript redaction patterns
+	for i, pattern := range cfg.TranscriptRedact {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("transcript_redact[%d]: invalid pattern %q: %w", i, pattern, err)
+		}
+	}
+
 	// Validate environment if specified
 	validEnvironments := map[string]bool{
 		"":               true, // empty is valid (auto-detect)
@@ -241,12 +544,55 @@ func ValidateFileConfig(cfg *FileConfig) error {
 		return fmt.Errorf("scope_limit cannot be negative")
 	}
 
+	// Validate max prompt steps if specified
+	if cfg.MaxPromptSteps != nil && *cfg.MaxPromptSteps < 0 {
+		return fmt.Errorf("max_prompt_steps cannot be negative")
+	}
+	if cfg.ProgressTail < 0 {
+		return fmt.Errorf("progress_tail cannot be negative")
+	}
+
+	// Validate context staleness settings if specified
+	if cfg.ContextMaxAge != nil && *cfg.ContextMaxAge < 0 {
+		return fmt.Errorf("context_max_age cannot be negative")
+	}
+	if cfg.ContextMaxChanges != nil && *cfg.ContextMaxChanges < 0 {
+		return fmt.Errorf("context_max_changes cannot be negative")
+	}
+
+	// Validate agent environment variable names
+	for name := range cfg.AgentEnv {
+		if name == "" || strings.ContainsAny(name, "= \t\n") {
+			return fmt.Errorf("invalid agent_env variable name %q", name)
+		}
+	}
+
+	for _, cmd := range cfg.FallbackAgents {
+		if strings.TrimSpace(cmd) == "" {
+			return fmt.Errorf("fallback_agents cannot contain an empty command")
+		}
+	}
+
+	// Validate iteration timeout if specified
+	if _, err := ParseIterationTimeout(cfg.IterationTimeout); err != nil {
+		return fmt.Errorf("invalid iteration_timeout %q: %w", cfg.IterationTimeout, err)
+	}
+
 	// Validate deadline format if specified
 	if cfg.Deadline != "" {
 		if _, err := parseDuration(cfg.Deadline); err != nil {
 			return fmt.Errorf("invalid deadline format %q: %w", cfg.Deadline, err)
 		}
 	}
+	if cfg.FeatureDeadline != "" {
+		d, err := parseDuration(cfg.FeatureDeadline)
+		if err != nil {
+			return fmt.Errorf("invalid feature_deadline format %q: %w", cfg.FeatureDeadline, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("feature_deadline must be positive")
+		}
+	}
 
 	// Validate replan strategy if specified
 	validReplanStrategies := map[string]bool{
@@ -273,6 +619,37 @@ func ValidateFileConfig(cfg *FileConfig) error {
 		return fmt.Errorf("parallel_agents cannot be negative")
 	}
 
+	// Validate agent backend if specified
+	validBackends := map[string]bool{
+		"":          true, // empty is valid (use default)
+		"cli":       true,
+		"openai":    true,
+		"anthropic": true,
+	}
+
+	if !validBackends[cfg.Backend] {
+		return fmt.Errorf("invalid backend %q: must be one of cli, openai, or anthropic", cfg.Backend)
+	}
+
+	// Validate API settings if specified
+	if cfg.APIMaxTokens < 0 {
+		return fmt.Errorf("api_max_tokens cannot be negative")
+	}
+	if cfg.APIInputCost < 0 || cfg.APIOutputCost < 0 {
+		return fmt.Errorf("api_input_cost and api_output_cost cannot be negative")
+	}
+
+	// Validate experiment split if specified
+	validSplits := map[string]bool{
+		"":          true, // empty is valid (use default)
+		"alternate": true,
+		"halves":    true,
+	}
+
+	if !validSplits[cfg.ExperimentSplit] {
+		return fmt.Errorf("invalid experiment_split %q: must be one of alternate or halves", cfg.ExperimentSplit)
+	}
+
 	return nil
 }
 
@@ -285,6 +662,19 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.Agent != "" && cfg.AgentCmd == DefaultAgentCmd {
 		cfg.AgentCmd = fileCfg.Agent
 	}
+	MergeAgentEnv(cfg, fileCfg.AgentEnv)
+	if len(fileCfg.FallbackAgents) > 0 && len(cfg.FallbackAgents) == 0 {
+		cfg.FallbackAgents = fileCfg.FallbackAgents
+	}
+	if fileCfg.RotateAgents && !cfg.RotateAgents {
+		cfg.RotateAgents = fileCfg.RotateAgents
+	}
+	if fileCfg.HealthCheck && !cfg.HealthCheck {
+		cfg.HealthCheck = fileCfg.HealthCheck
+	}
+	if fileCfg.HealthCheckPrompt != "" && cfg.HealthCheckPrompt == DefaultHealthCheckPrompt {
+		cfg.HealthCheckPrompt = fileCfg.HealthCheckPrompt
+	}
 
 	// Apply build system
 	if fileCfg.BuildSystem != "" && cfg.BuildSystem == "" {
@@ -298,6 +688,30 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.Test != "" && cfg.TestCmd == "" {
 		cfg.TestCmd = fileCfg.Test
 	}
+	if fileCfg.Lint && !cfg.Lint {
+		cfg.Lint = fileCfg.Lint
+	}
+	if fileCfg.LintCmd != "" && cfg.LintCmd == "" {
+		cfg.LintCmd = fileCfg.LintCmd
+	}
+	if fileCfg.TestReport != "" && cfg.TestReport == "" {
+		cfg.TestReport = fileCfg.TestReport
+	}
+	if fileCfg.CoverageGate != "" && cfg.CoverageGate == "" {
+		cfg.CoverageGate = fileCfg.CoverageGate
+	}
+	if fileCfg.CoverageCmd != "" && cfg.CoverageCmd == "" {
+		cfg.CoverageCmd = fileCfg.CoverageCmd
+	}
+	if fileCfg.ValidateOnComplete && !cfg.ValidateOnComplete {
+		cfg.ValidateOnComplete = true
+	}
+	if fileCfg.ReportDir != "" && cfg.ReportDir == DefaultReportDir {
+		cfg.ReportDir = fileCfg.ReportDir
+	}
+	if fileCfg.ReportHTML && !cfg.ReportHTML {
+		cfg.ReportHTML = true
+	}
 
 	// Apply file paths
 	if fileCfg.Plan != "" && cfg.PlanFile == DefaultPlanFile {
@@ -322,11 +736,47 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.RecoveryStrategy != "" && cfg.RecoveryStrategy == DefaultRecoveryStrategy {
 		cfg.RecoveryStrategy = fileCfg.RecoveryStrategy
 	}
+	if fileCfg.IterationTimeout != "" && cfg.IterationTimeout == "" {
+		cfg.IterationTimeout = fileCfg.IterationTimeout
+	}
+	if fileCfg.TimeoutRetry && !cfg.TimeoutRetry {
+		cfg.TimeoutRetry = fileCfg.TimeoutRetry
+	}
+	if fileCfg.RetryBackoff != "" && cfg.RetryBackoff == "" {
+		cfg.RetryBackoff = fileCfg.RetryBackoff
+	}
+	if fileCfg.RetryBackoffMultiplier > 0 && cfg.RetryBackoffMultiplier == DefaultRetryBackoffMultiplier {
+		cfg.RetryBackoffMultiplier = fileCfg.RetryBackoffMultiplier
+	}
+	if fileCfg.RetryBackoffMax != "" && cfg.RetryBackoffMax == DefaultRetryBackoffMax {
+		cfg.RetryBackoffMax = fileCfg.RetryBackoffMax
+	}
+	if fileCfg.RetryJitter != nil && cfg.RetryJitter == DefaultRetryJitter {
+		cfg.RetryJitter = *fileCfg.RetryJitter
+	}
+	if fileCfg.MinIterationInterval != "" && cfg.MinIterationInterval == "" {
+		cfg.MinIterationInterval = fileCfg.MinIterationInterval
+	}
+	if fileCfg.RateLimitBackoff != "" && cfg.RateLimitBackoff == DefaultRateLimitBackoff {
+		cfg.RateLimitBackoff = fileCfg.RateLimitBackoff
+	}
+	if fileCfg.RateLimitRetries != nil && cfg.RateLimitRetries == DefaultRateLimitRetries {
+		cfg.RateLimitRetries = *fileCfg.RateLimitRetries
+	}
+	if fileCfg.RetryBudget > 0 && cfg.RetryBudget == 0 {
+		cfg.RetryBudget = fileCfg.RetryBudget
+	}
+	if len(fileCfg.FailurePatterns) > 0 && len(cfg.FailurePatterns) == 0 {
+		cfg.FailurePatterns = fileCfg.FailurePatterns
+	}
 
 	// Apply environment setting
 	if fileCfg.Environment != "" && cfg.Environment == "" {
 		cfg.Environment = fileCfg.Environment
 	}
+	if fileCfg.EnvironmentReports && !cfg.EnvironmentReports {
+		cfg.EnvironmentReports = fileCfg.EnvironmentReports
+	}
 
 	// Apply UI settings
 	if fileCfg.NoColor && !cfg.NoColor {
@@ -341,11 +791,20 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.LogLevel != "" && cfg.LogLevel == DefaultLogLevel {
 		cfg.LogLevel = fileCfg.LogLevel
 	}
+	if fileCfg.Stream && !cfg.Stream {
+		cfg.Stream = fileCfg.Stream
+	}
+	if fileCfg.Notify && !cfg.Notify {
+		cfg.Notify = fileCfg.Notify
+	}
 
 	// Apply memory settings
 	if fileCfg.MemoryFile != "" && cfg.MemoryFile == DefaultMemoryFile {
 		cfg.MemoryFile = fileCfg.MemoryFile
 	}
+	if fileCfg.NoGlobalMemory && !cfg.NoGlobalMemory {
+		cfg.NoGlobalMemory = fileCfg.NoGlobalMemory
+	}
 	if fileCfg.MemoryRetention > 0 && cfg.MemoryRetention == DefaultMemoryRetention {
 		cfg.MemoryRetention = fileCfg.MemoryRetention
 	}
@@ -354,6 +813,9 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.NudgeFile != "" && cfg.NudgeFile == DefaultNudgeFile {
 		cfg.NudgeFile = fileCfg.NudgeFile
 	}
+	if fileCfg.NoNudgeConsole && !cfg.NoNudgeConsole {
+		cfg.NoNudgeConsole = fileCfg.NoNudgeConsole
+	}
 
 	// Apply scope control settings
 	if fileCfg.ScopeLimit > 0 && cfg.ScopeLimit == DefaultScopeLimit {
@@ -362,11 +824,40 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.Deadline != "" && cfg.Deadline == "" {
 		cfg.Deadline = fileCfg.Deadline
 	}
+	if fileCfg.FeatureDeadline != "" && cfg.FeatureDeadline == "" {
+		cfg.FeatureDeadline = fileCfg.FeatureDeadline
+	}
+	if fileCfg.NoScopeCalibration && !cfg.NoScopeCalibration {
+		cfg.NoScopeCalibration = fileCfg.NoScopeCalibration
+	}
+
+	// Apply prompt settings
+	if fileCfg.MaxPromptSteps != nil && cfg.MaxPromptSteps == DefaultMaxPromptSteps {
+		cfg.MaxPromptSteps = *fileCfg.MaxPromptSteps
+	}
+	if fileCfg.IterationPromptTemplate != "" && cfg.IterationPromptTemplate == "" {
+		cfg.IterationPromptTemplate = fileCfg.IterationPromptTemplate
+	}
+	if fileCfg.PlanPromptTemplate != "" && cfg.PlanPromptTemplate == "" {
+		cfg.PlanPromptTemplate = fileCfg.PlanPromptTemplate
+	}
+	if fileCfg.GoalPromptTemplate != "" && cfg.GoalPromptTemplate == "" {
+		cfg.GoalPromptTemplate = fileCfg.GoalPromptTemplate
+	}
+	if fileCfg.ProgressTail > 0 && cfg.ProgressTail == 0 {
+		cfg.ProgressTail = fileCfg.ProgressTail
+	}
+	if fileCfg.FeaturePrompt && !cfg.FeaturePrompt {
+		cfg.FeaturePrompt = fileCfg.FeaturePrompt
+	}
 
 	// Apply replan settings
 	if fileCfg.AutoReplan && !cfg.AutoReplan {
 		cfg.AutoReplan = fileCfg.AutoReplan
 	}
+	if fileCfg.ReplanConfirm && !cfg.ReplanConfirm {
+		cfg.ReplanConfirm = fileCfg.ReplanConfirm
+	}
 	if fileCfg.ReplanStrategy != "" && cfg.ReplanStrategy == DefaultReplanStrategy {
 		cfg.ReplanStrategy = fileCfg.ReplanStrategy
 	}
@@ -374,6 +865,17 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 		cfg.ReplanThreshold = fileCfg.ReplanThreshold
 	}
 
+	// Apply plan backup settings
+	if fileCfg.PlanVersionDir != "" && cfg.PlanVersionDir == DefaultPlanVersionDir {
+		cfg.PlanVersionDir = fileCfg.PlanVersionDir
+	}
+	if fileCfg.MaxPlanVersions > 0 && cfg.MaxPlanVersions == 0 {
+		cfg.MaxPlanVersions = fileCfg.MaxPlanVersions
+	}
+	if fileCfg.CompressPlanVersions && !cfg.CompressPlanVersions {
+		cfg.CompressPlanVersions = fileCfg.CompressPlanVersions
+	}
+
 	// Apply goal settings
 	if fileCfg.GoalsFile != "" && cfg.GoalsFile == DefaultGoalsFile {
 		cfg.GoalsFile = fileCfg.GoalsFile
@@ -389,6 +891,165 @@ func ApplyFileConfig(cfg *Config, fileCfg *FileConfig) {
 	if fileCfg.EnableMultiAgent && !cfg.EnableMultiAgent {
 		cfg.EnableMultiAgent = fileCfg.EnableMultiAgent
 	}
+	if len(fileCfg.Agents) > 0 {
+		cfg.Agents = fileCfg.Agents
+	}
+	if fileCfg.ReviewGate && !cfg.ReviewGate {
+		cfg.ReviewGate = fileCfg.ReviewGate
+	}
+
+	// Apply context staleness settings
+	if fileCfg.ContextMaxAge != nil && cfg.ContextMaxAge == DefaultContextMaxAge {
+		cfg.ContextMaxAge = *fileCfg.ContextMaxAge
+	}
+	if fileCfg.ContextMaxChanges != nil && cfg.ContextMaxChanges == DefaultContextMaxChanges {
+		cfg.ContextMaxChanges = *fileCfg.ContextMaxChanges
+	}
+	if fileCfg.RefreshStaleContext && !cfg.RefreshStaleContext {
+		cfg.RefreshStaleContext = fileCfg.RefreshStaleContext
+	}
+	if fileCfg.NoImpactAnalysis && !cfg.NoImpactAnalysis {
+		cfg.NoImpactAnalysis = fileCfg.NoImpactAnalysis
+	}
+
+	// Apply run history settings
+	if fileCfg.HistoryDir != "" && cfg.HistoryDir == DefaultHistoryDir {
+		cfg.HistoryDir = fileCfg.HistoryDir
+	}
+	if fileCfg.JUnitOutput != "" && cfg.JUnitOutput == "" {
+		cfg.JUnitOutput = fileCfg.JUnitOutput
+	}
+	if fileCfg.SummaryMarkdown != "" && cfg.SummaryMarkdown == "" {
+		cfg.SummaryMarkdown = fileCfg.SummaryMarkdown
+	}
+
+	// Apply changelog settings
+	if fileCfg.ChangelogFile != "" && cfg.ChangelogFile == "" {
+		cfg.ChangelogFile = fileCfg.ChangelogFile
+	}
+	if fileCfg.ChangelogFormat != "" && cfg.ChangelogFormat == DefaultChangelogFormat {
+		cfg.ChangelogFormat = fileCfg.ChangelogFormat
+	}
+
+	// Apply iteration diff settings
+	if fileCfg.DiffDir != "" && cfg.DiffDir == DefaultDiffDir {
+		cfg.DiffDir = fileCfg.DiffDir
+	}
+
+	// Apply transcript settings
+	if fileCfg.Transcript && !cfg.Transcript {
+		cfg.Transcript = fileCfg.Transcript
+	}
+	if fileCfg.TranscriptDir != "" && cfg.TranscriptDir == DefaultTranscriptDir {
+		cfg.TranscriptDir = fileCfg.TranscriptDir
+	}
+	if len(fileCfg.TranscriptRedact) > 0 && len(cfg.TranscriptRedact) == 0 {
+		cfg.TranscriptRedact = fileCfg.TranscriptRedact
+	}
+
+	// Apply telemetry settings
+	if fileCfg.Telemetry && !cfg.Telemetry {
+		cfg.Telemetry = fileCfg.Telemetry
+	}
+	if fileCfg.TelemetryFile != "" && cfg.TelemetryFile == DefaultTelemetryFile {
+		cfg.TelemetryFile = fileCfg.TelemetryFile
+	}
+
+	// Apply checkpoint settings
+	if fileCfg.CheckpointDir != "" && cfg.CheckpointDir == DefaultCheckpointDir {
+		cfg.CheckpointDir = fileCfg.CheckpointDir
+	}
+
+	// Apply flaky test settings
+	if fileCfg.FlakyFile != "" && cfg.FlakyFile == DefaultFlakyFile {
+		cfg.FlakyFile = fileCfg.FlakyFile
+	}
+
+	// Apply API backend settings
+	if fileCfg.Backend != "" && cfg.AgentBackend == DefaultAgentBackend {
+		cfg.AgentBackend = fileCfg.Backend
+	}
+	if fileCfg.APIBaseURL != "" && cfg.APIBaseURL == "" {
+		cfg.APIBaseURL = fileCfg.APIBaseURL
+	}
+	if fileCfg.APIModel != "" && cfg.APIModel == "" {
+		cfg.APIModel = fileCfg.APIModel
+	}
+	if fileCfg.APIKeyEnv != "" && cfg.APIKeyEnv == "" {
+		cfg.APIKeyEnv = fileCfg.APIKeyEnv
+	}
+	if fileCfg.APIMaxTokens > 0 && cfg.APIMaxTokens == 0 {
+		cfg.APIMaxTokens = fileCfg.APIMaxTokens
+	}
+	if fileCfg.APIInputCost > 0 && cfg.APIInputCost == 0 {
+		cfg.APIInputCost = fileCfg.APIInputCost
+	}
+	if fileCfg.APIOutputCost > 0 && cfg.APIOutputCost == 0 {
+		cfg.APIOutputCost = fileCfg.APIOutputCost
+	}
+
+	// Apply experiment settings
+	if fileCfg.Experiment && !cfg.Experiment {
+		cfg.Experiment = fileCfg.Experiment
+	}
+	if fileCfg.ExperimentAgentB != "" && cfg.ExperimentAgentB == "" {
+		cfg.ExperimentAgentB = fileCfg.ExperimentAgentB
+	}
+	if fileCfg.ExperimentPromptA != "" && cfg.ExperimentPromptA == "" {
+		cfg.ExperimentPromptA = fileCfg.ExperimentPromptA
+	}
+	if fileCfg.ExperimentPromptB != "" && cfg.ExperimentPromptB == "" {
+		cfg.ExperimentPromptB = fileCfg.ExperimentPromptB
+	}
+	if fileCfg.ExperimentSplit != "" && cfg.ExperimentSplit == DefaultExperimentSplit {
+		cfg.ExperimentSplit = fileCfg.ExperimentSplit
+	}
+
+	// Apply safety settings
+	if fileCfg.NoPathGuard && !cfg.NoPathGuard {
+		cfg.NoPathGuard = fileCfg.NoPathGuard
+	}
+	if fileCfg.IsolatedWorktree && !cfg.IsolatedWorktree {
+		cfg.IsolatedWorktree = fileCfg.IsolatedWorktree
+	}
+	if fileCfg.Parallel > 0 && cfg.Parallel == 0 {
+		cfg.Parallel = fileCfg.Parallel
+	}
+	if fileCfg.Hooks != nil && cfg.Hooks.IsEmpty() {
+		cfg.Hooks = *fileCfg.Hooks
+	}
+	if fileCfg.Tracker != nil && cfg.Tracker == nil {
+		cfg.Tracker = fileCfg.Tracker
+	}
+	if fileCfg.Approve && !cfg.Approve {
+		cfg.Approve = fileCfg.Approve
+	}
+	if fileCfg.PolicyFile != "" && cfg.PolicyFile == "" {
+		cfg.PolicyFile = fileCfg.PolicyFile
+	}
+	if len(fileCfg.GuardPaths) > 0 && cfg.GuardPaths == "" {
+		cfg.GuardPaths = strings.Join(fileCfg.GuardPaths, ",")
+	}
+	if fileCfg.NoRedact && !cfg.NoRedact {
+		cfg.NoRedact = fileCfg.NoRedact
+	}
+	if len(fileCfg.RedactPatterns) > 0 && len(cfg.RedactPatterns) == 0 {
+		cfg.RedactPatterns = fileCfg.RedactPatterns
+	}
+}
+
+// MergeAgentEnv adds agent environment variables from the config file that
+// have not already been set (e.g., by -agent-env flags)
+func MergeAgentEnv(cfg *Config, env map[string]string) {
+	for name, value := range env {
+		if _, ok := cfg.AgentEnv[name]; ok {
+			continue
+		}
+		if cfg.AgentEnv == nil {
+			cfg.AgentEnv = make(map[string]string)
+		}
+		cfg.AgentEnv[name] = value
+	}
 }
 
 // parseDuration parses a duration string like "1h", "30m", "2h30m"
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 // TestDiscoverConfigFileCurrentDir tests config file discovery in current directory
@@ -303,6 +304,82 @@ func TestValidateFileConfigInvalid(t *testing.T) {
 			name: "Negative iterations",
 			cfg:  FileConfig{Iterations: -1},
 		},
+		{
+			name: "Invalid backend",
+			cfg:  FileConfig{Backend: "grpc"},
+		},
+		{
+			name: "Negative API max tokens",
+			cfg:  FileConfig{Backend: "openai", APIMaxTokens: -1},
+		},
+		{
+			name: "Invalid agent env name",
+			cfg:  FileConfig{AgentEnv: map[string]string{"BAD=NAME": "x"}},
+		},
+		{
+			name: "Invalid iteration timeout",
+			cfg:  FileConfig{IterationTimeout: "soon"},
+		},
+		{
+			name: "Non-positive iteration timeout",
+			cfg:  FileConfig{IterationTimeout: "0s"},
+		},
+		{
+			name: "Invalid retry backoff",
+			cfg:  FileConfig{RetryBackoff: "later"},
+		},
+		{
+			name: "Retry jitter above 1",
+			cfg:  FileConfig{RetryJitter: func() *float64 { v := 1.5; return &v }()},
+		},
+		{
+			name: "Negative retry budget",
+			cfg:  FileConfig{RetryBudget: -1},
+		},
+		{
+			name: "Negative max prompt steps",
+			cfg:  FileConfig{MaxPromptSteps: func() *int { v := -1; return &v }()},
+		},
+		{
+			name: "Negative context max age",
+			cfg:  FileConfig{ContextMaxAge: func() *int { v := -1; return &v }()},
+		},
+		{
+			name: "Failure pattern without pattern",
+			cfg:  FileConfig{FailurePatterns: []FailurePattern{{Name: "empty"}}},
+		},
+		{
+			name: "Invalid failure pattern regex",
+			cfg:  FileConfig{FailurePatterns: []FailurePattern{{Pattern: "("}}},
+		},
+		{
+			name: "Invalid failure pattern severity",
+			cfg:  FileConfig{FailurePatterns: []FailurePattern{{Pattern: "oops", Severity: "critical"}}},
+		},
+		{
+			name: "Invalid failure pattern type",
+			cfg:  FileConfig{FailurePatterns: []FailurePattern{{Pattern: "oops", Type: "style"}}},
+		},
+		{
+			name: "Empty hook command",
+			cfg:  FileConfig{Hooks: &Hooks{OnFailure: []string{" "}}},
+		},
+		{
+			name: "Invalid hook timeout",
+			cfg:  FileConfig{Hooks: &Hooks{PreIteration: []string{"make warm"}, Timeout: "0s"}},
+		},
+		{
+			name: "Invalid tracker provider",
+			cfg:  FileConfig{Tracker: &Tracker{Provider: "gitlab", Project: "o/r"}},
+		},
+		{
+			name: "Tracker without project",
+			cfg:  FileConfig{Tracker: &Tracker{Provider: "github"}},
+		},
+		{
+			name: "Jira tracker without URL",
+			cfg:  FileConfig{Tracker: &Tracker{Provider: "jira", Project: "PROJ"}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -356,6 +433,91 @@ func TestApplyFileConfig(t *testing.T) {
 	}
 }
 
+// TestLoadConfigFileHooks tests loading hooks and applying them
+func TestLoadConfigFileHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ralph.yaml")
+	content := `
+hooks:
+  pre_iteration:
+    - ./scripts/warm-cache.sh
+  on_complete:
+    - gh issue close 12
+    - echo done
+  on_milestone_complete:
+    - ralph -changelog -changelog-file CHANGELOG.md
+  timeout: 30s
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileCfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := New()
+	ApplyFileConfig(cfg, fileCfg)
+	if len(cfg.Hooks.PreIteration) != 1 || len(cfg.Hooks.OnComplete) != 2 || cfg.Hooks.OnComplete[0] != "gh issue close 12" || len(cfg.Hooks.OnMilestoneComplete) != 1 {
+		t.Errorf("Hooks = %+v", cfg.Hooks)
+	}
+	if cfg.HookTimeoutDuration() != 30*time.Second {
+		t.Errorf("HookTimeoutDuration() = %s, want 30s", cfg.HookTimeoutDuration())
+	}
+}
+
+// TestLoadConfigFileTracker tests loading the issue tracker and applying it
+func TestLoadConfigFileTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ralph.yaml")
+	content := `
+tracker:
+  provider: github
+  project: acme/shop
+  token: env:SHOP_TOKEN
+  labels: [ralph]
+  categories:
+    bug: fix
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileCfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := New()
+	ApplyFileConfig(cfg, fileCfg)
+	if cfg.Tracker == nil || cfg.Tracker.Project != "acme/shop" || cfg.Tracker.Token != "env:SHOP_TOKEN" || cfg.Tracker.Categories["bug"] != "fix" {
+		t.Errorf("Tracker = %+v", cfg.Tracker)
+	}
+}
+
+// TestApplyFileConfigRetryBackoff tests that a zero jitter in the file is applied
+func TestApplyFileConfigRetryBackoff(t *testing.T) {
+	cfg := New()
+	noJitter := 0.0
+	ApplyFileConfig(cfg, &FileConfig{RetryBackoff: "30s", RetryBackoffMax: "2m", RetryJitter: &noJitter, RetryBudget: 10})
+
+	if cfg.RetryBackoffDuration() != 30*time.Second || cfg.RetryBackoffMaxDuration() != 2*time.Minute {
+		t.Errorf("backoff = %s up to %s, want 30s up to 2m", cfg.RetryBackoffDuration(), cfg.RetryBackoffMaxDuration())
+	}
+	if cfg.RetryJitter != 0 {
+		t.Errorf("RetryJitter = %v, want 0", cfg.RetryJitter)
+	}
+	if cfg.RetryBackoffMultiplier != DefaultRetryBackoffMultiplier || cfg.RetryBudget != 10 {
+		t.Errorf("multiplier = %v, budget = %d", cfg.RetryBackoffMultiplier, cfg.RetryBudget)
+	}
+}
+
+// TestApplyFileConfigMaxPromptSteps tests that a zero step limit in the file disables truncation
+func TestApplyFileConfigMaxPromptSteps(t *testing.T) {
+	cfg := New()
+	unlimited := 0
+	ApplyFileConfig(cfg, &FileConfig{MaxPromptSteps: &unlimited})
+
+	if cfg.MaxPromptSteps != 0 {
+		t.Errorf("MaxPromptSteps = %d, want 0", cfg.MaxPromptSteps)
+	}
+}
+
 // TestApplyFileConfigDoesNotOverrideExisting tests that existing values are not overridden
 func TestApplyFileConfigDoesNotOverrideExisting(t *testing.T) {
 	cfg := New()
@@ -475,3 +637,25 @@ iterations: 7
 		t.Errorf("TypeCheck = %q, want empty", cfg.TypeCheck)
 	}
 }
+
+func TestIterationTimeoutDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"15m", 15 * time.Minute, false},
+		{"soon", 0, true},
+		{"0s", 0, true},
+		{"-1m", 0, true},
+	}
+	for _, tt := range tests {
+		cfg := New()
+		cfg.IterationTimeout = tt.value
+		got, err := cfg.IterationTimeoutDuration()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("IterationTimeoutDuration(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
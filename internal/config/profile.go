@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey is the config file key holding the profiles
+const profilesKey = "profiles"
+
+// ProfileNames returns the names of the profiles in a config file, sorted
+func (f *FileConfig) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns a copy of the config with the settings of the named
+// profile overlaid. Keys the profile does not set keep their value; maps,
+// such as agent_env, are merged.
+func (f *FileConfig) WithProfile(name string) (*FileConfig, error) {
+	profile, ok := f.Profiles[name]
+	if !ok {
+		if len(f.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q: the config file has no profiles", name)
+		}
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(f.ProfileNames(), ", "))
+	}
+	if _, ok := profile[profilesKey]; ok {
+		return nil, fmt.Errorf("profile %q: profiles cannot be nested", name)
+	}
+
+	data, err := yaml.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q: %w", name, err)
+	}
+	merged := *f
+	if f.AgentEnv != nil {
+		// Merged into a copy, so the base config is left as it is
+		merged.AgentEnv = make(map[string]string, len(f.AgentEnv))
+		for k, v := range f.AgentEnv {
+			merged.AgentEnv[k] = v
+		}
+	}
+	if f.Hooks != nil {
+		hooks := *f.Hooks
+		merged.Hooks = &hooks
+	}
+	if f.Tracker != nil {
+		tracker := *f.Tracker
+		if f.Tracker.Categories != nil {
+			tracker.Categories = make(map[string]string, len(f.Tracker.Categories))
+			for k, v := range f.Tracker.Categories {
+				tracker.Categories[k] = v
+			}
+		}
+		merged.Tracker = &tracker
+	}
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	merged.Profiles = nil
+	return &merged, nil
+}
+
+// ProfileKeys returns the keys the named profile sets
+func (f *FileConfig) ProfileKeys(name string) []string {
+	keys := make([]string, 0, len(f.Profiles[name]))
+	for key := range f.Profiles[name] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithProfile(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+	}{
+		{".ralph.yaml", `
+agent: claude
+scope_limit: 4
+plan: tasks.json
+agent_env:
+  A: x
+profiles:
+  ci:
+    scope_limit: 2
+    quiet: true
+    agent_env:
+      B: y
+  local:
+    verbose: true
+`},
+		{".ralph.json", `{
+  "agent": "claude", "scope_limit": 4, "plan": "tasks.json", "agent_env": {"A": "x"},
+  "profiles": {
+    "ci": {"scope_limit": 2, "quiet": true, "agent_env": {"B": "y"}},
+    "local": {"verbose": true}
+  }
+}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			base, err := LoadConfigFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(base.ProfileNames(), ","); got != "ci,local" {
+				t.Errorf("ProfileNames() = %s", got)
+			}
+
+			ci, err := base.WithProfile("ci")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ci.ScopeLimit != 2 || !ci.Quiet || ci.Agent != "claude" || ci.Plan != "tasks.json" || ci.Verbose {
+				t.Errorf("ci profile = %+v", ci)
+			}
+			if len(ci.AgentEnv) != 2 || len(base.AgentEnv) != 1 {
+				t.Errorf("agent_env = %v, base %v", ci.AgentEnv, base.AgentEnv)
+			}
+			if ci.Profiles != nil {
+				t.Error("profiles kept after applying one")
+			}
+			if base.ScopeLimit != 4 {
+				t.Error("WithProfile() modified the base config")
+			}
+			if got := strings.Join(base.ProfileKeys("ci"), ","); got != "agent_env,quiet,scope_limit" {
+				t.Errorf("ProfileKeys() = %s", got)
+			}
+
+			if _, err := base.WithProfile("nightly"); err == nil || !strings.Contains(err.Error(), "available: ci, local") {
+				t.Errorf("WithProfile() of an unknown profile = %v", err)
+			}
+		})
+	}
+}
+
+func TestWithProfileErrors(t *testing.T) {
+	if _, err := (&FileConfig{}).WithProfile("ci"); err == nil {
+		t.Error("WithProfile() accepted a config without profiles")
+	}
+	f := &FileConfig{Profiles: map[string]map[string]any{
+		"nested": {"profiles": map[string]any{"ci": map[string]any{}}},
+		"bad":    {"scope_limit": "many"},
+	}}
+	if _, err := f.WithProfile("nested"); err == nil {
+		t.Error("WithProfile() accepted nested profiles")
+	}
+	if _, err := f.WithProfile("bad"); err == nil {
+		t.Error("WithProfile() accepted a non-numeric scope_limit")
+	}
+}
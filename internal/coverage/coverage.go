@@ -0,0 +1,236 @@
+// Package coverage measures the test coverage of a project, enforces a
+// minimum coverage gate and tracks how coverage changes over a run.
+package coverage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/logimos/ralph/internal/proc"
+)
+
+// DefaultProfile is where the default Go coverage command writes its profile
+const DefaultProfile = ".ralph/coverage.out"
+
+// DefaultCommands are the coverage commands of the build systems that can
+// report coverage without extra project configuration
+var DefaultCommands = map[string]string{
+	"go":     "go test -coverprofile=" + DefaultProfile + " ./...",
+	"npm":    "npm test -- --coverage --coverageReporters=text-summary",
+	"yarn":   "yarn test --coverage --coverageReporters=text-summary",
+	"pnpm":   "pnpm test -- --coverage --coverageReporters=text-summary",
+	"python": "pytest --cov --cov-report=term",
+	"cargo":  "cargo tarpaulin",
+}
+
+// totalPatterns find the total coverage percentage in the output of
+// coverage tools, in order of preference
+var totalPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^total:\s+\(statements\)\s+([\d.]+)%`),      // go tool cover -func
+	regexp.MustCompile(`(?m)^Statements\s*:\s*([\d.]+)%`),               // Jest/Istanbul text-summary
+	regexp.MustCompile(`(?m)^All files\s*\|\s*([\d.]+)`),                // Jest/Istanbul text table
+	regexp.MustCompile(`(?m)^TOTAL\s+(?:\S+\s+)*?([\d.]+)%\s*$`),        // coverage.py / pytest-cov
+	regexp.MustCompile(`(?m)([\d.]+)% coverage, \d+/\d+ lines covered`), // cargo tarpaulin
+	regexp.MustCompile(`(?m)Total coverage:\s*([\d.]+)%`),               // Generic
+}
+
+// profileFlag finds the Go coverage profile a command writes
+var profileFlag = regexp.MustCompile(`-coverprofile[= ](\S+)`)
+
+// ParseTotal returns the total coverage percentage reported in the output of
+// a coverage command
+func ParseTotal(output string) (float64, error) {
+	for _, re := range totalPatterns {
+		if m := re.FindStringSubmatch(output); m != nil {
+			if total, err := strconv.ParseFloat(m[1], 64); err == nil {
+				return total, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no total coverage found in the output")
+}
+
+// ParseProfile returns the percentage of statements covered by a Go coverage
+// profile. Blocks listed more than once (e.g., with -coverpkg) count once, as
+// covered if any listing covers them.
+func ParseProfile(path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read coverage profile: %w", err)
+	}
+	defer f.Close()
+
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := make(map[string]*block)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// file.go:line.col,line.col statements count
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		statements, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		b := blocks[fields[0]]
+		if b == nil {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read coverage profile: %w", err)
+	}
+
+	total, covered := 0, 0
+	for _, b := range blocks {
+		total += b.statements
+		if b.covered {
+			covered += b.statements
+		}
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("coverage profile %s lists no statements", path)
+	}
+	return 100 * float64(covered) / float64(total), nil
+}
+
+// Measure runs a coverage command and returns the total coverage with the
+// command's output. A Go command that writes a coverage profile
+// (-coverprofile) is measured from the profile.
+func Measure(ctx context.Context, command string) (float64, string, error) {
+	cmd := proc.CommandLine(ctx, command)
+	if cmd == nil {
+		return 0, "", fmt.Errorf("no coverage command")
+	}
+
+	profile := ""
+	if m := profileFlag.FindStringSubmatch(command); m != nil {
+		profile = m[1]
+		if err := os.MkdirAll(filepath.Dir(profile), 0755); err != nil {
+			return 0, "", fmt.Errorf("failed to create coverage profile directory: %w", err)
+		}
+		os.Remove(profile)
+	}
+
+	out, err := cmd.CombinedOutput()
+	output := string(out)
+	if err != nil {
+		return 0, output, fmt.Errorf("coverage command failed: %w", err)
+	}
+
+	if total, err := ParseTotal(output); err == nil {
+		return total, output, nil
+	}
+	if profile != "" {
+		total, err := ParseProfile(profile)
+		return total, output, err
+	}
+	return 0, output, fmt.Errorf("no total coverage found in the output of %q", command)
+}
+
+// ParseGate parses a coverage percentage such as "80%" or "80"
+func ParseGate(s string) (float64, error) {
+	value := strings.TrimSuffix(strings.TrimSpace(s), "%")
+	gate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || gate < 0 || gate > 100 || math.IsNaN(gate) {
+		return 0, fmt.Errorf("invalid coverage gate %q: must be a percentage between 0 and 100 (e.g., 80%%)", s)
+	}
+	return gate, nil
+}
+
+// Gate blocks coverage regressions. Coverage must stay at or above the
+// minimum, or, in a project whose coverage was already below the minimum,
+// must not drop below the coverage it started with.
+type Gate struct {
+	Min      float64 // Minimum total coverage percentage
+	Baseline float64 // Coverage at the start of the run (negative if unknown)
+}
+
+// NewGate creates a gate with the given minimum and no baseline yet
+func NewGate(min float64) *Gate {
+	return &Gate{Min: min, Baseline: -1}
+}
+
+// Required returns the coverage a measurement must reach to pass the gate
+func (g *Gate) Required() float64 {
+	if g.Baseline >= 0 && g.Baseline < g.Min {
+		return g.Baseline
+	}
+	return g.Min
+}
+
+// Check returns an error describing the regression if total fails the gate
+func (g *Gate) Check(total float64) error {
+	required := g.Required()
+	// Compare at the precision coverage is reported, so rounding noise
+	// between two runs of the same tests does not fail the gate
+	if math.Round(total*10) >= math.Round(required*10) {
+		return nil
+	}
+	if required < g.Min {
+		return fmt.Errorf("total coverage %.1f%% dropped below the %.1f%% it started at (gate %.1f%%)", total, required, g.Min)
+	}
+	return fmt.Errorf("total coverage %.1f%% is below the %.1f%% gate", total, g.Min)
+}
+
+// Point is one coverage measurement of a run
+type Point struct {
+	Iteration int     // Iteration after which coverage was measured (0 = start of the run)
+	Total     float64 // Total coverage percentage
+	Passed    bool    // Whether the measurement passed the gate
+}
+
+// Trend is the sequence of coverage measurements of a run
+type Trend struct {
+	Points []Point
+}
+
+// Record adds a measurement to the trend
+func (t *Trend) Record(iteration int, total float64, passed bool) {
+	t.Points = append(t.Points, Point{Iteration: iteration, Total: total, Passed: passed})
+}
+
+// Summary describes how coverage changed over the run, or returns "" if
+// coverage was never measured
+func (t *Trend) Summary() string {
+	if len(t.Points) == 0 {
+		return ""
+	}
+	first, last := t.Points[0], t.Points[len(t.Points)-1]
+	if len(t.Points) == 1 {
+		return fmt.Sprintf("Coverage: %.1f%%", first.Total)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Coverage: %.1f%% -> %.1f%% (%+.1f)\n", first.Total, last.Total, last.Total-first.Total)
+	for _, p := range t.Points {
+		label := "start"
+		if p.Iteration > 0 {
+			label = fmt.Sprintf("iteration %d", p.Iteration)
+		}
+		status := ""
+		if !p.Passed {
+			status = " (blocked)"
+		}
+		fmt.Fprintf(&sb, "  %-14s %5.1f%%%s\n", label+":", p.Total, status)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,138 @@
+package coverage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTotal(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   float64
+	}{
+		{"go tool cover", "example.com/app/sum.go:3:\tSum\t100.0%\ntotal:\t\t\t\t(statements)\t\t76.9%\n", 76.9},
+		{"jest text-summary", "=============================== Coverage summary ===============================\nStatements   : 85.71% ( 12/14 )\nBranches     : 50% ( 1/2 )\nLines        : 84.61% ( 11/13 )\n", 85.71},
+		{"jest table", "----------|---------|----------|\nFile      | % Stmts | % Branch |\n----------|---------|----------|\nAll files |   91.3  |    75    |\n", 91.3},
+		{"pytest-cov", "Name          Stmts   Miss  Cover\n---------------------------------\napp/sum.py       10      2    80%\n---------------------------------\nTOTAL            10      2    80%\n", 80},
+		{"pytest-cov with branches", "TOTAL   120   10   40    6   88.75%\n", 88.75},
+		{"tarpaulin", "|| src/lib.rs: 17/20\n85.00% coverage, 17/20 lines covered\n", 85},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTotal(tt.output)
+			if err != nil || got != tt.want {
+				t.Errorf("ParseTotal() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseTotal("ok  \texample.com/app\t0.01s\tcoverage: 80.0% of statements\n"); err == nil {
+		t.Error("ParseTotal() of per-package coverage succeeded")
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coverage.out")
+	profile := `mode: set
+example.com/app/sum.go:3.24,5.2 2 1
+example.com/app/sum.go:7.28,9.2 2 0
+example.com/app/sum.go:11.30,14.2 4 0
+example.com/app/sum.go:11.30,14.2 4 1
+`
+	if err := os.WriteFile(path, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The duplicated block counts once, as covered
+	got, err := ParseProfile(path)
+	if err != nil || got != 75 {
+		t.Errorf("ParseProfile() = %v, %v; want 75", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte("mode: set\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseProfile(path); err == nil {
+		t.Error("ParseProfile() of an empty profile succeeded")
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	total, output, err := Measure(context.Background(), "echo Total coverage: 64.2%")
+	if err != nil || total != 64.2 || !strings.Contains(output, "64.2%") {
+		t.Errorf("Measure() = %v, %q, %v; want 64.2", total, output, err)
+	}
+
+	if _, _, err := Measure(context.Background(), "echo no coverage here"); err == nil {
+		t.Error("Measure() without a total succeeded")
+	}
+	if _, _, err := Measure(context.Background(), ""); err == nil {
+		t.Error("Measure() without a command succeeded")
+	}
+}
+
+func TestParseGate(t *testing.T) {
+	for input, want := range map[string]float64{"80%": 80, "80": 80, " 72.5 % ": 72.5, "0": 0, "100%": 100} {
+		if got, err := ParseGate(input); err != nil || got != want {
+			t.Errorf("ParseGate(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "high", "-5%", "101%", "NaN"} {
+		if _, err := ParseGate(input); err == nil {
+			t.Errorf("ParseGate(%q) succeeded", input)
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	gate := NewGate(80)
+	if gate.Required() != 80 {
+		t.Errorf("Required() without a baseline = %v, want 80", gate.Required())
+	}
+	if err := gate.Check(80.04); err != nil {
+		t.Errorf("Check(80.04) = %v, want nil", err)
+	}
+	if err := gate.Check(79.9); err == nil || !strings.Contains(err.Error(), "below the 80.0% gate") {
+		t.Errorf("Check(79.9) = %v", err)
+	}
+
+	// A project starting above the gate must stay above the gate
+	gate.Baseline = 90
+	if gate.Required() != 80 || gate.Check(85) != nil {
+		t.Errorf("with baseline 90: Required() = %v, Check(85) = %v", gate.Required(), gate.Check(85))
+	}
+
+	// A project starting below the gate must not regress further
+	gate.Baseline = 60
+	if gate.Required() != 60 || gate.Check(65) != nil {
+		t.Errorf("with baseline 60: Required() = %v, Check(65) = %v", gate.Required(), gate.Check(65))
+	}
+	if err := gate.Check(58); err == nil || !strings.Contains(err.Error(), "dropped below the 60.0% it started at") {
+		t.Errorf("Check(58) with baseline 60 = %v", err)
+	}
+}
+
+func TestTrendSummary(t *testing.T) {
+	var trend Trend
+	if trend.Summary() != "" {
+		t.Error("Summary() without measurements is not empty")
+	}
+
+	trend.Record(0, 71.2, true)
+	if got := trend.Summary(); got != "Coverage: 71.2%" {
+		t.Errorf("Summary() = %q", got)
+	}
+
+	trend.Record(2, 68, false)
+	trend.Record(3, 80.5, true)
+	got := trend.Summary()
+	for _, want := range []string{"Coverage: 71.2% -> 80.5% (+9.3)", "start:", "iteration 2:", "68.0% (blocked)", "iteration 3:"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Summary() missing %q:\n%s", want, got)
+		}
+	}
+}
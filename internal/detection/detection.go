@@ -12,6 +12,7 @@ import (
 type BuildSystemPreset struct {
 	TypeCheck string
 	Test      string
+	Lint      string // Optional lint step, run only when enabled with -lint
 }
 
 // BuildSystemPresets defines commands for common build systems
@@ -19,34 +20,42 @@ var BuildSystemPresets = map[string]BuildSystemPreset{
 	"pnpm": {
 		TypeCheck: "pnpm typecheck",
 		Test:      "pnpm test",
+		Lint:      "pnpm lint",
 	},
 	"npm": {
 		TypeCheck: "npm run typecheck",
 		Test:      "npm test",
+		Lint:      "npx eslint .",
 	},
 	"yarn": {
 		TypeCheck: "yarn typecheck",
 		Test:      "yarn test",
+		Lint:      "yarn lint",
 	},
 	"gradle": {
 		TypeCheck: "./gradlew check",
 		Test:      "./gradlew test",
+		Lint:      "./gradlew spotlessCheck",
 	},
 	"maven": {
 		TypeCheck: "mvn compile",
 		Test:      "mvn test",
+		Lint:      "mvn checkstyle:check",
 	},
 	"cargo": {
 		TypeCheck: "cargo check",
 		Test:      "cargo test",
+		Lint:      "cargo clippy -- -D warnings",
 	},
 	"go": {
 		TypeCheck: "go build ./...",
 		Test:      "go test ./...",
+		Lint:      "golangci-lint run",
 	},
 	"python": {
 		TypeCheck: "mypy .",
 		Test:      "pytest",
+		Lint:      "ruff check .",
 	},
 }
 
@@ -108,6 +117,19 @@ func DetectBuildSystem() string {
 	return "pnpm"
 }
 
+// ApplyLintConfig sets the lint command from the build system preset when
+// the lint step is enabled (-lint) without an explicit lint command
+func ApplyLintConfig(cfg *config.Config) {
+	if !cfg.Lint || cfg.LintCmd != "" {
+		return
+	}
+	buildSystem := cfg.BuildSystem
+	if buildSystem == "" || buildSystem == "auto" {
+		buildSystem = DetectBuildSystem()
+	}
+	cfg.LintCmd = BuildSystemPresets[buildSystem].Lint
+}
+
 // ApplyBuildSystemConfig applies build system presets or auto-detection
 func ApplyBuildSystemConfig(cfg *config.Config) {
 	// If both typecheck and test are explicitly set, don't override
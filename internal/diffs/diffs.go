@@ -0,0 +1,197 @@
+// Package diffs stores the patch of every iteration so that exactly what the
+// agent changed in each step can be audited, and reapplied or reverted later.
+package diffs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	filePrefix = "iteration-"
+	fileSuffix = ".patch"
+)
+
+// Store keeps iteration patches in one directory per run:
+// <dir>/<run-id>/iteration-NNN.patch
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store rooted at dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the store's root directory
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Path returns the patch file path of an iteration
+func (s *Store) Path(runID string, iteration int) string {
+	return filepath.Join(s.dir, runID, fmt.Sprintf("%s%03d%s", filePrefix, iteration, fileSuffix))
+}
+
+// Save writes an iteration's patch and returns its path. An empty patch is
+// saved too, recording that the iteration changed nothing.
+func (s *Store) Save(runID string, iteration int, patch string) (string, error) {
+	path := s.Path(runID, iteration)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create diff directory: %w", err)
+	}
+	if patch != "" && !strings.HasSuffix(patch, "\n") {
+		patch += "\n"
+	}
+	if err := os.WriteFile(path, []byte(patch), 0644); err != nil {
+		return "", fmt.Errorf("failed to write diff: %w", err)
+	}
+	return path, nil
+}
+
+// Load reads an iteration's patch
+func (s *Store) Load(runID string, iteration int) (string, error) {
+	data, err := os.ReadFile(s.Path(runID, iteration))
+	if os.IsNotExist(err) {
+		available, _ := s.Iterations(runID)
+		if len(available) == 0 {
+			return "", fmt.Errorf("no diffs recorded for run %s", runID)
+		}
+		return "", fmt.Errorf("no diff recorded for iteration %d of run %s (recorded: %s)",
+			iteration, runID, formatIterations(available))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read diff: %w", err)
+	}
+	return string(data), nil
+}
+
+// Runs returns the IDs of runs with recorded diffs, oldest first
+func (s *Store) Runs() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read diff directory: %w", err)
+	}
+
+	var runs []string
+	for _, e := range entries {
+		if e.IsDir() {
+			runs = append(runs, e.Name())
+		}
+	}
+	// Run IDs embed their start time, so name order is chronological
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// LatestRun returns the ID of the most recent run with recorded diffs
+func (s *Store) LatestRun() (string, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return "", err
+	}
+	if len(runs) == 0 {
+		return "", fmt.Errorf("no iteration diffs recorded in %s", s.dir)
+	}
+	return runs[len(runs)-1], nil
+}
+
+// Iterations returns the iterations of a run with recorded diffs, in order
+func (s *Store) Iterations(runID string) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, runID))
+	if err != nil {
+		return nil, err
+	}
+
+	var iterations []int
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err == nil {
+			iterations = append(iterations, n)
+		}
+	}
+	sort.Ints(iterations)
+	return iterations, nil
+}
+
+// ChangedSince returns the files changed by the iterations whose patches were
+// saved after t, sorted
+func (s *Store) ChangedSince(t time.Time) ([]string, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool)
+	for _, run := range runs {
+		iterations, err := s.Iterations(run)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read diffs of run %s: %w", run, err)
+		}
+		for _, n := range iterations {
+			info, err := os.Stat(s.Path(run, n))
+			if err != nil || !info.ModTime().After(t) {
+				continue
+			}
+			patch, err := s.Load(run, n)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range Files(patch) {
+				changed[f] = true
+			}
+		}
+	}
+
+	files := make([]string, 0, len(changed))
+	for f := range changed {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Files returns the paths a patch touches, in order of appearance; both
+// sides of a rename are included
+func Files(patch string) []string {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	for _, line := range strings.Split(patch, "\n") {
+		rest, ok := strings.CutPrefix(line, "diff --git a/")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(rest, " b/"); i >= 0 {
+			add(rest[:i])
+			add(rest[i+len(" b/"):])
+		}
+	}
+	return files
+}
+
+// formatIterations joins iteration numbers for display
+func formatIterations(iterations []int) string {
+	parts := make([]string, len(iterations))
+	for i, n := range iterations {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,115 @@
+package diffs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "diffs"))
+
+	patch := "diff --git a/main.go b/main.go\n+// change"
+	path, err := store.Save("run-20260101-120000", 3, patch)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if filepath.Base(path) != "iteration-003.patch" {
+		t.Errorf("unexpected file name %s", path)
+	}
+
+	got, err := store.Load("run-20260101-120000", 3)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got != patch+"\n" {
+		t.Errorf("Load = %q", got)
+	}
+
+	// Iterations that changed nothing are recorded as empty patches
+	if _, err := store.Save("run-20260101-120000", 12, ""); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	iterations, err := store.Iterations("run-20260101-120000")
+	if err != nil || len(iterations) != 2 || iterations[0] != 3 || iterations[1] != 12 {
+		t.Errorf("Iterations = %v (%v)", iterations, err)
+	}
+
+	_, err = store.Load("run-20260101-120000", 4)
+	if err == nil || !strings.Contains(err.Error(), "recorded: 3, 12") {
+		t.Errorf("expected error listing recorded iterations, got %v", err)
+	}
+}
+
+func TestLatestRun(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	if _, err := store.LatestRun(); err == nil {
+		t.Error("expected error when no diffs are recorded")
+	}
+
+	for _, run := range []string{"run-20260102-090000", "run-20260101-120000"} {
+		if _, err := store.Save(run, 1, "patch"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a run"), 0644)
+
+	latest, err := store.LatestRun()
+	if err != nil {
+		t.Fatalf("LatestRun failed: %v", err)
+	}
+	if latest != "run-20260102-090000" {
+		t.Errorf("LatestRun = %s", latest)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	patch := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-package main
++package app
+diff --git a/old name.txt b/new name.txt
+similarity index 100%
+rename from old name.txt
+rename to new name.txt
+diff --git a/logo.png b/logo.png
+Binary files a/logo.png and b/logo.png differ
+`
+	if got, want := Files(patch), []string{"main.go", "old name.txt", "new name.txt", "logo.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+	if got := Files(""); got != nil {
+		t.Errorf("Files() of an empty patch = %v", got)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if files, err := store.ChangedSince(time.Now()); err != nil || len(files) != 0 {
+		t.Errorf("ChangedSince() without diffs = %v, %v", files, err)
+	}
+
+	old, _ := store.Save("run-20260101-120000", 1, "diff --git a/old.go b/old.go\n")
+	before := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(old, before.Add(-time.Hour), before.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	store.Save("run-20260102-090000", 1, "diff --git a/main.go b/main.go\n")
+	store.Save("run-20260102-090000", 2, "diff --git a/util.go b/util.go\ndiff --git a/main.go b/main.go\n")
+
+	files, err := store.ChangedSince(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"main.go", "util.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("ChangedSince() = %v, want %v", files, want)
+	}
+}
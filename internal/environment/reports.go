@@ -0,0 +1,142 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Report files written to the report directory when environment reports are
+// enabled.
+const (
+	// JUnitReportFile is the JUnit XML report, for the test reports of GitLab
+	// (artifacts:reports:junit) and Jenkins (the junit step)
+	JUnitReportFile = "junit.xml"
+	// DotenvReportFile holds the run outcome as variables for later GitLab
+	// jobs (artifacts:reports:dotenv)
+	DotenvReportFile = "ralph.env"
+	// PropertiesReportFile holds the run outcome as Java properties, for the
+	// readProperties step of Jenkins pipelines
+	PropertiesReportFile = "ralph.properties"
+)
+
+// MarkerPrefix starts the lines printed for Jenkins at the end of a run, so
+// that build steps and log parsers can find them.
+const MarkerPrefix = "[ralph]"
+
+// RunOutcome summarizes a finished run for the CI reports.
+type RunOutcome struct {
+	RunID             string
+	Iterations        int  // Iterations run
+	FeaturesCompleted int  // Features completed during the run
+	FeaturesTested    int  // Tested features of the plan
+	FeaturesTotal     int  // Features of the plan
+	Failures          int  // Failures detected during the run
+	PlanComplete      bool // Whether the completion signal was detected
+}
+
+// Variable is a named value of the run outcome.
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// Status returns "complete" if the plan is complete and "incomplete"
+// otherwise.
+func (o RunOutcome) Status() string {
+	if o.PlanComplete {
+		return "complete"
+	}
+	return "incomplete"
+}
+
+// Variables returns the run outcome as RALPH_* variables.
+func (o RunOutcome) Variables() []Variable {
+	return []Variable{
+		{"RALPH_RUN_ID", o.RunID},
+		{"RALPH_STATUS", o.Status()},
+		{"RALPH_ITERATIONS", strconv.Itoa(o.Iterations)},
+		{"RALPH_FEATURES_COMPLETED", strconv.Itoa(o.FeaturesCompleted)},
+		{"RALPH_FEATURES_TESTED", strconv.Itoa(o.FeaturesTested)},
+		{"RALPH_FEATURES_TOTAL", strconv.Itoa(o.FeaturesTotal)},
+		{"RALPH_FAILURES", strconv.Itoa(o.Failures)},
+	}
+}
+
+// SupportsReports returns true if Ralph writes reports for the environment:
+// GitLab CI and Jenkins.
+func (p *EnvironmentProfile) SupportsReports() bool {
+	return p.Type == EnvGitLabCI || p.Type == EnvJenkins
+}
+
+// WriteReports writes the variable files of the environment to dir: a dotenv
+// file for GitLab CI and a properties file for Jenkins. It returns the paths
+// written, none for other environments. The JUnit report is written by the
+// caller, which knows the test cases.
+func (p *EnvironmentProfile) WriteReports(dir string, outcome RunOutcome) ([]string, error) {
+	var name, content string
+	switch p.Type {
+	case EnvGitLabCI:
+		name, content = DotenvReportFile, Dotenv(outcome.Variables())
+	case EnvJenkins:
+		name, content = PropertiesReportFile, Properties(outcome.Variables())
+	default:
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return []string{path}, nil
+}
+
+// Markers returns the lines to print at the end of a run. Jenkins gets the
+// build description and result (SUCCESS if the plan is complete, UNSTABLE
+// otherwise), for the Description Setter plugin or a log parser to pick up;
+// other environments get none.
+func (p *EnvironmentProfile) Markers(outcome RunOutcome) []string {
+	if p.Type != EnvJenkins {
+		return nil
+	}
+	result := "UNSTABLE"
+	if outcome.PlanComplete {
+		result = "SUCCESS"
+	}
+	description := fmt.Sprintf("%d/%d features tested, %d completed in %d iteration(s)",
+		outcome.FeaturesTested, outcome.FeaturesTotal, outcome.FeaturesCompleted, outcome.Iterations)
+	return []string{
+		MarkerPrefix + " BUILD_DESCRIPTION: " + description,
+		MarkerPrefix + " BUILD_RESULT: " + result,
+	}
+}
+
+// Dotenv formats variables as a dotenv file. GitLab reads values verbatim
+// and allows no line breaks, so line breaks are replaced by spaces.
+func Dotenv(vars []Variable) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		sb.WriteString(v.Name)
+		sb.WriteString("=")
+		sb.WriteString(strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(v.Value))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Properties formats variables as a Java properties file.
+func Properties(vars []Variable) string {
+	escaper := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "=", `\=`, ":", `\:`)
+	var sb strings.Builder
+	for _, v := range vars {
+		sb.WriteString(v.Name)
+		sb.WriteString("=")
+		sb.WriteString(escaper.Replace(v.Value))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testOutcome() RunOutcome {
+	return RunOutcome{
+		RunID:             "20261016-101500",
+		Iterations:        6,
+		FeaturesCompleted: 2,
+		FeaturesTested:    5,
+		FeaturesTotal:     8,
+		Failures:          1,
+	}
+}
+
+func TestWriteReports_GitLabCI(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	profile := &EnvironmentProfile{Type: EnvGitLabCI}
+
+	paths, err := profile.WriteReports(dir, testOutcome())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, DotenvReportFile)}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("WriteReports() = %v, want %v", paths, want)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `RALPH_RUN_ID=20261016-101500
+RALPH_STATUS=incomplete
+RALPH_ITERATIONS=6
+RALPH_FEATURES_COMPLETED=2
+RALPH_FEATURES_TESTED=5
+RALPH_FEATURES_TOTAL=8
+RALPH_FAILURES=1
+`
+	if string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", DotenvReportFile, data, want)
+	}
+	if markers := profile.Markers(testOutcome()); len(markers) != 0 {
+		t.Errorf("Markers() = %v, want none for GitLab CI", markers)
+	}
+}
+
+func TestWriteReports_Jenkins(t *testing.T) {
+	dir := t.TempDir()
+	profile := &EnvironmentProfile{Type: EnvJenkins}
+	outcome := testOutcome()
+	outcome.PlanComplete = true
+
+	paths, err := profile.WriteReports(dir, outcome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, PropertiesReportFile)}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("WriteReports() = %v, want %v", paths, want)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(string(data), "RALPH_STATUS=complete\n") {
+		t.Errorf("%s is missing the status:\n%s", PropertiesReportFile, data)
+	}
+
+	want := []string{
+		"[ralph] BUILD_DESCRIPTION: 5/8 features tested, 2 completed in 6 iteration(s)",
+		"[ralph] BUILD_RESULT: SUCCESS",
+	}
+	if got := profile.Markers(outcome); !reflect.DeepEqual(got, want) {
+		t.Errorf("Markers() = %v, want %v", got, want)
+	}
+	if got := profile.Markers(testOutcome()); got[1] != "[ralph] BUILD_RESULT: UNSTABLE" {
+		t.Errorf("Markers() of an incomplete plan = %v", got)
+	}
+}
+
+func TestWriteReports_OtherEnvironments(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	for _, envType := range []EnvironmentType{EnvLocal, EnvGitHubActions, EnvGenericCI} {
+		profile := &EnvironmentProfile{Type: envType}
+		if profile.SupportsReports() {
+			t.Errorf("SupportsReports() = true for %s", envType)
+		}
+		if paths, err := profile.WriteReports(dir, testOutcome()); err != nil || len(paths) != 0 {
+			t.Errorf("WriteReports() for %s = %v, %v, want nothing", envType, paths, err)
+		}
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("report directory was created for environments without reports")
+	}
+}
+
+func TestDotenvAndProperties(t *testing.T) {
+	vars := []Variable{{"RALPH_NOTE", "a=b: c\nd"}, {"RALPH_PATH", `C:\ralph`}}
+	if got, want := Dotenv(vars), "RALPH_NOTE=a=b: c d\nRALPH_PATH=C:\\ralph\n"; got != want {
+		t.Errorf("Dotenv() = %q, want %q", got, want)
+	}
+	if got, want := Properties(vars), "RALPH_NOTE=a\\=b\\: c\\nd\nRALPH_PATH=C\\:\\\\ralph\n"; got != want {
+		t.Errorf("Properties() = %q, want %q", got, want)
+	}
+}
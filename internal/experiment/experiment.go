@@ -0,0 +1,205 @@
+// Package experiment implements A/B experiment mode, which alternates between two
+// agent/prompt variants within a single run and compares their results.
+package experiment
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+)
+
+// Split determines how iterations are assigned to variants
+type Split string
+
+const (
+	// SplitAlternate assigns odd iterations to variant A and even iterations to variant B
+	SplitAlternate Split = "alternate"
+	// SplitHalves assigns features in the first half of the plan to variant A and
+	// the rest to variant B
+	SplitHalves Split = "halves"
+)
+
+// PromptPlaceholder is replaced with the iteration prompt when present in a
+// variant prompt template. Templates without it are prepended to the prompt.
+const PromptPlaceholder = "{{prompt}}"
+
+// ParseSplit converts a string to a Split
+func ParseSplit(s string) (Split, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "alternate", "alt":
+		return SplitAlternate, nil
+	case "halves", "half":
+		return SplitHalves, nil
+	default:
+		return "", fmt.Errorf("unknown experiment split %q: must be alternate or halves", s)
+	}
+}
+
+// Variant is one arm of an experiment
+type Variant struct {
+	Name       string // "A" or "B"
+	AgentCmd   string // Agent command used for this variant
+	PromptFile string // Optional prompt template file
+
+	template             string
+	iterations           int
+	failures             int
+	featuresCompleted    []int
+	iterationsPerFeature map[int]int
+}
+
+// NewVariant creates a variant, loading its prompt template if one is given
+func NewVariant(name, agentCmd, promptFile string) (*Variant, error) {
+	v := &Variant{
+		Name:                 name,
+		AgentCmd:             agentCmd,
+		PromptFile:           promptFile,
+		featuresCompleted:    []int{},
+		iterationsPerFeature: make(map[int]int),
+	}
+
+	if promptFile != "" {
+		data, err := os.ReadFile(promptFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template for variant %s: %w", name, err)
+		}
+		v.template = strings.TrimSpace(string(data))
+	}
+
+	return v, nil
+}
+
+// Describe returns a short human-readable description of the variant
+func (v *Variant) Describe() string {
+	desc := fmt.Sprintf("variant %s: agent %s", v.Name, v.AgentCmd)
+	if v.PromptFile != "" {
+		desc += ", prompt " + v.PromptFile
+	}
+	return desc
+}
+
+// ApplyPrompt applies the variant's prompt template to an iteration prompt
+func (v *Variant) ApplyPrompt(prompt string) string {
+	if v.template == "" {
+		return prompt
+	}
+	if strings.Contains(v.template, PromptPlaceholder) {
+		return strings.ReplaceAll(v.template, PromptPlaceholder, prompt)
+	}
+	return v.template + "\n\n" + prompt
+}
+
+// RecordIteration records the outcome of an iteration run with this variant
+func (v *Variant) RecordIteration(featureID int, failed bool, completed []int) {
+	v.iterations++
+	if failed {
+		v.failures++
+	}
+	if featureID > 0 {
+		v.iterationsPerFeature[featureID]++
+	}
+	v.featuresCompleted = append(v.featuresCompleted, completed...)
+}
+
+// Iterations returns the number of iterations run with this variant
+func (v *Variant) Iterations() int {
+	return v.iterations
+}
+
+// Experiment alternates between two variants across a run
+type Experiment struct {
+	Split Split
+	A     *Variant
+	B     *Variant
+
+	totalIterations int
+	featureOrder    map[int]int // Feature ID -> position in the plan
+	featureCount    int
+}
+
+// New creates a new experiment. featureIDs lists plan features in plan order and
+// is used by the halves split.
+func New(split Split, a, b *Variant, totalIterations int, featureIDs []int) *Experiment {
+	order := make(map[int]int, len(featureIDs))
+	for i, id := range featureIDs {
+		order[id] = i
+	}
+	return &Experiment{
+		Split:           split,
+		A:               a,
+		B:               b,
+		totalIterations: totalIterations,
+		featureOrder:    order,
+		featureCount:    len(featureIDs),
+	}
+}
+
+// Select returns the variant to use for the given iteration (1-based) and the
+// feature currently being worked on (0 if unknown)
+func (e *Experiment) Select(iteration, featureID int) *Variant {
+	if e.Split == SplitHalves {
+		if pos, ok := e.featureOrder[featureID]; ok && e.featureCount > 0 {
+			if pos < (e.featureCount+1)/2 {
+				return e.A
+			}
+			return e.B
+		}
+		// Unknown feature: fall back to splitting the iteration budget in half
+		if iteration <= (e.totalIterations+1)/2 {
+			return e.A
+		}
+		return e.B
+	}
+
+	if iteration%2 == 1 {
+		return e.A
+	}
+	return e.B
+}
+
+// VariantRuns builds a history record for each variant from the overall run.
+// Both records are tagged with the experiment ID and variant name.
+func (e *Experiment) VariantRuns(base *history.Run) (*history.Run, *history.Run) {
+	return e.variantRun(base, e.A), e.variantRun(base, e.B)
+}
+
+func (e *Experiment) variantRun(base *history.Run, v *Variant) *history.Run {
+	label := "variant-" + v.Name
+	if base.Label != "" {
+		label = base.Label + "-" + v.Name
+	}
+
+	run := &history.Run{
+		ID:                   base.ID + "-" + v.Name,
+		Label:                label,
+		Agent:                v.AgentCmd,
+		PlanFile:             base.PlanFile,
+		StartTime:            base.StartTime,
+		EndTime:              base.EndTime,
+		IterationsLimit:      base.IterationsLimit,
+		IterationsRun:        v.iterations,
+		Completed:            base.Completed,
+		FeaturesCompleted:    append([]int{}, v.featuresCompleted...),
+		Failures:             v.failures,
+		IterationsPerFeature: make(map[int]int, len(v.iterationsPerFeature)),
+		Tags: map[string]string{
+			"experiment": base.ID,
+			"variant":    v.Name,
+			"split":      string(e.Split),
+		},
+	}
+	if run.EndTime.IsZero() {
+		run.EndTime = time.Now()
+	}
+	for id, n := range v.iterationsPerFeature {
+		run.IterationsPerFeature[id] = n
+	}
+	if v.PromptFile != "" {
+		run.Tags["prompt"] = v.PromptFile
+	}
+
+	return run
+}
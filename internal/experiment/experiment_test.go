@@ -0,0 +1,136 @@
+package experiment
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+)
+
+func TestParseSplit(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Split
+		wantErr bool
+	}{
+		{"", SplitAlternate, false},
+		{"alternate", SplitAlternate, false},
+		{"HALVES", SplitHalves, false},
+		{"random", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSplit(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSplit(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSplit(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestVariant_ApplyPrompt(t *testing.T) {
+	dir := t.TempDir()
+	prefixFile := filepath.Join(dir, "prefix.txt")
+	wrapFile := filepath.Join(dir, "wrap.txt")
+	os.WriteFile(prefixFile, []byte("Be terse.\n"), 0644)
+	os.WriteFile(wrapFile, []byte("BEGIN {{prompt}} END"), 0644)
+
+	plain, _ := NewVariant("A", "agent", "")
+	if got := plain.ApplyPrompt("task"); got != "task" {
+		t.Errorf("variant without template should not change prompt, got %q", got)
+	}
+
+	prefix, err := NewVariant("B", "agent", prefixFile)
+	if err != nil {
+		t.Fatalf("NewVariant failed: %v", err)
+	}
+	if got := prefix.ApplyPrompt("task"); got != "Be terse.\n\ntask" {
+		t.Errorf("unexpected prefixed prompt %q", got)
+	}
+
+	wrap, _ := NewVariant("B", "agent", wrapFile)
+	if got := wrap.ApplyPrompt("task"); got != "BEGIN task END" {
+		t.Errorf("unexpected wrapped prompt %q", got)
+	}
+
+	if _, err := NewVariant("B", "agent", filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected error for missing template file")
+	}
+}
+
+func TestExperiment_SelectAlternate(t *testing.T) {
+	a, _ := NewVariant("A", "agent-a", "")
+	b, _ := NewVariant("B", "agent-b", "")
+	exp := New(SplitAlternate, a, b, 4, nil)
+
+	want := []string{"A", "B", "A", "B"}
+	for i, name := range want {
+		if got := exp.Select(i+1, 0).Name; got != name {
+			t.Errorf("iteration %d: got variant %s, want %s", i+1, got, name)
+		}
+	}
+}
+
+func TestExperiment_SelectHalves(t *testing.T) {
+	a, _ := NewVariant("A", "agent-a", "")
+	b, _ := NewVariant("B", "agent-b", "")
+	exp := New(SplitHalves, a, b, 6, []int{10, 20, 30, 40})
+
+	tests := []struct {
+		iteration int
+		featureID int
+		want      string
+	}{
+		{1, 10, "A"},
+		{5, 20, "A"},
+		{1, 30, "B"},
+		{2, 40, "B"},
+		{2, 0, "A"}, // unknown feature, first half of iterations
+		{5, 0, "B"}, // unknown feature, second half of iterations
+	}
+
+	for _, tt := range tests {
+		if got := exp.Select(tt.iteration, tt.featureID).Name; got != tt.want {
+			t.Errorf("Select(%d, %d) = %s, want %s", tt.iteration, tt.featureID, got, tt.want)
+		}
+	}
+}
+
+func TestExperiment_VariantRuns(t *testing.T) {
+	a, _ := NewVariant("A", "agent-a", "")
+	b, _ := NewVariant("B", "agent-b", "")
+	exp := New(SplitAlternate, a, b, 4, nil)
+
+	a.RecordIteration(1, false, []int{1})
+	b.RecordIteration(2, true, nil)
+	a.RecordIteration(3, false, nil)
+	b.RecordIteration(2, false, []int{2})
+
+	base := history.NewRun("agent-a", "plan.json", "exp")
+	base.EndTime = base.StartTime.Add(time.Minute)
+
+	runA, runB := exp.VariantRuns(base)
+	if runA.ID != base.ID+"-A" || runB.Label != "exp-B" {
+		t.Errorf("unexpected variant identities: %s / %s", runA.ID, runB.Label)
+	}
+	if runA.IterationsRun != 2 || runB.Failures != 1 {
+		t.Errorf("unexpected variant stats: A=%+v B=%+v", runA, runB)
+	}
+	if runB.IterationsPerFeature[2] != 2 {
+		t.Errorf("expected 2 iterations on feature 2 for B, got %d", runB.IterationsPerFeature[2])
+	}
+	if runA.Tags["experiment"] != base.ID || runB.Tags["variant"] != "B" {
+		t.Errorf("variant runs should be tagged: %v / %v", runA.Tags, runB.Tags)
+	}
+
+	report := history.Compare(runA, runB).Format()
+	if !strings.Contains(report, "Completed only in A: #1") || !strings.Contains(report, "Completed only in B: #2") {
+		t.Errorf("unexpected comparison report:\n%s", report)
+	}
+}
@@ -0,0 +1,196 @@
+// Package filelock provides advisory locks on files shared between Ralph
+// processes (and people editing them), such as plan.json and progress.txt.
+// A lock is a <file>.lock file holding the PID and host of its owner, so a
+// lock left behind by a process that died can be recognized as stale.
+package filelock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is how long to wait for another process to release a lock
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultStaleAfter is the age after which a lock counts as stale even if
+	// its owner is still running; locks are only held for single writes
+	DefaultStaleAfter = 5 * time.Minute
+
+	// pollInterval is how often a held lock is checked while waiting
+	pollInterval = 50 * time.Millisecond
+)
+
+// ErrLocked is returned when a file stays locked by another process
+var ErrLocked = errors.New("file is locked by another process")
+
+// Options controls how locks are acquired
+type Options struct {
+	Timeout    time.Duration // How long to wait for another process to release the lock
+	StaleAfter time.Duration // Age after which a lock counts as stale
+	Force      bool          // Take over locks held by other processes instead of waiting
+}
+
+// DefaultOptions are the options used by With. Ralph sets Force from -force.
+var DefaultOptions = Options{Timeout: DefaultTimeout, StaleAfter: DefaultStaleAfter}
+
+// Owner describes the process holding a lock
+type Owner struct {
+	PID  int
+	Host string
+	Time time.Time
+}
+
+// String describes the owner for messages
+func (o Owner) String() string {
+	return fmt.Sprintf("PID %d on %s since %s", o.PID, o.Host, o.Time.Format(time.RFC3339))
+}
+
+// Lock is an acquired lock
+type Lock struct {
+	path string
+}
+
+// Path returns the lock file that guards path
+func Path(path string) string {
+	return path + ".lock"
+}
+
+// Acquire locks path, waiting up to opts.Timeout for another process to
+// release it. Stale locks, whose owner is no longer running or which are
+// older than opts.StaleAfter, are taken over, as are all locks with
+// opts.Force.
+func Acquire(path string, opts Options) (*Lock, error) {
+	lockPath := Path(path)
+	hostname, _ := os.Hostname()
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		content := fmt.Sprintf("%d\n%s\n%s\n", os.Getpid(), hostname, time.Now().Format(time.RFC3339Nano))
+		err := create(lockPath, []byte(content))
+		if err == nil {
+			return &Lock{path: lockPath}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", lockPath, err)
+		}
+
+		data, err := os.ReadFile(lockPath)
+		if os.IsNotExist(err) {
+			continue // Released in the meantime
+		}
+		owner, perr := parseOwner(lockPath, data)
+		if err != nil || perr != nil || opts.Force || owner.stale(hostname, opts.StaleAfter) {
+			// Unreadable lock files are left over from a crash while writing them
+			if err := takeOver(lockPath, data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w: %s is locked by %s (remove %s or use -force to override)", ErrLocked, path, owner, lockPath)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release releases the lock
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// With runs fn while holding the lock on path, acquired with DefaultOptions
+func With(path string, fn func() error) error {
+	lock, err := Acquire(path, DefaultOptions)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return fn()
+}
+
+// create creates the lock file with content, failing if it exists. The
+// content is written to a temporary file that is then linked as the lock
+// file, so that other processes never see a lock file without its owner.
+func create(lockPath string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(lockPath), filepath.Base(lockPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, werr := tmp.Write(content)
+	if cerr := tmp.Close(); werr != nil || cerr != nil {
+		return errors.Join(werr, cerr)
+	}
+	return os.Link(tmp.Name(), lockPath)
+}
+
+// takeOver removes the lock file at lockPath if it still holds data, the
+// content found stale. The file is first renamed to a name of this process,
+// so that of several processes taking over the same stale lock only one
+// removes it; a lock another process created in the meantime is put back
+// instead.
+func takeOver(lockPath string, data []byte) error {
+	moved := fmt.Sprintf("%s.%d.%d", lockPath, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockPath, moved); err != nil {
+		if os.IsNotExist(err) {
+			return nil // Taken over or released by another process
+		}
+		return fmt.Errorf("failed to take over lock file %s: %w", lockPath, err)
+	}
+	defer os.Remove(moved)
+	current, err := os.ReadFile(moved)
+	if err != nil || bytes.Equal(current, data) {
+		return nil
+	}
+	// Link fails if yet another process locked the file since; the lock
+	// moved away is then lost, as it would be had it been stale
+	if err := os.Link(moved, lockPath); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to restore lock file %s: %w", lockPath, err)
+	}
+	return nil
+}
+
+// readOwner reads the owner of a lock file
+func readOwner(lockPath string) (Owner, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return Owner{}, err
+	}
+	return parseOwner(lockPath, data)
+}
+
+// parseOwner parses the content of a lock file
+func parseOwner(lockPath string, data []byte) (Owner, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 3 {
+		return Owner{}, fmt.Errorf("invalid lock file %s", lockPath)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return Owner{}, fmt.Errorf("invalid lock file %s: %w", lockPath, err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(lines[2]))
+	if err != nil {
+		return Owner{}, fmt.Errorf("invalid lock file %s: %w", lockPath, err)
+	}
+	return Owner{PID: pid, Host: strings.TrimSpace(lines[1]), Time: t}, nil
+}
+
+// stale reports whether the lock was left behind: its owner on this host is
+// no longer running, or it is older than staleAfter
+func (o Owner) stale(hostname string, staleAfter time.Duration) bool {
+	if staleAfter > 0 && time.Since(o.Time) > staleAfter {
+		return true
+	}
+	return o.Host == hostname && !processRunning(o.PID)
+}
@@ -0,0 +1,152 @@
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeLock writes a lock file for path owned by the given process
+func writeLock(t *testing.T, path string, pid int, host string, at time.Time) {
+	t.Helper()
+	content := fmt.Sprintf("%d\n%s\n%s\n", pid, host, at.Format(time.RFC3339Nano))
+	if err := os.WriteFile(Path(path), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	lock, err := Acquire(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Acquire() failed: %v", err)
+	}
+	owner, err := readOwner(Path(path))
+	if err != nil || owner.PID != os.Getpid() {
+		t.Errorf("lock owner = %+v, %v; want this process", owner, err)
+	}
+
+	// A second acquisition waits for the lock and gives up
+	start := time.Now()
+	if _, err := Acquire(path, Options{Timeout: 100 * time.Millisecond}); !errors.Is(err, ErrLocked) {
+		t.Errorf("Acquire() of a held lock = %v, want ErrLocked", err)
+	}
+	if time.Since(start) < 100*time.Millisecond {
+		t.Error("Acquire() did not wait for the lock")
+	}
+
+	// It succeeds once the lock is released while waiting
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.Release()
+	}()
+	second, err := Acquire(path, Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Acquire() after release failed: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("Release() failed: %v", err)
+	}
+	if _, err := os.Stat(Path(path)); !os.IsNotExist(err) {
+		t.Error("lock file left behind after Release()")
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	hostname, _ := os.Hostname()
+	tests := []struct {
+		name    string
+		content func(path string)
+		opts    Options
+		wantErr bool
+	}{
+		{"dead owner", func(path string) { writeLock(t, path, deadPID(t), hostname, time.Now()) }, Options{}, false},
+		{"old lock", func(path string) { writeLock(t, path, os.Getppid(), hostname, time.Now().Add(-time.Hour)) }, Options{StaleAfter: time.Minute}, false},
+		{"garbage", func(path string) { os.WriteFile(Path(path), []byte("???"), 0644) }, Options{}, false},
+		{"live owner", func(path string) { writeLock(t, path, os.Getppid(), hostname, time.Now()) }, Options{StaleAfter: time.Minute}, true},
+		{"live owner forced", func(path string) { writeLock(t, path, os.Getppid(), hostname, time.Now()) }, Options{Force: true}, false},
+		{"other host", func(path string) { writeLock(t, path, deadPID(t), "elsewhere", time.Now()) }, Options{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "progress.txt")
+			tt.content(path)
+			lock, err := Acquire(path, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Acquire() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if lock != nil {
+				lock.Release()
+			}
+		})
+	}
+}
+
+func TestAcquireStaleConcurrently(t *testing.T) {
+	hostname, _ := os.Hostname()
+	path := filepath.Join(t.TempDir(), "plan.json")
+	writeLock(t, path, deadPID(t), hostname, time.Now())
+
+	// Every waiter finds the lock stale; only one may take it over at a time
+	var held, maxHeld atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := Acquire(path, Options{Timeout: 10 * time.Second})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := held.Add(1)
+			for m := maxHeld.Load(); n > m && !maxHeld.CompareAndSwap(m, n); m = maxHeld.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+			held.Add(-1)
+			lock.Release()
+		}()
+	}
+	wg.Wait()
+	if maxHeld.Load() != 1 {
+		t.Errorf("lock held by %d waiters at once", maxHeld.Load())
+	}
+	matches, _ := filepath.Glob(Path(path) + "*")
+	if len(matches) != 0 {
+		t.Errorf("lock files left behind: %v", matches)
+	}
+}
+
+func TestWith(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	ran := false
+	err := With(path, func() error {
+		ran = true
+		if _, err := os.Stat(Path(path)); err != nil {
+			t.Errorf("lock not held while running: %v", err)
+		}
+		return errors.New("write failed")
+	})
+	if !ran || err == nil || err.Error() != "write failed" {
+		t.Errorf("With() = %v, ran = %v", err, ran)
+	}
+	if _, err := os.Stat(Path(path)); !os.IsNotExist(err) {
+		t.Error("lock file left behind after With()")
+	}
+}
+
+// deadPID returns the PID of a process that has exited
+func deadPID(t *testing.T) int {
+	t.Helper()
+	p, err := os.StartProcess(os.Args[0], []string{os.Args[0], "-test.run=^$"}, &os.ProcAttr{})
+	if err != nil {
+		t.Skipf("cannot start a process: %v", err)
+	}
+	p.Wait()
+	return p.Pid
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package filelock
+
+import "syscall"
+
+// processRunning reports whether a process with the given PID exists
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM means it exists but belongs to another user
+	return err == nil || err == syscall.EPERM
+}
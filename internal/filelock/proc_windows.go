@@ -0,0 +1,16 @@
+//go:build windows
+
+package filelock
+
+import "os"
+
+// processRunning reports whether a process with the given PID exists. On
+// Windows, FindProcess fails if it does not.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
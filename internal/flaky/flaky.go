@@ -0,0 +1,355 @@
+// Package flaky detects flaky tests: tests that fail in one iteration and pass
+// in a later one without any related code change in between. Test results are
+// parsed from go test, jest and pytest output, and flaky tests are kept in a
+// store so their failures can be quarantined instead of triggering recovery.
+package flaky
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultFlakyFile is the default path of the flaky test store
+const DefaultFlakyFile = ".ralph/flaky.json"
+
+// Result is the outcome of a test, or of a whole suite, found in test output
+type Result struct {
+	Suite  string // Go package, or test file for jest and pytest ("" if unknown)
+	Name   string // Test name; empty when the result covers the whole suite
+	Passed bool
+}
+
+// Key identifies the test across iterations and runs
+func (r Result) Key() string {
+	if r.Suite == "" || r.Name == "" {
+		return r.Suite + r.Name
+	}
+	return r.Suite + ": " + r.Name
+}
+
+var (
+	goTestPattern    = regexp.MustCompile(`^\s*--- (FAIL|PASS): (\S+)`)
+	goPackagePattern = regexp.MustCompile(`^(FAIL|ok)\s+(\S+)\s+(\(cached\)|[\d.]+s)`)
+	jestSuitePattern = regexp.MustCompile(`^\s*(PASS|FAIL) (\S+\.[cm]?[jt]sx?)\b`)
+	jestTestPattern  = regexp.MustCompile(`^\s*● (.+›.+)$`)
+	pytestPattern    = regexp.MustCompile(`^(\S+\.py)::(\S+)\s+(PASSED|FAILED|ERROR)\b`)
+	pytestSummary    = regexp.MustCompile(`^(FAILED|ERROR) (\S+\.py)::(\S+?)(\s+-\s.*)?$`)
+	pytestDots       = regexp.MustCompile(`^(\S+\.py) [.sx]+\s*(\[\s*\d+%\])?$`)
+	testFilePattern  = regexp.MustCompile(`\.(py|[cm]?[jt]sx?)$`)
+
+	// Summary lines that only repeat the results above them
+	summaryPattern = regexp.MustCompile(`^(FAIL|PASS|ok)$|^Tests?:\s+.*\d+ (failed|passed)|^=+ .*\d+ (failed|passed).* =+$`)
+)
+
+// Parse extracts test results from go test, jest and pytest output, in order
+func Parse(output string) []Result {
+	var results []Result
+	var pendingGo []int // Go tests whose package line has not been seen yet
+	var jestSuite string
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case goTestPattern.MatchString(line):
+			m := goTestPattern.FindStringSubmatch(line)
+			pendingGo = append(pendingGo, len(results))
+			results = append(results, Result{Name: m[2], Passed: m[1] == "PASS"})
+		case goPackagePattern.MatchString(line):
+			m := goPackagePattern.FindStringSubmatch(line)
+			for _, i := range pendingGo {
+				results[i].Suite = m[2]
+			}
+			pendingGo = nil
+			if m[1] == "ok" {
+				results = append(results, Result{Suite: m[2], Passed: true})
+			}
+		case jestSuitePattern.MatchString(line):
+			m := jestSuitePattern.FindStringSubmatch(line)
+			jestSuite = m[2]
+			if m[1] == "PASS" {
+				results = append(results, Result{Suite: jestSuite, Passed: true})
+			}
+		case jestTestPattern.MatchString(line):
+			m := jestTestPattern.FindStringSubmatch(line)
+			results = append(results, Result{Suite: jestSuite, Name: strings.TrimSpace(m[1])})
+		case pytestPattern.MatchString(line):
+			m := pytestPattern.FindStringSubmatch(line)
+			results = append(results, Result{Suite: m[1], Name: m[2], Passed: m[3] == "PASSED"})
+		case pytestSummary.MatchString(line):
+			m := pytestSummary.FindStringSubmatch(line)
+			results = append(results, Result{Suite: m[2], Name: m[3]})
+		case pytestDots.MatchString(line):
+			results = append(results, Result{Suite: pytestDots.FindStringSubmatch(line)[1], Passed: true})
+		}
+	}
+	return results
+}
+
+// Failed returns the distinct failed tests among the results
+func Failed(results []Result) []Result {
+	var failed []Result
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if !r.Passed && !seen[r.Key()] {
+			seen[r.Key()] = true
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Strip removes the lines reporting test results and test summaries from the
+// output, so what remains can be checked for failures other than tests
+func Strip(output string) string {
+	var kept []string
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimRight(line, "\r")
+		if goTestPattern.MatchString(trimmed) || goPackagePattern.MatchString(trimmed) ||
+			jestSuitePattern.MatchString(trimmed) || jestTestPattern.MatchString(trimmed) ||
+			pytestPattern.MatchString(trimmed) || pytestSummary.MatchString(trimmed) ||
+			pytestDots.MatchString(trimmed) || summaryPattern.MatchString(strings.TrimSpace(trimmed)) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// Test is a test that has failed at some point
+type Test struct {
+	Suite       string    `json:"suite,omitempty"`
+	Name        string    `json:"name"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	Flaky       bool      `json:"flaky"`
+	FlakySince  time.Time `json:"flaky_since"`
+}
+
+// String returns the test name with its suite
+func (t *Test) String() string {
+	if t.Suite == "" {
+		return t.Name
+	}
+	return fmt.Sprintf("%s (%s)", t.Name, t.Suite)
+}
+
+// pendingFailure is a failure not yet followed by a pass
+type pendingFailure struct {
+	result  Result
+	changed bool // Related code changed since the failure
+}
+
+// Store tracks test failures across iterations and the tests found flaky
+type Store struct {
+	path    string
+	Tests   map[string]*Test `json:"tests"`
+	pending map[string]*pendingFailure
+}
+
+// NewStore creates an empty store saved to path
+func NewStore(path string) *Store {
+	return &Store{path: path, Tests: make(map[string]*Test), pending: make(map[string]*pendingFailure)}
+}
+
+// Load reads the store at path, returning an empty store if it does not exist
+func Load(path string) (*Store, error) {
+	s := NewStore(path)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flaky test file: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse flaky test file %s: %w", path, err)
+	}
+	if s.Tests == nil {
+		s.Tests = make(map[string]*Test)
+	}
+	return s, nil
+}
+
+// Save writes the store to disk
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal flaky tests: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create flaky test directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write flaky test file: %w", err)
+	}
+	return nil
+}
+
+// Observe records the test results of an iteration and returns the tests
+// found flaky by it. changed lists the repository-relative paths the
+// iteration changed; changedKnown is false if they could not be determined,
+// in which case every earlier failure counts as possibly fixed by a change.
+// A test that fails and then passes within the same iteration is not flaky,
+// since the agent may have fixed it in between.
+func (s *Store) Observe(results []Result, changed []string, changedKnown bool) []*Test {
+	for _, p := range s.pending {
+		if !changedKnown || related(p.result.Suite, changed) {
+			p.changed = true
+		}
+	}
+
+	var found []*Test
+	failing := make(map[string]Result) // Tests whose latest result in this iteration is a failure
+	for _, r := range results {
+		if !r.Passed {
+			t := s.test(r)
+			t.Failures++
+			t.LastFailure = time.Now()
+			failing[r.Key()] = r
+			continue
+		}
+		for key, f := range failing {
+			if covers(r, f) {
+				delete(failing, key)
+			}
+		}
+		// Failures of earlier iterations that passed without a related change are flaky
+		for key, p := range s.pending {
+			if !covers(r, p.result) {
+				continue
+			}
+			delete(s.pending, key)
+			if t := s.test(p.result); !p.changed && !t.Flaky {
+				t.Flaky = true
+				t.FlakySince = time.Now()
+				found = append(found, t)
+			}
+		}
+	}
+
+	for key, r := range failing {
+		s.pending[key] = &pendingFailure{result: r}
+	}
+	return found
+}
+
+// test returns the tracked test for a result, adding it if needed
+func (s *Store) test(r Result) *Test {
+	t, ok := s.Tests[r.Key()]
+	if !ok {
+		t = &Test{Suite: r.Suite, Name: r.Name}
+		s.Tests[r.Key()] = t
+	}
+	return t
+}
+
+// IsFlaky reports whether the test of a result has been found flaky
+func (s *Store) IsFlaky(r Result) bool {
+	t, ok := s.Tests[r.Key()]
+	return ok && t.Flaky
+}
+
+// Quarantined returns the flaky tests, sorted by suite and name
+func (s *Store) Quarantined() []*Test {
+	var tests []*Test
+	for _, t := range s.Tests {
+		if t.Flaky {
+			tests = append(tests, t)
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Suite != tests[j].Suite {
+			return tests[i].Suite < tests[j].Suite
+		}
+		return tests[i].Name < tests[j].Name
+	})
+	return tests
+}
+
+// OnlyFlakyFailures returns the failed tests among the results if all of them
+// are flaky, or nil if there are none or some failure is genuine
+func (s *Store) OnlyFlakyFailures(results []Result) []Result {
+	failed := Failed(results)
+	for _, r := range failed {
+		if !s.IsFlaky(r) {
+			return nil
+		}
+	}
+	return failed
+}
+
+// Unmark clears the flaky mark of the tests whose key or name is name, or of
+// all tests if name is empty, and returns how many were cleared
+func (s *Store) Unmark(name string) int {
+	n := 0
+	for key, t := range s.Tests {
+		if t.Flaky && (name == "" || key == name || t.Name == name) {
+			t.Flaky = false
+			t.FlakySince = time.Time{}
+			n++
+		}
+	}
+	return n
+}
+
+// covers reports whether a passing result shows that the failed test passed:
+// it is the same test, or the whole suite of the test passed
+func covers(pass, failure Result) bool {
+	if pass.Suite != failure.Suite {
+		return false
+	}
+	return pass.Name == "" || pass.Name == failure.Name
+}
+
+// manifests are dependency files whose changes may affect any test
+var manifests = map[string]bool{
+	"go.mod": true, "go.sum": true, "package.json": true, "package-lock.json": true,
+	"yarn.lock": true, "pnpm-lock.yaml": true, "requirements.txt": true,
+	"pyproject.toml": true, "setup.py": true, "poetry.lock": true,
+}
+
+// related reports whether any changed path may affect the tests of a suite:
+// it is in the directory of the Go package or test file, it is the source
+// file a test file is named after, or it is a dependency manifest. Any change
+// counts as related when the suite is unknown.
+func related(suite string, changed []string) bool {
+	if suite == "" {
+		return len(changed) > 0
+	}
+	isFile := testFilePattern.MatchString(suite)
+	dir := suite
+	if isFile {
+		dir = path.Dir(suite)
+	}
+	for _, c := range changed {
+		c = filepath.ToSlash(c)
+		d := path.Dir(c)
+		switch {
+		case d == dir || manifests[path.Base(c)]:
+			return true
+		case !isFile && strings.HasSuffix(dir, "/"+d):
+			return true
+		case isFile && stem(c) == stem(suite):
+			return true
+		}
+	}
+	return false
+}
+
+// stem returns a file's base name without its extension and test markers,
+// e.g. "sum" for sum.test.js, test_sum.py and sum.py
+func stem(file string) string {
+	name := path.Base(file)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	for _, suffix := range []string{".test", ".spec", "_test"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return strings.TrimPrefix(name, "test_")
+}
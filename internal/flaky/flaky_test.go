@@ -0,0 +1,214 @@
+package flaky
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goOutput = `=== RUN   TestSum
+--- PASS: TestSum (0.00s)
+=== RUN   TestFetch
+--- FAIL: TestFetch (1.02s)
+    fetch_test.go:12: timeout
+FAIL
+FAIL	example.com/app/net	1.031s
+ok  	example.com/app/calc	(cached)
+`
+
+const jestOutput = `PASS src/sum.test.js
+FAIL src/fetch.test.js
+  ● fetch › retries on error
+
+    expect(received).toBe(expected)
+
+Tests:       1 failed, 3 passed, 4 total
+`
+
+const pytestOutput = `tests/test_sum.py::test_add PASSED                  [ 50%]
+tests/test_net.py::test_fetch FAILED                [100%]
+=========================== short test summary info ============================
+FAILED tests/test_net.py::test_fetch - TimeoutError
+========================= 1 failed, 1 passed in 0.12s ==========================
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Result
+	}{
+		{"go", goOutput, []Result{
+			{Suite: "example.com/app/net", Name: "TestSum", Passed: true},
+			{Suite: "example.com/app/net", Name: "TestFetch"},
+			{Suite: "example.com/app/calc", Passed: true},
+		}},
+		{"jest", jestOutput, []Result{
+			{Suite: "src/sum.test.js", Passed: true},
+			{Suite: "src/fetch.test.js", Name: "fetch › retries on error"},
+		}},
+		{"pytest", pytestOutput, []Result{
+			{Suite: "tests/test_sum.py", Name: "test_add", Passed: true},
+			{Suite: "tests/test_net.py", Name: "test_fetch"},
+			{Suite: "tests/test_net.py", Name: "test_fetch"},
+		}},
+		{"pytest dots", "tests/test_sum.py ..s.  [100%]\n", []Result{
+			{Suite: "tests/test_sum.py", Passed: true},
+		}},
+		{"no tests", "Implemented the feature.\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailed(t *testing.T) {
+	failed := Failed(Parse(pytestOutput))
+	if len(failed) != 1 || failed[0].Key() != "tests/test_net.py: test_fetch" {
+		t.Errorf("Failed() = %+v, want the single test_fetch failure", failed)
+	}
+}
+
+func TestStrip(t *testing.T) {
+	for name, output := range map[string]string{"go": goOutput, "jest": jestOutput, "pytest": pytestOutput} {
+		stripped := Strip(output)
+		for _, marker := range []string{"FAIL", "FAILED", "failed"} {
+			for _, line := range strings.Split(stripped, "\n") {
+				if strings.HasPrefix(strings.TrimSpace(line), marker) {
+					t.Errorf("%s: Strip() kept result line %q", name, line)
+				}
+			}
+		}
+	}
+	if got := Strip("build failed\n--- FAIL: TestX (0.00s)"); got != "build failed" {
+		t.Errorf("Strip() = %q, want other output kept", got)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	fail := Result{Suite: "example.com/app/net", Name: "TestFetch"}
+	pass := Result{Suite: "example.com/app/net", Name: "TestFetch", Passed: true}
+	suitePass := Result{Suite: "example.com/app/net", Passed: true}
+
+	tests := []struct {
+		name      string
+		second    []Result
+		changed   []string
+		known     bool
+		wantFlaky bool
+	}{
+		{"passes without changes", []Result{pass}, nil, true, true},
+		{"suite passes without changes", []Result{suitePass}, nil, true, true},
+		{"unrelated change", []Result{pass}, []string{"docs/guide.md"}, true, true},
+		{"related change", []Result{pass}, []string{"net/fetch.go"}, true, false},
+		{"root change", []Result{pass}, []string{"progress.txt"}, true, true},
+		{"dependency change", []Result{pass}, []string{"go.mod"}, true, false},
+		{"changes unknown", []Result{pass}, nil, false, false},
+		{"still failing", []Result{fail}, nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore("")
+			if found := s.Observe([]Result{fail}, nil, true); len(found) != 0 {
+				t.Fatalf("first failure reported flaky: %v", found)
+			}
+			found := s.Observe(tt.second, tt.changed, tt.known)
+			if (len(found) == 1) != tt.wantFlaky || s.IsFlaky(fail) != tt.wantFlaky {
+				t.Errorf("Observe() = %v, IsFlaky() = %v; want flaky %v", found, s.IsFlaky(fail), tt.wantFlaky)
+			}
+		})
+	}
+}
+
+func TestObserveSameIteration(t *testing.T) {
+	fail := Result{Suite: "src/fetch.test.js", Name: "fetch › retries"}
+	pass := Result{Suite: "src/fetch.test.js", Name: "fetch › retries", Passed: true}
+
+	// The agent fixed the test within the iteration
+	s := NewStore("")
+	s.Observe([]Result{fail, pass}, []string{"src/fetch.js"}, true)
+	if found := s.Observe([]Result{pass}, nil, true); len(found) != 0 || s.IsFlaky(fail) {
+		t.Errorf("test fixed within an iteration reported flaky: %v", found)
+	}
+	if s.Tests[fail.Key()].Failures != 1 {
+		t.Errorf("Failures = %d, want 1", s.Tests[fail.Key()].Failures)
+	}
+}
+
+func TestOnlyFlakyFailures(t *testing.T) {
+	flakyFail := Result{Suite: "tests/test_net.py", Name: "test_fetch"}
+	s := NewStore("")
+	s.Observe([]Result{flakyFail}, nil, true)
+	s.Observe([]Result{{Suite: "tests/test_net.py", Name: "test_fetch", Passed: true}}, nil, true)
+
+	if got := s.OnlyFlakyFailures([]Result{flakyFail, flakyFail}); len(got) != 1 {
+		t.Errorf("OnlyFlakyFailures() = %v, want the flaky test", got)
+	}
+	if got := s.OnlyFlakyFailures([]Result{flakyFail, {Suite: "tests/test_sum.py", Name: "test_add"}}); got != nil {
+		t.Errorf("OnlyFlakyFailures() with a genuine failure = %v, want nil", got)
+	}
+	if got := s.OnlyFlakyFailures(nil); got != nil {
+		t.Errorf("OnlyFlakyFailures() without failures = %v, want nil", got)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	tests := []struct {
+		suite   string
+		changed string
+		want    bool
+	}{
+		{"example.com/app/net", "net/fetch.go", true},
+		{"example.com/app/net", "calc/sum.go", false},
+		{"example.com/app/netutil", "net/fetch.go", false},
+		{"src/sum.test.js", "src/other.js", true},
+		{"src/sum.test.js", "lib/sum.ts", true},
+		{"src/sum.test.js", "lib/other.ts", false},
+		{"tests/test_sum.py", "app/sum.py", true},
+		{"tests/test_sum.py", "README.md", false},
+		{"tests/test_sum.py", "requirements.txt", true},
+		{"", "anything.go", true},
+	}
+
+	for _, tt := range tests {
+		if got := related(tt.suite, []string{tt.changed}); got != tt.want {
+			t.Errorf("related(%q, %q) = %v, want %v", tt.suite, tt.changed, got, tt.want)
+		}
+	}
+}
+
+func TestStoreSaveLoadUnmark(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ralph", "flaky.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of a missing file failed: %v", err)
+	}
+	for _, name := range []string{"TestA", "TestB"} {
+		s.Observe([]Result{{Suite: "pkg", Name: name}}, nil, true)
+		s.Observe([]Result{{Suite: "pkg", Name: name, Passed: true}}, nil, true)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.Quarantined(); len(got) != 2 || got[0].Name != "TestA" {
+		t.Fatalf("Quarantined() = %v, want TestA and TestB", got)
+	}
+	if n := loaded.Unmark("TestB"); n != 1 {
+		t.Errorf("Unmark(TestB) = %d, want 1", n)
+	}
+	if n := loaded.Unmark(""); n != 1 || len(loaded.Quarantined()) != 0 {
+		t.Errorf("Unmark(\"\") = %d, left %v", n, loaded.Quarantined())
+	}
+}
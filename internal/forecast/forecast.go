@@ -0,0 +1,138 @@
+// Package forecast estimates the iterations and wall-clock time needed to
+// finish the rest of a plan, from the complexity of the remaining features
+// and how earlier runs performed (the scope calibration and run history).
+package forecast
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/logimos/ralph/internal/scope"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// Feature is the estimate of a remaining feature
+type Feature struct {
+	ID          int              `json:"id"`
+	Description string           `json:"description"`
+	Milestone   string           `json:"milestone,omitempty"`
+	Complexity  scope.Complexity `json:"complexity"`
+	Iterations  int              `json:"iterations"`
+	Calibrated  bool             `json:"calibrated"` // Whether the estimate comes from completed features rather than the built-in default
+}
+
+// Estimate is the work left for a set of features
+type Estimate struct {
+	Features   int           `json:"features"`
+	Iterations int           `json:"iterations"`
+	Duration   time.Duration `json:"duration_ns,omitempty"` // Wall-clock time, 0 if no iteration time is known
+}
+
+// Milestone is the estimate of the remaining features of a milestone
+type Milestone struct {
+	Name string `json:"name"`
+	Estimate
+}
+
+// Forecast is the estimate of the work left in a plan
+type Forecast struct {
+	Total             Estimate      `json:"total"`
+	Milestones        []Milestone   `json:"milestones"`                      // Milestones with remaining features, in plan order
+	Features          []Feature     `json:"features"`                        // Remaining features, in plan order
+	Questions         int           `json:"questions"`                       // Open questions, which are not estimated
+	IterationDuration time.Duration `json:"iteration_duration_ns,omitempty"` // Average time of an iteration in recent runs
+}
+
+// New forecasts the remaining work of plans: the untested features that are
+// not deferred. Each is estimated from its complexity with the calibration
+// (built-in defaults if nil), and iterations take perIteration each (unknown
+// if 0).
+func New(plans []plan.Plan, calibration *scope.Calibration, perIteration time.Duration) *Forecast {
+	f := &Forecast{Milestones: []Milestone{}, Features: []Feature{}, IterationDuration: perIteration}
+	index := make(map[string]int)
+	for _, p := range plans {
+		if p.Tested || p.Deferred {
+			continue
+		}
+		if p.IsQuestion() {
+			f.Questions++
+			continue
+		}
+		complexity := scope.EstimateComplexity(len(p.Steps), p.Description)
+		feature := Feature{
+			ID:          p.ID,
+			Description: p.Description,
+			Milestone:   p.Milestone,
+			Complexity:  complexity,
+			Iterations:  calibration.Iterations(complexity),
+			Calibrated:  calibration.Calibrated(complexity),
+		}
+		f.Features = append(f.Features, feature)
+		f.Total.add(feature.Iterations, perIteration)
+
+		if p.Milestone == "" {
+			continue
+		}
+		i, ok := index[p.Milestone]
+		if !ok {
+			i = len(f.Milestones)
+			index[p.Milestone] = i
+			f.Milestones = append(f.Milestones, Milestone{Name: p.Milestone})
+		}
+		f.Milestones[i].add(feature.Iterations, perIteration)
+	}
+	return f
+}
+
+// add adds a feature of the given iterations to the estimate
+func (e *Estimate) add(iterations int, perIteration time.Duration) {
+	e.Features++
+	e.Iterations += iterations
+	e.Duration += time.Duration(iterations) * perIteration
+}
+
+// Calibrated reports whether every remaining feature is estimated from
+// completed features
+func (f *Forecast) Calibrated() bool {
+	for _, feature := range f.Features {
+		if !feature.Calibrated {
+			return false
+		}
+	}
+	return true
+}
+
+// Insufficient reports whether iterations are clearly too few to finish the
+// remaining features: the estimate is at least InsufficientRatio times more
+func (f *Forecast) Insufficient(iterations int) bool {
+	return iterations > 0 && float64(f.Total.Iterations) >= InsufficientRatio*float64(iterations)
+}
+
+// InsufficientRatio is how many times the estimated iterations must exceed
+// the iterations of a run for Insufficient to report it
+const InsufficientRatio = 1.5
+
+// String describes the estimate, e.g. "12 features, ~40 iterations, ~3h20m"
+func (e Estimate) String() string {
+	s := fmt.Sprintf("%d feature(s), ~%d iterations", e.Features, e.Iterations)
+	if e.Duration > 0 {
+		s += ", ~" + FormatDuration(e.Duration)
+	}
+	return s
+}
+
+// FormatDuration rounds a duration for display, e.g. "45m", "3h20m" or "2d5h"
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		hours := int(d.Round(time.Hour).Hours())
+		return fmt.Sprintf("%dd%dh", hours/24, hours%24)
+	}
+}
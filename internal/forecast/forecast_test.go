@@ -0,0 +1,80 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/scope"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestNew(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Description: "Done", Tested: true},
+		{ID: 2, Description: "Login form", Milestone: "Alpha"},                                                  // low
+		{ID: 3, Description: "Search", Milestone: "Beta", Steps: []string{"a", "b", "c"}},                       // medium
+		{ID: 4, Description: "Profile page", Milestone: "Alpha", Steps: []string{"a", "b", "c", "d", "e", "f"}}, // high
+		{ID: 5, Description: "Later", Deferred: true},
+		{ID: 6, Description: "Which database?", Type: plan.TypeQuestion},
+		{ID: 7, Description: "Footer"}, // low, no milestone
+	}
+	calibration := scope.NewCalibration()
+	for i := 0; i < scope.MinCalibrationSamples; i++ {
+		calibration.Add(scope.ComplexityLow, 2)
+	}
+
+	f := New(plans, calibration, 10*time.Minute)
+	want := Estimate{Features: 4, Iterations: 2 + 5 + 10 + 2, Duration: 190 * time.Minute}
+	if f.Total != want {
+		t.Errorf("Total = %+v, want %+v", f.Total, want)
+	}
+	if len(f.Features) != 4 || !f.Features[0].Calibrated || f.Features[1].Calibrated || f.Calibrated() {
+		t.Errorf("Features = %+v", f.Features)
+	}
+	if f.Questions != 1 {
+		t.Errorf("Questions = %d, want 1", f.Questions)
+	}
+	if len(f.Milestones) != 2 || f.Milestones[0].Name != "Alpha" || f.Milestones[0].Iterations != 12 || f.Milestones[1].Features != 1 {
+		t.Errorf("Milestones = %+v", f.Milestones)
+	}
+
+	// Without a calibration or iteration time, the defaults are used and no time is estimated
+	f = New(plans[:2], nil, 0)
+	if f.Total.Iterations != scope.ComplexityToIterations(scope.ComplexityLow) || f.Total.Duration != 0 {
+		t.Errorf("Total without history = %+v", f.Total)
+	}
+}
+
+func TestInsufficient(t *testing.T) {
+	f := &Forecast{Total: Estimate{Iterations: 30}}
+	tests := []struct {
+		iterations int
+		want       bool
+	}{
+		{10, true},
+		{20, true},
+		{21, false},
+		{40, false},
+		{0, false},
+	}
+	for _, tt := range tests {
+		if got := f.Insufficient(tt.iterations); got != tt.want {
+			t.Errorf("Insufficient(%d) = %v, want %v", tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		20 * time.Second:              "<1m",
+		45 * time.Minute:              "45m",
+		3*time.Hour + 5*time.Minute:   "3h05m",
+		53*time.Hour + 20*time.Minute: "2d5h",
+		47*time.Hour + 50*time.Minute: "2d0h",
+	}
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
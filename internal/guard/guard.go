@@ -0,0 +1,508 @@
+// Package guard detects and reverts changes made outside the repository root
+// during autonomous runs. It snapshots sensitive locations (e.g., ~/.ssh) and the
+// repository's git state before an iteration, and checks them afterwards.
+package guard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxEntriesPerPath caps the number of files tracked under each watched path
+	maxEntriesPerPath = 2000
+	// maxWatchDepth limits how deep watched directories are walked
+	maxWatchDepth = 3
+	// maxContentSize is the largest file whose content is captured for restoring
+	maxContentSize = 64 * 1024
+	// maxHashSize is the largest file compared by content; larger files are
+	// compared by modification time and size
+	maxHashSize = 16 * 1024 * 1024
+)
+
+// DefaultWatchedPaths returns the sensitive locations outside the repository that
+// are checked for modifications after each iteration. They are credential,
+// shell startup and system account, privilege and startup files that
+// background processes do not rewrite. Whole directories such as /etc,
+// ~/.kube or ~/.docker change on their own (DHCP, discovery caches,
+// credential helpers) and can be added with -guard-paths.
+func DefaultWatchedPaths() []string {
+	var paths []string
+
+	if runtime.GOOS == "windows" {
+		if systemRoot := os.Getenv("SystemRoot"); systemRoot != "" {
+			paths = append(paths, filepath.Join(systemRoot, "System32", "drivers", "etc", "hosts"))
+		}
+	} else {
+		paths = append(paths,
+			"/etc/passwd", "/etc/shadow", "/etc/group", "/etc/sudoers", "/etc/sudoers.d",
+			"/etc/hosts", "/etc/profile", "/etc/environment", "/etc/ld.so.preload",
+			"/etc/crontab", "/etc/cron.d", "/etc/ssh/sshd_config",
+		)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		for _, name := range []string{
+			".ssh/authorized_keys", ".ssh/config", ".ssh/id_rsa", ".ssh/id_ecdsa", ".ssh/id_ed25519",
+			".aws/credentials", ".aws/config", ".gnupg/private-keys-v1.d",
+			".git-credentials", ".netrc", ".npmrc", ".pypirc", ".gitconfig",
+			".bashrc", ".bash_profile", ".zshrc", ".profile",
+		} {
+			paths = append(paths, filepath.Join(home, name))
+		}
+	}
+
+	return paths
+}
+
+// ResolveWithin resolves path (relative paths are taken relative to root) after
+// following symlinks, and returns an error if the result lies outside root.
+// Paths that do not exist yet are resolved through their nearest existing parent.
+func ResolveWithin(root, path string) (string, error) {
+	realRoot, err := resolve(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root %s: %w", root, err)
+	}
+
+	target := path
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+	resolved, err := resolve(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	if !isWithin(realRoot, resolved) {
+		return resolved, fmt.Errorf("%s resolves to %s, outside the repository %s", path, resolved, realRoot)
+	}
+	return resolved, nil
+}
+
+// resolve returns the absolute, symlink-free form of path. For paths that do not
+// exist, the nearest existing ancestor is resolved and the remainder appended.
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var rest []string
+	current := abs
+	for {
+		real, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			parts := append([]string{real}, rest...)
+			return filepath.Join(parts...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
+
+// isWithin reports whether path is root or lies below it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// fileState records the observable state of a watched file
+type fileState struct {
+	modTime    time.Time
+	size       int64
+	mode       fs.FileMode
+	hash       []byte // SHA-256 of the content, for regular files up to maxHashSize
+	content    []byte
+	hasContent bool
+}
+
+// changed reports whether a watched file differs from its earlier state.
+// Files are compared by content, so rewriting a file with the same content
+// (e.g., touching it) is not a change.
+func (f fileState) changed(old fileState) bool {
+	if f.mode != old.mode {
+		return true
+	}
+	if f.hash != nil && old.hash != nil {
+		return !bytes.Equal(f.hash, old.hash)
+	}
+	return !f.modTime.Equal(old.modTime) || f.size != old.size
+}
+
+// Snapshot captures the state checked by the guard before an iteration
+type Snapshot struct {
+	files     map[string]fileState
+	head      string
+	dirty     map[string]bool // Repository paths with uncommitted changes
+	isGitRepo bool
+}
+
+// Violation describes a change outside the repository
+type Violation struct {
+	Path          string // Path that was touched
+	Change        string // modified, created, deleted, or escapes repository
+	Restored      bool   // True if the original state was restored
+	Unrecoverable bool   // True if the original content was not captured (files over 64 KB), so it cannot be restored
+}
+
+// String returns a human-readable description of the violation
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s: %s", v.Change, v.Path)
+	switch {
+	case v.Restored:
+		s += " (restored)"
+	case v.Unrecoverable:
+		s += " (cannot be restored: too large to snapshot)"
+	}
+	return s
+}
+
+// Guard checks that iterations only mutate files inside the repository root
+type Guard struct {
+	root    string
+	watched []string
+	ignored []string // Repository paths left alone by Revert, slash-separated; directories end in "/"
+}
+
+// New creates a guard for the repository at root, watching the default
+// sensitive locations plus any extra paths. Watched paths inside the root are ignored.
+func New(root string, extra []string) (*Guard, error) {
+	realRoot, err := resolve(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve repository root: %w", err)
+	}
+
+	g := &Guard{root: realRoot}
+	seen := make(map[string]bool)
+	for _, p := range append(DefaultWatchedPaths(), extra...) {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				p = filepath.Join(home, p[2:])
+			}
+		}
+		real, err := resolve(p)
+		if err != nil || seen[real] || isWithin(realRoot, real) {
+			continue
+		}
+		seen[real] = true
+		g.watched = append(g.watched, real)
+	}
+
+	return g, nil
+}
+
+// Root returns the resolved repository root
+func (g *Guard) Root() string {
+	return g.root
+}
+
+// Watched returns the resolved list of watched paths
+func (g *Guard) Watched() []string {
+	return g.watched
+}
+
+// Ignore makes Revert leave repository paths alone, such as Ralph's own state
+// written during an iteration. Paths are relative to the root or absolute;
+// directories are given with a trailing slash.
+func (g *Guard) Ignore(paths ...string) {
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		dir := strings.HasSuffix(p, "/") || strings.HasSuffix(p, string(filepath.Separator))
+		if filepath.IsAbs(p) {
+			real, err := resolve(p)
+			if err != nil || !isWithin(g.root, real) {
+				continue
+			}
+			if p, err = filepath.Rel(g.root, real); err != nil {
+				continue
+			}
+		}
+		p = filepath.ToSlash(filepath.Clean(p))
+		if dir {
+			p += "/"
+		}
+		g.ignored = append(g.ignored, p)
+	}
+}
+
+// isIgnored reports whether a repository path, as listed by git, is ignored
+func (g *Guard) isIgnored(path string) bool {
+	for _, p := range g.ignored {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot records the current state of watched paths and the repository
+func (g *Guard) Snapshot() *Snapshot {
+	snap := &Snapshot{
+		files: make(map[string]fileState),
+		dirty: make(map[string]bool),
+	}
+
+	for _, path := range g.watched {
+		g.snapshotPath(path, snap.files)
+	}
+
+	if head, err := g.git("rev-parse", "HEAD"); err == nil {
+		snap.isGitRepo = true
+		snap.head = strings.TrimSpace(head)
+		paths, _ := g.changedPaths()
+		for _, p := range paths {
+			snap.dirty[p] = true
+		}
+	}
+
+	return snap
+}
+
+// snapshotPath records the state of a file or of the files under a directory
+func (g *Guard) snapshotPath(path string, files map[string]fileState) {
+	baseDepth := strings.Count(path, string(filepath.Separator))
+	count := 0
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped
+		}
+		if count >= maxEntriesPerPath {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if strings.Count(p, string(filepath.Separator))-baseDepth >= maxWatchDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		state := fileState{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+		if info.Mode().IsRegular() && info.Size() <= maxContentSize {
+			if data, err := os.ReadFile(p); err == nil {
+				sum := sha256.Sum256(data)
+				state.hash = sum[:]
+				state.content = data
+				state.hasContent = true
+			}
+		} else if info.Mode().IsRegular() && info.Size() <= maxHashSize {
+			state.hash = hashFile(p)
+		}
+		files[p] = state
+		count++
+		return nil
+	})
+}
+
+// hashFile returns the SHA-256 of a file's content, or nil if it cannot be read
+func hashFile(path string) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil
+	}
+	return h.Sum(nil)
+}
+
+// Check compares the current state against a snapshot taken before the
+// iteration and returns the watched files changed outside the repository and
+// the repository paths changed during the iteration that resolve outside it.
+// Paths that were already changed before the snapshot are not checked again.
+func (g *Guard) Check(before *Snapshot) []Violation {
+	var violations []Violation
+
+	after := make(map[string]fileState)
+	for _, path := range g.watched {
+		g.snapshotPath(path, after)
+	}
+
+	for path, old := range before.files {
+		cur, ok := after[path]
+		switch {
+		case !ok:
+			violations = append(violations, Violation{Path: path, Change: "deleted", Unrecoverable: !old.hasContent})
+		case cur.changed(old):
+			violations = append(violations, Violation{Path: path, Change: "modified", Unrecoverable: !old.hasContent})
+		}
+	}
+	for path := range after {
+		if _, ok := before.files[path]; !ok {
+			violations = append(violations, Violation{Path: path, Change: "created"})
+		}
+	}
+
+	// Files changed inside the repository must not resolve outside it (e.g., via symlinks)
+	if before.isGitRepo {
+		paths, _ := g.changedPaths()
+		for _, p := range paths {
+			if before.dirty[p] {
+				continue // Predates the iteration
+			}
+			if _, err := ResolveWithin(g.root, p); err != nil {
+				violations = append(violations, Violation{Path: filepath.Join(g.root, p), Change: "escapes repository"})
+			}
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].Path < violations[j].Path
+	})
+	return violations
+}
+
+// Revert undoes the iteration: watched files with captured content are restored,
+// commits made since the snapshot are reset (keeping earlier local changes), and
+// repository paths that were clean before the snapshot are restored or removed.
+// The Restored flag on each violation is updated.
+func (g *Guard) Revert(before *Snapshot, violations []Violation) error {
+	var errs []string
+
+	for i, v := range violations {
+		old, ok := before.files[v.Path]
+		if !ok || !old.hasContent {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(v.Path), 0700); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := os.WriteFile(v.Path, old.content, old.mode.Perm()); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		os.Chtimes(v.Path, old.modTime, old.modTime)
+		violations[i].Restored = true
+	}
+
+	if before.isGitRepo {
+		if err := g.revertRepo(before); err != nil {
+			errs = append(errs, err.Error())
+		}
+		for i, v := range violations {
+			if v.Change == "escapes repository" {
+				if _, err := os.Lstat(v.Path); os.IsNotExist(err) {
+					violations[i].Restored = true
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("revert incomplete: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// revertRepo restores the repository to the snapshot state
+func (g *Guard) revertRepo(before *Snapshot) error {
+	if head, err := g.git("rev-parse", "HEAD"); err == nil && strings.TrimSpace(head) != before.head && before.head != "" {
+		if _, err := g.git("reset", "--keep", before.head); err != nil {
+			return fmt.Errorf("failed to reset to %s: %w", before.head, err)
+		}
+	}
+
+	paths, err := g.changedPaths()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		if before.dirty[p] || g.isIgnored(p) {
+			continue // Changes that predate the iteration, and Ralph's state, are left alone
+		}
+		if _, err := g.git("cat-file", "-e", "HEAD:"+p); err == nil {
+			if _, err := g.git("checkout", "HEAD", "--", p); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", p, err)
+			}
+			continue
+		}
+		g.git("rm", "--cached", "--quiet", "--", p)
+		if err := os.RemoveAll(filepath.Join(g.root, p)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// changedPaths lists repository paths with uncommitted changes, including untracked files
+func (g *Guard) changedPaths() ([]string, error) {
+	out, err := g.git("status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		status, path := entry[:2], entry[3:]
+		paths = append(paths, path)
+		// Renames and copies are followed by the original path
+		if status[0] == 'R' || status[0] == 'C' {
+			i++
+		}
+	}
+	return paths, nil
+}
+
+// git runs a git command in the repository root
+func (g *Guard) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.root
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// FormatViolations returns a multi-line description of the violations
+func FormatViolations(violations []Violation) string {
+	var sb strings.Builder
+	for _, v := range violations {
+		sb.WriteString("  - ")
+		sb.WriteString(v.String())
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
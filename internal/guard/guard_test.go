@@ -0,0 +1,289 @@
+package guard
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initRepo creates a git repository with a single committed file
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	run("init", "-q")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	run("add", ".")
+	run("commit", "-q", "-m", "initial")
+	return dir
+}
+
+func TestResolveWithin(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "pkg"), 0755)
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"pkg/file.go", false},
+		{"new/dir/file.go", false},
+		{filepath.Join(root, "main.go"), false},
+		{"../escape.txt", true},
+		{"/etc/passwd", true},
+		{"link/authorized_keys", true},
+	}
+
+	for _, tt := range tests {
+		_, err := ResolveWithin(root, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveWithin(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNew_SkipsWatchedPathsInsideRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	g, err := New(root, []string{filepath.Join(root, "sub"), outside, outside})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	count := 0
+	for _, w := range g.Watched() {
+		if isWithin(g.Root(), w) {
+			t.Errorf("watched path %s is inside root", w)
+		}
+		if real, _ := resolve(outside); w == real {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected extra path to be watched exactly once, got %d", count)
+	}
+}
+
+func TestGuard_DetectsAndRestoresOutsideChanges(t *testing.T) {
+	root := initRepo(t)
+	outside := t.TempDir()
+	keyFile := filepath.Join(outside, "authorized_keys")
+	os.WriteFile(keyFile, []byte("original\n"), 0600)
+
+	g, err := New(root, []string{outside})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	before := g.Snapshot()
+	if v := g.Check(before); len(v) != 0 {
+		t.Fatalf("expected no violations without changes, got %v", v)
+	}
+
+	// Simulate an agent touching files inside and outside the repository
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main // changed\n"), 0644)
+	os.WriteFile(filepath.Join(root, "new.go"), []byte("package main\n"), 0644)
+	os.WriteFile(keyFile, []byte("attacker key\n"), 0600)
+	os.WriteFile(filepath.Join(outside, "dropped"), []byte("x"), 0644)
+
+	violations := g.Check(before)
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %v", violations)
+	}
+	report := FormatViolations(violations)
+	if !strings.Contains(report, "modified: "+keyFile) || !strings.Contains(report, "created:") {
+		t.Errorf("unexpected violations:\n%s", report)
+	}
+
+	if err := g.Revert(before, violations); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(keyFile)
+	if string(data) != "original\n" {
+		t.Errorf("outside file not restored, got %q", string(data))
+	}
+	data, _ = os.ReadFile(filepath.Join(root, "main.go"))
+	if string(data) != "package main\n" {
+		t.Errorf("repository change not reverted, got %q", string(data))
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); !os.IsNotExist(err) {
+		t.Error("untracked file created during the iteration should be removed")
+	}
+}
+
+func TestGuard_RevertKeepsPreexistingChanges(t *testing.T) {
+	root := initRepo(t)
+	g, err := New(root, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// Local work that predates the iteration
+	os.WriteFile(filepath.Join(root, "wip.go"), []byte("package main\n"), 0644)
+
+	before := g.Snapshot()
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main // changed\n"), 0644)
+
+	if err := g.Revert(before, nil); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "wip.go")); err != nil {
+		t.Error("pre-existing untracked file should be kept")
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "main.go"))
+	if string(data) != "package main\n" {
+		t.Errorf("iteration change not reverted, got %q", string(data))
+	}
+}
+
+func TestGuard_DetectsSymlinkEscape(t *testing.T) {
+	root := initRepo(t)
+	outside := t.TempDir()
+
+	g, err := New(root, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	before := g.Snapshot()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	violations := g.Check(before)
+	if len(violations) != 1 || violations[0].Change != "escapes repository" {
+		t.Fatalf("expected symlink escape violation, got %v", violations)
+	}
+
+	if err := g.Revert(before, violations); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if !violations[0].Restored {
+		t.Error("escaping symlink should be removed on revert")
+	}
+}
+
+func TestDefaultWatchedPaths(t *testing.T) {
+	paths := DefaultWatchedPaths()
+	for _, p := range paths {
+		for _, noisy := range []string{"/etc", "/etc/resolv.conf", ".kube", ".docker", filepath.Join(".config", "gh")} {
+			if p == noisy || strings.HasSuffix(p, string(filepath.Separator)+noisy) {
+				t.Errorf("DefaultWatchedPaths() includes %s, which background processes rewrite", p)
+			}
+		}
+	}
+	if runtime.GOOS != "windows" {
+		for _, system := range []string{"/etc/passwd", "/etc/sudoers", "/etc/ld.so.preload"} {
+			if !slices.Contains(paths, system) {
+				t.Errorf("DefaultWatchedPaths() does not include %s", system)
+			}
+		}
+	}
+}
+
+func TestGuard_IgnoresPreexistingSymlinkEscape(t *testing.T) {
+	root := initRepo(t)
+	if err := os.Symlink(t.TempDir(), filepath.Join(root, "escape")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+
+	g, err := New(root, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	before := g.Snapshot()
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main // changed\n"), 0644)
+
+	if v := g.Check(before); len(v) != 0 {
+		t.Errorf("symlink that predates the iteration reported: %v", v)
+	}
+}
+
+func TestGuard_RevertKeepsIgnoredPaths(t *testing.T) {
+	root := initRepo(t)
+	g, err := New(root, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	g.Ignore(".ralph/", "progress.txt", filepath.Join(root, "state")+string(filepath.Separator))
+
+	before := g.Snapshot()
+	kept := []string{filepath.Join(".ralph", "diffs", "1.diff"), "progress.txt", filepath.Join("state", "run.json")}
+	for _, p := range append(kept, "new.go") {
+		os.MkdirAll(filepath.Join(root, filepath.Dir(p)), 0755)
+		os.WriteFile(filepath.Join(root, p), []byte("data\n"), 0644)
+	}
+
+	if err := g.Revert(before, nil); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	for _, p := range kept {
+		if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+			t.Errorf("ignored path %s removed by Revert", p)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); !os.IsNotExist(err) {
+		t.Error("file created by the iteration not removed")
+	}
+}
+
+func TestGuard_ComparesContent(t *testing.T) {
+	root := initRepo(t)
+	outside := t.TempDir()
+	config := filepath.Join(outside, "config")
+	os.WriteFile(config, []byte("region = us-east-1\n"), 0600)
+	large := filepath.Join(outside, "large")
+	os.WriteFile(large, []byte(strings.Repeat("x", maxContentSize+1)), 0600)
+
+	g, err := New(root, []string{outside})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	before := g.Snapshot()
+
+	// Rewriting a file with the same content is not a change
+	later := time.Now().Add(time.Hour)
+	os.WriteFile(config, []byte("region = us-east-1\n"), 0600)
+	os.Chtimes(config, later, later)
+	if v := g.Check(before); len(v) != 0 {
+		t.Fatalf("expected no violations for unchanged content, got %v", v)
+	}
+
+	// Files too large to snapshot are reported as not restorable
+	os.WriteFile(large, []byte(strings.Repeat("y", maxContentSize+1)), 0600)
+	violations := g.Check(before)
+	if len(violations) != 1 || violations[0].Change != "modified" || !violations[0].Unrecoverable {
+		t.Fatalf("expected an unrecoverable modification, got %+v", violations)
+	}
+	if err := g.Revert(before, violations); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if violations[0].Restored || !strings.Contains(violations[0].String(), "cannot be restored") {
+		t.Errorf("large file reported as %q", violations[0].String())
+	}
+}
@@ -0,0 +1,180 @@
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/prompt"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// apiKeyEnv holds the key the fake agent is called with
+const apiKeyEnv = "RALPH_HARNESS_API_KEY"
+
+// Reply is a scripted response of the fake agent to one iteration
+type Reply struct {
+	Output   string            // Text of the response, e.g. test output with failures
+	Files    map[string]string // Files to write, by path relative to the repository
+	Tested   []int             // Features to mark tested in the plan file
+	Complete bool              // Append the completion signal
+}
+
+// Script chooses the reply to the n-th call (starting at 1) given its prompt
+type Script func(n int, prompt string) Reply
+
+// Agent is a fake agent: an OpenAI-compatible API server answering each
+// request with a scripted reply. Files of the reply are written by Ralph's
+// API backend, exactly as it does for a real model.
+type Agent struct {
+	t        testing.TB
+	server   *httptest.Server
+	script   Script
+	planFile string
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+// NewAgent starts a fake agent that answers with the replies in order, and
+// with an empty reply once they are used up
+func NewAgent(t testing.TB, replies ...Reply) *Agent {
+	return NewScriptedAgent(t, func(n int, _ string) Reply {
+		if n > len(replies) {
+			return Reply{Output: "Nothing left to do."}
+		}
+		return replies[n-1]
+	})
+}
+
+// NewScriptedAgent starts a fake agent whose replies are chosen by script
+func NewScriptedAgent(t testing.TB, script Script) *Agent {
+	t.Helper()
+	a := &Agent{t: t, script: script, planFile: "plan.json"}
+	a.server = httptest.NewServer(http.HandlerFunc(a.handle))
+	t.Cleanup(a.server.Close)
+	return a
+}
+
+// Configure makes cfg use the fake agent as its API backend
+func (a *Agent) Configure(cfg *config.Config) {
+	a.t.Setenv(apiKeyEnv, "harness")
+	cfg.AgentBackend = "openai"
+	cfg.APIBaseURL = a.server.URL
+	cfg.APIModel = "harness"
+	cfg.APIKeyEnv = apiKeyEnv
+	a.planFile = cfg.PlanFile
+}
+
+// Prompts returns the prompts received so far, in order
+func (a *Agent) Prompts() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.prompts...)
+}
+
+// Calls returns the number of requests received so far
+func (a *Agent) Calls() int {
+	return len(a.Prompts())
+}
+
+// handle answers a chat completion request with the next scripted reply
+func (a *Agent) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var userPrompt string
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			userPrompt = m.Content
+		}
+	}
+
+	a.mu.Lock()
+	a.prompts = append(a.prompts, userPrompt)
+	n := len(a.prompts)
+	a.mu.Unlock()
+
+	content, err := a.render(a.script(n, userPrompt))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": map[string]string{"role": "assistant", "content": content}},
+		},
+		"usage": map[string]int{"prompt_tokens": len(userPrompt) / 4, "completion_tokens": len(content) / 4},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// render turns a reply into response text with file blocks
+func (a *Agent) render(reply Reply) (string, error) {
+	files := make(map[string]string, len(reply.Files)+1)
+	for path, content := range reply.Files {
+		files[path] = content
+	}
+	if len(reply.Tested) > 0 {
+		planJSON, err := a.markTested(reply.Tested)
+		if err != nil {
+			return "", err
+		}
+		files[a.planFile] = planJSON
+	}
+
+	var sb strings.Builder
+	sb.WriteString(reply.Output)
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(&sb, "\n<file path=%q>\n%s</file>", path, files[path])
+	}
+	if reply.Complete {
+		sb.WriteString("\n" + prompt.CompleteSignal)
+	}
+	return sb.String(), nil
+}
+
+// markTested returns the plan file with the given features marked tested
+func (a *Agent) markTested(ids []int) (string, error) {
+	plans, err := plan.ReadFile(a.planFile)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range ids {
+		p := plan.GetByID(plans, id)
+		if p == nil {
+			return "", fmt.Errorf("feature #%d not found in plan", id)
+		}
+		p.Tested = true
+	}
+	data, err := json.MarshalIndent(plans, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,119 @@
+// Package harness supports end-to-end tests of the Ralph main loop. It sets up
+// a temporary git repository holding a plan, and a fake agent served through
+// the OpenAI-compatible API backend whose replies are scripted by the test, so
+// that recovery, replanning and deferral can be exercised across modules
+// without a real agent CLI.
+package harness
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// Repo is a temporary git repository that is the current directory of a test
+type Repo struct {
+	t   testing.TB
+	Dir string
+}
+
+// NewRepo creates a git repository with the plans committed in plan.json and
+// an empty progress.txt, and makes it the current directory of the test
+func NewRepo(t testing.TB, plans ...plan.Plan) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	r := &Repo{t: t, Dir: t.TempDir()}
+	t.Chdir(r.Dir)
+	t.Setenv("GIT_AUTHOR_NAME", "ralph-harness")
+	t.Setenv("GIT_AUTHOR_EMAIL", "harness@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "ralph-harness")
+	t.Setenv("GIT_COMMITTER_EMAIL", "harness@example.com")
+
+	if err := plan.WriteFile("plan.json", plans); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+	r.WriteFile("progress.txt", "")
+	r.Git("init", "-q")
+	r.Git("add", ".")
+	r.Git("commit", "-q", "-m", "initial plan")
+	return r
+}
+
+// Config returns the default configuration for a run in the repository, with
+// quiet output (set Quiet to false to see the run when debugging a test) and
+// no path guard
+func (r *Repo) Config() *config.Config {
+	cfg := config.New()
+	cfg.PlanFile = "plan.json"
+	cfg.ProgressFile = "progress.txt"
+	cfg.NoColor = true
+	cfg.Quiet = true
+	cfg.NoPathGuard = true
+	return cfg
+}
+
+// Plans returns the plans currently in plan.json
+func (r *Repo) Plans() []plan.Plan {
+	r.t.Helper()
+	plans, err := plan.ReadFile("plan.json")
+	if err != nil {
+		r.t.Fatalf("failed to read plan: %v", err)
+	}
+	return plans
+}
+
+// Feature returns a feature of the current plan, failing the test if it is missing
+func (r *Repo) Feature(id int) plan.Plan {
+	r.t.Helper()
+	p := plan.GetByID(r.Plans(), id)
+	if p == nil {
+		r.t.Fatalf("feature #%d not found in plan", id)
+	}
+	return *p
+}
+
+// Progress returns the contents of progress.txt
+func (r *Repo) Progress() string {
+	return r.ReadFile("progress.txt")
+}
+
+// ReadFile returns the contents of a file in the repository, or "" if it does not exist
+func (r *Repo) ReadFile(path string) string {
+	data, err := os.ReadFile(filepath.Join(r.Dir, path))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// WriteFile writes a file in the repository, creating its directory
+func (r *Repo) WriteFile(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.Dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// Git runs git in the repository and returns its trimmed output
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
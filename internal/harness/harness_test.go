@@ -0,0 +1,77 @@
+package harness
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/internal/agent"
+	"github.com/logimos/ralph/internal/prompt"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestAgentAppliesReplies(t *testing.T) {
+	repo := NewRepo(t, plan.Plan{ID: 1, Description: "Add greeting"}, plan.Plan{ID: 2, Description: "Add farewell"})
+	fake := NewAgent(t, Reply{
+		Output:   "Added the greeting.",
+		Files:    map[string]string{"greet/hello.txt": "hello\n"},
+		Tested:   []int{1},
+		Complete: true,
+	})
+	cfg := repo.Config()
+	fake.Configure(cfg)
+
+	out, err := agent.Execute(cfg, "Implement feature #1")
+	if err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if !strings.Contains(out, "Added the greeting.") || !strings.Contains(out, prompt.CompleteSignal) {
+		t.Errorf("output = %q, want the reply text and completion signal", out)
+	}
+	if got := repo.ReadFile("greet/hello.txt"); got != "hello\n" {
+		t.Errorf("hello.txt = %q", got)
+	}
+	if !repo.Feature(1).Tested || repo.Feature(2).Tested {
+		t.Errorf("plan = %+v, want only feature #1 tested", repo.Plans())
+	}
+
+	// Replies are used up: the agent answers without changes
+	if _, err := agent.Execute(cfg, "Implement feature #2"); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if prompts := fake.Prompts(); len(prompts) != 2 || !strings.Contains(prompts[1], "feature #2") {
+		t.Errorf("prompts = %q", prompts)
+	}
+	if repo.Feature(2).Tested {
+		t.Error("feature #2 marked tested by an empty reply")
+	}
+}
+
+func TestScriptedAgent(t *testing.T) {
+	repo := NewRepo(t)
+	fake := NewScriptedAgent(t, func(n int, p string) Reply {
+		return Reply{Output: strings.Repeat("x", n) + " " + p}
+	})
+	cfg := repo.Config()
+	fake.Configure(cfg)
+
+	for _, want := range []string{"x one", "xx two"} {
+		out, err := agent.Execute(cfg, strings.Fields(want)[1])
+		if err != nil || out != want {
+			t.Errorf("Execute() = %q, %v; want %q", out, err, want)
+		}
+	}
+}
+
+func TestRepo(t *testing.T) {
+	repo := NewRepo(t, plan.Plan{ID: 1, Description: "Feature"})
+	if repo.Git("status", "--porcelain") != "" {
+		t.Error("new repository has uncommitted changes")
+	}
+	repo.WriteFile("dir/file.txt", "content")
+	if repo.ReadFile("dir/file.txt") != "content" || repo.ReadFile("missing") != "" {
+		t.Error("ReadFile() did not return the written content")
+	}
+	if len(repo.Plans()) != 1 || repo.Progress() != "" {
+		t.Errorf("plans = %v, progress = %q", repo.Plans(), repo.Progress())
+	}
+}
@@ -0,0 +1,511 @@
+// Package history records per-run statistics so that separate Ralph runs can be
+// compared against each other (e.g., A/B testing agents, models, or prompt templates
+// on the same plan).
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/config"
+)
+
+// runIDTimeFormat is the timestamp layout used when generating run IDs
+const runIDTimeFormat = "20060102-150405"
+
+// Run captures the outcome of a single Ralph run
+type Run struct {
+	ID                   string            `json:"id"`
+	Label                string            `json:"label,omitempty"` // User-supplied label (e.g., "claude-baseline")
+	Agent                string            `json:"agent"`
+	PlanFile             string            `json:"plan_file"`
+	StartTime            time.Time         `json:"start_time"`
+	EndTime              time.Time         `json:"end_time"`
+	IterationsLimit      int               `json:"iterations_limit"`
+	IterationsRun        int               `json:"iterations_run"`
+	Completed            bool              `json:"completed"`          // True if the completion signal was detected
+	FeaturesCompleted    []int             `json:"features_completed"` // Feature IDs marked tested during this run
+	FeaturesSkipped      int               `json:"features_skipped"`
+	FeaturesTotal        int               `json:"features_total,omitempty"`  // Features of the plan at the end of the run
+	FeaturesTested       int               `json:"features_tested,omitempty"` // Tested features of the plan at the end of the run
+	Failures             int               `json:"failures"`
+	FailuresRecovered    int               `json:"failures_recovered"`
+	IterationsPerFeature map[int]int       `json:"iterations_per_feature,omitempty"`
+	FeatureComplexity    map[int]string    `json:"feature_complexity,omitempty"` // Estimated complexity of each feature worked on
+	InputTokens          int               `json:"input_tokens,omitempty"`       // Input tokens consumed, when the backend reports them
+	OutputTokens         int               `json:"output_tokens,omitempty"`      // Output tokens consumed, when the backend reports them
+	Cost                 float64           `json:"cost,omitempty"`               // Estimated cost in USD, when the backend reports it
+	IterationAgents      map[int]string    `json:"iteration_agents,omitempty"`   // Agent that served each iteration, when fallback agents are configured
+	Tags                 map[string]string `json:"tags,omitempty"`
+
+	// Per-feature outcomes, aggregated by -analytics
+	FeatureCategories map[int]string          `json:"feature_categories,omitempty"` // Category of each feature worked on
+	FeatureFailures   map[int]int             `json:"feature_failures,omitempty"`   // Failures of each feature
+	FailuresByType    map[string]int          `json:"failures_by_type,omitempty"`
+	Validations       map[int]ValidationCount `json:"validations,omitempty"` // Validation results of each feature validated
+	Deferrals         map[int]string          `json:"deferrals,omitempty"`   // Reason of each feature deferred during the run
+}
+
+// ValidationCount is the number of validations of a feature that passed and
+// failed
+type ValidationCount struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+// RunID returns the ID of a run started at t
+func RunID(t time.Time) string {
+	return "run-" + t.Format(runIDTimeFormat)
+}
+
+// NewRun creates a new run record starting now
+func NewRun(agent, planFile, label string) *Run {
+	now := time.Now()
+	return &Run{
+		ID:                   RunID(now),
+		Label:                label,
+		Agent:                agent,
+		PlanFile:             planFile,
+		StartTime:            now,
+		FeaturesCompleted:    []int{},
+		IterationsPerFeature: make(map[int]int),
+	}
+}
+
+// Duration returns how long the run took
+func (r *Run) Duration() time.Duration {
+	if r.EndTime.IsZero() {
+		return time.Since(r.StartTime)
+	}
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// AverageIterationsPerFeature returns the mean number of iterations spent per
+// completed feature, or 0 if no features were completed
+func (r *Run) AverageIterationsPerFeature() float64 {
+	if len(r.FeaturesCompleted) == 0 {
+		return 0
+	}
+	return float64(r.IterationsRun) / float64(len(r.FeaturesCompleted))
+}
+
+// DisplayName returns the label if set, otherwise the run ID
+func (r *Run) DisplayName() string {
+	if r.Label != "" {
+		return r.Label
+	}
+	return r.ID
+}
+
+// VelocityWindow is how far back Velocity looks for completed features
+const VelocityWindow = 14 * 24 * time.Hour
+
+// Velocity returns the number of features completed per day by the runs
+// started within VelocityWindow before now. The rate is measured from the
+// first of those runs (at least one day), so idle days count. It returns 0
+// if no feature was completed in the window.
+func Velocity(runs []*Run, now time.Time) float64 {
+	since := now.Add(-VelocityWindow)
+	var first time.Time
+	completed := 0
+	for _, r := range runs {
+		if r.StartTime.Before(since) || r.StartTime.After(now) {
+			continue
+		}
+		if first.IsZero() || r.StartTime.Before(first) {
+			first = r.StartTime
+		}
+		completed += len(r.FeaturesCompleted)
+	}
+	if completed == 0 {
+		return 0
+	}
+	days := now.Sub(first).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	return float64(completed) / days
+}
+
+// FeatureDuration returns the average run time per feature completed by the
+// finished runs started within VelocityWindow before now, or 0 if none of
+// them completed a feature. Unlike Velocity it ignores the time between runs.
+func FeatureDuration(runs []*Run, now time.Time) time.Duration {
+	since := now.Add(-VelocityWindow)
+	var total time.Duration
+	completed := 0
+	for _, r := range runs {
+		if r.EndTime.IsZero() || r.StartTime.Before(since) || r.StartTime.After(now) {
+			continue
+		}
+		total += r.Duration()
+		completed += len(r.FeaturesCompleted)
+	}
+	if completed == 0 {
+		return 0
+	}
+	return total / time.Duration(completed)
+}
+
+// IterationDuration returns the average wall-clock time of an iteration of
+// the finished runs started within VelocityWindow before now, or 0 if they
+// ran no iterations
+func IterationDuration(runs []*Run, now time.Time) time.Duration {
+	since := now.Add(-VelocityWindow)
+	var total time.Duration
+	iterations := 0
+	for _, r := range runs {
+		if r.EndTime.IsZero() || r.IterationsRun == 0 || r.StartTime.Before(since) || r.StartTime.After(now) {
+			continue
+		}
+		total += r.Duration()
+		iterations += r.IterationsRun
+	}
+	if iterations == 0 {
+		return 0
+	}
+	return total / time.Duration(iterations)
+}
+
+// CompletedFeatureIterations returns the iterations taken by the features
+// that runs of agent (of any agent if empty) completed, grouped by the
+// complexity estimated for them. Features without a recorded complexity are
+// left out.
+func CompletedFeatureIterations(runs []*Run, agent string) map[string][]int {
+	iterations := make(map[string][]int)
+	for _, r := range runs {
+		if agent != "" && r.Agent != agent {
+			continue
+		}
+		for _, id := range r.FeaturesCompleted {
+			complexity := r.FeatureComplexity[id]
+			if complexity == "" || r.IterationsPerFeature[id] == 0 {
+				continue
+			}
+			iterations[complexity] = append(iterations[complexity], r.IterationsPerFeature[id])
+		}
+	}
+	return iterations
+}
+
+// Store handles persistence of run records
+type Store struct {
+	dir string
+}
+
+// NewStore creates a new history store rooted at the given directory, or at
+// config.DefaultHistoryDir if dir is empty
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = config.DefaultHistoryDir
+	}
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory where run records are stored
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Reserve claims the run's ID in the store before the run is saved, so that
+// runs started in the same second (e.g., -parallel workers) do not overwrite
+// each other's record. If the ID is taken, a numeric suffix is appended to it.
+// The reserved record stays empty, and unlisted, until Save writes it.
+func (s *Store) Reserve(run *Run) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	base := run.ID
+	for n := 2; ; n++ {
+		f, err := os.OpenFile(filepath.Join(s.dir, run.ID+".json"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return f.Close()
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("failed to reserve run ID: %w", err)
+		}
+		run.ID = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// Save writes a run record to disk
+func (s *Store) Save(run *Run) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	path := filepath.Join(s.dir, run.ID+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run file: %w", err)
+	}
+
+	return nil
+}
+
+// List returns all recorded runs sorted by start time (oldest first)
+func (s *Store) List() ([]*Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Run{}, nil
+		}
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var runs []*Run
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		run, err := readRun(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue // Skip unreadable records rather than failing the whole listing
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartTime.Before(runs[j].StartTime)
+	})
+
+	return runs, nil
+}
+
+// Find resolves a run reference. The reference may be a run ID, a unique ID
+// prefix, a label, or one of the aliases "latest" and "previous".
+func (s *Store) Find(ref string) (*Run, error) {
+	runs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no runs recorded in %s", s.dir)
+	}
+
+	switch strings.ToLower(ref) {
+	case "latest", "last":
+		return runs[len(runs)-1], nil
+	case "previous", "prev":
+		if len(runs) < 2 {
+			return nil, fmt.Errorf("only one run recorded; no previous run available")
+		}
+		return runs[len(runs)-2], nil
+	}
+
+	// Exact ID match first
+	for _, run := range runs {
+		if run.ID == ref {
+			return run, nil
+		}
+	}
+
+	// Label match (most recent wins)
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Label != "" && runs[i].Label == ref {
+			return runs[i], nil
+		}
+	}
+
+	// Unique prefix match
+	var matches []*Run
+	for _, run := range runs {
+		if strings.HasPrefix(run.ID, ref) {
+			matches = append(matches, run)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("run reference %q is ambiguous (%d matches)", ref, len(matches))
+	}
+
+	return nil, fmt.Errorf("run not found: %s", ref)
+}
+
+// readRun loads a single run record from disk
+func readRun(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Comparison holds the differences between two runs
+type Comparison struct {
+	A                 *Run
+	B                 *Run
+	OnlyCompletedInA  []int // Features completed by A but not B
+	OnlyCompletedInB  []int // Features completed by B but not A
+	CompletedInBoth   []int
+	FeatureIterations []FeatureIterationDelta
+}
+
+// FeatureIterationDelta compares the iterations spent on a single feature
+type FeatureIterationDelta struct {
+	FeatureID   int
+	IterationsA int
+	IterationsB int
+}
+
+// Compare computes the differences between two runs
+func Compare(a, b *Run) *Comparison {
+	c := &Comparison{A: a, B: b}
+
+	inA := make(map[int]bool)
+	for _, id := range a.FeaturesCompleted {
+		inA[id] = true
+	}
+	inB := make(map[int]bool)
+	for _, id := range b.FeaturesCompleted {
+		inB[id] = true
+	}
+
+	for _, id := range a.FeaturesCompleted {
+		if inB[id] {
+			c.CompletedInBoth = append(c.CompletedInBoth, id)
+		} else {
+			c.OnlyCompletedInA = append(c.OnlyCompletedInA, id)
+		}
+	}
+	for _, id := range b.FeaturesCompleted {
+		if !inA[id] {
+			c.OnlyCompletedInB = append(c.OnlyCompletedInB, id)
+		}
+	}
+	sort.Ints(c.CompletedInBoth)
+	sort.Ints(c.OnlyCompletedInA)
+	sort.Ints(c.OnlyCompletedInB)
+
+	featureIDs := make(map[int]bool)
+	for id := range a.IterationsPerFeature {
+		featureIDs[id] = true
+	}
+	for id := range b.IterationsPerFeature {
+		featureIDs[id] = true
+	}
+	for id := range featureIDs {
+		if id <= 0 {
+			continue
+		}
+		c.FeatureIterations = append(c.FeatureIterations, FeatureIterationDelta{
+			FeatureID:   id,
+			IterationsA: a.IterationsPerFeature[id],
+			IterationsB: b.IterationsPerFeature[id],
+		})
+	}
+	sort.Slice(c.FeatureIterations, func(i, j int) bool {
+		return c.FeatureIterations[i].FeatureID < c.FeatureIterations[j].FeatureID
+	})
+
+	return c
+}
+
+// Format returns a human-readable comparison report
+func (c *Comparison) Format() string {
+	var sb strings.Builder
+
+	sb.WriteString("=== Run Comparison ===\n\n")
+	sb.WriteString(fmt.Sprintf("  A: %s (agent: %s, started %s)\n", c.A.DisplayName(), c.A.Agent, c.A.StartTime.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("  B: %s (agent: %s, started %s)\n\n", c.B.DisplayName(), c.B.Agent, c.B.StartTime.Format(time.RFC3339)))
+
+	sb.WriteString(fmt.Sprintf("  %-24s %12s %12s %12s\n", "Metric", "A", "B", "Delta"))
+	sb.WriteString(fmt.Sprintf("  %s\n", strings.Repeat("-", 63)))
+	writeIntRow(&sb, "Features completed", len(c.A.FeaturesCompleted), len(c.B.FeaturesCompleted))
+	writeIntRow(&sb, "Features skipped", c.A.FeaturesSkipped, c.B.FeaturesSkipped)
+	writeIntRow(&sb, "Failures", c.A.Failures, c.B.Failures)
+	writeIntRow(&sb, "Failures recovered", c.A.FailuresRecovered, c.B.FailuresRecovered)
+	writeIntRow(&sb, "Iterations run", c.A.IterationsRun, c.B.IterationsRun)
+	writeFloatRow(&sb, "Iterations/feature", c.A.AverageIterationsPerFeature(), c.B.AverageIterationsPerFeature(), "%.2f")
+	writeFloatRow(&sb, "Duration (min)", c.A.Duration().Minutes(), c.B.Duration().Minutes(), "%.1f")
+	if c.A.InputTokens+c.A.OutputTokens > 0 || c.B.InputTokens+c.B.OutputTokens > 0 {
+		writeIntRow(&sb, "Input tokens", c.A.InputTokens, c.B.InputTokens)
+		writeIntRow(&sb, "Output tokens", c.A.OutputTokens, c.B.OutputTokens)
+	}
+	if c.A.Cost > 0 || c.B.Cost > 0 {
+		writeFloatRow(&sb, "Cost (USD)", c.A.Cost, c.B.Cost, "%.4f")
+	} else {
+		sb.WriteString(fmt.Sprintf("  %-24s %12s %12s %12s\n", "Cost (USD)", "n/a", "n/a", ""))
+	}
+
+	if len(c.OnlyCompletedInA) > 0 || len(c.OnlyCompletedInB) > 0 {
+		sb.WriteString("\nFeature differences:\n")
+		if len(c.OnlyCompletedInA) > 0 {
+			sb.WriteString(fmt.Sprintf("  Completed only in A: %s\n", formatIDs(c.OnlyCompletedInA)))
+		}
+		if len(c.OnlyCompletedInB) > 0 {
+			sb.WriteString(fmt.Sprintf("  Completed only in B: %s\n", formatIDs(c.OnlyCompletedInB)))
+		}
+	}
+	if len(c.CompletedInBoth) > 0 {
+		sb.WriteString(fmt.Sprintf("  Completed in both:   %s\n", formatIDs(c.CompletedInBoth)))
+	}
+
+	if len(c.FeatureIterations) > 0 {
+		sb.WriteString("\nIterations per feature:\n")
+		for _, f := range c.FeatureIterations {
+			sb.WriteString(fmt.Sprintf("  #%-6d A: %-4d B: %-4d (%+d)\n", f.FeatureID, f.IterationsA, f.IterationsB, f.IterationsB-f.IterationsA))
+		}
+	}
+
+	return sb.String()
+}
+
+// FormatList returns a human-readable table of recorded runs
+func FormatList(runs []*Run) string {
+	if len(runs) == 0 {
+		return "No runs recorded"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-22s %-20s %-16s %6s %9s %9s\n", "ID", "Label", "Agent", "Iters", "Features", "Failures"))
+	for _, r := range runs {
+		sb.WriteString(fmt.Sprintf("%-22s %-20s %-16s %6d %9d %9d\n",
+			r.ID, truncate(r.Label, 20), truncate(r.Agent, 16), r.IterationsRun, len(r.FeaturesCompleted), r.Failures))
+	}
+	return sb.String()
+}
+
+// writeIntRow writes a comparison row for an integer metric
+func writeIntRow(sb *strings.Builder, name string, a, b int) {
+	sb.WriteString(fmt.Sprintf("  %-24s %12d %12d %+12d\n", name, a, b, b-a))
+}
+
+// writeFloatRow writes a comparison row for a floating-point metric
+func writeFloatRow(sb *strings.Builder, name string, a, b float64, format string) {
+	sb.WriteString(fmt.Sprintf("  %-24s %12s %12s %12s\n", name,
+		fmt.Sprintf(format, a), fmt.Sprintf(format, b), fmt.Sprintf("%+"+format[1:], b-a)))
+}
+
+// formatIDs formats a list of feature IDs as "#1, #2, #3"
+func formatIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// truncate shortens a string to the specified length
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
@@ -0,0 +1,305 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/config"
+)
+
+func newTestRun(id, label string, start time.Time) *Run {
+	return &Run{
+		ID:                   id,
+		Label:                label,
+		Agent:                "cursor-agent",
+		PlanFile:             "plan.json",
+		StartTime:            start,
+		EndTime:              start.Add(10 * time.Minute),
+		FeaturesCompleted:    []int{},
+		IterationsPerFeature: make(map[int]int),
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	store := NewStore("")
+	if store.Dir() != config.DefaultHistoryDir {
+		t.Errorf("expected default dir %q, got %q", config.DefaultHistoryDir, store.Dir())
+	}
+
+	store = NewStore("custom")
+	if store.Dir() != "custom" {
+		t.Errorf("expected dir %q, got %q", "custom", store.Dir())
+	}
+}
+
+func TestNewRun(t *testing.T) {
+	run := NewRun("claude", "plan.json", "baseline")
+	if !strings.HasPrefix(run.ID, "run-") {
+		t.Errorf("expected ID to start with run-, got %q", run.ID)
+	}
+	if run.Agent != "claude" || run.Label != "baseline" {
+		t.Errorf("unexpected run fields: %+v", run)
+	}
+	if run.IterationsPerFeature == nil {
+		t.Error("IterationsPerFeature should be initialized")
+	}
+}
+
+func TestStore_SaveListFind(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	// Empty store
+	runs, err := store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("expected no runs, got %d", len(runs))
+	}
+	if _, err := store.Find("latest"); err == nil {
+		t.Error("expected error finding run in empty store")
+	}
+
+	base := time.Now().Add(-time.Hour)
+	first := newTestRun("run-20260101-100000", "agent-a", base)
+	second := newTestRun("run-20260101-110000", "agent-b", base.Add(30*time.Minute))
+
+	// Save out of order to verify sorting
+	if err := store.Save(second); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := store.Save(first); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	runs, err = store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	if runs[0].ID != first.ID {
+		t.Errorf("expected oldest run first, got %s", runs[0].ID)
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"latest", second.ID, false},
+		{"previous", first.ID, false},
+		{first.ID, first.ID, false},
+		{"agent-b", second.ID, false},
+		{"run-20260101-10", first.ID, false},
+		{"run-2026", "", true}, // ambiguous
+		{"missing", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := store.Find(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Find(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.ID != tt.want {
+			t.Errorf("Find(%q) = %s, want %s", tt.ref, got.ID, tt.want)
+		}
+	}
+}
+
+func TestStore_Reserve(t *testing.T) {
+	store := NewStore(t.TempDir())
+	start := time.Now()
+	first := newTestRun(RunID(start), "", start)
+	second := newTestRun(RunID(start), "", start)
+
+	if err := store.Reserve(first); err != nil {
+		t.Fatalf("reserve failed: %v", err)
+	}
+	if err := store.Reserve(second); err != nil {
+		t.Fatalf("reserve failed: %v", err)
+	}
+	if first.ID != RunID(start) || second.ID != RunID(start)+"-2" {
+		t.Fatalf("IDs = %q, %q, want %q and a -2 suffix", first.ID, second.ID, RunID(start))
+	}
+
+	// Reserved records are not listed until they are saved
+	if runs, err := store.List(); err != nil || len(runs) != 0 {
+		t.Fatalf("List() = %d runs, %v; want none", len(runs), err)
+	}
+	if err := store.Save(first); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := store.Save(second); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if runs, err := store.List(); err != nil || len(runs) != 2 {
+		t.Errorf("List() = %d runs, %v; want 2", len(runs), err)
+	}
+}
+
+func TestRun_AverageIterationsPerFeature(t *testing.T) {
+	run := newTestRun("run-1", "", time.Now())
+	if avg := run.AverageIterationsPerFeature(); avg != 0 {
+		t.Errorf("expected 0 with no completed features, got %f", avg)
+	}
+
+	run.IterationsRun = 6
+	run.FeaturesCompleted = []int{1, 2, 3}
+	if avg := run.AverageIterationsPerFeature(); avg != 2 {
+		t.Errorf("expected 2, got %f", avg)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	a := newTestRun("run-a", "", now)
+	a.FeaturesCompleted = []int{1, 2, 3}
+	a.IterationsRun = 6
+	a.Failures = 2
+	a.IterationsPerFeature = map[int]int{1: 1, 2: 2, 3: 3}
+
+	b := newTestRun("run-b", "", now)
+	b.FeaturesCompleted = []int{2, 3, 4}
+	b.IterationsRun = 4
+	b.Failures = 0
+	b.IterationsPerFeature = map[int]int{2: 1, 3: 1, 4: 2}
+
+	c := Compare(a, b)
+
+	if len(c.OnlyCompletedInA) != 1 || c.OnlyCompletedInA[0] != 1 {
+		t.Errorf("OnlyCompletedInA = %v, want [1]", c.OnlyCompletedInA)
+	}
+	if len(c.OnlyCompletedInB) != 1 || c.OnlyCompletedInB[0] != 4 {
+		t.Errorf("OnlyCompletedInB = %v, want [4]", c.OnlyCompletedInB)
+	}
+	if len(c.CompletedInBoth) != 2 {
+		t.Errorf("CompletedInBoth = %v, want [2 3]", c.CompletedInBoth)
+	}
+	if len(c.FeatureIterations) != 4 {
+		t.Errorf("expected 4 feature iteration deltas, got %d", len(c.FeatureIterations))
+	}
+	if c.FeatureIterations[0].FeatureID != 1 {
+		t.Errorf("feature deltas should be sorted by ID, got %d first", c.FeatureIterations[0].FeatureID)
+	}
+
+	report := c.Format()
+	for _, want := range []string{"Run Comparison", "Features completed", "Failures", "Completed only in A: #1", "Completed only in B: #4", "n/a"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestCompare_WithCost(t *testing.T) {
+	now := time.Now()
+	a := newTestRun("run-a", "", now)
+	a.Cost = 1.5
+	b := newTestRun("run-b", "", now)
+	b.Cost = 0.75
+	b.InputTokens = 1200
+	b.OutputTokens = 300
+
+	report := Compare(a, b).Format()
+	if !strings.Contains(report, "1.5000") || !strings.Contains(report, "-0.7500") {
+		t.Errorf("report should include cost values and delta:\n%s", report)
+	}
+	if !strings.Contains(report, "Input tokens") || !strings.Contains(report, "1200") {
+		t.Errorf("report should include token usage:\n%s", report)
+	}
+}
+
+func TestFormatList(t *testing.T) {
+	if got := FormatList(nil); got != "No runs recorded" {
+		t.Errorf("FormatList(nil) = %q", got)
+	}
+
+	run := newTestRun("run-1", "my-label", time.Now())
+	run.FeaturesCompleted = []int{1}
+	out := FormatList([]*Run{run})
+	if !strings.Contains(out, "run-1") || !strings.Contains(out, "my-label") {
+		t.Errorf("FormatList output missing run details:\n%s", out)
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	runs := []*Run{
+		{StartTime: now.AddDate(0, 0, -30), FeaturesCompleted: []int{1, 2, 3, 4, 5}}, // Outside the window
+		{StartTime: now.AddDate(0, 0, -4), FeaturesCompleted: []int{6, 7}},
+		{StartTime: now.AddDate(0, 0, -1), FeaturesCompleted: []int{8}},
+		{StartTime: now.Add(-time.Hour), FeaturesCompleted: []int{9}},
+	}
+	if got := Velocity(runs, now); got != 1 {
+		t.Errorf("Velocity() = %v, want 1 (4 features in 4 days)", got)
+	}
+	if got := Velocity(runs[3:], now); got != 1 {
+		t.Errorf("Velocity() of a single recent run = %v, want 1 (measured over at least a day)", got)
+	}
+	if got := Velocity(runs[:1], now); got != 0 {
+		t.Errorf("Velocity() without recent runs = %v, want 0", got)
+	}
+}
+
+func TestFeatureDuration(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+	runs := []*Run{
+		{StartTime: at(30), EndTime: at(30).Add(time.Hour), FeaturesCompleted: []int{1}}, // Outside the window
+		{StartTime: at(3), EndTime: at(3).Add(2 * time.Hour), FeaturesCompleted: []int{2, 3}},
+		{StartTime: at(1), EndTime: at(1).Add(time.Hour), FeaturesCompleted: []int{4}},
+		{StartTime: now.Add(-time.Minute), FeaturesCompleted: []int{5}}, // Still running
+	}
+	if got := FeatureDuration(runs, now); got != time.Hour {
+		t.Errorf("FeatureDuration() = %v, want 1h (3 features in 3 hours)", got)
+	}
+	if got := FeatureDuration(runs[:1], now); got != 0 {
+		t.Errorf("FeatureDuration() without recent runs = %v, want 0", got)
+	}
+}
+
+func TestIterationDuration(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	at := func(daysAgo int) time.Time { return now.AddDate(0, 0, -daysAgo) }
+	runs := []*Run{
+		{StartTime: at(30), EndTime: at(30).Add(time.Hour), IterationsRun: 1}, // Outside the window
+		{StartTime: at(3), EndTime: at(3).Add(50 * time.Minute), IterationsRun: 5},
+		{StartTime: at(1), EndTime: at(1).Add(10 * time.Minute), IterationsRun: 1},
+		{StartTime: now.Add(-time.Minute), IterationsRun: 1}, // Still running
+	}
+	if got := IterationDuration(runs, now); got != 10*time.Minute {
+		t.Errorf("IterationDuration() = %v, want 10m (6 iterations in an hour)", got)
+	}
+	if got := IterationDuration(runs[:1], now); got != 0 {
+		t.Errorf("IterationDuration() without recent runs = %v, want 0", got)
+	}
+}
+
+func TestCompletedFeatureIterations(t *testing.T) {
+	runs := []*Run{
+		{
+			Agent:                "claude",
+			FeaturesCompleted:    []int{1, 2, 3},
+			IterationsPerFeature: map[int]int{1: 2, 2: 6, 3: 1, 4: 9},
+			FeatureComplexity:    map[int]string{1: "low", 2: "high", 4: "high"}, // #3 has no complexity, #4 was not completed
+		},
+		{
+			Agent:                "cursor-agent",
+			FeaturesCompleted:    []int{5},
+			IterationsPerFeature: map[int]int{5: 4},
+			FeatureComplexity:    map[int]string{5: "low"},
+		},
+		{Agent: "claude", FeaturesCompleted: []int{6}}, // Recorded before complexities were
+	}
+
+	got := CompletedFeatureIterations(runs, "claude")
+	if len(got) != 2 || len(got["low"]) != 1 || got["low"][0] != 2 || len(got["high"]) != 1 || got["high"][0] != 6 {
+		t.Errorf("CompletedFeatureIterations(claude) = %v", got)
+	}
+	if got := CompletedFeatureIterations(runs, ""); len(got["low"]) != 2 {
+		t.Errorf("CompletedFeatureIterations(any agent) = %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+// Package hooks runs user-defined commands at points of a run (before and
+// after each iteration, when an iteration fails, and when the plan is
+// complete), so integrations like cache warming, custom notifications or
+// ticket updates need no changes to Ralph.
+//
+// Each command runs with the shell in the working directory. It gets the
+// context of the event as RALPH_* environment variables and as a JSON object
+// on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Event is a point of a run at which hooks run
+type Event string
+
+const (
+	// PreIteration runs before the agent is started for an iteration
+	PreIteration Event = "pre_iteration"
+	// PostIteration runs once an iteration has been checked
+	PostIteration Event = "post_iteration"
+	// OnFailure runs when an iteration fails
+	OnFailure Event = "on_failure"
+	// OnComplete runs when the plan is complete
+	OnComplete Event = "on_complete"
+	// OnMilestoneComplete runs when all features of a milestone are tested
+	OnMilestoneComplete Event = "on_milestone_complete"
+)
+
+// Iteration results passed to post_iteration hooks
+const (
+	ResultSuccess    = "success"
+	ResultFailure    = "failure"
+	ResultRolledBack = "rolled_back"
+)
+
+// DefaultTimeout is how long a hook may run before it is killed
+const DefaultTimeout = time.Minute
+
+// Context describes the event a hook runs for
+type Context struct {
+	Event      Event  `json:"event"`
+	RunID      string `json:"run_id,omitempty"`
+	Iteration  int    `json:"iteration,omitempty"`
+	Iterations int    `json:"iterations,omitempty"` // Maximum iterations of the run
+	FeatureID  int    `json:"feature_id,omitempty"`
+	Feature    string `json:"feature,omitempty"` // Description of the feature
+	Agent      string `json:"agent,omitempty"`
+	Result     string `json:"result,omitempty"`    // success, failure or rolled_back (post_iteration)
+	Error      string `json:"error,omitempty"`     // Why the iteration failed or was rolled back
+	Milestone  string `json:"milestone,omitempty"` // Milestone completed (on_milestone_complete)
+}
+
+// Env returns the context as environment variables
+func (c Context) Env() []string {
+	return []string{
+		"RALPH_EVENT=" + string(c.Event),
+		"RALPH_RUN_ID=" + c.RunID,
+		"RALPH_ITERATION=" + strconv.Itoa(c.Iteration),
+		"RALPH_ITERATIONS=" + strconv.Itoa(c.Iterations),
+		"RALPH_FEATURE_ID=" + strconv.Itoa(c.FeatureID),
+		"RALPH_FEATURE=" + c.Feature,
+		"RALPH_AGENT=" + c.Agent,
+		"RALPH_RESULT=" + c.Result,
+		"RALPH_ERROR=" + c.Error,
+		"RALPH_MILESTONE=" + c.Milestone,
+	}
+}
+
+// Result is the outcome of one hook command
+type Result struct {
+	Command  string
+	Output   string // Combined stdout and stderr
+	Duration time.Duration
+	Err      error
+}
+
+// Runner runs the hooks of a run. A nil Runner runs nothing.
+type Runner struct {
+	commands map[Event][]string
+	timeout  time.Duration
+}
+
+// New creates a runner for the commands of each event, or returns nil if
+// there are none. A timeout of 0 uses DefaultTimeout.
+func New(commands map[Event][]string, timeout time.Duration) *Runner {
+	n := 0
+	for _, cmds := range commands {
+		n += len(cmds)
+	}
+	if n == 0 {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Runner{commands: commands, timeout: timeout}
+}
+
+// Has reports whether any hooks run for the event
+func (r *Runner) Has(event Event) bool {
+	return r != nil && len(r.commands[event]) > 0
+}
+
+// Commands returns the hook commands of the event
+func (r *Runner) Commands(event Event) []string {
+	if r == nil {
+		return nil
+	}
+	return r.commands[event]
+}
+
+// Run runs the hooks of c.Event in order. A failing hook does not stop the
+// ones after it.
+func (r *Runner) Run(c Context) []Result {
+	if !r.Has(c.Event) {
+		return nil
+	}
+	input, err := json.Marshal(c)
+	if err != nil {
+		return []Result{{Err: fmt.Errorf("failed to encode hook context: %w", err)}}
+	}
+	results := make([]Result, 0, len(r.commands[c.Event]))
+	for _, command := range r.commands[c.Event] {
+		results = append(results, r.run(command, c, input))
+	}
+	return results
+}
+
+// run runs one hook command
+func (r *Runner) run(command string, c Context, input []byte) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	name, args := shell(command)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), c.Env()...)
+	cmd.Stdin = bytes.NewReader(input)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Do not wait for background processes the hook left holding the output
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := Result{Command: command, Output: out.String(), Duration: time.Since(start)}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Err = fmt.Errorf("timed out after %s", r.timeout)
+	case err != nil:
+		result.Err = err
+	}
+	return result
+}
+
+// shell returns the command line running command with the platform's shell
+func shell(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
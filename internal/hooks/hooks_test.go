@@ -0,0 +1,64 @@
+package hooks
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if r := New(map[Event][]string{PreIteration: nil}, 0); r != nil {
+		t.Error("New() without commands returned a runner")
+	}
+	var r *Runner
+	if r.Has(OnComplete) || r.Run(Context{Event: OnComplete}) != nil {
+		t.Error("nil runner ran hooks")
+	}
+	r = New(map[Event][]string{OnFailure: {"true"}}, 0)
+	if !r.Has(OnFailure) || r.Has(PreIteration) || r.timeout != DefaultTimeout {
+		t.Errorf("New() = %+v", r)
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not supported")
+	}
+	r := New(map[Event][]string{PostIteration: {
+		`echo "$RALPH_EVENT $RALPH_ITERATION $RALPH_FEATURE_ID $RALPH_RESULT $RALPH_MILESTONE"`,
+		`exit 3`,
+		`cat`,
+	}}, 0)
+	c := Context{Event: PostIteration, RunID: "run-1", Iteration: 2, FeatureID: 7, Feature: "Login", Result: ResultFailure, Error: "tests failed", Milestone: "Alpha"}
+	results := r.Run(c)
+	if len(results) != 3 {
+		t.Fatalf("Run() = %d results, want 3 (a failing hook does not stop the others)", len(results))
+	}
+	if got := strings.TrimSpace(results[0].Output); got != "post_iteration 2 7 failure Alpha" || results[0].Err != nil {
+		t.Errorf("environment = %q (%v)", got, results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("failing hook reported no error")
+	}
+	var stdin Context
+	if err := json.Unmarshal([]byte(results[2].Output), &stdin); err != nil || stdin != c {
+		t.Errorf("stdin = %s (%v), want %+v", results[2].Output, err, c)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands not supported")
+	}
+	r := New(map[Event][]string{PreIteration: {"sleep 5"}}, 100*time.Millisecond)
+	start := time.Now()
+	results := r.Run(Context{Event: PreIteration})
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "timed out") {
+		t.Errorf("Run() = %v, want a timeout", results[0].Err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("hook was not killed at the timeout")
+	}
+}
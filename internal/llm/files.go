@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/logimos/ralph/internal/guard"
+)
+
+// MaxInlineFileSize is the largest file that will be inlined into a prompt
+const MaxInlineFileSize = 256 * 1024
+
+// fileBlockPattern matches <file path="...">...</file> blocks in model output
+var fileBlockPattern = regexp.MustCompile(`(?s)<file path="([^"]+)">\n?(.*?)</file>`)
+
+// ExpandFileReferences inlines the contents of files referenced with the
+// "@path" syntax used by agent CLIs, since API models cannot read the
+// filesystem themselves. References to missing or oversized files are left as-is.
+func ExpandFileReferences(prompt string) string {
+	var attachments strings.Builder
+	seen := make(map[string]bool)
+
+	for _, field := range strings.Fields(prompt) {
+		if !strings.HasPrefix(field, "@") || len(field) < 2 {
+			continue
+		}
+		path := field[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() > MaxInlineFileSize {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&attachments, "<file path=%q>\n%s\n</file>\n", path, strings.TrimRight(string(data), "\n"))
+	}
+
+	if attachments.Len() == 0 {
+		return prompt
+	}
+	return prompt + "\n\nReferenced files:\n" + attachments.String()
+}
+
+// ApplyFileBlocks writes every <file path="...">content</file> block found in
+// output to disk, relative to root. Paths that resolve outside root (including
+// through symlinks) are rejected.
+// Returns the list of files written.
+func ApplyFileBlocks(output, root string) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root: %w", err)
+	}
+
+	var written []string
+	for _, match := range fileBlockPattern.FindAllStringSubmatch(output, -1) {
+		// Resolve symlinks so that links inside the root cannot redirect writes outside it
+		target, err := guard.ResolveWithin(absRoot, match[1])
+		if err != nil {
+			return written, fmt.Errorf("refusing to write %s: %w", match[1], err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, fmt.Errorf("failed to create directory for %s: %w", match[1], err)
+		}
+		if err := os.WriteFile(target, []byte(match[2]), 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", match[1], err)
+		}
+		written = append(written, filepath.Clean(match[1]))
+	}
+
+	return written, nil
+}
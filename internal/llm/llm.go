@@ -0,0 +1,399 @@
+// Package llm provides a direct HTTP client for OpenAI- and Anthropic-compatible
+// chat completion APIs, allowing Ralph to run without an agent CLI installed.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider identifies the wire protocol spoken by the API endpoint
+type Provider string
+
+const (
+	// ProviderOpenAI speaks the OpenAI chat completions protocol
+	ProviderOpenAI Provider = "openai"
+	// ProviderAnthropic speaks the Anthropic messages protocol
+	ProviderAnthropic Provider = "anthropic"
+)
+
+const (
+	// DefaultOpenAIBaseURL is the default base URL for the OpenAI API
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	// DefaultAnthropicBaseURL is the default base URL for the Anthropic API
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	// DefaultOpenAIKeyEnv is the default environment variable holding the OpenAI API key
+	DefaultOpenAIKeyEnv = "OPENAI_API_KEY"
+	// DefaultAnthropicKeyEnv is the default environment variable holding the Anthropic API key
+	DefaultAnthropicKeyEnv = "ANTHROPIC_API_KEY"
+	// DefaultMaxTokens is the default maximum number of tokens to generate per request
+	DefaultMaxTokens = 8192
+	// DefaultTimeout is the default HTTP request timeout
+	DefaultTimeout = 10 * time.Minute
+	// AnthropicVersion is the API version header sent to Anthropic endpoints
+	AnthropicVersion = "2023-06-01"
+)
+
+// ParseProvider converts a string to a Provider
+func ParseProvider(s string) (Provider, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "openai":
+		return ProviderOpenAI, nil
+	case "anthropic":
+		return ProviderAnthropic, nil
+	default:
+		return "", fmt.Errorf("unknown API provider %q: must be openai or anthropic", s)
+	}
+}
+
+// IsProvider reports whether s names a supported API provider
+func IsProvider(s string) bool {
+	_, err := ParseProvider(s)
+	return err == nil
+}
+
+// Config holds the settings for an API client
+type Config struct {
+	Provider  Provider      // Wire protocol (openai or anthropic)
+	BaseURL   string        // Base URL of the API (default depends on provider)
+	Model     string        // Model name sent with each request
+	APIKeyEnv string        // Environment variable holding the API key
+	MaxTokens int           // Maximum tokens to generate per request
+	Timeout   time.Duration // HTTP request timeout
+	// Env holds variables consulted before the process environment, so each
+	// agent can use its own API key and proxy (HTTPS_PROXY/HTTP_PROXY)
+	Env map[string]string
+}
+
+// withDefaults returns a copy of the config with empty fields filled in
+func (c Config) withDefaults() Config {
+	switch c.Provider {
+	case ProviderAnthropic:
+		if c.BaseURL == "" {
+			c.BaseURL = DefaultAnthropicBaseURL
+		}
+		if c.APIKeyEnv == "" {
+			c.APIKeyEnv = DefaultAnthropicKeyEnv
+		}
+	default:
+		if c.BaseURL == "" {
+			c.BaseURL = DefaultOpenAIBaseURL
+		}
+		if c.APIKeyEnv == "" {
+			c.APIKeyEnv = DefaultOpenAIKeyEnv
+		}
+	}
+	if c.MaxTokens <= 0 {
+		c.MaxTokens = DefaultMaxTokens
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+	return c
+}
+
+// KeyEnv returns the environment variable the API key will be read from
+func (c Config) KeyEnv() string {
+	return c.withDefaults().APIKeyEnv
+}
+
+// Usage records token consumption
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+	}
+}
+
+// Total returns the total number of tokens consumed
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Response is the result of a completion request
+type Response struct {
+	Content string
+	Usage   Usage
+}
+
+// Client sends completion requests to an API endpoint
+type Client struct {
+	cfg        Config
+	apiKey     string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+// NewClient creates a new API client. The API key is read from the configured
+// environment variable and must be set.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Provider == "" {
+		return nil, fmt.Errorf("API provider is required")
+	}
+	if _, err := ParseProvider(string(cfg.Provider)); err != nil {
+		return nil, err
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("model is required for the %s backend", cfg.Provider)
+	}
+
+	cfg = cfg.withDefaults()
+	apiKey := cfg.lookupEnv(cfg.APIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key not found: environment variable %s is not set", cfg.APIKeyEnv)
+	}
+
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if proxy := cfg.proxyFunc(); proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = proxy
+		httpClient.Transport = transport
+	}
+
+	return &Client{
+		cfg:        cfg,
+		apiKey:     apiKey,
+		httpClient: httpClient,
+	}, nil
+}
+
+// lookupEnv returns the value of name from Env, falling back to the process environment
+func (c Config) lookupEnv(name string) string {
+	if v, ok := c.Env[name]; ok {
+		return v
+	}
+	return os.Getenv(name)
+}
+
+// proxyFunc returns a proxy selector for proxies set in Env, or nil to use the
+// process-wide proxy settings
+func (c Config) proxyFunc() func(*http.Request) (*url.URL, error) {
+	httpsProxy := firstNonEmpty(c.Env["HTTPS_PROXY"], c.Env["https_proxy"])
+	httpProxy := firstNonEmpty(c.Env["HTTP_PROXY"], c.Env["http_proxy"])
+	if httpsProxy == "" && httpProxy == "" {
+		return nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := httpProxy
+		if req.URL.Scheme == "https" && httpsProxy != "" {
+			proxy = httpsProxy
+		}
+		if proxy == "" {
+			return http.ProxyFromEnvironment(req)
+		}
+		return url.Parse(proxy)
+	}
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Config returns the effective client configuration
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
+// TotalUsage returns the token usage accumulated across all requests
+func (c *Client) TotalUsage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// Complete sends a single-turn completion request with an optional system prompt
+func (c *Client) Complete(ctx context.Context, system, prompt string) (*Response, error) {
+	var (
+		resp *Response
+		err  error
+	)
+	switch c.cfg.Provider {
+	case ProviderAnthropic:
+		resp, err = c.completeAnthropic(ctx, system, prompt)
+	default:
+		resp, err = c.completeOpenAI(ctx, system, prompt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.usage = c.usage.Add(resp.Usage)
+	c.mu.Unlock()
+
+	return resp, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *Client) completeOpenAI(ctx context.Context, system, prompt string) (*Response, error) {
+	var messages []chatMessage
+	if system != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: system})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: prompt})
+
+	body := openAIRequest{
+		Model:     c.cfg.Model,
+		Messages:  messages,
+		MaxTokens: c.cfg.MaxTokens,
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + c.apiKey,
+	}
+
+	var out openAIResponse
+	if err := c.post(ctx, "/chat/completions", headers, body, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("API response contained no choices")
+	}
+
+	return &Response{
+		Content: out.Choices[0].Message.Content,
+		Usage: Usage{
+			InputTokens:  out.Usage.PromptTokens,
+			OutputTokens: out.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+type anthropicRequest struct {
+	Model     string        `json:"model"`
+	System    string        `json:"system,omitempty"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (c *Client) completeAnthropic(ctx context.Context, system, prompt string) (*Response, error) {
+	body := anthropicRequest{
+		Model:     c.cfg.Model,
+		System:    system,
+		Messages:  []chatMessage{{Role: "user", Content: prompt}},
+		MaxTokens: c.cfg.MaxTokens,
+	}
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": AnthropicVersion,
+	}
+
+	var out anthropicResponse
+	if err := c.post(ctx, "/messages", headers, body, &out); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range out.Content {
+		if block.Type == "text" || block.Type == "" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return &Response{
+		Content: text.String(),
+		Usage: Usage{
+			InputTokens:  out.Usage.InputTokens,
+			OutputTokens: out.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// post sends a JSON request and decodes the JSON response into out
+func (c *Client) post(ctx context.Context, path string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read API response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	return nil
+}
+
+// EstimateCost returns the cost of the given usage, with prices expressed in
+// currency units per million tokens
+func EstimateCost(usage Usage, inputPerMTok, outputPerMTok float64) float64 {
+	return float64(usage.InputTokens)/1e6*inputPerMTok + float64(usage.OutputTokens)/1e6*outputPerMTok
+}
@@ -0,0 +1,260 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProvider(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Provider
+		wantErr bool
+	}{
+		{"openai", ProviderOpenAI, false},
+		{"Anthropic", ProviderAnthropic, false},
+		{" openai ", ProviderOpenAI, false},
+		{"cli", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseProvider(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseProvider(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseProvider(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_Defaults(t *testing.T) {
+	cfg := Config{Provider: ProviderAnthropic}.withDefaults()
+	if cfg.BaseURL != DefaultAnthropicBaseURL || cfg.APIKeyEnv != DefaultAnthropicKeyEnv {
+		t.Errorf("unexpected anthropic defaults: %+v", cfg)
+	}
+
+	cfg = Config{Provider: ProviderOpenAI, BaseURL: "http://localhost:8080/v1/"}.withDefaults()
+	if cfg.BaseURL != "http://localhost:8080/v1" {
+		t.Errorf("expected trailing slash trimmed, got %q", cfg.BaseURL)
+	}
+	if cfg.APIKeyEnv != DefaultOpenAIKeyEnv {
+		t.Errorf("expected %s, got %s", DefaultOpenAIKeyEnv, cfg.APIKeyEnv)
+	}
+	if cfg.MaxTokens != DefaultMaxTokens {
+		t.Errorf("expected default max tokens %d, got %d", DefaultMaxTokens, cfg.MaxTokens)
+	}
+}
+
+func TestNewClient_Errors(t *testing.T) {
+	t.Setenv("RALPH_TEST_KEY", "")
+
+	if _, err := NewClient(Config{Model: "m"}); err == nil {
+		t.Error("expected error for missing provider")
+	}
+	if _, err := NewClient(Config{Provider: ProviderOpenAI}); err == nil {
+		t.Error("expected error for missing model")
+	}
+	if _, err := NewClient(Config{Provider: ProviderOpenAI, Model: "m", APIKeyEnv: "RALPH_TEST_KEY"}); err == nil {
+		t.Error("expected error for missing API key")
+	}
+}
+
+func TestNewClient_KeyFromEnv(t *testing.T) {
+	t.Setenv("RALPH_TEST_KEY", "global")
+
+	client, err := NewClient(Config{
+		Provider:  ProviderOpenAI,
+		Model:     "m",
+		APIKeyEnv: "RALPH_TEST_KEY",
+		Env:       map[string]string{"RALPH_TEST_KEY": "per-agent", "HTTPS_PROXY": "http://proxy.internal:3128"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if client.apiKey != "per-agent" {
+		t.Errorf("expected the per-agent key to take precedence, got %q", client.apiKey)
+	}
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		t.Fatal("expected a transport with the per-agent proxy")
+	}
+	req, _ := http.NewRequest("POST", "https://api.example.com/v1", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "proxy.internal:3128" {
+		t.Errorf("unexpected proxy %v (%v)", proxy, err)
+	}
+}
+
+func TestClient_CompleteOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.Model != "gpt-test" || len(req.Messages) != 2 || req.Messages[0].Role != "system" {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"done"}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("RALPH_TEST_KEY", "secret")
+	client, err := NewClient(Config{Provider: ProviderOpenAI, BaseURL: server.URL, Model: "gpt-test", APIKeyEnv: "RALPH_TEST_KEY"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Complete(context.Background(), "system", "hello")
+		if err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+		if resp.Content != "done" {
+			t.Errorf("expected content %q, got %q", "done", resp.Content)
+		}
+	}
+
+	usage := client.TotalUsage()
+	if usage.InputTokens != 20 || usage.OutputTokens != 10 || usage.Total() != 30 {
+		t.Errorf("unexpected accumulated usage: %+v", usage)
+	}
+}
+
+func TestClient_CompleteAnthropic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "secret" || r.Header.Get("anthropic-version") != AnthropicVersion {
+			t.Errorf("missing anthropic headers: %v", r.Header)
+		}
+
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if req.System != "system" || req.MaxTokens != DefaultMaxTokens {
+			t.Errorf("unexpected request: %+v", req)
+		}
+
+		w.Write([]byte(`{"content":[{"type":"text","text":"part one "},{"type":"text","text":"part two"}],"usage":{"input_tokens":7,"output_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("RALPH_TEST_KEY", "secret")
+	client, err := NewClient(Config{Provider: ProviderAnthropic, BaseURL: server.URL, Model: "claude-test", APIKeyEnv: "RALPH_TEST_KEY"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	resp, err := client.Complete(context.Background(), "system", "hello")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Content != "part one part two" {
+		t.Errorf("unexpected content %q", resp.Content)
+	}
+	if resp.Usage.InputTokens != 7 || resp.Usage.OutputTokens != 3 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestClient_CompleteHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	t.Setenv("RALPH_TEST_KEY", "secret")
+	client, err := NewClient(Config{Provider: ProviderOpenAI, BaseURL: server.URL, Model: "m", APIKeyEnv: "RALPH_TEST_KEY"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	_, err = client.Complete(context.Background(), "", "hello")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	cost := EstimateCost(Usage{InputTokens: 2_000_000, OutputTokens: 500_000}, 3, 15)
+	if cost != 13.5 {
+		t.Errorf("expected 13.5, got %f", cost)
+	}
+}
+
+func TestExpandFileReferences(t *testing.T) {
+	dir := t.TempDir()
+	planPath := filepath.Join(dir, "plan.json")
+	if err := os.WriteFile(planPath, []byte(`[{"id":1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := "@" + planPath + " @" + filepath.Join(dir, "missing.txt") + " do the work"
+	expanded := ExpandFileReferences(prompt)
+
+	if !strings.HasPrefix(expanded, prompt) {
+		t.Error("expanded prompt should start with the original prompt")
+	}
+	if !strings.Contains(expanded, `[{"id":1}]`) {
+		t.Errorf("expected plan contents to be inlined:\n%s", expanded)
+	}
+	if strings.Contains(expanded, "missing.txt\">") {
+		t.Error("missing files should not be inlined")
+	}
+
+	if got := ExpandFileReferences("no references"); got != "no references" {
+		t.Errorf("prompt without references should be unchanged, got %q", got)
+	}
+}
+
+func TestApplyFileBlocks(t *testing.T) {
+	dir := t.TempDir()
+
+	output := "Here are the changes.\n" +
+		"<file path=\"main.go\">\npackage main\n</file>\n" +
+		"<file path=\"pkg/util.go\">package pkg\n</file>\n"
+
+	written, err := ApplyFileBlocks(output, dir)
+	if err != nil {
+		t.Fatalf("ApplyFileBlocks failed: %v", err)
+	}
+	if len(written) != 2 {
+		t.Fatalf("expected 2 files written, got %v", written)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "pkg", "util.go"))
+	if err != nil {
+		t.Fatalf("expected nested file to be written: %v", err)
+	}
+	if string(data) != "package pkg\n" {
+		t.Errorf("unexpected file contents %q", string(data))
+	}
+
+	_, err = ApplyFileBlocks("<file path=\"../escape.txt\">x</file>", dir)
+	if err == nil {
+		t.Error("expected error for path outside root")
+	}
+	if _, statErr := os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt")); statErr == nil {
+		t.Error("file outside root should not have been written")
+	}
+}
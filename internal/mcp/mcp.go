@@ -0,0 +1,243 @@
+// Package mcp implements a Model Context Protocol server over stdio, so agent
+// environments (Claude Desktop, IDE agents) can call Ralph's plan, memory,
+// nudge and validation operations as tools instead of editing its files.
+//
+// Messages are newline-delimited JSON-RPC 2.0. Only the tools capability is
+// offered: initialize, ping, tools/list and tools/call.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ProtocolVersion is the newest protocol version the server speaks. Clients
+// asking for one of supportedVersions get the version they asked for.
+const ProtocolVersion = "2025-06-18"
+
+var supportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize is the largest message the server reads
+const maxMessageSize = 10 * 1024 * 1024
+
+// Tool is an operation clients can call
+type Tool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON schema of the arguments (an object schema)
+	InputSchema map[string]any
+	// Handler runs the tool. A string result is returned as is, anything else
+	// as indented JSON. Errors are reported to the client as tool errors.
+	Handler func(args json.RawMessage) (any, error)
+}
+
+// Server answers MCP requests with a set of tools
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+}
+
+// NewServer creates a server identifying itself with name and version
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version}
+}
+
+// AddTool registers a tool
+func (s *Server) AddTool(t Tool) {
+	if t.InputSchema == nil {
+		t.InputSchema = Schema(nil)
+	}
+	s.tools = append(s.tools, t)
+}
+
+// Schema returns an object schema with the given properties, all required
+// unless listed in optional
+func Schema(properties map[string]any, optional ...string) map[string]any {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	skip := make(map[string]bool)
+	for _, name := range optional {
+		skip[name] = true
+	}
+	required := []string{}
+	for name := range properties {
+		if !skip[name] {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// Property returns the schema of a property of the given JSON type
+func Property(typ, description string) map[string]any {
+	return map[string]any{"type": typ, "description": description}
+}
+
+// request is a JSON-RPC request or notification (no ID)
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// content is a text block of a tool result
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// toolResult is the result of tools/call
+type toolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Serve reads requests from r and writes responses to w until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.handle(line); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers one message, returning nil for notifications
+func (s *Server) handle(msg []byte) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if req.ID == nil {
+			return nil
+		}
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+	if req.ID == nil {
+		// Notifications (notifications/initialized, notifications/cancelled)
+		// need no answer
+		return nil
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req)
+	case "ping":
+		return &response{JSONRPC: "2.0", ID: req.ID, Result: struct{}{}}
+	case "tools/list":
+		return s.listTools(req)
+	case "tools/call":
+		return s.callTool(req)
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, "method not found: "+req.Method)
+	}
+}
+
+func (s *Server) initialize(req request) *response {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(req.Params, &params)
+	version := ProtocolVersion
+	if supportedVersions[params.ProtocolVersion] {
+		version = params.ProtocolVersion
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+	}}
+}
+
+func (s *Server) listTools(req request) *response {
+	tools := make([]map[string]any, 0, len(s.tools))
+	for _, t := range s.tools {
+		tools = append(tools, map[string]any{"name": t.Name, "description": t.Description, "inputSchema": t.InputSchema})
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: map[string]any{"tools": tools}}
+}
+
+func (s *Server) callTool(req request) *response {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return errorResponse(req.ID, codeInvalidParams, "invalid params: "+err.Error())
+	}
+	var tool *Tool
+	for i := range s.tools {
+		if s.tools[i].Name == params.Name {
+			tool = &s.tools[i]
+		}
+	}
+	if tool == nil {
+		return errorResponse(req.ID, codeInvalidParams, "unknown tool: "+params.Name)
+	}
+	if len(params.Arguments) == 0 || string(params.Arguments) == "null" {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: runTool(tool, params.Arguments)}
+}
+
+// runTool runs a tool and converts its result or error to a tool result
+func runTool(tool *Tool, args json.RawMessage) toolResult {
+	result, err := tool.Handler(args)
+	if err == nil {
+		if text, ok := result.(string); ok {
+			return toolResult{Content: []content{{Type: "text", Text: text}}}
+		}
+		var data []byte
+		if data, err = json.MarshalIndent(result, "", "  "); err == nil {
+			return toolResult{Content: []content{{Type: "text", Text: string(data)}}}
+		}
+	}
+	return toolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// serve sends the messages to a server and decodes its responses
+func serve(t *testing.T, s *Server, messages ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	var responses []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]any
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func newTestServer() *Server {
+	s := NewServer("ralph", "1.2.3")
+	s.AddTool(Tool{
+		Name:        "greet",
+		Description: "Greet someone",
+		InputSchema: Schema(map[string]any{"name": Property("string", "Who"), "loud": Property("boolean", "Shout")}, "loud"),
+		Handler: func(args json.RawMessage) (any, error) {
+			var req struct{ Name string }
+			json.Unmarshal(args, &req)
+			if req.Name == "" {
+				return nil, fmt.Errorf("name is required")
+			}
+			return "Hello " + req.Name, nil
+		},
+	})
+	s.AddTool(Tool{
+		Name: "count",
+		Handler: func(json.RawMessage) (any, error) {
+			return map[string]int{"features": 3}, nil
+		},
+	})
+	return s
+}
+
+func TestInitialize(t *testing.T) {
+	responses := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"ping"}`,
+	)
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3 (none for the notification): %v", len(responses), responses)
+	}
+	result := responses[0]["result"].(map[string]any)
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("protocolVersion = %v, want the client's", result["protocolVersion"])
+	}
+	if info := result["serverInfo"].(map[string]any); info["name"] != "ralph" || info["version"] != "1.2.3" {
+		t.Errorf("serverInfo = %v", info)
+	}
+	if _, ok := result["capabilities"].(map[string]any)["tools"]; !ok {
+		t.Errorf("capabilities = %v, want tools", result["capabilities"])
+	}
+	if v := responses[1]["result"].(map[string]any)["protocolVersion"]; v != ProtocolVersion {
+		t.Errorf("protocolVersion for an unknown version = %v", v)
+	}
+	if responses[2]["id"] != float64(3) || responses[2]["error"] != nil {
+		t.Errorf("ping = %v", responses[2])
+	}
+}
+
+func TestTools(t *testing.T) {
+	responses := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet","arguments":{"name":"Ralph"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"greet","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"count"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`,
+	)
+	if len(responses) != 5 {
+		t.Fatalf("got %d responses: %v", len(responses), responses)
+	}
+
+	tools := responses[0]["result"].(map[string]any)["tools"].([]any)
+	greet := tools[0].(map[string]any)
+	schema := greet["inputSchema"].(map[string]any)
+	if greet["name"] != "greet" || fmt.Sprint(schema["required"]) != "[name]" {
+		t.Errorf("tools/list = %v", tools)
+	}
+	if schema := tools[1].(map[string]any)["inputSchema"].(map[string]any); schema["type"] != "object" {
+		t.Errorf("default input schema = %v", schema)
+	}
+
+	text := func(resp map[string]any) (string, bool) {
+		result := resp["result"].(map[string]any)
+		isError, _ := result["isError"].(bool)
+		return result["content"].([]any)[0].(map[string]any)["text"].(string), isError
+	}
+	if got, isError := text(responses[1]); got != "Hello Ralph" || isError {
+		t.Errorf("greet = %q (error %v)", got, isError)
+	}
+	if got, isError := text(responses[2]); got != "name is required" || !isError {
+		t.Errorf("greet without a name = %q (error %v)", got, isError)
+	}
+	if got, _ := text(responses[3]); !strings.Contains(got, `"features": 3`) {
+		t.Errorf("count = %q, want indented JSON", got)
+	}
+	if e, ok := responses[4]["error"].(map[string]any); !ok || e["code"] != float64(codeInvalidParams) {
+		t.Errorf("unknown tool = %v", responses[4])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	responses := serve(t, newTestServer(),
+		`{not json`,
+		`{"jsonrpc":"2.0","id":"a","method":"resources/list"}`,
+		`{"jsonrpc":"1.0","id":"b","method":"ping"}`,
+	)
+	want := []int{codeParseError, codeMethodNotFound, codeInvalidRequest}
+	if len(responses) != len(want) {
+		t.Fatalf("got %d responses: %v", len(responses), responses)
+	}
+	for i, resp := range responses {
+		e, ok := resp["error"].(map[string]any)
+		if !ok || e["code"] != float64(want[i]) {
+			t.Errorf("response %d = %v, want error %d", i, resp, want[i])
+		}
+	}
+	if responses[1]["id"] != "a" {
+		t.Errorf("id = %v, want the request's", responses[1]["id"])
+	}
+}
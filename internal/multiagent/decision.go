@@ -0,0 +1,217 @@
+package multiagent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// DefaultDecisionFile is the default path of the pending decision file
+const DefaultDecisionFile = ".ralph-multiagent-decisions.json"
+
+// HumanDecider is the agent name recorded for decisions made by a human
+const HumanDecider = "human"
+
+// ErrDecisionPending is returned when a conflict waits for a human to fill in
+// its decision in the pending decision file
+var ErrDecisionPending = errors.New("conflict is waiting for a human decision")
+
+// Decider asks a human to settle a conflict the resolution strategy could not.
+// It returns one of the conflict's options.
+type Decider interface {
+	Decide(conflict Conflict) (string, error)
+}
+
+// NewDecider returns a decider that prompts on the terminal when stdin is a
+// TTY, and otherwise records the conflict in the pending decision file at path
+func NewDecider(path string) Decider {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return NewPromptDecider(os.Stdin, os.Stderr)
+	}
+	return NewFileDecider(path)
+}
+
+// PromptDecider asks for decisions interactively
+type PromptDecider struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPromptDecider creates a decider reading answers from in and writing to out
+func NewPromptDecider(in io.Reader, out io.Writer) *PromptDecider {
+	return &PromptDecider{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Decide shows the conflict and asks until one of its options is chosen,
+// by number or by name
+func (d *PromptDecider) Decide(conflict Conflict) (string, error) {
+	fmt.Fprintf(d.out, "\nAgents could not resolve a conflict: %s\n", conflict.Description)
+	if len(conflict.AgentIDs) > 0 {
+		fmt.Fprintf(d.out, "Agents involved: %s\n", strings.Join(conflict.AgentIDs, ", "))
+	}
+	for i, option := range conflict.Options {
+		fmt.Fprintf(d.out, "  %d) %s\n", i+1, option)
+	}
+
+	for {
+		fmt.Fprint(d.out, "Your decision: ")
+		line, err := d.in.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer != "" {
+			if option, ok := conflict.option(answer); ok {
+				return option, nil
+			}
+			fmt.Fprintf(d.out, "Please answer 1-%d or one of: %s\n", len(conflict.Options), strings.Join(conflict.Options, ", "))
+		}
+		if err != nil {
+			if err == io.EOF {
+				return "", fmt.Errorf("no decision given for conflict %q", conflict.Description)
+			}
+			return "", err
+		}
+	}
+}
+
+// PendingDecision is a conflict waiting in the decision file. A human settles
+// it by setting Decision to one of Options.
+type PendingDecision struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	AgentIDs    []string  `json:"agent_ids"`
+	Options     []string  `json:"options"`
+	Decision    string    `json:"decision"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FileDecider hands conflicts to a human through a pending decision file, for
+// runs without a terminal
+type FileDecider struct {
+	path string
+}
+
+// NewFileDecider creates a decider using the pending decision file at path
+func NewFileDecider(path string) *FileDecider {
+	if path == "" {
+		path = DefaultDecisionFile
+	}
+	return &FileDecider{path: path}
+}
+
+// Path returns the pending decision file path
+func (d *FileDecider) Path() string {
+	return d.path
+}
+
+// Decide returns the decision a human filled in for the conflict and removes it
+// from the file. Until then the conflict is added to the file if needed and
+// ErrDecisionPending is returned.
+func (d *FileDecider) Decide(conflict Conflict) (string, error) {
+	pending, err := d.Load()
+	if err != nil {
+		return "", err
+	}
+
+	id := conflict.ID()
+	for i, p := range pending {
+		if p.ID != id {
+			continue
+		}
+		if p.Decision == "" {
+			return "", fmt.Errorf("%w (edit %s)", ErrDecisionPending, d.path)
+		}
+		option, ok := conflict.option(p.Decision)
+		if !ok {
+			return "", fmt.Errorf("invalid decision %q for %q in %s: must be one of %s",
+				p.Decision, conflict.Description, d.path, strings.Join(conflict.Options, ", "))
+		}
+		if err := d.save(append(pending[:i], pending[i+1:]...)); err != nil {
+			return "", err
+		}
+		return option, nil
+	}
+
+	pending = append(pending, PendingDecision{
+		ID:          id,
+		Type:        conflict.Type,
+		Description: conflict.Description,
+		AgentIDs:    conflict.AgentIDs,
+		Options:     conflict.Options,
+		CreatedAt:   time.Now(),
+	})
+	if err := d.save(pending); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%w (edit %s)", ErrDecisionPending, d.path)
+}
+
+// Load reads the pending decisions, returning none if the file does not exist
+func (d *FileDecider) Load() ([]PendingDecision, error) {
+	data, err := os.ReadFile(d.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read decision file: %w", err)
+	}
+	var pending []PendingDecision
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to parse decision file %s: %w", d.path, err)
+	}
+	return pending, nil
+}
+
+// save writes the pending decisions, removing the file once none are left
+func (d *FileDecider) save(pending []PendingDecision) error {
+	if len(pending) == 0 {
+		if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove decision file: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal decisions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("failed to create decision directory: %w", err)
+	}
+	if err := os.WriteFile(d.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write decision file: %w", err)
+	}
+	return nil
+}
+
+// ID identifies the conflict across runs by its type, description and agents
+func (c Conflict) ID() string {
+	agents := append([]string(nil), c.AgentIDs...)
+	sort.Strings(agents)
+	return c.Type + ":" + c.Description + ":" + strings.Join(agents, ",")
+}
+
+// option matches an answer against the conflict's options, by 1-based number
+// or case-insensitive name
+func (c Conflict) option(answer string) (string, bool) {
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(c.Options) {
+		return c.Options[n-1], true
+	}
+	for _, option := range c.Options {
+		if strings.EqualFold(option, answer) {
+			return option, true
+		}
+	}
+	return "", false
+}
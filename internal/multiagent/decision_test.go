@@ -0,0 +1,233 @@
+package multiagent
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDecider answers every conflict with a fixed choice
+type fakeDecider struct {
+	choice string
+	asked  []Conflict
+}
+
+func (f *fakeDecider) Decide(conflict Conflict) (string, error) {
+	f.asked = append(f.asked, conflict)
+	return f.choice, nil
+}
+
+func TestPromptDecider(t *testing.T) {
+	conflict := Conflict{
+		Type:        "approval",
+		Description: "Reviewers disagree on approval",
+		AgentIDs:    []string{"review-1", "review-2"},
+		Options:     []string{VerdictApprove, VerdictReject},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "by number", input: "2\n", want: VerdictReject},
+		{name: "by name", input: "Approve\n", want: VerdictApprove},
+		{name: "invalid then valid", input: "maybe\n1\n", want: VerdictApprove},
+		{name: "answer without newline", input: "reject", want: VerdictReject},
+		{name: "no input", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, err := NewPromptDecider(strings.NewReader(tt.input), &out).Decide(conflict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decide error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decide = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(out.String(), "review-1, review-2") {
+				t.Errorf("prompt should list the agents involved:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestFileDecider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.json")
+	decider := NewFileDecider(path)
+	conflict := Conflict{
+		Type:        "approval",
+		Description: "Reviewers disagree on approval",
+		AgentIDs:    []string{"review-2", "review-1"},
+		Options:     []string{VerdictApprove, VerdictReject},
+	}
+
+	// The first time, the conflict is written to the file and left pending
+	if _, err := decider.Decide(conflict); !errors.Is(err, ErrDecisionPending) {
+		t.Fatalf("expected ErrDecisionPending, got %v", err)
+	}
+	if _, err := decider.Decide(conflict); !errors.Is(err, ErrDecisionPending) {
+		t.Fatalf("expected ErrDecisionPending while undecided, got %v", err)
+	}
+	pending, err := decider.Load()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected 1 pending decision, got %v (%v)", pending, err)
+	}
+
+	// An invalid decision is reported
+	pending[0].Decision = "maybe"
+	data, _ := json.Marshal(pending)
+	os.WriteFile(path, data, 0644)
+	if _, err := decider.Decide(conflict); err == nil || errors.Is(err, ErrDecisionPending) {
+		t.Fatalf("expected invalid decision error, got %v", err)
+	}
+
+	// A valid decision is returned and removed from the file
+	pending[0].Decision = "reject"
+	data, _ = json.Marshal(pending)
+	os.WriteFile(path, data, 0644)
+	got, err := decider.Decide(Conflict{
+		Type:        conflict.Type,
+		Description: conflict.Description,
+		AgentIDs:    []string{"review-1", "review-2"},
+		Options:     conflict.Options,
+	})
+	if err != nil || got != VerdictReject {
+		t.Fatalf("Decide = %q, %v; want reject", got, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("decision file should be removed once no decisions are pending")
+	}
+}
+
+func TestResolveConflicts_HumanDecision(t *testing.T) {
+	disagreeing := []AgentResult{
+		{AgentID: "review-1", Role: RoleReviewer, Status: StatusComplete, Approved: true},
+		{AgentID: "review-2", Role: RoleReviewer, Status: StatusComplete, Approved: false},
+	}
+
+	tests := []struct {
+		name        string
+		strategy    string
+		priorities  [2]int
+		wantAsked   bool
+		wantVerdict string
+	}{
+		{name: "priority with a clear winner", strategy: "priority", priorities: [2]int{10, 5}, wantVerdict: VerdictApprove},
+		{name: "priority tie", strategy: "priority", priorities: [2]int{5, 5}, wantAsked: true, wantVerdict: VerdictReject},
+		{name: "tied vote", strategy: "vote", wantAsked: true, wantVerdict: VerdictReject},
+		{name: "merge cannot merge verdicts", strategy: "merge", priorities: [2]int{10, 5}, wantAsked: true, wantVerdict: VerdictReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &MultiAgentConfig{
+				Agents: []AgentConfig{
+					{ID: "review-1", Role: RoleReviewer, Command: "cmd", Priority: tt.priorities[0], Enabled: true},
+					{ID: "review-2", Role: RoleReviewer, Command: "cmd", Priority: tt.priorities[1], Enabled: true},
+				},
+				ConflictResolution: tt.strategy,
+			}
+			contextPath := filepath.Join(t.TempDir(), "context.json")
+			orch := NewOrchestrator(config, contextPath)
+			decider := &fakeDecider{choice: VerdictReject}
+			orch.SetDecider(decider)
+
+			resolution, err := orch.ResolveConflicts(disagreeing)
+			if err != nil {
+				t.Fatalf("ResolveConflicts error = %v", err)
+			}
+			if !resolution.Resolved {
+				t.Error("resolution should be resolved")
+			}
+			if resolution.Verdict != tt.wantVerdict {
+				t.Errorf("Verdict = %q, want %q", resolution.Verdict, tt.wantVerdict)
+			}
+			if (len(decider.asked) > 0) != tt.wantAsked {
+				t.Errorf("human asked = %v, want %v", len(decider.asked) > 0, tt.wantAsked)
+			}
+
+			// Human decisions are recorded in the shared context
+			saved := NewSharedContext(contextPath)
+			if err := saved.Load(); err != nil {
+				t.Fatalf("Load error = %v", err)
+			}
+			if tt.wantAsked {
+				if len(saved.Decisions) != 1 || saved.Decisions[0].Decision != VerdictReject || saved.Decisions[0].Agents[0] != HumanDecider {
+					t.Errorf("unexpected recorded decisions %+v", saved.Decisions)
+				}
+			} else if len(saved.Decisions) != 0 {
+				t.Errorf("no decision should be recorded, got %+v", saved.Decisions)
+			}
+		})
+	}
+}
+
+func TestResolveConflicts_Pending(t *testing.T) {
+	config := &MultiAgentConfig{
+		Agents: []AgentConfig{
+			{ID: "review-1", Role: RoleReviewer, Command: "cmd", Enabled: true},
+			{ID: "review-2", Role: RoleReviewer, Command: "cmd", Enabled: true},
+		},
+		ConflictResolution: "vote",
+		DecisionFile:       filepath.Join(t.TempDir(), "decisions.json"),
+	}
+	orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "ctx.json"))
+	orch.SetDecider(NewFileDecider(config.DecisionFile))
+
+	resolution, err := orch.ResolveConflicts([]AgentResult{
+		{AgentID: "review-1", Role: RoleReviewer, Status: StatusComplete, Approved: true},
+		{AgentID: "review-2", Role: RoleReviewer, Status: StatusComplete, Approved: false},
+	})
+	if err != nil {
+		t.Fatalf("ResolveConflicts error = %v", err)
+	}
+	if resolution.Resolved || len(resolution.Pending) != 1 {
+		t.Errorf("expected 1 pending conflict, got resolved=%v pending=%v", resolution.Resolved, resolution.Pending)
+	}
+	if _, err := os.Stat(config.DecisionFile); err != nil {
+		t.Errorf("decision file should be written: %v", err)
+	}
+}
+
+func TestResolveConflicts_SplitSuggestion(t *testing.T) {
+	config := &MultiAgentConfig{
+		Agents: []AgentConfig{
+			{ID: "impl-1", Role: RoleImplementer, Command: "cmd", Enabled: true},
+			{ID: "review-1", Role: RoleReviewer, Command: "cmd", Enabled: true},
+			{ID: "review-2", Role: RoleReviewer, Command: "cmd", Enabled: true},
+			{ID: "review-3", Role: RoleReviewer, Command: "cmd", Enabled: true},
+		},
+		ConflictResolution: "vote",
+	}
+	orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "ctx.json"))
+	decider := &fakeDecider{choice: "keep"}
+	orch.SetDecider(decider)
+
+	resolution, err := orch.ResolveConflicts([]AgentResult{
+		{AgentID: "review-1", Role: RoleReviewer, Status: StatusComplete, Approved: true, Suggestions: []string{"Add logging"}},
+		{AgentID: "review-2", Role: RoleReviewer, Status: StatusComplete, Approved: true, Suggestions: []string{"Add logging"}},
+		{AgentID: "review-3", Role: RoleReviewer, Status: StatusComplete, Approved: false},
+		{AgentID: "impl-1", Role: RoleImplementer, Status: StatusComplete},
+	})
+	if err != nil {
+		t.Fatalf("ResolveConflicts error = %v", err)
+	}
+	if resolution.Verdict != VerdictApprove {
+		t.Errorf("majority should approve, got %q", resolution.Verdict)
+	}
+	if len(decider.asked) != 1 || decider.asked[0].Subject != "Add logging" {
+		t.Fatalf("expected to be asked about the split suggestion, got %+v", decider.asked)
+	}
+	for _, r := range resolution.WinningResults {
+		if len(r.Suggestions) != 1 || r.Suggestions[0] != "Add logging" {
+			t.Errorf("kept suggestion missing from %s: %v", r.AgentID, r.Suggestions)
+		}
+	}
+}
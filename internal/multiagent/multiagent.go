@@ -5,13 +5,20 @@ package multiagent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	ralphagent "github.com/logimos/ralph/internal/agent"
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/prompt"
+	"gopkg.in/yaml.v3"
 )
 
 // AgentRole represents the role an agent plays in the collaboration
@@ -81,6 +88,11 @@ type AgentConfig struct {
 
 	// PromptSuffix is appended to all prompts sent to this agent
 	PromptSuffix string `json:"prompt_suffix,omitempty" yaml:"prompt_suffix,omitempty"`
+
+	// Env holds environment variables set only for this agent's process (API keys,
+	// models, proxies). Values may be literal or credential references:
+	// "env:NAME" reads NAME from Ralph's environment, "file:PATH" reads a secret file.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
 }
 
 // MultiAgentConfig holds the configuration for multiple agents
@@ -97,6 +109,24 @@ type MultiAgentConfig struct {
 	// ConflictResolution determines how to resolve conflicts between agents
 	// Options: "priority" (use highest priority), "merge" (attempt to merge), "vote" (majority wins)
 	ConflictResolution string `json:"conflict_resolution,omitempty" yaml:"conflict_resolution,omitempty"`
+
+	// DecisionFile is where conflicts wait for a human decision when there is no terminal to ask on
+	DecisionFile string `json:"decision_file,omitempty" yaml:"decision_file,omitempty"`
+
+	// PromptTemplates maps the tester, reviewer and refactorer roles to a prompt template
+	// file rendered with StagePromptData instead of the built-in stage prompt
+	PromptTemplates map[AgentRole]string `json:"prompt_templates,omitempty" yaml:"prompt_templates,omitempty"`
+}
+
+// StagePromptData is the data available to a role prompt template
+// (prompt_templates). {{.Default}} renders the built-in prompt.
+type StagePromptData struct {
+	Role         AgentRole
+	Instruction  string        // Built-in instruction for the role
+	Task         string        // Prompt of the implementation stage
+	PreviousRole AgentRole     // Role whose output the stage builds on
+	Previous     []AgentResult // Completed results of PreviousRole with output
+	Default      string        // Built-in prompt
 }
 
 // AgentResult represents the result of an agent's execution
@@ -344,6 +374,7 @@ type Orchestrator struct {
 	config       *MultiAgentConfig
 	context      *SharedContext
 	executor     AgentExecutor
+	decider      Decider
 	mu           sync.Mutex
 	agentStatus  map[string]AgentStatus
 	healthChecks map[string]time.Time
@@ -360,11 +391,13 @@ type DefaultAgentExecutor struct {
 	Verbose bool
 }
 
-// Execute runs an agent command and returns the output
+// Execute runs an agent command with the agent's own environment and returns the output
 func (e *DefaultAgentExecutor) Execute(ctx context.Context, agentConfig *AgentConfig, prompt string) (string, error) {
-	// This will be implemented to call the actual agent command
-	// For now, we'll use the existing agent.Execute function pattern
-	return "", fmt.Errorf("default executor not fully implemented - use with actual agent package")
+	cfg := config.New()
+	cfg.AgentCmd = agentConfig.Command
+	cfg.AgentEnv = agentConfig.Env
+	cfg.Verbose = e.Verbose
+	return ralphagent.ExecuteContext(ctx, cfg, prompt, nil, nil)
 }
 
 // NewOrchestrator creates a new multi-agent orchestrator
@@ -379,11 +412,15 @@ func NewOrchestrator(config *MultiAgentConfig, contextPath string) *Orchestrator
 	if contextPath == "" {
 		contextPath = ".ralph-multiagent-context.json"
 	}
+	if config.DecisionFile == "" {
+		config.DecisionFile = DefaultDecisionFile
+	}
 
 	return &Orchestrator{
 		config:       config,
 		context:      NewSharedContext(contextPath),
 		executor:     &DefaultAgentExecutor{},
+		decider:      NewDecider(config.DecisionFile),
 		agentStatus:  make(map[string]AgentStatus),
 		healthChecks: make(map[string]time.Time),
 	}
@@ -394,6 +431,12 @@ func (o *Orchestrator) SetExecutor(executor AgentExecutor) {
 	o.executor = executor
 }
 
+// SetDecider sets how conflicts the strategy cannot settle are put to a human.
+// With a nil decider such conflicts stay pending.
+func (o *Orchestrator) SetDecider(decider Decider) {
+	o.decider = decider
+}
+
 // GetEnabledAgents returns all enabled agents sorted by priority (high to low)
 func (o *Orchestrator) GetEnabledAgents() []AgentConfig {
 	var enabled []AgentConfig
@@ -457,7 +500,11 @@ func (o *Orchestrator) ExecuteWorkflow(ctx context.Context, featureID int, featu
 	// Stage 2: Testing (depends on implementation)
 	testers := o.GetAgentsByRole(RoleTester)
 	if len(testers) > 0 {
-		testPrompt := o.buildDependentPrompt(basePrompt, RoleImplementer, "Validate the implementation by running and writing tests.")
+		testPrompt, err := o.buildDependentPrompt(RoleTester, basePrompt, RoleImplementer, "Validate the implementation by running and writing tests.")
+		if err != nil {
+			result.EndTime = time.Now()
+			return result, err
+		}
 		stageResult := o.executeStage(ctx, "testing", testers, testPrompt, nil)
 		result.Stages = append(result.Stages, stageResult)
 		// Testing failures don't stop the workflow, but are recorded
@@ -466,15 +513,39 @@ func (o *Orchestrator) ExecuteWorkflow(ctx context.Context, featureID int, featu
 	// Stage 3: Review (depends on implementation)
 	reviewers := o.GetAgentsByRole(RoleReviewer)
 	if len(reviewers) > 0 {
-		reviewPrompt := o.buildDependentPrompt(basePrompt, RoleImplementer, "Review the implementation for code quality, best practices, and potential issues.")
+		reviewPrompt, err := o.buildDependentPrompt(RoleReviewer, basePrompt, RoleImplementer, reviewInstruction)
+		if err != nil {
+			result.EndTime = time.Now()
+			return result, err
+		}
 		stageResult := o.executeStage(ctx, "review", reviewers, reviewPrompt, nil)
 		result.Stages = append(result.Stages, stageResult)
+
+		// Reviewers that disagree are settled by the strategy, or by a human
+		if len(stageResult.Results) > 1 {
+			resolution, err := o.ResolveConflicts(stageResult.Results)
+			if err != nil {
+				result.EndTime = time.Now()
+				return result, fmt.Errorf("failed to resolve review conflict: %w", err)
+			}
+			result.Resolution = resolution
+			if !resolution.Resolved {
+				result.EndTime = time.Now()
+				result.Success = false
+				result.Error = "review conflict waiting for a human decision in " + o.config.DecisionFile
+				return result, nil
+			}
+		}
 	}
 
 	// Stage 4: Refactoring (optional, based on review feedback)
 	refactorers := o.GetAgentsByRole(RoleRefactorer)
 	if len(refactorers) > 0 && o.shouldRefactor(result) {
-		refactorPrompt := o.buildDependentPrompt(basePrompt, RoleReviewer, "Refactor the code based on review feedback to improve quality.")
+		refactorPrompt, err := o.buildDependentPrompt(RoleRefactorer, basePrompt, RoleReviewer, "Refactor the code based on review feedback to improve quality.")
+		if err != nil {
+			result.EndTime = time.Now()
+			return result, err
+		}
 		stageResult := o.executeStage(ctx, "refactoring", refactorers, refactorPrompt, nil)
 		result.Stages = append(result.Stages, stageResult)
 	}
@@ -493,6 +564,80 @@ func (o *Orchestrator) ExecuteWorkflow(ctx context.Context, featureID int, featu
 	return result, nil
 }
 
+// reviewInstruction is the built-in instruction of the review stage
+const reviewInstruction = "Review the implementation for code quality, best practices, and potential issues."
+
+// ReviewResult is the outcome of reviewing an implementation with Review
+type ReviewResult struct {
+	Stage      StageResult
+	Resolution *ConflictResolution // Set when several reviewers took part
+	Approved   bool
+}
+
+// Issues returns the issues raised by reviewers that did not approve
+func (r *ReviewResult) Issues() []string {
+	var issues []string
+	for _, res := range r.Stage.Results {
+		if !res.Approved {
+			issues = append(issues, res.Issues...)
+		}
+	}
+	return deduplicateStrings(issues)
+}
+
+// Review runs the review stage on an implementation produced outside the
+// workflow, such as an iteration of the single agent. task is the prompt the
+// implementation answers. The implementation is approved if every reviewer
+// approves it, or if reviewers disagree and the conflict is settled with
+// approval. A reviewer that fails does not approve.
+func (o *Orchestrator) Review(ctx context.Context, featureID int, featureDesc string, iteration int, task, implementation string) (*ReviewResult, error) {
+	reviewers := o.GetAgentsByRole(RoleReviewer)
+	if len(reviewers) == 0 {
+		return nil, fmt.Errorf("no reviewer agents configured")
+	}
+
+	o.mu.Lock()
+	o.context.SetFeature(featureID, featureDesc, iteration)
+	if err := o.context.Load(); err != nil {
+		o.mu.Unlock()
+		return nil, fmt.Errorf("failed to load context: %w", err)
+	}
+	o.mu.Unlock()
+
+	implemented := AgentResult{AgentID: "ralph", Role: RoleImplementer, Status: StatusComplete, Output: implementation}
+	reviewPrompt, err := o.buildStagePrompt(RoleReviewer, task, RoleImplementer, reviewInstruction, []AgentResult{implemented})
+	if err != nil {
+		return nil, err
+	}
+	result := &ReviewResult{Stage: o.executeStage(ctx, "review", reviewers, reviewPrompt, nil)}
+
+	result.Approved = true
+	for _, r := range result.Stage.Results {
+		if r.Status != StatusComplete || !r.Approved {
+			result.Approved = false
+		}
+	}
+	if len(result.Stage.Results) > 1 {
+		resolution, err := o.ResolveConflicts(result.Stage.Results)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve review conflict: %w", err)
+		}
+		result.Resolution = resolution
+		if !resolution.Resolved {
+			result.Approved = false
+		} else if resolution.Verdict != "" {
+			result.Approved = resolution.Verdict == VerdictApprove
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.context.Save(); err != nil {
+		return result, fmt.Errorf("failed to save context: %w", err)
+	}
+	return result, nil
+}
+
 // executeStage runs a group of agents for a workflow stage
 func (o *Orchestrator) executeStage(ctx context.Context, stageName string, agents []AgentConfig, prompt string, previousResults []AgentResult) StageResult {
 	result := StageResult{
@@ -610,18 +755,32 @@ func (o *Orchestrator) executeAgent(ctx context.Context, agent *AgentConfig, pro
 	return result
 }
 
-// buildDependentPrompt creates a prompt that includes context from previous stages
-func (o *Orchestrator) buildDependentPrompt(basePrompt string, dependsOnRole AgentRole, instruction string) string {
+// buildDependentPrompt creates the prompt of role's stage, which includes
+// context from previous stages, using the role's prompt template if there is one
+func (o *Orchestrator) buildDependentPrompt(role AgentRole, basePrompt string, dependsOnRole AgentRole, instruction string) (string, error) {
+	return o.buildStagePrompt(role, basePrompt, dependsOnRole, instruction, o.context.GetResultsByRole(dependsOnRole))
+}
+
+// buildStagePrompt creates the prompt of role's stage from the results of
+// the stage it depends on
+func (o *Orchestrator) buildStagePrompt(role AgentRole, basePrompt string, dependsOnRole AgentRole, instruction string, results []AgentResult) (string, error) {
+	data := StagePromptData{
+		Role:         role,
+		Instruction:  instruction,
+		Task:         basePrompt,
+		PreviousRole: dependsOnRole,
+	}
+
 	var sb strings.Builder
 	sb.WriteString(instruction)
 	sb.WriteString("\n\n")
 
 	// Include relevant results from the dependent role
-	results := o.context.GetResultsByRole(dependsOnRole)
 	if len(results) > 0 {
 		sb.WriteString(fmt.Sprintf("=== Previous %s Output ===\n", dependsOnRole))
 		for _, r := range results {
 			if r.Status == StatusComplete && r.Output != "" {
+				data.Previous = append(data.Previous, r)
 				sb.WriteString(r.Output)
 				sb.WriteString("\n---\n")
 			}
@@ -631,12 +790,22 @@ func (o *Orchestrator) buildDependentPrompt(basePrompt string, dependsOnRole Age
 
 	sb.WriteString("=== Original Task ===\n")
 	sb.WriteString(basePrompt)
+	data.Default = sb.String()
 
-	return sb.String()
+	path := o.config.PromptTemplates[role]
+	if path == "" {
+		return data.Default, nil
+	}
+	return prompt.RenderTemplate(path, data)
 }
 
 // shouldRefactor determines if refactoring should be triggered based on review results
 func (o *Orchestrator) shouldRefactor(result *WorkflowResult) bool {
+	// A settled review conflict overrides the individual verdicts
+	if result.Resolution != nil && result.Resolution.Verdict != "" {
+		return result.Resolution.Verdict == VerdictReject
+	}
+
 	// Check if there are review results with issues
 	for _, stage := range result.Stages {
 		if stage.Name == "review" {
@@ -706,10 +875,135 @@ func (o *Orchestrator) ResolveConflicts(results []AgentResult) (*ConflictResolut
 		resolution.WinningResults = o.resolveByPriority(results, conflicts)
 	}
 
-	resolution.Resolved = true
+	// Put what the strategy could not settle to a human, instead of silently
+	// falling back to the highest priority agent
+	for _, c := range o.undecided(resolution, results, conflicts) {
+		if o.decider == nil {
+			resolution.Pending = append(resolution.Pending, c)
+			continue
+		}
+		choice, err := o.decider.Decide(c)
+		if errors.Is(err, ErrDecisionPending) {
+			resolution.Pending = append(resolution.Pending, c)
+			continue
+		}
+		if err != nil {
+			return resolution, err
+		}
+
+		decision := ContextDecision{Topic: c.Description, Decision: choice, Agents: []string{HumanDecider}}
+		o.context.AddDecision(decision)
+		resolution.Decisions = append(resolution.Decisions, decision)
+		applyDecision(resolution, c, choice)
+	}
+	if len(resolution.Decisions) > 0 {
+		if err := o.context.Save(); err != nil {
+			return resolution, fmt.Errorf("failed to save context: %w", err)
+		}
+	}
+
+	resolution.Resolved = len(resolution.Pending) == 0
 	return resolution, nil
 }
 
+// undecided returns the conflicts the strategy cannot settle on its own and
+// records the verdict of those it can: reviewer verdicts tied on priority or
+// votes (and always under merge, which cannot merge approve with reject), and
+// suggestions exactly half of the agents vote for
+func (o *Orchestrator) undecided(resolution *ConflictResolution, results []AgentResult, conflicts []Conflict) []Conflict {
+	priorities := make(map[string]int)
+	for _, agent := range o.config.Agents {
+		priorities[agent.ID] = agent.Priority
+	}
+
+	var open []Conflict
+	for _, c := range conflicts {
+		if c.Type != "approval" {
+			continue
+		}
+		approve, reject := 0, 0
+		approvePriority, rejectPriority := math.MinInt, math.MinInt
+		for _, r := range results {
+			if r.Role != RoleReviewer {
+				continue
+			}
+			if r.Approved {
+				approve++
+				approvePriority = max(approvePriority, priorities[r.AgentID])
+			} else {
+				reject++
+				rejectPriority = max(rejectPriority, priorities[r.AgentID])
+			}
+		}
+
+		var approved bool
+		switch o.config.ConflictResolution {
+		case "merge":
+			open = append(open, c)
+			continue
+		case "vote":
+			if approve == reject {
+				open = append(open, c)
+				continue
+			}
+			approved = approve > reject
+		default:
+			if approvePriority == rejectPriority {
+				open = append(open, c)
+				continue
+			}
+			approved = approvePriority > rejectPriority
+		}
+		resolution.Verdict = VerdictReject
+		if approved {
+			resolution.Verdict = VerdictApprove
+		}
+	}
+
+	if o.config.ConflictResolution == "vote" && len(results) > 1 {
+		supporters := make(map[string][]string)
+		var suggestions []string
+		for _, r := range results {
+			if r.Status != StatusComplete {
+				continue
+			}
+			for _, s := range deduplicateStrings(r.Suggestions) {
+				if len(supporters[s]) == 0 {
+					suggestions = append(suggestions, s)
+				}
+				supporters[s] = append(supporters[s], r.AgentID)
+			}
+		}
+		for _, s := range suggestions {
+			if len(supporters[s])*2 == len(results) {
+				open = append(open, Conflict{
+					Type:        "suggestion",
+					Description: fmt.Sprintf("Agents are split on suggestion %q", s),
+					Subject:     s,
+					AgentIDs:    supporters[s],
+					Options:     []string{"keep", "drop"},
+				})
+			}
+		}
+	}
+	return open
+}
+
+// applyDecision applies a human decision to the resolution
+func applyDecision(resolution *ConflictResolution, c Conflict, choice string) {
+	switch c.Type {
+	case "approval":
+		resolution.Verdict = choice
+	case "suggestion":
+		if choice != "keep" {
+			return
+		}
+		for i := range resolution.WinningResults {
+			resolution.WinningResults[i].Suggestions = append(resolution.WinningResults[i].Suggestions, c.Subject)
+		}
+	}
+}
+
 // resolveByPriority resolves conflicts by using the highest priority agent's result
 func (o *Orchestrator) resolveByPriority(results []AgentResult, conflicts []Conflict) []AgentResult {
 	// Get agent priorities
@@ -851,14 +1145,15 @@ func (o *Orchestrator) GetHealthStatus() map[string]HealthInfo {
 
 // WorkflowResult contains the results of a multi-agent workflow execution
 type WorkflowResult struct {
-	FeatureID   int           `json:"feature_id"`
-	FeatureDesc string        `json:"feature_desc"`
-	Iteration   int           `json:"iteration"`
-	StartTime   time.Time     `json:"start_time"`
-	EndTime     time.Time     `json:"end_time"`
-	Stages      []StageResult `json:"stages"`
-	Success     bool          `json:"success"`
-	Error       string        `json:"error,omitempty"`
+	FeatureID   int                 `json:"feature_id"`
+	FeatureDesc string              `json:"feature_desc"`
+	Iteration   int                 `json:"iteration"`
+	StartTime   time.Time           `json:"start_time"`
+	EndTime     time.Time           `json:"end_time"`
+	Stages      []StageResult       `json:"stages"`
+	Resolution  *ConflictResolution `json:"resolution,omitempty"`
+	Success     bool                `json:"success"`
+	Error       string              `json:"error,omitempty"`
 }
 
 // StageResult contains results for a single workflow stage
@@ -870,19 +1165,30 @@ type StageResult struct {
 	Success   bool          `json:"success"`
 }
 
+// Review verdicts of a settled approval conflict
+const (
+	VerdictApprove = "approve"
+	VerdictReject  = "reject"
+)
+
 // ConflictResolution contains the results of conflict resolution
 type ConflictResolution struct {
-	Strategy       string        `json:"strategy"`
-	Conflicts      []Conflict    `json:"conflicts"`
-	WinningResults []AgentResult `json:"winning_results"`
-	Resolved       bool          `json:"resolved"`
+	Strategy       string            `json:"strategy"`
+	Conflicts      []Conflict        `json:"conflicts"`
+	WinningResults []AgentResult     `json:"winning_results"`
+	Verdict        string            `json:"verdict,omitempty"`   // Settled review verdict when reviewers disagreed
+	Decisions      []ContextDecision `json:"decisions,omitempty"` // Decisions made by a human
+	Pending        []Conflict        `json:"pending,omitempty"`   // Conflicts still waiting for a human decision
+	Resolved       bool              `json:"resolved"`
 }
 
 // Conflict represents a conflict between agent results
 type Conflict struct {
 	Type        string   `json:"type"`
 	Description string   `json:"description"`
+	Subject     string   `json:"subject,omitempty"` // What the agents disagree on (e.g., the suggestion)
 	AgentIDs    []string `json:"agent_ids"`
+	Options     []string `json:"options,omitempty"` // Choices offered to a human when the strategy cannot decide
 }
 
 // HealthInfo contains health information for an agent
@@ -1028,6 +1334,7 @@ func detectConflicts(results []AgentResult) []Conflict {
 			Type:        "approval",
 			Description: "Reviewers disagree on approval",
 			AgentIDs:    append(approved, rejected...),
+			Options:     []string{VerdictApprove, VerdictReject},
 		})
 	}
 
@@ -1047,7 +1354,8 @@ func deduplicateStrings(slice []string) []string {
 	return result
 }
 
-// LoadMultiAgentConfig loads multi-agent configuration from a file
+// LoadMultiAgentConfig loads multi-agent configuration from a file. Files
+// ending in .yaml or .yml are parsed as YAML, others as JSON.
 func LoadMultiAgentConfig(path string) (*MultiAgentConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -1055,7 +1363,13 @@ func LoadMultiAgentConfig(path string) (*MultiAgentConfig, error) {
 	}
 
 	config := &MultiAgentConfig{}
-	if err := json.Unmarshal(data, config); err != nil {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, config)
+	default:
+		err = json.Unmarshal(data, config)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse agents config file: %w", err)
 	}
 
@@ -1067,6 +1381,25 @@ func LoadMultiAgentConfig(path string) (*MultiAgentConfig, error) {
 	return config, nil
 }
 
+// ParseAgentsSection builds a multi-agent configuration from the agents
+// section of the main config file, which lists agent definitions in the same
+// form as the agents of a standalone agents file. maxParallel is the
+// parallel_agents setting.
+func ParseAgentsSection(agents []map[string]any, maxParallel int) (*MultiAgentConfig, error) {
+	data, err := yaml.Marshal(agents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode agents section: %w", err)
+	}
+	config := &MultiAgentConfig{MaxParallel: maxParallel}
+	if err := yaml.Unmarshal(data, &config.Agents); err != nil {
+		return nil, fmt.Errorf("failed to parse agents section: %w", err)
+	}
+	if err := validateMultiAgentConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
 // validateMultiAgentConfig validates the multi-agent configuration
 func validateMultiAgentConfig(config *MultiAgentConfig) error {
 	if len(config.Agents) == 0 {
@@ -1090,6 +1423,12 @@ func validateMultiAgentConfig(config *MultiAgentConfig) error {
 		if _, err := ParseAgentRole(string(agent.Role)); err != nil {
 			return fmt.Errorf("agent %s: %w", agent.ID, err)
 		}
+
+		for name := range agent.Env {
+			if err := ralphagent.ValidateEnvName(name); err != nil {
+				return fmt.Errorf("agent %s: %w", agent.ID, err)
+			}
+		}
 	}
 
 	if config.MaxParallel < 0 {
@@ -1106,6 +1445,18 @@ func validateMultiAgentConfig(config *MultiAgentConfig) error {
 		return fmt.Errorf("invalid conflict_resolution %q: must be priority, merge, or vote", config.ConflictResolution)
 	}
 
+	for role, path := range config.PromptTemplates {
+		if _, err := ParseAgentRole(string(role)); err != nil {
+			return fmt.Errorf("prompt_templates: %w", err)
+		}
+		if role == RoleImplementer {
+			return fmt.Errorf("prompt_templates: the implementer prompt is the iteration prompt, set iteration_prompt_template instead")
+		}
+		if _, err := prompt.LoadTemplate(path); err != nil {
+			return fmt.Errorf("prompt_templates: %s: %w", role, err)
+		}
+	}
+
 	return nil
 }
 
@@ -1150,6 +1501,15 @@ func (wr *WorkflowResult) Summary() string {
 			}
 		}
 	}
+
+	if res := wr.Resolution; res != nil {
+		for _, d := range res.Decisions {
+			sb.WriteString(fmt.Sprintf("\nHuman decision: %s -> %s\n", d.Topic, d.Decision))
+		}
+		for _, c := range res.Pending {
+			sb.WriteString(fmt.Sprintf("\nWaiting for a human decision: %s (%s)\n", c.Description, strings.Join(c.Options, "/")))
+		}
+	}
 	
 	return sb.String()
 }
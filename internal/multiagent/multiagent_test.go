@@ -3,8 +3,10 @@ package multiagent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -268,7 +270,7 @@ func TestOrchestrator(t *testing.T) {
 	}
 
 	t.Run("NewOrchestrator", func(t *testing.T) {
-		orch := NewOrchestrator(config, "")
+		orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 		if orch == nil {
 			t.Fatal("NewOrchestrator returned nil")
 		}
@@ -278,7 +280,7 @@ func TestOrchestrator(t *testing.T) {
 	})
 
 	t.Run("GetEnabledAgents", func(t *testing.T) {
-		orch := NewOrchestrator(config, "")
+		orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 		enabled := orch.GetEnabledAgents()
 		
 		if len(enabled) != 3 {
@@ -292,7 +294,7 @@ func TestOrchestrator(t *testing.T) {
 	})
 
 	t.Run("GetAgentsByRole", func(t *testing.T) {
-		orch := NewOrchestrator(config, "")
+		orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 		implementers := orch.GetAgentsByRole(RoleImplementer)
 		
 		if len(implementers) != 1 { // disabled one shouldn't be included
@@ -301,7 +303,7 @@ func TestOrchestrator(t *testing.T) {
 	})
 
 	t.Run("GetAgentStatus", func(t *testing.T) {
-		orch := NewOrchestrator(config, "")
+		orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 		status := orch.GetAgentStatus("impl-1")
 		
 		if status != StatusIdle {
@@ -344,6 +346,39 @@ func TestOrchestratorExecution(t *testing.T) {
 		}
 	})
 
+	t.Run("Role prompt template", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		templatePath := filepath.Join(tmpDir, "tester.tmpl")
+		template := `Test as a {{.Role}} after {{.PreviousRole}}:{{range .Previous}} [{{.AgentID}}: {{.Output}}]{{end}}
+Task: {{.Task}}`
+		os.WriteFile(templatePath, []byte(template), 0644)
+
+		templated := *config
+		templated.PromptTemplates = map[AgentRole]string{RoleTester: templatePath}
+		orch := NewOrchestrator(&templated, filepath.Join(tmpDir, "context.json"))
+		mock := NewMockExecutor()
+		mock.SetResult("impl-1", "Implemented X")
+		mock.SetResult("test-1", "All tests passed")
+		orch.SetExecutor(mock)
+
+		if _, err := orch.ExecuteWorkflow(context.Background(), 1, "Test feature", 1, "Implement feature X"); err != nil {
+			t.Fatalf("ExecuteWorkflow error = %v", err)
+		}
+		want := "Test as a tester after implementer: [impl-1: Implemented X]\nTask: Implement feature X"
+		tested := false
+		for _, call := range mock.calls {
+			if call.AgentID == "test-1" {
+				tested = true
+				if call.Prompt != want {
+					t.Errorf("tester prompt = %q, want %q", call.Prompt, want)
+				}
+			}
+		}
+		if !tested {
+			t.Error("tester was not run")
+		}
+	})
+
 	t.Run("ExecuteParallel", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		contextPath := filepath.Join(tmpDir, "context.json")
@@ -462,6 +497,71 @@ Problems detected:
 	}
 }
 
+func TestReview(t *testing.T) {
+	tests := []struct {
+		name       string
+		outputs    map[string]string
+		priorities [2]int
+		approved   bool
+		issues     int
+	}{
+		{name: "all approve", outputs: map[string]string{"review-1": "LGTM", "review-2": "Looks good"}, approved: true},
+		{name: "all reject", outputs: map[string]string{"review-1": "Needs work\nIssues:\n- No tests", "review-2": "Rejected"}, issues: 1},
+		{name: "priority approves", outputs: map[string]string{"review-1": "Approved", "review-2": "Needs work"}, priorities: [2]int{10, 5}, approved: true},
+		{name: "priority rejects", outputs: map[string]string{"review-1": "Approved", "review-2": "Needs work"}, priorities: [2]int{5, 10}},
+		{name: "reviewer fails", outputs: map[string]string{"review-1": "Approved"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &MultiAgentConfig{
+				Agents: []AgentConfig{
+					{ID: "impl-1", Role: RoleImplementer, Command: "cmd", Enabled: true},
+					{ID: "review-1", Role: RoleReviewer, Command: "cmd", Priority: tt.priorities[0], Enabled: true},
+					{ID: "review-2", Role: RoleReviewer, Command: "cmd", Priority: tt.priorities[1], Enabled: true},
+				},
+				ConflictResolution: "priority",
+			}
+			orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
+			orch.SetDecider(nil)
+			mock := NewMockExecutor()
+			for id, out := range tt.outputs {
+				mock.SetResult(id, out)
+			}
+			if _, ok := tt.outputs["review-2"]; !ok {
+				mock.SetError("review-2", errors.New("command not found"))
+			}
+			orch.SetExecutor(mock)
+
+			review, err := orch.Review(context.Background(), 1, "Login", 3, "Implement login", "Added the login form")
+			if err != nil {
+				t.Fatalf("Review error = %v", err)
+			}
+			if review.Approved != tt.approved {
+				t.Errorf("Approved = %v, want %v", review.Approved, tt.approved)
+			}
+			if len(review.Issues()) != tt.issues {
+				t.Errorf("Issues() = %v, want %d", review.Issues(), tt.issues)
+			}
+			for _, call := range mock.calls {
+				if call.AgentID == "impl-1" {
+					t.Error("Review ran the implementer")
+				}
+				if !strings.Contains(call.Prompt, "Added the login form") || !strings.Contains(call.Prompt, "Implement login") {
+					t.Errorf("review prompt = %q, want the implementation and the task", call.Prompt)
+				}
+			}
+		})
+	}
+
+	t.Run("no reviewers", func(t *testing.T) {
+		orch := NewOrchestrator(&MultiAgentConfig{Agents: []AgentConfig{{ID: "impl-1", Role: RoleImplementer, Command: "cmd", Enabled: true}}}, filepath.Join(t.TempDir(), "context.json"))
+		if _, err := orch.Review(context.Background(), 1, "Login", 3, "Implement login", "done"); err == nil {
+			t.Error("Review without reviewers should error")
+		}
+	})
+}
+
 func TestIsApproved(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -526,7 +626,7 @@ func TestConflictResolution(t *testing.T) {
 	}
 
 	t.Run("ResolveByPriority", func(t *testing.T) {
-		orch := NewOrchestrator(config, "")
+		orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 		results := []AgentResult{
 			{AgentID: "agent-1", Role: RoleReviewer, Status: StatusComplete, Approved: true},
 			{AgentID: "agent-2", Role: RoleReviewer, Status: StatusComplete, Approved: false},
@@ -544,7 +644,7 @@ func TestConflictResolution(t *testing.T) {
 	})
 
 	t.Run("ResolveByMerge", func(t *testing.T) {
-		orch := NewOrchestrator(config, "")
+		orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 		results := []AgentResult{
 			{AgentID: "agent-1", Status: StatusComplete, Suggestions: []string{"A", "B"}},
 			{AgentID: "agent-2", Status: StatusComplete, Suggestions: []string{"B", "C"}},
@@ -641,6 +741,92 @@ func TestLoadMultiAgentConfig(t *testing.T) {
 		}
 	})
 
+	t.Run("Per-agent env", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "agents.json")
+
+		configJSON := `{
+			"agents": [
+				{"id": "fast", "role": "implementer", "command": "claude", "enabled": true,
+				 "env": {"ANTHROPIC_API_KEY": "env:TEAM_A_KEY", "ANTHROPIC_MODEL": "claude-haiku"}},
+				{"id": "review", "role": "reviewer", "command": "claude", "enabled": true,
+				 "env": {"HTTPS_PROXY": "http://proxy:3128"}}
+			]
+		}`
+		os.WriteFile(configPath, []byte(configJSON), 0644)
+
+		loaded, err := LoadMultiAgentConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadMultiAgentConfig error = %v", err)
+		}
+		if loaded.Agents[0].Env["ANTHROPIC_API_KEY"] != "env:TEAM_A_KEY" || loaded.Agents[1].Env["HTTPS_PROXY"] == "" {
+			t.Errorf("per-agent env not loaded: %+v", loaded.Agents)
+		}
+	})
+
+	t.Run("Invalid config - bad env name", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "agents.json")
+
+		configJSON := `{
+			"agents": [
+				{"id": "agent-1", "role": "implementer", "command": "cmd", "enabled": true, "env": {"BAD=NAME": "x"}}
+			]
+		}`
+		os.WriteFile(configPath, []byte(configJSON), 0644)
+
+		if _, err := LoadMultiAgentConfig(configPath); err == nil {
+			t.Error("Should error on invalid env variable name")
+		}
+	})
+
+	t.Run("YAML config", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "agents.yaml")
+		configYAML := `
+agents:
+  - id: impl-1
+    role: implementer
+    command: claude
+    timeout: 10m
+    enabled: true
+max_parallel: 3
+conflict_resolution: merge
+`
+		os.WriteFile(configPath, []byte(configYAML), 0644)
+
+		loaded, err := LoadMultiAgentConfig(configPath)
+		if err != nil {
+			t.Fatalf("LoadMultiAgentConfig error = %v", err)
+		}
+		if len(loaded.Agents) != 1 || loaded.Agents[0].Timeout != 10*time.Minute || loaded.MaxParallel != 3 || loaded.ConflictResolution != "merge" {
+			t.Errorf("YAML config not loaded: %+v", loaded)
+		}
+	})
+
+	t.Run("Prompt templates", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		configPath := filepath.Join(tmpDir, "agents.json")
+		os.WriteFile(filepath.Join(tmpDir, "review.tmpl"), []byte("{{.Default}}"), 0644)
+		os.WriteFile(filepath.Join(tmpDir, "broken.tmpl"), []byte("{{.Default"), 0644)
+
+		for templates, valid := range map[string]bool{
+			`{"reviewer": "` + filepath.Join(tmpDir, "review.tmpl") + `"}`:    true,
+			`{"reviewer": "` + filepath.Join(tmpDir, "broken.tmpl") + `"}`:    false,
+			`{"reviewer": "` + filepath.Join(tmpDir, "missing.tmpl") + `"}`:   false,
+			`{"implementer": "` + filepath.Join(tmpDir, "review.tmpl") + `"}`: false,
+			`{"designer": "` + filepath.Join(tmpDir, "review.tmpl") + `"}`:    false,
+		} {
+			configJSON := `{
+				"agents": [{"id": "agent-1", "role": "reviewer", "command": "cmd", "enabled": true}],
+				"prompt_templates": ` + templates + `
+			}`
+			os.WriteFile(configPath, []byte(configJSON), 0644)
+			if _, err := LoadMultiAgentConfig(configPath); (err == nil) != valid {
+				t.Errorf("prompt_templates %s: error = %v, want valid = %v", templates, err, valid)
+			}
+		}
+	})
+
 	t.Run("File not found", func(t *testing.T) {
 		_, err := LoadMultiAgentConfig("/nonexistent/path/agents.json")
 		if err == nil {
@@ -649,6 +835,31 @@ func TestLoadMultiAgentConfig(t *testing.T) {
 	})
 }
 
+func TestParseAgentsSection(t *testing.T) {
+	agents := []map[string]any{
+		{"id": "impl-1", "role": "implementer", "command": "claude", "enabled": true, "timeout": "5m"},
+		{"id": "review-1", "role": "reviewer", "command": "claude", "priority": 4},
+	}
+	config, err := ParseAgentsSection(agents, 3)
+	if err != nil {
+		t.Fatalf("ParseAgentsSection error = %v", err)
+	}
+	if len(config.Agents) != 2 || config.MaxParallel != 3 {
+		t.Fatalf("ParseAgentsSection = %+v", config)
+	}
+	if config.Agents[0].Timeout != 5*time.Minute || config.Agents[1].Priority != 4 || config.Agents[1].Enabled {
+		t.Errorf("agents = %+v", config.Agents)
+	}
+
+	agents[1]["role"] = "designer"
+	if _, err := ParseAgentsSection(agents, 3); err == nil || !strings.Contains(err.Error(), "review-1") {
+		t.Errorf("ParseAgentsSection accepted an invalid role: %v", err)
+	}
+	if _, err := ParseAgentsSection([]map[string]any{{"id": "x", "command": []string{"a"}}}, 0); err == nil {
+		t.Error("ParseAgentsSection accepted a list as command")
+	}
+}
+
 func TestWorkflowResultSummary(t *testing.T) {
 	result := &WorkflowResult{
 		FeatureID:   1,
@@ -694,7 +905,7 @@ func TestHealthStatus(t *testing.T) {
 		},
 	}
 
-	orch := NewOrchestrator(config, "")
+	orch := NewOrchestrator(config, filepath.Join(t.TempDir(), "context.json"))
 	
 	health := orch.GetHealthStatus()
 	
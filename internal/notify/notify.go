@@ -0,0 +1,96 @@
+// Package notify shows desktop notifications during long local runs
+// (-notify): when the run finishes, a milestone completes, or an approval gate
+// is waiting. Notifications use the tools that come with each platform:
+// osascript on macOS, notify-send on Linux and a PowerShell toast on Windows.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds how long a notification command may take, so a hung
+// notification daemon cannot stall the run
+const commandTimeout = 5 * time.Second
+
+// Notifier sends desktop notifications. A nil or disabled Notifier does nothing.
+type Notifier struct {
+	goos string
+	run  func(ctx context.Context, name string, args ...string) error
+}
+
+// New creates a notifier for this platform. It returns nil, disabling
+// notifications, unless enabled is set and Ralph is not running in CI, where
+// there is no desktop to notify.
+func New(enabled, ci bool) *Notifier {
+	if !enabled || ci {
+		return nil
+	}
+	return &Notifier{goos: runtime.GOOS, run: runCommand}
+}
+
+// Notify shows a notification with a title and message
+func (n *Notifier) Notify(title, message string) error {
+	if n == nil {
+		return nil
+	}
+	name, args, ok := Command(n.goos, title, message)
+	if !ok {
+		return fmt.Errorf("desktop notifications are not supported on %s", n.goos)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	if err := n.run(ctx, name, args...); err != nil {
+		return fmt.Errorf("failed to show notification with %s: %w", name, err)
+	}
+	return nil
+}
+
+// Command returns the command that shows a notification on goos, and false
+// if the platform has no supported notification tool
+func Command(goos, title, message string) (string, []string, bool) {
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		return "osascript", []string{"-e", script}, true
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return "notify-send", []string{"--app-name=Ralph", title, message}, true
+	case "windows":
+		script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode(%s)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode(%s)) | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('Ralph').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`,
+			powerShellString(title), powerShellString(message))
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, true
+	}
+	return "", nil, false
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// powerShellString quotes s as a single-quoted PowerShell string literal
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// runCommand runs a notification command, including its output in errors
+func runCommand(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
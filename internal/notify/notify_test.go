@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if New(false, false) != nil {
+		t.Error("New() without -notify returned a notifier")
+	}
+	if New(true, true) != nil {
+		t.Error("New() in CI returned a notifier")
+	}
+	if New(true, false) == nil {
+		t.Error("New() with -notify returned nil")
+	}
+
+	// A nil notifier does nothing
+	var n *Notifier
+	if err := n.Notify("Ralph", "done"); err != nil {
+		t.Errorf("Notify() on nil notifier = %v", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		goos     string
+		wantName string
+		wantArg  string
+	}{
+		{"darwin", "osascript", `display notification "Feature \"#3\" done" with title "Ralph: it's done"`},
+		{"linux", "notify-send", `Feature "#3" done`},
+		{"windows", "powershell", `CreateTextNode('Ralph: it''s done')`},
+	}
+	for _, tt := range tests {
+		name, args, ok := Command(tt.goos, "Ralph: it's done", `Feature "#3" done`)
+		if !ok || name != tt.wantName {
+			t.Fatalf("Command(%s) = %s, %v", tt.goos, name, ok)
+		}
+		if joined := strings.Join(args, " "); !strings.Contains(joined, tt.wantArg) {
+			t.Errorf("Command(%s) args = %q, want them to contain %q", tt.goos, joined, tt.wantArg)
+		}
+	}
+	if _, _, ok := Command("plan9", "Ralph", "done"); ok {
+		t.Error("Command(plan9) is supported")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var got []string
+	n := &Notifier{goos: "linux", run: func(ctx context.Context, name string, args ...string) error {
+		got = append([]string{name}, args...)
+		return nil
+	}}
+	if err := n.Notify("Ralph", "Run finished"); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if strings.Join(got, " ") != "notify-send --app-name=Ralph Ralph Run finished" {
+		t.Errorf("ran %q", got)
+	}
+
+	n.run = func(ctx context.Context, name string, args ...string) error { return errors.New("no daemon") }
+	if err := n.Notify("Ralph", "Run finished"); err == nil || !strings.Contains(err.Error(), "no daemon") {
+		t.Errorf("Notify() = %v, want the command error", err)
+	}
+	n.goos = "plan9"
+	if err := n.Notify("Ralph", "Run finished"); err == nil {
+		t.Error("Notify() on an unsupported platform succeeded")
+	}
+}
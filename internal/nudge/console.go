@@ -0,0 +1,118 @@
+package nudge
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ConsoleKey is the line that opens the nudge prompt of a Console
+const ConsoleKey = "n"
+
+// Console lets the user add nudges by typing them into the terminal during a
+// run, instead of editing the nudge file from another terminal. Typing
+// ConsoleKey and Enter asks for a nudge in the -nudge format; "n <nudge>"
+// adds it directly. Added nudges are saved to the store right away, so they
+// are injected into the next iteration.
+type Console struct {
+	store   *Store
+	in      *bufio.Reader
+	out     io.Writer
+	mu      sync.Mutex
+	stopped bool
+	done    chan struct{}
+}
+
+// NewConsole creates a console reading from in and adding nudges to store
+func NewConsole(store *Store, in io.Reader, out io.Writer) *Console {
+	return &Console{
+		store: store,
+		in:    bufio.NewReader(in),
+		out:   out,
+		done:  make(chan struct{}),
+	}
+}
+
+// Start reads input in the background until the input ends or Stop is called
+func (c *Console) Start() {
+	go c.run()
+}
+
+// Stop stops handling input. A line that is being typed is discarded.
+func (c *Console) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+}
+
+// Done is closed when the console has stopped reading because the input ended
+func (c *Console) Done() <-chan struct{} {
+	return c.done
+}
+
+// run handles input lines until the input ends
+func (c *Console) run() {
+	defer close(c.done)
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return
+		}
+
+		switch {
+		case line == "":
+		case strings.EqualFold(line, ConsoleKey):
+			c.printf("Nudge ([feature:<id>:|milestone:<name>:]type:content, e.g., focus:Work on feature 5): ")
+			if line, err = c.readLine(); err != nil {
+				return
+			}
+			if line != "" {
+				c.add(line)
+			}
+		case strings.HasPrefix(strings.ToLower(line), ConsoleKey+" "):
+			c.add(strings.TrimSpace(line[len(ConsoleKey):]))
+		default:
+			c.printf("Type %s and press Enter to add a nudge\n", ConsoleKey)
+		}
+	}
+}
+
+// add parses a nudge and saves it to the store
+func (c *Console) add(text string) {
+	n, err := Parse(text)
+	if err == nil {
+		if c.isStopped() {
+			return
+		}
+		var added *Nudge
+		if added, err = c.store.Insert(n); err == nil {
+			c.printf("Nudge added: [%s] %s (applies from the next iteration)\n", strings.ToUpper(string(added.Type)), added.Content)
+			return
+		}
+	}
+	c.printf("Nudge not added: %v\n", err)
+}
+
+// readLine reads a trimmed line of input
+func (c *Console) readLine() (string, error) {
+	line, err := c.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// printf writes to the console output unless the console was stopped
+func (c *Console) printf(format string, args ...any) {
+	if !c.isStopped() {
+		fmt.Fprintf(c.out, format, args...)
+	}
+}
+
+func (c *Console) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
@@ -0,0 +1,49 @@
+package nudge
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsole(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "nudges.json"))
+	store.Load()
+
+	in := strings.NewReader("hello\nn\nfocus:Work on feature 5\nN feature:3:constraint:Keep the API\n\nn\nbogus:content\nn\n\n")
+	var out strings.Builder
+	console := NewConsole(store, in, &out)
+	console.Start()
+	<-console.Done()
+
+	all := store.GetAll()
+	if len(all) != 2 {
+		t.Fatalf("store has %d nudges, want 2: %+v", len(all), all)
+	}
+	if all[0].Type != NudgeTypeFocus || all[0].Content != "Work on feature 5" {
+		t.Errorf("first nudge = %+v", all[0])
+	}
+	if all[1].Feature != 3 || all[1].Content != "Keep the API" {
+		t.Errorf("second nudge = %+v", all[1])
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"Type n and press Enter to add a nudge",
+		"Nudge added: [FOCUS] Work on feature 5",
+		"Nudge not added: invalid nudge type",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("console output missing %q:\n%s", want, got)
+		}
+	}
+
+	// Once stopped, input is ignored
+	stopped := NewConsole(store, strings.NewReader("n style:Tabs\n"), &out)
+	stopped.Stop()
+	stopped.Start()
+	<-stopped.Done()
+	if store.Count() != 2 {
+		t.Errorf("stopped console added a nudge")
+	}
+}
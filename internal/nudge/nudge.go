@@ -9,9 +9,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 const (
@@ -31,6 +34,9 @@ const (
 	NudgeTypeConstraint NudgeType = "constraint"
 	// NudgeTypeStyle specifies coding style preferences
 	NudgeTypeStyle NudgeType = "style"
+	// NudgeTypeCheckpoint asks the running loop to create a named checkpoint.
+	// It is handled by Ralph itself and never injected into agent prompts.
+	NudgeTypeCheckpoint NudgeType = "checkpoint"
 )
 
 // Nudge represents a single nudge entry that provides guidance to the AI agent
@@ -38,12 +44,114 @@ type Nudge struct {
 	ID           string    `json:"id"`
 	Type         NudgeType `json:"type"`
 	Content      string    `json:"content"`
-	Priority     int       `json:"priority,omitempty"` // Higher = more important (default 0)
+	Priority     int       `json:"priority,omitempty"`   // Higher = more important (default 0)
+	Feature      int       `json:"feature,omitempty"`    // Only applies while this feature is worked on
+	Milestone    string    `json:"milestone,omitempty"`  // Only applies while a feature of this milestone is worked on
+	Sticky       bool      `json:"sticky,omitempty"`     // Not acknowledged after being injected
+	TTL          string    `json:"ttl,omitempty"`        // Expiry: a duration (e.g., "2h") or a number of iterations (e.g., "3")
+	Injections   int       `json:"injections,omitempty"` // Iterations the nudge was injected into
 	CreatedAt    time.Time `json:"created_at"`
 	Acknowledged bool      `json:"acknowledged,omitempty"` // Set to true when processed
 	AckedAt      time.Time `json:"acked_at,omitempty"`     // When it was acknowledged
 }
 
+// Scoped reports whether the nudge targets a feature or milestone instead of
+// the next iteration
+func (n Nudge) Scoped() bool {
+	return n.Feature > 0 || n.Milestone != ""
+}
+
+// AppliesTo reports whether the nudge applies while the given feature is
+// worked on. Unscoped nudges apply to any feature; feature is nil when the
+// current feature is unknown.
+func (n Nudge) AppliesTo(feature *plan.Plan) bool {
+	if !n.Scoped() {
+		return true
+	}
+	if feature == nil {
+		return false
+	}
+	if n.Feature > 0 && n.Feature != feature.ID {
+		return false
+	}
+	return n.Milestone == "" || strings.EqualFold(n.Milestone, feature.Milestone)
+}
+
+// Target describes the feature or milestone a scoped nudge applies to
+func (n Nudge) Target() string {
+	switch {
+	case n.Feature > 0 && n.Milestone != "":
+		return fmt.Sprintf("feature #%d, milestone %s", n.Feature, n.Milestone)
+	case n.Feature > 0:
+		return fmt.Sprintf("feature #%d", n.Feature)
+	case n.Milestone != "":
+		return "milestone " + n.Milestone
+	}
+	return ""
+}
+
+// Persistent reports whether the nudge stays active after being injected:
+// sticky, expiring and scoped nudges do
+func (n Nudge) Persistent() bool {
+	return n.Sticky || n.TTL != "" || n.Scoped()
+}
+
+// Expired reports whether the nudge's TTL has run out at now
+func (n Nudge) Expired(now time.Time) bool {
+	duration, iterations, err := ParseTTL(n.TTL)
+	if err != nil {
+		return false
+	}
+	if duration > 0 {
+		return !now.Before(n.CreatedAt.Add(duration))
+	}
+	return iterations > 0 && n.Injections >= iterations
+}
+
+// Lifetime describes how long an active nudge remains in effect at now
+func (n Nudge) Lifetime(now time.Time) string {
+	var parts []string
+	if n.Scoped() {
+		parts = append(parts, "until "+n.Target()+" is complete")
+	}
+	if duration, iterations, err := ParseTTL(n.TTL); err == nil && duration > 0 {
+		remaining := n.CreatedAt.Add(duration).Sub(now).Round(time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		parts = append(parts, fmt.Sprintf("expires in %s", remaining))
+	} else if iterations > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d iteration(s) left", max(iterations-n.Injections, 0), iterations))
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, ", ")
+	}
+	if n.Sticky {
+		return "sticky, until cleared"
+	}
+	return "next iteration only"
+}
+
+// ParseTTL parses a nudge TTL: either a duration such as "30m" or "2h", or a
+// number of iterations such as "3". An empty TTL never expires.
+func ParseTTL(ttl string) (time.Duration, int, error) {
+	ttl = strings.TrimSpace(ttl)
+	if ttl == "" {
+		return 0, 0, nil
+	}
+	if iterations, err := strconv.Atoi(ttl); err == nil {
+		if iterations <= 0 {
+			return 0, 0, fmt.Errorf("invalid nudge ttl %q: must be positive", ttl)
+		}
+		return 0, iterations, nil
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil || duration <= 0 {
+		return 0, 0, fmt.Errorf("invalid nudge ttl %q: expected a duration (e.g., 2h) or a number of iterations", ttl)
+	}
+	return duration, 0, nil
+}
+
 // NudgeFile represents the complete nudges file structure
 type NudgeFile struct {
 	Nudges      []Nudge   `json:"nudges"`
@@ -112,6 +220,11 @@ func (s *Store) Load() error {
 	if err := json.Unmarshal(data, &nf); err != nil {
 		return fmt.Errorf("failed to parse nudge file: %w", err)
 	}
+	for _, n := range nf.Nudges {
+		if _, _, err := ParseTTL(n.TTL); err != nil {
+			return fmt.Errorf("nudge %s: %w", n.ID, err)
+		}
+	}
 
 	s.nudgeFile = &nf
 	return nil
@@ -175,6 +288,12 @@ func (s *Store) Clear() error {
 
 // Add creates and saves a new nudge
 func (s *Store) Add(nudgeType NudgeType, content string, priority int) (*Nudge, error) {
+	return s.Insert(Nudge{Type: nudgeType, Content: content, Priority: priority})
+}
+
+// Insert saves a new nudge built by the caller (e.g., by Parse), assigning
+// its ID and creation time
+func (s *Store) Insert(nudge Nudge) (*Nudge, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -185,14 +304,16 @@ func (s *Store) Add(nudgeType NudgeType, content string, priority int) (*Nudge,
 		}
 	}
 
-	nudge := Nudge{
-		ID:        generateID(),
-		Type:      nudgeType,
-		Content:   strings.TrimSpace(content),
-		Priority:  priority,
-		CreatedAt: time.Now(),
+	if _, _, err := ParseTTL(nudge.TTL); err != nil {
+		return nil, err
 	}
 
+	nudge.ID = generateID()
+	nudge.Content = strings.TrimSpace(nudge.Content)
+	nudge.TTL = strings.TrimSpace(nudge.TTL)
+	nudge.Milestone = strings.TrimSpace(nudge.Milestone)
+	nudge.CreatedAt = time.Now()
+
 	s.nudgeFile.Nudges = append(s.nudgeFile.Nudges, nudge)
 
 	if err := s.saveUnsafe(); err != nil {
@@ -202,7 +323,8 @@ func (s *Store) Add(nudgeType NudgeType, content string, priority int) (*Nudge,
 	return &nudge, nil
 }
 
-// GetActive returns all non-acknowledged nudges sorted by priority (highest first)
+// GetActive returns all non-acknowledged, unexpired nudges sorted by priority
+// (highest first)
 func (s *Store) GetActive() []Nudge {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -211,9 +333,10 @@ func (s *Store) GetActive() []Nudge {
 		return []Nudge{}
 	}
 
+	now := time.Now()
 	var active []Nudge
 	for _, n := range s.nudgeFile.Nudges {
-		if !n.Acknowledged {
+		if !n.Acknowledged && !n.Expired(now) {
 			active = append(active, n)
 		}
 	}
@@ -229,6 +352,29 @@ func (s *Store) GetActive() []Nudge {
 	return active
 }
 
+// GetActiveFor returns the non-acknowledged nudges that apply while the
+// given feature is worked on, sorted like GetActive
+func (s *Store) GetActiveFor(feature *plan.Plan) []Nudge {
+	var result []Nudge
+	for _, n := range s.GetActive() {
+		if n.AppliesTo(feature) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// GetActiveByType returns the non-acknowledged nudges of the given type
+func (s *Store) GetActiveByType(nudgeType NudgeType) []Nudge {
+	var result []Nudge
+	for _, n := range s.GetActive() {
+		if n.Type == nudgeType {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
 // GetAll returns all nudges
 func (s *Store) GetAll() []Nudge {
 	s.mu.RLock()
@@ -303,6 +449,91 @@ func (s *Store) AcknowledgeAll() error {
 	return nil
 }
 
+// AcknowledgeInjected records that nudges were injected into an iteration
+// and marks them as processed. Persistent nudges stay active: sticky ones
+// until cleared, scoped ones until their feature or milestone is complete
+// (see CompleteScoped) and expiring ones until their TTL runs out. Expired
+// nudges are marked as processed too. It returns the acknowledged nudges.
+func (s *Store) AcknowledgeInjected(injected []Nudge) ([]Nudge, error) {
+	ids := make(map[string]bool)
+	for _, n := range injected {
+		ids[n.ID] = true
+	}
+
+	s.mu.Lock()
+	if s.nudgeFile != nil {
+		for i := range s.nudgeFile.Nudges {
+			if ids[s.nudgeFile.Nudges[i].ID] {
+				s.nudgeFile.Nudges[i].Injections++
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	return s.acknowledgeWhere(func(n Nudge) bool {
+		return (ids[n.ID] && !n.Persistent()) || n.Expired(now)
+	}, len(ids) > 0)
+}
+
+// CompleteScoped marks the scoped nudges whose feature or milestone is
+// complete in plans as processed. A milestone is complete when all of its
+// features are tested. It returns the acknowledged nudges.
+func (s *Store) CompleteScoped(plans []plan.Plan) ([]Nudge, error) {
+	return s.acknowledgeWhere(func(n Nudge) bool {
+		if !n.Scoped() {
+			return false
+		}
+		if n.Feature > 0 {
+			if p := plan.GetByID(plans, n.Feature); p == nil || !p.Tested {
+				return false
+			}
+		}
+		if n.Milestone != "" {
+			found := false
+			for _, p := range plans {
+				if strings.EqualFold(p.Milestone, n.Milestone) {
+					if !p.Tested {
+						return false
+					}
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}, false)
+}
+
+// acknowledgeWhere marks the active nudges matching done as processed and
+// returns them. The store is saved if any nudge was acknowledged or changed
+// is set.
+func (s *Store) acknowledgeWhere(done func(Nudge) bool, changed bool) ([]Nudge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nudgeFile == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var acked []Nudge
+	for i, n := range s.nudgeFile.Nudges {
+		if !n.Acknowledged && done(n) {
+			s.nudgeFile.Nudges[i].Acknowledged = true
+			s.nudgeFile.Nudges[i].AckedAt = now
+			acked = append(acked, s.nudgeFile.Nudges[i])
+		}
+	}
+
+	if len(acked) == 0 && !changed {
+		return nil, nil
+	}
+	return acked, s.saveUnsafe()
+}
+
 // HasChanged checks if the nudge file has been modified since last load
 func (s *Store) HasChanged() bool {
 	s.mu.RLock()
@@ -347,9 +578,10 @@ func (s *Store) ActiveCount() int {
 		return 0
 	}
 
+	now := time.Now()
 	count := 0
 	for _, n := range s.nudgeFile.Nudges {
-		if !n.Acknowledged {
+		if !n.Acknowledged && !n.Expired(now) {
 			count++
 		}
 	}
@@ -358,7 +590,19 @@ func (s *Store) ActiveCount() int {
 
 // BuildPromptContext creates a formatted string of nudges to inject into agent prompts
 func (s *Store) BuildPromptContext() string {
-	active := s.GetActive()
+	return FormatPromptContext(s.GetActive())
+}
+
+// FormatPromptContext formats the given nudges for injection into agent
+// prompts (e.g., the result of GetActiveFor)
+func FormatPromptContext(nudges []Nudge) string {
+	// Checkpoint nudges are instructions for Ralph, not guidance for the agent
+	var active []Nudge
+	for _, n := range nudges {
+		if n.Type != NudgeTypeCheckpoint {
+			active = append(active, n)
+		}
+	}
 	if len(active) == 0 {
 		return ""
 	}
@@ -393,6 +637,9 @@ func (s *Store) BuildPromptContext() string {
 			if n.Priority > 0 {
 				priorityStr = fmt.Sprintf(" (priority: %d)", n.Priority)
 			}
+			if n.Scoped() {
+				priorityStr += fmt.Sprintf(" (%s)", n.Target())
+			}
 			b.WriteString(fmt.Sprintf("- [%s%s] %s\n", label, priorityStr, n.Content))
 		}
 	}
@@ -402,6 +649,27 @@ func (s *Store) BuildPromptContext() string {
 	return b.String()
 }
 
+// List returns the active nudges with their remaining lifetime
+func (s *Store) List() string {
+	active := s.GetActive()
+	if len(active) == 0 {
+		return "No active nudges"
+	}
+
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Active nudges: %d (%s)\n\n", len(active), s.path))
+	for _, n := range active {
+		priorityStr := ""
+		if n.Priority > 0 {
+			priorityStr = fmt.Sprintf(" (p%d)", n.Priority)
+		}
+		b.WriteString(fmt.Sprintf("  [%s]%s %s\n", strings.ToUpper(string(n.Type)), priorityStr, n.Content))
+		b.WriteString(fmt.Sprintf("      %s - %s\n", n.ID, n.Lifetime(now)))
+	}
+	return b.String()
+}
+
 // Summary returns a formatted summary of all nudges
 func (s *Store) Summary() string {
 	s.mu.RLock()
@@ -412,10 +680,11 @@ func (s *Store) Summary() string {
 	}
 
 	var b strings.Builder
+	now := time.Now()
 	active := 0
 	acknowledged := 0
 	for _, n := range s.nudgeFile.Nudges {
-		if n.Acknowledged {
+		if n.Acknowledged || n.Expired(now) {
 			acknowledged++
 		} else {
 			active++
@@ -433,7 +702,7 @@ func (s *Store) Summary() string {
 		typeGroups[n.Type] = append(typeGroups[n.Type], n)
 	}
 
-	typeOrder := []NudgeType{NudgeTypeFocus, NudgeTypeSkip, NudgeTypeConstraint, NudgeTypeStyle}
+	typeOrder := []NudgeType{NudgeTypeFocus, NudgeTypeSkip, NudgeTypeConstraint, NudgeTypeStyle, NudgeTypeCheckpoint}
 	for _, t := range typeOrder {
 		nudges := typeGroups[t]
 		if len(nudges) == 0 {
@@ -443,13 +712,22 @@ func (s *Store) Summary() string {
 		b.WriteString(fmt.Sprintf("=== %s ===\n", strings.ToUpper(string(t))))
 		for _, n := range nudges {
 			status := "[ ]"
-			if n.Acknowledged {
+			if n.Acknowledged || n.Expired(now) {
 				status = "[x]"
 			}
 			priorityStr := ""
 			if n.Priority > 0 {
 				priorityStr = fmt.Sprintf(" (p%d)", n.Priority)
 			}
+			if n.Scoped() {
+				priorityStr += fmt.Sprintf(" [%s]", n.Target())
+			}
+			if n.Sticky {
+				priorityStr += " [sticky]"
+			}
+			if n.TTL != "" {
+				priorityStr += fmt.Sprintf(" [ttl %s]", n.TTL)
+			}
 			b.WriteString(fmt.Sprintf("  %s%s %s\n", status, priorityStr, n.Content))
 		}
 		b.WriteString("\n")
@@ -469,14 +747,61 @@ func ParseNudgeType(s string) (NudgeType, error) {
 		return NudgeTypeConstraint, nil
 	case "style":
 		return NudgeTypeStyle, nil
+	case "checkpoint":
+		return NudgeTypeCheckpoint, nil
 	default:
-		return "", fmt.Errorf("invalid nudge type: %s (must be focus, skip, constraint, or style)", s)
+		return "", fmt.Errorf("invalid nudge type: %s (must be focus, skip, constraint, style, or checkpoint)", s)
+	}
+}
+
+// Parse parses a nudge in the -nudge format: "type:content", optionally
+// scoped by a "feature:<id>:" or "milestone:<name>:" prefix (e.g.,
+// "feature:5:constraint:Keep the public API"). A scoped nudge only applies
+// while its feature or milestone is worked on.
+func Parse(s string) (Nudge, error) {
+	var n Nudge
+	for {
+		parts := strings.SplitN(s, ":", 3)
+		if len(parts) < 3 {
+			break
+		}
+		scope, value := strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		if scope == "feature" {
+			id, err := strconv.Atoi(value)
+			if err != nil || id <= 0 {
+				return Nudge{}, fmt.Errorf("invalid nudge feature %q: expected a feature ID", value)
+			}
+			n.Feature = id
+		} else if scope == "milestone" {
+			if value == "" {
+				return Nudge{}, fmt.Errorf("invalid nudge format: empty milestone name")
+			}
+			n.Milestone = value
+		} else {
+			break
+		}
+		s = parts[2]
+	}
+
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return Nudge{}, fmt.Errorf("invalid nudge format: expected '[feature:<id>:|milestone:<name>:]type:content' (e.g., 'focus:Work on feature 5')")
+	}
+	nudgeType, err := ParseNudgeType(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Nudge{}, err
+	}
+	if nudgeType == NudgeTypeCheckpoint && n.Scoped() {
+		return Nudge{}, fmt.Errorf("checkpoint nudges cannot be scoped to a feature or milestone")
 	}
+	n.Type = nudgeType
+	n.Content = strings.TrimSpace(parts[1])
+	return n, nil
 }
 
 // ValidNudgeTypes returns all valid nudge type strings
 func ValidNudgeTypes() []string {
-	return []string{"focus", "skip", "constraint", "style"}
+	return []string{"focus", "skip", "constraint", "style", "checkpoint"}
 }
 
 // generateID creates a unique ID for a nudge
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 func TestNewStore(t *testing.T) {
@@ -412,6 +415,25 @@ func TestBuildPromptContext(t *testing.T) {
 	}
 }
 
+func TestBuildPromptContextExcludesCheckpoints(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "nudges.json"))
+	store.Load()
+
+	store.Add(NudgeTypeCheckpoint, "before refactor", 0)
+	if ctx := store.BuildPromptContext(); ctx != "" {
+		t.Errorf("checkpoint nudges should not produce prompt context, got %q", ctx)
+	}
+
+	store.Add(NudgeTypeFocus, "Work on feature 5", 0)
+	ctx := store.BuildPromptContext()
+	if contains(ctx, "before refactor") {
+		t.Error("checkpoint nudge content should not be injected into prompts")
+	}
+	if len(store.GetActiveByType(NudgeTypeCheckpoint)) != 1 {
+		t.Error("expected one active checkpoint nudge")
+	}
+}
+
 func TestBuildPromptContextWithAcknowledged(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "nudge_test")
 	if err != nil {
@@ -505,7 +527,7 @@ func TestParseNudgeType(t *testing.T) {
 
 func TestValidNudgeTypes(t *testing.T) {
 	types := ValidNudgeTypes()
-	expected := []string{"focus", "skip", "constraint", "style"}
+	expected := []string{"focus", "skip", "constraint", "style", "checkpoint"}
 
 	if len(types) != len(expected) {
 		t.Errorf("Expected %d types, got %d", len(expected), len(types))
@@ -621,3 +643,173 @@ func containsSubstr(s, substr string) bool {
 	}
 	return false
 }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Nudge
+		wantErr bool
+	}{
+		{in: "focus:Work on feature 5", want: Nudge{Type: NudgeTypeFocus, Content: "Work on feature 5"}},
+		{in: "style:Use the format a:b", want: Nudge{Type: NudgeTypeStyle, Content: "Use the format a:b"}},
+		{in: "feature:5:constraint:Keep the public API", want: Nudge{Type: NudgeTypeConstraint, Content: "Keep the public API", Feature: 5}},
+		{in: "milestone:Alpha:focus:Ship the minimum", want: Nudge{Type: NudgeTypeFocus, Content: "Ship the minimum", Milestone: "Alpha"}},
+		{in: "milestone:Beta:feature:7:skip:Defer export", want: Nudge{Type: NudgeTypeSkip, Content: "Defer export", Feature: 7, Milestone: "Beta"}},
+		{in: "feature:abc:focus:x", wantErr: true},
+		{in: "feature:5:checkpoint:before", wantErr: true},
+		{in: "invalid:content", wantErr: true},
+		{in: "focus", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopedNudges(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "nudges.json"))
+	store.Load()
+	store.Add(NudgeTypeStyle, "Use early returns", 0)
+	store.Insert(Nudge{Type: NudgeTypeConstraint, Content: "Keep the public API", Feature: 2})
+	store.Insert(Nudge{Type: NudgeTypeFocus, Content: "Ship the minimum", Milestone: "alpha"})
+
+	plans := []plan.Plan{
+		{ID: 1, Milestone: "Alpha"},
+		{ID: 2, Milestone: "Alpha"},
+		{ID: 3},
+	}
+
+	if got := store.GetActiveFor(&plans[2]); len(got) != 1 || got[0].Scoped() {
+		t.Errorf("GetActiveFor(feature 3) = %+v, want only the unscoped nudge", got)
+	}
+	if got := store.GetActiveFor(nil); len(got) != 1 {
+		t.Errorf("GetActiveFor(nil) = %+v, want only the unscoped nudge", got)
+	}
+	injected := store.GetActiveFor(&plans[1])
+	if len(injected) != 3 {
+		t.Fatalf("GetActiveFor(feature 2) = %+v, want all 3 nudges", injected)
+	}
+	ctx := FormatPromptContext(injected)
+	if !strings.Contains(ctx, "- [CONSTRAINT (feature #2)] Keep the public API") || !strings.Contains(ctx, "(milestone alpha)") {
+		t.Errorf("FormatPromptContext() = %q", ctx)
+	}
+
+	// Only the unscoped nudge is acknowledged after the iteration
+	acked, err := store.AcknowledgeInjected(injected)
+	if err != nil || len(acked) != 1 || acked[0].Content != "Use early returns" {
+		t.Fatalf("AcknowledgeInjected() = %+v, %v", acked, err)
+	}
+	if store.ActiveCount() != 2 {
+		t.Errorf("ActiveCount() = %d, want 2", store.ActiveCount())
+	}
+
+	// Scoped nudges end when their feature or milestone is complete
+	plans[1].Tested = true
+	acked, _ = store.CompleteScoped(plans)
+	if len(acked) != 1 || acked[0].Feature != 2 {
+		t.Errorf("CompleteScoped() = %+v, want the feature 2 nudge", acked)
+	}
+	plans[0].Tested = true
+	acked, _ = store.CompleteScoped(plans)
+	if len(acked) != 1 || acked[0].Milestone != "alpha" {
+		t.Errorf("CompleteScoped() = %+v, want the milestone nudge", acked)
+	}
+	if store.ActiveCount() != 0 {
+		t.Errorf("ActiveCount() = %d, want 0", store.ActiveCount())
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := []struct {
+		in         string
+		duration   time.Duration
+		iterations int
+		wantErr    bool
+	}{
+		{in: ""},
+		{in: "3", iterations: 3},
+		{in: "90m", duration: 90 * time.Minute},
+		{in: "0", wantErr: true},
+		{in: "-1h", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		duration, iterations, err := ParseTTL(tt.in)
+		if (err != nil) != tt.wantErr || duration != tt.duration || iterations != tt.iterations {
+			t.Errorf("ParseTTL(%q) = %v, %d, %v", tt.in, duration, iterations, err)
+		}
+	}
+}
+
+func TestStickyAndExpiringNudges(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "nudges.json"))
+	store.Load()
+	store.Add(NudgeTypeFocus, "Fix the login bug", 0)
+	store.Insert(Nudge{Type: NudgeTypeConstraint, Content: "No new dependencies", Sticky: true})
+	store.Insert(Nudge{Type: NudgeTypeStyle, Content: "Use early returns", TTL: "2"})
+	old, _ := store.Insert(Nudge{Type: NudgeTypeSkip, Content: "Skip the docs", TTL: "1h"})
+	if _, err := store.Insert(Nudge{Type: NudgeTypeFocus, Content: "x", TTL: "soon"}); err == nil {
+		t.Error("Insert() accepted an invalid TTL")
+	}
+
+	// Backdate the nudge with a 1h TTL so it has expired
+	store.nudgeFile.Nudges[3].CreatedAt = time.Now().Add(-2 * time.Hour)
+	if got := store.GetActive(); len(got) != 3 {
+		t.Fatalf("GetActive() = %+v, want 3 unexpired nudges", got)
+	}
+
+	acked, err := store.AcknowledgeInjected(store.GetActive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acked) != 2 || acked[0].Content != "Fix the login bug" || acked[1].ID != old.ID {
+		t.Errorf("AcknowledgeInjected() = %+v, want the one-time and the expired nudge", acked)
+	}
+	list := store.List()
+	for _, want := range []string{"sticky, until cleared", "1 of 2 iteration(s) left"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("List() missing %q:\n%s", want, list)
+		}
+	}
+
+	// The iteration TTL runs out after the second injection
+	store.AcknowledgeInjected(store.GetActive())
+	active := store.GetActive()
+	if len(active) != 1 || !active[0].Sticky {
+		t.Errorf("GetActive() = %+v, want only the sticky nudge", active)
+	}
+
+	// Injection counts are saved
+	reloaded := NewStore(store.Path())
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if n := reloaded.GetAll()[1]; n.Injections != 2 || n.Acknowledged {
+		t.Errorf("sticky nudge after reload = %+v", n)
+	}
+}
+
+func TestNudgeLifetime(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		nudge Nudge
+		want  string
+	}{
+		{Nudge{}, "next iteration only"},
+		{Nudge{Sticky: true}, "sticky, until cleared"},
+		{Nudge{TTL: "30m", CreatedAt: now.Add(-10 * time.Minute)}, "expires in 20m0s"},
+		{Nudge{Feature: 4, TTL: "3", Injections: 1}, "until feature #4 is complete, 2 of 3 iteration(s) left"},
+	}
+	for _, tt := range tests {
+		if got := tt.nudge.Lifetime(now); got != tt.want {
+			t.Errorf("Lifetime(%+v) = %q, want %q", tt.nudge, got, tt.want)
+		}
+	}
+}
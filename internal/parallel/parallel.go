@@ -0,0 +1,285 @@
+// Package parallel works on independent features of a plan at the same time.
+//
+// Each feature gets its own git worktree and its own Ralph process (a worker),
+// which sees a copy of the plan in which every other feature is deferred. Once
+// all workers have exited, the changes of the features they completed are
+// applied to the main checkout one at a time and the features are marked
+// tested, so only the coordinating process ever writes the main plan.
+package parallel
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/logimos/ralph/internal/filelock"
+	"github.com/logimos/ralph/internal/worktree"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// DeferReason is the deferral reason of the features a worker must leave alone
+const DeferReason = "parallel: assigned to another worker"
+
+// Options controls a parallel run
+type Options struct {
+	Dir         string   // Working directory of the run, inside a git checkout
+	PlanFile    string   // Plan file, relative to Dir
+	AppendFiles []string // Files the workers append to (progress logs), relative to Dir
+	StateFiles  []string // Other files copied into each worktree but never merged, relative to Dir
+	LogDir      string   // Directory for the output and progress of each worker
+	Command     []string // Ralph command each worker runs in its worktree
+	WorktreeDir string   // Parent directory of the worktrees (default: the system temp dir)
+}
+
+// Result is the outcome of one feature
+type Result struct {
+	Feature  plan.Plan
+	Tested   bool     // The worker completed the feature and its changes were applied
+	Files    []string // Files changed by the applied changes
+	LogFile  string   // Output of the worker
+	Worktree string   // Worktree kept for inspection when the feature was not completed
+	Err      error    // Why the feature was not completed
+}
+
+// worker is the state of one feature's worker
+type worker struct {
+	wt     *worktree.Worktree
+	dir    string         // Working directory of the worker in its worktree
+	before map[string]int // Size of each append file when the worker started
+	result Result
+}
+
+// Select returns up to n features that can be worked on at the same time:
+// actionable features that are not blocked by prerequisite milestones and are
+// not in skip
+func Select(plans []plan.Plan, blocked map[int][]string, skip map[int]bool, n int) []plan.Plan {
+	var selected []plan.Plan
+	for _, p := range plans {
+		if len(selected) == n {
+			break
+		}
+		if p.IsActionable() && len(blocked[p.ID]) == 0 && !skip[p.ID] {
+			selected = append(selected, p)
+		}
+	}
+	return selected
+}
+
+// Isolate returns a copy of plans in which every actionable feature except
+// featureID is deferred
+func Isolate(plans []plan.Plan, featureID int) []plan.Plan {
+	isolated := make([]plan.Plan, len(plans))
+	copy(isolated, plans)
+	for i := range isolated {
+		if isolated[i].ID != featureID && isolated[i].IsActionable() {
+			isolated[i].Deferred = true
+			isolated[i].DeferReason = DeferReason
+		}
+	}
+	return isolated
+}
+
+// Run works on features concurrently, one worker each, then applies the
+// changes of the features the workers completed to the main checkout in the
+// order of features and marks them tested in the main plan. The worktrees of
+// features that were not completed are kept for inspection.
+func Run(features []plan.Plan, opts Options) ([]Result, error) {
+	if len(opts.Command) == 0 {
+		return nil, fmt.Errorf("no worker command")
+	}
+	dir, err := filepath.EvalSymlinks(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", opts.Dir, err)
+	}
+	root, err := worktree.RepoRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("parallel features require a git repository: %w", err)
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate %s in %s: %w", dir, root, err)
+	}
+	planPath := filepath.Join(dir, opts.PlanFile)
+	plans, err := plan.ReadFile(planPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.LogDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create worker log directory: %w", err)
+	}
+
+	var stateFiles []string
+	for _, f := range append(append([]string{opts.PlanFile}, opts.AppendFiles...), opts.StateFiles...) {
+		if f != "" {
+			stateFiles = append(stateFiles, filepath.Join(rel, f))
+		}
+	}
+
+	// Worktrees are created one at a time, as creating one reads the main
+	// checkout's index
+	workers := make([]*worker, 0, len(features))
+	for _, f := range features {
+		w, err := newWorker(root, dir, f, Isolate(plans, f.ID), stateFiles, opts)
+		if err != nil {
+			for _, w := range workers {
+				w.wt.Remove()
+			}
+			return nil, err
+		}
+		workers = append(workers, w)
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.run(opts)
+		}(w)
+	}
+	wg.Wait()
+
+	results := make([]Result, len(workers))
+	for i, w := range workers {
+		w.collect(dir, opts)
+		w.merge(planPath, opts)
+		results[i] = w.result
+	}
+	return results, nil
+}
+
+// newWorker creates the worktree of a feature and writes its isolated plan
+func newWorker(root, dir string, feature plan.Plan, isolated []plan.Plan, stateFiles []string, opts Options) (*worker, error) {
+	wt, err := worktree.Create(root, worktree.Options{StateFiles: stateFiles, Exclude: []string{".ralph"}, Dir: opts.WorktreeDir})
+	if err != nil {
+		return nil, err
+	}
+	w := &worker{
+		wt:     wt,
+		dir:    wt.Translate(dir),
+		before: make(map[string]int),
+		result: Result{Feature: feature, LogFile: filepath.Join(opts.LogDir, fmt.Sprintf("feature-%d.log", feature.ID))},
+	}
+	if err := plan.WriteFile(filepath.Join(w.dir, opts.PlanFile), isolated); err != nil {
+		wt.Remove()
+		return nil, err
+	}
+	for _, f := range opts.AppendFiles {
+		if info, err := os.Stat(filepath.Join(w.dir, f)); err == nil {
+			w.before[f] = int(info.Size())
+		}
+	}
+	return w, nil
+}
+
+// run runs the worker's Ralph process, writing its output to the log file
+func (w *worker) run(opts Options) {
+	log, err := os.Create(w.result.LogFile)
+	if err != nil {
+		w.result.Err = fmt.Errorf("failed to create worker log: %w", err)
+		return
+	}
+	defer log.Close()
+
+	cmd := exec.Command(opts.Command[0], opts.Command[1:]...)
+	cmd.Dir = w.dir
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		w.result.Err = fmt.Errorf("worker failed: %w", err)
+	}
+}
+
+// collect appends what the worker added to the append files to the main
+// checkout's files, and keeps a copy per feature in the log directory
+func (w *worker) collect(dir string, opts Options) {
+	for _, f := range opts.AppendFiles {
+		data, err := os.ReadFile(filepath.Join(w.dir, f))
+		if err != nil || len(data) <= w.before[f] {
+			continue
+		}
+		added := data[w.before[f]:]
+		appendFile(filepath.Join(dir, f), added)
+		os.WriteFile(filepath.Join(opts.LogDir, fmt.Sprintf("feature-%d-%s", w.result.Feature.ID, filepath.Base(f))), added, 0644)
+	}
+}
+
+// merge applies the worker's changes to the main checkout and marks its
+// feature tested, if the worker completed the feature. The worktree is
+// removed once merged and kept otherwise.
+func (w *worker) merge(planPath string, opts Options) {
+	keep := func(err error) {
+		if err != nil {
+			w.result.Err = err
+		}
+		w.result.Worktree = w.wt.Path
+	}
+
+	plans, err := plan.ReadFile(filepath.Join(w.dir, opts.PlanFile))
+	if err != nil {
+		keep(err)
+		return
+	}
+	if !isTested(plans, w.result.Feature.ID) {
+		if w.result.Err == nil {
+			w.result.Err = fmt.Errorf("worker did not complete the feature")
+		}
+		keep(nil)
+		return
+	}
+
+	if _, err := w.wt.Seal(); err != nil {
+		keep(err)
+		return
+	}
+	files, err := w.wt.ApplyChanges()
+	if err != nil {
+		keep(err)
+		return
+	}
+	if err := markTested(planPath, w.result.Feature.ID); err != nil {
+		keep(fmt.Errorf("changes applied, but the feature could not be marked tested: %w", err))
+		return
+	}
+	w.result.Tested = true
+	w.result.Files = files
+	w.result.Err = nil
+	w.wt.Remove()
+}
+
+// isTested reports whether the feature is tested in plans
+func isTested(plans []plan.Plan, featureID int) bool {
+	for _, p := range plans {
+		if p.ID == featureID {
+			return p.Tested
+		}
+	}
+	return false
+}
+
+// markTested marks a feature tested in the plan file
+func markTested(planPath string, featureID int) error {
+	return plan.Update(planPath, func(plans []plan.Plan) ([]plan.Plan, error) {
+		for i := range plans {
+			if plans[i].ID == featureID {
+				plans[i].Tested = true
+			}
+		}
+		return plans, nil
+	})
+}
+
+// appendFile appends data to a file shared with other Ralph processes
+func appendFile(path string, data []byte) error {
+	return filelock.With(path, func() error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,127 @@
+package parallel
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// TestMain lets the test binary act as a worker: it completes the only
+// actionable feature of the plan in its working directory, except feature 2
+func TestMain(m *testing.M) {
+	if os.Getenv("RALPH_TEST_WORKER") != "1" {
+		os.Exit(m.Run())
+	}
+	plans, err := plan.ReadFile("plan.json")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for i, p := range plans {
+		if !p.IsActionable() {
+			continue
+		}
+		f, _ := os.OpenFile("progress.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		fmt.Fprintf(f, "worked on feature %d\n", p.ID)
+		f.Close()
+		if p.ID == 2 {
+			os.Exit(1)
+		}
+		os.WriteFile(fmt.Sprintf("feature%d.go", p.ID), []byte("package main\n"), 0644)
+		plans[i].Tested = true
+	}
+	if err := plan.WriteFile("plan.json", plans); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestSelectAndIsolate(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Tested: true},
+		{ID: 2},
+		{ID: 3},
+		{ID: 4},
+		{ID: 5},
+	}
+	blocked := map[int][]string{3: {"Alpha"}}
+	selected := Select(plans, blocked, map[int]bool{4: true}, 2)
+	if len(selected) != 2 || selected[0].ID != 2 || selected[1].ID != 5 {
+		t.Errorf("Select() = %+v, want features 2 and 5", selected)
+	}
+
+	isolated := Isolate(plans, 2)
+	for _, p := range isolated {
+		if want := p.ID > 2; p.Deferred != want {
+			t.Errorf("feature %d deferred = %v, want %v", p.ID, p.Deferred, want)
+		}
+	}
+	if plans[2].Deferred {
+		t.Error("Isolate() modified the plans")
+	}
+}
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	for _, args := range [][]string{{"init", "-q"}, {"add", "."}, {"commit", "-q", "-m", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	plans := []plan.Plan{{ID: 1, Description: "one"}, {ID: 2, Description: "two"}, {ID: 3, Description: "three"}}
+	if err := plan.WriteFile(filepath.Join(dir, "plan.json"), plans); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("RALPH_TEST_WORKER", "1")
+	results, err := Run(plans, Options{
+		Dir:         dir,
+		PlanFile:    "plan.json",
+		AppendFiles: []string{"progress.txt"},
+		LogDir:      filepath.Join(t.TempDir(), "logs"),
+		Command:     []string{os.Args[0]},
+		WorktreeDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !results[0].Tested || results[1].Tested || !results[2].Tested {
+		t.Fatalf("Run() = %+v, want features 1 and 3 completed", results)
+	}
+	if results[1].Err == nil || results[1].Worktree == "" {
+		t.Errorf("failed feature = %+v, want its error and kept worktree", results[1])
+	}
+	for _, f := range []string{"feature1.go", "feature3.go"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("%s not merged", f)
+		}
+	}
+
+	merged, _ := plan.ReadFile(filepath.Join(dir, "plan.json"))
+	for _, p := range merged {
+		if p.Tested != (p.ID != 2) || p.Deferred {
+			t.Errorf("feature %d: tested %v, deferred %v", p.ID, p.Tested, p.Deferred)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "progress.txt"))
+	for _, want := range []string{"worked on feature 1", "worked on feature 2", "worked on feature 3"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("progress = %q, want %q", data, want)
+		}
+	}
+}
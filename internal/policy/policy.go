@@ -0,0 +1,294 @@
+// Package policy loads and enforces the autonomous behavior limits defined in
+// ralph-policy.yaml: maximum cost per run, allowed commands, protected paths,
+// plan categories that require human review and forbidden dependencies.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the policy file names searched for, in order of precedence
+var FileNames = []string{
+	"ralph-policy.yaml",
+	"ralph-policy.yml",
+	"ralph-policy.json",
+}
+
+// manifestFiles are the dependency manifests checked for forbidden dependencies
+var manifestFiles = []string{
+	"go.mod",
+	"package.json",
+	"requirements.txt",
+	"requirements-dev.txt",
+	"Pipfile",
+	"pyproject.toml",
+	"Cargo.toml",
+	"Gemfile",
+	"build.gradle",
+	"build.gradle.kts",
+	"pom.xml",
+	"composer.json",
+}
+
+// Policy bounds what Ralph and the agent may do. A nil *Policy allows everything.
+type Policy struct {
+	MaxCostPerRun           float64  `json:"max_cost_per_run,omitempty" yaml:"max_cost_per_run,omitempty"`                   // Stop the run once the estimated cost (USD) reaches this
+	AllowedCommands         []string `json:"allowed_commands,omitempty" yaml:"allowed_commands,omitempty"`                   // Executables Ralph may run (glob patterns); empty = any
+	ProtectedPaths          []string `json:"protected_paths,omitempty" yaml:"protected_paths,omitempty"`                     // Paths the agent must not change (glob patterns or dir/)
+	RequireReviewCategories []string `json:"require_review_categories,omitempty" yaml:"require_review_categories,omitempty"` // Plan categories whose iterations need human approval
+	ForbiddenDependencies   []string `json:"forbidden_dependencies,omitempty" yaml:"forbidden_dependencies,omitempty"`       // Dependencies that must not be added
+
+	// Path is the file the policy was loaded from
+	Path string `json:"-" yaml:"-"`
+}
+
+// Violation describes a change or action that breaks the policy
+type Violation struct {
+	Rule   string // Policy rule that was broken (e.g., "protected_paths")
+	Detail string // What broke it
+}
+
+// String returns a human-readable description of the violation
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Detail)
+}
+
+// Discover returns the path of the policy file in dir, or empty string if there is none
+func Discover(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads and validates a policy file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	p := &Policy{}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, p)
+	} else {
+		err = yaml.Unmarshal(data, p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	p.Path = path
+	return p, nil
+}
+
+// Validate checks that the policy is well-formed
+func (p *Policy) Validate() error {
+	if p.MaxCostPerRun < 0 {
+		return fmt.Errorf("max_cost_per_run cannot be negative")
+	}
+	for _, list := range [][]string{p.AllowedCommands, p.ProtectedPaths} {
+		for _, pattern := range list {
+			if _, err := filepath.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Summary returns a one-line description of the active limits
+func (p *Policy) Summary() string {
+	if p == nil {
+		return "none"
+	}
+	var parts []string
+	if p.MaxCostPerRun > 0 {
+		parts = append(parts, fmt.Sprintf("max cost $%.2f", p.MaxCostPerRun))
+	}
+	if len(p.AllowedCommands) > 0 {
+		parts = append(parts, fmt.Sprintf("%d allowed command(s)", len(p.AllowedCommands)))
+	}
+	if len(p.ProtectedPaths) > 0 {
+		parts = append(parts, fmt.Sprintf("%d protected path(s)", len(p.ProtectedPaths)))
+	}
+	if len(p.RequireReviewCategories) > 0 {
+		parts = append(parts, "review required for "+strings.Join(p.RequireReviewCategories, ", "))
+	}
+	if len(p.ForbiddenDependencies) > 0 {
+		parts = append(parts, fmt.Sprintf("%d forbidden dependenc(ies)", len(p.ForbiddenDependencies)))
+	}
+	if len(parts) == 0 {
+		return "no limits"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CheckCommand returns an error if the command's executable is not allowed.
+// The executable is matched by full path and by base name.
+func (p *Policy) CheckCommand(command string) error {
+	if p == nil || len(p.AllowedCommands) == 0 {
+		return nil
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	exe := fields[0]
+	for _, pattern := range p.AllowedCommands {
+		if ok, _ := filepath.Match(pattern, exe); ok {
+			return nil
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(exe)); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("command %q is not allowed by policy (allowed_commands: %s)", exe, strings.Join(p.AllowedCommands, ", "))
+}
+
+// CheckCost returns an error once the estimated cost reaches the per-run maximum
+func (p *Policy) CheckCost(cost float64) error {
+	if p == nil || p.MaxCostPerRun <= 0 || cost < p.MaxCostPerRun {
+		return nil
+	}
+	return fmt.Errorf("estimated cost $%.4f reached the policy maximum of $%.2f per run", cost, p.MaxCostPerRun)
+}
+
+// ChecksChanges reports whether the policy restricts the changes an iteration may make
+func (p *Policy) ChecksChanges() bool {
+	return p != nil && (len(p.ProtectedPaths) > 0 || len(p.ForbiddenDependencies) > 0)
+}
+
+// RequiresReview reports whether features in the given plan category need human approval
+func (p *Policy) RequiresReview(category string) bool {
+	if p == nil || category == "" {
+		return false
+	}
+	for _, c := range p.RequireReviewCategories {
+		if strings.EqualFold(c, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtected reports whether the repository-relative path is protected.
+// The policy file itself is always protected.
+func (p *Policy) IsProtected(path string) bool {
+	if p == nil {
+		return false
+	}
+	path = filepath.ToSlash(path)
+	if p.Path != "" && filepath.Base(path) == filepath.Base(p.Path) {
+		return true
+	}
+	for _, pattern := range p.ProtectedPaths {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckChanges checks a set of changed files and their unified diff against
+// the protected paths and forbidden dependencies
+func (p *Policy) CheckChanges(files []string, diff string) []Violation {
+	if p == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for _, f := range files {
+		if p.IsProtected(f) {
+			violations = append(violations, Violation{Rule: "protected_paths", Detail: f})
+		}
+	}
+	for _, dep := range p.AddedForbiddenDependencies(diff) {
+		violations = append(violations, Violation{Rule: "forbidden_dependencies", Detail: dep})
+	}
+	return violations
+}
+
+// AddedForbiddenDependencies returns the forbidden dependencies that appear in
+// lines added to dependency manifests in the given unified diff
+func (p *Policy) AddedForbiddenDependencies(diff string) []string {
+	if p == nil || len(p.ForbiddenDependencies) == 0 {
+		return nil
+	}
+
+	patterns := make(map[string]*regexp.Regexp, len(p.ForbiddenDependencies))
+	for _, dep := range p.ForbiddenDependencies {
+		patterns[dep] = regexp.MustCompile(`(^|[^A-Za-z0-9_.@-])` + regexp.QuoteMeta(dep) + `($|[^A-Za-z0-9_-])`)
+	}
+
+	var found []string
+	seen := make(map[string]bool)
+	inManifest := false
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			inManifest = isManifest(strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/"))
+			continue
+		}
+		if !inManifest || !strings.HasPrefix(line, "+") {
+			continue
+		}
+		for _, dep := range p.ForbiddenDependencies {
+			if !seen[dep] && patterns[dep].MatchString(line[1:]) {
+				seen[dep] = true
+				found = append(found, dep)
+			}
+		}
+	}
+	return found
+}
+
+// FormatViolations returns a multi-line description of the violations
+func FormatViolations(violations []Violation) string {
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = "  - " + v.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isManifest reports whether the path is a dependency manifest
+func isManifest(path string) bool {
+	base := filepath.Base(path)
+	for _, name := range manifestFiles {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath matches a repository-relative path against a protected path
+// pattern. Patterns ending in "/" or "/**" match everything below that
+// directory; patterns without a slash match the base name anywhere.
+func matchPath(pattern, path string) bool {
+	pattern = filepath.ToSlash(pattern)
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		pattern = dir + "/"
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path+"/", pattern) || path+"/" == pattern
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := filepath.Match(pattern, filepath.Base(path))
+		return ok
+	}
+	ok, _ := filepath.Match(pattern, path)
+	return ok
+}
@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ralph-policy.yaml")
+	os.WriteFile(path, []byte(`
+max_cost_per_run: 5
+allowed_commands: [claude, go, "npm*"]
+protected_paths: [".github/", "*.pem", "deploy/prod/**"]
+require_review_categories: [security]
+forbidden_dependencies: [left-pad]
+`), 0644)
+
+	if got := Discover(dir); got != path {
+		t.Fatalf("Discover = %q, want %q", got, path)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p.MaxCostPerRun != 5 || len(p.AllowedCommands) != 3 || p.Path != path {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+	if !strings.Contains(p.Summary(), "review required for security") {
+		t.Errorf("unexpected summary %q", p.Summary())
+	}
+
+	os.WriteFile(path, []byte("max_cost_per_run: -1\n"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Error("expected negative max cost to be rejected")
+	}
+}
+
+func TestNilPolicyAllowsEverything(t *testing.T) {
+	var p *Policy
+	if err := p.CheckCommand("rm -rf /"); err != nil {
+		t.Errorf("CheckCommand: %v", err)
+	}
+	if err := p.CheckCost(1000); err != nil {
+		t.Errorf("CheckCost: %v", err)
+	}
+	if p.RequiresReview("security") || p.IsProtected("main.go") {
+		t.Error("nil policy should not require review or protect paths")
+	}
+	if v := p.CheckChanges([]string{"go.mod"}, "+++ b/go.mod\n+require evil v1.0.0"); v != nil {
+		t.Errorf("CheckChanges = %v", v)
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	p := &Policy{AllowedCommands: []string{"claude", "go", "npm*"}}
+	tests := map[string]bool{
+		"claude":                   true,
+		"/usr/local/bin/claude":    true,
+		"go test ./...":            true,
+		"npx jest":                 false,
+		"npm test":                 true,
+		"curl http://example.com":  false,
+		"sh -c 'curl example.com'": false,
+	}
+	for command, allowed := range tests {
+		if err := p.CheckCommand(command); (err == nil) != allowed {
+			t.Errorf("CheckCommand(%q) = %v, want allowed=%v", command, err, allowed)
+		}
+	}
+}
+
+func TestCheckCost(t *testing.T) {
+	p := &Policy{MaxCostPerRun: 2}
+	if err := p.CheckCost(1.99); err != nil {
+		t.Errorf("cost below the maximum should pass: %v", err)
+	}
+	if err := p.CheckCost(2); err == nil {
+		t.Error("cost at the maximum should fail")
+	}
+}
+
+func TestIsProtected(t *testing.T) {
+	p := &Policy{ProtectedPaths: []string{".github/", "*.pem", "deploy/prod/**", "config/secrets.yaml"}, Path: "/repo/ralph-policy.yaml"}
+	tests := map[string]bool{
+		".github/workflows/ci.yml":  true,
+		"certs/server.pem":          true,
+		"deploy/prod/app.yaml":      true,
+		"deploy/staging/app.yaml":   false,
+		"config/secrets.yaml":       true,
+		"other/config/secrets.yaml": false,
+		"ralph-policy.yaml":         true,
+		"main.go":                   false,
+	}
+	for path, want := range tests {
+		if got := p.IsProtected(path); got != want {
+			t.Errorf("IsProtected(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCheckChanges(t *testing.T) {
+	p := &Policy{
+		ProtectedPaths:        []string{".github/"},
+		ForbiddenDependencies: []string{"left-pad", "github.com/evil/pkg"},
+	}
+	diff := `diff --git a/package.json b/package.json
+--- a/package.json
++++ b/package.json
+@@ -1,3 +1,4 @@
+   "dependencies": {
++    "left-pad": "^1.3.0",
+-    "left-pad-old": "^1.0.0"
+diff --git a/go.mod b/go.mod
+--- a/go.mod
++++ b/go.mod
+@@ -1 +1,2 @@
++require github.com/evil/pkg/v2 v2.0.0
+diff --git a/README.md b/README.md
++++ b/README.md
++We no longer use left-pad.
+`
+	violations := p.CheckChanges([]string{".github/workflows/ci.yml", "go.mod", "package.json"}, diff)
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		"protected_paths: .github/workflows/ci.yml",
+		"forbidden_dependencies: left-pad",
+		"forbidden_dependencies: github.com/evil/pkg",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+
+	// Similar names are not flagged
+	if deps := p.AddedForbiddenDependencies("+++ b/package.json\n+  \"left-pad-ng\": \"1.0.0\""); len(deps) != 0 {
+		t.Errorf("unexpected forbidden dependencies %v", deps)
+	}
+}
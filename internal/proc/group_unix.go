@@ -0,0 +1,47 @@
+//go:build !windows
+
+package proc
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// SetGroup starts the command in its own process group and makes
+// cancellation kill the whole group, so that the processes it spawns
+// (shells, test runners, dev servers) do not outlive it. Call it before
+// Start.
+func SetGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// Start starts the command. Its process group is set by SetGroup.
+func Start(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+// Kill kills the started command, with its process group if it was started
+// with SetGroup
+func Kill(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr != nil && cmd.SysProcAttr.Setpgid {
+		return KillGroup(cmd.Process.Pid)
+	}
+	return cmd.Process.Kill()
+}
+
+// KillGroup kills every process left in the group led by pid
+func KillGroup(pid int) error {
+	err := syscall.Kill(-pid, syscall.SIGKILL)
+	if err == syscall.ESRCH {
+		// The group has already exited
+		return nil
+	}
+	return err
+}
+
+// Release forgets the group of pid without killing it. Process groups need
+// no cleanup, so it does nothing.
+func Release(pid int) {}
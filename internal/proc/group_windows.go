@@ -0,0 +1,148 @@
+//go:build windows
+
+package proc
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	jobsMu sync.Mutex
+	// jobs holds the job object of each command started with Start, by PID
+	jobs = make(map[int]windows.Handle)
+)
+
+// SetGroup starts the command in a new process group, so that it does not
+// receive the console's Ctrl+C, and makes cancellation kill its job: the
+// command and every process it started. Call it before Start.
+func SetGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP}
+	cmd.Cancel = func() error {
+		return Kill(cmd)
+	}
+}
+
+// Kill kills the started command with every process in its job, or only
+// the command if it has no job
+func Kill(cmd *exec.Cmd) error {
+	if job := takeJob(cmd.Process.Pid); job != 0 {
+		defer windows.CloseHandle(job)
+		return windows.TerminateJobObject(job, 1)
+	}
+	return cmd.Process.Kill()
+}
+
+// Start starts the command suspended, assigns it to a new job object and
+// then resumes it, so every process it starts joins the job and KillGroup
+// kills them with it. If the job cannot be set up, the command is killed
+// before it runs and the error is returned.
+func Start(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	job, err := assignJob(uint32(cmd.Process.Pid))
+	if err == nil {
+		if err = resume(uint32(cmd.Process.Pid)); err != nil {
+			windows.CloseHandle(job)
+		}
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("failed to set up job for %s: %w", cmd.Path, err)
+	}
+
+	jobsMu.Lock()
+	jobs[cmd.Process.Pid] = job
+	jobsMu.Unlock()
+	return nil
+}
+
+// assignJob creates a job object and assigns the process pid to it
+func assignJob(pid uint32) (windows.Handle, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create job object: %w", err)
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, pid)
+	if err != nil {
+		windows.CloseHandle(job)
+		return 0, fmt.Errorf("open process: %w", err)
+	}
+	defer windows.CloseHandle(process)
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return 0, fmt.Errorf("assign process to job: %w", err)
+	}
+	return job, nil
+}
+
+// resume resumes the threads of the suspended process pid. A process
+// created suspended has only its main thread.
+func resume(pid uint32) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return fmt.Errorf("snapshot threads: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	resumed := false
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != pid {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return fmt.Errorf("open thread: %w", err)
+		}
+		_, err = windows.ResumeThread(thread)
+		windows.CloseHandle(thread)
+		if err != nil {
+			return fmt.Errorf("resume thread: %w", err)
+		}
+		resumed = true
+	}
+	if !resumed {
+		return fmt.Errorf("no thread found for process %d", pid)
+	}
+	return nil
+}
+
+// KillGroup kills every process in the job of the command started with pid.
+// It does nothing if the command has no job.
+func KillGroup(pid int) error {
+	job := takeJob(pid)
+	if job == 0 {
+		return nil
+	}
+	defer windows.CloseHandle(job)
+	return windows.TerminateJobObject(job, 1)
+}
+
+// Release closes the job of the command started with pid without killing
+// the processes in it
+func Release(pid int) {
+	if job := takeJob(pid); job != 0 {
+		windows.CloseHandle(job)
+	}
+}
+
+// takeJob removes and returns the job of pid, or 0 if it has none
+func takeJob(pid int) windows.Handle {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	job := jobs[pid]
+	delete(jobs, pid)
+	return job
+}
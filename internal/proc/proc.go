@@ -0,0 +1,40 @@
+// Package proc starts the commands Ralph runs (agents, validations, checks)
+// so that they can be killed together with every process they start, on
+// Unix through process groups and on Windows through job objects, and runs
+// command lines the way the platform expects.
+package proc
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CommandLine returns the command running a command line such as a test or
+// check command. On Windows it runs through cmd /C, so that builtins and the
+// .cmd shims of tools like npm work; elsewhere the line is split into fields
+// and run directly. It returns nil for an empty line.
+func CommandLine(ctx context.Context, line string) *exec.Cmd {
+	return commandLine(ctx, runtime.GOOS, line)
+}
+
+// commandLine returns the command running line on the given OS
+func commandLine(ctx context.Context, goos, line string) *exec.Cmd {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if goos == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", line)
+	}
+	fields := strings.Fields(line)
+	return exec.CommandContext(ctx, fields[0], fields[1:]...)
+}
+
+// Run starts cmd with Start and waits for it to finish
+func Run(cmd *exec.Cmd) error {
+	if err := Start(cmd); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
@@ -0,0 +1,94 @@
+package proc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCommandLine(t *testing.T) {
+	ctx := context.Background()
+	if cmd := commandLine(ctx, "linux", "  "); cmd != nil {
+		t.Errorf("commandLine() of an empty line = %v, want nil", cmd.Args)
+	}
+	if cmd := commandLine(ctx, "linux", "go test  ./..."); !reflect.DeepEqual(cmd.Args, []string{"go", "test", "./..."}) {
+		t.Errorf("commandLine() on Linux = %v", cmd.Args)
+	}
+	if cmd := commandLine(ctx, "windows", "npm test"); !reflect.DeepEqual(cmd.Args, []string{"cmd", "/C", "npm test"}) {
+		t.Errorf("commandLine() on Windows = %v", cmd.Args)
+	}
+}
+
+// heartbeat starts a shell in its own group that leaves a background process
+// appending to a file, and returns the file and the shell's Wait
+func heartbeat(t *testing.T, ctx context.Context) (string, func() error) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	file := filepath.Join(t.TempDir(), "heartbeat")
+	cmd := exec.CommandContext(ctx, "sh", "-c", "(while true; do echo x >> "+file+"; sleep 0.05; done) & sleep 30")
+	SetGroup(cmd)
+	if err := Start(cmd); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { KillGroup(cmd.Process.Pid) })
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if info, err := os.Stat(file); err == nil && info.Size() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background process did not start")
+		}
+	}
+	return file, cmd.Wait
+}
+
+// stopped reports whether the file stops growing
+func stopped(file string) bool {
+	time.Sleep(200 * time.Millisecond)
+	before, _ := os.Stat(file)
+	time.Sleep(300 * time.Millisecond)
+	after, _ := os.Stat(file)
+	return after.Size() == before.Size()
+}
+
+func TestCancelKillsGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	file, wait := heartbeat(t, ctx)
+
+	cancel()
+	if err := wait(); err == nil {
+		t.Error("Wait() = nil, want the command to be killed")
+	}
+	if !stopped(file) {
+		t.Error("background process outlived the cancelled command")
+	}
+}
+
+func TestKill(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	cmd := CommandLine(context.Background(), "sleep 30")
+	SetGroup(cmd)
+	if err := Start(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := Kill(cmd); err != nil {
+		t.Fatalf("Kill() = %v", err)
+	}
+	if err := cmd.Wait(); err == nil {
+		t.Error("Wait() = nil, want the command to be killed")
+	}
+	// The group is gone
+	if err := KillGroup(cmd.Process.Pid); err != nil {
+		t.Errorf("KillGroup() of an exited group = %v", err)
+	}
+	Release(cmd.Process.Pid)
+}
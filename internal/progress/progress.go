@@ -0,0 +1,246 @@
+// Package progress provides the structured progress log, a JSONL companion
+// to the free-form progress file. Every message appended to the progress file
+// is also recorded as an event with its type, feature and iteration, so the
+// history of a run can be filtered (ralph -progress-query).
+package progress
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/filelock"
+)
+
+// TypeNote is the type of messages without a "TYPE:" prefix
+const TypeNote = "note"
+
+// Event is a single entry of the structured progress log
+type Event struct {
+	Time      time.Time         `json:"time"`
+	Type      string            `json:"type"`                 // e.g., "failure", "validation", "checkpoint"
+	FeatureID int               `json:"feature_id,omitempty"` // 0 if the event is not about a feature
+	Iteration int               `json:"iteration,omitempty"`  // 0 outside of iterations
+	Message   string            `json:"message"`
+	Payload   map[string]string `json:"payload,omitempty"`
+}
+
+// Context is the iteration and feature being worked on, recorded with events
+// whose messages do not name them
+type Context struct {
+	Iteration int
+	FeatureID int
+}
+
+var (
+	// typePattern matches the "TYPE:" or "TYPE [detail]:" prefix of a message
+	typePattern = regexp.MustCompile(`^([A-Z][A-Z ]*[A-Z])(?: \[([^\]]*)\])?:\s*`)
+	// featurePattern matches the first feature a message names
+	featurePattern = regexp.MustCompile(`(?i)\bfeature(?:\(s\))? #(\d+)`)
+	// iterationPattern matches the iteration a message names
+	iterationPattern = regexp.MustCompile(`(?i)\biteration (\d+)`)
+)
+
+// JSONLPath returns the structured log kept next to a progress file
+// (progress.txt -> progress.jsonl)
+func JSONLPath(progressPath string) string {
+	return strings.TrimSuffix(progressPath, filepath.Ext(progressPath)) + ".jsonl"
+}
+
+// NewEvent builds the event for a progress message. The type comes from the
+// message's "TYPE:" prefix, lowercased with spaces as underscores, and a
+// bracketed detail ("FAILURE [test]:") is kept as the "detail" payload. The
+// feature and iteration come from the message, falling back to ctx.
+func NewEvent(message string, ctx Context) Event {
+	e := Event{
+		Time:      time.Now(),
+		Type:      TypeNote,
+		FeatureID: ctx.FeatureID,
+		Iteration: ctx.Iteration,
+		Message:   message,
+	}
+	if m := typePattern.FindStringSubmatch(message); m != nil {
+		e.Type = strings.ToLower(strings.ReplaceAll(m[1], " ", "_"))
+		if m[2] != "" {
+			e.Payload = map[string]string{"detail": m[2]}
+		}
+	}
+	if m := featurePattern.FindStringSubmatch(message); m != nil {
+		e.FeatureID, _ = strconv.Atoi(m[1])
+	}
+	if m := iterationPattern.FindStringSubmatch(message); m != nil {
+		e.Iteration, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
+
+// Append appends an event to a JSONL log, holding its lock while writing
+func Append(path string, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode progress event: %w", err)
+	}
+	return filelock.With(path, func() error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open progress log: %w", err)
+		}
+		defer f.Close()
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write to progress log: %w", err)
+		}
+		return nil
+	})
+}
+
+// Read reads all events of a JSONL log. Lines that do not parse (e.g., one
+// cut short by a crash) are skipped.
+func Read(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress log: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read progress log: %w", err)
+	}
+	return events, nil
+}
+
+// Query selects events of the progress log. Zero fields match everything.
+type Query struct {
+	FeatureID int
+	Types     []string
+	Since     time.Time
+}
+
+// ParseQuery parses a query of space-separated filters:
+//
+//	feature=3             events about feature #3
+//	type=failure,coverage events of any of these types
+//	since=2h              events of the last two hours (or a date, 2006-01-02,
+//	                      or an RFC3339 time)
+//
+// "all" (or an empty query) matches every event.
+func ParseQuery(s string, now time.Time) (Query, error) {
+	var q Query
+	s = strings.TrimSpace(s)
+	if s == "" || s == "all" {
+		return q, nil
+	}
+	for _, filter := range strings.Fields(s) {
+		key, value, ok := strings.Cut(filter, "=")
+		if !ok || value == "" {
+			return q, fmt.Errorf("invalid filter %q (expected key=value)", filter)
+		}
+		switch strings.ToLower(key) {
+		case "feature":
+			id, err := strconv.Atoi(strings.TrimPrefix(value, "#"))
+			if err != nil || id <= 0 {
+				return q, fmt.Errorf("invalid feature %q", value)
+			}
+			q.FeatureID = id
+		case "type":
+			for _, t := range strings.Split(value, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					q.Types = append(q.Types, strings.ToLower(t))
+				}
+			}
+		case "since":
+			since, err := parseSince(value, now)
+			if err != nil {
+				return q, err
+			}
+			q.Since = since
+		default:
+			return q, fmt.Errorf("unknown filter %q (use feature, type or since)", key)
+		}
+	}
+	return q, nil
+}
+
+// parseSince parses a duration before now, a date or an RFC3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q (use a duration like 2h, a date like 2006-01-02, or an RFC3339 time)", value)
+}
+
+// Match reports whether an event is selected by the query
+func (q Query) Match(e Event) bool {
+	if q.FeatureID != 0 && e.FeatureID != q.FeatureID {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the events selected by the query
+func (q Query) Filter(events []Event) []Event {
+	var matched []Event
+	for _, e := range events {
+		if q.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// Format formats an event as a single line for display
+func Format(e Event) string {
+	var b strings.Builder
+	b.WriteString(e.Time.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "  %-12s", e.Type)
+	if e.FeatureID != 0 {
+		fmt.Fprintf(&b, "  #%-4d", e.FeatureID)
+	} else {
+		b.WriteString("       ")
+	}
+	if e.Iteration != 0 {
+		fmt.Fprintf(&b, "  iter %-3d", e.Iteration)
+	} else {
+		b.WriteString("          ")
+	}
+	// Multi-line messages (e.g., SAFETY file lists) are shown by their first line
+	message, _, _ := strings.Cut(e.Message, "\n")
+	b.WriteString("  " + message)
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package progress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEvent(t *testing.T) {
+	ctx := Context{Iteration: 4, FeatureID: 2}
+	tests := []struct {
+		message   string
+		wantType  string
+		feature   int
+		iteration int
+		detail    string
+	}{
+		{"FAILURE [test_failure]: 2 tests failed (feature #7, retry 1)", "failure", 7, 4, "test_failure"},
+		{"TIMEOUT: iteration 5 timed out after 10m0s, retrying", "timeout", 2, 5, ""},
+		{"DEFERRED: Feature #3 - Export (reason: blocked)", "deferred", 3, 4, ""},
+		{"GOAL DECOMPOSED: Add auth into 3 features", "goal_decomposed", 2, 4, ""},
+		{"COVERAGE: feature(s) #1, #9 not marked tested", "coverage", 1, 4, ""},
+		{"Acknowledged nudges:\n  - [FOCUS] tests", TypeNote, 2, 4, ""},
+	}
+	for _, tt := range tests {
+		e := NewEvent(tt.message, ctx)
+		if e.Type != tt.wantType || e.FeatureID != tt.feature || e.Iteration != tt.iteration || e.Payload["detail"] != tt.detail {
+			t.Errorf("NewEvent(%q) = %+v, want type %s, feature %d, iteration %d, detail %q",
+				tt.message, e, tt.wantType, tt.feature, tt.iteration, tt.detail)
+		}
+		if e.Message != tt.message {
+			t.Errorf("NewEvent(%q).Message = %q", tt.message, e.Message)
+		}
+	}
+}
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	first := NewEvent("CHECKPOINT: cp-1 \"start\"", Context{})
+	second := NewEvent("VALIDATION: feature #2 passed 3 validation(s)", Context{Iteration: 1})
+	for _, e := range []Event{first, second} {
+		if err := Append(path, e); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	// A line cut short by a crash is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time": "2026-`)
+	f.Close()
+
+	events, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != "checkpoint" || events[1].FeatureID != 2 || events[1].Iteration != 1 {
+		t.Errorf("Read() = %+v", events)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Time: now.Add(-3 * time.Hour), Type: "failure", FeatureID: 3, Message: "old failure"},
+		{Time: now.Add(-time.Hour), Type: "failure", FeatureID: 3, Message: "new failure"},
+		{Time: now.Add(-time.Hour), Type: "coverage", FeatureID: 4, Message: "coverage"},
+		{Time: now.Add(-time.Minute), Type: "checkpoint", Message: "checkpoint"},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"all", []string{"old failure", "new failure", "coverage", "checkpoint"}},
+		{"feature=3", []string{"old failure", "new failure"}},
+		{"type=FAILURE,checkpoint since=2h", []string{"new failure", "checkpoint"}},
+		{"feature=#4 type=coverage", []string{"coverage"}},
+		{"since=2026-10-16T11:30:00Z", []string{"checkpoint"}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query, now)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, e := range q.Filter(events) {
+			got = append(got, e.Message)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("query %q = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"feature", "feature=x", "since=soon", "status=failed"} {
+		if _, err := ParseQuery(bad, now); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestJSONLPath(t *testing.T) {
+	if got := JSONLPath("logs/progress.txt"); got != "logs/progress.jsonl" {
+		t.Errorf("JSONLPath() = %q", got)
+	}
+	if got := JSONLPath("PROGRESS"); got != "PROGRESS.jsonl" {
+		t.Errorf("JSONLPath() = %q", got)
+	}
+}
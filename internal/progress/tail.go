@@ -0,0 +1,79 @@
+package progress
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxTailLength bounds the length of the progress context in a prompt, so
+// long notes do not crowd out the instructions. Older entries are dropped
+// first.
+const maxTailLength = 4000
+
+// Tail returns the last n entries of a progress file, oldest first. Entries
+// are the blocks of text separated by blank lines: the messages Ralph
+// appends and the notes agents leave. A missing file has no entries.
+func Tail(path string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read progress file: %w", err)
+	}
+
+	var entries []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			entries = append(entries, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	// Keep the newest entries that fit
+	length := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		length += len(entries[i]) + 1
+		if length > maxTailLength {
+			if i == len(entries)-1 {
+				// Even the newest entry is too long: keep its end
+				entries[i] = "..." + entries[i][len(entries[i])-maxTailLength:]
+				return entries[i:], nil
+			}
+			return entries[i+1:], nil
+		}
+	}
+	return entries, nil
+}
+
+// BuildPromptContext formats progress entries for the iteration prompt
+func BuildPromptContext(entries []string) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n[RECENT PROGRESS - What previous iterations did, oldest first:]\n")
+	for _, e := range entries {
+		b.WriteString(e + "\n")
+	}
+	b.WriteString("[END RECENT PROGRESS]\n\n")
+	return b.String()
+}
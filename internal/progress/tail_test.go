@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.txt")
+	if entries, err := Tail(path, 3); err != nil || entries != nil {
+		t.Errorf("Tail() of a missing file = %v, %v", entries, err)
+	}
+
+	content := "# Progress\n\n[2026-10-16T10:00:00Z] ITERATION 1: started\n\n" +
+		"Implemented login form.\nNext: session handling.\n\n\n" +
+		"[2026-10-16T10:05:00Z] FAILURE [test]: 2 tests failed\n" +
+		"\n[2026-10-16T10:06:00Z] DEFERRED: Feature #3 - Export\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Tail(path, 3)
+	if err != nil {
+		t.Fatalf("Tail() failed: %v", err)
+	}
+	want := []string{
+		"Implemented login form.\nNext: session handling.",
+		"[2026-10-16T10:05:00Z] FAILURE [test]: 2 tests failed",
+		"[2026-10-16T10:06:00Z] DEFERRED: Feature #3 - Export",
+	}
+	if strings.Join(entries, "|") != strings.Join(want, "|") {
+		t.Errorf("Tail() = %q, want %q", entries, want)
+	}
+	if entries, _ := Tail(path, 0); entries != nil {
+		t.Errorf("Tail(0) = %q", entries)
+	}
+
+	context := BuildPromptContext(entries)
+	if !strings.Contains(context, "[RECENT PROGRESS") || !strings.Contains(context, "Next: session handling.\n[2026") {
+		t.Errorf("BuildPromptContext() = %q", context)
+	}
+	if BuildPromptContext(nil) != "" {
+		t.Error("BuildPromptContext(nil) is not empty")
+	}
+}
+
+func TestTailLength(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.txt")
+	long := strings.Repeat("x", 3000)
+	if err := os.WriteFile(path, []byte("old "+long+"\n\nnew "+long+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := Tail(path, 5)
+	if len(entries) != 1 || !strings.HasPrefix(entries[0], "new ") {
+		t.Errorf("Tail() kept %d entries, want only the newest", len(entries))
+	}
+
+	if err := os.WriteFile(path, []byte("start "+strings.Repeat("y", 2*maxTailLength)+" end\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = Tail(path, 5)
+	if len(entries) != 1 || len(entries[0]) != maxTailLength+3 || !strings.HasSuffix(entries[0], " end") {
+		t.Errorf("Tail() of a huge entry = %d bytes", len(entries[0]))
+	}
+}
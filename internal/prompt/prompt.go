@@ -2,19 +2,36 @@
 package prompt
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 const (
 	// CompleteSignal is the marker indicating the plan is complete
 	CompleteSignal = "<promise>COMPLETE</promise>"
+	// CondensedPlanFile is the copy of the plan with truncated step lists that
+	// prompts reference when a feature has more steps than fit in a prompt
+	CondensedPlanFile = ".ralph/prompt-plan.json"
 )
 
-// BuildIterationPrompt builds the prompt for an iteration
+// BuildIterationPrompt builds the prompt for an iteration. If a feature has
+// more than cfg.MaxPromptSteps steps, the prompt references a condensed copy
+// of the plan written to CondensedPlanFile instead of the plan itself.
 func BuildIterationPrompt(cfg *config.Config) string {
+	return NewIterationData(cfg, 0, 0).Instructions
+}
+
+// NewIterationData resolves the files and commands of an iteration prompt and
+// builds its instructions. featureID is the feature expected to be worked on
+// (0 if unknown). Context sections (baseline, memories, nudges, guidance) are
+// left for the caller to fill in.
+func NewIterationData(cfg *config.Config, iteration, featureID int) IterationData {
 	// Resolve absolute paths for the plan and progress files
 	planPath, err := filepath.Abs(cfg.PlanFile)
 	if err != nil {
@@ -26,12 +43,55 @@ func BuildIterationPrompt(cfg *config.Config) string {
 		progressPath = cfg.ProgressFile
 	}
 
+	// Reference a condensed copy of the plan if some step lists are too long
+	planRef := planPath
+	condensed, err := WriteCondensedPlan(cfg.PlanFile, CondensedPlanFile, cfg.MaxPromptSteps)
+	if err == nil && len(condensed) > 0 {
+		if abs, err := filepath.Abs(CondensedPlanFile); err == nil {
+			planRef = abs
+		} else {
+			planRef = CondensedPlanFile
+		}
+	}
+
+	data := IterationData{
+		Iteration:      iteration,
+		PlanFile:       planRef,
+		FullPlanFile:   planPath,
+		ProgressFile:   progressPath,
+		TypeCheckCmd:   cfg.TypeCheckCmd,
+		TestCmd:        cfg.TestCmd,
+		LintCmd:        cfg.LintCmd,
+		CompleteSignal: CompleteSignal,
+	}
+	var plans []plan.Plan
+	if featureID > 0 {
+		if plans, err = plan.ReadFile(cfg.PlanFile); err == nil {
+			data.Feature = plan.GetByID(plans, featureID)
+		}
+	}
+
+	// With -feature-prompt, the agent is shown only the feature to work on
+	if cfg.FeaturePrompt && data.Feature != nil && data.Feature.IsActionable() {
+		data.Instructions = buildFeatureInstructions(cfg, data, plans)
+		return data
+	}
+
 	// Build the prompt string as a single line (matching bash script behavior)
 	// The bash script uses backslash continuation, which results in a single-line string
-	prompt := fmt.Sprintf("@%s @%s ", planPath, progressPath)
+	prompt := fmt.Sprintf("@%s @%s ", planRef, progressPath)
+	if planRef != planPath {
+		prompt += fmt.Sprintf("This is a condensed copy of the PRD in which long step lists are truncated. "+
+			"The full PRD is %s: read the remaining steps of the feature you work on there, and update that file, not the copy. ", planPath)
+	}
 	prompt += "1. Find the highest-priority feature to work on and work only on that feature. "
 	prompt += "This should be the one YOU decide has the highest priority - not necessarily the first in the list. "
+	prompt += "Skip features with \"type\": \"question\" - they are unresolved requirements waiting for a human answer. "
+	prompt += "If a feature has an \"answer\", implement it as that answer clarifies. "
 	prompt += fmt.Sprintf("2. Check that the types check via %s and that the tests pass via %s. ", cfg.TypeCheckCmd, cfg.TestCmd)
+	if cfg.LintCmd != "" {
+		prompt += fmt.Sprintf("Also check that the linter passes via %s. ", cfg.LintCmd)
+	}
 	prompt += "3. Update the PRD with the work that was done. "
 	prompt += "4. Append your progress to the progress.txt file. "
 	prompt += "Use this to leave a note for the next person working in the codebase. "
@@ -39,7 +99,64 @@ func BuildIterationPrompt(cfg *config.Config) string {
 	prompt += "ONLY WORK ON A SINGLE FEATURE. "
 	prompt += fmt.Sprintf("If, while implementing the feature, you notice the PRD is complete, output %s. ", CompleteSignal)
 
-	return prompt
+	data.Instructions = prompt
+	return data
+}
+
+// buildFeatureInstructions builds the instructions of a -feature-prompt
+// iteration: the details of data.Feature, which is the only feature the agent
+// may work on, instead of a reference to the whole plan
+func buildFeatureInstructions(cfg *config.Config, data IterationData, plans []plan.Plan) string {
+	f := *data.Feature
+	condensed, _ := CondensePlans([]plan.Plan{f}, cfg.MaxPromptSteps)
+	steps := condensed[0].Steps
+
+	others := 0
+	for _, p := range plans {
+		if p.ID != f.ID && p.IsActionable() {
+			others++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "@%s ", data.ProgressFile)
+	fmt.Fprintf(&b, "You are working on a single feature of the PRD %s. Its details are below.\n\n", data.FullPlanFile)
+	fmt.Fprintf(&b, "[FEATURE #%d", f.ID)
+	if f.Category != "" {
+		fmt.Fprintf(&b, " - %s", f.Category)
+	}
+	b.WriteString("]\n")
+	fmt.Fprintf(&b, "Description: %s\n", f.Description)
+	if f.Answer != "" {
+		fmt.Fprintf(&b, "Clarification: %s\n", f.Answer)
+	}
+	if len(steps) > 0 {
+		b.WriteString("Steps:\n")
+		for i, step := range steps {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+		}
+	}
+	if f.ExpectedOutput != "" {
+		fmt.Fprintf(&b, "Expected output: %s\n", f.ExpectedOutput)
+	}
+	b.WriteString("[END FEATURE]\n\n")
+
+	fmt.Fprintf(&b, "1. Implement only feature #%d. Do not implement, change or refactor code for other features, "+
+		"and do not edit their entries in the PRD, even if you notice work they need - mention it in your progress note instead. ", f.ID)
+	fmt.Fprintf(&b, "2. Check that the types check via %s and that the tests pass via %s. ", cfg.TypeCheckCmd, cfg.TestCmd)
+	if cfg.LintCmd != "" {
+		fmt.Fprintf(&b, "Also check that the linter passes via %s. ", cfg.LintCmd)
+	}
+	fmt.Fprintf(&b, "3. Once the feature works, set \"tested\": true for feature #%d in the PRD. ", f.ID)
+	b.WriteString("4. Append your progress to the progress.txt file. ")
+	b.WriteString("Use this to leave a note for the next person working in the codebase. ")
+	b.WriteString("5. Make a git commit of that feature. ")
+	if others == 0 {
+		fmt.Fprintf(&b, "This is the last unfinished feature: once it is done, output %s. ", CompleteSignal)
+	} else {
+		fmt.Fprintf(&b, "%d other feature(s) remain, so do not output %s. ", others, CompleteSignal)
+	}
+	return b.String()
 }
 
 // BuildPlanGenerationPrompt creates the prompt for converting notes to plan.json
@@ -54,6 +171,9 @@ func BuildPlanGenerationPrompt(notesPath, outputPath string) string {
 	prompt += "\"expected_output\": string (what success looks like), "
 	prompt += "\"tested\": boolean (default false) }. "
 	prompt += "Break down the notes into logical, sequential features/tasks. "
+	prompt += "When the notes leave a requirement unclear or ambiguous, do not guess: add a plan item with "
+	prompt += "\"type\": \"question\" whose description is the question a human needs to answer, "
+	prompt += "and keep features that depend on the answer as separate items. "
 	prompt += "Each plan item should be self-contained and implementable. "
 	prompt += "Categories should reflect the type of work: 'chore' for setup/tooling, 'infra' for infrastructure, "
 	prompt += "'db' for database work, 'ui' for frontend, 'feature' for features, 'other' for core logic/services. "
@@ -62,3 +182,76 @@ func BuildPlanGenerationPrompt(notesPath, outputPath string) string {
 
 	return prompt
 }
+
+// OversizedFeatures returns the actionable features with more than maxSteps
+// steps. maxSteps <= 0 means no limit.
+func OversizedFeatures(plans []plan.Plan, maxSteps int) []plan.Plan {
+	if maxSteps <= 0 {
+		return nil
+	}
+	var result []plan.Plan
+	for _, p := range plans {
+		if p.IsActionable() && len(p.Steps) > maxSteps {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// CondensePlans returns a copy of plans in which step lists longer than
+// maxSteps are cut to their first maxSteps steps plus a note saying how many
+// were left out, along with the IDs of the features that were cut. maxSteps
+// <= 0 means no limit.
+func CondensePlans(plans []plan.Plan, maxSteps int) ([]plan.Plan, []int) {
+	if maxSteps <= 0 {
+		return plans, nil
+	}
+
+	var condensed []plan.Plan
+	var ids []int
+	for i, p := range plans {
+		if len(p.Steps) <= maxSteps {
+			continue
+		}
+		if condensed == nil {
+			condensed = append([]plan.Plan(nil), plans...)
+		}
+		steps := make([]string, maxSteps, maxSteps+1)
+		copy(steps, p.Steps)
+		condensed[i].Steps = append(steps, fmt.Sprintf("... %d more steps omitted (see the full PRD)", len(p.Steps)-maxSteps))
+		ids = append(ids, p.ID)
+	}
+	if condensed == nil {
+		return plans, nil
+	}
+	return condensed, ids
+}
+
+// WriteCondensedPlan writes the condensed copy of the plan at planPath to
+// outPath if any feature has more than maxSteps steps, and returns
+// the IDs of the features that were cut. Otherwise it removes a stale copy
+// left by an earlier iteration and returns nil.
+func WriteCondensedPlan(planPath, outPath string, maxSteps int) ([]int, error) {
+	plans, err := plan.ReadFile(planPath)
+	if err != nil {
+		return nil, err
+	}
+
+	condensed, ids := CondensePlans(plans, maxSteps)
+	if len(ids) == 0 {
+		os.Remove(outPath)
+		return nil, nil
+	}
+
+	data, err := json.MarshalIndent(condensed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal condensed plan: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create condensed plan directory: %w", err)
+	}
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write condensed plan: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,130 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func steps(n int) []string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = fmt.Sprintf("Step %d", i+1)
+	}
+	return s
+}
+
+func TestCondensePlans(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Description: "Small", Steps: steps(3)},
+		{ID: 2, Description: "Huge", Steps: steps(300)},
+	}
+
+	condensed, ids := CondensePlans(plans, 10)
+	if len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("ids = %v, want [2]", ids)
+	}
+	if len(condensed[0].Steps) != 3 {
+		t.Errorf("small feature has %d steps, want 3", len(condensed[0].Steps))
+	}
+	got := condensed[1].Steps
+	if len(got) != 11 || got[9] != "Step 10" || !strings.Contains(got[10], "290 more steps") {
+		t.Errorf("huge feature steps = %d, last %q", len(got), got[len(got)-1])
+	}
+	if len(plans[1].Steps) != 300 {
+		t.Error("CondensePlans modified the original plans")
+	}
+}
+
+func TestCondensePlansNoLimit(t *testing.T) {
+	plans := []plan.Plan{{ID: 1, Steps: steps(300)}}
+	for _, max := range []int{0, 300} {
+		if _, ids := CondensePlans(plans, max); ids != nil {
+			t.Errorf("CondensePlans(%d) cut features %v", max, ids)
+		}
+	}
+}
+
+func TestOversizedFeatures(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Steps: steps(50)},
+		{ID: 2, Steps: steps(50), Tested: true},
+		{ID: 3, Steps: steps(5)},
+	}
+	got := OversizedFeatures(plans, 25)
+	if len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("OversizedFeatures() = %v, want feature 1 only", got)
+	}
+	if got := OversizedFeatures(plans, 0); got != nil {
+		t.Errorf("OversizedFeatures(0) = %v, want none", got)
+	}
+}
+
+func TestBuildIterationPromptCondensesPlan(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	writePlan := func(plans []plan.Plan) {
+		t.Helper()
+		if err := plan.WriteFile("plan.json", plans); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.New()
+	cfg.MaxPromptSteps = 10
+
+	writePlan([]plan.Plan{{ID: 1, Description: "Huge", Steps: steps(100)}})
+	p := BuildIterationPrompt(cfg)
+	condensedPath, _ := filepath.Abs(CondensedPlanFile)
+	if !strings.HasPrefix(p, "@"+condensedPath+" ") {
+		t.Errorf("prompt does not reference the condensed plan: %s", p)
+	}
+	planPath, _ := filepath.Abs("plan.json")
+	if !strings.Contains(p, "The full PRD is "+planPath) {
+		t.Errorf("prompt does not point at the full plan: %s", p)
+	}
+
+	data, err := os.ReadFile(CondensedPlanFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var condensed []plan.Plan
+	if err := json.Unmarshal(data, &condensed); err != nil {
+		t.Fatal(err)
+	}
+	if len(condensed[0].Steps) != 11 {
+		t.Errorf("condensed plan has %d steps, want 11", len(condensed[0].Steps))
+	}
+
+	// Once the feature is split, the plan is referenced again and the copy removed
+	writePlan([]plan.Plan{{ID: 1, Description: "Small", Steps: steps(5)}})
+	p = BuildIterationPrompt(cfg)
+	if !strings.HasPrefix(p, "@"+planPath+" ") {
+		t.Errorf("prompt does not reference the plan: %s", p)
+	}
+	if _, err := os.Stat(CondensedPlanFile); !os.IsNotExist(err) {
+		t.Error("stale condensed plan was not removed")
+	}
+}
+
+func TestBuildIterationPromptLint(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := plan.WriteFile("plan.json", []plan.Plan{{ID: 1, Description: "Feature"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	if p := BuildIterationPrompt(cfg); strings.Contains(p, "linter") {
+		t.Errorf("prompt mentions a linter without a lint command: %s", p)
+	}
+	cfg.LintCmd = "golangci-lint run"
+	if p := BuildIterationPrompt(cfg); !strings.Contains(p, "the linter passes via golangci-lint run") {
+		t.Errorf("prompt does not ask for the linter to pass: %s", p)
+	}
+}
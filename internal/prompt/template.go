@@ -0,0 +1,176 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/pkg/goals"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// IterationData is the data available to an iteration prompt template
+// (iteration_prompt_template). {{.Default}} renders the built-in prompt.
+type IterationData struct {
+	Iteration      int
+	Feature        *plan.Plan // Feature expected to be worked on, nil if unknown
+	PlanFile       string     // Plan the agent reads (a condensed copy if step lists were cut)
+	FullPlanFile   string     // Plan the agent updates
+	ProgressFile   string
+	TypeCheckCmd   string
+	TestCmd        string
+	LintCmd        string
+	CompleteSignal string // Marker the agent outputs when the plan is complete
+	Instructions   string // Built-in instructions, without the context sections
+	Baseline       string // Codebase structure and conventions
+	Impact         string // Files the current feature will likely touch, predicted from the baseline
+	Memories       string // Relevant memories from earlier runs
+	Progress       string // Recent progress file entries (-progress-tail)
+	Nudges         string // Active nudges
+	Blocked        string // Features that must not be started yet (milestone dependencies)
+	Budget         string // Iterations left for the feature before it is deferred
+	Guidance       string // Recovery and plan repair guidance after a failure
+}
+
+// Default returns the built-in iteration prompt: the guidance and context
+// sections followed by the instructions
+func (d IterationData) Default() string {
+	prompt := d.Nudges + d.Blocked + d.Budget + d.Memories + d.Progress + d.Baseline + d.Impact + d.Instructions
+	if d.Guidance != "" {
+		prompt = d.Guidance + "\n\n" + prompt
+	}
+	return prompt
+}
+
+// PlanData is the data available to a plan generation prompt template
+// (plan_prompt_template)
+type PlanData struct {
+	NotesFile  string
+	OutputFile string // Where the agent writes the plan
+	Default    string // Built-in prompt
+}
+
+// GoalData is the data available to a goal decomposition prompt template
+// (goal_prompt_template)
+type GoalData struct {
+	Goal          *goals.Goal
+	ExistingPlans []plan.Plan
+	PreviousPlans []plan.Plan // Items generated from the goal before (-redecompose-goal)
+	Feedback      string      // Feedback on the previous items (-redecompose-goal)
+	NextID        int         // First free plan ID
+	OutputFile    string      // Where the agent writes the new plan items
+	Default       string      // Built-in prompt
+}
+
+// templateFuncs are the functions available in prompt templates besides the
+// text/template built-ins
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"trim": strings.TrimSpace,
+}
+
+// LoadTemplate reads and parses a prompt template file. Referencing a
+// variable that does not exist is an error when the template is rendered.
+func LoadTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	tmpl, err := template.New(path).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// RenderTemplate renders the prompt template at path with data
+func RenderTemplate(path string, data any) (string, error) {
+	tmpl, err := LoadTemplate(path)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", path, err)
+	}
+	return b.String(), nil
+}
+
+// CheckTemplates parses the prompt templates configured in cfg, so mistakes
+// are reported before the agent runs
+func CheckTemplates(cfg *config.Config) error {
+	for name, path := range map[string]string{
+		"iteration_prompt_template": cfg.IterationPromptTemplate,
+		"plan_prompt_template":      cfg.PlanPromptTemplate,
+		"goal_prompt_template":      cfg.GoalPromptTemplate,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := LoadTemplate(path); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RenderIterationPrompt builds the iteration prompt from data, using the
+// configured template if there is one
+func RenderIterationPrompt(cfg *config.Config, data IterationData) (string, error) {
+	if cfg.IterationPromptTemplate == "" {
+		return data.Default(), nil
+	}
+	return RenderTemplate(cfg.IterationPromptTemplate, data)
+}
+
+// RenderPlanGenerationPrompt builds the prompt for converting notes to a
+// plan, using the configured template if there is one
+func RenderPlanGenerationPrompt(cfg *config.Config, notesPath, outputPath string) (string, error) {
+	data := PlanData{NotesFile: notesPath, OutputFile: outputPath, Default: BuildPlanGenerationPrompt(notesPath, outputPath)}
+	if cfg.PlanPromptTemplate == "" {
+		return data.Default, nil
+	}
+	return RenderTemplate(cfg.PlanPromptTemplate, data)
+}
+
+// RenderGoalDecompositionPrompt builds the prompt for decomposing a goal into
+// plan items, using the configured template if there is one
+func RenderGoalDecompositionPrompt(cfg *config.Config, goal *goals.Goal, existingPlans []plan.Plan, outputPath string) (string, error) {
+	data := GoalData{
+		Goal:          goal,
+		ExistingPlans: existingPlans,
+		NextID:        1,
+		OutputFile:    outputPath,
+		Default:       goals.BuildGoalDecompositionPrompt(goal, existingPlans, outputPath),
+	}
+	for _, p := range existingPlans {
+		if p.ID >= data.NextID {
+			data.NextID = p.ID + 1
+		}
+	}
+	if cfg.GoalPromptTemplate == "" {
+		return data.Default, nil
+	}
+	return RenderTemplate(cfg.GoalPromptTemplate, data)
+}
+
+// RenderGoalRedecompositionPrompt builds the prompt for regenerating the plan
+// items of a goal with feedback, using the goal decomposition template if
+// there is one
+func RenderGoalRedecompositionPrompt(cfg *config.Config, goal *goals.Goal, existingPlans []plan.Plan, feedback, outputPath string) (string, error) {
+	data := GoalData{
+		Goal:          goal,
+		ExistingPlans: existingPlans,
+		PreviousPlans: goals.GoalPlans(goal, existingPlans),
+		Feedback:      feedback,
+		NextID:        goals.GetNextPlanID(existingPlans),
+		OutputFile:    outputPath,
+		Default:       goals.BuildGoalRedecompositionPrompt(goal, existingPlans, feedback, outputPath),
+	}
+	if cfg.GoalPromptTemplate == "" {
+		return data.Default, nil
+	}
+	return RenderTemplate(cfg.GoalPromptTemplate, data)
+}
@@ -0,0 +1,166 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/pkg/goals"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRenderIterationPrompt(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	cfg := config.New()
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	cfg.TestCmd = "go test ./..."
+	if err := plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 3, Description: "Add login", Steps: []string{"Form", "Session"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data := NewIterationData(cfg, 2, 3)
+	data.Memories = "MEMORIES\n"
+	data.Guidance = "Fix the failing test"
+
+	// Without a template the built-in prompt is used
+	got, err := RenderIterationPrompt(cfg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "Fix the failing test\n\nMEMORIES\n@") || !strings.HasSuffix(got, data.Instructions) {
+		t.Errorf("default prompt = %q", got)
+	}
+
+	cfg.IterationPromptTemplate = writeTemplate(t, `Iteration {{.Iteration}}: work on #{{.Feature.ID}} {{.Feature.Description}} ({{join .Feature.Steps ", "}}).
+Plan: {{.PlanFile}}. Test with {{.TestCmd}}.
+{{.Memories}}{{if .LintCmd}}Lint with {{.LintCmd}}.{{end}}Say {{.CompleteSignal}} when done.`)
+	got, err = RenderIterationPrompt(cfg, data)
+	if err != nil {
+		t.Fatalf("RenderIterationPrompt() failed: %v", err)
+	}
+	want := "Iteration 2: work on #3 Add login (Form, Session).\nPlan: " + cfg.PlanFile + ". Test with go test ./....\nMEMORIES\nSay " + CompleteSignal + " when done."
+	if got != want {
+		t.Errorf("RenderIterationPrompt() = %q, want %q", got, want)
+	}
+
+	// Templates can wrap the built-in prompt
+	cfg.IterationPromptTemplate = writeTemplate(t, "Follow the style guide.\n{{.Default}}")
+	got, _ = RenderIterationPrompt(cfg, data)
+	if got != "Follow the style guide.\n"+data.Default() {
+		t.Errorf("wrapped prompt = %q", got)
+	}
+
+	cfg.IterationPromptTemplate = writeTemplate(t, "{{.Features}}")
+	if _, err := RenderIterationPrompt(cfg, data); err == nil {
+		t.Error("RenderIterationPrompt() accepted an unknown variable")
+	}
+}
+
+func TestRenderPlanAndGoalPrompts(t *testing.T) {
+	cfg := config.New()
+	got, err := RenderPlanGenerationPrompt(cfg, "/notes.md", "/plan.json")
+	if err != nil || got != BuildPlanGenerationPrompt("/notes.md", "/plan.json") {
+		t.Errorf("default plan prompt = %q, %v", got, err)
+	}
+	cfg.PlanPromptTemplate = writeTemplate(t, "Turn {{.NotesFile}} into {{.OutputFile}}")
+	if got, _ := RenderPlanGenerationPrompt(cfg, "/notes.md", "/plan.json"); got != "Turn /notes.md into /plan.json" {
+		t.Errorf("plan prompt = %q", got)
+	}
+
+	goal := &goals.Goal{Description: "Add auth", SuccessCriteria: []string{"Users can log in"}}
+	existing := []plan.Plan{{ID: 4}, {ID: 9}}
+	cfg.GoalPromptTemplate = writeTemplate(t, "{{.Goal.Description}}: {{range .Goal.SuccessCriteria}}{{.}}{{end}}; IDs from {{.NextID}}, write {{.OutputFile}}")
+	got, err = RenderGoalDecompositionPrompt(cfg, goal, existing, "/plan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Add auth: Users can log in; IDs from 10, write /plan.json"; got != want {
+		t.Errorf("goal prompt = %q, want %q", got, want)
+	}
+
+	goal.GeneratedPlanIDs = []int{9}
+	cfg.GoalPromptTemplate = writeTemplate(t, "{{.Feedback}}: revise{{range .PreviousPlans}} #{{.ID}}{{end}}")
+	got, err = RenderGoalRedecompositionPrompt(cfg, goal, existing, "Split it up", "/plan.json")
+	if err != nil || got != "Split it up: revise #9" {
+		t.Errorf("goal redecomposition prompt = %q, %v", got, err)
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	cfg := config.New()
+	if err := CheckTemplates(cfg); err != nil {
+		t.Errorf("CheckTemplates() without templates = %v", err)
+	}
+	cfg.GoalPromptTemplate = writeTemplate(t, "{{.Goal.Description")
+	if err := CheckTemplates(cfg); err == nil || !strings.Contains(err.Error(), "goal_prompt_template") {
+		t.Errorf("CheckTemplates() = %v, want a goal_prompt_template error", err)
+	}
+	cfg.GoalPromptTemplate = filepath.Join(t.TempDir(), "missing.tmpl")
+	if err := CheckTemplates(cfg); err == nil {
+		t.Error("CheckTemplates() accepted a missing template")
+	}
+}
+
+func TestFeaturePrompt(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	cfg := config.New()
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	cfg.TestCmd = "go test ./..."
+	cfg.MaxPromptSteps = 2
+	cfg.FeaturePrompt = true
+	plans := []plan.Plan{
+		{ID: 1, Description: "Setup", Tested: true},
+		{ID: 2, Category: "ui", Description: "Login form", Steps: []string{"Fields", "Validation", "Submit"}, ExpectedOutput: "Users can log in"},
+		{ID: 3, Description: "Export"},
+	}
+	if err := plan.WriteFile(cfg.PlanFile, plans); err != nil {
+		t.Fatal(err)
+	}
+
+	got := NewIterationData(cfg, 1, 2).Instructions
+	for _, want := range []string{
+		"[FEATURE #2 - ui]\nDescription: Login form\nSteps:\n1. Fields\n2. Validation\n3. ... 1 more steps omitted",
+		"Expected output: Users can log in\n[END FEATURE]",
+		"Implement only feature #2.",
+		"set \"tested\": true for feature #2",
+		"1 other feature(s) remain, so do not output " + CompleteSignal,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("feature prompt missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Export") {
+		t.Error("feature prompt includes another feature")
+	}
+
+	plans[1].Tested = true
+	if err := plan.WriteFile(cfg.PlanFile, plans); err != nil {
+		t.Fatal(err)
+	}
+	got = NewIterationData(cfg, 1, 3).Instructions
+	if !strings.Contains(got, "last unfinished feature: once it is done, output "+CompleteSignal) {
+		t.Errorf("last feature prompt = %s", got)
+	}
+
+	// Without a known actionable feature the whole plan is referenced
+	for _, id := range []int{0, 1} {
+		if got := NewIterationData(cfg, 1, id).Instructions; !strings.Contains(got, "Find the highest-priority feature") {
+			t.Errorf("NewIterationData(feature %d) = %s", id, got)
+		}
+	}
+}
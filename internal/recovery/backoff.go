@@ -0,0 +1,78 @@
+package recovery
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff spaces out retries of a failing feature, so that flaky external
+// services the agent depends on are not hammered. The zero value retries
+// immediately.
+type Backoff struct {
+	Initial    time.Duration // Delay before the first retry; 0 disables backoff
+	Multiplier float64       // Factor applied to the delay after each retry (values below 1 are treated as 1)
+	Max        time.Duration // Upper bound of the delay; 0 means no bound
+	Jitter     float64       // Fraction of the delay randomly added or removed (0-1)
+}
+
+// Validate checks that the backoff settings are usable
+func (b Backoff) Validate() error {
+	if b.Initial < 0 || b.Max < 0 {
+		return fmt.Errorf("backoff delays cannot be negative")
+	}
+	if b.Multiplier < 0 {
+		return fmt.Errorf("backoff multiplier cannot be negative")
+	}
+	if b.Jitter < 0 || b.Jitter > 1 {
+		return fmt.Errorf("backoff jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Delay returns how long to wait before the given retry of a feature
+// (1 for the first retry), including jitter
+func (b Backoff) Delay(retry int) time.Duration {
+	return b.jittered(b.base(retry), rand.Float64())
+}
+
+// base returns the delay before the given retry without jitter
+func (b Backoff) base(retry int) time.Duration {
+	if b.Initial <= 0 || retry < 1 {
+		return 0
+	}
+	multiplier := math.Max(b.Multiplier, 1)
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(retry-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		return b.Max
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// jittered spreads the delay by up to ±Jitter of it; r is uniform in [0, 1)
+func (b Backoff) jittered(delay time.Duration, r float64) time.Duration {
+	if b.Jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	spread := float64(delay) * b.Jitter
+	return time.Duration(float64(delay) - spread + 2*spread*r)
+}
+
+// String describes the backoff settings
+func (b Backoff) String() string {
+	if b.Initial <= 0 {
+		return "none"
+	}
+	s := fmt.Sprintf("%s x%g", b.Initial, math.Max(b.Multiplier, 1))
+	if b.Max > 0 {
+		s += fmt.Sprintf(" up to %s", b.Max)
+	}
+	if b.Jitter > 0 {
+		s += fmt.Sprintf(", ±%.0f%% jitter", b.Jitter*100)
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package recovery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Base(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Second, Multiplier: 2, Max: time.Minute}
+	want := []time.Duration{0, 10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for retry, w := range want {
+		if got := b.base(retry); got != w {
+			t.Errorf("base(%d) = %s, want %s", retry, got, w)
+		}
+	}
+
+	// A multiplier below 1 keeps the delay constant
+	flat := Backoff{Initial: time.Second, Multiplier: 0.5}
+	if got := flat.base(5); got != time.Second {
+		t.Errorf("base(5) with multiplier 0.5 = %s, want 1s", got)
+	}
+
+	// Unbounded delays do not overflow
+	huge := Backoff{Initial: time.Hour, Multiplier: 10}
+	if got := huge.base(100); got <= 0 {
+		t.Errorf("base(100) overflowed to %s", got)
+	}
+}
+
+func TestBackoff_Zero(t *testing.T) {
+	var b Backoff
+	for retry := 0; retry < 5; retry++ {
+		if got := b.Delay(retry); got != 0 {
+			t.Errorf("zero backoff Delay(%d) = %s, want 0", retry, got)
+		}
+	}
+	if b.String() != "none" {
+		t.Errorf("String() = %q, want none", b.String())
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+	if got := b.jittered(10*time.Second, 0); got != 8*time.Second {
+		t.Errorf("jittered low = %s, want 8s", got)
+	}
+	if got := b.jittered(10*time.Second, 0.5); got != 10*time.Second {
+		t.Errorf("jittered mid = %s, want 10s", got)
+	}
+	for i := 0; i < 100; i++ {
+		if got := b.Delay(2); got < 16*time.Second || got > 24*time.Second {
+			t.Fatalf("Delay(2) = %s, want within 16s-24s", got)
+		}
+	}
+}
+
+func TestBackoff_Validate(t *testing.T) {
+	valid := Backoff{Initial: time.Second, Multiplier: 2, Max: time.Minute, Jitter: 0.5}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	for _, b := range []Backoff{
+		{Initial: -time.Second},
+		{Multiplier: -1},
+		{Jitter: 1.5},
+	} {
+		if err := b.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", b)
+		}
+	}
+}
+
+func TestRecoveryManager_BackoffAndBudget(t *testing.T) {
+	rm := NewRecoveryManager(5, StrategyRetry)
+	rm.SetBackoff(Backoff{Initial: time.Second, Multiplier: 3})
+	rm.SetRetryBudget(3)
+
+	// Delays grow per feature
+	_, first := rm.HandleFailure("FAIL: test error", 1, 1, 1)
+	_, second := rm.HandleFailure("FAIL: test error", 1, 1, 2)
+	if first.Delay != time.Second || second.Delay != 3*time.Second {
+		t.Errorf("delays = %s, %s; want 1s, 3s", first.Delay, second.Delay)
+	}
+	_, other := rm.HandleFailure("FAIL: test error", 1, 2, 3)
+	if other.Delay != time.Second {
+		t.Errorf("first retry of another feature waited %s, want 1s", other.Delay)
+	}
+
+	// The budget is shared by all features
+	if !rm.RetryBudgetExhausted() || rm.RetriesUsed() != 3 {
+		t.Fatalf("budget should be spent after 3 retries (used %d)", rm.RetriesUsed())
+	}
+	_, result := rm.HandleFailure("FAIL: test error", 1, 3, 4)
+	if result.ShouldRetry || !result.ShouldSkip || result.Delay != 0 {
+		t.Errorf("expected a skip once the budget is spent, got %+v", result)
+	}
+}
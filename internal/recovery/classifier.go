@@ -0,0 +1,237 @@
+package recovery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severity ranks how serious a failure pattern match is
+type Severity string
+
+const (
+	// SeverityWarning is reported, but the iteration is not treated as failed
+	SeverityWarning Severity = "warning"
+	// SeverityError fails the iteration; the recovery strategy handles it
+	SeverityError Severity = "error"
+	// SeverityFatal fails the iteration in a way retrying cannot fix, so the feature is skipped
+	SeverityFatal Severity = "fatal"
+)
+
+// ParseSeverity parses a string into a Severity. Empty means error.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "", "error":
+		return SeverityError, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "fatal":
+		return SeverityFatal, nil
+	default:
+		return "", fmt.Errorf("unknown severity: %s (valid: warning, error, fatal)", s)
+	}
+}
+
+// rank orders severities from least to most serious
+func (s Severity) rank() int {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityError:
+		return 2
+	case SeverityFatal:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ParseFailureType parses a failure type, accepting the short names test,
+// typecheck, lint, agent and timeout. Empty leaves the type to DetectFailure.
+func ParseFailureType(s string) (FailureType, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return "", nil
+	case "test", string(FailureTypeTest):
+		return FailureTypeTest, nil
+	case "typecheck", string(FailureTypeTypeCheck):
+		return FailureTypeTypeCheck, nil
+	case "lint", string(FailureTypeLint):
+		return FailureTypeLint, nil
+	case "agent", string(FailureTypeAgentError):
+		return FailureTypeAgentError, nil
+	case "timeout":
+		return FailureTypeTimeout, nil
+	default:
+		return "", fmt.Errorf("unknown failure type: %s (valid: test, typecheck, lint, agent, timeout)", s)
+	}
+}
+
+// Rule is a failure pattern matched against agent and check output
+type Rule struct {
+	Name         string      // Name shown when the rule matches
+	Pattern      string      // Regular expression, matched per line ((?i) for case-insensitive)
+	Type         FailureType // Failure type of a match; empty leaves it to DetectFailure
+	Severity     Severity    // How serious a match is
+	BuildSystems []string    // Build systems the rule applies to; empty means all
+
+	re *regexp.Regexp
+}
+
+// Match describes the rule that classified some output
+type Match struct {
+	Rule     string      // Name of the matching rule
+	Type     FailureType // Failure type of the rule (may be empty)
+	Severity Severity    // Severity of the rule
+	Line     string      // Output line that matched
+}
+
+// Failed reports whether the match fails the iteration
+func (m *Match) Failed() bool {
+	return m != nil && m.Severity.rank() >= SeverityError.rank()
+}
+
+// DefaultRules are the built-in failure patterns. Build system specific
+// rules come first so their more precise failure types win over the
+// generic keywords at the same severity.
+var DefaultRules = []Rule{
+	// Go
+	{Name: "go-test-fail", Pattern: `^(--- FAIL: |FAIL\s)`, Type: FailureTypeTest, Severity: SeverityError, BuildSystems: []string{"go"}},
+	{Name: "go-build", Pattern: `^\S+\.go:\d+:\d+: `, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"go"}},
+	{Name: "go-vet", Pattern: `^vet: `, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"go"}},
+
+	// Node (pnpm, npm, yarn)
+	{Name: "typescript", Pattern: `error TS\d+:`, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"pnpm", "npm", "yarn"}},
+	{Name: "node-tests", Pattern: `^\s*Tests?:.*\d+ failed|^\s*✕ |\d+ failing$`, Type: FailureTypeTest, Severity: SeverityError, BuildSystems: []string{"pnpm", "npm", "yarn"}},
+	{Name: "npm-error", Pattern: `^npm ERR!|^ERR_PNPM_|^error Command failed`, Type: FailureTypeAgentError, Severity: SeverityError, BuildSystems: []string{"pnpm", "npm", "yarn"}},
+	{Name: "node-deprecated", Pattern: `^npm WARN deprecated`, Severity: SeverityWarning, BuildSystems: []string{"pnpm", "npm", "yarn"}},
+
+	// Python
+	{Name: "pytest", Pattern: `^FAILED |^=+ .*\d+ failed`, Type: FailureTypeTest, Severity: SeverityError, BuildSystems: []string{"python"}},
+	{Name: "python-syntax", Pattern: `^\s*(SyntaxError|IndentationError|ModuleNotFoundError|ImportError): `, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"python"}},
+	{Name: "mypy", Pattern: `^\S+\.py:\d+: error: `, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"python"}},
+
+	// Rust
+	{Name: "cargo-build", Pattern: `^error(\[E\d+\])?: `, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"cargo"}},
+	{Name: "cargo-test", Pattern: `^test result: FAILED`, Type: FailureTypeTest, Severity: SeverityError, BuildSystems: []string{"cargo"}},
+
+	// JVM (gradle, maven)
+	{Name: "jvm-compile", Pattern: `COMPILATION ERROR|^e: |error: cannot find symbol|Compilation failed`, Type: FailureTypeTypeCheck, Severity: SeverityError, BuildSystems: []string{"gradle", "maven"}},
+	{Name: "jvm-tests", Pattern: `Tests run:.*Failures: [1-9]|There were failing tests|\d+ tests completed, \d+ failed`, Type: FailureTypeTest, Severity: SeverityError, BuildSystems: []string{"gradle", "maven"}},
+	{Name: "jvm-build", Pattern: `BUILD FAILURE|BUILD FAILED`, Severity: SeverityError, BuildSystems: []string{"gradle", "maven"}},
+
+	// Problems retrying cannot fix
+	{Name: "disk-full", Pattern: `(?i)no space left on device`, Type: FailureTypeAgentError, Severity: SeverityFatal},
+	{Name: "auth", Pattern: `(?i)(invalid|missing|expired|incorrect) api key`, Type: FailureTypeAgentError, Severity: SeverityFatal},
+
+	// Generic keywords, for any build system
+	{Name: "panic", Pattern: `(?i)panic:`, Severity: SeverityError},
+	{Name: "build-failed", Pattern: `(?i)cannot compile|build failed`, Type: FailureTypeTypeCheck, Severity: SeverityError},
+	{Name: "test-failed", Pattern: `(?i)test failed|assertion failed`, Type: FailureTypeTest, Severity: SeverityError},
+	{Name: "error", Pattern: `(?i)error:`, Severity: SeverityError},
+	{Name: "fail", Pattern: `(?i)fail`, Severity: SeverityError},
+}
+
+// Classifier decides whether output shows a failure, and of what type and
+// severity, by matching it against failure pattern rules
+type Classifier struct {
+	rules []Rule
+}
+
+// NewClassifier creates a classifier with the default rules for the build
+// system and the custom rules, which take precedence over the defaults at the
+// same severity. An empty build system uses only the rules for all build systems.
+func NewClassifier(buildSystem string, custom []Rule) (*Classifier, error) {
+	c := &Classifier{}
+	for _, r := range custom {
+		if err := c.add(r, buildSystem); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range DefaultRules {
+		if err := c.add(r, buildSystem); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// add compiles the rule and adds it if it applies to the build system
+func (c *Classifier) add(r Rule, buildSystem string) error {
+	if len(r.BuildSystems) > 0 {
+		applies := false
+		for _, bs := range r.BuildSystems {
+			if strings.EqualFold(bs, buildSystem) {
+				applies = true
+			}
+		}
+		if !applies {
+			return nil
+		}
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("failure pattern %q has no pattern", r.Name)
+	}
+	re, err := regexp.Compile("(?m)" + r.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid failure pattern %q: %w", r.Name, err)
+	}
+	if r.Severity == "" {
+		r.Severity = SeverityError
+	} else if r.Severity.rank() == 0 {
+		return fmt.Errorf("failure pattern %q: unknown severity %q (valid: warning, error, fatal)", r.Name, r.Severity)
+	}
+	if r.Name == "" {
+		r.Name = r.Pattern
+	}
+	r.re = re
+	c.rules = append(c.rules, r)
+	return nil
+}
+
+// Rules returns the rules in use, in order of precedence
+func (c *Classifier) Rules() []Rule {
+	return c.rules
+}
+
+// Classify returns the most severe match in the output, the first rule
+// winning among equally severe ones, or nil if no rule matches
+func (c *Classifier) Classify(output string) *Match {
+	if c == nil {
+		return nil
+	}
+	var best *Match
+	for _, r := range c.rules {
+		if best != nil && r.Severity.rank() <= best.Severity.rank() {
+			continue
+		}
+		loc := r.re.FindStringIndex(output)
+		if loc == nil {
+			continue
+		}
+		best = &Match{
+			Rule:     r.Name,
+			Type:     r.Type,
+			Severity: r.Severity,
+			Line:     lineAt(output, loc[0]),
+		}
+	}
+	return best
+}
+
+// Failed reports whether the output shows a failure of error severity or worse
+func (c *Classifier) Failed(output string) bool {
+	return c.Classify(output).Failed()
+}
+
+// lineAt returns the trimmed line of s containing offset i
+func lineAt(s string, i int) string {
+	start := strings.LastIndex(s[:i], "\n") + 1
+	end := strings.Index(s[i:], "\n")
+	if end < 0 {
+		end = len(s)
+	} else {
+		end += i
+	}
+	return strings.TrimSpace(s[start:end])
+}
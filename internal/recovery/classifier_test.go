@@ -0,0 +1,137 @@
+package recovery
+
+import "testing"
+
+func TestClassifier_Defaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		buildSystem string
+		output      string
+		wantRule    string
+		wantType    FailureType
+		wantFailed  bool
+	}{
+		{name: "clean output", buildSystem: "go", output: "All done, implemented the feature"},
+		{name: "go test failure", buildSystem: "go", output: "ok  \tpkg/a\n--- FAIL: TestFoo (0.00s)\nFAIL\tpkg/b", wantRule: "go-test-fail", wantType: FailureTypeTest, wantFailed: true},
+		{name: "go compile error", buildSystem: "go", output: "./main.go:12:3: undefined: foo", wantRule: "go-build", wantType: FailureTypeTypeCheck, wantFailed: true},
+		{name: "typescript error", buildSystem: "pnpm", output: "src/app.ts(3,1): error TS2304: Cannot find name 'x'.", wantRule: "typescript", wantType: FailureTypeTypeCheck, wantFailed: true},
+		{name: "cargo test failure", buildSystem: "cargo", output: "test result: FAILED. 1 passed; 1 failed", wantRule: "cargo-test", wantType: FailureTypeTest, wantFailed: true},
+		{name: "other build system rules do not apply", buildSystem: "python", output: "error TS2304: Cannot find name 'x'."},
+		{name: "generic keyword", buildSystem: "", output: "the build failed", wantRule: "build-failed", wantType: FailureTypeTypeCheck, wantFailed: true},
+		{name: "fatal beats error", buildSystem: "go", output: "--- FAIL: TestFoo\nwrite: no space left on device", wantRule: "disk-full", wantType: FailureTypeAgentError, wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClassifier(tt.buildSystem, nil)
+			if err != nil {
+				t.Fatalf("NewClassifier error = %v", err)
+			}
+			m := c.Classify(tt.output)
+			if m.Failed() != tt.wantFailed {
+				t.Fatalf("Failed = %v, want %v (match %+v)", m.Failed(), tt.wantFailed, m)
+			}
+			if !tt.wantFailed {
+				return
+			}
+			if m.Rule != tt.wantRule || m.Type != tt.wantType {
+				t.Errorf("match = %+v, want rule %q type %q", m, tt.wantRule, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestClassifier_Custom(t *testing.T) {
+	c, err := NewClassifier("go", []Rule{
+		{Name: "flaky", Pattern: `^--- FAIL: TestFlaky`, Type: FailureTypeTest, Severity: SeverityWarning},
+		{Name: "migration", Pattern: `(?i)migration .* failed`, Type: FailureTypeTypeCheck},
+		{Name: "cargo-only", Pattern: `oops`, BuildSystems: []string{"cargo"}},
+	})
+	if err != nil {
+		t.Fatalf("NewClassifier error = %v", err)
+	}
+
+	// Custom rules win over the defaults at the same severity
+	m := c.Classify("Migration 042 failed: column exists")
+	if m == nil || m.Rule != "migration" || m.Severity != SeverityError || m.Line != "Migration 042 failed: column exists" {
+		t.Errorf("unexpected match %+v", m)
+	}
+
+	// A more severe default still wins over a warning
+	if m := c.Classify("--- FAIL: TestFlaky (0.01s)"); m == nil || m.Rule != "go-test-fail" {
+		t.Errorf("expected the default rule to fail the output, got %+v", m)
+	}
+
+	// Rules for other build systems are dropped
+	if m := c.Classify("oops"); m != nil {
+		t.Errorf("rule for another build system matched: %+v", m)
+	}
+}
+
+func TestClassifier_Warning(t *testing.T) {
+	c, err := NewClassifier("npm", nil)
+	if err != nil {
+		t.Fatalf("NewClassifier error = %v", err)
+	}
+	m := c.Classify("npm WARN deprecated left-pad@1.0.0")
+	if m == nil || m.Severity != SeverityWarning || m.Failed() {
+		t.Errorf("expected a warning that does not fail, got %+v", m)
+	}
+}
+
+func TestNewClassifier_Invalid(t *testing.T) {
+	for _, rule := range []Rule{
+		{Name: "empty"},
+		{Name: "bad regex", Pattern: "("},
+		{Name: "bad severity", Pattern: "x", Severity: "critical"},
+	} {
+		if _, err := NewClassifier("", []Rule{rule}); err == nil {
+			t.Errorf("NewClassifier should reject rule %q", rule.Name)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Severity
+		wantErr bool
+	}{
+		{"", SeverityError, false},
+		{"Warning", SeverityWarning, false},
+		{"fatal", SeverityFatal, false},
+		{"critical", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSeverity(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSeverity(%q) = %v, %v; want %v, wantErr %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRecoveryManager_Classifier(t *testing.T) {
+	c, err := NewClassifier("go", nil)
+	if err != nil {
+		t.Fatalf("NewClassifier error = %v", err)
+	}
+	rm := NewRecoveryManager(3, StrategyRetry)
+	rm.SetClassifier(c)
+
+	failure, result := rm.HandleFailure("./main.go:3:1: syntax error: unexpected }", 1, 1, 1)
+	if failure == nil || failure.Type != FailureTypeTypeCheck || failure.Rule != "go-build" {
+		t.Fatalf("unexpected failure %+v", failure)
+	}
+	if !result.ShouldRetry {
+		t.Error("error severity failures should be retried")
+	}
+
+	// Fatal failures skip the feature without retrying
+	failure, result = rm.HandleFailure("write /tmp/x: no space left on device", 1, 2, 2)
+	if failure == nil || failure.Severity != SeverityFatal {
+		t.Fatalf("unexpected failure %+v", failure)
+	}
+	if !result.ShouldSkip || result.ShouldRetry {
+		t.Errorf("fatal failures should skip the feature, got %+v", result)
+	}
+}
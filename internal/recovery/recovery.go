@@ -18,12 +18,20 @@ const (
 	FailureTypeTest FailureType = "test_failure"
 	// FailureTypeTypeCheck indicates type check/compilation failures
 	FailureTypeTypeCheck FailureType = "typecheck_failure"
+	// FailureTypeLint indicates lint/static analysis failures
+	FailureTypeLint FailureType = "lint_failure"
 	// FailureTypeAgentError indicates agent execution errors
 	FailureTypeAgentError FailureType = "agent_error"
 	// FailureTypeTimeout indicates timeout failures
 	FailureTypeTimeout FailureType = "timeout"
 )
 
+// TimeoutRetryGuidance is prepended to the prompt when an iteration that timed
+// out is retried immediately
+const TimeoutRetryGuidance = `IMPORTANT: The previous attempt at this iteration timed out and was stopped.
+Be concise: make the smallest change that moves the feature forward, avoid
+long-running commands (watch modes, dev servers, full test suites), and finish promptly.`
+
 // Failure represents a detected failure with context
 type Failure struct {
 	Type        FailureType
@@ -33,6 +41,8 @@ type Failure struct {
 	Iteration   int
 	Timestamp   time.Time
 	RetryCount  int
+	Severity    Severity // Severity of the failure pattern that matched (error if none did)
+	Rule        string   // Name of the failure pattern that matched, if any
 }
 
 // String returns a human-readable representation of the failure
@@ -85,6 +95,28 @@ func (ft *FailureTracker) ResetFeature(featureID int) {
 	ft.retryCounts[featureID] = 0
 }
 
+// CountByType returns the number of tracked failures per failure type
+func (ft *FailureTracker) CountByType() map[FailureType]int {
+	counts := make(map[FailureType]int)
+	for _, failures := range ft.failures {
+		for _, f := range failures {
+			counts[f.Type]++
+		}
+	}
+	return counts
+}
+
+// CountByFeature returns the number of tracked failures per feature
+func (ft *FailureTracker) CountByFeature() map[int]int {
+	counts := make(map[int]int)
+	for featureID, failures := range ft.failures {
+		if len(failures) > 0 {
+			counts[featureID] = len(failures)
+		}
+	}
+	return counts
+}
+
 // GetSummary returns a summary of all tracked failures
 func (ft *FailureTracker) GetSummary() string {
 	if len(ft.failures) == 0 {
@@ -275,6 +307,9 @@ func isTestRelated(output string) bool {
 	return false
 }
 
+// lintIssue matches a linter's report of an issue at a file location
+var lintIssue = regexp.MustCompile(`\S+:\d+(:\d+)?:? `)
+
 // getFailureMessage extracts a meaningful message from the output
 func getFailureMessage(failureType FailureType, output string) string {
 	lines := strings.Split(output, "\n")
@@ -296,6 +331,10 @@ func getFailureMessage(failureType FailureType, output string) string {
 			   strings.Contains(lineLower, "undefined") {
 				return strings.TrimSpace(line)
 			}
+		case FailureTypeLint:
+			if lintIssue.MatchString(line) {
+				return strings.TrimSpace(line)
+			}
 		case FailureTypeTimeout:
 			if strings.Contains(lineLower, "timeout") ||
 			   strings.Contains(lineLower, "deadline") {
@@ -315,6 +354,8 @@ func getFailureMessage(failureType FailureType, output string) string {
 		return "Test execution failed"
 	case FailureTypeTypeCheck:
 		return "Type check/compilation failed"
+	case FailureTypeLint:
+		return "Lint check failed"
 	case FailureTypeTimeout:
 		return "Operation timed out"
 	case FailureTypeAgentError:
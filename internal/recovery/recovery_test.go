@@ -119,6 +119,20 @@ func TestFailureTracker_ResetFeature(t *testing.T) {
 	}
 }
 
+func TestFailureTracker_CountByFeature(t *testing.T) {
+	ft := NewFailureTracker(3)
+
+	ft.RecordFailure(&Failure{FeatureID: 1, Type: FailureTypeTest})
+	ft.RecordFailure(&Failure{FeatureID: 1, Type: FailureTypeLint})
+	ft.RecordFailure(&Failure{FeatureID: 2, Type: FailureTypeTest})
+	ft.ResetFeature(1)
+
+	counts := ft.CountByFeature()
+	if len(counts) != 2 || counts[1] != 2 || counts[2] != 1 {
+		t.Errorf("CountByFeature() = %v, want map[1:2 2:1]", counts)
+	}
+}
+
 func TestFailureTracker_GetSummary(t *testing.T) {
 	ft := NewFailureTracker(3)
 
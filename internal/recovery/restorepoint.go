@@ -0,0 +1,152 @@
+package recovery
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// restorePointPrefix is the git ref namespace holding restore points
+	restorePointPrefix = "refs/ralph/"
+	// BeforeRollbackRef keeps the state from before the last rollback, so it can be recovered
+	BeforeRollbackRef = restorePointPrefix + "before-rollback"
+)
+
+// Restore point kinds
+const (
+	KindFeature   = "features"
+	KindIteration = "iterations"
+)
+
+// RestorePoint describes a snapshot saved in the repository
+type RestorePoint struct {
+	Ref     string    // Git ref holding the snapshot
+	Key     int       // Feature ID or iteration number
+	Label   string    // What the snapshot was taken before
+	Created time.Time // When it was saved
+}
+
+// FeatureRef returns the ref of the restore point taken before a feature was started
+func FeatureRef(id int) string {
+	return fmt.Sprintf("%s%s/%d", restorePointPrefix, KindFeature, id)
+}
+
+// IterationRef returns the ref of the restore point taken before an iteration
+func IterationRef(n int) string {
+	return fmt.Sprintf("%s%s/%d", restorePointPrefix, KindIteration, n)
+}
+
+// Save stores the snapshot in the repository under ref as a commit whose tree
+// is the snapshot's working tree. Its parents are the HEAD at the time (if
+// any) and a commit of the staged contents, as git stash does.
+func (s *Snapshot) Save(ref, label string) error {
+	var indexTree string
+	err := withIndex(s.index, func(env []string) error {
+		var err error
+		indexTree, err = gitEnv(s.root, env, "write-tree")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+
+	var headParent []string
+	if s.head != "" {
+		headParent = []string{"-p", s.head}
+	}
+	// Restore point commits are internal, so they need no user identity
+	env := append(os.Environ(),
+		"GIT_AUTHOR_NAME=ralph", "GIT_AUTHOR_EMAIL=ralph@localhost",
+		"GIT_COMMITTER_NAME=ralph", "GIT_COMMITTER_EMAIL=ralph@localhost")
+	indexCommit, err := gitEnv(s.root, env, append([]string{"commit-tree", indexTree, "-m", "index of " + label}, headParent...)...)
+	if err != nil {
+		return fmt.Errorf("failed to save the index: %w", err)
+	}
+	commit, err := gitEnv(s.root, env, append(append([]string{"commit-tree", s.tree, "-m", label}, headParent...), "-p", indexCommit)...)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	if _, err := gitIn(s.root, "update-ref", ref, commit); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot loads the snapshot saved under ref. Paths in exclude are
+// treated as in TakeSnapshot.
+func LoadSnapshot(ref string, exclude ...string) (*Snapshot, error) {
+	root, err := gitIn("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not in a git repository: %w", err)
+	}
+	line, err := gitIn(root, "rev-list", "--parents", "-n", "1", ref, "--")
+	if err != nil {
+		return nil, fmt.Errorf("no restore point %s", ref)
+	}
+
+	// <commit> [<head>] <index commit>
+	ids := strings.Fields(line)
+	s := &Snapshot{root: root, exclude: repoPaths(root, exclude)}
+	switch len(ids) {
+	case 2:
+	case 3:
+		s.head = ids[1]
+	default:
+		return nil, fmt.Errorf("%s is not a restore point", ref)
+	}
+	if s.tree, err = gitIn(root, "rev-parse", ids[0]+"^{tree}"); err != nil {
+		return nil, err
+	}
+	if s.indexTree, err = gitIn(root, "rev-parse", ids[len(ids)-1]+"^{tree}"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// HasRestorePoint reports whether a restore point exists under ref
+func HasRestorePoint(ref string) bool {
+	_, err := gitIn("", "rev-parse", "--verify", "-q", ref)
+	return err == nil
+}
+
+// ListRestorePoints returns the restore points of a kind, ordered by key
+func ListRestorePoints(kind string) ([]RestorePoint, error) {
+	out, err := gitIn("", "for-each-ref", "--format=%(refname)%09%(committerdate:unix)%09%(subject)", restorePointPrefix+kind+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var points []RestorePoint
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		key, err := strconv.Atoi(fields[0][strings.LastIndex(fields[0], "/")+1:])
+		if err != nil {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[1], 10, 64)
+		points = append(points, RestorePoint{Ref: fields[0], Key: key, Label: fields[2], Created: time.Unix(unix, 0)})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Key < points[j].Key })
+	return points, nil
+}
+
+// DeleteRestorePoints removes all restore points of a kind
+func DeleteRestorePoints(kind string) error {
+	points, err := ListRestorePoints(kind)
+	if err != nil {
+		return err
+	}
+	for _, p := range points {
+		if _, err := gitIn("", "update-ref", "-d", p.Ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package recovery
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRestorePoint_SaveAndRollback(t *testing.T) {
+	dir := initSnapshotRepo(t)
+
+	// State before the iteration: a staged change, an unstaged change and an untracked file
+	os.WriteFile(filepath.Join(dir, "old.go"), []byte("package main // staged\n"), 0644)
+	runGitCmd(t, dir, "add", "old.go")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // wip\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("notes"), 0644)
+	head := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	snap, err := TakeSnapshot(".ralph")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+	if err := snap.Save(IterationRef(1), "before iteration 1"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := snap.Save(FeatureRef(3), "before feature #3"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Later iterations commit, edit, delete and create files
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // agent\n"), 0644)
+	os.Remove(filepath.Join(dir, "notes.md"))
+	runGitCmd(t, dir, "add", "-A")
+	runGitCmd(t, dir, "commit", "-q", "-m", "agent work")
+	os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, ".ralph"), 0755)
+	os.WriteFile(filepath.Join(dir, ".ralph", "state"), []byte("kept"), 0644)
+
+	loaded, err := LoadSnapshot(IterationRef(1), ".ralph")
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if err := loaded.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if got := gitOutput(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want %s", got, head)
+	}
+	if got := readString(t, filepath.Join(dir, "main.go")); got != "package main // wip\n" {
+		t.Errorf("main.go = %q", got)
+	}
+	if got := readString(t, filepath.Join(dir, "notes.md")); got != "notes" {
+		t.Errorf("notes.md = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.go")); !os.IsNotExist(err) {
+		t.Error("new.go should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".ralph", "state")); err != nil {
+		t.Error("excluded .ralph/state should be kept")
+	}
+	if got := gitOutput(t, dir, "status", "--porcelain", "--", ".", ":(exclude).ralph"); got != " M main.go\nM  old.go\n?? notes.md" {
+		t.Errorf("status after rollback:\n%s", got)
+	}
+
+	points, err := ListRestorePoints(KindIteration)
+	if err != nil || len(points) != 1 || points[0].Key != 1 || points[0].Label != "before iteration 1" {
+		t.Fatalf("ListRestorePoints = %+v, %v", points, err)
+	}
+	if !HasRestorePoint(FeatureRef(3)) || HasRestorePoint(FeatureRef(4)) {
+		t.Error("HasRestorePoint reported the wrong restore points")
+	}
+	if err := DeleteRestorePoints(KindIteration); err != nil {
+		t.Fatalf("DeleteRestorePoints failed: %v", err)
+	}
+	if HasRestorePoint(IterationRef(1)) || !HasRestorePoint(FeatureRef(3)) {
+		t.Error("only iteration restore points should be deleted")
+	}
+	if _, err := LoadSnapshot(IterationRef(1)); err == nil {
+		t.Error("loading a deleted restore point should fail")
+	}
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return strings.TrimRight(string(out), "\n")
+}
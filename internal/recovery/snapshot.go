@@ -0,0 +1,301 @@
+package recovery
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Snapshot records the state of a git working tree so that the changes made
+// after it was taken can be shown and rolled back. Unlike the rollback
+// strategy, rolling back a snapshot keeps uncommitted changes that existed
+// before it was taken and removes files created since.
+type Snapshot struct {
+	root  string // Repository root
+	head  string // HEAD commit when taken ("" on an unborn branch)
+	tree  string // Tree object holding the working tree contents (tracked and untracked, not ignored)
+	index []byte // Contents of the index file, nil if there was none
+
+	indexTree string   // Tree of the staged contents, set instead of index for saved restore points
+	exclude   []string // Repository-relative paths left out of the snapshot
+}
+
+// TakeSnapshot snapshots the working tree of the git repository containing
+// the current directory. Nothing in the repository is modified. Paths in
+// exclude (relative to the current directory, or absolute) are neither shown
+// in diffs nor touched by a rollback, e.g. Ralph's own state directories.
+func TakeSnapshot(exclude ...string) (*Snapshot, error) {
+	root, err := gitIn("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	s := &Snapshot{root: root, exclude: repoPaths(root, exclude)}
+	s.head, _ = gitIn(root, "rev-parse", "--verify", "-q", "HEAD")
+	if s.tree, err = s.workingTree(); err != nil {
+		return nil, err
+	}
+	if path, err := s.indexPath(); err == nil {
+		s.index, _ = os.ReadFile(path)
+	}
+	return s, nil
+}
+
+// DiffStat returns a `git diff --stat` summary of the changes since the snapshot
+func (s *Snapshot) DiffStat() (string, error) {
+	return s.diff("--stat")
+}
+
+// ShortStat returns a one-line summary of the changes since the snapshot
+func (s *Snapshot) ShortStat() (string, error) {
+	return s.diff("--shortstat")
+}
+
+// Diff returns the full diff of the changes since the snapshot
+func (s *Snapshot) Diff() (string, error) {
+	return s.diff()
+}
+
+// Patch returns the changes since the snapshot as a patch that git apply can
+// apply or reverse, including binary files
+func (s *Snapshot) Patch() (string, error) {
+	return s.diff("--binary")
+}
+
+// ChangedFiles returns the paths changed since the snapshot
+func (s *Snapshot) ChangedFiles() ([]string, error) {
+	return s.changed("")
+}
+
+// Rollback restores the working tree, index and HEAD to the snapshot:
+// commits made since are undone, changed and deleted files are restored and
+// files created since are removed. Ignored files are left alone.
+func (s *Snapshot) Rollback() error {
+	// Undo commits made since the snapshot, keeping the working tree
+	current, _ := gitIn(s.root, "rev-parse", "--verify", "-q", "HEAD")
+	if current != s.head {
+		var err error
+		if s.head == "" {
+			_, err = gitIn(s.root, "update-ref", "-d", "HEAD")
+		} else {
+			_, err = gitIn(s.root, "reset", "-q", "--soft", s.head)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to reset HEAD: %w", err)
+		}
+	}
+
+	// Remove files created since the snapshot
+	added, err := s.changed("A")
+	if err != nil {
+		return err
+	}
+	for _, path := range added {
+		full := filepath.Join(s.root, path)
+		if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removeEmptyParents(s.root, filepath.Dir(full))
+	}
+
+	// Restore modified and deleted files from the snapshot tree
+	restore, err := s.changed("DMT")
+	if err != nil {
+		return err
+	}
+	if len(restore) > 0 {
+		err := s.withTempIndex(func(env []string) error {
+			if _, err := gitEnv(s.root, env, "read-tree", s.tree); err != nil {
+				return err
+			}
+			_, err := gitEnv(s.root, env, append([]string{"checkout-index", "-f", "--"}, restore...)...)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore files: %w", err)
+		}
+	}
+
+	// Put back what was staged at the time of the snapshot
+	if s.indexTree != "" {
+		if _, err := gitIn(s.root, "read-tree", s.indexTree); err != nil {
+			return fmt.Errorf("failed to restore the index: %w", err)
+		}
+		return nil
+	}
+	indexPath, err := s.indexPath()
+	if err != nil {
+		return err
+	}
+	if s.index == nil {
+		os.Remove(indexPath)
+		return nil
+	}
+	return os.WriteFile(indexPath, s.index, 0644)
+}
+
+// diff runs git diff between the snapshot and the current working tree
+func (s *Snapshot) diff(args ...string) (string, error) {
+	current, err := s.workingTree()
+	if err != nil {
+		return "", err
+	}
+	args = append([]string{"diff", "--no-renames"}, args...)
+	out, err := gitRaw(s.root, nil, append(args, s.tree, current)...)
+	return strings.TrimRight(out, "\n"), err
+}
+
+// changed lists the paths changed since the snapshot, optionally filtered by
+// git diff status letters (e.g., "A" for added)
+func (s *Snapshot) changed(filter string) ([]string, error) {
+	current, err := s.workingTree()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"diff", "--no-renames", "--name-only", "-z"}
+	if filter != "" {
+		args = append(args, "--diff-filter="+filter)
+	}
+	out, err := gitRaw(s.root, nil, append(args, s.tree, current)...)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// workingTree writes the current working tree contents to a tree object
+// using a temporary index, so the real index is not touched
+func (s *Snapshot) workingTree() (string, error) {
+	var tree string
+	err := s.withTempIndex(func(env []string) error {
+		if _, err := gitEnv(s.root, env, append([]string{"add", "-A", "--", "."}, s.pathspec()...)...); err != nil {
+			return err
+		}
+		var err error
+		tree, err = gitEnv(s.root, env, "write-tree")
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %w", err)
+	}
+	return tree, nil
+}
+
+// withTempIndex runs fn with an environment pointing git at a temporary copy
+// of the index
+func (s *Snapshot) withTempIndex(fn func(env []string) error) error {
+	// Start from the real index so unchanged files need not be rehashed
+	var data []byte
+	if path, err := s.indexPath(); err == nil {
+		data, _ = os.ReadFile(path)
+	}
+	return withIndex(data, fn)
+}
+
+// withIndex runs fn with an environment pointing git at a temporary index
+// holding data, or no index at all if data is nil
+func withIndex(data []byte, fn func(env []string) error) error {
+	tmp, err := os.CreateTemp("", "ralph-index-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if data != nil {
+		if err := os.WriteFile(tmp.Name(), data, 0644); err != nil {
+			return err
+		}
+	} else {
+		os.Remove(tmp.Name())
+	}
+
+	return fn(append(os.Environ(), "GIT_INDEX_FILE="+tmp.Name()))
+}
+
+// repoPaths converts paths relative to the current directory, or absolute,
+// to paths relative to the repository root, dropping those outside it
+func repoPaths(root string, paths []string) []string {
+	prefix, _ := gitIn("", "rev-parse", "--show-prefix")
+	var rel []string
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if filepath.IsAbs(path) {
+			r, err := filepath.Rel(root, path)
+			if err != nil || strings.HasPrefix(r, "..") {
+				continue
+			}
+			path = r
+		} else {
+			path = filepath.Join(prefix, path)
+		}
+		rel = append(rel, filepath.ToSlash(path))
+	}
+	return rel
+}
+
+// pathspec returns the pathspecs that leave excluded paths out
+func (s *Snapshot) pathspec() []string {
+	specs := make([]string, len(s.exclude))
+	for i, path := range s.exclude {
+		specs[i] = ":(exclude)" + path
+	}
+	return specs
+}
+
+// indexPath returns the path of the repository's index file
+func (s *Snapshot) indexPath() (string, error) {
+	path, err := gitIn(s.root, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+	return path, nil
+}
+
+// removeEmptyParents removes dir and its parents up to root while they are empty
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// gitIn runs git in dir and returns its trimmed output
+func gitIn(dir string, args ...string) (string, error) {
+	return gitEnv(dir, nil, args...)
+}
+
+// gitEnv runs git in dir with the given environment and returns its trimmed output
+func gitEnv(dir string, env []string, args ...string) (string, error) {
+	out, err := gitRaw(dir, env, args...)
+	return strings.TrimSpace(out), err
+}
+
+// gitRaw runs git in dir with the given environment and returns its output unmodified
+func gitRaw(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,154 @@
+package recovery
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initSnapshotRepo creates a git repository with one committed file and
+// makes it the current directory
+func initSnapshotRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "old.go"), []byte("package main\n"), 0644)
+	for _, args := range [][]string{{"init", "-q"}, {"add", "."}, {"commit", "-q", "-m", "initial"}} {
+		runGitCmd(t, dir, args...)
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func runGitCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestSnapshot_DiffAndRollback(t *testing.T) {
+	dir := initSnapshotRepo(t)
+
+	// Uncommitted work that exists before the snapshot must survive a rollback
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // wip\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("notes"), 0644)
+
+	snap, err := TakeSnapshot()
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+	if files, _ := snap.ChangedFiles(); len(files) != 0 {
+		t.Errorf("expected no changes right after the snapshot, got %v", files)
+	}
+
+	// The iteration edits, creates, deletes and commits files
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // agent\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "pkg", "feature"), 0755)
+	os.WriteFile(filepath.Join(dir, "pkg", "feature", "feature.go"), []byte("package feature\n"), 0644)
+	os.Remove(filepath.Join(dir, "old.go"))
+	runGitCmd(t, dir, "add", "-A")
+	runGitCmd(t, dir, "commit", "-q", "-m", "agent commit")
+
+	stat, err := snap.DiffStat()
+	if err != nil {
+		t.Fatalf("DiffStat failed: %v", err)
+	}
+	for _, want := range []string{"main.go", "pkg/feature/feature.go", "old.go", "3 files changed"} {
+		if !strings.Contains(stat, want) {
+			t.Errorf("diff stat missing %q:\n%s", want, stat)
+		}
+	}
+	if diff, _ := snap.Diff(); !strings.Contains(diff, "+package main // agent") {
+		t.Errorf("full diff missing the change:\n%s", diff)
+	}
+
+	if err := snap.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if got := readString(t, filepath.Join(dir, "main.go")); got != "package main // wip\n" {
+		t.Errorf("main.go = %q, want the pre-snapshot uncommitted version", got)
+	}
+	if got := readString(t, filepath.Join(dir, "notes.md")); got != "notes" {
+		t.Errorf("untracked file changed: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.go")); err != nil {
+		t.Error("deleted file was not restored")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pkg")); !os.IsNotExist(err) {
+		t.Error("created files and directories were not removed")
+	}
+	if log, _ := gitIn(dir, "log", "--oneline"); strings.Contains(log, "agent commit") {
+		t.Errorf("commit made after the snapshot was not undone:\n%s", log)
+	}
+	if files, _ := snap.ChangedFiles(); len(files) != 0 {
+		t.Errorf("expected no changes after rollback, got %v", files)
+	}
+}
+
+func TestSnapshot_Exclude(t *testing.T) {
+	dir := initSnapshotRepo(t)
+
+	snap, err := TakeSnapshot(".ralph")
+	if err != nil {
+		t.Fatalf("TakeSnapshot failed: %v", err)
+	}
+
+	// Ralph's own state written during the iteration is neither reported nor rolled back
+	os.MkdirAll(filepath.Join(dir, ".ralph", "diffs"), 0755)
+	os.WriteFile(filepath.Join(dir, ".ralph", "diffs", "iteration-001.patch"), []byte("patch"), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // agent\n"), 0644)
+
+	files, err := snap.ChangedFiles()
+	if err != nil || strings.Join(files, ",") != "main.go" {
+		t.Errorf("ChangedFiles = %v (%v)", files, err)
+	}
+	if stat, _ := snap.ShortStat(); !strings.Contains(stat, "1 file changed") {
+		t.Errorf("ShortStat = %q", stat)
+	}
+
+	if err := snap.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".ralph", "diffs", "iteration-001.patch")); err != nil {
+		t.Error("excluded file was removed by the rollback")
+	}
+	if got := readString(t, filepath.Join(dir, "main.go")); got != "package main\n" {
+		t.Errorf("main.go not rolled back: %q", got)
+	}
+}
+
+func TestTakeSnapshot_NotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+
+	if _, err := TakeSnapshot(); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // StrategyType represents the type of recovery strategy
@@ -34,11 +35,12 @@ func ParseStrategyType(s string) (StrategyType, error) {
 
 // RecoveryResult represents the result of applying a recovery strategy
 type RecoveryResult struct {
-	Success       bool
-	Message       string
-	ShouldRetry   bool   // Should the feature be retried
-	ShouldSkip    bool   // Should the feature be skipped
-	ModifiedPrompt string // Optional modified prompt for retry
+	Success        bool
+	Message        string
+	ShouldRetry    bool          // Should the feature be retried
+	ShouldSkip     bool          // Should the feature be skipped
+	ModifiedPrompt string        // Optional modified prompt for retry
+	Delay          time.Duration // How long to wait before retrying (backoff)
 }
 
 // RecoveryStrategy defines the interface for recovery strategies
@@ -122,6 +124,15 @@ Please focus on:
 2. Ensure the code compiles cleanly
 3. Check imports and dependencies`, failure.Message)
 
+	case FailureTypeLint:
+		emphasis = fmt.Sprintf(`IMPORTANT: The previous attempt failed the lint check (style/static analysis issues).
+Error: %s
+
+Please focus on:
+1. Fix every issue the linter reports, without changing behavior
+2. Follow the project's existing style and conventions
+3. Do not disable lint rules or add ignore comments to silence them`, failure.Message)
+
 	case FailureTypeTimeout:
 		emphasis = fmt.Sprintf(`IMPORTANT: The previous attempt timed out.
 Error: %s
@@ -289,6 +300,11 @@ type RecoveryManager struct {
 	defaultStrategy  StrategyType
 	strategies       map[StrategyType]RecoveryStrategy
 	maxRetries       int
+	classifier       *Classifier
+	applied          map[StrategyType]int // Number of times each strategy was applied
+	backoff          Backoff
+	retryBudget      int // Maximum retries per run across all features (0 = unlimited)
+	retriesUsed      int
 }
 
 // NewRecoveryManager creates a new recovery manager
@@ -299,6 +315,7 @@ func NewRecoveryManager(maxRetries int, defaultStrategy StrategyType) *RecoveryM
 		tracker:         tracker,
 		defaultStrategy: defaultStrategy,
 		maxRetries:      maxRetries,
+		applied:         make(map[StrategyType]int),
 		strategies: map[StrategyType]RecoveryStrategy{
 			StrategyRetry:    NewRetryStrategy(maxRetries, tracker),
 			StrategySkip:     NewSkipStrategy(tracker),
@@ -312,6 +329,33 @@ func (rm *RecoveryManager) GetTracker() *FailureTracker {
 	return rm.tracker
 }
 
+// SetClassifier sets the failure pattern rules used to refine detected
+// failures. Without one, failures are typed by DetectFailure alone.
+func (rm *RecoveryManager) SetClassifier(c *Classifier) {
+	rm.classifier = c
+}
+
+// SetBackoff sets the delay applied between retries of a feature
+func (rm *RecoveryManager) SetBackoff(b Backoff) {
+	rm.backoff = b
+}
+
+// SetRetryBudget limits the number of retries in a run across all features.
+// Once it is spent, failing features are skipped. 0 means unlimited.
+func (rm *RecoveryManager) SetRetryBudget(budget int) {
+	rm.retryBudget = budget
+}
+
+// RetryBudgetExhausted reports whether the run's retry budget is spent
+func (rm *RecoveryManager) RetryBudgetExhausted() bool {
+	return rm.retryBudget > 0 && rm.retriesUsed >= rm.retryBudget
+}
+
+// RetriesUsed returns the number of retries granted so far in the run
+func (rm *RecoveryManager) RetriesUsed() int {
+	return rm.retriesUsed
+}
+
 // HandleFailure processes a failure and applies the appropriate recovery strategy
 func (rm *RecoveryManager) HandleFailure(output string, exitCode int, featureID, iteration int) (*Failure, RecoveryResult) {
 	// Detect failure
@@ -320,22 +364,76 @@ func (rm *RecoveryManager) HandleFailure(output string, exitCode int, featureID,
 		return nil, RecoveryResult{Success: true, Message: "No failure detected"}
 	}
 
+	// A matching failure pattern gives the type, severity and message
+	failure.Severity = SeverityError
+	if match := rm.classifier.Classify(output); match.Failed() {
+		failure.Severity = match.Severity
+		failure.Rule = match.Rule
+		if match.Type != "" {
+			failure.Type = match.Type
+			failure.Message = match.Line
+		}
+	}
+
+	return failure, rm.handle(failure)
+}
+
+// HandleLintFailure records an iteration whose changes failed the lint step
+// and applies the appropriate recovery strategy. The output is the linter's,
+// so the failure is not classified like agent output.
+func (rm *RecoveryManager) HandleLintFailure(output string, featureID, iteration int) (*Failure, RecoveryResult) {
+	failure := &Failure{
+		Type:      FailureTypeLint,
+		Message:   getFailureMessage(FailureTypeLint, output),
+		FeatureID: featureID,
+		Iteration: iteration,
+		Timestamp: time.Now(),
+		Output:    output,
+		Severity:  SeverityError,
+	}
+	return failure, rm.handle(failure)
+}
+
+// HandleTimeout records an agent execution that was killed after exceeding the
+// iteration timeout and applies the appropriate recovery strategy
+func (rm *RecoveryManager) HandleTimeout(output string, timeout time.Duration, featureID, iteration int) (*Failure, RecoveryResult) {
+	failure := &Failure{
+		Type:      FailureTypeTimeout,
+		Message:   fmt.Sprintf("Agent exceeded the iteration timeout of %s and was stopped", timeout),
+		FeatureID: featureID,
+		Iteration: iteration,
+		Timestamp: time.Now(),
+		Output:    output,
+		Severity:  SeverityError,
+	}
+	return failure, rm.handle(failure)
+}
+
+// handle records a failure and applies the selected recovery strategy
+func (rm *RecoveryManager) handle(failure *Failure) RecoveryResult {
 	// Record the failure
 	rm.tracker.RecordFailure(failure)
 
 	// Select strategy based on failure type and configuration
 	strategy := rm.selectStrategy(failure)
+	rm.applied[strategy.Name()]++
 
 	// Apply the strategy
 	result := strategy.Apply(failure)
-
-	return failure, result
+	if result.ShouldRetry {
+		rm.retriesUsed++
+		result.Delay = rm.backoff.Delay(rm.tracker.GetRetryCount(failure.FeatureID))
+	} else if rm.RetryBudgetExhausted() {
+		result.Message = fmt.Sprintf("Retry budget (%d per run) exhausted. %s", rm.retryBudget, result.Message)
+	}
+	return result
 }
 
 // selectStrategy chooses the appropriate strategy based on failure and config
 func (rm *RecoveryManager) selectStrategy(failure *Failure) RecoveryStrategy {
-	// Check if we've exceeded max retries - force skip
-	if !rm.tracker.CanRetry(failure.FeatureID) {
+	// Check if we've exceeded max retries or the run's retry budget, or
+	// retrying cannot help - force skip
+	if !rm.tracker.CanRetry(failure.FeatureID) || rm.RetryBudgetExhausted() || failure.Severity == SeverityFatal {
 		return rm.strategies[StrategySkip]
 	}
 
@@ -359,6 +457,15 @@ func (rm *RecoveryManager) selectStrategy(failure *Failure) RecoveryStrategy {
 	return rm.strategies[StrategyRetry]
 }
 
+// StrategyUsage returns the number of times each strategy was applied
+func (rm *RecoveryManager) StrategyUsage() map[StrategyType]int {
+	usage := make(map[StrategyType]int, len(rm.applied))
+	for s, n := range rm.applied {
+		usage[s] = n
+	}
+	return usage
+}
+
 // GetFailureSummary returns a summary of all tracked failures
 func (rm *RecoveryManager) GetFailureSummary() string {
 	return rm.tracker.GetSummary()
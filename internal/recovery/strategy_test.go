@@ -3,6 +3,7 @@ package recovery
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseStrategyType(t *testing.T) {
@@ -235,6 +236,46 @@ func TestRecoveryManager_HandleFailure_WithFailure(t *testing.T) {
 	}
 }
 
+func TestRecoveryManager_HandleTimeout(t *testing.T) {
+	rm := NewRecoveryManager(3, StrategyRetry)
+
+	failure, result := rm.HandleTimeout("partial output", 15*time.Minute, 2, 4)
+
+	if failure.Type != FailureTypeTimeout {
+		t.Errorf("failure.Type = %v, want timeout", failure.Type)
+	}
+	if !strings.Contains(failure.Message, "15m0s") {
+		t.Errorf("failure.Message should mention the timeout, got %q", failure.Message)
+	}
+	if !result.ShouldRetry || !strings.Contains(result.ModifiedPrompt, "timed out") {
+		t.Errorf("expected a retry with timeout guidance, got %+v", result)
+	}
+	if rm.GetTracker().GetRetryCount(2) != 1 {
+		t.Error("timeout should be recorded against the feature")
+	}
+}
+
+func TestRecoveryManager_HandleLintFailure(t *testing.T) {
+	rm := NewRecoveryManager(3, StrategyRetry)
+
+	// Linter output looks like compiler output, but must not be classified as such
+	output := "level=info msg=\"running linters\"\nmain.go:12:6: func `unused` is unused (unused)\n1 issues:\n"
+	failure, result := rm.HandleLintFailure(output, 2, 4)
+
+	if failure.Type != FailureTypeLint {
+		t.Errorf("failure.Type = %v, want lint_failure", failure.Type)
+	}
+	if failure.Message != "main.go:12:6: func `unused` is unused (unused)" {
+		t.Errorf("failure.Message = %q, want the first lint issue", failure.Message)
+	}
+	if !result.ShouldRetry || !strings.Contains(result.ModifiedPrompt, "failed the lint check") {
+		t.Errorf("expected a retry with lint guidance, got %+v", result)
+	}
+	if rm.GetTracker().CountByType()[FailureTypeLint] != 1 {
+		t.Error("lint failure should be recorded against the feature")
+	}
+}
+
 func TestRecoveryManager_HandleFailure_SkipStrategy(t *testing.T) {
 	rm := NewRecoveryManager(3, StrategySkip)
 
@@ -330,3 +371,19 @@ func TestRecoveryResult_Fields(t *testing.T) {
 		t.Error("ModifiedPrompt not set correctly")
 	}
 }
+
+func TestRecoveryManager_StrategyUsage(t *testing.T) {
+	rm := NewRecoveryManager(2, StrategyRetry)
+	rm.HandleFailure("FAIL: test error", 1, 1, 1)
+	rm.HandleFailure("FAIL: test error", 1, 1, 2)
+	rm.HandleTimeout("", time.Minute, 2, 3)
+
+	usage := rm.StrategyUsage()
+	if usage[StrategyRetry] != 2 || usage[StrategySkip] != 1 {
+		t.Errorf("StrategyUsage = %v, want 2 retries and 1 skip", usage)
+	}
+	counts := rm.GetTracker().CountByType()
+	if counts[FailureTypeTest] != 2 || counts[FailureTypeTimeout] != 1 {
+		t.Errorf("CountByType = %v", counts)
+	}
+}
@@ -0,0 +1,148 @@
+// Package redact masks secrets such as API keys, tokens, private keys and
+// passwords in text before Ralph sends it to the agent or persists it
+// (progress log, memories, transcripts). Common secret formats are built in;
+// projects can add their own patterns, and known secret values (e.g., the API
+// key of the backend) are masked wherever they appear.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Mask replaces each secret
+const Mask = "[REDACTED]"
+
+// minValueLength is the shortest known secret value that is masked; shorter
+// values would mask ordinary words
+const minValueLength = 8
+
+// rule is a pattern matching a secret. If the pattern has a group named
+// "secret", only that group is masked (e.g., the value of password=...).
+type rule struct {
+	name    string
+	pattern *regexp.Regexp
+	valid   func(secret string) bool // Optional check that a match is a secret
+}
+
+// builtinRules match common secret formats
+var builtinRules = []rule{
+	{name: "private-key", pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{name: "aws-access-key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "github-token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{name: "api-key", pattern: regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{name: "stripe-key", pattern: regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}`)},
+	{name: "slack-token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{name: "google-api-key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "bearer-token", pattern: regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/-]{20,}=*)`)},
+	{name: "url-credentials", pattern: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:(?P<secret>[^\s:/@]+)@`)},
+	// Assignments such as API_KEY=..., "password": "...", client_secret: ...;
+	// the value must mix letters and digits so "token: required" is left alone
+	{name: "assignment", pattern: regexp.MustCompile(`(?i)\b[\w.-]*(?:api[_-]?key|secret|token|passw(?:or)?d|pwd)[\w.-]*["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9_\-+/=.~]{8,})`),
+		valid: hasLetterAndDigit},
+}
+
+// Redactor masks secrets in text. A nil Redactor masks nothing.
+type Redactor struct {
+	rules  []rule
+	values []string
+}
+
+// New creates a redactor for the built-in secret formats and the given
+// regular expressions
+func New(patterns []string) (*Redactor, error) {
+	r := &Redactor{rules: append([]rule(nil), builtinRules...)}
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule{name: fmt.Sprintf("pattern-%d", i+1), pattern: re})
+	}
+	return r, nil
+}
+
+// AddValues masks the given secret values wherever they appear. Values
+// shorter than 8 characters are ignored.
+func (r *Redactor) AddValues(values ...string) {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len(v) >= minValueLength {
+			r.values = append(r.values, v)
+		}
+	}
+	// Longer values first, so a value containing another is masked whole
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+// Redact returns s with every secret replaced by Mask
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, Mask)
+	}
+	for _, rl := range r.rules {
+		s = rl.apply(s)
+	}
+	return s
+}
+
+// Find returns the names of the kinds of secrets found in s, for reporting
+// what was masked without repeating it
+func (r *Redactor) Find(s string) []string {
+	if r == nil || s == "" {
+		return nil
+	}
+	var names []string
+	for _, v := range r.values {
+		if strings.Contains(s, v) {
+			names = append(names, "known-value")
+			break
+		}
+	}
+	for _, rl := range r.rules {
+		if rl.apply(s) != s {
+			names = append(names, rl.name)
+		}
+	}
+	return names
+}
+
+// apply masks the matches of a rule in s
+func (rl rule) apply(s string) string {
+	group := rl.pattern.SubexpIndex("secret")
+	matches := rl.pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if group > 0 {
+			start, end = m[2*group], m[2*group+1]
+			if start < 0 {
+				continue
+			}
+		}
+		if rl.valid != nil && !rl.valid(s[start:end]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Mask)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// hasLetterAndDigit reports whether s contains both a letter and a digit
+func hasLetterAndDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0 && strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
@@ -0,0 +1,197 @@
+package replan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// compressedExt is appended to the names of compressed backups
+const compressedExt = ".gz"
+
+// BackupOptions configures where plan backups are stored and how many are kept
+type BackupOptions struct {
+	Dir         string // Directory for backups; empty keeps them next to the plan file
+	MaxVersions int    // Number of backups to keep, removing the oldest first; 0 keeps all
+	Compress    bool   // Gzip new backups
+}
+
+// backupFile is a backup found on disk
+type backupFile struct {
+	path    string
+	version int
+}
+
+// SetOptions configures where backups are stored and how many are kept.
+// Backups next to the plan file (where they were kept before a backup
+// directory was configured) are moved into the directory, keeping their
+// version numbers unless the directory already has them. Backups beyond
+// MaxVersions are removed, oldest first.
+func (pv *PlanVersioner) SetOptions(options BackupOptions) error {
+	pv.options = options
+	if err := pv.migrateBackups(); err != nil {
+		return err
+	}
+	if err := pv.DiscoverBackups(); err != nil {
+		return err
+	}
+	return pv.prune()
+}
+
+// backupDir returns the directory backups are stored in
+func (pv *PlanVersioner) backupDir() string {
+	if pv.options.Dir != "" {
+		return pv.options.Dir
+	}
+	return filepath.Dir(pv.basePath)
+}
+
+// backupPath returns the path of a version's backup in dir, e.g.
+// plan.bak.3.json (plan.bak.3.json.gz when compressing)
+func (pv *PlanVersioner) backupPath(dir string, version int) string {
+	ext := filepath.Ext(pv.basePath)
+	base := strings.TrimSuffix(filepath.Base(pv.basePath), ext)
+	path := filepath.Join(dir, fmt.Sprintf("%s.bak.%d%s", base, version, ext))
+	if pv.options.Compress {
+		path += compressedExt
+	}
+	return path
+}
+
+// findBackups returns the backups of the plan file in dir, compressed or
+// not, ordered by version
+func (pv *PlanVersioner) findBackups(dir string) ([]backupFile, error) {
+	ext := filepath.Ext(pv.basePath)
+	base := strings.TrimSuffix(filepath.Base(pv.basePath), ext)
+
+	matches, err := filepath.Glob(filepath.Join(dir, base+".bak.*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find backups: %w", err)
+	}
+
+	var backups []backupFile
+	for _, match := range matches {
+		// Extract version number from filename
+		name := strings.TrimSuffix(filepath.Base(match), compressedExt)
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, base+".bak."), ext))
+		if err != nil || version < 1 {
+			continue
+		}
+		backups = append(backups, backupFile{path: match, version: version})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].version < backups[j].version })
+	return backups, nil
+}
+
+// nextVersion returns the number for a new backup
+func (pv *PlanVersioner) nextVersion() int {
+	next := 1
+	for _, v := range pv.versions {
+		if v.Version >= next {
+			next = v.Version + 1
+		}
+	}
+	return next
+}
+
+// migrateBackups moves the backups next to the plan file into the backup
+// directory
+func (pv *PlanVersioner) migrateBackups() error {
+	planDir := filepath.Dir(pv.basePath)
+	if pv.options.Dir == "" || filepath.Clean(pv.options.Dir) == filepath.Clean(planDir) {
+		return nil
+	}
+	legacy, err := pv.findBackups(planDir)
+	if err != nil || len(legacy) == 0 {
+		return err
+	}
+	if err := pv.DiscoverBackups(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(pv.options.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	taken := make(map[int]bool)
+	for _, v := range pv.versions {
+		taken[v.Version] = true
+	}
+	next := pv.nextVersion()
+	for _, b := range legacy {
+		version := b.version
+		if taken[version] {
+			version = next
+			next++
+		}
+		target := filepath.Join(pv.options.Dir, strings.Replace(filepath.Base(b.path),
+			fmt.Sprintf(".bak.%d.", b.version), fmt.Sprintf(".bak.%d.", version), 1))
+		if err := os.Rename(b.path, target); err != nil {
+			return fmt.Errorf("failed to move backup %s: %w", b.path, err)
+		}
+		taken[version] = true
+		if version >= next {
+			next = version + 1
+		}
+	}
+	return nil
+}
+
+// prune removes the oldest backups beyond MaxVersions
+func (pv *PlanVersioner) prune() error {
+	excess := len(pv.versions) - pv.options.MaxVersions
+	if pv.options.MaxVersions <= 0 || excess <= 0 {
+		return nil
+	}
+	for _, v := range pv.versions[:excess] {
+		if err := os.Remove(v.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+	}
+	pv.versions = append([]PlanVersion(nil), pv.versions[excess:]...)
+	return nil
+}
+
+// writeBackup writes a backup, gzipped if compress is set
+func writeBackup(path string, data []byte, compress bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return fmt.Errorf("failed to compress backup: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to compress backup: %w", err)
+		}
+		data = buf.Bytes()
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// readBackup reads a backup, decompressing it if it is gzipped
+func readBackup(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasSuffix(path, compressedExt) {
+		return data, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
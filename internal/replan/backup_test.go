@@ -0,0 +1,105 @@
+package replan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestPlanVersionerOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	planPath := filepath.Join(tmpDir, "plan.json")
+	backupDir := filepath.Join(tmpDir, ".ralph", "plan-versions")
+
+	versioner := NewPlanVersioner(planPath)
+	if err := versioner.SetOptions(BackupOptions{Dir: backupDir, MaxVersions: 2, Compress: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, description := range []string{"First", "Second", "Third"} {
+		if err := plan.WriteFile(planPath, []plan.Plan{{ID: 1, Description: description}}); err != nil {
+			t.Fatal(err)
+		}
+		path, err := versioner.CreateBackup(TriggerManual)
+		if err != nil {
+			t.Fatalf("failed to create backup: %v", err)
+		}
+		if filepath.Dir(path) != backupDir || !strings.HasSuffix(path, ".json.gz") {
+			t.Errorf("backup path = %s", path)
+		}
+	}
+
+	// Only the two newest backups are kept, and numbering continues
+	versions := versioner.GetVersions()
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 3 {
+		t.Fatalf("versions = %+v", versions)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, "plan.bak.1.json.gz")); !os.IsNotExist(err) {
+		t.Error("oldest backup should be removed")
+	}
+
+	// Compressed backups are found again and restored decompressed
+	versioner = NewPlanVersioner(planPath)
+	if err := versioner.SetOptions(BackupOptions{Dir: backupDir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := versioner.RestoreVersion(2); err != nil {
+		t.Fatalf("failed to restore version: %v", err)
+	}
+	restored, err := plan.ReadFile(planPath)
+	if err != nil || restored[0].Description != "Second" {
+		t.Errorf("restored plan = %+v, %v", restored, err)
+	}
+	if err := versioner.RestoreVersion(1); err == nil {
+		t.Error("restoring a removed version should fail")
+	}
+}
+
+func TestPlanVersionerMigratesBackups(t *testing.T) {
+	tmpDir := t.TempDir()
+	planPath := filepath.Join(tmpDir, "plan.json")
+	backupDir := filepath.Join(tmpDir, "versions")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(tmpDir, "plan.bak.1.json"):    `[{"id": 1, "description": "Old one"}]`,
+		filepath.Join(tmpDir, "plan.bak.2.json"):    `[{"id": 1, "description": "Old two"}]`,
+		filepath.Join(tmpDir, "other.bak.1.json"):   `[]`,
+		filepath.Join(backupDir, "plan.bak.2.json"): `[{"id": 1, "description": "New two"}]`,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versioner := NewPlanVersioner(planPath)
+	if err := versioner.SetOptions(BackupOptions{Dir: backupDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The colliding backup gets the next free version number
+	want := map[int]string{1: "Old one", 2: "New two", 3: "Old two"}
+	versions := versioner.GetVersions()
+	if len(versions) != len(want) {
+		t.Fatalf("versions = %+v", versions)
+	}
+	for _, v := range versions {
+		if filepath.Dir(v.Path) != backupDir {
+			t.Errorf("version %d not migrated: %s", v.Version, v.Path)
+		}
+		data, _ := os.ReadFile(v.Path)
+		if !strings.Contains(string(data), want[v.Version]) {
+			t.Errorf("version %d = %s, want %q", v.Version, data, want[v.Version])
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(tmpDir, "plan.bak.*")); len(matches) != 0 {
+		t.Errorf("backups left next to the plan: %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "other.bak.1.json")); err != nil {
+		t.Error("backups of other plans should be left alone")
+	}
+}
@@ -8,11 +8,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/internal/filelock"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 // TriggerType represents the type of condition that triggered replanning
@@ -29,6 +28,12 @@ const (
 	TriggerBlockedFeature TriggerType = "blocked_feature"
 	// TriggerManual indicates manually triggered replanning
 	TriggerManual TriggerType = "manual"
+	// TriggerDeadlinePressure indicates replanning because the deadline will
+	// be missed at the current velocity
+	TriggerDeadlinePressure TriggerType = "deadline_pressure"
+	// TriggerScopeExplosion indicates replanning because a feature used far
+	// more iterations than estimated
+	TriggerScopeExplosion TriggerType = "scope_explosion"
 )
 
 // ReplanTrigger defines the interface for conditions that trigger replanning
@@ -59,6 +64,24 @@ type ReplanState struct {
 	TotalIterations int
 	// Plans contains the current plan data
 	Plans []plan.Plan
+	// FeatureIterations is the number of iterations spent on the current feature
+	FeatureIterations int
+	// EstimatedIterations is the number of iterations the current feature was
+	// estimated to need (0 if unknown)
+	EstimatedIterations int
+	// RemainingFeatures is the number of features left to implement
+	RemainingFeatures int
+	// FeatureDuration is the average time to complete a feature at the current
+	// velocity (0 if unknown)
+	FeatureDuration time.Duration
+	// TimeRemaining is the time left until the deadline (0 if there is none)
+	TimeRemaining time.Duration
+}
+
+// ProjectedDuration returns how long the remaining features take at the
+// current velocity, or 0 if the velocity is unknown
+func (s *ReplanState) ProjectedDuration() time.Duration {
+	return s.FeatureDuration * time.Duration(s.RemainingFeatures)
 }
 
 // TestFailureTrigger triggers replanning when tests fail repeatedly
@@ -142,6 +165,79 @@ func (t *BlockedFeatureTrigger) Check(state *ReplanState) bool {
 	return len(state.BlockedFeatures) >= t.MinBlocked
 }
 
+// DeadlinePressureTrigger triggers replanning when the remaining features
+// will not be done before the deadline at the current velocity. It fires once
+// per number of remaining features, so completing a feature while still
+// behind schedule triggers it again.
+type DeadlinePressureTrigger struct {
+	firedAt int // RemainingFeatures when the trigger last fired
+}
+
+// NewDeadlinePressureTrigger creates a new deadline pressure trigger
+func NewDeadlinePressureTrigger() *DeadlinePressureTrigger {
+	return &DeadlinePressureTrigger{}
+}
+
+// Name returns the trigger name
+func (t *DeadlinePressureTrigger) Name() TriggerType {
+	return TriggerDeadlinePressure
+}
+
+// Description returns a human-readable description
+func (t *DeadlinePressureTrigger) Description() string {
+	return "Trigger replanning when the deadline will be missed at the current velocity"
+}
+
+// Check evaluates if the trigger condition is met
+func (t *DeadlinePressureTrigger) Check(state *ReplanState) bool {
+	if state.TimeRemaining <= 0 || state.FeatureDuration <= 0 || state.RemainingFeatures == 0 {
+		return false
+	}
+	if state.ProjectedDuration() <= state.TimeRemaining || state.RemainingFeatures == t.firedAt {
+		return false
+	}
+	t.firedAt = state.RemainingFeatures
+	return true
+}
+
+// ScopeExplosionTrigger triggers replanning when the current feature has used
+// more than Factor times its estimated iterations. It fires once per feature.
+type ScopeExplosionTrigger struct {
+	// Factor is how many times the estimate a feature may use
+	Factor float64
+	fired  map[int]bool
+}
+
+// NewScopeExplosionTrigger creates a new scope explosion trigger
+func NewScopeExplosionTrigger(factor float64) *ScopeExplosionTrigger {
+	if factor <= 0 {
+		factor = 2 // default
+	}
+	return &ScopeExplosionTrigger{Factor: factor, fired: make(map[int]bool)}
+}
+
+// Name returns the trigger name
+func (t *ScopeExplosionTrigger) Name() TriggerType {
+	return TriggerScopeExplosion
+}
+
+// Description returns a human-readable description
+func (t *ScopeExplosionTrigger) Description() string {
+	return fmt.Sprintf("Trigger replanning when a feature uses more than %gx its estimated iterations", t.Factor)
+}
+
+// Check evaluates if the trigger condition is met
+func (t *ScopeExplosionTrigger) Check(state *ReplanState) bool {
+	if state.FeatureID <= 0 || state.EstimatedIterations <= 0 || t.fired[state.FeatureID] {
+		return false
+	}
+	if float64(state.FeatureIterations) <= t.Factor*float64(state.EstimatedIterations) {
+		return false
+	}
+	t.fired[state.FeatureID] = true
+	return true
+}
+
 // ManualTrigger is activated explicitly by user request
 type ManualTrigger struct {
 	triggered bool
@@ -190,9 +286,11 @@ type PlanVersion struct {
 type PlanVersioner struct {
 	basePath string
 	versions []PlanVersion
+	options  BackupOptions
 }
 
-// NewPlanVersioner creates a new plan versioner
+// NewPlanVersioner creates a new plan versioner. Backups are kept next to
+// the plan file until SetOptions configures otherwise.
 func NewPlanVersioner(planPath string) *PlanVersioner {
 	return &PlanVersioner{
 		basePath: planPath,
@@ -219,16 +317,12 @@ func (pv *PlanVersioner) CreateBackup(trigger TriggerType) (string, error) {
 	}
 
 	// Determine next version number
-	nextVersion := len(pv.versions) + 1
-
-	// Create backup path
-	ext := filepath.Ext(pv.basePath)
-	base := strings.TrimSuffix(pv.basePath, ext)
-	backupPath := fmt.Sprintf("%s.bak.%d%s", base, nextVersion, ext)
+	nextVersion := pv.nextVersion()
 
 	// Write backup
-	if err := os.WriteFile(backupPath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write backup: %w", err)
+	backupPath := pv.backupPath(pv.backupDir(), nextVersion)
+	if err := writeBackup(backupPath, data, pv.options.Compress); err != nil {
+		return "", err
 	}
 
 	// Record version
@@ -241,10 +335,13 @@ func (pv *PlanVersioner) CreateBackup(trigger TriggerType) (string, error) {
 	}
 	pv.versions = append(pv.versions, version)
 
+	if err := pv.prune(); err != nil {
+		return "", err
+	}
 	return backupPath, nil
 }
 
-// GetVersions returns all recorded versions
+// GetVersions returns all recorded versions, oldest first
 func (pv *PlanVersioner) GetVersions() []PlanVersion {
 	return pv.versions
 }
@@ -259,58 +356,57 @@ func (pv *PlanVersioner) GetLatestVersion() *PlanVersion {
 
 // RestoreVersion restores a specific version
 func (pv *PlanVersioner) RestoreVersion(version int) error {
-	if version < 1 || version > len(pv.versions) {
+	var v *PlanVersion
+	for i := range pv.versions {
+		if pv.versions[i].Version == version {
+			v = &pv.versions[i]
+			break
+		}
+	}
+	if v == nil {
 		return fmt.Errorf("invalid version number: %d", version)
 	}
 
-	v := pv.versions[version-1]
-	data, err := os.ReadFile(v.Path)
+	data, err := readBackup(v.Path)
 	if err != nil {
 		return fmt.Errorf("failed to read backup: %w", err)
 	}
 
-	if err := os.WriteFile(pv.basePath, data, 0644); err != nil {
+	err = filelock.With(pv.basePath, func() error {
+		return os.WriteFile(pv.basePath, data, 0644)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to restore plan: %w", err)
 	}
 
 	return nil
 }
 
-// DiscoverBackups finds existing backup files
+// DiscoverBackups finds existing backup files in the backup directory,
+// replacing the versions recorded so far
 func (pv *PlanVersioner) DiscoverBackups() error {
-	ext := filepath.Ext(pv.basePath)
-	base := strings.TrimSuffix(pv.basePath, ext)
-
-	// Find all matching backup files
-	pattern := fmt.Sprintf("%s.bak.*%s", base, ext)
-	matches, err := filepath.Glob(pattern)
+	matches, err := pv.findBackups(pv.backupDir())
 	if err != nil {
-		return fmt.Errorf("failed to find backups: %w", err)
+		return err
 	}
 
+	pv.versions = make([]PlanVersion, 0, len(matches))
 	for _, match := range matches {
-		// Extract version number from filename
-		var version int
-		_, err := fmt.Sscanf(filepath.Base(match), filepath.Base(base)+".bak.%d"+ext, &version)
-		if err != nil {
-			continue
-		}
-
 		// Read file for hash
-		data, err := os.ReadFile(match)
+		data, err := readBackup(match.path)
 		if err != nil {
 			continue
 		}
 
-		info, err := os.Stat(match)
+		info, err := os.Stat(match.path)
 		if err != nil {
 			continue
 		}
 
 		pv.versions = append(pv.versions, PlanVersion{
-			Version:   version,
+			Version:   match.version,
 			Timestamp: info.ModTime(),
-			Path:      match,
+			Path:      match.path,
 			Hash:      fmt.Sprintf("%x", md5.Sum(data)),
 		})
 	}
@@ -318,182 +414,6 @@ func (pv *PlanVersioner) DiscoverBackups() error {
 	return nil
 }
 
-// PlanDiff represents changes between two plan versions
-type PlanDiff struct {
-	Added    []plan.Plan `json:"added"`
-	Removed  []plan.Plan `json:"removed"`
-	Modified []PlanChange `json:"modified"`
-}
-
-// PlanChange represents a modification to a plan
-type PlanChange struct {
-	ID          int    `json:"id"`
-	Field       string `json:"field"`
-	OldValue    string `json:"old_value"`
-	NewValue    string `json:"new_value"`
-	Description string `json:"description,omitempty"`
-}
-
-// ComputeDiff computes the difference between two plan lists
-func ComputeDiff(oldPlans, newPlans []plan.Plan) *PlanDiff {
-	diff := &PlanDiff{
-		Added:    make([]plan.Plan, 0),
-		Removed:  make([]plan.Plan, 0),
-		Modified: make([]PlanChange, 0),
-	}
-
-	// Create maps for lookup
-	oldMap := make(map[int]plan.Plan)
-	newMap := make(map[int]plan.Plan)
-
-	for _, p := range oldPlans {
-		oldMap[p.ID] = p
-	}
-	for _, p := range newPlans {
-		newMap[p.ID] = p
-	}
-
-	// Find added and modified
-	for _, newP := range newPlans {
-		oldP, exists := oldMap[newP.ID]
-		if !exists {
-			diff.Added = append(diff.Added, newP)
-		} else {
-			// Check for modifications
-			changes := comparePlans(oldP, newP)
-			diff.Modified = append(diff.Modified, changes...)
-		}
-	}
-
-	// Find removed
-	for _, oldP := range oldPlans {
-		if _, exists := newMap[oldP.ID]; !exists {
-			diff.Removed = append(diff.Removed, oldP)
-		}
-	}
-
-	return diff
-}
-
-// comparePlans compares two plans and returns the changes
-func comparePlans(old, new plan.Plan) []PlanChange {
-	var changes []PlanChange
-
-	if old.Description != new.Description {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "description",
-			OldValue: old.Description,
-			NewValue: new.Description,
-		})
-	}
-
-	if old.Category != new.Category {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "category",
-			OldValue: old.Category,
-			NewValue: new.Category,
-		})
-	}
-
-	if old.Tested != new.Tested {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "tested",
-			OldValue: fmt.Sprintf("%v", old.Tested),
-			NewValue: fmt.Sprintf("%v", new.Tested),
-		})
-	}
-
-	if old.Deferred != new.Deferred {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "deferred",
-			OldValue: fmt.Sprintf("%v", old.Deferred),
-			NewValue: fmt.Sprintf("%v", new.Deferred),
-		})
-	}
-
-	// Compare steps
-	oldSteps := strings.Join(old.Steps, "|")
-	newSteps := strings.Join(new.Steps, "|")
-	if oldSteps != newSteps {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "steps",
-			OldValue: fmt.Sprintf("%d steps", len(old.Steps)),
-			NewValue: fmt.Sprintf("%d steps", len(new.Steps)),
-		})
-	}
-
-	if old.ExpectedOutput != new.ExpectedOutput {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "expected_output",
-			OldValue: truncate(old.ExpectedOutput, 50),
-			NewValue: truncate(new.ExpectedOutput, 50),
-		})
-	}
-
-	if old.Milestone != new.Milestone {
-		changes = append(changes, PlanChange{
-			ID:       old.ID,
-			Field:    "milestone",
-			OldValue: old.Milestone,
-			NewValue: new.Milestone,
-		})
-	}
-
-	return changes
-}
-
-// truncate shortens a string to the specified length
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
-
-// IsEmpty returns true if there are no changes
-func (d *PlanDiff) IsEmpty() bool {
-	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
-}
-
-// Summary returns a human-readable summary of the diff
-func (d *PlanDiff) Summary() string {
-	if d.IsEmpty() {
-		return "No changes detected"
-	}
-
-	var sb strings.Builder
-	sb.WriteString("Plan Changes:\n")
-
-	if len(d.Added) > 0 {
-		sb.WriteString(fmt.Sprintf("  + Added: %d feature(s)\n", len(d.Added)))
-		for _, p := range d.Added {
-			sb.WriteString(fmt.Sprintf("    - #%d: %s\n", p.ID, truncate(p.Description, 60)))
-		}
-	}
-
-	if len(d.Removed) > 0 {
-		sb.WriteString(fmt.Sprintf("  - Removed: %d feature(s)\n", len(d.Removed)))
-		for _, p := range d.Removed {
-			sb.WriteString(fmt.Sprintf("    - #%d: %s\n", p.ID, truncate(p.Description, 60)))
-		}
-	}
-
-	if len(d.Modified) > 0 {
-		sb.WriteString(fmt.Sprintf("  ~ Modified: %d change(s)\n", len(d.Modified)))
-		for _, c := range d.Modified {
-			sb.WriteString(fmt.Sprintf("    - #%d.%s: %s -> %s\n", c.ID, c.Field, c.OldValue, c.NewValue))
-		}
-	}
-
-	return sb.String()
-}
-
 // CalculatePlanHash computes a hash of the plan file content
 func CalculatePlanHash(planPath string) (string, error) {
 	data, err := os.ReadFile(planPath)
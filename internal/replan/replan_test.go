@@ -6,7 +6,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 func TestTestFailureTrigger(t *testing.T) {
@@ -164,6 +164,66 @@ func TestBlockedFeatureTrigger(t *testing.T) {
 	}
 }
 
+func TestDeadlinePressureTrigger(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    ReplanState
+		expected bool
+	}{
+		{"no deadline", ReplanState{RemainingFeatures: 5, FeatureDuration: time.Hour}, false},
+		{"velocity unknown", ReplanState{RemainingFeatures: 5, TimeRemaining: time.Hour}, false},
+		{"nothing left", ReplanState{FeatureDuration: time.Hour, TimeRemaining: time.Hour}, false},
+		{"on schedule", ReplanState{RemainingFeatures: 2, FeatureDuration: time.Hour, TimeRemaining: 3 * time.Hour}, false},
+		{"behind schedule", ReplanState{RemainingFeatures: 4, FeatureDuration: time.Hour, TimeRemaining: 3 * time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := NewDeadlinePressureTrigger()
+			if got := trigger.Check(&tt.state); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	// Fires once per number of remaining features
+	trigger := NewDeadlinePressureTrigger()
+	state := &ReplanState{RemainingFeatures: 4, FeatureDuration: time.Hour, TimeRemaining: 3 * time.Hour}
+	if !trigger.Check(state) || trigger.Check(state) {
+		t.Error("trigger should fire once while nothing changes")
+	}
+	state.RemainingFeatures = 3
+	state.TimeRemaining = 2 * time.Hour
+	if !trigger.Check(state) {
+		t.Error("trigger should fire again when still behind after completing a feature")
+	}
+}
+
+func TestScopeExplosionTrigger(t *testing.T) {
+	trigger := NewScopeExplosionTrigger(0)
+	if trigger.Factor != 2 {
+		t.Errorf("expected default factor 2, got %v", trigger.Factor)
+	}
+
+	state := &ReplanState{FeatureID: 1, FeatureIterations: 6, EstimatedIterations: 3}
+	if trigger.Check(state) {
+		t.Error("trigger should not fire at exactly twice the estimate")
+	}
+	state.FeatureIterations = 7
+	if !trigger.Check(state) {
+		t.Error("trigger should fire above twice the estimate")
+	}
+	if trigger.Check(state) {
+		t.Error("trigger should fire once per feature")
+	}
+	if trigger.Check(&ReplanState{FeatureID: 2, FeatureIterations: 7}) {
+		t.Error("trigger should not fire without an estimate")
+	}
+	if !trigger.Check(&ReplanState{FeatureID: 2, FeatureIterations: 11, EstimatedIterations: 5}) {
+		t.Error("trigger should fire for another feature")
+	}
+}
+
 func TestManualTrigger(t *testing.T) {
 	trigger := NewManualTrigger()
 	state := &ReplanState{}
@@ -278,84 +338,6 @@ func TestPlanVersioner(t *testing.T) {
 	}
 }
 
-func TestComputeDiff(t *testing.T) {
-	oldPlans := []plan.Plan{
-		{ID: 1, Description: "Feature A", Category: "infra"},
-		{ID: 2, Description: "Feature B", Category: "chore"},
-		{ID: 3, Description: "Feature C", Tested: false},
-	}
-
-	newPlans := []plan.Plan{
-		{ID: 1, Description: "Feature A Modified", Category: "infra"},
-		{ID: 2, Description: "Feature B", Category: "data"},
-		{ID: 4, Description: "Feature D", Category: "new"},
-	}
-
-	diff := ComputeDiff(oldPlans, newPlans)
-
-	// Check added
-	if len(diff.Added) != 1 {
-		t.Errorf("expected 1 added, got %d", len(diff.Added))
-	}
-	if diff.Added[0].ID != 4 {
-		t.Error("expected feature 4 to be added")
-	}
-
-	// Check removed
-	if len(diff.Removed) != 1 {
-		t.Errorf("expected 1 removed, got %d", len(diff.Removed))
-	}
-	if diff.Removed[0].ID != 3 {
-		t.Error("expected feature 3 to be removed")
-	}
-
-	// Check modified (should have changes for features 1 and 2)
-	if len(diff.Modified) < 2 {
-		t.Errorf("expected at least 2 modifications, got %d", len(diff.Modified))
-	}
-
-	// Check IsEmpty
-	if diff.IsEmpty() {
-		t.Error("diff should not be empty")
-	}
-
-	// Check empty diff
-	emptyDiff := ComputeDiff(oldPlans, oldPlans)
-	if !emptyDiff.IsEmpty() {
-		t.Error("diff of same plans should be empty")
-	}
-}
-
-func TestPlanDiffSummary(t *testing.T) {
-	diff := &PlanDiff{
-		Added: []plan.Plan{
-			{ID: 4, Description: "New feature"},
-		},
-		Removed: []plan.Plan{
-			{ID: 3, Description: "Old feature"},
-		},
-		Modified: []PlanChange{
-			{ID: 1, Field: "description", OldValue: "old", NewValue: "new"},
-		},
-	}
-
-	summary := diff.Summary()
-	if summary == "" {
-		t.Error("summary should not be empty")
-	}
-
-	// Check that summary contains expected elements
-	if !containsString(summary, "Added: 1") {
-		t.Error("summary should mention added features")
-	}
-	if !containsString(summary, "Removed: 1") {
-		t.Error("summary should mention removed features")
-	}
-	if !containsString(summary, "Modified: 1") {
-		t.Error("summary should mention modified features")
-	}
-}
-
 func TestCalculatePlanHash(t *testing.T) {
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "replan_test")
@@ -430,26 +412,6 @@ func TestCalculatePlansHash(t *testing.T) {
 	}
 }
 
-func TestTruncate(t *testing.T) {
-	tests := []struct {
-		input    string
-		maxLen   int
-		expected string
-	}{
-		{"short", 10, "short"},
-		{"exactly10!", 10, "exactly10!"},
-		{"this is a long string", 10, "this is..."},
-		{"", 10, ""},
-	}
-
-	for _, tt := range tests {
-		result := truncate(tt.input, tt.maxLen)
-		if result != tt.expected {
-			t.Errorf("truncate(%q, %d) = %q, want %q", tt.input, tt.maxLen, result, tt.expected)
-		}
-	}
-}
-
 func TestTriggerDescriptions(t *testing.T) {
 	triggers := []ReplanTrigger{
 		NewTestFailureTrigger(3),
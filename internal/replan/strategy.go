@@ -6,7 +6,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 // StrategyType represents the type of replanning strategy
@@ -43,7 +43,7 @@ type ReplanResult struct {
 	Strategy    StrategyType `json:"strategy"`
 	OldPlanPath string      `json:"old_plan_path,omitempty"`
 	NewPlans    []plan.Plan `json:"new_plans,omitempty"`
-	Diff        *PlanDiff   `json:"diff,omitempty"`
+	Diff        *plan.Diff   `json:"diff,omitempty"`
 	Timestamp   time.Time   `json:"timestamp"`
 }
 
@@ -107,12 +107,20 @@ func (s *IncrementalStrategy) Execute(state *ReplanState, trigger TriggerType) (
 		// For requirement changes, validate and reconcile
 		adjustments = s.handleRequirementChange(newPlans, state)
 
+	case TriggerScopeExplosion:
+		// For features far over their estimate, flag them for breaking down
+		adjustments = s.handleScopeExplosion(newPlans, state)
+
+	case TriggerDeadlinePressure:
+		// For a deadline that will be missed, defer what does not fit
+		adjustments = s.handleDeadlinePressure(newPlans, state)
+
 	default:
 		adjustments = append(adjustments, "General plan validation performed")
 	}
 
 	// Calculate diff
-	diff := ComputeDiff(state.Plans, newPlans)
+	diff := plan.ComputeDiff(state.Plans, newPlans)
 
 	return &ReplanResult{
 		Success:   true,
@@ -173,7 +181,7 @@ func (s *IncrementalStrategy) handleBlockedFeature(plans []plan.Plan, state *Rep
 
 	// Find next viable feature
 	for _, p := range plans {
-		if !p.Tested && !p.Deferred {
+		if p.IsActionable() {
 			adjustments = append(adjustments,
 				fmt.Sprintf("Next feature to work on: #%d", p.ID))
 			break
@@ -209,6 +217,67 @@ func (s *IncrementalStrategy) handleRequirementChange(plans []plan.Plan, state *
 	return adjustments
 }
 
+// handleScopeExplosion handles replanning due to a feature using far more
+// iterations than estimated
+func (s *IncrementalStrategy) handleScopeExplosion(plans []plan.Plan, state *ReplanState) []string {
+	var adjustments []string
+
+	for i := range plans {
+		if plans[i].ID == state.FeatureID && !plans[i].Tested && !plans[i].Deferred {
+			adjustments = append(adjustments,
+				fmt.Sprintf("Feature #%d used %d iterations (estimated %d) - consider breaking it into smaller tasks",
+					plans[i].ID, state.FeatureIterations, state.EstimatedIterations))
+
+			if !strings.Contains(plans[i].Description, "[REQUIRES REVIEW]") {
+				plans[i].Description = plans[i].Description + " [REQUIRES REVIEW: Scope exceeded estimate]"
+				adjustments = append(adjustments,
+					fmt.Sprintf("Marked feature #%d for review", plans[i].ID))
+			}
+			break
+		}
+	}
+
+	return adjustments
+}
+
+// handleDeadlinePressure handles replanning due to a deadline that will be
+// missed at the current velocity. The features that fit in the remaining
+// time are kept in plan order, starting with the current feature; the rest
+// are deferred.
+func (s *IncrementalStrategy) handleDeadlinePressure(plans []plan.Plan, state *ReplanState) []string {
+	var adjustments []string
+	if state.FeatureDuration <= 0 {
+		return append(adjustments, "Velocity unknown - no features deferred")
+	}
+
+	fits := int(state.TimeRemaining / state.FeatureDuration)
+	if fits < 1 {
+		fits = 1 // Always keep the feature being worked on
+	}
+	adjustments = append(adjustments,
+		fmt.Sprintf("%d of %d remaining features fit before the deadline", min(fits, state.RemainingFeatures), state.RemainingFeatures))
+
+	kept := 0
+	if current := plan.GetByID(plans, state.FeatureID); current != nil && current.IsActionable() {
+		kept++
+	}
+	for i := range plans {
+		if !plans[i].IsActionable() || plans[i].ID == state.FeatureID {
+			continue
+		}
+		if kept < fits {
+			kept++
+			continue
+		}
+		plans[i].Deferred = true
+		plans[i].DeferReason = string(TriggerDeadlinePressure)
+		adjustments = append(adjustments,
+			fmt.Sprintf("Deferred feature #%d", plans[i].ID))
+	}
+
+	return adjustments
+}
+
 // identifyPotentialPrerequisites looks for features that might be prerequisites
 func (s *IncrementalStrategy) identifyPotentialPrerequisites(plans []plan.Plan, currentID int) []string {
 	var adjustments []string
@@ -308,7 +377,7 @@ func (s *AgentBasedStrategy) Execute(state *ReplanState, trigger TriggerType) (*
 	}
 
 	// Calculate diff
-	diff := ComputeDiff(state.Plans, newPlans)
+	diff := plan.ComputeDiff(state.Plans, newPlans)
 
 	return &ReplanResult{
 		Success:   true,
@@ -365,6 +434,18 @@ func (s *AgentBasedStrategy) buildReplanPrompt(state *ReplanState, trigger Trigg
 		sb.WriteString("1. Validate the updated plan for consistency\n")
 		sb.WriteString("2. Suggest any necessary adjustments\n")
 		sb.WriteString("3. Identify any new dependencies\n")
+	case TriggerScopeExplosion:
+		sb.WriteString(fmt.Sprintf("Feature #%d has used %d iterations against an estimate of %d. Please suggest:\n",
+			state.FeatureID, state.FeatureIterations, state.EstimatedIterations))
+		sb.WriteString("1. How to break the feature into smaller features\n")
+		sb.WriteString("2. Whether parts of it should be deferred\n")
+		sb.WriteString("3. An updated plan with the smaller features\n")
+	case TriggerDeadlinePressure:
+		sb.WriteString(fmt.Sprintf("At the current velocity the %d remaining features need %s, but only %s is left before the deadline. Please suggest:\n",
+			state.RemainingFeatures, state.ProjectedDuration().Round(time.Minute), state.TimeRemaining.Round(time.Minute)))
+		sb.WriteString("1. Which features matter most and must be kept\n")
+		sb.WriteString("2. Which features should be deferred (set \"deferred\": true)\n")
+		sb.WriteString("3. Features whose scope can be reduced\n")
 	default:
 		sb.WriteString("Please analyze the current state and suggest improvements to the plan.\n")
 	}
@@ -410,6 +491,10 @@ func parseJSON(s string, v interface{}) error {
 	return nil // Will be replaced with actual implementation
 }
 
+// ConfirmFunc decides whether a replan is applied. It is given the result of
+// the strategy, including the diff to the current plan.
+type ConfirmFunc func(result *ReplanResult) bool
+
 // ReplanManager orchestrates the replanning process
 type ReplanManager struct {
 	triggers   []ReplanTrigger
@@ -418,6 +503,11 @@ type ReplanManager struct {
 	planPath   string
 	state      *ReplanState
 	autoReplan bool
+
+	// Features in these categories may not be changed by replanning
+	protectedCategories []string
+	// Asked before a replan overwrites the plan, if set
+	confirm ConfirmFunc
 }
 
 // NewReplanManager creates a new replan manager
@@ -439,6 +529,8 @@ func NewReplanManager(planPath string, agentCmd string, autoReplan bool) *Replan
 		NewTestFailureTrigger(3),
 		NewRequirementChangeTrigger(),
 		NewBlockedFeatureTrigger(1),
+		NewScopeExplosionTrigger(2),
+		NewDeadlinePressureTrigger(),
 	)
 
 	// Register default strategies
@@ -451,6 +543,75 @@ func NewReplanManager(planPath string, agentCmd string, autoReplan bool) *Replan
 	return rm
 }
 
+// SetFailureThreshold sets the number of consecutive failures that triggers
+// replanning (values below 1 keep the default of 3)
+func (rm *ReplanManager) SetFailureThreshold(threshold int) {
+	for _, t := range rm.triggers {
+		if trigger, ok := t.(*TestFailureTrigger); ok && threshold > 0 {
+			trigger.Threshold = threshold
+		}
+	}
+}
+
+// SetBackupOptions configures where plan backups are stored and how many
+// are kept (see PlanVersioner.SetOptions)
+func (rm *ReplanManager) SetBackupOptions(options BackupOptions) error {
+	return rm.versioner.SetOptions(options)
+}
+
+// SetProtectedCategories prevents replanning from adding, removing or
+// modifying features in the given plan categories, such as categories that
+// require human review under a policy file
+func (rm *ReplanManager) SetProtectedCategories(categories []string) {
+	rm.protectedCategories = categories
+}
+
+// SetConfirm makes replans that change the plan ask confirm before the plan
+// is overwritten; a replan that is not confirmed is not applied. Nil applies
+// replans without asking.
+func (rm *ReplanManager) SetConfirm(confirm ConfirmFunc) {
+	rm.confirm = confirm
+}
+
+// protectedChange describes the first change to a protected feature in
+// newPlans, or returns empty string if there is none
+func (rm *ReplanManager) protectedChange(newPlans []plan.Plan) string {
+	if len(rm.protectedCategories) == 0 {
+		return ""
+	}
+	protected := func(category string) bool {
+		for _, c := range rm.protectedCategories {
+			if strings.EqualFold(c, category) {
+				return true
+			}
+		}
+		return false
+	}
+
+	diff := plan.ComputeDiff(rm.state.Plans, newPlans)
+	for _, p := range diff.Added {
+		if protected(p.Category) {
+			return fmt.Sprintf("would add feature #%d in protected category %q", p.ID, p.Category)
+		}
+	}
+	for _, p := range diff.Removed {
+		if protected(p.Category) {
+			return fmt.Sprintf("would remove feature #%d in protected category %q", p.ID, p.Category)
+		}
+	}
+	for _, change := range diff.Modified {
+		for _, p := range rm.state.Plans {
+			if p.ID == change.ID && protected(p.Category) {
+				return fmt.Sprintf("would modify feature #%d in protected category %q", p.ID, p.Category)
+			}
+		}
+		if change.Field == "category" && protected(change.NewValue) {
+			return fmt.Sprintf("would move feature #%d into protected category %q", change.ID, change.NewValue)
+		}
+	}
+	return ""
+}
+
 // UpdateState updates the replan state with current information
 func (rm *ReplanManager) UpdateState(featureID int, consecutiveFailures int, failureTypes []string, plans []plan.Plan) {
 	// Save old hash
@@ -481,6 +642,33 @@ func (rm *ReplanManager) ClearBlockedFeatures() {
 	rm.state.BlockedFeatures = make([]int, 0)
 }
 
+// Progress describes how far a run has got, for the proactive triggers
+// (deadline pressure and scope explosion)
+type Progress struct {
+	FeatureID           int           // Feature being worked on
+	FeatureIterations   int           // Iterations spent on the feature
+	EstimatedIterations int           // Iterations the feature was estimated to need
+	FeatureDuration     time.Duration // Average time to complete a feature (0 if unknown)
+	TimeRemaining       time.Duration // Time left until the deadline (0 if there is none)
+}
+
+// UpdateProgress records the progress of the run and the current plans.
+// Unlike UpdateState it does not touch the failure count or the plan hash.
+func (rm *ReplanManager) UpdateProgress(progress Progress, plans []plan.Plan) {
+	rm.state.FeatureID = progress.FeatureID
+	rm.state.FeatureIterations = progress.FeatureIterations
+	rm.state.EstimatedIterations = progress.EstimatedIterations
+	rm.state.FeatureDuration = progress.FeatureDuration
+	rm.state.TimeRemaining = progress.TimeRemaining
+	rm.state.Plans = plans
+	rm.state.RemainingFeatures = 0
+	for _, p := range plans {
+		if p.IsActionable() {
+			rm.state.RemainingFeatures++
+		}
+	}
+}
+
 // IncrementIterations increments the iteration counter
 func (rm *ReplanManager) IncrementIterations() {
 	rm.state.TotalIterations++
@@ -505,6 +693,21 @@ func (rm *ReplanManager) ShouldReplan() (bool, TriggerType) {
 	return rm.autoReplan, trigger
 }
 
+// ShouldReplanProactively is ShouldReplan for iterations that did not fail:
+// only the triggers that look ahead (deadline pressure and scope explosion)
+// are checked
+func (rm *ReplanManager) ShouldReplanProactively() (bool, TriggerType) {
+	for _, trigger := range rm.triggers {
+		switch trigger.Name() {
+		case TriggerDeadlinePressure, TriggerScopeExplosion:
+			if trigger.Check(rm.state) {
+				return rm.autoReplan, trigger.Name()
+			}
+		}
+	}
+	return false, TriggerNone
+}
+
 // ExecuteReplan performs replanning with the specified strategy
 func (rm *ReplanManager) ExecuteReplan(strategyType StrategyType, trigger TriggerType) (*ReplanResult, error) {
 	// Create backup before replanning
@@ -527,6 +730,27 @@ func (rm *ReplanManager) ExecuteReplan(strategyType StrategyType, trigger Trigge
 
 	result.OldPlanPath = backupPath
 
+	// Replanning must leave protected features alone
+	if result.Success && len(result.NewPlans) > 0 {
+		if reason := rm.protectedChange(result.NewPlans); reason != "" {
+			result.Success = false
+			result.Message = fmt.Sprintf("replan not applied: %s", reason)
+			return result, nil
+		}
+	}
+
+	// Changes to the plan are only applied once confirmed
+	if result.Success && len(result.NewPlans) > 0 && rm.confirm != nil {
+		if result.Diff == nil {
+			result.Diff = plan.ComputeDiff(rm.state.Plans, result.NewPlans)
+		}
+		if !result.Diff.IsEmpty() && !rm.confirm(result) {
+			result.Success = false
+			result.Message = "replan not applied: not confirmed"
+			return result, nil
+		}
+	}
+
 	// If successful and we have new plans, write them
 	if result.Success && len(result.NewPlans) > 0 {
 		if err := plan.WriteFile(rm.planPath, result.NewPlans); err != nil {
@@ -3,9 +3,11 @@ package replan
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 func TestParseStrategyType(t *testing.T) {
@@ -347,6 +349,25 @@ func TestReplanManagerShouldReplan(t *testing.T) {
 	}
 }
 
+func TestReplanManagerSetFailureThreshold(t *testing.T) {
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	testPlan := []plan.Plan{{ID: 1, Description: "Feature A"}}
+	if err := plan.WriteFile(planPath, testPlan); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := NewReplanManager(planPath, "test-agent", true)
+	mgr.UpdateState(1, 2, []string{"test_failure"}, testPlan)
+	if shouldReplan, _ := mgr.ShouldReplan(); shouldReplan {
+		t.Error("should not replan below the default threshold of 3")
+	}
+
+	mgr.SetFailureThreshold(2)
+	if shouldReplan, trigger := mgr.ShouldReplan(); !shouldReplan || trigger != TriggerTestFailure {
+		t.Errorf("ShouldReplan() = %v, %v; want test failure trigger at threshold 2", shouldReplan, trigger)
+	}
+}
+
 func TestReplanManagerExecuteReplan(t *testing.T) {
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "replan_test")
@@ -391,6 +412,176 @@ func TestReplanManagerExecuteReplan(t *testing.T) {
 	}
 }
 
+func TestReplanManagerProtectedCategories(t *testing.T) {
+	tmpDir := t.TempDir()
+	planPath := filepath.Join(tmpDir, "plan.json")
+	testPlan := []plan.Plan{
+		{ID: 1, Category: "security", Description: "Auth hardening"},
+		{ID: 2, Category: "ui", Description: "Settings page"},
+	}
+	if err := plan.WriteFile(planPath, testPlan); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := NewReplanManager(planPath, "test-agent", true)
+	mgr.SetProtectedCategories([]string{"Security"})
+	mgr.AddBlockedFeature(1)
+	mgr.UpdateState(1, 0, nil, testPlan)
+
+	// Deferring the blocked security feature would change a protected feature
+	result, err := mgr.ExecuteReplan(StrategyIncremental, TriggerBlockedFeature)
+	if err != nil {
+		t.Fatalf("replan failed: %v", err)
+	}
+	if result.Success {
+		t.Fatal("replan changing a protected feature should not be applied")
+	}
+	if !strings.Contains(result.Message, "feature #1") {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+
+	plans, err := plan.ReadFile(planPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plans[0].Deferred {
+		t.Error("protected feature was changed on disk")
+	}
+}
+
+func TestReplanManagerConfirm(t *testing.T) {
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	testPlan := []plan.Plan{
+		{ID: 1, Description: "Feature A"},
+		{ID: 2, Description: "Feature B"},
+	}
+	if err := plan.WriteFile(planPath, testPlan); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := NewReplanManager(planPath, "test-agent", true)
+	var shown *plan.Diff
+	mgr.SetConfirm(func(result *ReplanResult) bool {
+		shown = result.Diff
+		return false
+	})
+	mgr.AddBlockedFeature(1)
+	mgr.UpdateState(1, 0, nil, testPlan)
+
+	// A declined replan leaves the plan alone
+	result, err := mgr.ExecuteReplan(StrategyIncremental, TriggerBlockedFeature)
+	if err != nil {
+		t.Fatalf("replan failed: %v", err)
+	}
+	if result.Success || !strings.Contains(result.Message, "not confirmed") {
+		t.Errorf("declined replan = %+v", result)
+	}
+	if shown == nil || shown.IsEmpty() {
+		t.Fatal("confirmation was not shown the diff")
+	}
+	if plans, _ := plan.ReadFile(planPath); plans[0].Deferred {
+		t.Error("declined replan changed the plan on disk")
+	}
+
+	// A confirmed one is applied
+	mgr.SetConfirm(func(*ReplanResult) bool { return true })
+	if result, err = mgr.ExecuteReplan(StrategyIncremental, TriggerBlockedFeature); err != nil || !result.Success {
+		t.Fatalf("confirmed replan = %+v, %v", result, err)
+	}
+	if plans, _ := plan.ReadFile(planPath); !plans[0].Deferred {
+		t.Error("confirmed replan was not written")
+	}
+}
+
+func TestIncrementalStrategyProactiveTriggers(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Description: "Setup", Tested: true},
+		{ID: 2, Description: "Login"},
+		{ID: 3, Description: "Profile"},
+		{ID: 4, Description: "Search"},
+		{ID: 5, Description: "Export"},
+	}
+	strategy := NewIncrementalStrategy()
+
+	// The current feature and the next one fit; the rest are deferred
+	state := &ReplanState{
+		FeatureID:         3,
+		Plans:             plans,
+		RemainingFeatures: 4,
+		FeatureDuration:   time.Hour,
+		TimeRemaining:     150 * time.Minute,
+	}
+	result, err := strategy.Execute(state, TriggerDeadlinePressure)
+	if err != nil || !result.Success {
+		t.Fatalf("deadline replan = %+v, %v", result, err)
+	}
+	for _, p := range result.NewPlans {
+		wantDeferred := p.ID == 4 || p.ID == 5
+		if p.Deferred != wantDeferred {
+			t.Errorf("feature #%d deferred = %v, want %v", p.ID, p.Deferred, wantDeferred)
+		}
+		if p.Deferred && p.DeferReason != string(TriggerDeadlinePressure) {
+			t.Errorf("feature #%d defer reason = %q", p.ID, p.DeferReason)
+		}
+	}
+	if plans[3].Deferred {
+		t.Error("strategy modified the original plans")
+	}
+
+	state = &ReplanState{FeatureID: 2, Plans: plans, FeatureIterations: 11, EstimatedIterations: 5}
+	result, err = strategy.Execute(state, TriggerScopeExplosion)
+	if err != nil || !result.Success {
+		t.Fatalf("scope replan = %+v, %v", result, err)
+	}
+	if !strings.Contains(result.NewPlans[1].Description, "[REQUIRES REVIEW") {
+		t.Errorf("exploded feature = %q", result.NewPlans[1].Description)
+	}
+	if !strings.Contains(result.Message, "used 11 iterations (estimated 5)") {
+		t.Errorf("message = %q", result.Message)
+	}
+}
+
+func TestReplanManagerShouldReplanProactively(t *testing.T) {
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	testPlan := []plan.Plan{
+		{ID: 1, Description: "Feature A", Tested: true},
+		{ID: 2, Description: "Feature B"},
+		{ID: 3, Description: "Feature C", Deferred: true},
+		{ID: 4, Description: "Feature D"},
+	}
+	if err := plan.WriteFile(planPath, testPlan); err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewReplanManager(planPath, "test-agent", true)
+
+	// Blocked features only trigger replanning after a failure
+	mgr.AddBlockedFeature(2)
+	mgr.UpdateProgress(Progress{FeatureID: 2, FeatureIterations: 1, EstimatedIterations: 3}, testPlan)
+	if should, trigger := mgr.ShouldReplanProactively(); should {
+		t.Errorf("unexpected proactive trigger %v", trigger)
+	}
+	if mgr.GetState().RemainingFeatures != 2 {
+		t.Errorf("expected 2 remaining features, got %d", mgr.GetState().RemainingFeatures)
+	}
+	mgr.ClearBlockedFeatures()
+
+	mgr.UpdateProgress(Progress{FeatureID: 2, FeatureIterations: 7, EstimatedIterations: 3}, testPlan)
+	if should, trigger := mgr.ShouldReplanProactively(); !should || trigger != TriggerScopeExplosion {
+		t.Errorf("expected scope explosion, got %v, %v", should, trigger)
+	}
+
+	mgr.UpdateProgress(Progress{FeatureID: 2, FeatureIterations: 8, EstimatedIterations: 3, FeatureDuration: time.Hour, TimeRemaining: time.Hour}, testPlan)
+	if should, trigger := mgr.ShouldReplanProactively(); !should || trigger != TriggerDeadlinePressure {
+		t.Errorf("expected deadline pressure, got %v, %v", should, trigger)
+	}
+
+	mgr.SetAutoReplan(false)
+	mgr.UpdateProgress(Progress{FeatureID: 4, FeatureIterations: 20, EstimatedIterations: 3}, testPlan)
+	if should, trigger := mgr.ShouldReplanProactively(); should || trigger != TriggerScopeExplosion {
+		t.Errorf("expected trigger without replanning when auto-replan is off, got %v, %v", should, trigger)
+	}
+}
+
 func TestReplanManagerManualReplan(t *testing.T) {
 	// Create temp directory
 	tmpDir, err := os.MkdirTemp("", "replan_test")
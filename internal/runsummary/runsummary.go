@@ -0,0 +1,207 @@
+// Package runsummary renders the end-of-run summary as Markdown, for pasting
+// into a pull request description or a chat message (-summary-markdown).
+package runsummary
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/pkg/milestone"
+	"github.com/logimos/ralph/pkg/plan"
+	"github.com/logimos/ralph/pkg/validation"
+)
+
+// Summary is everything the Markdown summary of a run is built from
+type Summary struct {
+	Run         *history.Run                     // The finalized run record
+	Plans       []plan.Plan                      // The plan at the end of the run
+	Errors      []string                         // Failures encountered during the run
+	Validations []validation.ValidationRunResult // Validations run for completed features
+	Milestones  []*milestone.Progress            // Milestone progress at the end of the run
+}
+
+// Markdown renders the summary
+func (s *Summary) Markdown() string {
+	r := s.Run
+	var b strings.Builder
+
+	status := "Stopped"
+	if r.Completed {
+		status = "Completed"
+	}
+	fmt.Fprintf(&b, "## Ralph run summary\n\n")
+	fmt.Fprintf(&b, "**%s**: %d feature(s) completed in %d of %d iteration(s) (%s)\n\n",
+		status, len(r.FeaturesCompleted), r.IterationsRun, r.IterationsLimit, formatDuration(r.Duration()))
+
+	remaining := 0
+	for _, p := range s.Plans {
+		if p.IsActionable() {
+			remaining++
+		}
+	}
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Run | %s |\n", cell(r.DisplayName()))
+	if r.Agent != "" {
+		fmt.Fprintf(&b, "| Agent | %s |\n", cell(r.Agent))
+	}
+	if len(r.IterationAgents) > 0 {
+		fmt.Fprintf(&b, "| Served by | %s |\n", cell(servedBy(r.IterationAgents)))
+	}
+	fmt.Fprintf(&b, "| Iterations | %d of %d |\n", r.IterationsRun, r.IterationsLimit)
+	fmt.Fprintf(&b, "| Features completed | %d |\n", len(r.FeaturesCompleted))
+	fmt.Fprintf(&b, "| Features remaining | %d |\n", remaining)
+	if r.FeaturesSkipped > 0 {
+		fmt.Fprintf(&b, "| Features deferred | %d |\n", r.FeaturesSkipped)
+	}
+	fmt.Fprintf(&b, "| Failures | %d (%d recovered) |\n", r.Failures, r.FailuresRecovered)
+	if r.Cost > 0 {
+		fmt.Fprintf(&b, "| Estimated cost | $%.2f |\n", r.Cost)
+	}
+	fmt.Fprintf(&b, "| Duration | %s |\n", formatDuration(r.Duration()))
+
+	if len(r.FeaturesCompleted) > 0 {
+		b.WriteString("\n### Features completed\n\n")
+		for _, id := range sortedIDs(r.FeaturesCompleted) {
+			fmt.Fprintf(&b, "- [x] %s", s.feature(id))
+			if n := r.IterationsPerFeature[id]; n > 0 {
+				fmt.Fprintf(&b, " (%d iteration(s))", n)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(s.Validations) > 0 {
+		b.WriteString("\n### Validation\n\n")
+		validations := append([]validation.ValidationRunResult(nil), s.Validations...)
+		sort.SliceStable(validations, func(i, j int) bool { return validations[i].FeatureID < validations[j].FeatureID })
+		for _, v := range validations {
+			mark := "✅"
+			if !v.Success {
+				mark = "❌"
+			}
+			fmt.Fprintf(&b, "- %s %s: %d/%d passed\n", mark, s.feature(v.FeatureID), v.PassedCount, v.TotalCount)
+			for _, result := range v.Results {
+				if !result.Success {
+					fmt.Fprintf(&b, "  - %s\n", oneLine(result.Message))
+				}
+			}
+		}
+	}
+
+	if len(s.Errors) > 0 {
+		b.WriteString("\n### Failures\n\n")
+		for _, e := range s.Errors {
+			fmt.Fprintf(&b, "- %s\n", oneLine(e))
+		}
+	}
+
+	var deferred []plan.Plan
+	for _, p := range s.Plans {
+		if p.Deferred {
+			deferred = append(deferred, p)
+		}
+	}
+	if len(deferred) > 0 {
+		b.WriteString("\n### Deferred\n\n")
+		for _, p := range deferred {
+			fmt.Fprintf(&b, "- #%d %s", p.ID, p.Description)
+			if p.DeferReason != "" {
+				fmt.Fprintf(&b, " (%s)", p.DeferReason)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(s.Milestones) > 0 {
+		b.WriteString("\n### Milestones\n\n")
+		for _, m := range s.Milestones {
+			mark := " "
+			if m.Status == milestone.StatusComplete {
+				mark = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s: %d/%d features (%.0f%%)",
+				mark, m.Milestone.Name, m.CompletedFeatures, m.TotalFeatures, m.Percentage)
+			if m.Schedule != nil {
+				fmt.Fprintf(&b, " - %s", m.Schedule)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// Write writes the Markdown summary to path, creating its directory
+func (s *Summary) Write(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create summary directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(s.Markdown()), 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown summary: %w", err)
+	}
+	return nil
+}
+
+// feature describes a feature by ID and, if it is still in the plan, description
+func (s *Summary) feature(id int) string {
+	if p := plan.GetByID(s.Plans, id); p != nil && p.Description != "" {
+		return fmt.Sprintf("#%d %s", id, oneLine(p.Description))
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+// servedBy lists the agents that served the iterations of a run, with the
+// iterations each served, in the order the agents first served
+func servedBy(iterationAgents map[int]string) string {
+	iterations := make([]int, 0, len(iterationAgents))
+	for i := range iterationAgents {
+		iterations = append(iterations, i)
+	}
+	sort.Ints(iterations)
+
+	var agents []string
+	served := make(map[string][]string)
+	for _, i := range iterations {
+		name := iterationAgents[i]
+		if served[name] == nil {
+			agents = append(agents, name)
+		}
+		served[name] = append(served[name], fmt.Sprintf("%d", i))
+	}
+	parts := make([]string, len(agents))
+	for j, name := range agents {
+		parts[j] = fmt.Sprintf("%s (iteration(s) %s)", name, strings.Join(served[name], ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// sortedIDs returns a sorted copy of ids
+func sortedIDs(ids []int) []int {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+// oneLine joins the lines of s, so it fits in a list item
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cell escapes s for a table cell
+func cell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", `\|`)
+}
+
+// formatDuration formats a duration for humans (e.g., 12m30s)
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
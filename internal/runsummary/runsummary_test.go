@@ -0,0 +1,101 @@
+package runsummary
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/pkg/milestone"
+	"github.com/logimos/ralph/pkg/plan"
+	"github.com/logimos/ralph/pkg/validation"
+)
+
+func testSummary() *Summary {
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	return &Summary{
+		Run: &history.Run{
+			ID:                   "run-20261016-120000",
+			Label:                "nightly | main",
+			Agent:                "claude",
+			StartTime:            start,
+			EndTime:              start.Add(12*time.Minute + 30*time.Second),
+			IterationsLimit:      10,
+			IterationsRun:        7,
+			FeaturesCompleted:    []int{3, 1},
+			FeaturesSkipped:      1,
+			Failures:             2,
+			FailuresRecovered:    1,
+			IterationsPerFeature: map[int]int{1: 2, 3: 4},
+			IterationAgents:      map[int]string{1: "claude", 2: "aider", 3: "claude"},
+		},
+		Plans: []plan.Plan{
+			{ID: 1, Description: "Set up project", Tested: true, Milestone: "MVP"},
+			{ID: 2, Description: "Export", Deferred: true, DeferReason: "scope_exceeded"},
+			{ID: 3, Description: "Health endpoint", Tested: true, Milestone: "MVP"},
+			{ID: 4, Description: "Import", Milestone: "MVP"},
+		},
+		Errors: []string{"test_failure: 2 tests failed\nin pkg/api"},
+		Validations: []validation.ValidationRunResult{
+			{FeatureID: 3, Success: false, TotalCount: 2, PassedCount: 1, FailedCount: 1, Results: []validation.ValidationResult{
+				{Success: true, Message: "GET /health returned 200"},
+				{Success: false, Message: "GET /ready returned 500"},
+			}},
+			{FeatureID: 1, Success: true, TotalCount: 1, PassedCount: 1},
+		},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	s := testSummary()
+	s.Milestones = milestone.NewManager(s.Plans).CalculateAllProgress()
+	md := s.Markdown()
+
+	for _, want := range []string{
+		"**Stopped**: 2 feature(s) completed in 7 of 10 iteration(s) (12m30s)",
+		`| Run | nightly \| main |`,
+		"| Features remaining | 1 |",
+		"| Failures | 2 (1 recovered) |",
+		"| Served by | claude (iteration(s) 1, 3); aider (iteration(s) 2) |",
+		"- [x] #1 Set up project (2 iteration(s))\n- [x] #3 Health endpoint (4 iteration(s))",
+		"- ✅ #1 Set up project: 1/1 passed\n- ❌ #3 Health endpoint: 1/2 passed\n  - GET /ready returned 500\n",
+		"- test_failure: 2 tests failed in pkg/api",
+		"- #2 Export (scope_exceeded)",
+		"- [ ] MVP: 2/3 features (67%)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Estimated cost") {
+		t.Errorf("Markdown() shows a cost the backend did not report:\n%s", md)
+	}
+}
+
+func TestMarkdownMinimal(t *testing.T) {
+	s := &Summary{Run: &history.Run{ID: "run-1", Completed: true, IterationsLimit: 3, IterationsRun: 1,
+		StartTime: time.Now(), EndTime: time.Now()}}
+	md := s.Markdown()
+	if !strings.HasPrefix(md, "## Ralph run summary\n\n**Completed**") || strings.Contains(md, "Served by") {
+		t.Errorf("Markdown() = %q", md)
+	}
+	for _, section := range []string{"### Features completed", "### Validation", "### Failures", "### Deferred", "### Milestones"} {
+		if strings.Contains(md, section) {
+			t.Errorf("Markdown() has empty section %q", section)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "summary.md")
+	s := testSummary()
+	if err := s.Write(path); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != s.Markdown() {
+		t.Errorf("written summary = %q, %v", data, err)
+	}
+}
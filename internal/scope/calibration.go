@@ -0,0 +1,70 @@
+package scope
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MinCalibrationSamples is the number of completed features of a complexity
+// level needed before the iterations they took replace the built-in estimate
+const MinCalibrationSamples = 3
+
+// Calibration records how many iterations completed features of each
+// complexity level actually took, so estimates follow how the project and
+// agent perform rather than the built-in defaults
+type Calibration struct {
+	samples map[Complexity][]int
+}
+
+// NewCalibration creates an empty calibration
+func NewCalibration() *Calibration {
+	return &Calibration{samples: make(map[Complexity][]int)}
+}
+
+// Add records that a completed feature of the given complexity took
+// iterations iterations
+func (c *Calibration) Add(complexity Complexity, iterations int) {
+	if iterations > 0 {
+		c.samples[complexity] = append(c.samples[complexity], iterations)
+	}
+}
+
+// Samples returns the number of features recorded for a complexity level
+func (c *Calibration) Samples(complexity Complexity) int {
+	return len(c.samples[complexity])
+}
+
+// Calibrated reports whether enough features were recorded for a complexity
+// level to replace the built-in estimate
+func (c *Calibration) Calibrated(complexity Complexity) bool {
+	return c != nil && c.Samples(complexity) >= MinCalibrationSamples
+}
+
+// Iterations returns the estimated iterations for a complexity level: the
+// average iterations of the recorded features, rounded up, once the level is
+// calibrated, ComplexityToIterations otherwise
+func (c *Calibration) Iterations(complexity Complexity) int {
+	if !c.Calibrated(complexity) {
+		return ComplexityToIterations(complexity)
+	}
+	total := 0
+	for _, n := range c.samples[complexity] {
+		total += n
+	}
+	samples := len(c.samples[complexity])
+	return (total + samples - 1) / samples
+}
+
+// Summary describes the estimate of each complexity level, e.g.
+// "low: 2 (5 features), medium: 5 (default), high: 14 (3 features)"
+func (c *Calibration) Summary() string {
+	var parts []string
+	for _, complexity := range []Complexity{ComplexityLow, ComplexityMedium, ComplexityHigh} {
+		source := "default"
+		if c.Calibrated(complexity) {
+			source = fmt.Sprintf("%d features", c.Samples(complexity))
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d (%s)", complexity, c.Iterations(complexity), source))
+	}
+	return strings.Join(parts, ", ")
+}
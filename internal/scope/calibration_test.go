@@ -0,0 +1,92 @@
+package scope
+
+import "testing"
+
+func TestCalibrationIterations(t *testing.T) {
+	c := NewCalibration()
+	c.Add(ComplexityHigh, 2)
+	c.Add(ComplexityHigh, 3)
+	c.Add(ComplexityLow, 0) // Ignored
+	if c.Calibrated(ComplexityHigh) || c.Iterations(ComplexityHigh) != 10 {
+		t.Errorf("with %d samples, estimate = %d, want the default 10", c.Samples(ComplexityHigh), c.Iterations(ComplexityHigh))
+	}
+
+	c.Add(ComplexityHigh, 3)
+	if !c.Calibrated(ComplexityHigh) || c.Iterations(ComplexityHigh) != 3 {
+		t.Errorf("calibrated estimate = %d, want 3 (8/3 rounded up)", c.Iterations(ComplexityHigh))
+	}
+	if c.Iterations(ComplexityLow) != 3 || c.Samples(ComplexityLow) != 0 {
+		t.Errorf("uncalibrated low estimate = %d", c.Iterations(ComplexityLow))
+	}
+
+	want := "low: 3 (default), medium: 5 (default), high: 3 (3 features)"
+	if got := c.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+
+	// A nil calibration uses the built-in estimates
+	var none *Calibration
+	if none.Calibrated(ComplexityMedium) || none.Iterations(ComplexityMedium) != 5 {
+		t.Error("nil calibration should use the defaults")
+	}
+}
+
+func TestManagerCalibration(t *testing.T) {
+	slow := NewCalibration()
+	fast := NewCalibration()
+	for i := 0; i < MinCalibrationSamples; i++ {
+		slow.Add(ComplexityLow, 8)
+		fast.Add(ComplexityHigh, 2)
+	}
+
+	t.Run("estimates", func(t *testing.T) {
+		m := NewManager(nil)
+		m.SetCalibration(slow)
+		m.StartFeature(1, 1, "Add a flag")
+		m.StartFeature(2, 4, "Add a report")
+		if got := m.EstimatedIterations(1); got != 8 {
+			t.Errorf("calibrated estimate = %d, want 8", got)
+		}
+		if got := m.EstimatedIterations(2); got != 5 {
+			t.Errorf("uncalibrated estimate = %d, want 5", got)
+		}
+		if got := m.EstimatedIterations(99); got != 0 {
+			t.Errorf("estimate of an unknown feature = %d, want 0", got)
+		}
+	})
+
+	t.Run("limit raised to the estimate", func(t *testing.T) {
+		m := NewManager(&Constraints{MaxIterationsPerFeature: 5})
+		m.SetCalibration(slow)
+		m.StartFeature(1, 1, "Add a flag")
+		for i := 0; i < 5; i++ {
+			m.RecordIteration(1)
+		}
+		if deferred, _ := m.ShouldDefer(1); deferred {
+			t.Error("should not defer before the calibrated estimate")
+		}
+		if got := m.RemainingIterations(1); got != 3 {
+			t.Errorf("RemainingIterations() = %d, want 3", got)
+		}
+		for i := 0; i < 3; i++ {
+			m.RecordIteration(1)
+		}
+		if deferred, reason := m.ShouldDefer(1); !deferred || reason != DeferReasonIterationLimit {
+			t.Error("should defer at the calibrated estimate")
+		}
+	})
+
+	t.Run("simplification once the estimate is used", func(t *testing.T) {
+		m := NewManager(nil)
+		m.SetCalibration(fast)
+		m.StartFeature(1, 10, "Complex security refactoring")
+		m.RecordIteration(1)
+		if m.ShouldSuggestSimplification(1) {
+			t.Error("should not suggest simplification up front when high complexity features are usually quick")
+		}
+		m.RecordIteration(1)
+		if !m.ShouldSuggestSimplification(1) {
+			t.Error("should suggest simplification once the estimate is used")
+		}
+	})
+}
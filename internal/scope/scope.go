@@ -33,6 +33,8 @@ const (
 	DeferReasonComplexity DeferReason = "complexity"
 	// DeferReasonManual indicates the feature was manually deferred
 	DeferReasonManual DeferReason = "manual"
+	// DeferReasonFeatureDeadline indicates the feature exceeded its time budget
+	DeferReasonFeatureDeadline DeferReason = "feature_deadline"
 )
 
 // Constraints defines the scope limits for execution
@@ -41,6 +43,8 @@ type Constraints struct {
 	MaxIterationsPerFeature int
 	// Deadline is the optional time limit for the entire run
 	Deadline time.Time
+	// FeatureDeadline is the time the agent may spend on a single feature (0 = unlimited)
+	FeatureDeadline time.Duration
 	// QualityThreshold is the minimum test pass rate required (0-100, 0 = no requirement)
 	QualityThreshold int
 	// AutoDefer controls whether features are automatically deferred when limits are hit
@@ -62,7 +66,10 @@ type FeatureScope struct {
 	IterationsUsed    int
 	StartTime         time.Time
 	EndTime           time.Time
+	TimeUsed          time.Duration // Time spent in the feature's iterations
+	iterationStart    time.Time     // Start of the running iteration, zero between iterations
 	EstimatedComplexity Complexity
+	MaxIterations     int // Iteration limit of this feature, overriding the constraints (0 = none)
 	Deferred          bool
 	DeferReason       DeferReason
 	SimplificationSuggested bool
@@ -75,6 +82,7 @@ type Manager struct {
 	featureScope map[int]*FeatureScope
 	totalIterations int
 	deferredFeatures []int
+	calibration  *Calibration
 }
 
 // NewManager creates a new scope manager with the given constraints
@@ -99,6 +107,48 @@ func (m *Manager) SetDeadlineDuration(d time.Duration) {
 	m.constraints.Deadline = time.Now().Add(d)
 }
 
+// SetCalibration makes estimates use the iterations features actually took
+// in earlier runs. For a feature whose complexity level is calibrated, the
+// iteration limit is raised to its estimate if it is lower, and
+// simplification is suggested once the feature has used its estimate
+// rather than up front for high complexity.
+func (m *Manager) SetCalibration(c *Calibration) {
+	m.calibration = c
+}
+
+// EstimatedIterations returns the number of iterations a feature is expected
+// to take, from its complexity and the calibration, or 0 for a feature that
+// is not tracked
+func (m *Manager) EstimatedIterations(featureID int) int {
+	scope := m.featureScope[featureID]
+	if scope == nil {
+		return 0
+	}
+	return m.calibration.Iterations(scope.EstimatedComplexity)
+}
+
+// SetIterationLimit gives a feature its own iteration limit, overriding
+// MaxIterationsPerFeature and the calibration (0 removes it)
+func (m *Manager) SetIterationLimit(featureID int, limit int) {
+	if scope, ok := m.featureScope[featureID]; ok {
+		scope.MaxIterations = limit
+	}
+}
+
+// iterationLimit returns the iteration limit of a feature (0 = unlimited)
+func (m *Manager) iterationLimit(scope *FeatureScope) int {
+	if scope.MaxIterations > 0 {
+		return scope.MaxIterations
+	}
+	limit := m.constraints.MaxIterationsPerFeature
+	if limit > 0 && m.calibration.Calibrated(scope.EstimatedComplexity) {
+		if estimate := m.calibration.Iterations(scope.EstimatedComplexity); estimate > limit {
+			limit = estimate
+		}
+	}
+	return limit
+}
+
 // GetConstraints returns the current scope constraints
 func (m *Manager) GetConstraints() *Constraints {
 	return m.constraints
@@ -123,6 +173,39 @@ func (m *Manager) RecordIteration(featureID int) {
 	}
 }
 
+// StartIteration records the start of the agent's work on a feature in an
+// iteration, for the feature's time budget
+func (m *Manager) StartIteration(featureID int) {
+	if scope, ok := m.featureScope[featureID]; ok {
+		scope.iterationStart = time.Now()
+	}
+}
+
+// EndIteration records the end of the agent's work on a feature in an
+// iteration, adding the time since StartIteration to the time it used
+func (m *Manager) EndIteration(featureID int) {
+	if scope, ok := m.featureScope[featureID]; ok && !scope.iterationStart.IsZero() {
+		scope.TimeUsed += time.Since(scope.iterationStart)
+		scope.iterationStart = time.Time{}
+	}
+}
+
+// RemainingFeatureTime returns the time left in a feature's time budget, or
+// -1 if there is no budget
+func (m *Manager) RemainingFeatureTime(featureID int) time.Duration {
+	if m.constraints.FeatureDeadline <= 0 {
+		return -1
+	}
+	scope := m.featureScope[featureID]
+	if scope == nil {
+		return m.constraints.FeatureDeadline
+	}
+	if remaining := m.constraints.FeatureDeadline - scope.TimeUsed; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
 // GetFeatureScope returns the scope status for a feature
 func (m *Manager) GetFeatureScope(featureID int) *FeatureScope {
 	return m.featureScope[featureID]
@@ -136,12 +219,18 @@ func (m *Manager) ShouldDefer(featureID int) (bool, DeferReason) {
 	}
 
 	// Check iteration limit
-	if m.constraints.MaxIterationsPerFeature > 0 {
-		if scope.IterationsUsed >= m.constraints.MaxIterationsPerFeature {
+	if limit := m.iterationLimit(scope); limit > 0 {
+		if scope.IterationsUsed >= limit {
 			return true, DeferReasonIterationLimit
 		}
 	}
 
+	// Check feature time budget, which long iterations can use up even
+	// when few iterations were run
+	if m.constraints.FeatureDeadline > 0 && scope.TimeUsed >= m.constraints.FeatureDeadline {
+		return true, DeferReasonFeatureDeadline
+	}
+
 	// Check deadline
 	if !m.constraints.Deadline.IsZero() {
 		if time.Now().After(m.constraints.Deadline) {
@@ -196,20 +285,43 @@ func (m *Manager) IsDeadlineExceeded() bool {
 
 // RemainingIterations returns remaining iterations for a feature, or -1 if unlimited
 func (m *Manager) RemainingIterations(featureID int) int {
-	if m.constraints.MaxIterationsPerFeature <= 0 {
-		return -1
-	}
 	scope := m.featureScope[featureID]
 	if scope == nil {
+		if m.constraints.MaxIterationsPerFeature <= 0 {
+			return -1
+		}
 		return m.constraints.MaxIterationsPerFeature
 	}
-	remaining := m.constraints.MaxIterationsPerFeature - scope.IterationsUsed
+	limit := m.iterationLimit(scope)
+	if limit <= 0 {
+		return -1
+	}
+	remaining := limit - scope.IterationsUsed
 	if remaining < 0 {
 		return 0
 	}
 	return remaining
 }
 
+// BuildBudgetPromptContext tells the agent how many iterations, including
+// the current one, are left for a feature before it is deferred. It returns
+// empty string if the feature has no iteration limit (remaining < 0).
+func BuildBudgetPromptContext(featureID int, remaining int) string {
+	if featureID <= 0 || remaining < 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n[ITERATION BUDGET]\n")
+	if remaining <= 1 {
+		b.WriteString(fmt.Sprintf("This is the last iteration for feature #%d before it is deferred. ", featureID))
+	} else {
+		b.WriteString(fmt.Sprintf("Feature #%d has %d iterations left, including this one, before it is deferred. ", featureID, remaining))
+	}
+	b.WriteString("Aim for a working, tested version within the budget and leave refinements for later.\n")
+	b.WriteString("[END ITERATION BUDGET]\n\n")
+	return b.String()
+}
+
 // GetTotalIterations returns the total iterations run so far
 func (m *Manager) GetTotalIterations() int {
 	return m.totalIterations
@@ -398,6 +510,8 @@ func FormatDeferralReason(reason DeferReason) string {
 		return "too complex for current scope"
 	case DeferReasonManual:
 		return "manually deferred"
+	case DeferReasonFeatureDeadline:
+		return "exceeded feature time budget"
 	default:
 		return string(reason)
 	}
@@ -411,14 +525,19 @@ func (m *Manager) ShouldSuggestSimplification(featureID int) bool {
 	}
 
 	// Suggest simplification if:
-	// 1. Feature is high complexity
+	// 1. Feature is high complexity (or, once calibrated, has used the
+	//    iterations features of its complexity usually take)
 	// 2. Or iterations used is >= 50% of limit
-	if scope.EstimatedComplexity == ComplexityHigh {
+	if m.calibration.Calibrated(scope.EstimatedComplexity) {
+		if scope.IterationsUsed >= m.calibration.Iterations(scope.EstimatedComplexity) {
+			return true
+		}
+	} else if scope.EstimatedComplexity == ComplexityHigh {
 		return true
 	}
 
-	if m.constraints.MaxIterationsPerFeature > 0 {
-		halfLimit := m.constraints.MaxIterationsPerFeature / 2
+	if limit := m.iterationLimit(scope); limit > 0 {
+		halfLimit := limit / 2
 		if halfLimit == 0 {
 			halfLimit = 1
 		}
@@ -1,6 +1,7 @@
 package scope
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -147,6 +148,40 @@ func TestShouldDefer_Deadline(t *testing.T) {
 	}
 }
 
+func TestShouldDefer_FeatureDeadline(t *testing.T) {
+	m := NewManager(&Constraints{FeatureDeadline: 20 * time.Millisecond})
+	m.StartFeature(1, 3, "Test feature")
+	if got := m.RemainingFeatureTime(1); got != 20*time.Millisecond {
+		t.Errorf("RemainingFeatureTime() before any iteration = %v", got)
+	}
+
+	// Time between iterations does not count
+	m.RecordIteration(1)
+	m.StartIteration(1)
+	m.EndIteration(1)
+	time.Sleep(30 * time.Millisecond)
+	if shouldDefer, _ := m.ShouldDefer(1); shouldDefer {
+		t.Error("should not defer before the feature used its time budget")
+	}
+
+	// A single long iteration uses up the budget
+	m.RecordIteration(1)
+	m.StartIteration(1)
+	time.Sleep(30 * time.Millisecond)
+	m.EndIteration(1)
+	shouldDefer, reason := m.ShouldDefer(1)
+	if !shouldDefer || reason != DeferReasonFeatureDeadline {
+		t.Errorf("ShouldDefer() = %v, %s, want true, %s", shouldDefer, reason, DeferReasonFeatureDeadline)
+	}
+	if got := m.RemainingFeatureTime(1); got != 0 {
+		t.Errorf("RemainingFeatureTime() after the budget = %v, want 0", got)
+	}
+
+	if got := NewManager(nil).RemainingFeatureTime(1); got != -1 {
+		t.Errorf("RemainingFeatureTime() without a budget = %v, want -1", got)
+	}
+}
+
 func TestShouldDefer_NoLimit(t *testing.T) {
 	m := NewManager(nil) // No limits set
 	m.StartFeature(1, 3, "Test feature")
@@ -284,6 +319,53 @@ func TestRemainingIterations(t *testing.T) {
 	})
 }
 
+func TestSetIterationLimit(t *testing.T) {
+	m := NewManager(&Constraints{MaxIterationsPerFeature: 5})
+	m.StartFeature(1, 2, "Small fix")
+	m.StartFeature(2, 2, "Other fix")
+	m.SetIterationLimit(1, 2)
+
+	m.RecordIteration(1)
+	m.RecordIteration(2)
+	if got := m.RemainingIterations(1); got != 1 {
+		t.Errorf("RemainingIterations() with a feature limit = %d, want 1", got)
+	}
+	if got := m.RemainingIterations(2); got != 4 {
+		t.Errorf("RemainingIterations() with the global limit = %d, want 4", got)
+	}
+	m.RecordIteration(1)
+	if shouldDefer, reason := m.ShouldDefer(1); !shouldDefer || reason != DeferReasonIterationLimit {
+		t.Error("feature should be deferred at its own limit")
+	}
+
+	// A feature limit applies without a global one
+	m = NewManager(nil)
+	m.StartFeature(1, 2, "Small fix")
+	if got := m.RemainingIterations(1); got != -1 {
+		t.Errorf("RemainingIterations() without limits = %d, want -1", got)
+	}
+	m.SetIterationLimit(1, 3)
+	m.RecordIteration(1)
+	if got := m.RemainingIterations(1); got != 2 {
+		t.Errorf("RemainingIterations() = %d, want 2", got)
+	}
+}
+
+func TestBuildBudgetPromptContext(t *testing.T) {
+	if got := BuildBudgetPromptContext(3, -1); got != "" {
+		t.Errorf("budget without a limit = %q", got)
+	}
+	if got := BuildBudgetPromptContext(0, 2); got != "" {
+		t.Errorf("budget without a feature = %q", got)
+	}
+	if got := BuildBudgetPromptContext(3, 2); !strings.Contains(got, "Feature #3 has 2 iterations left, including this one") {
+		t.Errorf("budget = %q", got)
+	}
+	if got := BuildBudgetPromptContext(3, 1); !strings.Contains(got, "This is the last iteration for feature #3") {
+		t.Errorf("last iteration budget = %q", got)
+	}
+}
+
 func TestEstimateComplexity(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -458,6 +540,7 @@ func TestFormatDeferralReason(t *testing.T) {
 		{DeferReasonDeadline, "deadline reached"},
 		{DeferReasonComplexity, "too complex for current scope"},
 		{DeferReasonManual, "manually deferred"},
+		{DeferReasonFeatureDeadline, "exceeded feature time budget"},
 		{DeferReason("custom"), "custom"},
 	}
 
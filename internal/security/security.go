@@ -0,0 +1,297 @@
+// Package security runs security scanners (gosec, npm audit, trivy) and
+// parses their findings into a common form that can be filtered by severity
+// and summarized for the agent.
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/logimos/ralph/internal/proc"
+)
+
+// Severity is the severity of a finding
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+// DefaultMinSeverity is the lowest severity that fails a scan by default
+const DefaultMinSeverity = SeverityHigh
+
+// DefaultCommands are the scan commands of the supported tools, producing
+// the JSON output the parsers read
+var DefaultCommands = map[string]string{
+	"gosec": "gosec -fmt=json -quiet ./...",
+	"npm":   "npm audit --json",
+	"trivy": "trivy fs --format json --quiet .",
+}
+
+// String returns the severity name
+func (s Severity) String() string {
+	switch s {
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "info"
+	}
+}
+
+// ParseSeverity parses a severity name as reported by any supported tool
+// (e.g., "HIGH", "moderate", "critical")
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "info", "unknown", "none":
+		return SeverityInfo, nil
+	case "low":
+		return SeverityLow, nil
+	case "medium", "moderate":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	case "critical":
+		return SeverityCritical, nil
+	default:
+		return SeverityInfo, fmt.Errorf("unknown severity %q: must be one of info, low, medium, high, critical", s)
+	}
+}
+
+// Finding is a single issue reported by a scanner
+type Finding struct {
+	Tool     string   // Scanner that reported the finding
+	Severity Severity // Severity of the finding
+	ID       string   // Rule or vulnerability ID (e.g., G101, CVE-2023-1234)
+	Title    string   // Short description
+	Location string   // File and line, or package and version
+}
+
+// String returns a one-line description of the finding
+func (f Finding) String() string {
+	s := fmt.Sprintf("[%s] %s", strings.ToUpper(f.Severity.String()), f.Title)
+	if f.ID != "" {
+		s = fmt.Sprintf("[%s] %s: %s", strings.ToUpper(f.Severity.String()), f.ID, f.Title)
+	}
+	if f.Location != "" {
+		s += " (" + f.Location + ")"
+	}
+	return s
+}
+
+// Parse parses the JSON output of gosec, npm audit or trivy, recognizing
+// the tool by the shape of the output
+func Parse(output []byte) ([]Finding, error) {
+	// Tools may print progress lines before the JSON document
+	start := bytes.IndexByte(output, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("no JSON scan results found")
+	}
+	data := output[start:]
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse scan results: %w", err)
+	}
+	switch {
+	case probe["Issues"] != nil:
+		return parseGosec(data)
+	case probe["vulnerabilities"] != nil || probe["auditReportVersion"] != nil:
+		return parseNpmAudit(data)
+	case probe["Results"] != nil || probe["SchemaVersion"] != nil:
+		return parseTrivy(data)
+	default:
+		return nil, fmt.Errorf("unrecognized scan results: expected gosec, npm audit or trivy JSON output")
+	}
+}
+
+// parseGosec parses gosec -fmt=json output
+func parseGosec(data []byte) ([]Finding, error) {
+	var report struct {
+		Issues []struct {
+			Severity string `json:"severity"`
+			RuleID   string `json:"rule_id"`
+			Details  string `json:"details"`
+			File     string `json:"file"`
+			Line     string `json:"line"`
+		} `json:"Issues"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse gosec results: %w", err)
+	}
+	var findings []Finding
+	for _, issue := range report.Issues {
+		sev, _ := ParseSeverity(issue.Severity)
+		location := issue.File
+		if issue.Line != "" {
+			location += ":" + issue.Line
+		}
+		findings = append(findings, Finding{Tool: "gosec", Severity: sev, ID: issue.RuleID, Title: issue.Details, Location: location})
+	}
+	return findings, nil
+}
+
+// parseNpmAudit parses npm audit --json output (npm 7 and later)
+func parseNpmAudit(data []byte) ([]Finding, error) {
+	var report struct {
+		Vulnerabilities map[string]struct {
+			Name     string            `json:"name"`
+			Severity string            `json:"severity"`
+			Range    string            `json:"range"`
+			Via      []json.RawMessage `json:"via"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse npm audit results: %w", err)
+	}
+	var findings []Finding
+	for name, vuln := range report.Vulnerabilities {
+		sev, _ := ParseSeverity(vuln.Severity)
+		f := Finding{Tool: "npm audit", Severity: sev, Title: "vulnerable dependency", Location: name}
+		if vuln.Range != "" {
+			f.Location += "@" + vuln.Range
+		}
+		// via lists advisories, or names of the dependencies they come through
+		for _, via := range vuln.Via {
+			var advisory struct {
+				Title string `json:"title"`
+				URL   string `json:"url"`
+			}
+			if json.Unmarshal(via, &advisory) == nil && advisory.Title != "" {
+				f.Title, f.ID = advisory.Title, advisory.URL
+				break
+			}
+			var dep string
+			if json.Unmarshal(via, &dep) == nil && dep != "" {
+				f.Title = "vulnerable through " + dep
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// parseTrivy parses trivy --format json output
+func parseTrivy(data []byte) ([]Finding, error) {
+	var report struct {
+		Results []struct {
+			Target          string `json:"Target"`
+			Vulnerabilities []struct {
+				VulnerabilityID  string `json:"VulnerabilityID"`
+				PkgName          string `json:"PkgName"`
+				InstalledVersion string `json:"InstalledVersion"`
+				Severity         string `json:"Severity"`
+				Title            string `json:"Title"`
+			} `json:"Vulnerabilities"`
+			Misconfigurations []struct {
+				ID       string `json:"ID"`
+				Title    string `json:"Title"`
+				Severity string `json:"Severity"`
+			} `json:"Misconfigurations"`
+			Secrets []struct {
+				RuleID    string `json:"RuleID"`
+				Title     string `json:"Title"`
+				Severity  string `json:"Severity"`
+				StartLine int    `json:"StartLine"`
+			} `json:"Secrets"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy results: %w", err)
+	}
+	var findings []Finding
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			sev, _ := ParseSeverity(v.Severity)
+			title := v.Title
+			if title == "" {
+				title = "vulnerable package"
+			}
+			findings = append(findings, Finding{Tool: "trivy", Severity: sev, ID: v.VulnerabilityID, Title: title,
+				Location: fmt.Sprintf("%s %s in %s", v.PkgName, v.InstalledVersion, r.Target)})
+		}
+		for _, m := range r.Misconfigurations {
+			sev, _ := ParseSeverity(m.Severity)
+			findings = append(findings, Finding{Tool: "trivy", Severity: sev, ID: m.ID, Title: m.Title, Location: r.Target})
+		}
+		for _, s := range r.Secrets {
+			sev, _ := ParseSeverity(s.Severity)
+			findings = append(findings, Finding{Tool: "trivy", Severity: sev, ID: s.RuleID, Title: s.Title,
+				Location: fmt.Sprintf("%s:%d", r.Target, s.StartLine)})
+		}
+	}
+	return findings, nil
+}
+
+// AtLeast returns the findings of at least the given severity, most severe
+// first
+func AtLeast(findings []Finding, min Severity) []Finding {
+	var filtered []Finding
+	for _, f := range findings {
+		if f.Severity >= min {
+			filtered = append(filtered, f)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].Severity != filtered[j].Severity {
+			return filtered[i].Severity > filtered[j].Severity
+		}
+		return filtered[i].Location < filtered[j].Location
+	})
+	return filtered
+}
+
+// Scan runs a scan command and parses its findings. Scanners exit with a
+// non-zero status when they report findings, so the exit status is only an
+// error if the output holds no results.
+func Scan(ctx context.Context, command string) ([]Finding, string, error) {
+	cmd := proc.CommandLine(ctx, command)
+	if cmd == nil {
+		return nil, "", fmt.Errorf("no scan command")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	findings, err := Parse(stdout.Bytes())
+	if err != nil {
+		output := strings.TrimSpace(stdout.String() + "\n" + stderr.String())
+		if runErr != nil {
+			return nil, output, fmt.Errorf("scan command failed: %w", runErr)
+		}
+		return nil, output, err
+	}
+	return findings, stdout.String(), nil
+}
+
+// Summary describes findings for the agent, listing at most max of them
+// (0 = all)
+func Summary(findings []Finding, min Severity, max int) string {
+	if len(findings) == 0 {
+		return fmt.Sprintf("No security findings of %s severity or above.", min)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d security finding(s) of %s severity or above:\n", len(findings), min)
+	for i, f := range findings {
+		if max > 0 && i == max {
+			fmt.Fprintf(&sb, "- ... and %d more\n", len(findings)-max)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s\n", f)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,595 @@
+// Package server implements "ralph serve": a local HTTP API to start and watch
+// runs, pause them, add nudges and memories, and trigger replans, so editors,
+// dashboards and scripts can drive Ralph without one-shot CLI invocations.
+//
+// Runs are separate Ralph processes started with the flags given to
+// "ralph serve". Their output and progress events are streamed to clients as
+// server-sent events (GET /events).
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logimos/ralph/internal/proc"
+	"github.com/logimos/ralph/internal/progress"
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+const (
+	// DefaultAddr is the address the API listens on by default. It is only
+	// reachable from this machine.
+	DefaultAddr = "127.0.0.1:7777"
+	// TokenEnv is the environment variable holding the token clients must
+	// send as "Authorization: Bearer <token>". If it is not set, "ralph serve"
+	// generates a token and prints it.
+	TokenEnv = "RALPH_SERVE_TOKEN"
+	// PauseFile is the file whose presence pauses runs before their next
+	// iteration
+	PauseFile = ".ralph/pause"
+	// pollInterval is how often the progress log of a run is checked for new
+	// events
+	pollInterval = 500 * time.Millisecond
+)
+
+// Paused reports whether runs in the current directory are paused
+func Paused() bool {
+	_, err := os.Stat(PauseFile)
+	return err == nil
+}
+
+// Actions are the operations of the API that Ralph implements, so that they
+// behave like their CLI counterparts. Each returns a message describing the
+// result.
+type Actions struct {
+	AddNudge  func(spec string) (string, error)     // -nudge type:content
+	AddMemory func(spec string) (string, error)     // -add-memory type:content
+	Replan    func(strategy string) (string, error) // -replan with -replan-strategy
+	Blocked   func() map[int][]string               // Features blocked by prerequisite milestones
+}
+
+// Options configures the server
+type Options struct {
+	Command      []string // Ralph command that starts a run; -iterations N is appended
+	PlanFile     string
+	ProgressFile string
+	Token        string   // Token clients must send; empty = no authentication
+	Hosts        []string // Host names accepted besides loopback ones, e.g. of the listen address
+	Actions      Actions
+}
+
+// RunInfo describes the current or last run
+type RunInfo struct {
+	PID        int        `json:"pid"`
+	Iterations int        `json:"iterations"`
+	Started    time.Time  `json:"started"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Error      string     `json:"error,omitempty"` // Why the run failed, if it did
+}
+
+// FeatureCounts summarizes the plan
+type FeatureCounts struct {
+	Total     int `json:"total"`
+	Tested    int `json:"tested"`
+	Deferred  int `json:"deferred"`
+	Remaining int `json:"remaining"`
+}
+
+// Status is the response of GET /status
+type Status struct {
+	State          string        `json:"state"`  // idle or running
+	Paused         bool          `json:"paused"` // Runs wait before their next iteration
+	Run            *RunInfo      `json:"run,omitempty"`
+	Features       FeatureCounts `json:"features"`
+	CurrentFeature *plan.Plan    `json:"current_feature,omitempty"`
+}
+
+// Event is a server-sent event
+type Event struct {
+	Type string    `json:"type"` // run_started, run_finished, output, progress, paused, resumed, nudge, memory, replan
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// Server serves the API
+type Server struct {
+	opts Options
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd // Current run, nil when idle
+	run     *RunInfo  // Current or last run
+	clients map[chan Event]bool
+}
+
+// New creates a server
+func New(opts Options) *Server {
+	return &Server{opts: opts, clients: make(map[chan Event]bool)}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /feature", s.handleFeature)
+	mux.HandleFunc("POST /runs", s.handleStartRun)
+	mux.HandleFunc("POST /stop", s.handleStop)
+	mux.HandleFunc("POST /pause", s.handlePause)
+	mux.HandleFunc("POST /resume", s.handleResume)
+	mux.HandleFunc("POST /nudges", s.handleNudge)
+	mux.HandleFunc("POST /memories", s.handleMemory)
+	mux.HandleFunc("POST /replan", s.handleReplan)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	return s.checkOrigin(s.authenticate(mux))
+}
+
+// GenerateToken returns a random token for clients to authenticate with
+func GenerateToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkOrigin rejects requests addressed to a host other than this machine
+// (DNS rebinding) and requests sent by web pages of other origins, so that
+// pages opened in a browser cannot drive the API
+func (s *Server) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q not allowed", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !s.allowedHost(u.Host) {
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin %q not allowed", origin))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host, with or without a port, is a loopback
+// host or one of the configured hosts
+func (s *Server) allowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, h := range s.opts.Hosts {
+		if strings.EqualFold(host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate rejects requests without the token, if one is configured
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.Status()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleFeature(w http.ResponseWriter, r *http.Request) {
+	status, err := s.Status()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"feature": status.CurrentFeature})
+}
+
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Iterations int `json:"iterations"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if req.Iterations <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("iterations must be positive"))
+		return
+	}
+	info, err := s.StartRun(req.Iterations)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, info)
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if err := s.StopRun(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "stopping the run"})
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if err := os.MkdirAll(filepath.Dir(PauseFile), 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := os.WriteFile(PauseFile, nil, 0644); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.publish("paused", nil)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "runs pause before their next iteration"})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if err := os.Remove(PauseFile); err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.publish("resumed", nil)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "resumed"})
+}
+
+func (s *Server) handleNudge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Nudge string `json:"nudge"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	s.runAction(w, "nudge", func() (string, error) { return s.opts.Actions.AddNudge(req.Nudge) })
+}
+
+func (s *Server) handleMemory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Memory string `json:"memory"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	s.runAction(w, "memory", func() (string, error) { return s.opts.Actions.AddMemory(req.Memory) })
+}
+
+func (s *Server) handleReplan(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Strategy string `json:"strategy"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	// A run rewrites the plan too
+	if s.running() {
+		writeError(w, http.StatusConflict, fmt.Errorf("cannot replan while a run is in progress"))
+		return
+	}
+	s.runAction(w, "replan", func() (string, error) { return s.opts.Actions.Replan(req.Strategy) })
+}
+
+// runAction runs one of the actions, reporting its result to the client and
+// as an event
+func (s *Server) runAction(w http.ResponseWriter, event string, action func() (string, error)) {
+	message, err := action()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.publish(event, map[string]string{"message": message})
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+// handleEvents streams events to the client until it disconnects
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// Status returns the state of the server and the plan
+func (s *Server) Status() (*Status, error) {
+	status := &Status{State: "idle", Paused: Paused()}
+	s.mu.Lock()
+	if s.cmd != nil {
+		status.State = "running"
+	}
+	if s.run != nil {
+		info := *s.run
+		status.Run = &info
+	}
+	s.mu.Unlock()
+
+	plans, err := plan.ReadFile(s.opts.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	var blocked map[int][]string
+	if s.opts.Actions.Blocked != nil {
+		blocked = s.opts.Actions.Blocked()
+	}
+	status.Features.Total = len(plans)
+	for i, p := range plans {
+		switch {
+		case p.Tested:
+			status.Features.Tested++
+		case p.Deferred:
+			status.Features.Deferred++
+		default:
+			status.Features.Remaining++
+		}
+		if status.CurrentFeature == nil && p.IsActionable() && len(blocked[p.ID]) == 0 {
+			status.CurrentFeature = &plans[i]
+		}
+	}
+	return status, nil
+}
+
+// StartRun starts a run of the given number of iterations. Only one run can be
+// in progress at a time.
+func (s *Server) StartRun(iterations int) (*RunInfo, error) {
+	s.mu.Lock()
+	if s.cmd != nil {
+		pid := s.cmd.Process.Pid
+		s.mu.Unlock()
+		return nil, fmt.Errorf("a run is already in progress (PID %d)", pid)
+	}
+
+	args := append(append([]string{}, s.opts.Command[1:]...), "-iterations", fmt.Sprint(iterations))
+	cmd := exec.Command(s.opts.Command[0], args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture run output: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+	offset := fileSize(progress.JSONLPath(s.opts.ProgressFile))
+	if err := proc.Start(cmd); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+	s.cmd = cmd
+	s.run = &RunInfo{PID: cmd.Process.Pid, Iterations: iterations, Started: time.Now()}
+	info := *s.run
+	s.mu.Unlock()
+	s.publish("run_started", info)
+
+	done, flushed := make(chan struct{}), make(chan struct{})
+	go s.streamOutput(cmd, out, done, flushed)
+	go s.streamProgress(offset, done, flushed)
+	return &info, nil
+}
+
+// streamOutput publishes the output of a run line by line, then waits for the
+// run to finish. Closing done stops streamProgress, which closes flushed once
+// it has published the last progress events.
+func (s *Server) streamOutput(cmd *exec.Cmd, out io.Reader, done, flushed chan struct{}) {
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s.publish("output", scanner.Text())
+	}
+	err := cmd.Wait()
+	proc.Release(cmd.Process.Pid)
+
+	s.mu.Lock()
+	finished := time.Now()
+	s.run.Finished = &finished
+	if err != nil {
+		s.run.Error = err.Error()
+	}
+	info := *s.run
+	s.cmd = nil
+	s.mu.Unlock()
+
+	close(done)
+	<-flushed
+	s.publish("run_finished", info)
+}
+
+// streamProgress publishes the events a run appends to the progress log
+// after offset, until done is closed
+func (s *Server) streamProgress(offset int64, done, flushed chan struct{}) {
+	defer close(flushed)
+	path := progress.JSONLPath(s.opts.ProgressFile)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			s.publishProgress(path, &offset)
+			return
+		case <-ticker.C:
+			s.publishProgress(path, &offset)
+		}
+	}
+}
+
+// publishProgress publishes the complete lines of the progress log after
+// offset and advances it
+func (s *Server) publishProgress(path string, offset *int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(*offset, io.SeekStart); err != nil {
+		return
+	}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return // Incomplete lines are read again next time
+		}
+		*offset += int64(len(line))
+		var e json.RawMessage
+		if json.Unmarshal(line, &e) == nil {
+			s.publish("progress", e)
+		}
+	}
+}
+
+// StopRun interrupts the current run, which stops like it does on Ctrl+C
+func (s *Server) StopRun() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cmd == nil {
+		return fmt.Errorf("no run in progress")
+	}
+	if runtime.GOOS == "windows" {
+		// There is no interrupt to send; kill the run with the agents and
+		// validations it started
+		return proc.Kill(s.cmd)
+	}
+	return s.cmd.Process.Signal(os.Interrupt)
+}
+
+// running reports whether a run is in progress
+func (s *Server) running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cmd != nil
+}
+
+// subscribe registers a client for events
+func (s *Server) subscribe() chan Event {
+	ch := make(chan Event, 256)
+	s.mu.Lock()
+	s.clients[ch] = true
+	s.mu.Unlock()
+	return ch
+}
+
+// unsubscribe removes a client
+func (s *Server) unsubscribe(ch chan Event) {
+	s.mu.Lock()
+	delete(s.clients, ch)
+	s.mu.Unlock()
+}
+
+// publish sends an event to every client. Events are dropped for clients
+// that do not keep up.
+func (s *Server) publish(eventType string, data any) {
+	e := Event{Type: eventType, Time: time.Now(), Data: data}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// fileSize returns the size of a file, or 0 if it does not exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// errNotJSON is returned by decode for request bodies that are not JSON
+var errNotJSON = errors.New("request body must be sent as Content-Type: application/json")
+
+// decode reads a JSON request body; an empty body leaves v unchanged. A
+// body must be sent as application/json, which browsers do not send
+// cross-origin without asking first.
+func decode(r *http.Request, v any) error {
+	if r.ContentLength != 0 {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			return errNotJSON
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeDecodeError writes the response for a request body decode rejected
+func writeDecodeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotJSON) {
+		writeError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// newTestServer serves a plan of three features from a temporary directory
+func newTestServer(t *testing.T, opts Options) (*Server, *httptest.Server) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	plans := []plan.Plan{{ID: 1, Description: "Login", Tested: true}, {ID: 2, Description: "Signup"}, {ID: 3, Description: "Logout"}}
+	if err := plan.WriteFile("plan.json", plans); err != nil {
+		t.Fatal(err)
+	}
+	opts.PlanFile = "plan.json"
+	opts.ProgressFile = "progress.txt"
+	s := New(opts)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// call sends a request and decodes the JSON response into v
+func call(t *testing.T, method, url, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestStatusAndFeature(t *testing.T) {
+	_, ts := newTestServer(t, Options{Actions: Actions{Blocked: func() map[int][]string {
+		return map[int][]string{2: {"Alpha"}}
+	}}})
+
+	var status Status
+	if code := call(t, "GET", ts.URL+"/status", "", &status); code != http.StatusOK {
+		t.Fatalf("GET /status = %d", code)
+	}
+	if status.State != "idle" || status.Features != (FeatureCounts{Total: 3, Tested: 1, Remaining: 2}) {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Feature 2 waits for a milestone
+	var feature struct{ Feature *plan.Plan }
+	call(t, "GET", ts.URL+"/feature", "", &feature)
+	if feature.Feature == nil || feature.Feature.ID != 3 {
+		t.Errorf("GET /feature = %+v, want feature 3", feature.Feature)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+	call(t, "POST", ts.URL+"/pause", "", nil)
+	if !Paused() {
+		t.Fatal("POST /pause did not pause runs")
+	}
+	var status Status
+	if call(t, "GET", ts.URL+"/status", "", &status); !status.Paused {
+		t.Error("status not paused")
+	}
+	call(t, "POST", ts.URL+"/resume", "", nil)
+	if Paused() {
+		t.Error("POST /resume did not resume runs")
+	}
+}
+
+func TestActions(t *testing.T) {
+	var nudges []string
+	s, ts := newTestServer(t, Options{Actions: Actions{
+		AddNudge: func(spec string) (string, error) {
+			if !strings.Contains(spec, ":") {
+				return "", fmt.Errorf("invalid nudge")
+			}
+			nudges = append(nudges, spec)
+			return "Nudge added", nil
+		},
+		Replan: func(strategy string) (string, error) { return "replanned with " + strategy, nil },
+	}})
+	events := s.subscribe()
+
+	var resp map[string]string
+	if code := call(t, "POST", ts.URL+"/nudges", `{"nudge": "focus:Tests first"}`, &resp); code != http.StatusOK || resp["message"] != "Nudge added" {
+		t.Errorf("POST /nudges = %d %v", code, resp)
+	}
+	if code := call(t, "POST", ts.URL+"/nudges", `{"nudge": "nonsense"}`, &resp); code != http.StatusBadRequest || resp["error"] == "" {
+		t.Errorf("POST /nudges of an invalid nudge = %d %v", code, resp)
+	}
+	if len(nudges) != 1 {
+		t.Errorf("nudges = %v", nudges)
+	}
+	if e := <-events; e.Type != "nudge" {
+		t.Errorf("event = %+v, want a nudge event", e)
+	}
+
+	if call(t, "POST", ts.URL+"/replan", `{"strategy": "agent"}`, &resp); resp["message"] != "replanned with agent" {
+		t.Errorf("POST /replan = %v", resp)
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts not supported")
+	}
+	// The "run" appends a progress event and prints its arguments
+	script := `echo '{"type":"note","message":"working"}' >> progress.jsonl; echo "run $0 $1"; sleep 0.2`
+	s, ts := newTestServer(t, Options{Command: []string{"/bin/sh", "-c", script}})
+	events := s.subscribe()
+
+	var info RunInfo
+	if code := call(t, "POST", ts.URL+"/runs", `{"iterations": 3}`, &info); code != http.StatusAccepted || info.PID == 0 {
+		t.Fatalf("POST /runs = %d %+v", code, info)
+	}
+	var resp map[string]string
+	if code := call(t, "POST", ts.URL+"/runs", `{"iterations": 3}`, &resp); code != http.StatusConflict {
+		t.Errorf("second POST /runs = %d %v, want a conflict", code, resp)
+	}
+
+	seen := make(map[string]string)
+	timeout := time.After(10 * time.Second)
+	for seen["run_finished"] == "" {
+		select {
+		case e := <-events:
+			data, _ := json.Marshal(e.Data)
+			seen[e.Type] += string(data)
+		case <-timeout:
+			t.Fatalf("run did not finish, events: %v", seen)
+		}
+	}
+	if !strings.Contains(seen["output"], "run -iterations 3") {
+		t.Errorf("output events = %s", seen["output"])
+	}
+	if !strings.Contains(seen["progress"], "working") {
+		t.Errorf("progress events = %s", seen["progress"])
+	}
+
+	var status Status
+	call(t, "GET", ts.URL+"/status", "", &status)
+	if status.State != "idle" || status.Run == nil || status.Run.Finished == nil || status.Run.Error != "" {
+		t.Errorf("status after the run = %+v", status)
+	}
+}
+
+func TestToken(t *testing.T) {
+	_, ts := newTestServer(t, Options{Token: "secret"})
+	if code := call(t, "GET", ts.URL+"/status", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /status without a token = %d", code)
+	}
+	req, _ := http.NewRequest("GET", ts.URL+"/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /status with the token = %d", resp.StatusCode)
+	}
+}
+
+func TestRejectsBodiesNotSentAsJSON(t *testing.T) {
+	_, ts := newTestServer(t, Options{Actions: Actions{
+		AddNudge: func(spec string) (string, error) { return "Nudge added", nil },
+	}})
+	// A form a web page can post cross-origin without a preflight
+	resp, err := http.Post(ts.URL+"/nudges", "text/plain", strings.NewReader(`{"nudge": "focus:Tests first"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("POST /nudges as text/plain = %d", resp.StatusCode)
+	}
+	resp, err = http.Post(ts.URL+"/nudges", "application/json; charset=utf-8", strings.NewReader(`{"nudge": "focus:Tests first"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("POST /nudges as JSON = %d", resp.StatusCode)
+	}
+}
+
+func TestRejectsForeignHostAndOrigin(t *testing.T) {
+	_, ts := newTestServer(t, Options{Hosts: []string{"ralph.internal"}})
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		want   int
+	}{
+		{"loopback", "", "", http.StatusOK},
+		{"localhost", "localhost:7777", "", http.StatusOK},
+		{"IPv6 loopback", "[::1]:7777", "", http.StatusOK},
+		{"configured host", "ralph.internal:7777", "", http.StatusOK},
+		{"rebound host", "evil.example:7777", "", http.StatusForbidden},
+		{"local origin", "", "http://localhost:3000", http.StatusOK},
+		{"foreign origin", "", "https://evil.example", http.StatusForbidden},
+		{"null origin", "", "null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", ts.URL+"/status", nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("GET /status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	a, err := GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateToken()
+	if len(a) < 32 || a == b {
+		t.Errorf("GenerateToken() = %q, %q, want long distinct tokens", a, b)
+	}
+}
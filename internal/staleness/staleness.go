@@ -0,0 +1,114 @@
+// Package staleness detects when context injected into prompts, such as the
+// codebase baseline or stored memories, has drifted from the repository: it
+// is older than a maximum age, or many files have changed since it was made.
+package staleness
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy sets when context counts as stale
+type Policy struct {
+	MaxAge     time.Duration // Maximum age; 0 disables the age check
+	MaxChanges int           // Maximum files changed since the context was made; 0 disables the check
+}
+
+// Enabled reports whether the policy checks anything
+func (p Policy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxChanges > 0
+}
+
+// Report describes how stale a piece of context is
+type Report struct {
+	Name         string        // What was checked (e.g., the baseline file)
+	GeneratedAt  time.Time     // When the context was made
+	Age          time.Duration // Time since it was made
+	ChangedFiles int           // Files changed in the repository since, -1 if unknown
+	Reasons      []string      // Why the context is stale; empty if it is fresh
+}
+
+// Stale reports whether the context should be refreshed
+func (r Report) Stale() bool {
+	return len(r.Reasons) > 0
+}
+
+// String describes the report, e.g. "baseline.json is stale: 21 days old (max 14 days)"
+func (r Report) String() string {
+	if !r.Stale() {
+		return fmt.Sprintf("%s is up to date (%s old)", r.Name, FormatAge(r.Age))
+	}
+	return fmt.Sprintf("%s is stale: %s", r.Name, strings.Join(r.Reasons, ", "))
+}
+
+// Check reports whether context made at generatedAt is stale under the
+// policy. Changed files are counted in the git repository containing the
+// current directory; paths in ignore (e.g., Ralph's own state files, which
+// change every iteration) are not counted.
+func Check(name string, generatedAt time.Time, p Policy, ignore ...string) Report {
+	r := Report{Name: name, GeneratedAt: generatedAt, ChangedFiles: -1}
+	if generatedAt.IsZero() {
+		return r
+	}
+	r.Age = time.Since(generatedAt)
+
+	if p.MaxAge > 0 && r.Age > p.MaxAge {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%s old (max %s)", FormatAge(r.Age), FormatAge(p.MaxAge)))
+	}
+	if p.MaxChanges > 0 {
+		if n, err := ChangedFilesSince(generatedAt, ignore...); err == nil {
+			r.ChangedFiles = n
+			if n > p.MaxChanges {
+				r.Reasons = append(r.Reasons, fmt.Sprintf("%d files changed since (max %d)", n, p.MaxChanges))
+			}
+		}
+	}
+	return r
+}
+
+// ChangedFilesSince counts the files changed by commits since t plus those
+// with uncommitted changes, in the git repository containing the current
+// directory. Paths in ignore are relative to the current directory.
+func ChangedFilesSince(t time.Time, ignore ...string) (int, error) {
+	committed, err := git("log", "--since=@"+strconv.FormatInt(t.Unix(), 10), "--name-only", "--format=", "--relative", "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	uncommitted, err := git("diff", "--name-only", "--relative", "HEAD")
+	if err != nil {
+		return 0, err
+	}
+
+	skip := make(map[string]bool, len(ignore))
+	for _, path := range ignore {
+		skip[filepath.ToSlash(filepath.Clean(path))] = true
+	}
+	changed := make(map[string]bool)
+	for _, path := range strings.Split(committed+"\n"+uncommitted, "\n") {
+		if path = strings.TrimSpace(path); path != "" && !skip[path] {
+			changed[path] = true
+		}
+	}
+	return len(changed), nil
+}
+
+// FormatAge formats a duration in days, or hours below two days
+func FormatAge(d time.Duration) string {
+	if d < 48*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days", int(d.Hours()/24))
+}
+
+// git runs git in the current directory and returns its output
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
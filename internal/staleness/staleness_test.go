@@ -0,0 +1,126 @@
+package staleness
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initRepo creates a git repository whose first commit, of a.txt and
+// plan.json, is a month old, and makes it the current directory
+func initRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	writeFile(t, "a.txt", "a")
+	writeFile(t, "plan.json", "[]")
+	runGit(t, "init", "-q")
+	runGit(t, "add", ".")
+	month := time.Now().AddDate(0, -1, 0).Format(time.RFC3339)
+	cmd := exec.Command("git", "commit", "-q", "-m", "initial")
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+month, "GIT_COMMITTER_DATE="+month)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, args ...string) {
+	t.Helper()
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestChangedFilesSince(t *testing.T) {
+	initRepo(t)
+	generated := time.Now().AddDate(0, 0, -7)
+
+	if n, err := ChangedFilesSince(generated); err != nil || n != 0 {
+		t.Fatalf("ChangedFilesSince() = %d, %v; want 0", n, err)
+	}
+
+	// Two files committed since, one uncommitted change, and a state file
+	writeFile(t, "b.txt", "b")
+	writeFile(t, "c.txt", "c")
+	runGit(t, "add", ".")
+	runGit(t, "commit", "-q", "-m", "more")
+	writeFile(t, "a.txt", "changed")
+	writeFile(t, "plan.json", "[{}]")
+
+	if n, err := ChangedFilesSince(generated, "plan.json"); err != nil || n != 3 {
+		t.Errorf("ChangedFilesSince() = %d, %v; want 3", n, err)
+	}
+	if n, _ := ChangedFilesSince(generated); n != 4 {
+		t.Errorf("ChangedFilesSince() without ignores = %d, want 4", n)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	initRepo(t)
+	writeFile(t, "a.txt", "changed")
+
+	tests := []struct {
+		name      string
+		age       time.Duration
+		policy    Policy
+		wantStale string
+	}{
+		{"fresh", time.Hour, Policy{MaxAge: 24 * time.Hour, MaxChanges: 5}, ""},
+		{"at change limit", time.Hour, Policy{MaxChanges: 1}, ""},
+		{"too old", 20 * 24 * time.Hour, Policy{MaxAge: 14 * 24 * time.Hour}, "20 days old (max 14 days)"},
+		{"disabled", 20 * 24 * time.Hour, Policy{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Check("baseline.json", time.Now().Add(-tt.age), tt.policy)
+			if (tt.wantStale != "") != r.Stale() {
+				t.Fatalf("Stale() = %v, reasons %v", r.Stale(), r.Reasons)
+			}
+			if tt.wantStale != "" && !strings.Contains(r.String(), tt.wantStale) {
+				t.Errorf("String() = %q, want it to contain %q", r.String(), tt.wantStale)
+			}
+		})
+	}
+
+	// A second changed file goes over the limit
+	writeFile(t, "b.txt", "new")
+	runGit(t, "add", "b.txt")
+	r := Check("baseline.json", time.Now().Add(-time.Hour), Policy{MaxChanges: 1})
+	if !r.Stale() || r.ChangedFiles != 2 || !strings.Contains(r.String(), "2 files changed since (max 1)") {
+		t.Errorf("Check() = %+v", r)
+	}
+}
+
+func TestCheckUnknownGeneration(t *testing.T) {
+	r := Check("baseline.json", time.Time{}, Policy{MaxAge: time.Hour, MaxChanges: 1})
+	if r.Stale() {
+		t.Errorf("context without a generation time reported stale: %v", r.Reasons)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	if got := FormatAge(5 * time.Hour); got != "5h" {
+		t.Errorf("FormatAge(5h) = %q", got)
+	}
+	if got := FormatAge(72 * time.Hour); got != "3 days" {
+		t.Errorf("FormatAge(72h) = %q", got)
+	}
+}
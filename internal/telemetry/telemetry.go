@@ -0,0 +1,291 @@
+// Package telemetry aggregates anonymized usage statistics of Ralph runs
+// (features per run, failure rates, recovery strategy usage) in a local file.
+// Recording is opt-in and nothing is sent anywhere: the aggregate can only be
+// read or exported by the user, e.g. to share with a platform team.
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTelemetryFile is the default path of the local telemetry aggregate
+const DefaultTelemetryFile = ".ralph/telemetry.json"
+
+// schemaVersion is the version of the aggregate file format
+const schemaVersion = 1
+
+// RunStats is the anonymized outcome of a single run. It holds counts only:
+// no plan contents, file paths, prompts or agent output.
+type RunStats struct {
+	Agent             string         // Agent executable name or API backend (see AgentName)
+	BuildSystem       string         // Build system preset in use
+	Iterations        int            // Iterations run
+	FeaturesCompleted int            // Features marked tested during the run
+	FeaturesSkipped   int            // Features skipped by recovery
+	Failures          int            // Failures detected
+	FailuresRecovered int            // Failures recovered from
+	FailuresByType    map[string]int // Failures per failure type
+	StrategyUsage     map[string]int // Recovery strategies applied, per strategy
+	Replans           int            // Replans triggered
+	Completed         bool           // True if the plan was completed
+	Duration          time.Duration  // Wall-clock duration of the run
+}
+
+// AgentStats aggregates the runs of one agent
+type AgentStats struct {
+	Runs              int `json:"runs"`
+	Iterations        int `json:"iterations"`
+	FeaturesCompleted int `json:"features_completed"`
+	Failures          int `json:"failures"`
+}
+
+// Aggregate holds statistics summed over all recorded runs
+type Aggregate struct {
+	Version           int                    `json:"version"`
+	Since             time.Time              `json:"since"`
+	Updated           time.Time              `json:"updated"`
+	Runs              int                    `json:"runs"`
+	RunsCompleted     int                    `json:"runs_completed"`
+	Iterations        int                    `json:"iterations"`
+	FeaturesCompleted int                    `json:"features_completed"`
+	FeaturesSkipped   int                    `json:"features_skipped"`
+	Failures          int                    `json:"failures"`
+	FailuresRecovered int                    `json:"failures_recovered"`
+	Replans           int                    `json:"replans"`
+	DurationSeconds   float64                `json:"duration_seconds"`
+	FailuresByType    map[string]int         `json:"failures_by_type"`
+	StrategyUsage     map[string]int         `json:"strategy_usage"`
+	BuildSystems      map[string]int         `json:"build_systems"`
+	Agents            map[string]*AgentStats `json:"agents"`
+}
+
+// NewAggregate creates an empty aggregate
+func NewAggregate() *Aggregate {
+	return &Aggregate{
+		Version:        schemaVersion,
+		FailuresByType: make(map[string]int),
+		StrategyUsage:  make(map[string]int),
+		BuildSystems:   make(map[string]int),
+		Agents:         make(map[string]*AgentStats),
+	}
+}
+
+// Add adds a run to the aggregate
+func (a *Aggregate) Add(run RunStats) {
+	now := time.Now()
+	if a.Since.IsZero() {
+		a.Since = now
+	}
+	a.Updated = now
+
+	a.Runs++
+	if run.Completed {
+		a.RunsCompleted++
+	}
+	a.Iterations += run.Iterations
+	a.FeaturesCompleted += run.FeaturesCompleted
+	a.FeaturesSkipped += run.FeaturesSkipped
+	a.Failures += run.Failures
+	a.FailuresRecovered += run.FailuresRecovered
+	a.Replans += run.Replans
+	a.DurationSeconds += run.Duration.Seconds()
+
+	for t, n := range run.FailuresByType {
+		a.FailuresByType[t] += n
+	}
+	for s, n := range run.StrategyUsage {
+		a.StrategyUsage[s] += n
+	}
+	if run.BuildSystem != "" {
+		a.BuildSystems[run.BuildSystem]++
+	}
+
+	agent := run.Agent
+	if agent == "" {
+		agent = "unknown"
+	}
+	stats, ok := a.Agents[agent]
+	if !ok {
+		stats = &AgentStats{}
+		a.Agents[agent] = stats
+	}
+	stats.Runs++
+	stats.Iterations += run.Iterations
+	stats.FeaturesCompleted += run.FeaturesCompleted
+	stats.Failures += run.Failures
+}
+
+// Load reads the aggregate at path, returning an empty one if it does not exist
+func Load(path string) (*Aggregate, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewAggregate(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry file: %w", err)
+	}
+
+	a := NewAggregate()
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry file %s: %w", path, err)
+	}
+	if a.Version > schemaVersion {
+		return nil, fmt.Errorf("telemetry file %s has unsupported version %d", path, a.Version)
+	}
+	return a, nil
+}
+
+// Save writes the aggregate to path
+func (a *Aggregate) Save(path string) error {
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create telemetry directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write telemetry file: %w", err)
+	}
+	return nil
+}
+
+// Record adds a run to the aggregate at path
+func Record(path string, run RunStats) error {
+	a, err := Load(path)
+	if err != nil {
+		return err
+	}
+	a.Add(run)
+	return a.Save(path)
+}
+
+// AgentName reduces an agent command to its executable name, dropping the
+// directory and arguments, so that no paths or options are recorded
+func AgentName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// AgentReport holds the derived metrics of one agent
+type AgentReport struct {
+	Runs           int     `json:"runs"`
+	FeaturesPerRun float64 `json:"features_per_run"`
+	FailureRate    float64 `json:"failure_rate"`
+}
+
+// Report is the exportable summary of an aggregate: the totals plus derived
+// adoption and effectiveness metrics
+type Report struct {
+	GeneratedAt       time.Time              `json:"generated_at"`
+	Totals            *Aggregate             `json:"totals"`
+	FeaturesPerRun    float64                `json:"features_per_run"`
+	IterationsPerRun  float64                `json:"iterations_per_run"`
+	FailureRate       float64                `json:"failure_rate"`  // Failures per iteration
+	RecoveryRate      float64                `json:"recovery_rate"` // Share of failures recovered from
+	CompletionRate    float64                `json:"completion_rate"`
+	AverageRunMinutes float64                `json:"average_run_minutes"`
+	Agents            map[string]AgentReport `json:"agents"`
+}
+
+// Report computes the derived metrics of the aggregate
+func (a *Aggregate) Report() Report {
+	r := Report{
+		GeneratedAt:       time.Now(),
+		Totals:            a,
+		FeaturesPerRun:    ratio(a.FeaturesCompleted, a.Runs),
+		IterationsPerRun:  ratio(a.Iterations, a.Runs),
+		FailureRate:       ratio(a.Failures, a.Iterations),
+		RecoveryRate:      ratio(a.FailuresRecovered, a.Failures),
+		CompletionRate:    ratio(a.RunsCompleted, a.Runs),
+		AverageRunMinutes: ratioFloat(a.DurationSeconds/60, a.Runs),
+		Agents:            make(map[string]AgentReport, len(a.Agents)),
+	}
+	for name, s := range a.Agents {
+		r.Agents[name] = AgentReport{
+			Runs:           s.Runs,
+			FeaturesPerRun: ratio(s.FeaturesCompleted, s.Runs),
+			FailureRate:    ratio(s.Failures, s.Iterations),
+		}
+	}
+	return r
+}
+
+// Format returns a human-readable summary of the report
+func (r Report) Format() string {
+	a := r.Totals
+	if a.Runs == 0 {
+		return "No runs recorded yet. Enable telemetry with -telemetry or 'telemetry: true' in the config file.\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Runs recorded:      %d (since %s)\n", a.Runs, a.Since.Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("Completed plans:    %d (%.0f%%)\n", a.RunsCompleted, r.CompletionRate*100))
+	sb.WriteString(fmt.Sprintf("Features per run:   %.2f\n", r.FeaturesPerRun))
+	sb.WriteString(fmt.Sprintf("Iterations per run: %.2f\n", r.IterationsPerRun))
+	sb.WriteString(fmt.Sprintf("Failure rate:       %.0f%% of iterations\n", r.FailureRate*100))
+	sb.WriteString(fmt.Sprintf("Recovery rate:      %.0f%% of failures\n", r.RecoveryRate*100))
+	sb.WriteString(fmt.Sprintf("Average run:        %.1f min\n", r.AverageRunMinutes))
+	if a.Replans > 0 {
+		sb.WriteString(fmt.Sprintf("Replans:            %d\n", a.Replans))
+	}
+
+	writeCounts(&sb, "Failures by type", a.FailuresByType)
+	writeCounts(&sb, "Recovery strategies applied", a.StrategyUsage)
+	writeCounts(&sb, "Build systems", a.BuildSystems)
+
+	if len(r.Agents) > 0 {
+		sb.WriteString("\nAgents:\n")
+		for _, name := range sortedKeys(r.Agents) {
+			ar := r.Agents[name]
+			sb.WriteString(fmt.Sprintf("  %-20s %d run(s), %.2f features/run, %.0f%% failure rate\n",
+				name, ar.Runs, ar.FeaturesPerRun, ar.FailureRate*100))
+		}
+	}
+	return sb.String()
+}
+
+// writeCounts writes a titled list of counts, largest first
+func writeCounts(sb *strings.Builder, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := sortedKeys(counts)
+	sort.SliceStable(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+	sb.WriteString(fmt.Sprintf("\n%s:\n", title))
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("  %-20s %d\n", k, counts[k]))
+	}
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ratio divides two counts, returning 0 when the denominator is 0
+func ratio(n, d int) float64 {
+	return ratioFloat(float64(n), d)
+}
+
+// ratioFloat divides a value by a count, returning 0 when the count is 0
+func ratioFloat(n float64, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return n / float64(d)
+}
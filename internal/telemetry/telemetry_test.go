@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+
+	runs := []RunStats{
+		{
+			Agent:             "claude",
+			BuildSystem:       "go",
+			Iterations:        10,
+			FeaturesCompleted: 4,
+			Failures:          2,
+			FailuresRecovered: 2,
+			FailuresByType:    map[string]int{"test_failure": 2},
+			StrategyUsage:     map[string]int{"retry": 2},
+			Completed:         true,
+			Duration:          10 * time.Minute,
+		},
+		{
+			Agent:             "cursor-agent",
+			BuildSystem:       "go",
+			Iterations:        10,
+			FeaturesCompleted: 2,
+			FeaturesSkipped:   1,
+			Failures:          3,
+			FailuresRecovered: 1,
+			FailuresByType:    map[string]int{"test_failure": 1, "typecheck_failure": 2},
+			StrategyUsage:     map[string]int{"retry": 2, "skip": 1},
+			Replans:           1,
+			Duration:          20 * time.Minute,
+		},
+	}
+	for _, run := range runs {
+		if err := Record(path, run); err != nil {
+			t.Fatalf("Record error = %v", err)
+		}
+	}
+
+	agg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error = %v", err)
+	}
+	if agg.Runs != 2 || agg.RunsCompleted != 1 || agg.Iterations != 20 || agg.FeaturesCompleted != 6 || agg.Replans != 1 {
+		t.Errorf("unexpected totals %+v", agg)
+	}
+	if agg.FailuresByType["test_failure"] != 3 || agg.StrategyUsage["retry"] != 4 || agg.BuildSystems["go"] != 2 {
+		t.Errorf("unexpected counts: failures %v, strategies %v, build systems %v", agg.FailuresByType, agg.StrategyUsage, agg.BuildSystems)
+	}
+	if agg.Agents["claude"].FeaturesCompleted != 4 || agg.Agents["cursor-agent"].Failures != 3 {
+		t.Errorf("unexpected agent stats %+v", agg.Agents)
+	}
+
+	r := agg.Report()
+	if r.FeaturesPerRun != 3 || r.FailureRate != 0.25 || r.CompletionRate != 0.5 || r.AverageRunMinutes != 15 {
+		t.Errorf("unexpected report metrics %+v", r)
+	}
+	if got := r.Agents["claude"].FailureRate; got != 0.2 {
+		t.Errorf("claude failure rate = %v, want 0.2", got)
+	}
+
+	// The exported report is JSON with the totals included
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	if !strings.Contains(string(data), `"features_per_run":3`) || !strings.Contains(string(data), `"totals":{`) {
+		t.Errorf("unexpected export %s", data)
+	}
+
+	text := r.Format()
+	for _, want := range []string{"Runs recorded:      2", "Failures by type:", "typecheck_failure", "cursor-agent"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Format output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	agg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load error = %v", err)
+	}
+	if agg.Runs != 0 {
+		t.Errorf("expected an empty aggregate, got %+v", agg)
+	}
+	if !strings.Contains(agg.Report().Format(), "No runs recorded") {
+		t.Error("empty report should say that no runs were recorded")
+	}
+}
+
+func TestLoad_NewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	os.WriteFile(path, []byte(`{"version": 99}`), 0644)
+	if _, err := Load(path); err == nil {
+		t.Error("Load should reject a newer file version")
+	}
+}
+
+func TestAgentName(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "",
+		"claude":                             "claude",
+		"/home/alice/bin/cursor-agent":       "cursor-agent",
+		"./scripts/agent.sh --model fast -v": "agent.sh",
+		"anthropic:claude-sonnet":            "anthropic:claude-sonnet",
+	}
+	for command, want := range tests {
+		if got := AgentName(command); got != want {
+			t.Errorf("AgentName(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package testreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// goEvent is one line of go test -json output
+type goEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// ParseGoJSON parses go test -json output. Lines that are not test events
+// (e.g., build output mixed into the stream) are ignored.
+func ParseGoJSON(data []byte) (*Report, error) {
+	report := &Report{Format: "go"}
+	output := make(map[[2]string]*strings.Builder)
+	failedTests := make(map[string]bool) // Packages with a failed test
+	events := 0
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var ev goEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil || ev.Action == "" {
+			continue
+		}
+		events++
+
+		key := [2]string{ev.Package, ev.Test}
+		switch ev.Action {
+		case "output":
+			if output[key] == nil {
+				output[key] = &strings.Builder{}
+			}
+			if !isGoStatusLine(ev.Output) {
+				output[key].WriteString(ev.Output)
+			}
+		case "pass", "fail", "skip":
+			status := map[string]Status{"pass": StatusPassed, "fail": StatusFailed, "skip": StatusSkipped}[ev.Action]
+			if ev.Test == "" {
+				// A package that fails without a failed test did not build or crashed
+				if status == StatusFailed && !failedTests[ev.Package] {
+					report.Tests = append(report.Tests, TestCase{Suite: ev.Package, Status: status, Message: text(output[key]), Duration: seconds(ev.Elapsed)})
+				}
+				continue
+			}
+			tc := TestCase{Suite: ev.Package, Name: ev.Test, Status: status, Duration: seconds(ev.Elapsed)}
+			if status == StatusFailed {
+				tc.Message = text(output[key])
+				failedTests[ev.Package] = true
+			}
+			report.Tests = append(report.Tests, tc)
+		}
+	}
+
+	if events == 0 {
+		return nil, fmt.Errorf("no go test -json events found")
+	}
+	return report, nil
+}
+
+// isGoStatusLine reports whether a line of test output only repeats the
+// test's status (=== RUN, --- FAIL, PASS, ok ...)
+func isGoStatusLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"=== ", "--- PASS", "--- FAIL", "--- SKIP", "ok ", "ok\t", "FAIL\t"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return trimmed == "PASS" || trimmed == "FAIL"
+}
+
+// text returns the trimmed contents of a builder, which may be nil
+func text(sb *strings.Builder) string {
+	if sb == nil {
+		return ""
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,85 @@
+package testreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jestResults is the output of jest --json
+type jestResults struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			Duration        *float64 `json:"duration"` // Milliseconds
+			FailureMessages []string `json:"failureMessages"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+// ParseJestJSON parses the output of jest --json (or --outputFile)
+func ParseJestJSON(data []byte) (*Report, error) {
+	var results jestResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse Jest results: %w", err)
+	}
+	if results.TestResults == nil {
+		return nil, fmt.Errorf("no Jest test results found")
+	}
+
+	report := &Report{Format: "jest"}
+	for _, file := range results.TestResults {
+		suite := relativePath(file.Name)
+		// A suite that failed without running its tests did not compile or crashed
+		if file.Status == "failed" && len(file.AssertionResults) == 0 {
+			report.Tests = append(report.Tests, TestCase{Suite: suite, Status: StatusFailed, Message: strings.TrimSpace(file.Message)})
+			continue
+		}
+		for _, a := range file.AssertionResults {
+			tc := TestCase{Suite: suite, Name: a.FullName, Status: jestStatus(a.Status)}
+			if a.Duration != nil {
+				tc.Duration = seconds(*a.Duration / 1000)
+			}
+			if tc.Status == StatusFailed {
+				tc.Message = strings.TrimSpace(strings.Join(a.FailureMessages, "\n"))
+			}
+			report.Tests = append(report.Tests, tc)
+		}
+	}
+	return report, nil
+}
+
+// jestStatus maps a Jest assertion status to a test status
+func jestStatus(status string) Status {
+	switch status {
+	case "passed":
+		return StatusPassed
+	case "failed":
+		return StatusFailed
+	default: // pending, skipped, todo, disabled
+		return StatusSkipped
+	}
+}
+
+// relativePath makes an absolute path relative to the current directory if
+// it is inside it, since Jest reports absolute test file paths
+func relativePath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,198 @@
+package testreport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// junitSuite is a <testsuite> element, or the <testsuites> root holding them
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+// junitCase is a <testcase> element
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+// junitFailure is a <failure> or <error> element
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseJUnitXML parses a JUnit XML report, as written by pytest --junitxml,
+// Gradle, Maven Surefire and most other test runners. The root element may be
+// <testsuites> or a single <testsuite>.
+func ParseJUnitXML(data []byte) (*Report, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+	}
+	report := &Report{Format: "junit"}
+	addJUnitSuite(report, root)
+	return report, nil
+}
+
+// addJUnitSuite adds the test cases of a suite and its nested suites
+func addJUnitSuite(report *Report, suite junitSuite) {
+	for _, c := range suite.Cases {
+		tc := TestCase{Suite: c.ClassName, Name: c.Name, Status: StatusPassed}
+		if tc.Suite == "" {
+			tc.Suite = suite.Name
+		}
+		if t, err := strconv.ParseFloat(strings.ReplaceAll(c.Time, ",", ""), 64); err == nil {
+			tc.Duration = seconds(t)
+		}
+		switch {
+		case c.Failure != nil:
+			tc.Status, tc.Message = StatusFailed, c.Failure.message()
+		case c.Error != nil:
+			tc.Status, tc.Message = StatusFailed, c.Error.message()
+		case c.Skipped != nil:
+			tc.Status = StatusSkipped
+		}
+		report.Tests = append(report.Tests, tc)
+	}
+	for _, nested := range suite.Suites {
+		addJUnitSuite(report, nested)
+	}
+}
+
+// message returns the failure details, falling back to the message attribute
+func (f *junitFailure) message() string {
+	if text := strings.TrimSpace(f.Text); text != "" {
+		return text
+	}
+	return strings.TrimSpace(f.Message)
+}
+
+// junitOutSuites is the <testsuites> root of a written JUnit report
+type junitOutSuites struct {
+	XMLName  xml.Name        `xml:"testsuites"`
+	Name     string          `xml:"name,attr,omitempty"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Suites   []junitOutSuite `xml:"testsuite"`
+}
+
+// junitOutSuite is a written <testsuite> element
+type junitOutSuite struct {
+	Name     string         `xml:"name,attr"`
+	Tests    int            `xml:"tests,attr"`
+	Failures int            `xml:"failures,attr"`
+	Skipped  int            `xml:"skipped,attr"`
+	Time     string         `xml:"time,attr"`
+	Cases    []junitOutCase `xml:"testcase"`
+}
+
+// junitOutCase is a written <testcase> element
+type junitOutCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+// junitSkipped is a written <skipped> element
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// JUnitXML renders the report as JUnit XML, as read by the test summaries of
+// GitHub Actions, GitLab and Jenkins. Tests are grouped into one <testsuite>
+// per Suite, in the order the suites first appear; the message of a failed
+// test becomes its failure, and that of a skipped test the skip reason.
+func (r *Report) JUnitXML(name string) ([]byte, error) {
+	root := junitOutSuites{Name: name}
+	index := make(map[string]int)
+	var total time.Duration
+	durations := make(map[string]time.Duration)
+	for _, tc := range r.Tests {
+		i, ok := index[tc.Suite]
+		if !ok {
+			i = len(root.Suites)
+			index[tc.Suite] = i
+			root.Suites = append(root.Suites, junitOutSuite{Name: tc.Suite})
+		}
+		suite := &root.Suites[i]
+
+		c := junitOutCase{ClassName: tc.Suite, Name: tc.Name, Time: formatSeconds(tc.Duration)}
+		if c.Name == "" {
+			c.Name = tc.Suite
+		}
+		switch tc.Status {
+		case StatusFailed:
+			c.Failure = &junitFailure{Message: firstLine(tc.Message), Text: tc.Message}
+			suite.Failures++
+			root.Failures++
+		case StatusSkipped:
+			c.Skipped = &junitSkipped{Message: tc.Message}
+			suite.Skipped++
+			root.Skipped++
+		}
+		suite.Cases = append(suite.Cases, c)
+		suite.Tests++
+		root.Tests++
+		durations[tc.Suite] += tc.Duration
+		total += tc.Duration
+	}
+	for i := range root.Suites {
+		root.Suites[i].Time = formatSeconds(durations[root.Suites[i].Name])
+	}
+	root.Time = formatSeconds(total)
+
+	data, err := xml.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// WriteJUnitXML writes the report as JUnit XML to path, creating its
+// directory if needed
+func (r *Report) WriteJUnitXML(path, name string) error {
+	data, err := r.JUnitXML(name)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create JUnit report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
+
+// formatSeconds formats a duration in seconds, as JUnit time attributes are
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// firstLine returns the first non-blank line of a message
+func firstLine(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
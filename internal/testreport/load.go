@@ -0,0 +1,105 @@
+package testreport
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultReportPaths are where build systems write test reports by default
+var DefaultReportPaths = map[string]string{
+	"gradle": "build/test-results/test",
+	"maven":  "target/surefire-reports",
+}
+
+// Load reads the test report at path: a JUnit XML file, a directory of JUnit
+// XML files (e.g., Gradle's build/test-results/test), a Jest JSON file or a
+// file of go test -json output. Files last modified before since are
+// ignored, so reports left over from earlier runs are not mistaken for
+// current results. Load returns nil if there is no current report.
+func Load(path string, since time.Time) (*Report, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test report: %w", err)
+	}
+	if !info.IsDir() {
+		if info.ModTime().Before(since) {
+			return nil, nil
+		}
+		return loadFile(path)
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.xml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list test reports: %w", err)
+	}
+	sort.Strings(files)
+	var report *Report
+	for _, file := range files {
+		if fi, err := os.Stat(file); err != nil || fi.ModTime().Before(since) {
+			continue
+		}
+		r, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if report == nil {
+			report = &Report{Format: r.Format}
+		}
+		report.Tests = append(report.Tests, r.Tests...)
+	}
+	return report, nil
+}
+
+// loadFile parses a single report file according to its contents
+func loadFile(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test report: %w", err)
+	}
+	trimmed := bytes.TrimSpace(data)
+	var report *Report
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		report, err = ParseJUnitXML(trimmed)
+	case bytes.Contains(trimmed, []byte(`"testResults"`)):
+		report, err = ParseJestJSON(trimmed)
+	default:
+		report, err = ParseGoJSON(trimmed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
+
+// Parse extracts test results embedded in command or agent output: go test
+// -json events or a Jest JSON result line. It returns nil if the output holds
+// no structured results.
+func Parse(output string) *Report {
+	var goLines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, `{"Time":`) || strings.HasPrefix(line, `{"Action":`):
+			goLines = append(goLines, line)
+		case strings.HasPrefix(line, "{") && strings.Contains(line, `"testResults"`):
+			if report, err := ParseJestJSON([]byte(line)); err == nil {
+				return report
+			}
+		}
+	}
+	if len(goLines) > 0 {
+		if report, err := ParseGoJSON([]byte(strings.Join(goLines, "\n"))); err == nil {
+			return report
+		}
+	}
+	return nil
+}
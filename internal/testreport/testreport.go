@@ -0,0 +1,126 @@
+// Package testreport parses machine-readable test results (go test -json,
+// Jest JSON, JUnit XML as written by pytest, and Gradle or Maven test reports)
+// into a common structure, so recovery guidance can name the failed tests and
+// their messages instead of pasting raw logs. Results can also be written as
+// JUnit XML, for CI test summaries.
+package testreport
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status is the outcome of a test
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// maxMessageLines is the number of lines of a failure message kept in guidance
+const maxMessageLines = 8
+
+// TestCase is the result of a single test
+type TestCase struct {
+	Suite    string        // Package, test file or class containing the test
+	Name     string        // Test name; empty when the whole suite failed (e.g., to compile)
+	Status   Status        // Outcome of the test
+	Message  string        // Failure message, empty unless the test failed
+	Duration time.Duration // Time the test took, 0 if not reported
+}
+
+// String returns the test name qualified by its suite
+func (tc TestCase) String() string {
+	switch {
+	case tc.Suite == "":
+		return tc.Name
+	case tc.Name == "":
+		return tc.Suite
+	}
+	return tc.Suite + ": " + tc.Name
+}
+
+// Report holds the test results of one run
+type Report struct {
+	Format string // Format the report was parsed from (e.g., "go", "jest", "junit", "gradle")
+	Tests  []TestCase
+}
+
+// Failed returns the failed tests
+func (r *Report) Failed() []TestCase {
+	var failed []TestCase
+	for _, tc := range r.Tests {
+		if tc.Status == StatusFailed {
+			failed = append(failed, tc)
+		}
+	}
+	return failed
+}
+
+// Counts returns the number of passed, failed and skipped tests
+func (r *Report) Counts() (passed, failed, skipped int) {
+	for _, tc := range r.Tests {
+		switch tc.Status {
+		case StatusPassed:
+			passed++
+		case StatusFailed:
+			failed++
+		case StatusSkipped:
+			skipped++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// Guidance describes the failed tests for the agent: their names, durations
+// and the start of their failure messages, listing at most max tests (all if
+// max is 0). It returns "" if no test failed.
+func (r *Report) Guidance(max int) string {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return ""
+	}
+	passed, _, _ := r.Counts()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d test(s) failed (%d passed):\n", len(failed), passed)
+	for i, tc := range failed {
+		if max > 0 && i == max {
+			fmt.Fprintf(&sb, "- ... and %d more\n", len(failed)-max)
+			break
+		}
+		sb.WriteString("- " + tc.String())
+		if tc.Duration > 0 {
+			fmt.Fprintf(&sb, " (%s)", tc.Duration.Round(time.Millisecond))
+		}
+		sb.WriteString("\n")
+		for _, line := range messageLines(tc.Message) {
+			sb.WriteString("    " + line + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// messageLines returns the first non-blank lines of a failure message
+func messageLines(message string) []string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(lines) == maxMessageLines {
+			return append(lines, "...")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// seconds converts a duration in (possibly fractional) seconds
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,254 @@
+package testreport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const goJSON = `{"Action":"run","Package":"example.com/app","Test":"TestSum"}
+{"Action":"output","Package":"example.com/app","Test":"TestSum","Output":"=== RUN   TestSum\n"}
+{"Action":"output","Package":"example.com/app","Test":"TestSum","Output":"    sum_test.go:9: got 3, want 4\n"}
+{"Action":"output","Package":"example.com/app","Test":"TestSum","Output":"--- FAIL: TestSum (0.02s)\n"}
+{"Action":"fail","Package":"example.com/app","Test":"TestSum","Elapsed":0.02}
+{"Action":"pass","Package":"example.com/app","Test":"TestProduct","Elapsed":0}
+{"Action":"skip","Package":"example.com/app","Test":"TestSlow","Elapsed":0}
+{"Action":"fail","Package":"example.com/app","Elapsed":0.03}
+{"Action":"output","Package":"example.com/broken","Output":"broken.go:3:1: syntax error\n"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0}
+`
+
+const jestJSON = `{"numFailedTests":1,"testResults":[
+ {"name":"/nonexistent/src/sum.test.js","status":"failed","message":"","assertionResults":[
+  {"fullName":"sum adds numbers","status":"failed","duration":12,"failureMessages":["Error: expect(received).toBe(expected)\n\nExpected: 4\nReceived: 3"]},
+  {"fullName":"sum handles zero","status":"passed","duration":1,"failureMessages":[]},
+  {"fullName":"sum handles overflow","status":"pending","duration":null,"failureMessages":[]}]},
+ {"name":"/nonexistent/src/broken.test.js","status":"failed","message":"SyntaxError: Unexpected token","assertionResults":[]}]}`
+
+const junitXML = `<?xml version="1.0" encoding="utf-8"?>
+<testsuites>
+  <testsuite name="pytest" tests="3" failures="1">
+    <testcase classname="tests.test_sum" name="test_add" time="0.012">
+      <failure message="assert 3 == 4">def test_add():
+&gt;       assert add(1, 2) == 4
+E       assert 3 == 4</failure>
+    </testcase>
+    <testcase classname="tests.test_sum" name="test_zero" time="0.001"/>
+    <testcase classname="tests.test_sum" name="test_big" time="0"><skipped message="slow"/></testcase>
+  </testsuite>
+</testsuites>`
+
+const gradleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.example.CalcTest" tests="2" failures="0" errors="1" time="1.5">
+  <testcase name="divides()" classname="com.example.CalcTest" time="1,200.5">
+    <error message="java.lang.ArithmeticException: / by zero" type="java.lang.ArithmeticException"/>
+  </testcase>
+  <testcase name="adds()" classname="com.example.CalcTest" time="0.003"/>
+</testsuite>`
+
+// check compares the failed tests of a report and its counts
+func check(t *testing.T, r *Report, wantFailed []string, passed, skipped int) {
+	t.Helper()
+	var failed []string
+	for _, tc := range r.Failed() {
+		failed = append(failed, tc.String())
+	}
+	if strings.Join(failed, ",") != strings.Join(wantFailed, ",") {
+		t.Errorf("failed tests = %q, want %q", failed, wantFailed)
+	}
+	if p, _, s := r.Counts(); p != passed || s != skipped {
+		t.Errorf("Counts() passed = %d, skipped = %d; want %d, %d", p, s, passed, skipped)
+	}
+}
+
+func TestParseGoJSON(t *testing.T) {
+	r, err := ParseGoJSON([]byte("go: downloading example.com/dep v1.0.0\n" + goJSON))
+	if err != nil {
+		t.Fatalf("ParseGoJSON() failed: %v", err)
+	}
+	check(t, r, []string{"example.com/app: TestSum", "example.com/broken"}, 1, 1)
+
+	failed := r.Failed()
+	if failed[0].Message != "sum_test.go:9: got 3, want 4" || failed[0].Duration != 20*time.Millisecond {
+		t.Errorf("TestSum = %+v", failed[0])
+	}
+	if failed[1].Message != "broken.go:3:1: syntax error" {
+		t.Errorf("build failure message = %q", failed[1].Message)
+	}
+
+	if _, err := ParseGoJSON([]byte("ok  \texample.com/app\t0.01s\n")); err == nil {
+		t.Error("ParseGoJSON() of plain output succeeded")
+	}
+}
+
+func TestParseJestJSON(t *testing.T) {
+	r, err := ParseJestJSON([]byte(jestJSON))
+	if err != nil {
+		t.Fatalf("ParseJestJSON() failed: %v", err)
+	}
+	check(t, r, []string{"/nonexistent/src/sum.test.js: sum adds numbers", "/nonexistent/src/broken.test.js"}, 1, 1)
+	if tc := r.Failed()[0]; !strings.Contains(tc.Message, "Received: 3") || tc.Duration != 12*time.Millisecond {
+		t.Errorf("failed test = %+v", tc)
+	}
+
+	if _, err := ParseJestJSON([]byte(`{"other":1}`)); err == nil {
+		t.Error("ParseJestJSON() of unrelated JSON succeeded")
+	}
+}
+
+func TestParseJUnitXML(t *testing.T) {
+	r, err := ParseJUnitXML([]byte(junitXML))
+	if err != nil {
+		t.Fatalf("ParseJUnitXML() failed: %v", err)
+	}
+	check(t, r, []string{"tests.test_sum: test_add"}, 1, 1)
+	if tc := r.Failed()[0]; !strings.Contains(tc.Message, "E       assert 3 == 4") || tc.Duration != 12*time.Millisecond {
+		t.Errorf("failed test = %+v", tc)
+	}
+
+	// A single <testsuite> root, with an <error> and only a message attribute
+	r, err = ParseJUnitXML([]byte(gradleXML))
+	if err != nil {
+		t.Fatalf("ParseJUnitXML() failed: %v", err)
+	}
+	check(t, r, []string{"com.example.CalcTest: divides()"}, 1, 0)
+	if tc := r.Failed()[0]; tc.Message != "java.lang.ArithmeticException: / by zero" || tc.Duration != 1200500*time.Millisecond {
+		t.Errorf("failed test = %+v", tc)
+	}
+}
+
+func TestJUnitXML(t *testing.T) {
+	r := &Report{Tests: []TestCase{
+		{Suite: "Feature #1: Login", Name: "GET /login", Status: StatusPassed, Duration: 1500 * time.Millisecond},
+		{Suite: "Feature #1: Login", Name: "POST /login", Status: StatusFailed, Message: "expected status 200, got 500\n<html> & more"},
+		{Suite: "Feature #2: Logout", Name: "not started", Status: StatusSkipped, Message: "not tested yet"},
+	}}
+	data, err := r.JUnitXML("ralph")
+	if err != nil {
+		t.Fatalf("JUnitXML() failed: %v", err)
+	}
+	xml := string(data)
+	for _, want := range []string{
+		`<testsuites name="ralph" tests="3" failures="1" skipped="1" time="1.500">`,
+		`<testsuite name="Feature #1: Login" tests="2" failures="1" skipped="0" time="1.500">`,
+		`<failure message="expected status 200, got 500">expected status 200, got 500&#xA;&lt;html&gt; &amp; more</failure>`,
+		`<skipped message="not tested yet"></skipped>`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("JUnitXML() missing %s in:\n%s", want, xml)
+		}
+	}
+
+	// What is written reads back the same
+	parsed, err := ParseJUnitXML(data)
+	if err != nil {
+		t.Fatalf("ParseJUnitXML() failed: %v", err)
+	}
+	check(t, parsed, []string{"Feature #1: Login: POST /login"}, 1, 1)
+	if tc := parsed.Failed()[0]; tc.Message != r.Tests[1].Message {
+		t.Errorf("failure message = %q, want %q", tc.Message, r.Tests[1].Message)
+	}
+
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	if err := r.WriteJUnitXML(path, "ralph"); err != nil {
+		t.Fatalf("WriteJUnitXML() failed: %v", err)
+	}
+	if written, err := os.ReadFile(path); err != nil || string(written) != xml {
+		t.Errorf("WriteJUnitXML() wrote %q (%v), want the JUnitXML() output", written, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	results := filepath.Join(dir, "build", "test-results", "test")
+	write("build/test-results/test/TEST-com.example.CalcTest.xml", gradleXML)
+	stale := write("build/test-results/test/TEST-com.example.OldTest.xml", junitXML)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{"gradle directory", results, []string{"com.example.CalcTest: divides()"}},
+		{"junit file", write("report.xml", junitXML), []string{"tests.test_sum: test_add"}},
+		{"jest file", write("jest.json", jestJSON), []string{"/nonexistent/src/sum.test.js: sum adds numbers", "/nonexistent/src/broken.test.js"}},
+		{"go file", write("go-test.json", goJSON), []string{"example.com/app: TestSum", "example.com/broken"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Load(tt.path, since)
+			if err != nil || r == nil {
+				t.Fatalf("Load() = %v, %v", r, err)
+			}
+			var failed []string
+			for _, tc := range r.Failed() {
+				failed = append(failed, tc.String())
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("failed tests = %q, want %q", failed, tt.want)
+			}
+		})
+	}
+
+	if r, err := Load(stale, since); r != nil || err != nil {
+		t.Errorf("Load() of a stale report = %v, %v; want nil", r, err)
+	}
+	if r, err := Load(filepath.Join(dir, "missing"), since); r != nil || err != nil {
+		t.Errorf("Load() of a missing report = %v, %v; want nil", r, err)
+	}
+	if _, err := Load(write("bad.xml", "<testsuite><testcase"), since); err == nil {
+		t.Error("Load() of a malformed report succeeded")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if r := Parse("Running tests\n" + goJSON + "done\n"); r == nil || r.Format != "go" || len(r.Failed()) != 2 {
+		t.Errorf("Parse() of go test -json output = %+v", r)
+	}
+	jestLine := strings.ReplaceAll(jestJSON, "\n", "")
+	if r := Parse("> jest --json\n" + jestLine + "\n"); r == nil || r.Format != "jest" {
+		t.Errorf("Parse() of Jest output = %+v", r)
+	}
+	if r := Parse("--- FAIL: TestSum (0.00s)\nFAIL\n"); r != nil {
+		t.Errorf("Parse() of plain output = %+v, want nil", r)
+	}
+}
+
+func TestGuidance(t *testing.T) {
+	r := &Report{Tests: []TestCase{
+		{Suite: "pkg", Name: "TestA", Status: StatusFailed, Message: "line 1\n\nline 2", Duration: 1500 * time.Millisecond},
+		{Suite: "pkg", Name: "TestB", Status: StatusFailed, Message: strings.Repeat("x\n", 20)},
+		{Suite: "pkg", Name: "TestC", Status: StatusFailed},
+		{Suite: "pkg", Name: "TestD", Status: StatusPassed},
+	}}
+
+	got := r.Guidance(2)
+	for _, want := range []string{"3 test(s) failed (1 passed):", "- pkg: TestA (1.5s)\n    line 1\n    line 2\n", "- ... and 1 more"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Guidance() missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "TestC") || strings.Count(got, "    x") != maxMessageLines {
+		t.Errorf("Guidance() did not truncate:\n%s", got)
+	}
+	if (&Report{Tests: []TestCase{{Name: "ok", Status: StatusPassed}}}).Guidance(0) != "" {
+		t.Error("Guidance() without failures is not empty")
+	}
+}
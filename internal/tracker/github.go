@@ -0,0 +1,220 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// githubPageSize is the number of issues requested per page
+const githubPageSize = 100
+
+// githubCommentLimit bounds the comments fetched for a thread
+const githubCommentLimit = 500
+
+// github syncs with the issues of a GitHub repository
+type github struct {
+	*client
+	repo   string
+	labels []string
+}
+
+// newGitHubClient creates a client for the GitHub API. Without a token,
+// requests are anonymous, which is enough for public repositories.
+func newGitHubClient(cfg Config) *client {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	c := &client{
+		baseURL:    firstNonEmpty(cfg.URL, DefaultGitHubURL),
+		headers:    map[string]string{"Accept": "application/vnd.github+json"},
+		httpClient: &http.Client{Timeout: timeout},
+	}
+	if cfg.Token != "" {
+		c.headers["Authorization"] = "Bearer " + cfg.Token
+	}
+	return c
+}
+
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// Issues returns the open issues of the repository, without pull requests
+func (g *github) Issues(ctx context.Context) ([]Issue, error) {
+	query := url.Values{"state": {"open"}, "per_page": {strconv.Itoa(githubPageSize)}}
+	if len(g.labels) > 0 {
+		query.Set("labels", strings.Join(g.labels, ","))
+	}
+
+	var issues []Issue
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var batch []githubIssue
+		if err := g.do(ctx, http.MethodGet, "/repos/"+g.repo+"/issues?"+query.Encode(), nil, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list GitHub issues: %w", err)
+		}
+		for _, gi := range batch {
+			if gi.PullRequest != nil {
+				continue
+			}
+			issue := Issue{Key: strconv.Itoa(gi.Number), Title: gi.Title, Body: gi.Body, URL: gi.HTMLURL}
+			for _, l := range gi.Labels {
+				issue.Labels = append(issue.Labels, l.Name)
+			}
+			issues = append(issues, issue)
+		}
+		if len(batch) < githubPageSize {
+			return issues, nil
+		}
+	}
+}
+
+// Comment adds a comment to an issue
+func (g *github) Comment(ctx context.Context, key, body string) error {
+	if err := g.do(ctx, http.MethodPost, "/repos/"+g.repo+"/issues/"+key+"/comments", map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("failed to comment on GitHub issue #%s: %w", key, err)
+	}
+	return nil
+}
+
+// Close closes an issue as completed
+func (g *github) Close(ctx context.Context, key string) error {
+	body := map[string]string{"state": "closed", "state_reason": "completed"}
+	if err := g.do(ctx, http.MethodPatch, "/repos/"+g.repo+"/issues/"+key, body, nil); err != nil {
+		return fmt.Errorf("failed to close GitHub issue #%s: %w", key, err)
+	}
+	return nil
+}
+
+// GitHubRef identifies a GitHub issue or pull request
+type GitHubRef struct {
+	APIURL string // Base URL of the API serving the repository
+	Repo   string // owner/repo
+	Number int
+}
+
+// String returns the reference as owner/repo#number
+func (r GitHubRef) String() string {
+	return fmt.Sprintf("%s#%d", r.Repo, r.Number)
+}
+
+// ParseGitHubRef parses an issue or pull request given as owner/repo#123 or
+// as its URL. URLs of GitHub Enterprise hosts are served by https://HOST/api/v3.
+func ParseGitHubRef(s string) (GitHubRef, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("invalid issue %q: expected owner/repo#123 or an issue or pull request URL", s)
+
+	if repo, num, ok := strings.Cut(s, "#"); ok && !strings.Contains(repo, "://") {
+		number, err := strconv.Atoi(num)
+		if err != nil || number <= 0 || strings.Count(repo, "/") != 1 || strings.HasPrefix(repo, "/") || strings.HasSuffix(repo, "/") {
+			return GitHubRef{}, invalid
+		}
+		return GitHubRef{APIURL: DefaultGitHubURL, Repo: repo, Number: number}, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return GitHubRef{}, invalid
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || (parts[2] != "issues" && parts[2] != "pull") {
+		return GitHubRef{}, invalid
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return GitHubRef{}, invalid
+	}
+	apiURL := DefaultGitHubURL
+	if u.Host != "github.com" && u.Host != "www.github.com" {
+		apiURL = u.Scheme + "://" + u.Host + "/api/v3"
+	}
+	return GitHubRef{APIURL: apiURL, Repo: parts[0] + "/" + parts[1], Number: number}, nil
+}
+
+// Comment is a comment of an issue or pull request
+type Comment struct {
+	Author string
+	Body   string
+}
+
+// Thread is an issue or pull request with its comments
+type Thread struct {
+	Ref         GitHubRef
+	Title       string
+	Body        string
+	URL         string
+	PullRequest bool
+	Comments    []Comment
+}
+
+// FetchGitHubThread fetches an issue or pull request and its comments. The
+// API URL of ref is used unless cfg sets one; Project and Labels are ignored.
+func FetchGitHubThread(ctx context.Context, cfg Config, ref GitHubRef) (*Thread, error) {
+	cfg.URL = firstNonEmpty(cfg.URL, ref.APIURL)
+	c := newGitHubClient(cfg)
+	path := fmt.Sprintf("/repos/%s/issues/%d", ref.Repo, ref.Number)
+
+	var gi githubIssue
+	if err := c.do(ctx, http.MethodGet, path, nil, &gi); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	thread := &Thread{Ref: ref, Title: gi.Title, Body: gi.Body, URL: gi.HTMLURL, PullRequest: gi.PullRequest != nil}
+
+	for page := 1; len(thread.Comments) < githubCommentLimit; page++ {
+		var batch []struct {
+			Body string `json:"body"`
+			User struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		}
+		query := fmt.Sprintf("?per_page=%d&page=%d", githubPageSize, page)
+		if err := c.do(ctx, http.MethodGet, path+"/comments"+query, nil, &batch); err != nil {
+			return nil, fmt.Errorf("failed to fetch the comments of %s: %w", ref, err)
+		}
+		for _, gc := range batch {
+			thread.Comments = append(thread.Comments, Comment{Author: gc.User.Login, Body: gc.Body})
+		}
+		if len(batch) < githubPageSize {
+			break
+		}
+	}
+	return thread, nil
+}
+
+// Markdown renders the thread as notes for plan generation
+func (t *Thread) Markdown() string {
+	kind := "Issue"
+	if t.PullRequest {
+		kind = "Pull request"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s %s", t.Title, kind, t.Ref)
+	if t.URL != "" {
+		fmt.Fprintf(&b, " (%s)", t.URL)
+	}
+	b.WriteString("\n\n## Description\n\n")
+	if body := strings.TrimSpace(t.Body); body != "" {
+		b.WriteString(body + "\n")
+	} else {
+		b.WriteString("(no description)\n")
+	}
+	if len(t.Comments) > 0 {
+		b.WriteString("\n## Comments\n")
+		for _, c := range t.Comments {
+			fmt.Fprintf(&b, "\n### @%s\n\n%s\n", c.Author, strings.TrimSpace(c.Body))
+		}
+	}
+	return b.String()
+}
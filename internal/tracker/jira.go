@@ -0,0 +1,124 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// jiraPageSize is the number of issues requested per page
+const jiraPageSize = 100
+
+// jira syncs with the issues of a Jira project
+type jira struct {
+	*client
+	project string
+	labels  []string
+	cloud   bool // Jira Cloud, which pages searches with tokens
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+	} `json:"fields"`
+}
+
+type jiraSearch struct {
+	Issues        []jiraIssue `json:"issues"`
+	Total         int         `json:"total"`         // Jira Server and Data Center
+	NextPageToken string      `json:"nextPageToken"` // Jira Cloud
+}
+
+// jql returns the query selecting the open issues of the project
+func (j *jira) jql() string {
+	clauses := []string{fmt.Sprintf("project = %q", j.project), "statusCategory != Done"}
+	for _, label := range j.labels {
+		clauses = append(clauses, fmt.Sprintf("labels = %q", label))
+	}
+	return strings.Join(clauses, " AND ") + " ORDER BY created ASC"
+}
+
+// Issues returns the issues of the project that are not done
+func (j *jira) Issues(ctx context.Context) ([]Issue, error) {
+	query := url.Values{
+		"jql":        {j.jql()},
+		"fields":     {"summary,description,labels"},
+		"maxResults": {strconv.Itoa(jiraPageSize)},
+	}
+	path := "/rest/api/2/search"
+	if j.cloud {
+		path = "/rest/api/2/search/jql"
+	}
+
+	var issues []Issue
+	for {
+		if !j.cloud {
+			query.Set("startAt", strconv.Itoa(len(issues)))
+		}
+		var result jiraSearch
+		if err := j.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to search Jira issues: %w", err)
+		}
+		for _, ji := range result.Issues {
+			issues = append(issues, Issue{
+				Key:    ji.Key,
+				Title:  ji.Fields.Summary,
+				Body:   ji.Fields.Description,
+				Labels: ji.Fields.Labels,
+				URL:    strings.TrimRight(j.baseURL, "/") + "/browse/" + ji.Key,
+			})
+		}
+		if j.cloud {
+			if result.NextPageToken == "" {
+				return issues, nil
+			}
+			query.Set("nextPageToken", result.NextPageToken)
+		} else if len(result.Issues) == 0 || len(issues) >= result.Total {
+			return issues, nil
+		}
+	}
+}
+
+// Comment adds a comment to an issue
+func (j *jira) Comment(ctx context.Context, key, body string) error {
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("failed to comment on Jira issue %s: %w", key, err)
+	}
+	return nil
+}
+
+// Close moves an issue to the first status of the "done" category its
+// workflow allows
+func (j *jira) Close(ctx context.Context, key string) error {
+	path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+	var result struct {
+		Transitions []struct {
+			ID string `json:"id"`
+			To struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return fmt.Errorf("failed to list transitions of Jira issue %s: %w", key, err)
+	}
+	for _, t := range result.Transitions {
+		if t.To.StatusCategory.Key != "done" {
+			continue
+		}
+		body := map[string]any{"transition": map[string]string{"id": t.ID}}
+		if err := j.do(ctx, http.MethodPost, path, body, nil); err != nil {
+			return fmt.Errorf("failed to close Jira issue %s: %w", key, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to close Jira issue %s: no transition to a done status", key)
+}
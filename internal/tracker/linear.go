@@ -0,0 +1,142 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// linear syncs with the issues of a Linear team
+type linear struct {
+	*client
+	team   string
+	labels []string
+}
+
+const linearIssuesQuery = `query($team: String!, $after: String) {
+  issues(first: 100, after: $after, filter: {team: {key: {eq: $team}}, state: {type: {nin: ["completed", "canceled"]}}}) {
+    nodes { identifier title description url labels { nodes { name } } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+const linearCommentMutation = `mutation($issue: String!, $body: String!) {
+  commentCreate(input: {issueId: $issue, body: $body}) { success }
+}`
+
+const linearDoneStatesQuery = `query($team: String!) {
+  workflowStates(filter: {team: {key: {eq: $team}}, type: {eq: "completed"}}) { nodes { id position } }
+}`
+
+const linearUpdateMutation = `mutation($issue: String!, $state: String!) {
+  issueUpdate(id: $issue, input: {stateId: $state}) { success }
+}`
+
+// graphql sends a GraphQL request and decodes its data into out
+func (l *linear) graphql(ctx context.Context, query string, variables map[string]any, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := l.do(ctx, http.MethodPost, "", map[string]any{"query": query, "variables": variables}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("Linear returned errors: %s", strings.Join(messages, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("failed to parse tracker response: %w", err)
+	}
+	return nil
+}
+
+// Issues returns the issues of the team that are not completed or canceled
+func (l *linear) Issues(ctx context.Context) ([]Issue, error) {
+	var issues []Issue
+	variables := map[string]any{"team": l.team}
+	for {
+		var data struct {
+			Issues struct {
+				Nodes []struct {
+					Identifier  string `json:"identifier"`
+					Title       string `json:"title"`
+					Description string `json:"description"`
+					URL         string `json:"url"`
+					Labels      struct {
+						Nodes []struct {
+							Name string `json:"name"`
+						} `json:"nodes"`
+					} `json:"labels"`
+				} `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"issues"`
+		}
+		if err := l.graphql(ctx, linearIssuesQuery, variables, &data); err != nil {
+			return nil, fmt.Errorf("failed to list Linear issues: %w", err)
+		}
+		for _, n := range data.Issues.Nodes {
+			issue := Issue{Key: n.Identifier, Title: n.Title, Body: n.Description, URL: n.URL}
+			for _, label := range n.Labels.Nodes {
+				issue.Labels = append(issue.Labels, label.Name)
+			}
+			if hasLabels(issue.Labels, l.labels) {
+				issues = append(issues, issue)
+			}
+		}
+		if !data.Issues.PageInfo.HasNextPage {
+			return issues, nil
+		}
+		variables["after"] = data.Issues.PageInfo.EndCursor
+	}
+}
+
+// Comment adds a comment to an issue
+func (l *linear) Comment(ctx context.Context, key, body string) error {
+	if err := l.graphql(ctx, linearCommentMutation, map[string]any{"issue": key, "body": body}, nil); err != nil {
+		return fmt.Errorf("failed to comment on Linear issue %s: %w", key, err)
+	}
+	return nil
+}
+
+// Close moves an issue to the team's first completed state
+func (l *linear) Close(ctx context.Context, key string) error {
+	var data struct {
+		WorkflowStates struct {
+			Nodes []struct {
+				ID       string  `json:"id"`
+				Position float64 `json:"position"`
+			} `json:"nodes"`
+		} `json:"workflowStates"`
+	}
+	if err := l.graphql(ctx, linearDoneStatesQuery, map[string]any{"team": l.team}, &data); err != nil {
+		return fmt.Errorf("failed to find the completed state of Linear team %s: %w", l.team, err)
+	}
+	states := data.WorkflowStates.Nodes
+	if len(states) == 0 {
+		return fmt.Errorf("failed to close Linear issue %s: team %s has no completed state", key, l.team)
+	}
+	state := states[0]
+	for _, s := range states[1:] {
+		if s.Position < state.Position {
+			state = s
+		}
+	}
+	if err := l.graphql(ctx, linearUpdateMutation, map[string]any{"issue": key, "state": state.ID}, nil); err != nil {
+		return fmt.Errorf("failed to close Linear issue %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package tracker syncs plan features with an issue tracker (GitHub Issues,
+// Jira or Linear). Open issues are imported as features, one per issue, with
+// the issue's labels mapped to the feature's category, and issues whose
+// feature has been tested are commented on and closed.
+//
+// Imported features keep a reference to their issue (e.g., "github:42"), so
+// running the sync again neither imports an issue twice nor closes an issue
+// Ralph did not import.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// Provider identifies an issue tracker
+type Provider string
+
+const (
+	// ProviderGitHub syncs with GitHub Issues
+	ProviderGitHub Provider = "github"
+	// ProviderJira syncs with Jira
+	ProviderJira Provider = "jira"
+	// ProviderLinear syncs with Linear
+	ProviderLinear Provider = "linear"
+)
+
+const (
+	// DefaultGitHubURL is the base URL of the GitHub API
+	DefaultGitHubURL = "https://api.github.com"
+	// DefaultLinearURL is the URL of the Linear GraphQL API
+	DefaultLinearURL = "https://api.linear.app/graphql"
+	// DefaultTimeout is the default HTTP request timeout
+	DefaultTimeout = 30 * time.Second
+)
+
+// DefaultTokenEnv is the environment variable the token is read from when
+// none is configured, per provider
+var DefaultTokenEnv = map[Provider]string{
+	ProviderGitHub: "GITHUB_TOKEN",
+	ProviderJira:   "JIRA_API_TOKEN",
+	ProviderLinear: "LINEAR_API_KEY",
+}
+
+// ParseProvider converts a string to a Provider
+func ParseProvider(s string) (Provider, error) {
+	switch p := Provider(strings.ToLower(strings.TrimSpace(s))); p {
+	case ProviderGitHub, ProviderJira, ProviderLinear:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown tracker provider %q: must be github, jira or linear", s)
+	}
+}
+
+// Issue is an open issue of the tracker
+type Issue struct {
+	Key    string // Number (GitHub) or identifier (Jira, Linear) of the issue
+	Title  string
+	Body   string
+	Labels []string
+	URL    string // Web page of the issue
+}
+
+// Tracker lists open issues and updates them
+type Tracker interface {
+	// Issues returns the open issues of the project
+	Issues(ctx context.Context) ([]Issue, error)
+	// Comment adds a comment to an issue
+	Comment(ctx context.Context, key, body string) error
+	// Close closes (or moves to done) an issue
+	Close(ctx context.Context, key string) error
+}
+
+// Config holds the settings of a tracker
+type Config struct {
+	Provider Provider
+	Project  string        // owner/repo (GitHub), project key (Jira) or team key (Linear)
+	URL      string        // API base URL (required for Jira)
+	Token    string        // API token; "email:token" uses basic authentication with Jira Cloud
+	Labels   []string      // Only issues with all of these labels are imported
+	Timeout  time.Duration // HTTP request timeout
+}
+
+// New creates a tracker for the provider of cfg
+func New(cfg Config) (Tracker, error) {
+	if cfg.Project == "" {
+		return nil, fmt.Errorf("tracker project is required")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("tracker token is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	c := &client{httpClient: &http.Client{Timeout: cfg.Timeout}}
+
+	switch cfg.Provider {
+	case ProviderGitHub:
+		if strings.Count(cfg.Project, "/") != 1 {
+			return nil, fmt.Errorf("GitHub project must be owner/repo, got %q", cfg.Project)
+		}
+		return &github{client: newGitHubClient(cfg), repo: cfg.Project, labels: cfg.Labels}, nil
+	case ProviderJira:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("Jira requires the URL of the site (e.g., https://example.atlassian.net)")
+		}
+		c.baseURL = cfg.URL
+		// Jira Cloud authenticates with an account email and API token,
+		// Server and Data Center with a personal access token
+		cloud := strings.Contains(cfg.Token, ":")
+		c.headers = map[string]string{"Authorization": "Bearer " + cfg.Token}
+		if cloud {
+			c.headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg.Token))
+		}
+		return &jira{client: c, project: cfg.Project, labels: cfg.Labels, cloud: cloud}, nil
+	case ProviderLinear:
+		c.baseURL = firstNonEmpty(cfg.URL, DefaultLinearURL)
+		c.headers = map[string]string{"Authorization": cfg.Token}
+		return &linear{client: c, team: cfg.Project, labels: cfg.Labels}, nil
+	default:
+		_, err := ParseProvider(string(cfg.Provider))
+		return nil, err
+	}
+}
+
+// Ref returns the reference stored on a feature imported from an issue
+func Ref(provider Provider, key string) string {
+	return string(provider) + ":" + key
+}
+
+// Import appends a feature for each issue no feature references yet, and
+// returns the updated plan and the added features. A feature's category is
+// the category of the first label found in categories, or else its first label.
+func Import(plans []plan.Plan, issues []Issue, provider Provider, categories map[string]string) ([]plan.Plan, []plan.Plan) {
+	referenced := make(map[string]bool)
+	nextID := 1
+	for _, p := range plans {
+		if p.Issue != "" {
+			referenced[p.Issue] = true
+		}
+		if p.ID >= nextID {
+			nextID = p.ID + 1
+		}
+	}
+
+	var added []plan.Plan
+	for _, issue := range issues {
+		ref := Ref(provider, issue.Key)
+		if referenced[ref] {
+			continue
+		}
+		referenced[ref] = true
+		feature := plan.Plan{
+			ID:          nextID,
+			Category:    category(issue.Labels, categories),
+			Description: strings.TrimSpace(issue.Title),
+			Steps:       steps(issue.Body),
+			Issue:       ref,
+		}
+		nextID++
+		plans = append(plans, feature)
+		added = append(added, feature)
+	}
+	return plans, added
+}
+
+// Done returns the tested features whose issue is among the open issues
+func Done(plans []plan.Plan, issues []Issue, provider Provider) []plan.Plan {
+	open := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		open[Ref(provider, issue.Key)] = true
+	}
+	var done []plan.Plan
+	for _, p := range plans {
+		if p.Tested && open[p.Issue] {
+			done = append(done, p)
+		}
+	}
+	return done
+}
+
+// Key returns the issue key of a feature's reference, and false if the
+// feature was not imported from the provider
+func Key(provider Provider, ref string) (string, bool) {
+	return strings.CutPrefix(ref, string(provider)+":")
+}
+
+// category maps the labels of an issue to a feature category
+func category(labels []string, categories map[string]string) string {
+	for _, label := range labels {
+		for l, c := range categories {
+			if strings.EqualFold(l, label) {
+				return c
+			}
+		}
+	}
+	if len(labels) > 0 {
+		return strings.ToLower(labels[0])
+	}
+	return ""
+}
+
+// steps returns the list items (bullets and task list entries) of an issue
+// body as feature steps
+func steps(body string) []string {
+	var result []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		var item string
+		switch {
+		case strings.HasPrefix(line, "- ["), strings.HasPrefix(line, "* ["):
+			if _, rest, ok := strings.Cut(line, "]"); ok {
+				item = rest
+			}
+		case strings.HasPrefix(line, "- "), strings.HasPrefix(line, "* "):
+			item = line[2:]
+		}
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// hasLabels reports whether labels include all of want (case-insensitively)
+func hasLabels(labels, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, l := range labels {
+			if strings.EqualFold(l, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// client sends JSON requests to a tracker API
+type client struct {
+	baseURL    string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response
+// into out, if not nil
+func (c *client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.baseURL, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("tracker request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read tracker response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("tracker returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse tracker response: %w", err)
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
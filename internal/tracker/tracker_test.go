@@ -0,0 +1,312 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// recorder records the requests a test server receives
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (r *recorder) add(req *http.Request, body string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line := req.Method + " " + req.URL.Path
+	if body != "" {
+		line += " " + body
+	}
+	r.requests = append(r.requests, line)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"no project", Config{Provider: ProviderGitHub, Token: "t"}, "project is required"},
+		{"no token", Config{Provider: ProviderGitHub, Project: "o/r"}, "token is required"},
+		{"bad repo", Config{Provider: ProviderGitHub, Project: "repo", Token: "t"}, "owner/repo"},
+		{"jira without url", Config{Provider: ProviderJira, Project: "PROJ", Token: "t"}, "URL of the site"},
+		{"unknown", Config{Provider: "gitlab", Project: "p", Token: "t"}, "unknown tracker provider"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.add(r, string(body))
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodGet {
+			if r.URL.Query().Get("labels") != "ralph" {
+				t.Errorf("labels = %q, want ralph", r.URL.Query().Get("labels"))
+			}
+			w.Write([]byte(`[
+				{"number": 3, "title": "Login form", "body": "- [ ] Email field", "html_url": "https://github.com/o/r/issues/3", "labels": [{"name": "ui"}]},
+				{"number": 4, "title": "A pull request", "pull_request": {}}
+			]`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tr, err := New(Config{Provider: ProviderGitHub, Project: "o/r", URL: srv.URL, Token: "secret", Labels: []string{"ralph"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	issues, err := tr.Issues(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{{Key: "3", Title: "Login form", Body: "- [ ] Email field", Labels: []string{"ui"}, URL: "https://github.com/o/r/issues/3"}}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("Issues() = %+v, want %+v (pull requests are skipped)", issues, want)
+	}
+
+	if err := tr.Comment(ctx, "3", "done"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Close(ctx, "3"); err != nil {
+		t.Fatal(err)
+	}
+	wantRequests := []string{
+		"GET /repos/o/r/issues",
+		`POST /repos/o/r/issues/3/comments {"body":"done"}`,
+		`PATCH /repos/o/r/issues/3 {"state":"closed","state_reason":"completed"}`,
+	}
+	if !reflect.DeepEqual(rec.requests, wantRequests) {
+		t.Errorf("requests = %q, want %q", rec.requests, wantRequests)
+	}
+}
+
+func TestJira(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.add(r, string(body))
+		switch {
+		case r.URL.Path == "/rest/api/2/search":
+			if jql := r.URL.Query().Get("jql"); !strings.Contains(jql, `project = "PROJ"`) || !strings.Contains(jql, `labels = "ralph"`) {
+				t.Errorf("jql = %q", jql)
+			}
+			w.Write([]byte(`{"total": 1, "issues": [{"key": "PROJ-7", "fields": {"summary": "Export CSV", "description": "* Header row", "labels": ["feature"]}}]}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/transitions"):
+			w.Write([]byte(`{"transitions": [
+				{"id": "11", "to": {"statusCategory": {"key": "indeterminate"}}},
+				{"id": "31", "to": {"statusCategory": {"key": "done"}}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	tr, err := New(Config{Provider: ProviderJira, Project: "PROJ", URL: srv.URL, Token: "pat", Labels: []string{"ralph"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	issues, err := tr.Issues(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Key != "PROJ-7" || issues[0].URL != srv.URL+"/browse/PROJ-7" {
+		t.Errorf("Issues() = %+v", issues)
+	}
+	if err := tr.Close(ctx, "PROJ-7"); err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.requests[len(rec.requests)-1]; last != `POST /rest/api/2/issue/PROJ-7/transitions {"transition":{"id":"31"}}` {
+		t.Errorf("close request = %q, want the transition to done", last)
+	}
+}
+
+func TestLinear(t *testing.T) {
+	var mu sync.Mutex
+	var mutations []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_key" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case strings.Contains(req.Query, "issues("):
+			w.Write([]byte(`{"data": {"issues": {"nodes": [
+				{"identifier": "ENG-1", "title": "Dark mode", "labels": {"nodes": [{"name": "UI"}, {"name": "ralph"}]}},
+				{"identifier": "ENG-2", "title": "Unlabeled", "labels": {"nodes": []}}
+			], "pageInfo": {"hasNextPage": false}}}}`))
+		case strings.Contains(req.Query, "workflowStates"):
+			w.Write([]byte(`{"data": {"workflowStates": {"nodes": [{"id": "late", "position": 5}, {"id": "done", "position": 2}]}}}`))
+		case strings.Contains(req.Query, "commentCreate") && req.Variables["issue"] == "ENG-404":
+			w.Write([]byte(`{"errors": [{"message": "Entity not found"}]}`))
+		default:
+			mu.Lock()
+			mutations = append(mutations, req.Variables)
+			mu.Unlock()
+			w.Write([]byte(`{"data": {}}`))
+		}
+	}))
+	defer srv.Close()
+
+	tr, err := New(Config{Provider: ProviderLinear, Project: "ENG", URL: srv.URL, Token: "lin_key", Labels: []string{"Ralph"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	issues, err := tr.Issues(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Key != "ENG-1" {
+		t.Errorf("Issues() = %+v, want only the issue labeled ralph", issues)
+	}
+	if err := tr.Close(ctx, "ENG-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(mutations) != 1 || mutations[0]["state"] != "done" {
+		t.Errorf("mutations = %v, want the first completed state", mutations)
+	}
+	if err := tr.Comment(ctx, "ENG-404", "done"); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("Comment() error = %v, want the GraphQL error", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 4, Description: "Existing", Issue: "github:1"},
+		{ID: 2, Description: "Manual"},
+	}
+	issues := []Issue{
+		{Key: "1", Title: "Already imported"},
+		{Key: "2", Title: " Add search ", Body: "Search the catalog.\n\n- [x] Index\n- [ ] Query box\n* Ranking", Labels: []string{"Enhancement", "ui"}},
+		{Key: "3", Title: "Flaky login", Labels: []string{"Bug"}},
+	}
+	plans, added := Import(plans, issues, ProviderGitHub, map[string]string{"bug": "fix", "ui": "ui"})
+
+	want := []plan.Plan{
+		{ID: 5, Category: "ui", Description: "Add search", Steps: []string{"Index", "Query box", "Ranking"}, Issue: "github:2"},
+		{ID: 6, Category: "fix", Description: "Flaky login", Issue: "github:3"},
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("Import() added = %+v, want %+v", added, want)
+	}
+	if len(plans) != 4 {
+		t.Errorf("Import() = %d features, want 4", len(plans))
+	}
+
+	if _, again := Import(plans, issues, ProviderGitHub, nil); len(again) != 0 {
+		t.Errorf("second Import() added %+v, want nothing", again)
+	}
+	if got := category([]string{"Backend"}, nil); got != "backend" {
+		t.Errorf("category() = %q, want the first label", got)
+	}
+}
+
+func TestDone(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Tested: true, Issue: "github:1"},
+		{ID: 2, Tested: true, Issue: "github:2"}, // Issue already closed
+		{ID: 3, Issue: "github:3"},
+		{ID: 4, Tested: true, Issue: "jira:1"},
+		{ID: 5, Tested: true},
+	}
+	issues := []Issue{{Key: "1"}, {Key: "3"}}
+	done := Done(plans, issues, ProviderGitHub)
+	if len(done) != 1 || done[0].ID != 1 {
+		t.Errorf("Done() = %+v, want feature #1", done)
+	}
+	if key, ok := Key(ProviderGitHub, "github:12"); !ok || key != "12" {
+		t.Errorf("Key() = %q, %v", key, ok)
+	}
+	if _, ok := Key(ProviderGitHub, "jira:PROJ-1"); ok {
+		t.Error("Key() accepted another provider's reference")
+	}
+}
+
+func TestParseGitHubRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want GitHubRef
+	}{
+		{"acme/shop#12", GitHubRef{APIURL: DefaultGitHubURL, Repo: "acme/shop", Number: 12}},
+		{"https://github.com/acme/shop/issues/7", GitHubRef{APIURL: DefaultGitHubURL, Repo: "acme/shop", Number: 7}},
+		{"https://github.com/acme/shop/pull/8/files", GitHubRef{APIURL: DefaultGitHubURL, Repo: "acme/shop", Number: 8}},
+		{"https://git.acme.com/team/shop/issues/3", GitHubRef{APIURL: "https://git.acme.com/api/v3", Repo: "team/shop", Number: 3}},
+	}
+	for _, tt := range tests {
+		got, err := ParseGitHubRef(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseGitHubRef(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"shop#12", "acme/shop#x", "acme/shop", "https://github.com/acme/shop", "https://github.com/acme/shop/wiki/3"} {
+		if _, err := ParseGitHubRef(in); err == nil {
+			t.Errorf("ParseGitHubRef(%q) succeeded", in)
+		}
+	}
+}
+
+func TestFetchGitHubThread(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("anonymous request sent Authorization %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/repos/acme/shop/issues/12":
+			w.Write([]byte(`{"number": 12, "title": "Wishlist", "body": "Users can save products.", "html_url": "https://github.com/acme/shop/pull/12", "pull_request": {}}`))
+		case "/repos/acme/shop/issues/12/comments":
+			w.Write([]byte(`[{"body": "Also share the list by link", "user": {"login": "maria"}}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ref := GitHubRef{APIURL: srv.URL, Repo: "acme/shop", Number: 12}
+	thread, err := FetchGitHubThread(context.Background(), Config{}, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thread.PullRequest || len(thread.Comments) != 1 || thread.Comments[0].Author != "maria" {
+		t.Errorf("FetchGitHubThread() = %+v", thread)
+	}
+	notes := thread.Markdown()
+	for _, want := range []string{"# Wishlist", "Pull request acme/shop#12", "Users can save products.", "### @maria", "share the list"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("Markdown() = %q, missing %q", notes, want)
+		}
+	}
+
+	if _, err := FetchGitHubThread(context.Background(), Config{}, GitHubRef{APIURL: srv.URL, Repo: "acme/shop", Number: 404}); err == nil {
+		t.Error("FetchGitHubThread() of a missing issue succeeded")
+	}
+}
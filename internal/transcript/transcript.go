@@ -0,0 +1,241 @@
+// Package transcript records every prompt sent to the agent and the response
+// it gave in an append-only JSONL file per run (.ralph/transcripts/<run-id>.jsonl),
+// for compliance review of what the AI was asked and what it replied.
+// Redactors scrub sensitive text before anything is written.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileSuffix is the extension of transcript files
+const fileSuffix = ".jsonl"
+
+// Redacted replaces text removed by a redactor
+const Redacted = "[REDACTED]"
+
+// Entry is one exchange with the agent
+type Entry struct {
+	Time      time.Time     `json:"time"`
+	RunID     string        `json:"run_id"`
+	Iteration int           `json:"iteration,omitempty"`  // 0 outside of iterations (e.g., plan generation)
+	FeatureID int           `json:"feature_id,omitempty"` // Feature being worked on, if known
+	Agent     string        `json:"agent"`                // Agent command or API backend
+	Prompt    string        `json:"prompt"`
+	Response  string        `json:"response"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Redactor scrubs sensitive text from prompts and responses before they are
+// recorded
+type Redactor func(string) string
+
+// PatternRedactor returns a redactor replacing matches of the regular
+// expressions with Redacted
+func PatternRedactor(patterns []string) (Redactor, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return func(s string) string {
+		for _, re := range res {
+			s = re.ReplaceAllString(s, Redacted)
+		}
+		return s
+	}, nil
+}
+
+// Recorder appends entries to the transcript of a run. It is safe for
+// concurrent use (e.g., by parallel agents).
+type Recorder struct {
+	mu        sync.Mutex
+	path      string
+	runID     string
+	redactors []Redactor
+	iteration int
+	featureID int
+}
+
+// NewRecorder creates a recorder writing the transcript of runID to dir
+func NewRecorder(dir, runID string) *Recorder {
+	return &Recorder{path: filepath.Join(dir, runID+fileSuffix), runID: runID}
+}
+
+// Path returns the transcript file
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// AddRedactor adds a redactor applied to every entry recorded from now on
+func (r *Recorder) AddRedactor(redactor Redactor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactors = append(r.redactors, redactor)
+}
+
+// SetIteration sets the iteration and feature recorded with the following entries
+func (r *Recorder) SetIteration(iteration, featureID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.iteration = iteration
+	r.featureID = featureID
+}
+
+// Record redacts an entry and appends it to the transcript. The run,
+// iteration and feature are filled in from the recorder.
+func (r *Recorder) Record(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.RunID = r.runID
+	e.Iteration = r.iteration
+	e.FeatureID = r.featureID
+	for _, redact := range r.redactors {
+		e.Prompt = redact(e.Prompt)
+		e.Response = redact(e.Response)
+		e.Error = redact(e.Error)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript entry: %w", err)
+	}
+	// Transcripts may hold source code and business details, so only the
+	// owner can read them
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// Store reads the transcripts in a directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store for the transcripts in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Runs returns the IDs of the runs with a transcript, oldest first
+func (s *Store) Runs() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read transcript directory: %w", err)
+	}
+	var runs []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), fileSuffix) {
+			runs = append(runs, strings.TrimSuffix(e.Name(), fileSuffix))
+		}
+	}
+	// Run IDs embed their start time, so name order is chronological
+	sort.Strings(runs)
+	return runs, nil
+}
+
+// Find resolves a run reference: a run ID, a unique ID prefix, or "latest"
+func (s *Store) Find(ref string) (string, error) {
+	runs, err := s.Runs()
+	if err != nil {
+		return "", err
+	}
+	if len(runs) == 0 {
+		return "", fmt.Errorf("no transcripts recorded in %s (enable them with -transcript)", s.dir)
+	}
+	if ref == "" || strings.EqualFold(ref, "latest") || strings.EqualFold(ref, "last") {
+		return runs[len(runs)-1], nil
+	}
+
+	var matches []string
+	for _, run := range runs {
+		if run == ref {
+			return run, nil
+		}
+		if strings.HasPrefix(run, ref) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no transcript for run %s in %s", ref, s.dir)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("run reference %q is ambiguous (%d matches)", ref, len(matches))
+}
+
+// Load reads the entries of a run's transcript. A line cut short by a crash
+// is skipped.
+func (s *Store) Load(runID string) ([]Entry, error) {
+	f, err := os.Open(filepath.Join(s.dir, runID+fileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	// Prompts include the plan and responses can be long
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return entries, nil
+}
+
+// Format formats an entry for reading
+func Format(e Entry) string {
+	var b strings.Builder
+	heading := "Agent call"
+	if e.Iteration > 0 {
+		heading = fmt.Sprintf("Iteration %d", e.Iteration)
+	}
+	if e.FeatureID > 0 {
+		heading += fmt.Sprintf(" (feature #%d)", e.FeatureID)
+	}
+	fmt.Fprintf(&b, "=== %s - %s - %s (%s) ===\n", heading, e.Time.Local().Format("2006-01-02 15:04:05"),
+		e.Agent, e.Duration.Round(100*time.Millisecond))
+	fmt.Fprintf(&b, "--- Prompt ---\n%s\n", strings.TrimSpace(e.Prompt))
+	fmt.Fprintf(&b, "--- Response ---\n%s\n", strings.TrimSpace(e.Response))
+	if e.Error != "" {
+		fmt.Fprintf(&b, "--- Error ---\n%s\n", strings.TrimSpace(e.Error))
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "transcripts")
+	r := NewRecorder(dir, "run-20261016-120000")
+	redact, err := PatternRedactor([]string{`sk-[A-Za-z0-9]+`, `(?i)password=\S+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddRedactor(redact)
+
+	if err := r.Record(Entry{Agent: "claude", Prompt: "Generate a plan", Response: "[]"}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	r.SetIteration(2, 5)
+	if err := r.Record(Entry{Agent: "claude", Prompt: "Use key sk-abc123", Response: "Set password=hunter2", Error: "exit 1",
+		Duration: 1500 * time.Millisecond}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	info, err := os.Stat(r.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("transcript mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	entries, err := NewStore(dir).Load("run-20261016-120000")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load() = %d entries, want 2", len(entries))
+	}
+	if entries[0].Iteration != 0 || entries[0].RunID != "run-20261016-120000" {
+		t.Errorf("first entry = %+v", entries[0])
+	}
+	e := entries[1]
+	if e.Iteration != 2 || e.FeatureID != 5 || e.Prompt != "Use key [REDACTED]" || e.Response != "Set [REDACTED]" {
+		t.Errorf("second entry = %+v", e)
+	}
+
+	formatted := Format(e)
+	for _, want := range []string{"=== Iteration 2 (feature #5)", "claude (1.5s)", "--- Prompt ---\nUse key [REDACTED]", "--- Error ---\nexit 1"} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("Format() missing %q:\n%s", want, formatted)
+		}
+	}
+
+	if _, err := PatternRedactor([]string{"("}); err == nil {
+		t.Error("PatternRedactor() accepted an invalid pattern")
+	}
+}
+
+func TestRecorderConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRecorder(dir, "run-1")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Record(Entry{Prompt: strings.Repeat("p", 10000), Response: "ok"})
+		}()
+	}
+	wg.Wait()
+	entries, err := NewStore(dir).Load("run-1")
+	if err != nil || len(entries) != 20 {
+		t.Errorf("Load() = %d entries, %v; want 20", len(entries), err)
+	}
+}
+
+func TestStoreFind(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	if _, err := store.Find("latest"); err == nil {
+		t.Error("Find() with no transcripts succeeded")
+	}
+	for _, id := range []string{"run-20261015-090000", "run-20261016-120000", "run-20261016-130000"} {
+		if err := os.WriteFile(filepath.Join(dir, id+".jsonl"), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"latest", "run-20261016-130000", false},
+		{"", "run-20261016-130000", false},
+		{"run-20261015", "run-20261015-090000", false},
+		{"run-20261016-120000", "run-20261016-120000", false},
+		{"run-20261016", "", true},
+		{"run-2025", "", true},
+	}
+	for _, tt := range tests {
+		got, err := store.Find(tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Find(%q) = %q, %v; want %q", tt.ref, got, err, tt.want)
+		}
+	}
+}
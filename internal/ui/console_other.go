@@ -0,0 +1,11 @@
+//go:build !windows
+
+package ui
+
+import "os"
+
+// enableColors reports whether the terminal f renders ANSI colors, which
+// Unix terminals do
+func enableColors(f *os.File) bool {
+	return true
+}
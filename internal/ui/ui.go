@@ -3,10 +3,12 @@
 package ui
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -68,14 +70,15 @@ func New(cfg OutputConfig) *UI {
 		cfg.Writer = os.Stdout
 	}
 
-	// Detect if output is a TTY
-	isTTY := false
+	// Detect if output is a TTY, and whether it renders colors
+	isTTY, colors := false, false
 	if f, ok := cfg.Writer.(*os.File); ok {
 		isTTY = term.IsTerminal(int(f.Fd()))
+		colors = isTTY && enableColors(f)
 	}
 
 	// Disable colors if not a TTY or NoColor is set
-	if !isTTY || cfg.NoColor {
+	if !colors || cfg.NoColor {
 		cfg.NoColor = true
 	}
 
@@ -464,6 +467,48 @@ func (s *Spinner) SetMessage(msg string) {
 	s.mu.Unlock()
 }
 
+// StreamWriter forwards streamed output (e.g., live agent stdout) to the UI line
+// by line, so that quiet and JSON modes are respected
+type StreamWriter struct {
+	ui  *UI
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewStreamWriter creates a writer that prints complete lines through the UI.
+// Call Flush after the stream ends to print any trailing partial line.
+func (u *UI) NewStreamWriter() *StreamWriter {
+	return &StreamWriter{ui: u}
+}
+
+// Write buffers p and prints every complete line
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.ui.Print("%s", strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush prints any buffered partial line
+func (w *StreamWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.ui.Print("%s", strings.TrimRight(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
+
 // Summary holds information for the summary dashboard
 type Summary struct {
 	FeaturesCompleted int
@@ -475,6 +520,7 @@ type Summary struct {
 	StartTime         time.Time
 	EndTime           time.Time
 	Errors            []string
+	Agents            map[string]int // Iterations served by each agent, when fallback agents are configured
 }
 
 // PrintSummary displays a summary dashboard at the end of execution
@@ -496,6 +542,9 @@ func (u *UI) PrintSummary(s Summary) {
 			"duration_seconds":    duration.Seconds(),
 			"errors":              s.Errors,
 		}
+		if len(s.Agents) > 0 {
+			summaryJSON["agents"] = s.Agents
+		}
 		data, _ := json.Marshal(map[string]interface{}{"type": "summary", "data": summaryJSON})
 		fmt.Fprintln(u.config.Writer, string(data))
 		return
@@ -532,6 +581,22 @@ func (u *UI) PrintSummary(s Summary) {
 			fmt.Sprintf("%d", s.FailuresRecovered))
 	}
 	
+	// Iterations served by each agent, busiest first
+	agents := make([]string, 0, len(s.Agents))
+	for name := range s.Agents {
+		agents = append(agents, name)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		if s.Agents[agents[i]] != s.Agents[agents[j]] {
+			return s.Agents[agents[i]] > s.Agents[agents[j]]
+		}
+		return agents[i] < agents[j]
+	})
+	for _, name := range agents {
+		fmt.Fprintf(u.config.Writer, "│ %-20s %20s │\n", truncate("Agent "+name+":", 20),
+			fmt.Sprintf("%d iteration(s)", s.Agents[name]))
+	}
+
 	// Duration
 	fmt.Fprintf(u.config.Writer, "│ %-20s %20s │\n", "Duration:",
 		formatDuration(duration))
@@ -548,6 +613,14 @@ func (u *UI) PrintSummary(s Summary) {
 	}
 }
 
+// truncate shortens a string to the specified length
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
 // StatusLine displays a real-time status line
 func (u *UI) StatusLine(iteration, total int, feature string) {
 	if u.config.Quiet || u.config.JSONOutput {
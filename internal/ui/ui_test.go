@@ -352,6 +352,7 @@ func TestSummary(t *testing.T) {
 		StartTime:         time.Now().Add(-5 * time.Minute),
 		EndTime:           time.Now(),
 		Errors:            []string{"Error 1", "Error 2"},
+		Agents:            map[string]int{"claude": 6, "aider": 2},
 	}
 
 	ui.PrintSummary(summary)
@@ -371,6 +372,9 @@ func TestSummary(t *testing.T) {
 	if !strings.Contains(output, "Error 1") {
 		t.Error("Summary should list errors")
 	}
+	if !strings.Contains(output, "Agent claude:") || strings.Index(output, "claude") > strings.Index(output, "aider") {
+		t.Errorf("Summary should list the agents that served iterations, busiest first, got: %s", output)
+	}
 }
 
 func TestSummaryJSON(t *testing.T) {
@@ -580,3 +584,48 @@ func TestSubHeader(t *testing.T) {
 		t.Errorf("SubHeader output should contain formatted sub-title, got: %s", output)
 	}
 }
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := DefaultConfig()
+	cfg.Writer = &buf
+	u := New(cfg)
+
+	w := u.NewStreamWriter()
+	w.Write([]byte("first li"))
+	if buf.Len() != 0 {
+		t.Errorf("partial line should be buffered, got %q", buf.String())
+	}
+	w.Write([]byte("ne\r\nsecond line\nthird"))
+	if buf.String() != "first line\nsecond line\n" {
+		t.Errorf("unexpected streamed output %q", buf.String())
+	}
+	w.Flush()
+	if !strings.HasSuffix(buf.String(), "third\n") {
+		t.Errorf("Flush should print trailing partial line, got %q", buf.String())
+	}
+}
+
+func TestStreamWriterQuietAndJSON(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := DefaultConfig()
+	cfg.Writer = &buf
+	cfg.Quiet = true
+	New(cfg).NewStreamWriter().Write([]byte("hidden\n"))
+	if buf.Len() != 0 {
+		t.Errorf("quiet mode should suppress streamed output, got %q", buf.String())
+	}
+
+	buf.Reset()
+	cfg.Quiet = false
+	cfg.JSONOutput = true
+	New(cfg).NewStreamWriter().Write([]byte("agent line\n"))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("streamed output should be JSON in JSON mode: %v (%q)", err, buf.String())
+	}
+	if entry["level"] != "output" || entry["message"] != "agent line" {
+		t.Errorf("unexpected JSON entry: %v", entry)
+	}
+}
@@ -0,0 +1,384 @@
+// Package worktree runs Ralph in a temporary git worktree so that the user's
+// checkout is only touched once a run has finished and passed validation.
+//
+// The worktree starts from an exact copy of the main checkout, including
+// uncommitted and untracked (non-ignored) files. When the run succeeds, the
+// changes made in the worktree are applied to the main checkout as uncommitted
+// changes, and Ralph's state files (plan, progress, ...) are copied back.
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// baselineMessage is the commit message of the commit recording the starting state
+const baselineMessage = "ralph: isolated worktree baseline"
+
+// Options controls how a worktree is created
+type Options struct {
+	// StateFiles are paths relative to the repository root that are copied into
+	// the worktree and copied back verbatim on merge instead of being diffed
+	StateFiles []string
+	// Exclude lists paths relative to the repository root that are neither
+	// copied into the worktree nor merged back (e.g., ".ralph")
+	Exclude []string
+	// Dir is the parent directory for the worktree (default: the system temp dir)
+	Dir string
+}
+
+// Worktree is a temporary checkout of the repository
+type Worktree struct {
+	RepoRoot string // Root of the main checkout
+	Path     string // Root of the worktree
+	Base     string // Commit recording the state the worktree started from
+	Result   string // Commit recording the sealed result of the run (see Seal)
+
+	stateFiles []string
+	exclude    []string
+}
+
+// RepoRoot returns the root of the git checkout containing dir
+func RepoRoot(dir string) (string, error) {
+	root, err := gitOutput(dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	return root, nil
+}
+
+// Create adds a detached worktree mirroring the current state of the main
+// checkout at repoRoot
+func Create(repoRoot string, opts Options) (*Worktree, error) {
+	root, err := RepoRoot(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("isolated worktree requires a git repository: %w", err)
+	}
+	head, err := gitOutput(root, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("isolated worktree requires at least one commit: %w", err)
+	}
+
+	parent := opts.Dir
+	if parent == "" {
+		parent = os.TempDir()
+	}
+	dir, err := os.MkdirTemp(parent, "ralph-worktree-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	// git worktree add requires the target to not exist (or be empty)
+	os.Remove(dir)
+
+	// "git stash create" captures uncommitted tracked changes without touching the checkout
+	start := head
+	if stash, err := gitOutput(root, nil, "stash", "create", "ralph isolated worktree"); err == nil && stash != "" {
+		start = stash
+	}
+
+	if _, err := gitOutput(root, nil, "worktree", "add", "--detach", dir, start); err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+
+	w := &Worktree{
+		RepoRoot:   root,
+		Path:       dir,
+		stateFiles: cleanPaths(opts.StateFiles),
+		exclude:    cleanPaths(opts.Exclude),
+	}
+
+	if err := w.copyUntracked(); err != nil {
+		w.Remove()
+		return nil, err
+	}
+	for _, file := range w.stateFiles {
+		if err := copyFile(filepath.Join(root, file), filepath.Join(dir, file)); err != nil && !os.IsNotExist(err) {
+			w.Remove()
+			return nil, fmt.Errorf("failed to copy %s into worktree: %w", file, err)
+		}
+	}
+
+	base, err := w.snapshot(head)
+	if err != nil {
+		w.Remove()
+		return nil, err
+	}
+	w.Base = base
+
+	return w, nil
+}
+
+// copyUntracked copies untracked, non-ignored files from the main checkout
+func (w *Worktree) copyUntracked() error {
+	out, err := gitOutput(w.RepoRoot, nil, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	for _, file := range strings.Split(out, "\x00") {
+		if file == "" || w.isExcluded(file) {
+			continue
+		}
+		if err := copyFile(filepath.Join(w.RepoRoot, file), filepath.Join(w.Path, file)); err != nil {
+			return fmt.Errorf("failed to copy %s into worktree: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// snapshot records the worktree's current contents as a commit on top of parent
+// without moving any branch. Plumbing commands are used so that no git identity
+// needs to be configured.
+func (w *Worktree) snapshot(parent string) (string, error) {
+	if _, err := gitOutput(w.Path, nil, "add", "-A", "--", "."); err != nil {
+		return "", fmt.Errorf("failed to stage worktree: %w", err)
+	}
+	tree, err := gitOutput(w.Path, nil, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to record worktree: %w", err)
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=ralph", "GIT_AUTHOR_EMAIL=ralph@localhost",
+		"GIT_COMMITTER_NAME=ralph", "GIT_COMMITTER_EMAIL=ralph@localhost",
+	}
+	commit, err := gitOutput(w.Path, env, "commit-tree", tree, "-p", parent, "-m", baselineMessage)
+	if err != nil {
+		return "", fmt.Errorf("failed to record worktree: %w", err)
+	}
+	if _, err := gitOutput(w.Path, nil, "reset", "-q", commit); err != nil {
+		return "", fmt.Errorf("failed to reset worktree: %w", err)
+	}
+	return commit, nil
+}
+
+// pathspec returns the pathspec selecting everything except state files and exclusions
+func (w *Worktree) pathspec() []string {
+	spec := []string{"--", "."}
+	for _, p := range append(append([]string{}, w.stateFiles...), w.exclude...) {
+		spec = append(spec, ":(exclude)"+p)
+	}
+	return spec
+}
+
+// Seal records the worktree's current contents, including commits made there,
+// as the result to merge back. Files created afterwards (e.g., build artifacts
+// from validation) are not merged. Returns the changed files.
+func (w *Worktree) Seal() ([]string, error) {
+	result, err := w.snapshot("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	w.Result = result
+	return w.ChangedFiles()
+}
+
+// Diff returns a binary patch of the sealed changes. State files and
+// exclusions are omitted.
+func (w *Worktree) Diff() (string, error) {
+	if err := w.ensureSealed(); err != nil {
+		return "", err
+	}
+	args := append([]string{"diff", "--binary", w.Base, w.Result}, w.pathspec()...)
+	out, err := gitRaw(w.Path, nil, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff worktree: %w", err)
+	}
+	return out, nil
+}
+
+// ChangedFiles lists the files in the sealed changes
+func (w *Worktree) ChangedFiles() ([]string, error) {
+	if err := w.ensureSealed(); err != nil {
+		return nil, err
+	}
+	args := append([]string{"diff", "--name-only", w.Base, w.Result}, w.pathspec()...)
+	out, err := gitOutput(w.Path, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktree changes: %w", err)
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// ensureSealed seals the worktree if Seal has not been called yet
+func (w *Worktree) ensureSealed() error {
+	if w.Result != "" {
+		return nil
+	}
+	_, err := w.Seal()
+	return err
+}
+
+// MergeBack applies the sealed changes to the main checkout as uncommitted
+// changes and copies the state files back. Nothing is written if the patch
+// does not apply cleanly. Returns the changed files.
+func (w *Worktree) MergeBack() ([]string, error) {
+	files, err := w.ApplyChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range w.stateFiles {
+		err := copyFile(filepath.Join(w.Path, file), filepath.Join(w.RepoRoot, file))
+		if err != nil && !os.IsNotExist(err) {
+			return files, fmt.Errorf("failed to copy %s back: %w", file, err)
+		}
+	}
+
+	return files, nil
+}
+
+// ApplyChanges applies the sealed changes to the main checkout as uncommitted
+// changes, leaving the state files alone. Nothing is written if the patch does
+// not apply cleanly. Returns the changed files.
+func (w *Worktree) ApplyChanges() ([]string, error) {
+	files, err := w.ChangedFiles()
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	patch, err := w.Diff()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gitStdin(w.RepoRoot, patch, "apply", "--check", "--binary", "-"); err != nil {
+		return nil, fmt.Errorf("changes do not apply cleanly to %s (was it modified during the run?): %w", w.RepoRoot, err)
+	}
+	if _, err := gitStdin(w.RepoRoot, patch, "apply", "--binary", "-"); err != nil {
+		return nil, fmt.Errorf("failed to apply changes to %s: %w", w.RepoRoot, err)
+	}
+	return files, nil
+}
+
+// Translate maps a path inside the main checkout to the same path in the worktree
+func (w *Worktree) Translate(path string) string {
+	rel, err := filepath.Rel(w.RepoRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.Join(w.Path, rel)
+}
+
+// Run executes a command (e.g., the type check or test command) in the worktree
+// and returns its combined output
+func (w *Worktree) Run(command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Dir = w.Path
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// Remove deletes the worktree
+func (w *Worktree) Remove() error {
+	if _, err := gitOutput(w.RepoRoot, nil, "worktree", "remove", "--force", w.Path); err != nil {
+		// Fall back to deleting the directory and pruning the stale entry
+		os.RemoveAll(w.Path)
+		gitOutput(w.RepoRoot, nil, "worktree", "prune")
+	}
+	return nil
+}
+
+// isExcluded reports whether path is an exclusion or lies below one
+func (w *Worktree) isExcluded(path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	for _, ex := range append(append([]string{}, w.stateFiles...), w.exclude...) {
+		if path == ex || strings.HasPrefix(path, ex+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPaths normalizes relative paths, dropping empty, absolute and escaping ones
+func cleanPaths(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		if p == "" || filepath.IsAbs(p) {
+			continue
+		}
+		p = filepath.ToSlash(filepath.Clean(p))
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// copyFile copies src to dst, creating parent directories and keeping the mode
+func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		os.Remove(dst)
+		return os.Symlink(target, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// gitOutput runs git in dir and returns trimmed stdout
+func gitOutput(dir string, env []string, args ...string) (string, error) {
+	out, err := gitRaw(dir, env, args...)
+	return strings.TrimSpace(out), err
+}
+
+// gitRaw runs git in dir and returns stdout unmodified
+func gitRaw(dir string, env []string, args ...string) (string, error) {
+	return runGit(dir, env, nil, args...)
+}
+
+// gitStdin runs git in dir with the given standard input
+func gitStdin(dir, stdin string, args ...string) (string, error) {
+	return runGit(dir, nil, strings.NewReader(stdin), args...)
+}
+
+// runGit runs a git command and includes stderr in the error
+func runGit(dir string, env []string, stdin io.Reader, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
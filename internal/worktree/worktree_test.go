@@ -0,0 +1,203 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a git repository with one committed file
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("ignored/\n"), 0644)
+	for _, args := range [][]string{{"init", "-q"}, {"add", "."}, {"commit", "-q", "-m", "initial"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestCreate_MirrorsMainCheckout(t *testing.T) {
+	repo := initRepo(t)
+
+	// Uncommitted, untracked, ignored and state files in the main checkout
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main // wip\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "notes.md"), []byte("notes"), 0644)
+	os.MkdirAll(filepath.Join(repo, "ignored"), 0755)
+	os.WriteFile(filepath.Join(repo, "ignored", "cache"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(repo, "plan.json"), []byte("[]"), 0644)
+	os.MkdirAll(filepath.Join(repo, ".ralph", "history"), 0755)
+	os.WriteFile(filepath.Join(repo, ".ralph", "history", "run.json"), []byte("{}"), 0644)
+
+	wt, err := Create(repo, Options{StateFiles: []string{"plan.json", "progress.txt"}, Exclude: []string{".ralph"}, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer wt.Remove()
+
+	if got := readFile(t, filepath.Join(wt.Path, "main.go")); got != "package main // wip\n" {
+		t.Errorf("uncommitted change not mirrored, got %q", got)
+	}
+	if got := readFile(t, filepath.Join(wt.Path, "notes.md")); got != "notes" {
+		t.Errorf("untracked file not mirrored, got %q", got)
+	}
+	if got := readFile(t, filepath.Join(wt.Path, "plan.json")); got != "[]" {
+		t.Errorf("state file not copied, got %q", got)
+	}
+	for _, p := range []string{"ignored/cache", ".ralph/history/run.json"} {
+		if _, err := os.Stat(filepath.Join(wt.Path, p)); !os.IsNotExist(err) {
+			t.Errorf("%s should not be copied into the worktree", p)
+		}
+	}
+
+	files, err := wt.ChangedFiles()
+	if err != nil || len(files) != 0 {
+		t.Errorf("fresh worktree should have no changes, got %v (%v)", files, err)
+	}
+	if got := readFile(t, filepath.Join(repo, "main.go")); got != "package main // wip\n" {
+		t.Errorf("creating the worktree must not touch the main checkout, got %q", got)
+	}
+}
+
+func TestMergeBack(t *testing.T) {
+	repo := initRepo(t)
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main // wip\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "plan.json"), []byte(`[{"tested":false}]`), 0644)
+
+	wt, err := Create(repo, Options{StateFiles: []string{"plan.json"}, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer wt.Remove()
+
+	// The agent edits, adds and commits files in the worktree
+	os.WriteFile(filepath.Join(wt.Path, "main.go"), []byte("package main // done\n"), 0644)
+	os.WriteFile(filepath.Join(wt.Path, "feature.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(wt.Path, "plan.json"), []byte(`[{"tested":true}]`), 0644)
+	cmd := exec.Command("git", "commit", "-q", "-am", "agent commit")
+	cmd.Dir = wt.Path
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("commit in worktree failed: %v\n%s", err, out)
+	}
+
+	// The main checkout is untouched until the merge
+	if got := readFile(t, filepath.Join(repo, "main.go")); got != "package main // wip\n" {
+		t.Fatalf("main checkout changed during the run: %q", got)
+	}
+
+	if _, err := wt.Seal(); err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	// Artifacts produced after sealing (e.g., by validation) are not merged
+	os.WriteFile(filepath.Join(wt.Path, "app.bin"), []byte("binary"), 0755)
+
+	files, err := wt.MergeBack()
+	if err != nil {
+		t.Fatalf("MergeBack failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "app.bin")); !os.IsNotExist(err) {
+		t.Error("files created after Seal should not be merged")
+	}
+	if strings.Join(files, ",") != "feature.go,main.go" {
+		t.Errorf("unexpected changed files %v", files)
+	}
+	if got := readFile(t, filepath.Join(repo, "main.go")); got != "package main // done\n" {
+		t.Errorf("main.go not merged, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "feature.go")); err != nil {
+		t.Error("new file not merged")
+	}
+	if got := readFile(t, filepath.Join(repo, "plan.json")); got != `[{"tested":true}]` {
+		t.Errorf("state file not copied back, got %q", got)
+	}
+}
+
+func TestMergeBack_ConflictLeavesCheckoutUntouched(t *testing.T) {
+	repo := initRepo(t)
+	wt, err := Create(repo, Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer wt.Remove()
+
+	os.WriteFile(filepath.Join(wt.Path, "main.go"), []byte("package main // agent\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main // user\n"), 0644)
+
+	if _, err := wt.MergeBack(); err == nil {
+		t.Fatal("expected conflicting merge to fail")
+	}
+	if got := readFile(t, filepath.Join(repo, "main.go")); got != "package main // user\n" {
+		t.Errorf("user's change was overwritten: %q", got)
+	}
+}
+
+func TestApplyChanges_LeavesStateFiles(t *testing.T) {
+	repo := initRepo(t)
+	os.WriteFile(filepath.Join(repo, "plan.json"), []byte(`[]`), 0644)
+	wt, err := Create(repo, Options{StateFiles: []string{"plan.json"}, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer wt.Remove()
+
+	os.WriteFile(filepath.Join(wt.Path, "feature.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(wt.Path, "plan.json"), []byte(`[{"tested":true}]`), 0644)
+
+	files, err := wt.ApplyChanges()
+	if err != nil {
+		t.Fatalf("ApplyChanges failed: %v", err)
+	}
+	if strings.Join(files, ",") != "feature.go" {
+		t.Errorf("unexpected changed files %v", files)
+	}
+	if got := readFile(t, filepath.Join(repo, "plan.json")); got != `[]` {
+		t.Errorf("state file copied back: %q", got)
+	}
+}
+
+func TestTranslateAndRemove(t *testing.T) {
+	repo := initRepo(t)
+	wt, err := Create(repo, Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if got := wt.Translate(filepath.Join(wt.RepoRoot, "sub", "dir")); got != filepath.Join(wt.Path, "sub", "dir") {
+		t.Errorf("Translate = %s", got)
+	}
+	if got := wt.Translate("/elsewhere"); got != "/elsewhere" {
+		t.Errorf("paths outside the repository should be unchanged, got %s", got)
+	}
+
+	if err := wt.Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Error("worktree directory should be removed")
+	}
+}
@@ -0,0 +1,84 @@
+package goals
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+var (
+	// criterionURL finds a URL in a success criterion
+	criterionURL = regexp.MustCompile("https?://[^\\s\"'`<>)]+")
+	// criterionStatus finds an HTTP status code in a success criterion
+	criterionStatus = regexp.MustCompile(`\b[1-5][0-9]{2}\b`)
+	// criterionCode finds `quoted` commands and paths in a success criterion
+	criterionCode = regexp.MustCompile("`([^`]+)`")
+)
+
+// FinishedGoals returns the goals that are not complete yet although every
+// plan item generated from them is tested
+func (m *Manager) FinishedGoals() []*Goal {
+	var finished []*Goal
+	for i := range m.goals {
+		goal := &m.goals[i]
+		if goal.Status == StatusComplete || len(goal.GeneratedPlanIDs) == 0 {
+			continue
+		}
+		done := true
+		for _, id := range goal.GeneratedPlanIDs {
+			if p := plan.GetByID(m.plans, id); p == nil || !p.Tested {
+				done = false
+				break
+			}
+		}
+		if done {
+			finished = append(finished, goal)
+		}
+	}
+	return finished
+}
+
+// CriteriaValidations translates success criteria into validations, for the
+// criteria that name something checkable: a URL becomes an http_get (with
+// the status code the criterion mentions, 200 otherwise), a `quoted command`
+// a cli_command and a `quoted/path` a file_exists. Other criteria are prose
+// and have no validation.
+func CriteriaValidations(criteria []string) []plan.ValidationDefinition {
+	var validations []plan.ValidationDefinition
+	for _, criterion := range criteria {
+		if v, ok := criterionValidation(strings.TrimSpace(criterion)); ok {
+			validations = append(validations, v)
+		}
+	}
+	return validations
+}
+
+// criterionValidation translates a single success criterion
+func criterionValidation(criterion string) (plan.ValidationDefinition, bool) {
+	if url := criterionURL.FindString(criterion); url != "" {
+		url = strings.TrimRight(url, ".,;:")
+		status := 200
+		if code := criterionStatus.FindString(strings.Replace(criterion, url, "", 1)); code != "" {
+			status, _ = strconv.Atoi(code)
+		}
+		return plan.ValidationDefinition{Type: "http_get", URL: url, ExpectedStatus: status, Description: criterion}, true
+	}
+	for _, m := range criterionCode.FindAllStringSubmatch(criterion, -1) {
+		code := strings.TrimSpace(m[1])
+		if fields := strings.Fields(code); len(fields) > 1 {
+			return plan.ValidationDefinition{Type: "cli_command", Command: fields[0], Args: fields[1:], Description: criterion}, true
+		}
+		if strings.ContainsAny(code, "/.") {
+			return plan.ValidationDefinition{Type: "file_exists", Path: code, Description: criterion}, true
+		}
+	}
+	return plan.ValidationDefinition{}, false
+}
+
+// CelebrationMessage returns a celebration message for a completed goal
+func CelebrationMessage(goal *Goal) string {
+	return fmt.Sprintf("Goal '%s' achieved! All %d plan item(s) are done.", goal.Description, len(goal.GeneratedPlanIDs))
+}
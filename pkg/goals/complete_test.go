@@ -0,0 +1,58 @@
+package goals
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestFinishedGoals(t *testing.T) {
+	mgr := NewManager([]plan.Plan{
+		{ID: 1, Description: "Login", Tested: true},
+		{ID: 2, Description: "Logout", Tested: true},
+		{ID: 3, Description: "Billing"},
+	})
+	for _, g := range []Goal{
+		{ID: "auth", Description: "Add auth", GeneratedPlanIDs: []int{1, 2}},
+		{ID: "billing", Description: "Add billing", GeneratedPlanIDs: []int{2, 3}},
+		{ID: "docs", Description: "Write docs"},
+		{ID: "done", Description: "Already done", GeneratedPlanIDs: []int{1}, Status: StatusComplete},
+		{ID: "stale", Description: "Plan item removed", GeneratedPlanIDs: []int{9}},
+	} {
+		if err := mgr.AddGoal(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	finished := mgr.FinishedGoals()
+	if len(finished) != 1 || finished[0].ID != "auth" {
+		t.Fatalf("FinishedGoals() = %v, want only auth", finished)
+	}
+	if msg := CelebrationMessage(finished[0]); msg != "Goal 'Add auth' achieved! All 2 plan item(s) are done." {
+		t.Errorf("CelebrationMessage() = %q", msg)
+	}
+}
+
+func TestCriteriaValidations(t *testing.T) {
+	got := CriteriaValidations([]string{
+		"GET http://localhost:8080/health returns 200.",
+		"Unauthenticated requests to http://localhost:8080/admin get a 401",
+		"`go test ./auth/...` passes",
+		"A `docs/auth.md` guide exists",
+		"Users can log in with Google",
+		"`make` succeeds",
+	})
+	want := []plan.ValidationDefinition{
+		{Type: "http_get", URL: "http://localhost:8080/health", ExpectedStatus: 200, Description: "GET http://localhost:8080/health returns 200."},
+		{Type: "http_get", URL: "http://localhost:8080/admin", ExpectedStatus: 401, Description: "Unauthenticated requests to http://localhost:8080/admin get a 401"},
+		{Type: "cli_command", Command: "go", Args: []string{"test", "./auth/..."}, Description: "`go test ./auth/...` passes"},
+		{Type: "file_exists", Path: "docs/auth.md", Description: "A `docs/auth.md` guide exists"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CriteriaValidations() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := CriteriaValidations([]string{"Fast", "Secure"}); got != nil {
+		t.Errorf("CriteriaValidations() of prose = %+v", got)
+	}
+}
@@ -1,12 +1,12 @@
-// Package goals provides high-level goal management and automatic plan decomposition for Ralph.
 package goals
 
 import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"unicode"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 // DecompositionResult represents the result of goal decomposition
@@ -72,6 +72,9 @@ func BuildGoalDecompositionPrompt(goal *Goal, existingPlans []plan.Plan, outputP
 	sb.WriteString("  \"steps\": [\"<specific step 1>\", \"<specific step 2>\", ...],\n")
 	sb.WriteString("  \"expected_output\": \"<what success looks like>\",\n")
 	sb.WriteString("  \"tested\": false,\n")
+	if len(goal.SuccessCriteria) > 0 {
+		sb.WriteString("  \"validations\": [<checks of the success criteria this item delivers>], // optional\n")
+	}
 	sb.WriteString("  \"depends_on\": [<IDs of plan items this depends on>] // optional\n")
 	sb.WriteString("}\n")
 	sb.WriteString("```\n\n")
@@ -83,7 +86,13 @@ func BuildGoalDecompositionPrompt(goal *Goal, existingPlans []plan.Plan, outputP
 	sb.WriteString("4. Include setup/infrastructure tasks if needed\n")
 	sb.WriteString("5. Include testing tasks where appropriate\n")
 	sb.WriteString("6. Be specific in steps - avoid vague instructions\n")
-	sb.WriteString("7. Use the 'depends_on' field to indicate task dependencies\n\n")
+	sb.WriteString("7. Use the 'depends_on' field to indicate task dependencies\n")
+	if len(goal.SuccessCriteria) > 0 {
+		sb.WriteString("8. Turn each success criterion into a validation on the plan item that delivers it\n\n")
+		writeValidationInstructions(&sb)
+	} else {
+		sb.WriteString("\n")
+	}
 
 	sb.WriteString(fmt.Sprintf("Write the complete JSON array to: %s\n", outputPath))
 	sb.WriteString("The file should contain ONLY the JSON array of new plan items (not existing ones).\n")
@@ -147,15 +156,97 @@ func BuildMultiGoalDecompositionPrompt(goals []Goal, existingPlans []plan.Plan,
 	sb.WriteString("  \"expected_output\": \"<what success looks like>\",\n")
 	sb.WriteString("  \"tested\": false,\n")
 	sb.WriteString("  \"goal_id\": \"<ID of the goal this serves>\",\n")
+	sb.WriteString("  \"validations\": [<checks of the success criteria this item delivers>], // optional\n")
 	sb.WriteString("  \"depends_on\": [<IDs of plan items this depends on>]\n")
 	sb.WriteString("}\n")
 	sb.WriteString("```\n\n")
+	writeValidationInstructions(&sb)
 
 	sb.WriteString(fmt.Sprintf("Write the complete JSON array to: %s\n", outputPath))
 
 	return sb.String()
 }
 
+// writeValidationInstructions explains how success criteria become
+// validations of the generated plan items
+func writeValidationInstructions(sb *strings.Builder) {
+	sb.WriteString("Success criteria must be machine-checkable. For each criterion, add a validation to the plan item that delivers it, ")
+	sb.WriteString("with the criterion as its \"description\". Validation types:\n")
+	sb.WriteString("- {\"type\": \"http_get\", \"url\": \"...\", \"expected_status\": 200, \"expected_body\": \"<regex>\"}\n")
+	sb.WriteString("- {\"type\": \"http_post\", \"url\": \"...\", \"body\": \"...\", \"expected_status\": 201}\n")
+	sb.WriteString("- {\"type\": \"cli_command\", \"command\": \"<program>\", \"args\": [\"...\"], \"expected_body\": \"<regex of the output>\"}\n")
+	sb.WriteString("- {\"type\": \"file_exists\", \"path\": \"...\"}\n")
+	sb.WriteString("Leave out criteria that cannot be checked by a command, request or file.\n\n")
+}
+
+// AttachCriteriaValidations makes the success criteria of a goal checkable
+// on the plan items generated from it. Criteria the agent already turned into
+// a validation (one described by the criterion) are kept; the others are
+// translated with CriteriaValidations and added to the item that mentions
+// them most, or to the last item. The criteria that are left unchecked are
+// returned.
+func AttachCriteriaValidations(criteria []string, plans []plan.Plan) []string {
+	if len(plans) == 0 {
+		return criteria
+	}
+
+	covered := make(map[string]bool)
+	for _, p := range plans {
+		for _, v := range p.Validations {
+			covered[strings.ToLower(strings.TrimSpace(v.Description))] = true
+		}
+	}
+
+	var unchecked []string
+	for _, criterion := range criteria {
+		criterion = strings.TrimSpace(criterion)
+		if criterion == "" || covered[strings.ToLower(criterion)] {
+			continue
+		}
+		v, ok := criterionValidation(criterion)
+		if !ok {
+			unchecked = append(unchecked, criterion)
+			continue
+		}
+		i := bestMatchingPlan(criterion, plans)
+		plans[i].Validations = append(plans[i].Validations, v)
+	}
+	return unchecked
+}
+
+// bestMatchingPlan returns the index of the plan item sharing the most words
+// with a criterion, preferring later items
+func bestMatchingPlan(criterion string, plans []plan.Plan) int {
+	words := significantWords(criterion)
+	best, bestScore := len(plans)-1, 0
+	for i, p := range plans {
+		text := significantWords(p.Description + " " + p.ExpectedOutput + " " + strings.Join(p.Steps, " "))
+		score := 0
+		for w := range words {
+			if text[w] {
+				score++
+			}
+		}
+		if score > 0 && score >= bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// significantWords returns the lowercase words of s longer than three letters
+func significantWords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) > 3 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
 // ParseDecompositionResult parses the agent output to extract generated plans
 func ParseDecompositionResult(output string, goal *Goal) (*DecompositionResult, error) {
 	result := &DecompositionResult{
@@ -298,11 +389,5 @@ func ValidatePlanDependencies(plans []plan.Plan, deps map[int][]int) []string {
 
 // GetNextPlanID returns the next available plan ID given existing plans
 func GetNextPlanID(plans []plan.Plan) int {
-	maxID := 0
-	for _, p := range plans {
-		if p.ID > maxID {
-			maxID = p.ID
-		}
-	}
-	return maxID + 1
+	return plan.NextID(plans)
 }
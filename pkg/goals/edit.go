@@ -0,0 +1,159 @@
+package goals
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// validStatuses are the statuses a goal can be set to
+var validStatuses = []GoalStatus{StatusPending, StatusInProgress, StatusComplete, StatusBlocked}
+
+// SetPriority changes the priority of a goal
+func (m *Manager) SetPriority(id string, priority int) error {
+	goal := m.GetGoalByID(id)
+	if goal == nil {
+		return fmt.Errorf("goal %q not found", id)
+	}
+	goal.Priority = priority
+	goal.UpdatedAt = time.Now()
+	return nil
+}
+
+// ReplaceGoal replaces the goal with the given ID by an edited version. The
+// edited goal may have a new ID, which its dependents follow. Creation and
+// completion times are kept unless the status changes.
+func (m *Manager) ReplaceGoal(id string, edited Goal) error {
+	goal := m.GetGoalByID(id)
+	if goal == nil {
+		return fmt.Errorf("goal %q not found", id)
+	}
+
+	edited.ID = strings.TrimSpace(edited.ID)
+	edited.Description = strings.TrimSpace(edited.Description)
+	if edited.ID == "" {
+		return fmt.Errorf("goal ID cannot be empty")
+	}
+	if edited.Description == "" {
+		return fmt.Errorf("goal description cannot be empty")
+	}
+	if edited.ID != id && m.GetGoalByID(edited.ID) != nil {
+		return fmt.Errorf("goal with ID %q already exists", edited.ID)
+	}
+	if edited.Status == "" {
+		edited.Status = StatusPending
+	}
+	if !validStatus(edited.Status) {
+		return fmt.Errorf("invalid goal status %q (must be pending, in_progress, complete or blocked)", edited.Status)
+	}
+	for _, dep := range edited.Dependencies {
+		if dep == id || dep == edited.ID {
+			return fmt.Errorf("goal %q cannot depend on itself", edited.ID)
+		}
+		if m.GetGoalByID(dep) == nil {
+			return fmt.Errorf("goal %q depends on unknown goal %q", edited.ID, dep)
+		}
+	}
+
+	edited.CreatedAt = goal.CreatedAt
+	edited.UpdatedAt = time.Now()
+	switch {
+	case edited.Status != StatusComplete:
+		edited.CompletedAt = nil
+	case goal.Status != StatusComplete || goal.CompletedAt == nil:
+		now := time.Now()
+		edited.CompletedAt = &now
+	default:
+		edited.CompletedAt = goal.CompletedAt
+	}
+	*goal = edited
+	if edited.ID != id {
+		m.renameDependency(id, edited.ID)
+	}
+	return nil
+}
+
+// ReplaceGoalJSON replaces a goal by the JSON-encoded edited version (see
+// ReplaceGoal), e.g. after the user edited the output of GoalJSON
+func (m *Manager) ReplaceGoalJSON(id string, data []byte) error {
+	var edited Goal
+	if err := json.Unmarshal(data, &edited); err != nil {
+		return fmt.Errorf("invalid goal JSON: %w", err)
+	}
+	return m.ReplaceGoal(id, edited)
+}
+
+// GoalJSON returns a goal as indented JSON for editing
+func (m *Manager) GoalJSON(id string) ([]byte, error) {
+	goal := m.GetGoalByID(id)
+	if goal == nil {
+		return nil, fmt.Errorf("goal %q not found", id)
+	}
+	return json.MarshalIndent(goal, "", "    ")
+}
+
+// renameDependency replaces the dependency on goal from by one on goal to in
+// all goals, or drops it if to is empty
+func (m *Manager) renameDependency(from, to string) {
+	for i := range m.goals {
+		deps := m.goals[i].Dependencies[:0]
+		for _, dep := range m.goals[i].Dependencies {
+			switch {
+			case dep != from:
+				deps = append(deps, dep)
+			case to != "":
+				deps = append(deps, to)
+			}
+		}
+		m.goals[i].Dependencies = deps
+	}
+}
+
+// validStatus reports whether status is a known goal status
+func validStatus(status GoalStatus) bool {
+	for _, s := range validStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GoalReport is a goal with its progress, as listed by -goals -json-output
+type GoalReport struct {
+	Goal
+	Progress ProgressReport `json:"progress"`
+}
+
+// ProgressReport is the progress of a goal in a GoalReport
+type ProgressReport struct {
+	Status          GoalStatus `json:"status"` // Status derived from the plan items and dependencies
+	TotalItems      int        `json:"total_items"`
+	CompletedItems  int        `json:"completed_items"`
+	DeferredItems   int        `json:"deferred_items"`
+	RemainingItems  int        `json:"remaining_items"`
+	PercentComplete float64    `json:"percent_complete"`
+	BlockedBy       []string   `json:"blocked_by,omitempty"`
+}
+
+// Report returns all goals with their progress, highest priority first
+func (m *Manager) Report() []GoalReport {
+	reports := []GoalReport{}
+	for _, g := range m.GetGoalsByPriority() {
+		p := m.CalculateProgress(g.ID)
+		reports = append(reports, GoalReport{
+			Goal: g,
+			Progress: ProgressReport{
+				Status:          p.Status,
+				TotalItems:      p.TotalPlanItems,
+				CompletedItems:  p.CompletedItems,
+				DeferredItems:   p.DeferredItems,
+				RemainingItems:  p.RemainingItems,
+				PercentComplete: p.PercentComplete,
+				BlockedBy:       p.BlockedByGoals,
+			},
+		})
+	}
+	return reports
+}
@@ -0,0 +1,146 @@
+package goals
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func newEditManager(t *testing.T) *Manager {
+	t.Helper()
+	mgr := NewManager([]plan.Plan{
+		{ID: 1, Description: "Login", Tested: true},
+		{ID: 2, Description: "Logout"},
+	})
+	for _, g := range []Goal{
+		{ID: "auth", Description: "Add auth", Priority: 5, GeneratedPlanIDs: []int{1, 2}},
+		{ID: "admin", Description: "Admin panel", Priority: 8, Dependencies: []string{"auth"}},
+	} {
+		if err := mgr.AddGoal(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mgr
+}
+
+func TestSetPriority(t *testing.T) {
+	mgr := newEditManager(t)
+	if err := mgr.SetPriority("auth", 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.GetGoalsByPriority()[0].ID; got != "auth" {
+		t.Errorf("highest priority goal = %s, want auth", got)
+	}
+	if err := mgr.SetPriority("missing", 1); err == nil {
+		t.Error("SetPriority() accepted an unknown goal")
+	}
+}
+
+func TestReplaceGoal(t *testing.T) {
+	mgr := newEditManager(t)
+	created := mgr.GetGoalByID("auth").CreatedAt
+
+	edited := *mgr.GetGoalByID("auth")
+	edited.ID = "authentication"
+	edited.Description = "  Add authentication "
+	edited.Status = StatusComplete
+	if err := mgr.ReplaceGoal("auth", edited); err != nil {
+		t.Fatal(err)
+	}
+	goal := mgr.GetGoalByID("authentication")
+	if goal == nil || goal.Description != "Add authentication" || mgr.GetGoalByID("auth") != nil {
+		t.Fatalf("renamed goal = %+v", goal)
+	}
+	if !goal.CreatedAt.Equal(created) || goal.CompletedAt == nil {
+		t.Errorf("times = created %v, completed %v", goal.CreatedAt, goal.CompletedAt)
+	}
+	if deps := mgr.GetGoalByID("admin").Dependencies; len(deps) != 1 || deps[0] != "authentication" {
+		t.Errorf("dependent goal dependencies = %v", deps)
+	}
+
+	// Reopening a goal clears its completion time
+	edited = *goal
+	edited.Status = StatusInProgress
+	if err := mgr.ReplaceGoal("authentication", edited); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.GetGoalByID("authentication").CompletedAt != nil {
+		t.Error("reopened goal kept its completion time")
+	}
+
+	tests := []struct {
+		name string
+		edit func(g *Goal)
+	}{
+		{"empty description", func(g *Goal) { g.Description = " " }},
+		{"duplicate ID", func(g *Goal) { g.ID = "authentication" }},
+		{"bad status", func(g *Goal) { g.Status = "done" }},
+		{"unknown dependency", func(g *Goal) { g.Dependencies = []string{"billing"} }},
+		{"self dependency", func(g *Goal) { g.Dependencies = []string{"admin"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := *mgr.GetGoalByID("admin")
+			tt.edit(&edited)
+			if err := mgr.ReplaceGoal("admin", edited); err == nil {
+				t.Error("ReplaceGoal() accepted an invalid goal")
+			}
+		})
+	}
+}
+
+func TestReplaceGoalJSON(t *testing.T) {
+	mgr := newEditManager(t)
+	data, err := mgr.GoalJSON("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.Replace(string(data), "Admin panel", "Admin dashboard", 1))
+	if err := mgr.ReplaceGoalJSON("admin", data); err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.GetGoalByID("admin").Description; got != "Admin dashboard" {
+		t.Errorf("description = %q", got)
+	}
+	if err := mgr.ReplaceGoalJSON("admin", []byte("{")); err == nil {
+		t.Error("ReplaceGoalJSON() accepted invalid JSON")
+	}
+	if _, err := mgr.GoalJSON("missing"); err == nil {
+		t.Error("GoalJSON() accepted an unknown goal")
+	}
+}
+
+func TestRemoveGoalDropsDependencies(t *testing.T) {
+	mgr := newEditManager(t)
+	if !mgr.RemoveGoal("auth") {
+		t.Fatal("RemoveGoal() did not find the goal")
+	}
+	if deps := mgr.GetGoalByID("admin").Dependencies; len(deps) != 0 {
+		t.Errorf("dependencies after removal = %v", deps)
+	}
+}
+
+func TestReport(t *testing.T) {
+	mgr := newEditManager(t)
+	data, err := json.Marshal(mgr.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []map[string]any
+	if err := json.Unmarshal(data, &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0]["id"] != "admin" {
+		t.Fatalf("reports = %s", data)
+	}
+	auth := reports[1]["progress"].(map[string]any)
+	if auth["total_items"] != 2.0 || auth["completed_items"] != 1.0 || auth["percent_complete"] != 50.0 {
+		t.Errorf("auth progress = %v", auth)
+	}
+	admin := reports[0]["progress"].(map[string]any)
+	if blocked, _ := admin["blocked_by"].([]any); len(blocked) != 1 || blocked[0] != "auth" {
+		t.Errorf("admin progress = %v", admin)
+	}
+}
@@ -1,4 +1,10 @@
 // Package goals provides high-level goal management and automatic plan decomposition for Ralph.
+//
+// A Manager holds the goals of a project and the plan they decompose into.
+// The package runs no agent itself: BuildGoalDecompositionPrompt builds the
+// prompt for an agent of your choice, and ParseDecompositionResult and
+// MergePlans turn its answer into plan features. This package is part of
+// Ralph's public API.
 package goals
 
 import (
@@ -9,7 +15,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 // GoalStatus represents the current status of a goal
@@ -28,19 +34,20 @@ const (
 
 // Goal represents a high-level project goal that can be decomposed into plan items
 type Goal struct {
-	ID              string            `json:"id"`                          // Unique identifier for the goal
-	Description     string            `json:"description"`                 // High-level goal description
-	SuccessCriteria []string          `json:"success_criteria,omitempty"`  // What success looks like
-	Priority        int               `json:"priority,omitempty"`          // Priority for ordering (higher = more important)
-	Category        string            `json:"category,omitempty"`          // Category for grouping (e.g., "feature", "infrastructure")
-	Tags            []string          `json:"tags,omitempty"`              // Tags for filtering and organization
-	Dependencies    []string          `json:"dependencies,omitempty"`      // IDs of goals this depends on
-	GeneratedPlanIDs []int            `json:"generated_plan_ids,omitempty"` // IDs of plan items generated from this goal
-	Metadata        map[string]string `json:"metadata,omitempty"`          // Additional metadata
-	Status          GoalStatus        `json:"status,omitempty"`            // Current goal status
-	CreatedAt       time.Time         `json:"created_at,omitempty"`        // When the goal was created
-	UpdatedAt       time.Time         `json:"updated_at,omitempty"`        // When the goal was last updated
-	CompletedAt     *time.Time        `json:"completed_at,omitempty"`      // When the goal was completed (if complete)
+	ID               string                      `json:"id"`                           // Unique identifier for the goal
+	Description      string                      `json:"description"`                  // High-level goal description
+	SuccessCriteria  []string                    `json:"success_criteria,omitempty"`   // What success looks like
+	Validations      []plan.ValidationDefinition `json:"validations,omitempty"`        // Checks of the success criteria, run before the goal is completed
+	Priority         int                         `json:"priority,omitempty"`           // Priority for ordering (higher = more important)
+	Category         string                      `json:"category,omitempty"`           // Category for grouping (e.g., "feature", "infrastructure")
+	Tags             []string                    `json:"tags,omitempty"`               // Tags for filtering and organization
+	Dependencies     []string                    `json:"dependencies,omitempty"`       // IDs of goals this depends on
+	GeneratedPlanIDs []int                       `json:"generated_plan_ids,omitempty"` // IDs of plan items generated from this goal
+	Metadata         map[string]string           `json:"metadata,omitempty"`           // Additional metadata
+	Status           GoalStatus                  `json:"status,omitempty"`             // Current goal status
+	CreatedAt        time.Time                   `json:"created_at,omitempty"`         // When the goal was created
+	UpdatedAt        time.Time                   `json:"updated_at,omitempty"`         // When the goal was last updated
+	CompletedAt      *time.Time                  `json:"completed_at,omitempty"`       // When the goal was completed (if complete)
 }
 
 // GoalFile represents the structure of a goals.json file
@@ -206,11 +213,12 @@ func (m *Manager) AddGoalFromDescription(description string, priority int) (*Goa
 	return &goal, nil
 }
 
-// RemoveGoal removes a goal by ID
+// RemoveGoal removes a goal by ID. Other goals no longer depend on it.
 func (m *Manager) RemoveGoal(id string) bool {
 	for i, g := range m.goals {
 		if g.ID == id {
 			m.goals = append(m.goals[:i], m.goals[i+1:]...)
+			m.renameDependency(id, "")
 			return true
 		}
 	}
@@ -365,6 +373,18 @@ func (m *Manager) GetNextGoalToWork() *Goal {
 	return nil
 }
 
+// GoalForPlan returns the goal a plan item was generated from, or nil
+func (m *Manager) GoalForPlan(planID int) *Goal {
+	for i := range m.goals {
+		for _, id := range m.goals[i].GeneratedPlanIDs {
+			if id == planID {
+				return &m.goals[i]
+			}
+		}
+	}
+	return nil
+}
+
 // LinkPlanToGoal associates a plan item with a goal
 func (m *Manager) LinkPlanToGoal(goalID string, planID int) error {
 	goal := m.GetGoalByID(goalID)
@@ -7,7 +7,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 func TestNewManager(t *testing.T) {
@@ -289,6 +289,19 @@ func TestLinkPlanToGoal(t *testing.T) {
 	}
 }
 
+func TestGoalForPlan(t *testing.T) {
+	mgr := NewManager([]plan.Plan{{ID: 1}, {ID: 2}})
+	mgr.AddGoal(Goal{ID: "auth", Description: "Auth"})
+	mgr.LinkPlanToGoal("auth", 2)
+
+	if g := mgr.GoalForPlan(2); g == nil || g.ID != "auth" {
+		t.Errorf("GoalForPlan(2) = %v, want auth", g)
+	}
+	if g := mgr.GoalForPlan(1); g != nil {
+		t.Errorf("GoalForPlan(1) = %v, want nil", g)
+	}
+}
+
 func TestMarkGoalComplete(t *testing.T) {
 	mgr := NewManager(nil)
 
@@ -588,6 +601,46 @@ func TestBuildGoalDecompositionPrompt(t *testing.T) {
 	if !containsSubstring(prompt, "plan.json") {
 		t.Error("Prompt should contain output path")
 	}
+	if !containsSubstring(prompt, "Turn each success criterion into a validation") || !containsSubstring(prompt, `"type": "cli_command"`) {
+		t.Error("Prompt should ask for validations of the success criteria")
+	}
+
+	goal.SuccessCriteria = nil
+	if containsSubstring(BuildGoalDecompositionPrompt(goal, existingPlans, "plan.json"), "validations") {
+		t.Error("Prompt without success criteria should not ask for validations")
+	}
+}
+
+func TestAttachCriteriaValidations(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 10, Description: "Add health endpoint", Steps: []string{"Serve /health"}},
+		{ID: 11, Description: "Write the auth guide", ExpectedOutput: "docs/auth.md describes login",
+			Validations: []plan.ValidationDefinition{{Type: "file_exists", Path: "docs/auth.md", Description: "The auth guide exists"}}},
+		{ID: 12, Description: "Wire everything up"},
+	}
+	unchecked := AttachCriteriaValidations([]string{
+		"The health endpoint at http://localhost:8080/health responds",
+		"the auth guide exists",
+		"`go test ./...` passes",
+		"Users are happy",
+	}, plans)
+
+	if len(unchecked) != 1 || unchecked[0] != "Users are happy" {
+		t.Errorf("unchecked = %q", unchecked)
+	}
+	if v := plans[0].Validations; len(v) != 1 || v[0].Type != "http_get" || v[0].URL != "http://localhost:8080/health" {
+		t.Errorf("health item validations = %+v", v)
+	}
+	if v := plans[1].Validations; len(v) != 1 {
+		t.Errorf("criterion the agent covered was translated again: %+v", v)
+	}
+	if v := plans[2].Validations; len(v) != 1 || v[0].Type != "cli_command" || v[0].Command != "go" {
+		t.Errorf("last item validations = %+v", v)
+	}
+
+	if got := AttachCriteriaValidations([]string{"Fast"}, nil); len(got) != 1 {
+		t.Errorf("AttachCriteriaValidations() without plans = %q", got)
+	}
 }
 
 func TestBuildMultiGoalDecompositionPrompt(t *testing.T) {
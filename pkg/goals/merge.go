@@ -0,0 +1,101 @@
+package goals
+
+import (
+	"strings"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// MergeOptions controls how the features of another plan are merged
+type MergeOptions struct {
+	MilestonePrefix string  // Prepended to the milestones of the merged features (e.g., "mobile/")
+	Similarity      float64 // Skip features at least this similar to an existing one (0-1, 0 = merge all)
+}
+
+// Merge is the result of merging the features of another plan
+type Merge struct {
+	Plans      []plan.Plan      `json:"-"`          // Whole plan after the merge
+	Added      []plan.Plan      `json:"added"`      // Merged features, with their new IDs
+	Renumbered map[int]int      `json:"renumbered"` // Old ID -> new ID of the merged features whose ID was taken
+	Skipped    []SkippedFeature `json:"skipped"`    // Features left out as duplicates
+}
+
+// SkippedFeature is a feature left out of a merge as a duplicate
+type SkippedFeature struct {
+	Feature    plan.Plan `json:"feature"`
+	Duplicate  plan.Plan `json:"duplicate"` // Feature of the plan it duplicates
+	Similarity float64   `json:"similarity"`
+}
+
+// MergePlanFile merges the features of another plan into existing, resolving
+// ID conflicts like MergePlans. Features whose description is at least
+// opts.Similarity similar to one already in the plan, or merged before them,
+// are skipped.
+func MergePlanFile(existing, other []plan.Plan, opts MergeOptions) *Merge {
+	m := &Merge{Renumbered: make(map[int]int), Skipped: []SkippedFeature{}}
+	known := append([]plan.Plan{}, existing...)
+	var merged []plan.Plan
+	for _, p := range other {
+		if opts.Similarity > 0 {
+			if dup, score := mostSimilar(known, p.Description); score >= opts.Similarity {
+				m.Skipped = append(m.Skipped, SkippedFeature{Feature: p, Duplicate: dup, Similarity: score})
+				continue
+			}
+		}
+		if p.Milestone != "" {
+			p.Milestone = opts.MilestonePrefix + p.Milestone
+		}
+		p.Steps = append([]string(nil), p.Steps...)
+		known = append(known, p)
+		merged = append(merged, p)
+	}
+
+	oldIDs := make([]int, len(merged))
+	for i, p := range merged {
+		oldIDs[i] = p.ID
+	}
+	m.Plans = MergePlans(append([]plan.Plan{}, existing...), merged)
+	m.Added = m.Plans[len(existing):]
+	for i, p := range m.Added {
+		if p.ID != oldIDs[i] {
+			m.Renumbered[oldIDs[i]] = p.ID
+		}
+	}
+	return m
+}
+
+// mostSimilar returns the feature whose description is most similar to
+// description, and the similarity
+func mostSimilar(plans []plan.Plan, description string) (plan.Plan, float64) {
+	var best plan.Plan
+	bestScore := 0.0
+	for _, p := range plans {
+		if score := DescriptionSimilarity(p.Description, description); score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best, bestScore
+}
+
+// DescriptionSimilarity scores how alike two feature descriptions are, from
+// 0 to 1: the share of their significant words they have in common. Equal
+// descriptions, ignoring case and spacing, score 1.
+func DescriptionSimilarity(a, b string) float64 {
+	if strings.Join(strings.Fields(strings.ToLower(a)), " ") == strings.Join(strings.Fields(strings.ToLower(b)), " ") {
+		return 1
+	}
+	wa, wb := significantWords(a), significantWords(b)
+	union := len(wa)
+	shared := 0
+	for w := range wb {
+		if wa[w] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,58 @@
+package goals
+
+import (
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestMergePlanFile(t *testing.T) {
+	existing := []plan.Plan{
+		{ID: 1, Description: "User login with email"},
+		{ID: 2, Description: "Product search"},
+	}
+	other := []plan.Plan{
+		{ID: 1, Description: "user LOGIN with email", Milestone: "Alpha"},
+		{ID: 2, Description: "Push notifications", Milestone: "Alpha", Steps: []string{"Register device"}},
+		{ID: 7, Description: "Offline mode"},
+	}
+
+	m := MergePlanFile(existing, other, MergeOptions{MilestonePrefix: "mobile/", Similarity: 0.8})
+	if len(m.Plans) != 4 || len(m.Added) != 2 {
+		t.Fatalf("merged plans = %+v", m.Plans)
+	}
+	if m.Added[0].ID != 3 || m.Added[0].Milestone != "mobile/Alpha" || m.Added[1].ID != 7 || m.Added[1].Milestone != "" {
+		t.Errorf("Added = %+v", m.Added)
+	}
+	if len(m.Renumbered) != 1 || m.Renumbered[2] != 3 {
+		t.Errorf("Renumbered = %v, want 2 -> 3", m.Renumbered)
+	}
+	if len(m.Skipped) != 1 || m.Skipped[0].Duplicate.ID != 1 || m.Skipped[0].Similarity != 1 {
+		t.Errorf("Skipped = %+v", m.Skipped)
+	}
+	if other[1].Milestone != "Alpha" || existing[1].ID != 2 || len(existing) != 2 {
+		t.Error("MergePlanFile() modified its input")
+	}
+
+	// Without a similarity threshold every feature is merged
+	if m := MergePlanFile(existing, other, MergeOptions{}); len(m.Added) != 3 || len(m.Skipped) != 0 {
+		t.Errorf("MergePlanFile() without a threshold added %d, skipped %d", len(m.Added), len(m.Skipped))
+	}
+}
+
+func TestDescriptionSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Add user login", "add  USER login", 1},
+		{"User login form", "User login page", 0.5},
+		{"Product search", "Push notifications", 0},
+		{"Do it", "Do it now", 0},
+	}
+	for _, tt := range tests {
+		if got := DescriptionSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("DescriptionSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package goals
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+// Redecomposition is the result of regenerating the plan items of a goal
+type Redecomposition struct {
+	Plans    []plan.Plan // Whole plan after the regeneration
+	Kept     []plan.Plan // Tested items of the goal, preserved as they were
+	Replaced []plan.Plan // Untested items of the goal that were dropped
+	Added    []plan.Plan // Generated items that are new to the plan
+	Diff     *plan.Diff  // Changes to the items of the goal
+}
+
+// GoalPlans returns the plan items generated from a goal, in plan order
+func GoalPlans(goal *Goal, plans []plan.Plan) []plan.Plan {
+	ids := make(map[int]bool, len(goal.GeneratedPlanIDs))
+	for _, id := range goal.GeneratedPlanIDs {
+		ids[id] = true
+	}
+	var items []plan.Plan
+	for _, p := range plans {
+		if ids[p.ID] {
+			items = append(items, p)
+		}
+	}
+	return items
+}
+
+// BuildGoalRedecompositionPrompt creates the prompt for regenerating the plan
+// items of a goal, given the items generated before and feedback on them
+func BuildGoalRedecompositionPrompt(goal *Goal, existingPlans []plan.Plan, feedback, outputPath string) string {
+	var sb strings.Builder
+	previous := GoalPlans(goal, existingPlans)
+
+	sb.WriteString("The plan items generated earlier for the following goal need to be revised.\n\n")
+	if feedback != "" {
+		sb.WriteString("## Feedback\n")
+		sb.WriteString(feedback + "\n\n")
+	}
+
+	sb.WriteString("## Previously Generated Plan Items\n")
+	if len(previous) == 0 {
+		sb.WriteString("(none)\n")
+	}
+	for _, p := range previous {
+		sb.WriteString(fmt.Sprintf("- ID %d: [%s] [%s] %s\n", p.ID, p.Category, statusString(p.Tested), p.Description))
+		for _, step := range p.Steps {
+			sb.WriteString(fmt.Sprintf("    - %s\n", step))
+		}
+	}
+	sb.WriteString("\nItems marked done are implemented and stay in the plan as they are; do not include them again. ")
+	sb.WriteString("Items marked todo are replaced by the items you generate. ")
+	sb.WriteString("To keep a todo item, include it with the same description.\n\n")
+
+	// The rest of the prompt is the regular decomposition prompt, without
+	// the goal's previous items among the existing ones
+	others := make([]plan.Plan, 0, len(existingPlans))
+	for _, p := range existingPlans {
+		if !containsPlan(previous, p.ID) || p.Tested {
+			others = append(others, p)
+		}
+	}
+	sb.WriteString(BuildGoalDecompositionPrompt(goal, others, outputPath))
+	return sb.String()
+}
+
+// Redecompose replaces the untested plan items of a goal by regenerated
+// ones. Tested items are kept, as are untested items the regeneration
+// repeats (matched by description), which are updated in place. Generated
+// items repeating a tested item are dropped; the others are appended with
+// new IDs. The goal is linked to its new items.
+func Redecompose(goal *Goal, plans, generated []plan.Plan) *Redecomposition {
+	previous := GoalPlans(goal, plans)
+	r := &Redecomposition{}
+
+	tested := make(map[string]bool)
+	untested := make(map[string]int) // Description -> ID of an untested item
+	for _, p := range previous {
+		if p.Tested {
+			tested[descriptionKey(p.Description)] = true
+			r.Kept = append(r.Kept, p)
+		} else if _, ok := untested[descriptionKey(p.Description)]; !ok {
+			untested[descriptionKey(p.Description)] = p.ID
+		}
+	}
+
+	repeated := make(map[int]plan.Plan) // ID of an untested item -> its regenerated version
+	var added []plan.Plan
+	for _, g := range generated {
+		key := descriptionKey(g.Description)
+		if tested[key] {
+			continue
+		}
+		if id, ok := untested[key]; ok {
+			g.ID = id
+			g.Tested = false
+			repeated[id] = g
+			delete(untested, key)
+			continue
+		}
+		added = append(added, g)
+	}
+
+	var remaining []plan.Plan
+	for _, p := range plans {
+		if !containsPlan(previous, p.ID) || p.Tested {
+			remaining = append(remaining, p)
+		} else if g, ok := repeated[p.ID]; ok {
+			remaining = append(remaining, g)
+		} else {
+			r.Replaced = append(r.Replaced, p)
+		}
+	}
+	// New items get IDs above every existing one, including the replaced
+	// items, so their IDs are not reused
+	nextID := GetNextPlanID(plans)
+	for i := range added {
+		added[i].ID = nextID
+		nextID++
+	}
+	r.Added = added
+	r.Plans = append(remaining, added...)
+
+	goal.GeneratedPlanIDs = nil
+	for _, p := range r.Plans {
+		if containsPlan(previous, p.ID) || containsPlan(r.Added, p.ID) {
+			goal.GeneratedPlanIDs = append(goal.GeneratedPlanIDs, p.ID)
+		}
+	}
+	if goal.Status != StatusBlocked {
+		goal.Status = StatusInProgress
+	}
+	goal.CompletedAt = nil
+	r.Diff = plan.ComputeDiff(previous, GoalPlans(goal, r.Plans))
+	return r
+}
+
+// descriptionKey normalizes a plan item description for matching
+func descriptionKey(description string) string {
+	return strings.ToLower(strings.Join(strings.Fields(description), " "))
+}
+
+// containsPlan reports whether plans contains an item with the given ID
+func containsPlan(plans []plan.Plan, id int) bool {
+	return plan.GetByID(plans, id) != nil
+}
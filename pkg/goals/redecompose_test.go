@@ -0,0 +1,96 @@
+package goals
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestRedecompose(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Description: "Unrelated"},
+		{ID: 2, Description: "Add login form", Tested: true},
+		{ID: 3, Description: "Add session store", Steps: []string{"Use memory"}},
+		{ID: 4, Description: "Add password reset"},
+		{ID: 7, Description: "Unrelated too"},
+	}
+	goal := &Goal{ID: "auth", Description: "Add auth", GeneratedPlanIDs: []int{2, 3, 4}, Status: StatusComplete}
+	generated := []plan.Plan{
+		{ID: 20, Description: "add login  form"},                                // Repeats a tested item
+		{ID: 21, Description: "Add session store", Steps: []string{"Use Redis"}}, // Revises an untested item
+		{ID: 22, Description: "Add OAuth login"},
+	}
+
+	r := Redecompose(goal, plans, generated)
+	if len(r.Kept) != 1 || r.Kept[0].ID != 2 {
+		t.Errorf("Kept = %+v", r.Kept)
+	}
+	if len(r.Replaced) != 1 || r.Replaced[0].ID != 4 {
+		t.Errorf("Replaced = %+v", r.Replaced)
+	}
+	if len(r.Added) != 1 || r.Added[0].ID != 8 || r.Added[0].Description != "Add OAuth login" {
+		t.Errorf("Added = %+v", r.Added)
+	}
+
+	var ids []int
+	for _, p := range r.Plans {
+		ids = append(ids, p.ID)
+	}
+	if want := []int{1, 2, 3, 7, 8}; !equalInts(ids, want) {
+		t.Errorf("plan IDs = %v, want %v", ids, want)
+	}
+	if session := plan.GetByID(r.Plans, 3); session.Steps[0] != "Use Redis" {
+		t.Errorf("revised item = %+v", session)
+	}
+	if want := []int{2, 3, 8}; !equalInts(goal.GeneratedPlanIDs, want) {
+		t.Errorf("goal plan IDs = %v, want %v", goal.GeneratedPlanIDs, want)
+	}
+	if goal.Status != StatusInProgress || goal.CompletedAt != nil {
+		t.Errorf("goal status = %s", goal.Status)
+	}
+
+	summary := r.Diff.Summary()
+	for _, want := range []string{"Added: 1", "#8: Add OAuth login", "Removed: 1", "#4: Add password reset", "#3.steps"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("diff summary missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestBuildGoalRedecompositionPrompt(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Description: "Unrelated"},
+		{ID: 2, Description: "Add login form", Tested: true},
+		{ID: 3, Description: "Add session store", Steps: []string{"Use memory"}},
+	}
+	goal := &Goal{ID: "auth", Description: "Add auth", GeneratedPlanIDs: []int{2, 3}}
+	got := BuildGoalRedecompositionPrompt(goal, plans, "Use Redis for sessions", "/tmp/out.json")
+	for _, want := range []string{
+		"## Feedback\nUse Redis for sessions",
+		"- ID 2: [] [done] Add login form",
+		"- ID 3: [] [todo] Add session store\n    - Use memory",
+		"Description: Add auth",
+		"Start new plan IDs from 3",
+		"/tmp/out.json",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ID 3: [] [todo] Add session store [") || strings.Count(got, "Add session store") != 1 {
+		t.Errorf("untested item listed among the existing items:\n%s", got)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// GlobalMemoryFile returns the user-level memory file, $HOME/.ralph/memory.json,
+// for personal conventions shared by all projects. It returns "" if the home
+// directory is unknown.
+func GlobalMemoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".ralph", "memory.json")
+}
+
+// SetGlobal layers a user-level store underneath this project store. Reads
+// (prompt context, listings, counts) include the user-level memories; a
+// project memory with the same content (ignoring case and whitespace) hides
+// the user-level one, and project memories rank first on equal relevance.
+// New memories are added to the project store; edits, deletions and pins
+// apply to whichever store holds the memory.
+func (s *Store) SetGlobal(global *Store) {
+	if global != nil && global.path == s.path {
+		return
+	}
+	s.global = global
+}
+
+// Global returns the user-level store layered underneath, if any
+func (s *Store) Global() *Store {
+	return s.global
+}
+
+// Path returns the memory file of the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// visible returns the project entries followed by the user-level entries
+// they do not hide
+func (s *Store) visible() []Entry {
+	var entries []Entry
+	if s.memory != nil {
+		entries = s.memory.Entries
+	}
+	if s.global == nil || s.global.memory == nil || len(s.global.memory.Entries) == 0 {
+		return entries
+	}
+
+	local := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		local[contentKey(e.Content)] = true
+	}
+	merged := append([]Entry(nil), entries...)
+	for _, e := range s.global.memory.Entries {
+		if local[contentKey(e.Content)] {
+			continue
+		}
+		e.Global = true
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// locate finds the store holding the entry with the given ID (or unique ID
+// prefix), looking in the project store first
+func (s *Store) locate(id string) (*Store, int, error) {
+	i, err := s.Find(id)
+	if err == nil || s.global == nil {
+		return s, i, err
+	}
+	if gi, gerr := s.global.Find(id); gerr == nil {
+		return s.global, gi, nil
+	}
+	return s, -1, err
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_Global(t *testing.T) {
+	dir := t.TempDir()
+	global := NewStore(filepath.Join(dir, "home", "memory.json"))
+	global.Load()
+	global.Add(EntryTypeConvention, "Prefer table-driven tests", "", "user")
+	global.Add(EntryTypeDecision, "Use PostgreSQL", "", "user")
+	personal, _ := global.Add(EntryTypeContext, "I review PRs in the morning", "", "user")
+
+	project := NewStore(filepath.Join(dir, "project", ".ralph-memory.json"))
+	project.Load()
+	project.Add(EntryTypeDecision, "use postgresql", "", "user") // Same content as a global memory
+	project.SetGlobal(global)
+
+	all := project.GetAll()
+	if len(all) != 3 || project.Count() != 3 {
+		t.Fatalf("GetAll() = %+v, want the project memory and 2 global ones", all)
+	}
+	if all[0].Global || all[0].Content != "use postgresql" || !all[1].Global {
+		t.Errorf("GetAll() = %+v, want the project memory first, hiding the global duplicate", all)
+	}
+
+	// On equal relevance, project memories rank first
+	scoped := project.GetScoped("", "", 10)
+	if scoped[0].Global {
+		t.Errorf("GetScoped() = %+v, want the project decision first", scoped)
+	}
+	if !strings.Contains(project.Summary(), "Prefer table-driven tests (global)") {
+		t.Errorf("Summary() does not mark global memories:\n%s", project.Summary())
+	}
+
+	// New memories go to the project store; edits apply where the memory is
+	project.Add(EntryTypeConvention, "Use snake_case", "db", "user")
+	if global.Count() != 3 {
+		t.Errorf("global store has %d entries, want 3", global.Count())
+	}
+	if _, err := project.Edit(personal.ID, "I review PRs after lunch"); err != nil {
+		t.Fatalf("Edit() of a global memory failed: %v", err)
+	}
+	reloaded := NewStore(global.Path())
+	reloaded.Load()
+	found := false
+	for _, e := range reloaded.GetAll() {
+		if e.ID == personal.ID && e.Content == "I review PRs after lunch" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Edit() did not update the global store")
+	}
+	if _, err := project.Delete(personal.ID); err != nil || global.Count() != 2 {
+		t.Errorf("Delete() of a global memory = %v, global count %d", err, global.Count())
+	}
+
+	// A store cannot be layered on itself
+	project.SetGlobal(project)
+	if project.Global() == project {
+		t.Error("SetGlobal() layered a store on itself")
+	}
+}
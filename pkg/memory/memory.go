@@ -0,0 +1,959 @@
+// Package memory provides persistent memory management for cross-session continuity.
+// It allows Ralph to remember architectural decisions, coding conventions, tradeoffs,
+// and context across multiple runs, reducing repetitive guidance and maintaining consistency.
+//
+// A Store reads and writes one memory file: call Load before using it, and
+// methods that change entries save the file. This package is part of Ralph's
+// public API, and the memory file format is shared with the ralph CLI.
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// DefaultMemoryFile is the default memory file name
+	DefaultMemoryFile = ".ralph-memory.json"
+
+	// MemoryMarkerPattern is the regex pattern for extracting memories from agent output
+	MemoryMarkerPattern = `\[REMEMBER:([A-Z]+)\](.*?)\[/REMEMBER\]`
+
+	// DefaultRetentionDays is the default number of days to retain memories
+	DefaultRetentionDays = 90
+
+	// MinConfidence is the confidence below which memories are no longer
+	// injected into prompts
+	MinConfidence = 0.3
+)
+
+// EntryType represents the type of memory entry
+type EntryType string
+
+const (
+	// EntryTypeDecision represents an architectural choice
+	EntryTypeDecision EntryType = "decision"
+	// EntryTypeConvention represents a coding standard or pattern
+	EntryTypeConvention EntryType = "convention"
+	// EntryTypeTradeoff represents an accepted compromise
+	EntryTypeTradeoff EntryType = "tradeoff"
+	// EntryTypeContext represents project-specific knowledge
+	EntryTypeContext EntryType = "context"
+)
+
+// Entry represents a single memory entry
+type Entry struct {
+	ID        string    `json:"id"`
+	Type      EntryType `json:"type"`
+	Content   string    `json:"content"`
+	Category  string    `json:"category,omitempty"`  // Related feature category (e.g., "infra", "ui")
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Source    string    `json:"source,omitempty"` // "agent", "user", or feature ID
+	Pinned    bool      `json:"pinned,omitempty"` // Exempt from retention pruning
+	// Provenance of memories extracted from agent output
+	FeatureID int    `json:"feature_id,omitempty"` // Feature worked on when the memory was recorded
+	Iteration int    `json:"iteration,omitempty"`  // Iteration that recorded the memory
+	Agent     string `json:"agent,omitempty"`      // Agent that produced the memory
+	// Outcomes of the iterations the memory was injected into
+	Successes int `json:"successes,omitempty"`
+	Failures  int `json:"failures,omitempty"`
+
+	Global bool `json:"-"` // Read from the user-level store underneath the project's
+}
+
+// Provenance identifies the iteration and agent that produced a memory
+type Provenance struct {
+	FeatureID int
+	Iteration int
+	Agent     string
+}
+
+// Confidence estimates how helpful a memory's advice is, from 1 (no failures)
+// towards 0 as the iterations it was injected into keep failing. Successes
+// restore it.
+func (e Entry) Confidence() float64 {
+	return float64(e.Successes+1) / float64(e.Successes+e.Failures+1)
+}
+
+// Memory represents the complete memory state
+type Memory struct {
+	Entries       []Entry `json:"entries"`
+	LastUpdated   time.Time `json:"last_updated"`
+	RetentionDays int       `json:"retention_days,omitempty"`
+}
+
+// Store handles memory persistence and operations
+type Store struct {
+	path          string
+	memory        *Memory
+	retentionDays int
+	redact        func(string) string // Masks secrets in new entries, if set
+	global        *Store              // User-level store layered underneath, if set
+}
+
+// NewStore creates a new memory store for the given path
+func NewStore(path string) *Store {
+	if path == "" {
+		path = DefaultMemoryFile
+	}
+	return &Store{
+		path:          path,
+		retentionDays: DefaultRetentionDays,
+	}
+}
+
+// SetRetentionDays sets the number of days to retain memories
+func (s *Store) SetRetentionDays(days int) {
+	if days > 0 {
+		s.retentionDays = days
+	}
+}
+
+// SetRedactor masks secrets in the content of entries added from now on
+func (s *Store) SetRedactor(redact func(string) string) {
+	s.redact = redact
+}
+
+// Load reads the memory file from disk
+func (s *Store) Load() error {
+	// Initialize empty memory if file doesn't exist
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		s.memory = &Memory{
+			Entries:       []Entry{},
+			LastUpdated:   time.Now(),
+			RetentionDays: s.retentionDays,
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read memory file: %w", err)
+	}
+
+	var mem Memory
+	if err := json.Unmarshal(data, &mem); err != nil {
+		return fmt.Errorf("failed to parse memory file: %w", err)
+	}
+
+	s.memory = &mem
+	if s.memory.RetentionDays > 0 {
+		s.retentionDays = s.memory.RetentionDays
+	}
+
+	return nil
+}
+
+// Save writes the memory to disk
+func (s *Store) Save() error {
+	if s.memory == nil {
+		s.memory = &Memory{
+			Entries:       []Entry{},
+			LastUpdated:   time.Now(),
+			RetentionDays: s.retentionDays,
+		}
+	}
+
+	s.memory.LastUpdated = time.Now()
+	s.memory.RetentionDays = s.retentionDays
+
+	data, err := json.MarshalIndent(s.memory, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal memory: %w", err)
+	}
+
+	// Ensure directory exists
+	dir := filepath.Dir(s.path)
+	if dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+
+	return nil
+}
+
+// Clear removes all entries from memory and saves
+func (s *Store) Clear() error {
+	s.memory = &Memory{
+		Entries:       []Entry{},
+		LastUpdated:   time.Now(),
+		RetentionDays: s.retentionDays,
+	}
+	return s.Save()
+}
+
+// Add adds a new memory entry
+func (s *Store) Add(entryType EntryType, content, category, source string) (*Entry, error) {
+	if s.memory == nil {
+		if err := s.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.redact != nil {
+		content = s.redact(content)
+	}
+
+	entry := Entry{
+		ID:        generateID(),
+		Type:      entryType,
+		Content:   strings.TrimSpace(content),
+		Category:  category,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Source:    source,
+	}
+
+	s.memory.Entries = append(s.memory.Entries, entry)
+
+	if err := s.Save(); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// ImportResult summarizes an import of memory entries
+type ImportResult struct {
+	Added     int // Entries whose content was new
+	Updated   int // Existing entries replaced by a newer entry with the same content
+	Unchanged int // Entries whose content was already stored in the same or a newer entry
+}
+
+// Export writes all entries to path in the memory file format, so they can be
+// imported into another project or checked into the repository
+func (s *Store) Export(path string) (int, error) {
+	if s.memory == nil {
+		if err := s.Load(); err != nil {
+			return 0, err
+		}
+	}
+	data, err := json.MarshalIndent(Memory{Entries: s.memory.Entries, LastUpdated: s.memory.LastUpdated}, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal memory: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write memory export: %w", err)
+	}
+	return len(s.memory.Entries), nil
+}
+
+// ReadExport reads the entries of an exported memory file (or of another
+// project's memory file)
+func ReadExport(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory export: %w", err)
+	}
+	var mem Memory
+	if err := json.Unmarshal(data, &mem); err != nil {
+		return nil, fmt.Errorf("failed to parse memory export: %w", err)
+	}
+	for i, e := range mem.Entries {
+		if _, err := ParseEntryType(string(e.Type)); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		if strings.TrimSpace(e.Content) == "" {
+			return nil, fmt.Errorf("entry %d has no content", i+1)
+		}
+	}
+	return mem.Entries, nil
+}
+
+// Import adds entries to the store and saves it. With merge, the entries are
+// combined with the stored ones; otherwise they replace them. Entries with
+// the same content (ignoring case and whitespace) are stored once, keeping
+// the most recently updated one.
+func (s *Store) Import(entries []Entry, merge bool) (ImportResult, error) {
+	if s.memory == nil {
+		if err := s.Load(); err != nil {
+			return ImportResult{}, err
+		}
+	}
+
+	var result ImportResult
+	var merged []Entry
+	if merge {
+		merged = append(merged, s.memory.Entries...)
+	}
+	byContent := make(map[string]int) // Content key -> index in merged
+	ids := make(map[string]bool)
+	for i, e := range merged {
+		byContent[contentKey(e.Content)] = i
+		ids[e.ID] = true
+	}
+
+	for _, e := range entries {
+		if s.redact != nil {
+			e.Content = s.redact(e.Content)
+		}
+		e.Content = strings.TrimSpace(e.Content)
+		if e.UpdatedAt.IsZero() {
+			e.UpdatedAt = time.Now()
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = e.UpdatedAt
+		}
+
+		key := contentKey(e.Content)
+		if i, ok := byContent[key]; ok {
+			if e.UpdatedAt.After(merged[i].UpdatedAt) {
+				e.ID = merged[i].ID
+				merged[i] = e
+				result.Updated++
+			} else {
+				result.Unchanged++
+			}
+			continue
+		}
+
+		// IDs are only unique within a project
+		if e.ID == "" || ids[e.ID] {
+			e.ID = fmt.Sprintf("mem_%d_%d", time.Now().UnixNano(), len(merged))
+		}
+		ids[e.ID] = true
+		byContent[key] = len(merged)
+		merged = append(merged, e)
+		result.Added++
+	}
+
+	s.memory.Entries = merged
+	if err := s.Save(); err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+// contentKey normalizes content for detecting duplicate entries
+func contentKey(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+// Find returns the index of the entry with the given ID or unique ID prefix
+func (s *Store) Find(id string) (int, error) {
+	if s.memory == nil {
+		if err := s.Load(); err != nil {
+			return -1, err
+		}
+	}
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return -1, fmt.Errorf("memory ID cannot be empty")
+	}
+
+	match := -1
+	for i, e := range s.memory.Entries {
+		if e.ID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(e.ID, id) {
+			if match >= 0 {
+				return -1, fmt.Errorf("memory ID %q is ambiguous", id)
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return -1, fmt.Errorf("no memory with ID %q (see -show-memory)", id)
+	}
+	return match, nil
+}
+
+// Delete removes the entry with the given ID (or unique ID prefix) and saves
+func (s *Store) Delete(id string) (*Entry, error) {
+	st, i, err := s.locate(id)
+	if err != nil {
+		return nil, err
+	}
+	entry := st.memory.Entries[i]
+	st.memory.Entries = append(st.memory.Entries[:i], st.memory.Entries[i+1:]...)
+	if err := st.Save(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Edit replaces the content of the entry with the given ID (or unique ID
+// prefix) and saves
+func (s *Store) Edit(id, content string) (*Entry, error) {
+	if s.redact != nil {
+		content = s.redact(content)
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("memory content cannot be empty")
+	}
+	st, i, err := s.locate(id)
+	if err != nil {
+		return nil, err
+	}
+	st.memory.Entries[i].Content = content
+	st.memory.Entries[i].UpdatedAt = time.Now()
+	if err := st.Save(); err != nil {
+		return nil, err
+	}
+	entry := st.memory.Entries[i]
+	return &entry, nil
+}
+
+// Pin exempts the entry with the given ID (or unique ID prefix) from
+// retention pruning, or makes it subject to pruning again, and saves
+func (s *Store) Pin(id string, pinned bool) (*Entry, error) {
+	st, i, err := s.locate(id)
+	if err != nil {
+		return nil, err
+	}
+	st.memory.Entries[i].Pinned = pinned
+	if err := st.Save(); err != nil {
+		return nil, err
+	}
+	entry := st.memory.Entries[i]
+	return &entry, nil
+}
+
+// AddFromAgent adds a memory extracted from agent output, recording where it
+// came from
+func (s *Store) AddFromAgent(entryType EntryType, content, category string, prov Provenance) (*Entry, error) {
+	if s.memory == nil {
+		if err := s.Load(); err != nil {
+			return nil, err
+		}
+	}
+	if s.redact != nil {
+		content = s.redact(content)
+	}
+
+	entry := Entry{
+		ID:        generateID(),
+		Type:      entryType,
+		Content:   strings.TrimSpace(content),
+		Category:  category,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Source:    "agent",
+		FeatureID: prov.FeatureID,
+		Iteration: prov.Iteration,
+		Agent:     prov.Agent,
+	}
+	s.memory.Entries = append(s.memory.Entries, entry)
+	if err := s.Save(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// RecordOutcome records whether an iteration that the memories with the
+// given IDs were injected into failed, adjusting their confidence, and saves
+func (s *Store) RecordOutcome(ids []string, failed bool) error {
+	if s.memory == nil || len(ids) == 0 {
+		return nil
+	}
+	if s.global != nil {
+		if err := s.global.RecordOutcome(ids, failed); err != nil {
+			return err
+		}
+	}
+	injected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		injected[id] = true
+	}
+	changed := false
+	for i := range s.memory.Entries {
+		if !injected[s.memory.Entries[i].ID] {
+			continue
+		}
+		if failed {
+			s.memory.Entries[i].Failures++
+		} else {
+			s.memory.Entries[i].Successes++
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.Save()
+}
+
+// GetAll returns all memory entries
+func (s *Store) GetAll() []Entry {
+	if s.memory == nil {
+		return []Entry{}
+	}
+	return s.visible()
+}
+
+// GetByType returns entries of a specific type
+func (s *Store) GetByType(entryType EntryType) []Entry {
+	if s.memory == nil {
+		return []Entry{}
+	}
+
+	var entries []Entry
+	for _, e := range s.visible() {
+		if e.Type == entryType {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// GetByCategory returns entries related to a specific category
+func (s *Store) GetByCategory(category string) []Entry {
+	if s.memory == nil {
+		return []Entry{}
+	}
+
+	var entries []Entry
+	categoryLower := strings.ToLower(category)
+	for _, e := range s.visible() {
+		if strings.ToLower(e.Category) == categoryLower || e.Category == "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// GetRelevant returns all entries sorted by relevance to the given category,
+// leaving out those whose confidence fell below MinConfidence. Use GetScoped
+// to leave out other categories as well.
+func (s *Store) GetRelevant(category string, maxEntries int) []Entry {
+	if s.memory == nil {
+		return []Entry{}
+	}
+	entries := s.visible()
+	if len(entries) == 0 {
+		return []Entry{}
+	}
+
+	type scoredEntry struct {
+		entry Entry
+		score int
+	}
+
+	var scored []scoredEntry
+	categoryLower := strings.ToLower(category)
+
+	for _, e := range entries {
+		if e.Confidence() < MinConfidence {
+			continue
+		}
+		score := calculateRelevanceScore(e, categoryLower)
+		scored = append(scored, scoredEntry{entry: e, score: score})
+	}
+
+	// Sort by score (descending) then by updated time (most recent first)
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		// Project memories take precedence over user-level ones
+		if scored[i].entry.Global != scored[j].entry.Global {
+			return !scored[i].entry.Global
+		}
+		return scored[i].entry.UpdatedAt.After(scored[j].entry.UpdatedAt)
+	})
+
+	// Return top entries
+	var result []Entry
+	for i, se := range scored {
+		if maxEntries > 0 && i >= maxEntries {
+			break
+		}
+		result = append(result, se.entry)
+	}
+
+	return result
+}
+
+// GetScoped returns the entries of the given category plus the global entries
+// (those without a category), sorted by relevance. Entries whose confidence
+// fell below MinConfidence are left out. Besides the type, category
+// and recency, entries sharing words with text (e.g., the description of the
+// feature being worked on) rank higher. An empty category returns entries of
+// every category.
+func (s *Store) GetScoped(category, text string, maxEntries int) []Entry {
+	if s.memory == nil {
+		return []Entry{}
+	}
+	entries := s.visible()
+	if len(entries) == 0 {
+		return []Entry{}
+	}
+
+	type scoredEntry struct {
+		entry Entry
+		score int
+	}
+
+	var scored []scoredEntry
+	categoryLower := strings.ToLower(category)
+	words := significantWords(text)
+	for _, e := range entries {
+		if categoryLower != "" && e.Category != "" && strings.ToLower(e.Category) != categoryLower {
+			continue
+		}
+		if e.Confidence() < MinConfidence {
+			continue
+		}
+		score := calculateRelevanceScore(e, categoryLower) + sharedWordScore(e.Content, words)
+		scored = append(scored, scoredEntry{entry: e, score: score})
+	}
+
+	// Sort by score (descending) then by updated time (most recent first)
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		// Project memories take precedence over user-level ones
+		if scored[i].entry.Global != scored[j].entry.Global {
+			return !scored[i].entry.Global
+		}
+		return scored[i].entry.UpdatedAt.After(scored[j].entry.UpdatedAt)
+	})
+
+	var result []Entry
+	for i, se := range scored {
+		if maxEntries > 0 && i >= maxEntries {
+			break
+		}
+		result = append(result, se.entry)
+	}
+	return result
+}
+
+// Prune removes unpinned entries older than the retention period, from the
+// user-level store as well
+func (s *Store) Prune() (int, error) {
+	if s.memory == nil {
+		return 0, nil
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -s.retentionDays)
+	originalCount := len(s.memory.Entries)
+
+	var retained []Entry
+	for _, e := range s.memory.Entries {
+		if e.Pinned || e.UpdatedAt.After(cutoff) {
+			retained = append(retained, e)
+		}
+	}
+
+	removed := originalCount - len(retained)
+	if removed > 0 {
+		s.memory.Entries = retained
+		if err := s.Save(); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.global != nil {
+		n, err := s.global.Prune()
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+
+	return removed, nil
+}
+
+// Count returns the total number of memory entries
+func (s *Store) Count() int {
+	if s.memory == nil {
+		return 0
+	}
+	return len(s.visible())
+}
+
+// LastChanged returns when the most recently added or updated entry was
+// recorded, or the zero time if there are no entries
+func (s *Store) LastChanged() time.Time {
+	var latest time.Time
+	if s.memory == nil {
+		return latest
+	}
+	for _, e := range s.memory.Entries {
+		if e.UpdatedAt.After(latest) {
+			latest = e.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// Summary returns a formatted summary of all memories
+func (s *Store) Summary() string {
+	if s.memory == nil || s.Count() == 0 {
+		return "No memories stored"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Memory Store: %d entries (retention: %d days)\n", s.Count(), s.retentionDays))
+	if s.global != nil {
+		b.WriteString(fmt.Sprintf("User-level memories from: %s\n", s.global.path))
+	}
+	b.WriteString(fmt.Sprintf("Last updated: %s\n\n", s.memory.LastUpdated.Format(time.RFC3339)))
+
+	// Group by type
+	typeGroups := make(map[EntryType][]Entry)
+	for _, e := range s.visible() {
+		typeGroups[e.Type] = append(typeGroups[e.Type], e)
+	}
+
+	typeOrder := []EntryType{EntryTypeDecision, EntryTypeConvention, EntryTypeTradeoff, EntryTypeContext}
+	for _, t := range typeOrder {
+		entries := typeGroups[t]
+		if len(entries) == 0 {
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf("=== %s (%d) ===\n", strings.Title(string(t)), len(entries)))
+		for _, e := range entries {
+			categoryStr := ""
+			if e.Category != "" {
+				categoryStr = fmt.Sprintf(" [%s]", e.Category)
+			}
+			if e.Pinned {
+				categoryStr += " (pinned)"
+			}
+			if e.Global {
+				categoryStr += " (global)"
+			}
+			b.WriteString(fmt.Sprintf("  - %s  %s%s\n", e.ID, e.Content, categoryStr))
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Details describes every entry with its provenance and confidence, for
+// -show-memory -verbose
+func (s *Store) Details() string {
+	if s.memory == nil || s.Count() == 0 {
+		return "No memories stored"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Memory Store: %d entries (retention: %d days, minimum confidence: %.2f)\n\n",
+		s.Count(), s.retentionDays, MinConfidence))
+	for _, e := range s.visible() {
+		b.WriteString(fmt.Sprintf("%s [%s] %s\n", e.ID, strings.ToUpper(string(e.Type)), e.Content))
+		if e.Category != "" {
+			b.WriteString(fmt.Sprintf("  Category:   %s\n", e.Category))
+		}
+		source := e.Source
+		if e.FeatureID > 0 {
+			source += fmt.Sprintf(", feature #%d", e.FeatureID)
+		}
+		if e.Iteration > 0 {
+			source += fmt.Sprintf(", iteration %d", e.Iteration)
+		}
+		if e.Agent != "" {
+			source += ", " + e.Agent
+		}
+		b.WriteString(fmt.Sprintf("  Source:     %s\n", source))
+		b.WriteString(fmt.Sprintf("  Created:    %s (updated %s)\n", e.CreatedAt.Format(time.RFC3339), e.UpdatedAt.Format(time.RFC3339)))
+		confidence := fmt.Sprintf("%.2f (%d successful, %d failed iterations)", e.Confidence(), e.Successes, e.Failures)
+		if e.Confidence() < MinConfidence {
+			confidence += " - not injected"
+		}
+		b.WriteString(fmt.Sprintf("  Confidence: %s\n", confidence))
+		if e.Pinned {
+			b.WriteString("  Pinned:     yes\n")
+		}
+		if e.Global {
+			b.WriteString(fmt.Sprintf("  Store:      %s (user-level)\n", s.global.path))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ExtractFromOutput parses agent output for [REMEMBER:TYPE]...[/REMEMBER] markers
+// and returns the extracted entries without saving them
+func ExtractFromOutput(output string) []Entry {
+	re := regexp.MustCompile(`(?s)\[REMEMBER:([A-Z]+)\](.*?)\[/REMEMBER\]`)
+	matches := re.FindAllStringSubmatch(output, -1)
+
+	var entries []Entry
+	for _, match := range matches {
+		if len(match) < 3 {
+			continue
+		}
+
+		typeStr := strings.ToLower(match[1])
+		content := strings.TrimSpace(match[2])
+
+		if content == "" {
+			continue
+		}
+
+		entryType := parseEntryType(typeStr)
+
+		entries = append(entries, Entry{
+			ID:        generateID(),
+			Type:      entryType,
+			Content:   content,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Source:    "agent",
+		})
+	}
+
+	return entries
+}
+
+// BuildPromptContext creates a formatted string of memories to inject into prompts
+func (s *Store) BuildPromptContext(category string, maxEntries int) string {
+	return FormatPromptContext(s.GetRelevant(category, maxEntries))
+}
+
+// BuildScopedPromptContext builds the memory context for a feature: the
+// memories of its category and the global ones, ranked by relevance to text
+// (see GetScoped)
+func (s *Store) BuildScopedPromptContext(category, text string, maxEntries int) string {
+	return FormatPromptContext(s.GetScoped(category, text, maxEntries))
+}
+
+// FormatPromptContext formats memory entries for injection into prompts
+func FormatPromptContext(entries []Entry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n[MEMORY CONTEXT - Previous decisions and conventions to follow:]\n")
+
+	for _, e := range entries {
+		typeLabel := strings.ToUpper(string(e.Type))
+		b.WriteString(fmt.Sprintf("- [%s] %s\n", typeLabel, e.Content))
+	}
+
+	b.WriteString("[END MEMORY CONTEXT]\n\n")
+	b.WriteString("If you make any important architectural decisions, conventions, or tradeoffs, ")
+	b.WriteString("wrap them in [REMEMBER:TYPE]...[/REMEMBER] markers where TYPE is ")
+	b.WriteString("DECISION, CONVENTION, TRADEOFF, or CONTEXT.\n")
+
+	return b.String()
+}
+
+// parseEntryType converts a string to EntryType
+func parseEntryType(s string) EntryType {
+	switch strings.ToLower(s) {
+	case "decision":
+		return EntryTypeDecision
+	case "convention":
+		return EntryTypeConvention
+	case "tradeoff":
+		return EntryTypeTradeoff
+	case "context":
+		return EntryTypeContext
+	default:
+		return EntryTypeContext // Default to context for unknown types
+	}
+}
+
+// ParseEntryType is the exported version for use by other packages
+func ParseEntryType(s string) (EntryType, error) {
+	switch strings.ToLower(s) {
+	case "decision":
+		return EntryTypeDecision, nil
+	case "convention":
+		return EntryTypeConvention, nil
+	case "tradeoff":
+		return EntryTypeTradeoff, nil
+	case "context":
+		return EntryTypeContext, nil
+	default:
+		return "", fmt.Errorf("invalid memory type: %s (must be decision, convention, tradeoff, or context)", s)
+	}
+}
+
+// calculateRelevanceScore calculates how relevant an entry is to a category
+func calculateRelevanceScore(entry Entry, category string) int {
+	score := 0
+
+	// Base score by type (decisions and conventions are generally more important)
+	switch entry.Type {
+	case EntryTypeDecision:
+		score += 3
+	case EntryTypeConvention:
+		score += 3
+	case EntryTypeTradeoff:
+		score += 2
+	case EntryTypeContext:
+		score += 1
+	}
+
+	// Category match boost
+	if entry.Category != "" && strings.ToLower(entry.Category) == category {
+		score += 5
+	}
+
+	// Recency boost (entries updated in last 7 days get a boost)
+	if time.Since(entry.UpdatedAt) < 7*24*time.Hour {
+		score += 2
+	}
+
+	return score
+}
+
+// maxSharedWordScore caps the boost for words an entry shares with the
+// feature, so it cannot outweigh a category match
+const maxSharedWordScore = 4
+
+// significantWords returns the lowercase words of at least 4 letters in text
+func significantWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) >= 4 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// sharedWordScore scores the significant words content shares with words
+func sharedWordScore(content string, words map[string]bool) int {
+	if len(words) == 0 {
+		return 0
+	}
+	score := 0
+	for w := range significantWords(content) {
+		if words[w] {
+			score++
+		}
+	}
+	return min(score, maxSharedWordScore)
+}
+
+// generateID creates a unique ID for a memory entry
+func generateID() string {
+	return fmt.Sprintf("mem_%d", time.Now().UnixNano())
+}
+
+// ValidEntryTypes returns all valid entry type strings
+func ValidEntryTypes() []string {
+	return []string{"decision", "convention", "tradeoff", "context"}
+}
@@ -158,6 +158,27 @@ func TestStore_Add(t *testing.T) {
 	}
 }
 
+func TestStore_AddRedacted(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "test-memory.json"))
+	store.SetRedactor(func(s string) string { return strings.ReplaceAll(s, "s3cret", "[REDACTED]") })
+
+	entry, err := store.Add(EntryTypeDecision, "Staging password is s3cret", "", "agent")
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	if entry.Content != "Staging password is [REDACTED]" {
+		t.Errorf("expected redacted content, got %q", entry.Content)
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Error("secret persisted to the memory file")
+	}
+}
+
 func TestStore_Clear(t *testing.T) {
 	tmpDir := t.TempDir()
 	memFile := filepath.Join(tmpDir, "test-memory.json")
@@ -273,6 +294,26 @@ func TestStore_GetRelevant(t *testing.T) {
 	}
 }
 
+func TestStore_LastChanged(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "test-memory.json"))
+	store.Load()
+
+	if !store.LastChanged().IsZero() {
+		t.Errorf("expected zero time for empty store, got %v", store.LastChanged())
+	}
+
+	old := time.Now().AddDate(0, 0, -30)
+	newer := time.Now().AddDate(0, 0, -3)
+	store.memory.Entries = []Entry{
+		{ID: "newer", Type: EntryTypeDecision, Content: "Newer", CreatedAt: old, UpdatedAt: newer},
+		{ID: "old", Type: EntryTypeContext, Content: "Old", CreatedAt: old, UpdatedAt: old},
+	}
+
+	if !store.LastChanged().Equal(newer) {
+		t.Errorf("expected last change %v, got %v", newer, store.LastChanged())
+	}
+}
+
 func TestStore_Prune(t *testing.T) {
 	tmpDir := t.TempDir()
 	memFile := filepath.Join(tmpDir, "test-memory.json")
@@ -673,3 +714,215 @@ func TestStore_SaveWithDirectory(t *testing.T) {
 		t.Error("memory file should exist at nested path")
 	}
 }
+
+func TestStore_GetScoped(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "test-memory.json"))
+	store.Load()
+	store.Add(EntryTypeContext, "Login sessions expire after an hour", "", "agent")
+	store.Add(EntryTypeConvention, "Use snake_case column names", "db", "user")
+	store.Add(EntryTypeDecision, "Use Tailwind for styling", "ui", "user")
+	store.Add(EntryTypeTradeoff, "Forms validate on blur", "UI", "user")
+	store.Add(EntryTypeConvention, "Wrap errors with context", "", "user")
+
+	scoped := store.GetScoped("ui", "Build the login form with session handling", 10)
+	var got []string
+	for _, e := range scoped {
+		got = append(got, e.Content)
+	}
+	want := []string{"Use Tailwind for styling", "Forms validate on blur"}
+	if len(got) != 4 {
+		t.Fatalf("GetScoped() = %q, want the ui and global entries", got)
+	}
+	for _, c := range got {
+		if c == "Use snake_case column names" {
+			t.Errorf("GetScoped() included a db entry: %q", got)
+		}
+	}
+	if got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("GetScoped() = %q, want category entries first", got)
+	}
+
+	// Words shared with the feature rank global entries of the same type higher
+	scoped = store.GetScoped("api", "Wrap errors from the payment client", 10)
+	if len(scoped) != 2 || scoped[0].Content != "Wrap errors with context" {
+		t.Errorf("GetScoped(api) = %+v", scoped)
+	}
+
+	// Without a category nothing is left out
+	if all := store.GetScoped("", "", 0); len(all) != 5 {
+		t.Errorf("GetScoped(\"\") = %d entries, want 5", len(all))
+	}
+
+	context := store.BuildScopedPromptContext("db", "", 10)
+	if !strings.Contains(context, "snake_case") || strings.Contains(context, "Tailwind") {
+		t.Errorf("BuildScopedPromptContext(db) = %q", context)
+	}
+}
+
+func TestStore_ExportImport(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+
+	// Project A exports its memories
+	a := NewStore(filepath.Join(dir, "a.json"))
+	a.Load()
+	a.Add(EntryTypeConvention, "Use snake_case column names", "db", "user")
+	a.Add(EntryTypeDecision, "Use PostgreSQL", "", "user")
+	exportPath := filepath.Join(dir, "shared", "team-memory.json")
+	if n, err := a.Export(exportPath); err != nil || n != 2 {
+		t.Fatalf("Export() = %d, %v", n, err)
+	}
+	entries, err := ReadExport(exportPath)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ReadExport() = %d entries, %v", len(entries), err)
+	}
+
+	// Project B has an older copy of one memory and one of its own
+	b := NewStore(filepath.Join(dir, "b.json"))
+	b.Load()
+	b.Add(EntryTypeConvention, "  use SNAKE_CASE   column names ", "db", "user")
+	b.Add(EntryTypeContext, "Deploys run on Fridays", "", "user")
+	b.memory.Entries[0].UpdatedAt = old
+	b.memory.Entries[0].ID = entries[1].ID // IDs can collide between projects
+
+	result, err := b.Import(entries, true)
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	if result != (ImportResult{Added: 1, Updated: 1}) {
+		t.Errorf("Import() = %+v, want 1 added, 1 updated", result)
+	}
+	all := b.GetAll()
+	if len(all) != 3 || all[0].Content != "Use snake_case column names" || all[1].Content != "Deploys run on Fridays" {
+		t.Errorf("merged entries = %+v", all)
+	}
+	seen := map[string]bool{}
+	for _, e := range all {
+		if seen[e.ID] {
+			t.Errorf("duplicate ID %s after merge", e.ID)
+		}
+		seen[e.ID] = true
+	}
+
+	// Importing again changes nothing
+	if result, _ := b.Import(entries, true); result != (ImportResult{Unchanged: 2}) {
+		t.Errorf("second Import() = %+v, want 2 unchanged", result)
+	}
+
+	// Without merge, the imported entries replace the stored ones
+	reloaded := NewStore(filepath.Join(dir, "b.json"))
+	reloaded.Load()
+	if reloaded.Count() != 3 {
+		t.Errorf("saved store has %d entries, want 3", reloaded.Count())
+	}
+	if _, err := reloaded.Import(entries, false); err != nil || reloaded.Count() != 2 {
+		t.Errorf("Import(replace) = %d entries, %v", reloaded.Count(), err)
+	}
+
+	os.WriteFile(exportPath, []byte(`{"entries": [{"type": "opinion", "content": "x"}]}`), 0644)
+	if _, err := ReadExport(exportPath); err == nil {
+		t.Error("ReadExport() accepted an invalid entry type")
+	}
+}
+
+func TestStore_DeleteEditPin(t *testing.T) {
+	memFile := filepath.Join(t.TempDir(), "test-memory.json")
+	store := NewStore(memFile)
+	store.Load()
+	store.SetRetentionDays(30)
+	first, _ := store.Add(EntryTypeDecision, "Use PostgreSQL", "", "user")
+	second, _ := store.Add(EntryTypeConvention, "Use snake_case", "db", "user")
+	third, _ := store.Add(EntryTypeContext, "Staging is shared", "", "user")
+
+	if !strings.Contains(store.Summary(), first.ID+"  Use PostgreSQL") {
+		t.Errorf("Summary() does not show IDs:\n%s", store.Summary())
+	}
+
+	if _, err := store.Find("mem_"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Find(mem_) = %v, want an ambiguity error", err)
+	}
+	if _, err := store.Delete("mem_missing"); err == nil {
+		t.Error("Delete() of an unknown ID succeeded")
+	}
+
+	edited, err := store.Edit(first.ID, "Use PostgreSQL 16")
+	if err != nil || edited.Content != "Use PostgreSQL 16" || !edited.UpdatedAt.After(first.UpdatedAt) {
+		t.Errorf("Edit() = %+v, %v", edited, err)
+	}
+	if _, err := store.Edit(first.ID, "  "); err == nil {
+		t.Error("Edit() accepted empty content")
+	}
+
+	if deleted, err := store.Delete(second.ID); err != nil || deleted.Content != "Use snake_case" {
+		t.Errorf("Delete() = %+v, %v", deleted, err)
+	}
+
+	// Pinned memories survive pruning
+	if _, err := store.Pin(third.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	for i := range store.memory.Entries {
+		store.memory.Entries[i].UpdatedAt = time.Now().AddDate(0, 0, -60)
+	}
+	if pruned, _ := store.Prune(); pruned != 1 {
+		t.Errorf("Prune() removed %d entries, want 1", pruned)
+	}
+
+	reloaded := NewStore(memFile)
+	reloaded.Load()
+	all := reloaded.GetAll()
+	if len(all) != 1 || all[0].ID != third.ID || !all[0].Pinned {
+		t.Errorf("saved entries = %+v, want only the pinned one", all)
+	}
+	if !strings.Contains(reloaded.Summary(), "(pinned)") {
+		t.Error("Summary() does not mark pinned memories")
+	}
+}
+
+func TestStore_ProvenanceAndConfidence(t *testing.T) {
+	memFile := filepath.Join(t.TempDir(), "test-memory.json")
+	store := NewStore(memFile)
+	store.Load()
+	risky, err := store.AddFromAgent(EntryTypeConvention, "Mock the database in tests", "", Provenance{FeatureID: 4, Iteration: 2, Agent: "claude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if risky.Source != "agent" || risky.FeatureID != 4 || risky.Iteration != 2 || risky.Agent != "claude" {
+		t.Errorf("AddFromAgent() = %+v", risky)
+	}
+	good, _ := store.Add(EntryTypeDecision, "Use PostgreSQL", "", "user")
+	if risky.Confidence() != 1 {
+		t.Errorf("new memory confidence = %v, want 1", risky.Confidence())
+	}
+
+	// The risky memory keeps preceding failures; the good one recovers
+	store.RecordOutcome([]string{risky.ID, good.ID}, true)
+	store.RecordOutcome([]string{risky.ID}, true)
+	store.RecordOutcome([]string{good.ID}, false)
+	store.RecordOutcome([]string{risky.ID}, true)
+
+	reloaded := NewStore(memFile)
+	reloaded.Load()
+	byID := map[string]Entry{}
+	for _, e := range reloaded.GetAll() {
+		byID[e.ID] = e
+	}
+	if c := byID[risky.ID].Confidence(); c >= MinConfidence {
+		t.Errorf("risky confidence = %v, want below %v", c, MinConfidence)
+	}
+	if c := byID[good.ID].Confidence(); c < MinConfidence {
+		t.Errorf("good confidence = %v", c)
+	}
+
+	scoped := reloaded.GetScoped("", "", 10)
+	if len(scoped) != 1 || scoped[0].ID != good.ID {
+		t.Errorf("GetScoped() = %+v, want only the confident memory", scoped)
+	}
+
+	details := reloaded.Details()
+	for _, want := range []string{"agent, feature #4, iteration 2, claude", "0.25 (0 successful, 3 failed iterations) - not injected", "0.67 (1 successful, 1 failed"} {
+		if !strings.Contains(details, want) {
+			t.Errorf("Details() missing %q:\n%s", want, details)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package milestone
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// find returns the milestone definition with the given name or ID, or nil
+func find(milestones []Milestone, name string) *Milestone {
+	for i := range milestones {
+		if strings.EqualFold(milestones[i].Name, name) || strings.EqualFold(milestones[i].ID, name) {
+			return &milestones[i]
+		}
+	}
+	return nil
+}
+
+// checkDependencies checks that every depends_on entry names another defined
+// milestone and that the dependencies have no cycle
+func checkDependencies(milestones []Milestone) error {
+	for _, ms := range milestones {
+		for _, dep := range ms.DependsOn {
+			target := find(milestones, dep)
+			if target == nil {
+				return fmt.Errorf("milestone %q depends on unknown milestone %q", ms.Name, dep)
+			}
+			if target.Name == ms.Name {
+				return fmt.Errorf("milestone %q depends on itself", ms.Name)
+			}
+		}
+	}
+
+	// Depth-first search for a path back to a milestone on the stack
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var visit func(ms *Milestone, path []string) error
+	visit = func(ms *Milestone, path []string) error {
+		path = append(path, ms.Name)
+		switch state[ms.Name] {
+		case visiting:
+			return fmt.Errorf("milestone dependency cycle: %s", strings.Join(path, " -> "))
+		case visited:
+			return nil
+		}
+		state[ms.Name] = visiting
+		for _, dep := range ms.DependsOn {
+			if err := visit(find(milestones, dep), path); err != nil {
+				return err
+			}
+		}
+		state[ms.Name] = visited
+		return nil
+	}
+	for i := range milestones {
+		if err := visit(&milestones[i], nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isComplete reports whether all features of a milestone are tested. A
+// milestone without features has nothing left to do.
+func (m *Manager) isComplete(name string) bool {
+	for _, f := range m.GetFeaturesForMilestone(name) {
+		if !f.Tested {
+			return false
+		}
+	}
+	return true
+}
+
+// Prerequisites returns the names of the milestones the given milestone
+// depends on that are not complete yet. Its features should not be started
+// until there are none.
+func (m *Manager) Prerequisites(name string) []string {
+	ms := find(m.milestones, name)
+	if ms == nil {
+		return nil
+	}
+	var pending []string
+	for _, dep := range ms.DependsOn {
+		target := find(m.milestones, dep)
+		if target != nil && !m.isComplete(target.Name) {
+			pending = append(pending, target.Name)
+		}
+	}
+	return pending
+}
+
+// BlockedFeatures returns the untested features that belong to a milestone
+// with incomplete prerequisites, mapped to those prerequisites
+func (m *Manager) BlockedFeatures() map[int][]string {
+	blocked := make(map[int][]string)
+	for _, ms := range m.milestones {
+		pending := m.Prerequisites(ms.Name)
+		if len(pending) == 0 {
+			continue
+		}
+		for _, f := range m.GetFeaturesForMilestone(ms.Name) {
+			if !f.Tested {
+				blocked[f.ID] = appendUnique(blocked[f.ID], pending...)
+			}
+		}
+	}
+	return blocked
+}
+
+// BuildBlockedPromptContext tells the agent which features it must not start
+// yet because of milestone dependencies
+func BuildBlockedPromptContext(blocked map[int][]string) string {
+	if len(blocked) == 0 {
+		return ""
+	}
+
+	ids := make([]int, 0, len(blocked))
+	for id := range blocked {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var b strings.Builder
+	b.WriteString("\n[BLOCKED FEATURES - Do not start these; their milestone depends on milestones that are not complete yet:]\n")
+	for _, id := range ids {
+		b.WriteString(fmt.Sprintf("- Feature #%d (waits for %s)\n", id, strings.Join(blocked[id], ", ")))
+	}
+	b.WriteString("[END BLOCKED FEATURES]\n\n")
+	return b.String()
+}
+
+// HasDependencies reports whether any milestone depends on another
+func (m *Manager) HasDependencies() bool {
+	for _, ms := range m.milestones {
+		if len(ms.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDependencies renders the milestone dependency graph as a tree: each
+// milestone is listed under the milestones it depends on, so a milestone
+// with several prerequisites appears once under each of them
+func (m *Manager) FormatDependencies() string {
+	if !m.HasDependencies() {
+		return ""
+	}
+
+	progress := make(map[string]*Progress)
+	dependents := make(map[string][]string)
+	var roots []string
+	for _, p := range m.CalculateAllProgress() {
+		progress[p.Milestone.Name] = p
+		ms := p.Milestone
+		if len(ms.DependsOn) == 0 {
+			roots = append(roots, ms.Name)
+		}
+		for _, dep := range ms.DependsOn {
+			if target := find(m.milestones, dep); target != nil {
+				dependents[target.Name] = append(dependents[target.Name], ms.Name)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Milestone Dependencies:\n")
+	var render func(name, prefix, branch string)
+	render = func(name, prefix, branch string) {
+		line := name
+		if p := progress[name]; p != nil {
+			line = statusIcon(p.Status) + " " + name
+			if len(p.Blocked) > 0 {
+				line += " (blocked)"
+			}
+		}
+		b.WriteString(fmt.Sprintf("  %s%s%s\n", prefix, branch, line))
+
+		childPrefix := prefix
+		switch branch {
+		case "├─ ":
+			childPrefix += "│  "
+		case "└─ ":
+			childPrefix += "   "
+		}
+		children := dependents[name]
+		for i, child := range children {
+			if i == len(children)-1 {
+				render(child, childPrefix, "└─ ")
+			} else {
+				render(child, childPrefix, "├─ ")
+			}
+		}
+	}
+	for _, root := range roots {
+		render(root, "", "")
+	}
+	return b.String()
+}
+
+// appendUnique appends the values not already in list
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
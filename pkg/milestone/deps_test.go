@@ -0,0 +1,87 @@
+package milestone
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestDependencies(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Milestone: "Alpha", Tested: true},
+		{ID: 2, Milestone: "Alpha"},
+		{ID: 3, Milestone: "Beta"},
+		{ID: 4, Milestone: "Release"},
+		{ID: 5},
+	}
+	mgr := NewManager(plans)
+	mgr.SetMilestones([]Milestone{
+		{ID: "alpha", Name: "Alpha"},
+		{ID: "beta", Name: "Beta", DependsOn: []string{"alpha"}},
+		{ID: "release", Name: "Release", DependsOn: []string{"Alpha", "Beta"}},
+	})
+
+	if got := mgr.Prerequisites("Release"); strings.Join(got, ",") != "Alpha,Beta" {
+		t.Errorf("Prerequisites(Release) = %v", got)
+	}
+	blocked := mgr.BlockedFeatures()
+	if len(blocked) != 2 || strings.Join(blocked[3], ",") != "Alpha" || strings.Join(blocked[4], ",") != "Alpha,Beta" {
+		t.Errorf("BlockedFeatures() = %v", blocked)
+	}
+	if next := mgr.GetNextMilestoneToComplete(); next == nil || next.Milestone.Name != "Alpha" {
+		t.Errorf("GetNextMilestoneToComplete() = %+v, want Alpha", next)
+	}
+
+	ctx := BuildBlockedPromptContext(blocked)
+	if !strings.Contains(ctx, "- Feature #3 (waits for Alpha)\n- Feature #4 (waits for Alpha, Beta)\n") {
+		t.Errorf("BuildBlockedPromptContext() = %q", ctx)
+	}
+
+	want := `Milestone Dependencies:
+  ◐ Alpha
+  ├─ ○ Beta (blocked)
+  │  └─ ○ Release (blocked)
+  └─ ○ Release (blocked)
+`
+	if got := mgr.FormatDependencies(); got != want {
+		t.Errorf("FormatDependencies() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Completing Alpha unblocks Beta
+	mgr.plans[1].Tested = true
+	blocked = mgr.BlockedFeatures()
+	if len(blocked) != 1 || strings.Join(blocked[4], ",") != "Beta" {
+		t.Errorf("BlockedFeatures() after Alpha = %v", blocked)
+	}
+	if BuildBlockedPromptContext(nil) != "" {
+		t.Error("BuildBlockedPromptContext(nil) is not empty")
+	}
+}
+
+func TestLoadMilestonesDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{"valid", `[{"id": "a", "name": "A"}, {"id": "b", "name": "B", "depends_on": ["a"]}]`, ""},
+		{"unknown", `[{"id": "a", "name": "A", "depends_on": ["z"]}]`, "unknown milestone"},
+		{"self", `[{"id": "a", "name": "A", "depends_on": ["A"]}]`, "depends on itself"},
+		{"cycle", `[{"id": "a", "name": "A", "depends_on": ["c"]}, {"id": "b", "name": "B", "depends_on": ["a"]}, {"id": "c", "name": "C", "depends_on": ["b"]}]`, "cycle: A -> C -> B -> A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "milestones.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := NewManager(nil).LoadMilestones(path)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadMilestones() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
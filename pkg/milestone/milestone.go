@@ -1,4 +1,9 @@
 // Package milestone provides milestone-based progress tracking for Ralph.
+//
+// A Manager groups the features of a plan by milestone, reports their
+// progress and the features held back by incomplete prerequisite milestones.
+// It never modifies the plan it is given. This package is part of Ralph's
+// public API.
 package milestone
 
 import (
@@ -7,8 +12,9 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 // Status represents the current status of a milestone
@@ -31,6 +37,8 @@ type Milestone struct {
 	Criteria    string   `json:"criteria,omitempty"`    // Success criteria for the milestone
 	Order       int      `json:"order,omitempty"`       // Display/priority order
 	Features    []int    `json:"features,omitempty"`    // List of feature IDs (alternative to milestone field in Plan)
+	DueDate     string   `json:"due_date,omitempty"`    // Date the milestone is due (YYYY-MM-DD)
+	DependsOn   []string `json:"depends_on,omitempty"`  // Milestones (names or IDs) that must be complete before this one is started
 }
 
 // MilestoneFile represents the structure of a plan.json file that includes milestones
@@ -47,12 +55,15 @@ type Progress struct {
 	Percentage      float64
 	Status          Status
 	Features        []plan.Plan // Features belonging to this milestone
+	Schedule        *Schedule   // Schedule health, nil if the milestone has no due date
+	Blocked         []string    // Prerequisite milestones that are not complete yet
 }
 
 // Manager handles milestone operations
 type Manager struct {
 	milestones []Milestone
 	plans      []plan.Plan
+	velocity   float64 // Features completed per day, for schedule health
 }
 
 // NewManager creates a new milestone manager from plans
@@ -73,20 +84,33 @@ func (m *Manager) LoadMilestones(path string) error {
 	// First, try to parse as a file with milestones array
 	var milestoneFile MilestoneFile
 	if err := json.Unmarshal(data, &milestoneFile); err == nil && len(milestoneFile.Milestones) > 0 {
-		m.milestones = milestoneFile.Milestones
-		return nil
+		return m.setLoaded(milestoneFile.Milestones)
 	}
 
 	// Try to parse as a plain array of milestones
 	var milestones []Milestone
 	if err := json.Unmarshal(data, &milestones); err == nil && len(milestones) > 0 {
-		m.milestones = milestones
-		return nil
+		return m.setLoaded(milestones)
 	}
 
 	return nil // No milestones defined, which is valid
 }
 
+// setLoaded sets milestones read from a file, checking their due dates and
+// dependencies
+func (m *Manager) setLoaded(milestones []Milestone) error {
+	for _, ms := range milestones {
+		if _, err := ms.Due(); err != nil {
+			return err
+		}
+	}
+	if err := checkDependencies(milestones); err != nil {
+		return err
+	}
+	m.milestones = milestones
+	return nil
+}
+
 // SetMilestones sets the milestones manually (useful for testing)
 func (m *Manager) SetMilestones(milestones []Milestone) {
 	m.milestones = milestones
@@ -215,7 +239,7 @@ func (m *Manager) CalculateProgress(milestoneName string) *Progress {
 		status = StatusComplete
 	}
 	
-	return &Progress{
+	progress := &Progress{
 		Milestone:         milestone,
 		TotalFeatures:     total,
 		CompletedFeatures: completed,
@@ -223,6 +247,59 @@ func (m *Manager) CalculateProgress(milestoneName string) *Progress {
 		Status:            status,
 		Features:          features,
 	}
+	progress.Schedule = m.schedule(progress, time.Now())
+	if status != StatusComplete {
+		progress.Blocked = m.Prerequisites(milestone.Name)
+	}
+	return progress
+}
+
+// Report is a milestone with its progress, as listed by -milestones
+// -json-output. Features lists the IDs of all the milestone's features.
+type Report struct {
+	Milestone
+	Progress ProgressReport `json:"progress"`
+}
+
+// ProgressReport is the progress of a milestone in a Report
+type ProgressReport struct {
+	Status            Status   `json:"status"`
+	TotalFeatures     int      `json:"total_features"`
+	CompletedFeatures int      `json:"completed_features"`
+	Percentage        float64  `json:"percentage"`
+	Health            Health   `json:"health,omitempty"` // Schedule health, if the milestone has a due date
+	BlockedBy         []string `json:"blocked_by,omitempty"`
+}
+
+// Report returns the progress as a Report
+func (p *Progress) Report() Report {
+	r := Report{
+		Milestone: *p.Milestone,
+		Progress: ProgressReport{
+			Status:            p.Status,
+			TotalFeatures:     p.TotalFeatures,
+			CompletedFeatures: p.CompletedFeatures,
+			Percentage:        p.Percentage,
+			BlockedBy:         p.Blocked,
+		},
+	}
+	r.Features = make([]int, len(p.Features))
+	for i, f := range p.Features {
+		r.Features[i] = f.ID
+	}
+	if p.Schedule != nil {
+		r.Progress.Health = p.Schedule.Health
+	}
+	return r
+}
+
+// Report returns all milestones with their progress, in order
+func (m *Manager) Report() []Report {
+	reports := []Report{}
+	for _, p := range m.CalculateAllProgress() {
+		reports = append(reports, p.Report())
+	}
+	return reports
 }
 
 // CalculateAllProgress calculates progress for all milestones
@@ -257,12 +334,12 @@ func (m *Manager) GetCompletedMilestones() []*Progress {
 }
 
 // GetNextMilestoneToComplete returns the milestone closest to completion
-// that isn't already complete
+// that isn't already complete or blocked by its prerequisites
 func (m *Manager) GetNextMilestoneToComplete() *Progress {
 	var best *Progress
 	
 	for _, p := range m.CalculateAllProgress() {
-		if p.Status == StatusComplete {
+		if p.Status == StatusComplete || len(p.Blocked) > 0 {
 			continue
 		}
 		if best == nil || p.Percentage > best.Percentage {
@@ -275,20 +352,30 @@ func (m *Manager) GetNextMilestoneToComplete() *Progress {
 
 // FormatProgress returns a formatted string showing milestone progress
 func FormatProgress(p *Progress) string {
-	statusIcon := "○"
-	switch p.Status {
-	case StatusInProgress:
-		statusIcon = "◐"
-	case StatusComplete:
-		statusIcon = "●"
-	}
-	
-	return fmt.Sprintf("%s %s: %d/%d (%.0f%%)",
-		statusIcon,
+	line := fmt.Sprintf("%s %s: %d/%d (%.0f%%)",
+		statusIcon(p.Status),
 		p.Milestone.Name,
 		p.CompletedFeatures,
 		p.TotalFeatures,
 		p.Percentage)
+	if p.Schedule != nil {
+		line += " - " + p.Schedule.String()
+	}
+	if len(p.Blocked) > 0 {
+		line += " - blocked by " + strings.Join(p.Blocked, ", ")
+	}
+	return line
+}
+
+// statusIcon returns the symbol shown for a milestone status
+func statusIcon(status Status) string {
+	switch status {
+	case StatusInProgress:
+		return "◐"
+	case StatusComplete:
+		return "●"
+	}
+	return "○"
 }
 
 // FormatProgressBar returns a visual progress bar for a milestone
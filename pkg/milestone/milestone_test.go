@@ -5,7 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 func TestNewManager(t *testing.T) {
@@ -254,6 +254,26 @@ func TestCalculateAllProgress(t *testing.T) {
 	}
 }
 
+func TestReport(t *testing.T) {
+	plans := []plan.Plan{
+		{ID: 1, Milestone: "Alpha", Tested: true},
+		{ID: 2, Milestone: "Alpha"},
+		{ID: 3, Milestone: "Beta"},
+	}
+	reports := NewManager(plans).Report()
+	if len(reports) != 2 {
+		t.Fatalf("Report() = %d milestones, want 2", len(reports))
+	}
+	alpha := reports[0]
+	if alpha.Name != "Alpha" || alpha.Progress.Status != StatusInProgress || alpha.Progress.CompletedFeatures != 1 ||
+		len(alpha.Features) != 2 || alpha.Features[1] != 2 {
+		t.Errorf("Report()[0] = %+v", alpha)
+	}
+	if len(NewManager(nil).Report()) != 0 {
+		t.Error("Report() without milestones is not empty")
+	}
+}
+
 func TestGetCompletedMilestones(t *testing.T) {
 	plans := []plan.Plan{
 		{ID: 1, Milestone: "Alpha", Tested: true},
@@ -0,0 +1,99 @@
+package milestone
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DueDateFormat is the layout of milestone due dates
+const DueDateFormat = "2006-01-02"
+
+// Health is the schedule health of a milestone with a due date
+type Health string
+
+const (
+	// HealthOnTrack indicates the milestone is projected to finish by its due date
+	HealthOnTrack Health = "on-track"
+	// HealthAtRisk indicates the milestone is projected to finish after its
+	// due date, or there is no completion velocity to project from yet
+	HealthAtRisk Health = "at-risk"
+	// HealthLate indicates the due date has passed without the milestone
+	// being complete
+	HealthLate Health = "late"
+	// HealthDone indicates the milestone is complete
+	HealthDone Health = "done"
+)
+
+// Schedule describes how a milestone is tracking against its due date
+type Schedule struct {
+	DueDate   time.Time // End of the day the milestone is due
+	Health    Health
+	Remaining int       // Features left to complete
+	Velocity  float64   // Features completed per day
+	Projected time.Time // Projected completion, zero if there is no velocity
+}
+
+// Due returns the end of the day the milestone is due, or the zero time if it
+// has no due date
+func (ms Milestone) Due() (time.Time, error) {
+	if ms.DueDate == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.ParseInLocation(DueDateFormat, ms.DueDate, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("milestone %q: invalid due_date %q (expected YYYY-MM-DD)", ms.Name, ms.DueDate)
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// SetVelocity sets the completion velocity, in features per day, that
+// schedule health is projected from (see history.Velocity)
+func (m *Manager) SetVelocity(featuresPerDay float64) {
+	m.velocity = featuresPerDay
+}
+
+// schedule computes the schedule health of a milestone at now, or returns nil
+// if it has no due date
+func (m *Manager) schedule(p *Progress, now time.Time) *Schedule {
+	due, err := p.Milestone.Due()
+	if err != nil || due.IsZero() {
+		return nil
+	}
+
+	s := &Schedule{
+		DueDate:   due,
+		Remaining: p.TotalFeatures - p.CompletedFeatures,
+		Velocity:  m.velocity,
+	}
+	switch {
+	case p.Status == StatusComplete:
+		s.Health = HealthDone
+	case now.After(due):
+		s.Health = HealthLate
+	case m.velocity <= 0:
+		s.Health = HealthAtRisk
+	default:
+		days := float64(s.Remaining) / m.velocity
+		s.Projected = now.Add(time.Duration(math.Ceil(days * float64(24*time.Hour))))
+		s.Health = HealthOnTrack
+		if s.Projected.After(due) {
+			s.Health = HealthAtRisk
+		}
+	}
+	return s
+}
+
+// String describes the schedule, e.g. "due 2026-03-01, at-risk (projected
+// 2026-03-04 at 0.5 features/day)"
+func (s *Schedule) String() string {
+	desc := fmt.Sprintf("due %s, %s", s.DueDate.Format(DueDateFormat), s.Health)
+	switch {
+	case s.Health == HealthDone || s.Health == HealthLate:
+	case s.Projected.IsZero():
+		desc += " (no completion velocity yet)"
+	default:
+		desc += fmt.Sprintf(" (projected %s at %.1f features/day)", s.Projected.Format(DueDateFormat), s.Velocity)
+	}
+	return desc
+}
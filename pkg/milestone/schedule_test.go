@@ -0,0 +1,77 @@
+package milestone
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/pkg/plan"
+)
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	plans := []plan.Plan{
+		{ID: 1, Milestone: "Alpha", Tested: true},
+		{ID: 2, Milestone: "Alpha"},
+		{ID: 3, Milestone: "Alpha"},
+		{ID: 4, Milestone: "Beta", Tested: true},
+	}
+	mgr := NewManager(plans)
+	mgr.SetMilestones([]Milestone{
+		{ID: "alpha", Name: "Alpha", DueDate: "2026-03-12"},
+		{ID: "beta", Name: "Beta", DueDate: "2026-03-01"},
+		{ID: "gamma", Name: "Gamma"},
+	})
+
+	tests := []struct {
+		name     string
+		velocity float64
+		due      string
+		want     Health
+	}{
+		{"enough velocity", 1, "2026-03-12", HealthOnTrack},
+		{"too slow", 0.5, "2026-03-12", HealthAtRisk},
+		{"no velocity", 0, "2026-03-12", HealthAtRisk},
+		{"past due", 5, "2026-03-09", HealthLate},
+		{"due today", 5, "2026-03-10", HealthOnTrack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr.SetVelocity(tt.velocity)
+			mgr.milestones[0].DueDate = tt.due
+			p := mgr.CalculateProgress("Alpha")
+			s := mgr.schedule(p, now)
+			if s == nil || s.Health != tt.want || s.Remaining != 2 {
+				t.Errorf("schedule() = %+v, want %s", s, tt.want)
+			}
+		})
+	}
+
+	// Complete milestones are done, even past their due date
+	if s := mgr.schedule(mgr.CalculateProgress("Beta"), now); s == nil || s.Health != HealthDone {
+		t.Errorf("schedule(Beta) = %+v, want done", s)
+	}
+	if p := mgr.CalculateProgress("Gamma"); p.Schedule != nil {
+		t.Errorf("milestone without a due date has a schedule: %+v", p.Schedule)
+	}
+
+	mgr.SetVelocity(0.5)
+	mgr.milestones[0].DueDate = "2026-03-12"
+	s := mgr.schedule(mgr.CalculateProgress("Alpha"), now)
+	if got := s.String(); got != "due 2026-03-12, at-risk (projected 2026-03-14 at 0.5 features/day)" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestLoadMilestonesDueDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "milestones.json")
+	if err := os.WriteFile(path, []byte(`[{"id": "alpha", "name": "Alpha", "due_date": "next week"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := NewManager(nil).LoadMilestones(path)
+	if err == nil || !strings.Contains(err.Error(), "invalid due_date") {
+		t.Errorf("LoadMilestones() = %v, want an invalid due_date error", err)
+	}
+}
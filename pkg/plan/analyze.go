@@ -1,4 +1,3 @@
-// Package plan provides plan file operations for Ralph.
 package plan
 
 import (
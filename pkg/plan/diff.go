@@ -0,0 +1,182 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff describes the changes between two versions of a plan
+type Diff struct {
+	Added    []Plan   `json:"added"`
+	Removed  []Plan   `json:"removed"`
+	Modified []Change `json:"modified"`
+}
+
+// Change is a modification of a field of a feature
+type Change struct {
+	ID          int    `json:"id"`
+	Field       string `json:"field"`
+	OldValue    string `json:"old_value"`
+	NewValue    string `json:"new_value"`
+	Description string `json:"description,omitempty"`
+}
+
+// ComputeDiff computes the difference between two plan lists
+func ComputeDiff(oldPlans, newPlans []Plan) *Diff {
+	diff := &Diff{
+		Added:    make([]Plan, 0),
+		Removed:  make([]Plan, 0),
+		Modified: make([]Change, 0),
+	}
+
+	// Create maps for lookup
+	oldMap := make(map[int]Plan)
+	newMap := make(map[int]Plan)
+
+	for _, p := range oldPlans {
+		oldMap[p.ID] = p
+	}
+	for _, p := range newPlans {
+		newMap[p.ID] = p
+	}
+
+	// Find added and modified
+	for _, newP := range newPlans {
+		oldP, exists := oldMap[newP.ID]
+		if !exists {
+			diff.Added = append(diff.Added, newP)
+		} else {
+			// Check for modifications
+			changes := comparePlans(oldP, newP)
+			diff.Modified = append(diff.Modified, changes...)
+		}
+	}
+
+	// Find removed
+	for _, oldP := range oldPlans {
+		if _, exists := newMap[oldP.ID]; !exists {
+			diff.Removed = append(diff.Removed, oldP)
+		}
+	}
+
+	return diff
+}
+
+// comparePlans compares two plans and returns the changes
+func comparePlans(old, new Plan) []Change {
+	var changes []Change
+
+	if old.Description != new.Description {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "description",
+			OldValue: old.Description,
+			NewValue: new.Description,
+		})
+	}
+
+	if old.Category != new.Category {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "category",
+			OldValue: old.Category,
+			NewValue: new.Category,
+		})
+	}
+
+	if old.Tested != new.Tested {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "tested",
+			OldValue: fmt.Sprintf("%v", old.Tested),
+			NewValue: fmt.Sprintf("%v", new.Tested),
+		})
+	}
+
+	if old.Deferred != new.Deferred {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "deferred",
+			OldValue: fmt.Sprintf("%v", old.Deferred),
+			NewValue: fmt.Sprintf("%v", new.Deferred),
+		})
+	}
+
+	// Compare steps
+	oldSteps := strings.Join(old.Steps, "|")
+	newSteps := strings.Join(new.Steps, "|")
+	if oldSteps != newSteps {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "steps",
+			OldValue: fmt.Sprintf("%d steps", len(old.Steps)),
+			NewValue: fmt.Sprintf("%d steps", len(new.Steps)),
+		})
+	}
+
+	if old.ExpectedOutput != new.ExpectedOutput {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "expected_output",
+			OldValue: truncate(old.ExpectedOutput, 50),
+			NewValue: truncate(new.ExpectedOutput, 50),
+		})
+	}
+
+	if old.Milestone != new.Milestone {
+		changes = append(changes, Change{
+			ID:       old.ID,
+			Field:    "milestone",
+			OldValue: old.Milestone,
+			NewValue: new.Milestone,
+		})
+	}
+
+	return changes
+}
+
+// truncate shortens a string to the specified length
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+// IsEmpty returns true if there are no changes
+func (d *Diff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// Summary returns a human-readable summary of the diff
+func (d *Diff) Summary() string {
+	if d.IsEmpty() {
+		return "No changes detected"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Plan Changes:\n")
+
+	if len(d.Added) > 0 {
+		sb.WriteString(fmt.Sprintf("  + Added: %d feature(s)\n", len(d.Added)))
+		for _, p := range d.Added {
+			sb.WriteString(fmt.Sprintf("    - #%d: %s\n", p.ID, truncate(p.Description, 60)))
+		}
+	}
+
+	if len(d.Removed) > 0 {
+		sb.WriteString(fmt.Sprintf("  - Removed: %d feature(s)\n", len(d.Removed)))
+		for _, p := range d.Removed {
+			sb.WriteString(fmt.Sprintf("    - #%d: %s\n", p.ID, truncate(p.Description, 60)))
+		}
+	}
+
+	if len(d.Modified) > 0 {
+		sb.WriteString(fmt.Sprintf("  ~ Modified: %d change(s)\n", len(d.Modified)))
+		for _, c := range d.Modified {
+			sb.WriteString(fmt.Sprintf("    - #%d.%s: %s -> %s\n", c.ID, c.Field, c.OldValue, c.NewValue))
+		}
+	}
+
+	return sb.String()
+}
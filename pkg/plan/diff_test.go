@@ -0,0 +1,104 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComputeDiff(t *testing.T) {
+	oldPlans := []Plan{
+		{ID: 1, Description: "Feature A", Category: "infra"},
+		{ID: 2, Description: "Feature B", Category: "chore"},
+		{ID: 3, Description: "Feature C", Tested: false},
+	}
+
+	newPlans := []Plan{
+		{ID: 1, Description: "Feature A Modified", Category: "infra"},
+		{ID: 2, Description: "Feature B", Category: "data"},
+		{ID: 4, Description: "Feature D", Category: "new"},
+	}
+
+	diff := ComputeDiff(oldPlans, newPlans)
+
+	// Check added
+	if len(diff.Added) != 1 {
+		t.Errorf("expected 1 added, got %d", len(diff.Added))
+	}
+	if diff.Added[0].ID != 4 {
+		t.Error("expected feature 4 to be added")
+	}
+
+	// Check removed
+	if len(diff.Removed) != 1 {
+		t.Errorf("expected 1 removed, got %d", len(diff.Removed))
+	}
+	if diff.Removed[0].ID != 3 {
+		t.Error("expected feature 3 to be removed")
+	}
+
+	// Check modified (should have changes for features 1 and 2)
+	if len(diff.Modified) < 2 {
+		t.Errorf("expected at least 2 modifications, got %d", len(diff.Modified))
+	}
+
+	// Check IsEmpty
+	if diff.IsEmpty() {
+		t.Error("diff should not be empty")
+	}
+
+	// Check empty diff
+	emptyDiff := ComputeDiff(oldPlans, oldPlans)
+	if !emptyDiff.IsEmpty() {
+		t.Error("diff of same plans should be empty")
+	}
+}
+
+func TestDiffSummary(t *testing.T) {
+	diff := &Diff{
+		Added: []Plan{
+			{ID: 4, Description: "New feature"},
+		},
+		Removed: []Plan{
+			{ID: 3, Description: "Old feature"},
+		},
+		Modified: []Change{
+			{ID: 1, Field: "description", OldValue: "old", NewValue: "new"},
+		},
+	}
+
+	summary := diff.Summary()
+	if summary == "" {
+		t.Error("summary should not be empty")
+	}
+
+	// Check that summary contains expected elements
+	if !strings.Contains(summary, "Added: 1") {
+		t.Error("summary should mention added features")
+	}
+	if !strings.Contains(summary, "Removed: 1") {
+		t.Error("summary should mention removed features")
+	}
+	if !strings.Contains(summary, "Modified: 1") {
+		t.Error("summary should mention modified features")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxLen   int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this is a long string", 10, "this is..."},
+		{"", 10, ""},
+	}
+
+	for _, tt := range tests {
+		result := truncate(tt.input, tt.maxLen)
+		if result != tt.expected {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.input, tt.maxLen, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NextID returns the first free feature ID: one more than the highest ID
+func NextID(plans []Plan) int {
+	next := 1
+	for _, p := range plans {
+		if p.ID >= next {
+			next = p.ID + 1
+		}
+	}
+	return next
+}
+
+// Add appends a feature with the next free ID and returns it. Blank steps
+// are dropped.
+func Add(plans []Plan, feature Plan) ([]Plan, Plan, error) {
+	feature.Description = strings.TrimSpace(feature.Description)
+	if feature.Description == "" {
+		return plans, Plan{}, fmt.Errorf("feature description cannot be empty")
+	}
+	var steps []string
+	for _, step := range feature.Steps {
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	feature.Steps = steps
+	feature.ID = NextID(plans)
+	return append(plans, feature), feature, nil
+}
+
+// SetTested marks a feature tested or untested. Either way the feature is no
+// longer deferred: marking it untested puts it back in the queue. Open
+// questions must be answered first.
+func SetTested(plans []Plan, id int, tested bool) error {
+	p := GetByID(plans, id)
+	if p == nil {
+		return fmt.Errorf("feature #%d not found", id)
+	}
+	if p.IsQuestion() {
+		return fmt.Errorf("feature #%d is an open question: answer it first", id)
+	}
+	p.Tested = tested
+	p.Deferred = false
+	p.DeferReason = ""
+	return nil
+}
+
+// Defer defers an untested feature with the given reason
+func Defer(plans []Plan, id int, reason string) error {
+	p := GetByID(plans, id)
+	if p == nil {
+		return fmt.Errorf("feature #%d not found", id)
+	}
+	if p.Tested {
+		return fmt.Errorf("feature #%d is already tested", id)
+	}
+	MarkDeferred(plans, id, reason)
+	return nil
+}
+
+// Delete removes a feature from the plan. The other features keep their IDs.
+func Delete(plans []Plan, id int) ([]Plan, error) {
+	for i := range plans {
+		if plans[i].ID == id {
+			return append(plans[:i:i], plans[i+1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("feature #%d not found", id)
+}
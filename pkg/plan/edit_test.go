@@ -0,0 +1,82 @@
+package plan
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	plans := []Plan{{ID: 1}, {ID: 4}, {ID: 2}}
+
+	plans, added, err := Add(plans, Plan{Description: " Search ", Category: "ui", Steps: []string{"Box", " ", " Results "}})
+	if err != nil {
+		t.Fatalf("Add() = %v", err)
+	}
+	if added.ID != 5 || added.Description != "Search" || len(added.Steps) != 2 || added.Steps[1] != "Results" {
+		t.Errorf("Add() added %+v", added)
+	}
+	if len(plans) != 4 || plans[3].ID != 5 {
+		t.Errorf("Add() plans = %+v", plans)
+	}
+	if _, _, err := Add(plans, Plan{Description: "  "}); err == nil {
+		t.Error("Add() without a description should fail")
+	}
+	if _, added, _ := Add(nil, Plan{Description: "First"}); added.ID != 1 {
+		t.Errorf("Add() to an empty plan gave ID %d, want 1", added.ID)
+	}
+}
+
+func TestSetTested(t *testing.T) {
+	plans := []Plan{
+		{ID: 1, Deferred: true, DeferReason: "scope limit"},
+		{ID: 2, Type: TypeQuestion},
+	}
+
+	if err := SetTested(plans, 1, true); err != nil {
+		t.Fatalf("SetTested() = %v", err)
+	}
+	if !plans[0].Tested || plans[0].Deferred || plans[0].DeferReason != "" {
+		t.Errorf("after SetTested(1, true): %+v", plans[0])
+	}
+	if err := SetTested(plans, 1, false); err != nil || plans[0].Tested {
+		t.Errorf("SetTested(1, false) = %v, feature %+v", err, plans[0])
+	}
+	if err := SetTested(plans, 2, true); err == nil {
+		t.Error("SetTested() of an open question should fail")
+	}
+	if err := SetTested(plans, 3, true); err == nil {
+		t.Error("SetTested() of a missing feature should fail")
+	}
+}
+
+func TestDefer(t *testing.T) {
+	plans := []Plan{{ID: 1}, {ID: 2, Tested: true}}
+
+	if err := Defer(plans, 1, "waiting on design"); err != nil {
+		t.Fatalf("Defer() = %v", err)
+	}
+	if !plans[0].Deferred || plans[0].DeferReason != "waiting on design" {
+		t.Errorf("after Defer(1): %+v", plans[0])
+	}
+	if err := Defer(plans, 2, "later"); err == nil {
+		t.Error("Defer() of a tested feature should fail")
+	}
+	if err := Defer(plans, 3, "later"); err == nil {
+		t.Error("Defer() of a missing feature should fail")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	plans := []Plan{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	got, err := Delete(plans, 2)
+	if err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 3 {
+		t.Errorf("Delete(2) = %+v", got)
+	}
+	if plans[1].ID != 2 {
+		t.Errorf("Delete() modified its input: %+v", plans)
+	}
+	if _, err := Delete(plans, 4); err == nil {
+		t.Error("Delete() of a missing feature should fail")
+	}
+}
@@ -0,0 +1,161 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BackupFile is where the plan is backed up before each iteration, so a plan
+// file the agent damages can be restored
+const BackupFile = ".ralph/plan-backup.json"
+
+// Backup copies a valid plan file to backupPath. A plan file that does not
+// parse is not backed up, so the last good backup is kept.
+func Backup(path, backupPath string) error {
+	if _, err := ReadFile(path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan backup: %w", err)
+	}
+	return nil
+}
+
+// CheckIntegrity compares a plan file with its backup and returns what is
+// wrong with it: invalid JSON, features without an id or description,
+// duplicate ids, features or validations that were lost, and changed
+// iteration budgets. Features may otherwise be added and updated freely.
+func CheckIntegrity(path, backupPath string) ([]string, error) {
+	before, err := ReadFile(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan backup: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{fmt.Sprintf("%s was deleted", path)}, nil
+		}
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	after, problems := checkStructure(path, data)
+	if after == nil && len(problems) > 0 {
+		return problems, nil
+	}
+
+	for _, old := range before {
+		p := GetByID(after, old.ID)
+		switch {
+		case p == nil:
+			problems = append(problems, fmt.Sprintf("feature #%d (%s) was removed", old.ID, old.Description))
+		case len(old.Validations) > 0 && len(p.Validations) == 0:
+			problems = append(problems, fmt.Sprintf("feature #%d lost its validations", old.ID))
+		case len(old.Steps) > 0 && len(p.Steps) == 0:
+			problems = append(problems, fmt.Sprintf("feature #%d lost its steps", old.ID))
+		case p.MaxIterations != old.MaxIterations:
+			problems = append(problems, fmt.Sprintf("feature #%d's max_iterations was changed from %d to %d", old.ID, old.MaxIterations, p.MaxIterations))
+		}
+	}
+	return problems, nil
+}
+
+// CheckSchema returns the schema errors of the plan file at path: invalid
+// JSON, features without an id or description, negative or duplicate ids,
+// unknown feature types, negative iteration budgets and validations without
+// a type.
+func CheckSchema(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+	plans, problems := checkStructure(path, data)
+	for _, p := range plans {
+		if p.ID < 0 {
+			problems = append(problems, fmt.Sprintf("feature %q has a negative id (%d)", p.Description, p.ID))
+		}
+		if p.Type != "" && p.Type != TypeQuestion {
+			problems = append(problems, fmt.Sprintf("feature #%d has an unknown type %q", p.ID, p.Type))
+		}
+		if p.MaxIterations < 0 {
+			problems = append(problems, fmt.Sprintf("feature #%d has a negative max_iterations (%d)", p.ID, p.MaxIterations))
+		}
+		for i, v := range p.Validations {
+			if v.Type == "" {
+				problems = append(problems, fmt.Sprintf("validation %d of feature #%d has no type", i+1, p.ID))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// checkStructure parses the plan file data and returns its features and
+// what is wrong with them: invalid JSON, features without an id or
+// description, and duplicate ids. The features are nil if data does not
+// parse.
+func checkStructure(path string, data []byte) ([]Plan, []string) {
+	// Missing fields are only visible before they decode to zero values
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, []string{fmt.Sprintf("%s is not a valid JSON array of features: %v", path, err)}
+	}
+	var plans []Plan
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, []string{fmt.Sprintf("%s has invalid feature fields: %v", path, err)}
+	}
+
+	var problems []string
+	seen := make(map[int]bool)
+	for i, fields := range raw {
+		p := plans[i]
+		if _, ok := fields["id"]; !ok {
+			problems = append(problems, fmt.Sprintf("feature %d in the file (%q) has no id", i+1, p.Description))
+			continue
+		}
+		if seen[p.ID] {
+			problems = append(problems, fmt.Sprintf("feature id %d is used more than once", p.ID))
+		}
+		seen[p.ID] = true
+		if _, ok := fields["description"]; !ok {
+			problems = append(problems, fmt.Sprintf("feature #%d has no description", p.ID))
+		}
+	}
+	return plans, problems
+}
+
+// RestoreBackup restores a damaged plan file from its backup. Features the
+// damaged file still marks as tested stay tested, if it can be parsed, so the
+// progress of the iteration is kept. It returns the restored plans.
+func RestoreBackup(path, backupPath string) ([]Plan, error) {
+	plans, err := ReadFile(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan backup: %w", err)
+	}
+
+	if damaged, err := ReadFile(path); err == nil {
+		tested := make(map[int]bool)
+		for _, p := range damaged {
+			if p.Tested {
+				tested[p.ID] = true
+			}
+		}
+		for i := range plans {
+			if tested[plans[i].ID] {
+				plans[i].Tested = true
+			}
+		}
+	}
+
+	if err := WriteFile(path, plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
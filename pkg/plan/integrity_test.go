@@ -0,0 +1,161 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const integrityPlan = `[
+  {"id": 1, "description": "Set up project", "steps": ["init"], "tested": true},
+  {"id": 2, "description": "Health endpoint", "validations": [{"type": "http_get", "url": "http://localhost:8080/health"}]},
+  {"id": 3, "description": "Export"}
+]`
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.json")
+	backup := filepath.Join(dir, ".ralph", "plan-backup.json")
+	if err := os.WriteFile(path, []byte(integrityPlan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Backup(path, backup); err != nil {
+		t.Fatalf("Backup() failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"unchanged", integrityPlan, nil},
+		{"updated and added", `[{"id": 1, "description": "Set up project", "steps": ["init"], "tested": true},
+			{"id": 2, "description": "Health endpoint", "tested": true, "validations": [{"type": "http_get"}]},
+			{"id": 3, "description": "Export"}, {"id": 4, "description": "Import"}]`, nil},
+		{"invalid JSON", `[{"id": 1, "description": "Set up project",]`, []string{"is not a valid JSON array"}},
+		{"wrong field type", `[{"id": "1", "description": "Set up project"}]`, []string{"has invalid feature fields"}},
+		{"dropped fields", `[{"description": "Set up project", "steps": ["init"]},
+			{"id": 2, "validations": [{"type": "http_get"}]}, {"id": 3, "description": "Export"}, {"id": 3, "description": "Again"}]`,
+			[]string{`feature 1 in the file ("Set up project") has no id`, "feature #2 has no description", "feature id 3 is used more than once", "feature #1 (Set up project) was removed"}},
+		{"lost features", `[{"id": 1, "description": "Set up project"}, {"id": 2, "description": "Health endpoint"}]`,
+			[]string{"feature #1 lost its steps", "feature #2 lost its validations", "feature #3 (Export) was removed"}},
+		{"raised budget", `[{"id": 1, "description": "Set up project", "steps": ["init"]},
+			{"id": 2, "description": "Health endpoint", "validations": [{"type": "http_get"}]},
+			{"id": 3, "description": "Export", "max_iterations": 9}]`,
+			[]string{"feature #3's max_iterations was changed from 0 to 9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := CheckIntegrity(path, backup)
+			if err != nil {
+				t.Fatalf("CheckIntegrity() failed: %v", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("CheckIntegrity() = %q, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+
+	// A damaged plan is not backed up over the good one
+	if err := os.WriteFile(path, []byte(`not json`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Backup(path, backup); err == nil {
+		t.Error("Backup() of an invalid plan succeeded")
+	}
+	os.Remove(path)
+	if problems, err := CheckIntegrity(path, backup); err != nil || len(problems) != 1 || !strings.Contains(problems[0], "was deleted") {
+		t.Errorf("CheckIntegrity() of a deleted plan = %q, %v", problems, err)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"valid", integrityPlan, nil},
+		{"invalid JSON", `{"id": 1}`, []string{"is not a valid JSON array"}},
+		{"invalid features", `[{"id": -1, "description": "Negative"}, {"id": 2, "description": "Odd", "type": "epic"},
+			{"id": 3, "max_iterations": -2, "validations": [{"url": "http://localhost"}]}, {"id": 3, "description": "Again"}]`,
+			[]string{"feature #3 has no description", "feature id 3 is used more than once", `feature "Negative" has a negative id (-1)`,
+				`feature #2 has an unknown type "epic"`, "feature #3 has a negative max_iterations (-2)", "validation 1 of feature #3 has no type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			problems, err := CheckSchema(path)
+			if err != nil {
+				t.Fatalf("CheckSchema() failed: %v", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("CheckSchema() = %q, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+
+	if _, err := CheckSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("CheckSchema() of a missing plan returned no error")
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "plan.json")
+	backup := filepath.Join(dir, "plan-backup.json")
+	if err := os.WriteFile(path, []byte(integrityPlan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Backup(path, backup); err != nil {
+		t.Fatal(err)
+	}
+
+	// The agent marked feature #3 tested but dropped feature #2
+	damaged := `[{"id": 1, "description": "Set up project", "tested": true}, {"id": 3, "description": "Export", "tested": true}]`
+	if err := os.WriteFile(path, []byte(damaged), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plans, err := RestoreBackup(path, backup)
+	if err != nil {
+		t.Fatalf("RestoreBackup() failed: %v", err)
+	}
+	restored, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 3 || len(restored) != 3 || len(restored[1].Validations) != 1 || len(restored[0].Steps) != 1 {
+		t.Fatalf("restored plan = %+v", restored)
+	}
+	if !restored[0].Tested || restored[1].Tested || !restored[2].Tested {
+		t.Errorf("tested flags = %v %v %v, want true false true", restored[0].Tested, restored[1].Tested, restored[2].Tested)
+	}
+
+	// An unparseable plan is restored as it was backed up
+	if err := os.WriteFile(path, []byte(`[{"id": 1,`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreBackup(path, backup); err != nil {
+		t.Fatalf("RestoreBackup() failed: %v", err)
+	}
+	if restored, err := ReadFile(path); err != nil || len(restored) != 3 || restored[2].Tested {
+		t.Errorf("restored plan = %+v, %v", restored, err)
+	}
+}
@@ -0,0 +1,200 @@
+package plan
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	notesHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	notesCheckRe   = regexp.MustCompile(`^[-*+]\s+\[([ xX])\]\s+(.+)$`)
+	notesBulletRe  = regexp.MustCompile(`^[-*+]\s+(.+)$`)
+	notesNumberRe  = regexp.MustCompile(`^\d+[.)]\s+(.+)$`)
+)
+
+// notesContextSections are section titles whose items describe the project
+// rather than work to do, and are not turned into features
+var notesContextSections = []string{
+	"requirement", "constraint", "assumption", "background", "context",
+	"overview", "note", "non goal", "out of scope", "reference",
+}
+
+// notesGenericSections are section titles that hold features of no
+// particular category
+var notesGenericSections = map[string]bool{
+	"features": true, "feature": true, "tasks": true, "task": true,
+	"todo": true, "to do": true, "to-do": true, "backlog": true, "work": true,
+}
+
+// notesItem is a list item of a notes section
+type notesItem struct {
+	indent   int
+	numbered bool
+	checked  bool
+	text     string
+}
+
+// notesSection is a heading of the notes and the list items under it
+type notesSection struct {
+	level int
+	title string
+	items []notesItem
+}
+
+// ParseNotes turns structured markdown notes into features without an agent.
+// Each section (heading) is read on its own:
+//   - Top-level bullet and checklist items become features, with the items
+//     nested under them as steps. Checked items ("- [x]") are already tested.
+//   - A section holding only a numbered list becomes one feature named after
+//     the heading, with the numbered items as steps.
+//   - Items of sections about questions become question features, and those
+//     of context sections (requirements, constraints, notes, ...) are skipped.
+//
+// The section title is the category of its features; the document title
+// (level 1 heading) and sections named like "Features" or "Tasks" give
+// "feature". Features are numbered from 1.
+func ParseNotes(notes string) []Plan {
+	var plans []Plan
+	var titles [7]string // Title of the current section at each heading level
+	for _, s := range parseNotesSections(notes) {
+		titles[s.level] = s.title
+		for l := s.level + 1; l < len(titles); l++ {
+			titles[l] = ""
+		}
+		if len(s.items) == 0 || isNotesContextSection(s.title) {
+			continue
+		}
+
+		top := s.items[0].indent
+		allNumbered := true
+		for _, item := range s.items {
+			if item.indent < top {
+				top = item.indent
+			}
+		}
+		for _, item := range s.items {
+			if item.indent == top && !item.numbered {
+				allNumbered = false
+			}
+		}
+
+		if allNumbered && s.level > 0 && !isNotesQuestionSection(s.title) {
+			// Categorized by the enclosing section, if any
+			parent := ""
+			for l := s.level - 1; l >= 2 && parent == ""; l-- {
+				parent = titles[l]
+			}
+			feature := Plan{Category: notesCategory(parent), Description: s.title}
+			for _, item := range s.items {
+				feature.Steps = append(feature.Steps, item.text)
+			}
+			plans = append(plans, feature)
+			continue
+		}
+
+		category := "feature"
+		if s.level >= 2 {
+			category = notesCategory(s.title)
+		}
+		first := len(plans)
+		for _, item := range s.items {
+			if item.indent > top && len(plans) > first {
+				last := &plans[len(plans)-1]
+				last.Steps = append(last.Steps, item.text)
+				continue
+			}
+			feature := Plan{Category: category, Description: item.text, Tested: item.checked}
+			if isNotesQuestionSection(s.title) {
+				feature.Type = TypeQuestion
+				feature.Tested = false
+			}
+			plans = append(plans, feature)
+		}
+	}
+
+	for i := range plans {
+		plans[i].ID = i + 1
+	}
+	return plans
+}
+
+// parseNotesSections splits notes into sections of list items. Items before
+// the first heading form a section of level 0. Fenced code blocks are skipped
+// and indented lines that are not list items continue the previous item.
+func parseNotesSections(notes string) []notesSection {
+	sections := []notesSection{{}}
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(notes, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || trimmed == "" {
+			continue
+		}
+
+		current := &sections[len(sections)-1]
+		if m := notesHeadingRe.FindStringSubmatch(line); m != nil {
+			sections = append(sections, notesSection{level: len(m[1]), title: cleanNotesText(m[2])})
+			continue
+		}
+
+		indent := len(strings.ReplaceAll(line[:len(line)-len(strings.TrimLeft(line, " \t"))], "\t", "    "))
+		item := notesItem{indent: indent}
+		if m := notesCheckRe.FindStringSubmatch(trimmed); m != nil {
+			item.checked = m[1] != " "
+			item.text = m[2]
+		} else if m := notesBulletRe.FindStringSubmatch(trimmed); m != nil {
+			item.text = m[1]
+		} else if m := notesNumberRe.FindStringSubmatch(trimmed); m != nil {
+			item.numbered = true
+			item.text = m[1]
+		} else {
+			// A wrapped item continues on an indented line; other text is prose
+			if indent > 0 && len(current.items) > 0 {
+				last := &current.items[len(current.items)-1]
+				last.text += " " + trimmed
+			}
+			continue
+		}
+		if item.text = cleanNotesText(item.text); item.text != "" {
+			current.items = append(current.items, item)
+		}
+	}
+	return sections
+}
+
+// cleanNotesText removes emphasis markers from a heading or item
+func cleanNotesText(s string) string {
+	s = strings.ReplaceAll(s, "**", "")
+	s = strings.ReplaceAll(s, "__", "")
+	return strings.TrimSpace(s)
+}
+
+// notesCategory returns the category of the features of a section
+func notesCategory(title string) string {
+	t := strings.ToLower(strings.TrimSpace(title))
+	if t == "" || notesGenericSections[t] {
+		return "feature"
+	}
+	return strings.Join(strings.Fields(t), "-")
+}
+
+// isNotesContextSection reports whether a section describes the project
+// rather than work to do
+func isNotesContextSection(title string) bool {
+	// Compare whole words, so "Preferences" is not taken for "reference"
+	words := " " + strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(title, "-", " "))), " ") + " "
+	for _, s := range notesContextSections {
+		if strings.Contains(words, " "+s+" ") || strings.Contains(words, " "+s+"s ") {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotesQuestionSection reports whether a section lists open questions
+func isNotesQuestionSection(title string) bool {
+	return strings.Contains(strings.ToLower(title), "question")
+}
@@ -0,0 +1,56 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNotes(t *testing.T) {
+	notes := `# Shop
+
+- Project skeleton
+
+## Features
+- User authentication
+  - Sign up with email
+  - Log in and out
+- [x] Landing page
+- [ ] Product **search**
+  with filters
+
+## Requirements
+- Must use PostgreSQL
+
+## Checkout
+### Payment flow
+1. Collect the card
+2. Charge it
+   with retries
+
+## User Preferences
+* Dark mode
+
+## Open Questions
+- Which payment provider?
+
+` + "```" + `
+- not a feature
+` + "```" + `
+`
+	want := []Plan{
+		{ID: 1, Category: "feature", Description: "Project skeleton"},
+		{ID: 2, Category: "feature", Description: "User authentication", Steps: []string{"Sign up with email", "Log in and out"}},
+		{ID: 3, Category: "feature", Description: "Landing page", Tested: true},
+		{ID: 4, Category: "feature", Description: "Product search with filters"},
+		{ID: 5, Category: "checkout", Description: "Payment flow", Steps: []string{"Collect the card", "Charge it with retries"}},
+		{ID: 6, Category: "user-preferences", Description: "Dark mode"},
+		{ID: 7, Category: "open-questions", Description: "Which payment provider?", Type: TypeQuestion},
+	}
+	if got := ParseNotes(notes); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNotes() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := ParseNotes("Just some prose.\n\n## Ideas\nMaybe later."); len(got) != 0 {
+		t.Errorf("ParseNotes() of prose = %+v, want no features", got)
+	}
+}
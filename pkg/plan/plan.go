@@ -0,0 +1,330 @@
+// Package plan provides plan file operations for Ralph.
+//
+// A plan is a JSON array of features (see docs/reference/plan-format.md).
+// ReadFile, WriteFile and Update read and write plan files, safely against
+// concurrent Ralph processes; the remaining functions work on the features in
+// memory.
+// This package is part of Ralph's public API: exported identifiers keep their
+// behavior across minor releases, and the JSON format stays readable by older
+// and newer versions.
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/logimos/ralph/internal/filelock"
+)
+
+// ValidationDefinition represents a validation rule for a feature
+type ValidationDefinition struct {
+	Type            string                 `json:"type"`                       // http_get, http_post, cli_command, file_exists, output_contains, coverage, security_scan, db_query, browser
+	URL             string                 `json:"url,omitempty"`              // For HTTP validations
+	Method          string                 `json:"method,omitempty"`           // HTTP method (defaults based on type)
+	Body            string                 `json:"body,omitempty"`             // Request body for POST
+	Headers         map[string]string      `json:"headers,omitempty"`          // HTTP headers
+	ExpectedStatus  int                    `json:"expected_status,omitempty"`  // Expected HTTP status code
+	ExpectedBody    string                 `json:"expected_body,omitempty"`    // Expected response body pattern (regex)
+	ExpectedJSON    map[string]interface{} `json:"expected_json,omitempty"`    // JSON path -> expected value or assertions
+	ExpectedHeaders map[string]string      `json:"expected_headers,omitempty"` // Header name -> expected value pattern (regex)
+	Command         string                 `json:"command,omitempty"`          // For CLI validations
+	Args            []string               `json:"args,omitempty"`             // Command arguments
+	Path            string                 `json:"path,omitempty"`             // For file_exists validation
+	Pattern         string                 `json:"pattern,omitempty"`          // For output_contains validation
+	Input           string                 `json:"input,omitempty"`            // Input to check for pattern
+	DSN             string                 `json:"dsn,omitempty"`              // Database to connect to for db_query
+	Query           string                 `json:"query,omitempty"`            // Query to run for db_query
+	Timeout         string                 `json:"timeout,omitempty"`          // Timeout duration (e.g., "30s")
+	Retries         int                    `json:"retries,omitempty"`          // Number of retries
+	Description     string                 `json:"description,omitempty"`      // Human-readable description
+	Options         map[string]interface{} `json:"options,omitempty"`          // Additional options
+	ManagedProcess  *ManagedProcess        `json:"managed_process,omitempty"`  // Process to run while validating
+}
+
+// ManagedProcess describes a process (e.g., the app's server) that is started
+// before a validation, waited on until ready and stopped afterwards
+type ManagedProcess struct {
+	Command         string            `json:"command"`                    // Command that starts the process
+	Args            []string          `json:"args,omitempty"`             // Command arguments
+	Env             map[string]string `json:"env,omitempty"`              // Extra environment variables
+	ReadyPort       int               `json:"ready_port,omitempty"`       // Ready once this localhost TCP port accepts connections
+	ReadyPattern    string            `json:"ready_pattern,omitempty"`    // Ready once the output matches this regex
+	ReadyTimeout    string            `json:"ready_timeout,omitempty"`    // How long to wait for readiness (default: 30s)
+	TeardownCommand string            `json:"teardown_command,omitempty"` // Command run after the process is stopped
+}
+
+// Plan represents the structure of a plan file
+type Plan struct {
+	ID             int                    `json:"id"`
+	Category       string                 `json:"category,omitempty"`
+	Command        string                 `json:"command,omitempty"`
+	Description    string                 `json:"description"`
+	Steps          []string               `json:"steps,omitempty"`
+	ExpectedOutput string                 `json:"expected_output,omitempty"`
+	Tested         bool                   `json:"tested,omitempty"`
+	Milestone      string                 `json:"milestone,omitempty"`       // Optional milestone this feature belongs to
+	MilestoneOrder int                    `json:"milestone_order,omitempty"` // Order within the milestone (for prioritization)
+	Deferred       bool                   `json:"deferred,omitempty"`        // Whether this feature has been deferred due to scope constraints
+	DeferReason    string                 `json:"defer_reason,omitempty"`    // Reason for deferral (if deferred)
+	MaxIterations  int                    `json:"max_iterations,omitempty"`  // Iteration budget for the feature, overriding -scope-limit
+	Validations    []ValidationDefinition `json:"validations,omitempty"`     // Outcome-focused validations for the feature
+	Type           string                 `json:"type,omitempty"`            // "question" for an unresolved requirement; empty for a regular feature
+	Answer         string                 `json:"answer,omitempty"`          // Human answer that turned a question into an actionable feature
+	Issue          string                 `json:"issue,omitempty"`           // Tracker issue the feature was imported from (e.g., github:42)
+}
+
+// ReadFile reads and parses a plan file
+func ReadFile(path string) ([]Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	var plans []Plan
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file: %w", err)
+	}
+
+	return plans, nil
+}
+
+// WriteFile writes plans to a plan file
+func WriteFile(path string, plans []Plan) error {
+	// Other Ralph processes may be writing the plan too
+	return filelock.With(path, func() error {
+		return write(path, plans)
+	})
+}
+
+// ErrNoChange is returned by the function passed to Update to leave the plan
+// file as it is
+var ErrNoChange = errors.New("plan not changed")
+
+// Update reads a plan file, applies fn to its plans and writes the result,
+// holding the plan's lock throughout so that updates by other Ralph
+// processes are not lost. A missing plan file is read as an empty plan and
+// created. Nothing is written if fn returns an error; ErrNoChange is not
+// reported.
+func Update(path string, fn func([]Plan) ([]Plan, error)) error {
+	return filelock.With(path, func() error {
+		var plans []Plan
+		if _, err := os.Stat(path); err == nil {
+			if plans, err = ReadFile(path); err != nil {
+				return err
+			}
+		}
+		plans, err := fn(plans)
+		if errors.Is(err, ErrNoChange) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return write(path, plans)
+	})
+}
+
+// write writes plans to a plan file without locking it
+func write(path string, plans []Plan) error {
+	data, err := json.MarshalIndent(plans, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plans: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// MarkDeferred marks a plan as deferred with the given reason
+func MarkDeferred(plans []Plan, featureID int, reason string) bool {
+	for i := range plans {
+		if plans[i].ID == featureID {
+			plans[i].Deferred = true
+			plans[i].DeferReason = reason
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDeferred returns plans filtered by deferred status
+func FilterDeferred(plans []Plan, deferred bool) []Plan {
+	var result []Plan
+	for _, plan := range plans {
+		if plan.Deferred == deferred {
+			result = append(result, plan)
+		}
+	}
+	return result
+}
+
+// Summary counts the features of a plan by state. Each feature is counted in
+// one state: tested, else deferred, else question, else untested.
+type Summary struct {
+	Total     int `json:"total"`
+	Tested    int `json:"tested"`
+	Untested  int `json:"untested"` // Features that can be worked on
+	Deferred  int `json:"deferred"`
+	Questions int `json:"questions"` // Open questions waiting for an answer
+}
+
+// Summarize counts the features of plans by state
+func Summarize(plans []Plan) Summary {
+	s := Summary{Total: len(plans)}
+	for _, p := range plans {
+		switch {
+		case p.Tested:
+			s.Tested++
+		case p.Deferred:
+			s.Deferred++
+		case p.IsQuestion():
+			s.Questions++
+		default:
+			s.Untested++
+		}
+	}
+	return s
+}
+
+// GetByID returns a plan by its ID, or nil if not found
+func GetByID(plans []Plan, id int) *Plan {
+	for i := range plans {
+		if plans[i].ID == id {
+			return &plans[i]
+		}
+	}
+	return nil
+}
+
+// Filter filters plans by tested status
+func Filter(plans []Plan, tested bool) []Plan {
+	var result []Plan
+	for _, plan := range plans {
+		if plan.Tested == tested {
+			result = append(result, plan)
+		}
+	}
+	return result
+}
+
+// Print prints plans in a formatted table
+func Print(plans []Plan) {
+	// Find max widths for formatting
+	maxIDLen := 0
+	maxCatLen := 0
+	for _, plan := range plans {
+		idLen := len(fmt.Sprintf("%d", plan.ID))
+		if idLen > maxIDLen {
+			maxIDLen = idLen
+		}
+		if len(plan.Category) > maxCatLen {
+			maxCatLen = len(plan.Category)
+		}
+	}
+
+	// Ensure minimum widths
+	if maxIDLen < 2 {
+		maxIDLen = 2
+	}
+	if maxCatLen < 8 {
+		maxCatLen = 8
+	}
+
+	// Print formatted output
+	for _, plan := range plans {
+		fmt.Printf("%-*d  %-*s  %s\n", maxIDLen, plan.ID, maxCatLen, plan.Category, plan.Description)
+	}
+}
+
+// ExtractAndWrite attempts to extract JSON from agent output and write it to file
+func ExtractAndWrite(output, outputPath string) error {
+	// Try to find JSON array in the output
+	// Look for content between ```json and ``` or just find the JSON array
+	jsonStart := -1
+	for i := 0; i < len(output); i++ {
+		if output[i] == '[' {
+			jsonStart = i
+			break
+		}
+	}
+
+	if jsonStart == -1 {
+		// Try to find code block
+		jsonBlockStart := indexOf(output, "```json")
+		if jsonBlockStart != -1 {
+			jsonStart = jsonBlockStart + 7
+		} else {
+			jsonBlockStart = indexOf(output, "```")
+			if jsonBlockStart != -1 {
+				jsonStart = jsonBlockStart + 3
+			}
+		}
+	}
+
+	if jsonStart == -1 {
+		return fmt.Errorf("could not find JSON in output")
+	}
+
+	// Find the end of the JSON array
+	jsonEnd := lastIndexOf(output, "]")
+	if jsonEnd == -1 || jsonEnd <= jsonStart {
+		return fmt.Errorf("could not find end of JSON array")
+	}
+
+	// Extract JSON
+	jsonContent := trimSpace(output[jsonStart : jsonEnd+1])
+
+	// Validate it's valid JSON by parsing it
+	var plans []Plan
+	if err := json.Unmarshal([]byte(jsonContent), &plans); err != nil {
+		return fmt.Errorf("extracted content is not valid JSON: %w", err)
+	}
+
+	// Write to file with proper formatting
+	formattedJSON, err := json.MarshalIndent(plans, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to format JSON: %w", err)
+	}
+
+	if err := os.WriteFile(outputPath, formattedJSON, 0644); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+
+	return nil
+}
+
+// indexOf returns the index of the first occurrence of substr in s, or -1
+func indexOf(s, substr string) int {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastIndexOf returns the index of the last occurrence of substr in s, or -1
+func lastIndexOf(s, substr string) int {
+	for i := len(s) - len(substr); i >= 0; i-- {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
+
+// trimSpace removes leading and trailing whitespace
+func trimSpace(s string) string {
+	start := 0
+	for start < len(s) && (s[start] == ' ' || s[start] == '\t' || s[start] == '\n' || s[start] == '\r') {
+		start++
+	}
+	end := len(s)
+	for end > start && (s[end-1] == ' ' || s[end-1] == '\t' || s[end-1] == '\n' || s[end-1] == '\r') {
+		end--
+	}
+	return s[start:end]
+}
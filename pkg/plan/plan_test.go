@@ -0,0 +1,59 @@
+package plan
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	plans := []Plan{
+		{ID: 1, Tested: true},
+		{ID: 2},
+		{ID: 3, Deferred: true},
+		{ID: 4, Type: TypeQuestion},
+		{ID: 5, Tested: true, Deferred: true},
+	}
+	want := Summary{Total: 5, Tested: 2, Untested: 1, Deferred: 1, Questions: 1}
+	if got := Summarize(plans); got != want {
+		t.Errorf("Summarize() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	// Concurrent updates of a missing plan each add a feature; none is lost
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Update(path, func(plans []Plan) ([]Plan, error) {
+				plans, _, err := Add(plans, Plan{Category: "feature", Description: "Feature"})
+				return plans, err
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	plans, err := ReadFile(path)
+	if err != nil || len(plans) != 10 {
+		t.Fatalf("ReadFile() = %d features, %v; want 10", len(plans), err)
+	}
+
+	// Errors and ErrNoChange leave the file unchanged
+	failed := errors.New("failed")
+	if err := Update(path, func([]Plan) ([]Plan, error) { return nil, failed }); !errors.Is(err, failed) {
+		t.Errorf("Update() = %v, want the error of fn", err)
+	}
+	if err := Update(path, func([]Plan) ([]Plan, error) { return nil, ErrNoChange }); err != nil {
+		t.Errorf("Update() = %v, want nil for ErrNoChange", err)
+	}
+	if plans, _ := ReadFile(path); len(plans) != 10 {
+		t.Errorf("plan has %d features after failed updates, want 10", len(plans))
+	}
+}
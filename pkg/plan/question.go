@@ -0,0 +1,51 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeQuestion marks a feature that represents an unresolved requirement.
+// Questions are not worked on until a human answers them.
+const TypeQuestion = "question"
+
+// IsQuestion reports whether the feature is an open question
+func (p Plan) IsQuestion() bool {
+	return p.Type == TypeQuestion
+}
+
+// IsActionable reports whether the feature can be worked on: it is not
+// tested, not deferred and not an open question
+func (p Plan) IsActionable() bool {
+	return !p.Tested && !p.Deferred && !p.IsQuestion()
+}
+
+// FilterQuestions returns the open questions
+func FilterQuestions(plans []Plan) []Plan {
+	var result []Plan
+	for _, p := range plans {
+		if p.IsQuestion() {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// AnswerQuestion records the answer to an open question and turns it into an
+// actionable feature
+func AnswerQuestion(plans []Plan, id int, answer string) error {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return fmt.Errorf("answer cannot be empty")
+	}
+	p := GetByID(plans, id)
+	if p == nil {
+		return fmt.Errorf("feature #%d not found", id)
+	}
+	if !p.IsQuestion() {
+		return fmt.Errorf("feature #%d is not an open question", id)
+	}
+	p.Type = ""
+	p.Answer = answer
+	return nil
+}
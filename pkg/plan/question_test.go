@@ -0,0 +1,38 @@
+package plan
+
+import "testing"
+
+func TestQuestions(t *testing.T) {
+	plans := []Plan{
+		{ID: 1, Description: "Set up project"},
+		{ID: 2, Type: TypeQuestion, Description: "Should exports be CSV or JSON?"},
+		{ID: 3, Description: "Done already", Tested: true},
+	}
+
+	if plans[1].IsActionable() || !plans[0].IsActionable() || plans[2].IsActionable() {
+		t.Error("only untested, non-question features should be actionable")
+	}
+	if q := FilterQuestions(plans); len(q) != 1 || q[0].ID != 2 {
+		t.Fatalf("FilterQuestions = %v", q)
+	}
+
+	if err := AnswerQuestion(plans, 1, "CSV"); err == nil {
+		t.Error("answering a regular feature should fail")
+	}
+	if err := AnswerQuestion(plans, 2, "  "); err == nil {
+		t.Error("empty answer should fail")
+	}
+	if err := AnswerQuestion(plans, 9, "CSV"); err == nil {
+		t.Error("unknown feature should fail")
+	}
+
+	if err := AnswerQuestion(plans, 2, "CSV, with a header row"); err != nil {
+		t.Fatalf("AnswerQuestion failed: %v", err)
+	}
+	if !plans[1].IsActionable() || plans[1].Answer != "CSV, with a header row" {
+		t.Errorf("answered question should be actionable with its answer, got %+v", plans[1])
+	}
+	if len(FilterQuestions(plans)) != 0 {
+		t.Error("answered question should no longer be open")
+	}
+}
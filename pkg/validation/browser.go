@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// BrowserValidator validates a page loaded in headless Chrome. Loading pages
+// needs ralph built with -tags browser; other builds fail browser
+// validations with an explanation.
+type BrowserValidator struct {
+	URL        string
+	Title      *regexp.Regexp // Regex the page title must match (nil = no check)
+	Selector   string         // CSS selector that must be present (empty = body)
+	Pattern    *regexp.Regexp // Regex the selector's text must match (nil = no check)
+	Screenshot string         // Path to save a PNG screenshot to (empty = none)
+	ChromePath string         // Chrome executable (empty = found automatically)
+	Config     ValidatorConfig
+	Desc       string
+}
+
+// browserPage is what a browser validation reads from a loaded page
+type browserPage struct {
+	Title string
+	Text  string // Text of the selector, or of the body
+}
+
+// NewBrowserValidator creates a new browser validator from a definition
+func NewBrowserValidator(def ValidationDefinition) (*BrowserValidator, error) {
+	v := &BrowserValidator{
+		URL:  def.URL,
+		Desc: def.Description,
+	}
+	v.Selector, _ = def.Options["selector"].(string)
+	v.Screenshot, _ = def.Options["screenshot"].(string)
+	v.ChromePath, _ = def.Options["chrome_path"].(string)
+	if title, ok := def.Options["title"].(string); ok {
+		re, err := regexp.Compile(title)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern: %w", err)
+		}
+		v.Title = re
+	}
+	if def.Pattern != "" {
+		re, err := regexp.Compile(def.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		v.Pattern = re
+	}
+
+	timeout := DefaultTimeout * 2 // Starting Chrome takes a while
+	if def.Timeout != "" {
+		if d, err := time.ParseDuration(def.Timeout); err == nil {
+			timeout = d
+		}
+	}
+	v.Config = ValidatorConfig{Timeout: timeout}
+	return v, nil
+}
+
+// Validate loads the page and checks its title and text
+func (v *BrowserValidator) Validate(ctx context.Context) ValidationResult {
+	start := time.Now()
+	result := ValidationResult{
+		ValidatorID: fmt.Sprintf("browser_%s", sanitizeURL(v.URL)),
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, v.Config.Timeout)
+	defer cancel()
+	page, err := v.load(loadCtx)
+	result.Duration = time.Since(start)
+	result.Output = page.Text
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("failed to load %s in the browser", v.URL)
+		result.Error = err.Error()
+		return result
+	}
+
+	if v.Title != nil && !v.Title.MatchString(page.Title) {
+		result.Success = false
+		result.Message = fmt.Sprintf("page title %q does not match %q", page.Title, v.Title.String())
+		result.Error = "title mismatch"
+		return result
+	}
+	if v.Pattern != nil && !v.Pattern.MatchString(page.Text) {
+		result.Success = false
+		result.Message = fmt.Sprintf("text of %s does not match %q", v.target(), v.Pattern.String())
+		result.Error = "pattern not found"
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("%s renders %s as expected", v.URL, v.target())
+	if v.Screenshot != "" {
+		result.Message += fmt.Sprintf(" (screenshot: %s)", v.Screenshot)
+	}
+	return result
+}
+
+// target names the element the validation reads
+func (v *BrowserValidator) target() string {
+	if v.Selector == "" {
+		return "the page"
+	}
+	return fmt.Sprintf("%q", v.Selector)
+}
+
+// Type returns the validation type
+func (v *BrowserValidator) Type() ValidationType {
+	return ValidationTypeBrowser
+}
+
+// Description returns a human-readable description
+func (v *BrowserValidator) Description() string {
+	if v.Desc != "" {
+		return v.Desc
+	}
+	return fmt.Sprintf("browser: %s", v.URL)
+}
@@ -0,0 +1,58 @@
+//go:build browser
+
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/chromedp/chromedp"
+)
+
+// load opens the URL in a new headless Chrome, waits for the selector and
+// reads the page, saving a screenshot if one is requested
+func (v *BrowserValidator) load(ctx context.Context) (browserPage, error) {
+	opts := chromedp.DefaultExecAllocatorOptions[:]
+	if v.ChromePath != "" {
+		opts = append(opts, chromedp.ExecPath(v.ChromePath))
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	defer cancelAlloc()
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	defer cancelBrowser()
+
+	selector := v.Selector
+	if selector == "" {
+		selector = "body"
+	}
+	var page browserPage
+	var screenshot []byte
+	actions := []chromedp.Action{
+		chromedp.Navigate(v.URL),
+		chromedp.WaitReady(selector, chromedp.ByQuery),
+		chromedp.Title(&page.Title),
+		chromedp.Text(selector, &page.Text, chromedp.ByQuery),
+	}
+	if v.Screenshot != "" {
+		actions = append(actions, chromedp.FullScreenshot(&screenshot, 100)) // 100 = PNG
+	}
+	if err := chromedp.Run(browserCtx, actions...); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && v.Selector != "" {
+			return page, fmt.Errorf("selector %q not found before the timeout", v.Selector)
+		}
+		return page, err
+	}
+
+	if v.Screenshot != "" {
+		if err := os.MkdirAll(filepath.Dir(v.Screenshot), 0755); err != nil {
+			return page, fmt.Errorf("failed to save screenshot: %w", err)
+		}
+		if err := os.WriteFile(v.Screenshot, screenshot, 0644); err != nil {
+			return page, fmt.Errorf("failed to save screenshot: %w", err)
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,71 @@
+//go:build browser
+
+package validation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// requireChrome skips the test if no Chrome is installed
+func requireChrome(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return
+		}
+	}
+	t.Skip("Chrome not installed")
+}
+
+func TestBrowserValidator(t *testing.T) {
+	requireChrome(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Todo App</title></head><body>
+			<ul id="todos"></ul>
+			<script>document.getElementById("todos").innerHTML = "<li>Buy milk</li>";</script>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	screenshot := filepath.Join(t.TempDir(), "shots", "todos.png")
+	tests := []struct {
+		name        string
+		pattern     string
+		options     map[string]interface{}
+		wantSuccess bool
+	}{
+		{"title and rendered text", "Buy milk", map[string]interface{}{"title": "^Todo", "selector": "#todos", "screenshot": screenshot}, true},
+		{"wrong title", "", map[string]interface{}{"title": "^Login"}, false},
+		{"missing text", "Walk dog", map[string]interface{}{"selector": "#todos"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := CreateValidator(ValidationDefinition{
+				Type:    ValidationTypeBrowser,
+				URL:     server.URL,
+				Pattern: tt.pattern,
+				Timeout: "30s",
+				Options: tt.options,
+			})
+			if err != nil {
+				t.Fatalf("CreateValidator() error = %v", err)
+			}
+			result := v.Validate(context.Background())
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Validate() success = %v, want %v (%s %s)", result.Success, tt.wantSuccess, result.Message, result.Error)
+			}
+		})
+	}
+
+	if info, err := os.Stat(screenshot); err != nil || info.Size() == 0 {
+		t.Errorf("screenshot not saved: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !browser
+
+package validation
+
+import (
+	"context"
+	"fmt"
+)
+
+// load fails, as this build does not include the headless Chrome driver
+func (v *BrowserValidator) load(ctx context.Context) (browserPage, error) {
+	return browserPage{}, fmt.Errorf("browser validations need ralph built with -tags browser (make build-browser)")
+}
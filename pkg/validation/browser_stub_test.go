@@ -0,0 +1,20 @@
+//go:build !browser
+
+package validation
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBrowserValidatorWithoutBrowserBuild(t *testing.T) {
+	v, err := NewBrowserValidator(ValidationDefinition{Type: ValidationTypeBrowser, URL: "http://localhost:3000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := v.Validate(context.Background())
+	if result.Success || !strings.Contains(result.Error, "-tags browser") {
+		t.Errorf("Validate() = %+v, want a failure explaining the build tag", result)
+	}
+}
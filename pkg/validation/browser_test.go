@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewBrowserValidator(t *testing.T) {
+	v, err := CreateValidator(ValidationDefinition{
+		Type:    ValidationTypeBrowser,
+		URL:     "http://localhost:3000/login",
+		Pattern: "Sign in",
+		Timeout: "90s",
+		Options: map[string]interface{}{
+			"title":      "^Login",
+			"selector":   "form#login",
+			"screenshot": ".ralph/screenshots/login.png",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateValidator() error = %v", err)
+	}
+	bv := v.(*BrowserValidator)
+	if bv.Title.String() != "^Login" || bv.Pattern.String() != "Sign in" || bv.Selector != "form#login" ||
+		bv.Screenshot != ".ralph/screenshots/login.png" || bv.Config.Timeout != 90*time.Second {
+		t.Errorf("NewBrowserValidator() = %+v", bv)
+	}
+	if bv.Type() != ValidationTypeBrowser || bv.Description() != "browser: http://localhost:3000/login" {
+		t.Errorf("Type() = %v, Description() = %q", bv.Type(), bv.Description())
+	}
+
+	for _, def := range []ValidationDefinition{
+		{Type: ValidationTypeBrowser},
+		{Type: ValidationTypeBrowser, URL: "http://localhost", Pattern: "("},
+		{Type: ValidationTypeBrowser, URL: "http://localhost", Options: map[string]interface{}{"title": "["}},
+	} {
+		if _, err := CreateValidator(def); err == nil {
+			t.Errorf("CreateValidator(%+v) succeeded", def)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonOperators are the keys of an expected_json value that make it an
+// assertion instead of a literal to compare with
+var jsonOperators = map[string]bool{"equals": true, "type": true, "exists": true, "matches": true}
+
+// splitJSONPath splits a path such as $.items[0].name, items.0.name or
+// $['odd key'] into its keys and array indexes
+func splitJSONPath(path string) ([]string, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+	var segments []string
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: unclosed [", path)
+			}
+			segment := strings.TrimSpace(p[1:end])
+			if len(segment) >= 2 && (segment[0] == '\'' || segment[0] == '"') && segment[len(segment)-1] == segment[0] {
+				segment = segment[1 : len(segment)-1]
+			} else if _, err := strconv.Atoi(segment); err != nil {
+				return nil, fmt.Errorf("invalid JSON path %q: [%s] must be an index or a quoted key", path, segment)
+			}
+			segments = append(segments, segment)
+			p = p[end+1:]
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			segments = append(segments, p[:end])
+			p = p[end:]
+		}
+	}
+	return segments, nil
+}
+
+// LookupJSON returns the value at a path in a decoded JSON document, and
+// whether the path exists
+func LookupJSON(doc interface{}, path string) (interface{}, bool, error) {
+	segments, err := splitJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	value := doc
+	for _, segment := range segments {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[segment]
+			if !ok {
+				return nil, false, nil
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false, nil
+			}
+			value = node[i]
+		default:
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// normalizeJSON converts a value to what decoding its JSON gives (e.g., int
+// to float64), so expected values compare equal to decoded ones
+func normalizeJSON(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// formatJSON renders a value for messages
+func formatJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(data) > 100 {
+		return string(data[:100]) + "..."
+	}
+	return string(data)
+}
+
+// CheckJSON checks a JSON body against expected_json assertions. Each path
+// maps to a literal the value must equal, or to an object of assertions:
+// {"equals": v}, {"type": "string"}, {"exists": false} or {"matches": "regex"}.
+func CheckJSON(body []byte, expected map[string]interface{}) error {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("response body is not JSON: %w", err)
+	}
+
+	// Check paths in order, so the reported failure is stable
+	paths := make([]string, 0, len(expected))
+	for path := range expected {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		value, found, err := LookupJSON(doc, path)
+		if err != nil {
+			return err
+		}
+		assertions, ok := expected[path].(map[string]interface{})
+		if !ok || !isJSONAssertion(assertions) {
+			assertions = map[string]interface{}{"equals": expected[path]}
+		}
+		if err := checkJSONValue(path, value, found, assertions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isJSONAssertion reports whether an expected object holds assertions rather
+// than a literal object
+func isJSONAssertion(expected map[string]interface{}) bool {
+	if len(expected) == 0 {
+		return false
+	}
+	for key := range expected {
+		if !jsonOperators[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkJSONValue checks the value at a path against its assertions
+func checkJSONValue(path string, value interface{}, found bool, assertions map[string]interface{}) error {
+	if exists, ok := assertions["exists"].(bool); ok {
+		if exists != found {
+			if exists {
+				return fmt.Errorf("%s: not found", path)
+			}
+			return fmt.Errorf("%s: expected to be absent, got %s", path, formatJSON(value))
+		}
+		if !found {
+			return nil
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: not found", path)
+	}
+
+	if want, ok := assertions["type"]; ok {
+		if got := jsonType(value); got != fmt.Sprint(want) {
+			return fmt.Errorf("%s: expected a %v, got %s %s", path, want, got, formatJSON(value))
+		}
+	}
+	if pattern, ok := assertions["matches"]; ok {
+		re, err := regexp.Compile(fmt.Sprint(pattern))
+		if err != nil {
+			return fmt.Errorf("%s: invalid matches pattern: %w", path, err)
+		}
+		text, isString := value.(string)
+		if !isString {
+			text = formatJSON(value)
+		}
+		if !re.MatchString(text) {
+			return fmt.Errorf("%s: %s does not match %q", path, formatJSON(value), pattern)
+		}
+	}
+	if want, ok := assertions["equals"]; ok {
+		if !reflect.DeepEqual(value, normalizeJSON(want)) {
+			return fmt.Errorf("%s: expected %s, got %s", path, formatJSON(want), formatJSON(value))
+		}
+	}
+	return nil
+}
+
+// CheckHeaders checks response headers against expected_headers, mapping
+// header names to regexes their values must match
+func CheckHeaders(header http.Header, expected map[string]string) error {
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		re, err := regexp.Compile(expected[name])
+		if err != nil {
+			return fmt.Errorf("invalid pattern for header %s: %w", name, err)
+		}
+		values := header.Values(name)
+		if len(values) == 0 {
+			return fmt.Errorf("response has no %s header", name)
+		}
+		value := strings.Join(values, ", ")
+		if !re.MatchString(value) {
+			return fmt.Errorf("header %s: %q does not match %q", name, value, expected[name])
+		}
+	}
+	return nil
+}
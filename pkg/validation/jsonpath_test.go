@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const userJSON = `{
+	"user": {"id": 7, "name": "Ada", "email": "ada@example.com", "admin": false, "manager": null},
+	"roles": ["editor", "viewer"],
+	"items": [{"sku": "A-1", "qty": 2}, {"sku": "B-2", "qty": 1}],
+	"odd key": {"x.y": "dotted"}
+}`
+
+func TestLookupJSON(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(userJSON), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		want  interface{}
+		found bool
+	}{
+		{"$.user.name", "Ada", true},
+		{"user.name", "Ada", true},
+		{"$.items[1].sku", "B-2", true},
+		{"items.0.qty", float64(2), true},
+		{"$['odd key']['x.y']", "dotted", true},
+		{`$.roles["1"]`, "viewer", true},
+		{"$.user.manager", nil, true},
+		{"$.user.phone", nil, false},
+		{"$.items[5].sku", nil, false},
+		{"$.user.name.first", nil, false},
+	}
+	for _, tt := range tests {
+		got, found, err := LookupJSON(doc, tt.path)
+		if err != nil || found != tt.found || got != tt.want {
+			t.Errorf("LookupJSON(%q) = %v, %v, %v; want %v, %v", tt.path, got, found, err, tt.want, tt.found)
+		}
+	}
+
+	for _, path := range []string{"$.items[0", "$.items[first]"} {
+		if _, _, err := LookupJSON(doc, path); err == nil {
+			t.Errorf("LookupJSON(%q) succeeded", path)
+		}
+	}
+}
+
+func TestCheckJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected map[string]interface{}
+		wantErr  string
+	}{
+		{"literals", map[string]interface{}{"$.user.id": 7, "$.user.name": "Ada", "$.user.admin": false, "$.roles": []string{"editor", "viewer"}}, ""},
+		{"literal object", map[string]interface{}{"$.items[0]": map[string]interface{}{"sku": "A-1", "qty": 2}}, ""},
+		{"null", map[string]interface{}{"$.user.manager": nil}, ""},
+		{"assertions", map[string]interface{}{
+			"$.user.id":    map[string]interface{}{"type": "number"},
+			"$.user.email": map[string]interface{}{"matches": "@example\\.com$"},
+			"$.user.phone": map[string]interface{}{"exists": false},
+			"$.roles":      map[string]interface{}{"type": "array", "equals": []interface{}{"editor", "viewer"}},
+		}, ""},
+		{"wrong value", map[string]interface{}{"$.user.name": "Bob"}, `$.user.name: expected "Bob", got "Ada"`},
+		{"wrong type", map[string]interface{}{"$.user.id": map[string]interface{}{"type": "string"}}, "$.user.id: expected a string, got number 7"},
+		{"missing", map[string]interface{}{"$.user.phone": "555"}, "$.user.phone: not found"},
+		{"unexpected", map[string]interface{}{"$.user.email": map[string]interface{}{"exists": false}}, "expected to be absent"},
+		{"no match", map[string]interface{}{"$.user.email": map[string]interface{}{"matches": "^admin@"}}, "does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckJSON([]byte(userJSON), tt.expected)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("CheckJSON() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("CheckJSON() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := CheckJSON([]byte("<html>"), map[string]interface{}{"$.ok": true}); err == nil || !strings.Contains(err.Error(), "not JSON") {
+		t.Errorf("CheckJSON() of HTML = %v", err)
+	}
+}
+
+func TestCheckHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Add("Cache-Control", "no-store")
+
+	if err := CheckHeaders(header, map[string]string{"content-type": "^application/json", "Cache-Control": "no-store"}); err != nil {
+		t.Errorf("CheckHeaders() = %v", err)
+	}
+	if err := CheckHeaders(header, map[string]string{"Content-Type": "text/html"}); err == nil {
+		t.Error("CheckHeaders() with a mismatching header succeeded")
+	}
+	if err := CheckHeaders(header, map[string]string{"X-Request-Id": "."}); err == nil || !strings.Contains(err.Error(), "no X-Request-Id header") {
+		t.Errorf("CheckHeaders() with a missing header = %v", err)
+	}
+}
+
+func TestEndpointValidator_JSONAndHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/users/7")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(userJSON))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		json        map[string]interface{}
+		headers     map[string]string
+		wantSuccess bool
+	}{
+		{"matching", map[string]interface{}{"$.user.id": 7}, map[string]string{"Location": `^/users/\d+$`}, true},
+		{"json mismatch", map[string]interface{}{"$.user.id": 8}, nil, false},
+		{"header mismatch", nil, map[string]string{"Location": "^/accounts/"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewEndpointValidator(ValidationDefinition{
+				Type:            ValidationTypeHTTPPost,
+				URL:             server.URL,
+				ExpectedStatus:  http.StatusCreated,
+				ExpectedJSON:    tt.json,
+				ExpectedHeaders: tt.headers,
+			})
+			v.Config.MaxRetries = 0
+			result := v.Validate(context.Background())
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Validate() success = %v, want %v (%s)", result.Success, tt.wantSuccess, result.Message)
+			}
+		})
+	}
+}
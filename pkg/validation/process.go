@@ -0,0 +1,221 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/logimos/ralph/internal/proc"
+)
+
+// DefaultReadyTimeout is how long a managed process may take to become ready
+const DefaultReadyTimeout = 30 * time.Second
+
+// maxProcessOutput bounds the output kept from a managed process
+const maxProcessOutput = 64 * 1024
+
+// ManagedProcess describes a process (e.g., the app's server) that must be
+// running for a validation. The runner starts it before the first validation
+// that needs it, waits until it is ready, keeps it up for later validations
+// and kills its process tree when the run ends.
+type ManagedProcess struct {
+	Command         string            `json:"command"`                    // Command that starts the process
+	Args            []string          `json:"args,omitempty"`             // Command arguments
+	Env             map[string]string `json:"env,omitempty"`              // Extra environment variables
+	ReadyPort       int               `json:"ready_port,omitempty"`       // Ready once this localhost TCP port accepts connections
+	ReadyPattern    string            `json:"ready_pattern,omitempty"`    // Ready once the output matches this regex
+	ReadyTimeout    string            `json:"ready_timeout,omitempty"`    // How long to wait for readiness (default: 30s)
+	TeardownCommand string            `json:"teardown_command,omitempty"` // Command run after the process is stopped (e.g., "docker compose down")
+}
+
+// key identifies processes that are the same, so validations declaring the
+// same process share one instance
+func (p *ManagedProcess) key() string {
+	return strings.Join(append([]string{p.Command}, p.Args...), "\x00")
+}
+
+// String returns the command line of the process
+func (p *ManagedProcess) String() string {
+	return strings.TrimSpace(p.Command + " " + strings.Join(p.Args, " "))
+}
+
+// processOutput collects the most recent output of a managed process
+type processOutput struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write appends output, dropping the oldest beyond maxProcessOutput
+func (o *processOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, p...)
+	if len(o.buf) > maxProcessOutput {
+		o.buf = o.buf[len(o.buf)-maxProcessOutput:]
+	}
+	return len(p), nil
+}
+
+// String returns the collected output
+func (o *processOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return string(bytes.Clone(o.buf))
+}
+
+// processInstance is a running managed process
+type processInstance struct {
+	spec    ManagedProcess
+	once    sync.Once
+	cmd     *exec.Cmd
+	stop    context.CancelFunc // Kills the process tree
+	output  processOutput
+	exited  chan struct{}
+	started error // Why the process could not be started or did not become ready
+}
+
+// Start starts the process and waits until it is ready. Only the first call
+// starts it; later calls return the same result.
+func (p *processInstance) Start(ctx context.Context) error {
+	p.once.Do(func() {
+		p.started = p.start(ctx)
+	})
+	return p.started
+}
+
+func (p *processInstance) start(ctx context.Context) error {
+	timeout := DefaultReadyTimeout
+	if p.spec.ReadyTimeout != "" {
+		d, err := time.ParseDuration(p.spec.ReadyTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid ready_timeout %q: %w", p.spec.ReadyTimeout, err)
+		}
+		timeout = d
+	}
+	var pattern *regexp.Regexp
+	if p.spec.ReadyPattern != "" {
+		re, err := regexp.Compile(p.spec.ReadyPattern)
+		if err != nil {
+			return fmt.Errorf("invalid ready_pattern: %w", err)
+		}
+		pattern = re
+	}
+
+	// The process outlives the validation that starts it, so it is not tied
+	// to its context; the runner stops it when the run ends
+	procCtx, stop := context.WithCancel(context.Background())
+	p.cmd = exec.CommandContext(procCtx, p.spec.Command, p.spec.Args...)
+	proc.SetGroup(p.cmd)
+	p.cmd.Stdout = &p.output
+	p.cmd.Stderr = &p.output
+	p.cmd.Env = os.Environ()
+	for k, v := range p.spec.Env {
+		p.cmd.Env = append(p.cmd.Env, k+"="+v)
+	}
+	if err := proc.Start(p.cmd); err != nil {
+		stop()
+		p.cmd = nil
+		return fmt.Errorf("failed to start %q: %w", p.spec.String(), err)
+	}
+	p.stop = stop
+	p.exited = make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(p.exited)
+	}()
+
+	if p.spec.ReadyPort == 0 && pattern == nil {
+		return nil
+	}
+	address := net.JoinHostPort("localhost", strconv.Itoa(p.spec.ReadyPort))
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		ready := true
+		if p.spec.ReadyPort != 0 {
+			conn, err := net.DialTimeout("tcp", address, 200*time.Millisecond)
+			if err != nil {
+				ready = false
+			} else {
+				conn.Close()
+			}
+		}
+		if pattern != nil && !pattern.MatchString(p.output.String()) {
+			ready = false
+		}
+		if ready {
+			return nil
+		}
+
+		select {
+		case <-p.exited:
+			return fmt.Errorf("%q exited before it was ready: %s", p.spec.String(), p.cmd.ProcessState)
+		case <-deadline.C:
+			return fmt.Errorf("%q was not ready after %s", p.spec.String(), timeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop kills the process tree and runs the teardown command, if any. It
+// does nothing if the process was never started or is already stopped.
+func (p *processInstance) Stop() {
+	if p.cmd == nil {
+		return
+	}
+	p.stop()
+	<-p.exited
+	// Children that left the group leader behind are killed too
+	proc.KillGroup(p.cmd.Process.Pid)
+	p.cmd = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	if cmd := proc.CommandLine(ctx, p.spec.TeardownCommand); cmd != nil {
+		cmd.Run()
+	}
+}
+
+// managedValidator runs a validator once its managed process is ready
+type managedValidator struct {
+	Validator
+	process *processInstance
+}
+
+// Validate starts the process if needed, then runs the validation
+func (v *managedValidator) Validate(ctx context.Context) ValidationResult {
+	if err := v.process.Start(ctx); err != nil {
+		return ValidationResult{
+			Success: false,
+			Message: fmt.Sprintf("%s: managed process did not start", v.Description()),
+			Error:   err.Error(),
+			Output:  v.process.output.String(),
+		}
+	}
+	result := v.Validator.Validate(ctx)
+	if !result.Success && result.Output == "" {
+		// The server's log usually explains why it misbehaved
+		result.Output = v.process.output.String()
+	}
+	return result
+}
+
+// Cleanup stops the managed process and cleans up after the validator
+func (v *managedValidator) Cleanup() {
+	if c, ok := v.Validator.(cleaner); ok {
+		c.Cleanup()
+	}
+	v.process.Stop()
+}
@@ -0,0 +1,156 @@
+package validation
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManagedProcess_StartedOnceAndStopped(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups not supported")
+	}
+
+	dir := t.TempDir()
+	heartbeat := filepath.Join(dir, "heartbeat")
+	starts := filepath.Join(dir, "starts")
+	teardown := filepath.Join(dir, "teardown")
+
+	// The "server" becomes ready after a delay and keeps a child writing to
+	// a file; both validations share it
+	server := &ManagedProcess{
+		Command:         "sh",
+		Args:            []string{"-c", "echo started >> " + starts + "; sleep 0.2; echo listening on $APP_PORT; (while true; do echo x >> " + heartbeat + "; sleep 0.1; done) & wait"},
+		Env:             map[string]string{"APP_PORT": "8080"},
+		ReadyPattern:    "listening on 8080",
+		TeardownCommand: "touch " + teardown,
+	}
+	runner := NewValidationRunner()
+	err := runner.AddFromDefinitions([]ValidationDefinition{
+		{Type: ValidationTypeCLI, Command: "sh", Args: []string{"-c", "sleep 0.2; test -s " + heartbeat}, ManagedProcess: server},
+		{Type: ValidationTypeCLI, Command: "test", Args: []string{"-s", heartbeat}, ManagedProcess: server},
+	})
+	if err != nil {
+		t.Fatalf("AddFromDefinitions() error = %v", err)
+	}
+
+	result := runner.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected validations to pass against the managed process: %s", result.Summary())
+	}
+	if data, _ := os.ReadFile(starts); strings.Count(string(data), "started") != 1 {
+		t.Errorf("managed process started %d times, want once", strings.Count(string(data), "started"))
+	}
+	if _, err := os.Stat(teardown); err != nil {
+		t.Errorf("teardown command did not run: %v", err)
+	}
+
+	// Neither the process nor its children may outlive the run
+	before, _ := os.Stat(heartbeat)
+	time.Sleep(500 * time.Millisecond)
+	after, _ := os.Stat(heartbeat)
+	if after.Size() != before.Size() {
+		t.Error("managed process still running after the validation run")
+	}
+}
+
+func TestManagedProcess_ReadyPort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix commands")
+	}
+
+	// Reserve a free port, then open it only after a delay
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	opened := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		l, _ := net.Listen("tcp", l.Addr().String())
+		opened <- l
+	}()
+	t.Cleanup(func() {
+		if l := <-opened; l != nil {
+			l.Close()
+		}
+	})
+
+	runner := NewValidationRunner()
+	err = runner.AddFromDefinitions([]ValidationDefinition{{
+		Type:           ValidationTypeCLI,
+		Command:        "true",
+		ManagedProcess: &ManagedProcess{Command: "sleep", Args: []string{"30"}, ReadyPort: port, ReadyTimeout: "5s"},
+	}})
+	if err != nil {
+		t.Fatalf("AddFromDefinitions() error = %v", err)
+	}
+
+	start := time.Now()
+	result := runner.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected validation to pass once the port is open: %s", result.Summary())
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("validation ran before the port was open (after %s)", elapsed)
+	}
+}
+
+func TestManagedProcess_NotReady(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix commands")
+	}
+
+	tests := []struct {
+		name       string
+		process    *ManagedProcess
+		wantError  string
+		wantOutput string
+	}{
+		{
+			name:       "exits before ready",
+			process:    &ManagedProcess{Command: "sh", Args: []string{"-c", "echo address already in use; exit 1"}, ReadyPattern: "listening"},
+			wantError:  "exited before it was ready",
+			wantOutput: "address already in use",
+		},
+		{
+			name:      "ready timeout",
+			process:   &ManagedProcess{Command: "sleep", Args: []string{"30"}, ReadyPattern: "listening", ReadyTimeout: "300ms"},
+			wantError: "was not ready after 300ms",
+		},
+		{
+			name:      "missing command",
+			process:   &ManagedProcess{Command: "ralph-no-such-server"},
+			wantError: "failed to start",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewValidationRunner()
+			err := runner.AddFromDefinitions([]ValidationDefinition{{Type: ValidationTypeCLI, Command: "true", ManagedProcess: tt.process}})
+			if err != nil {
+				t.Fatalf("AddFromDefinitions() error = %v", err)
+			}
+			result := runner.Run(context.Background())
+			if result.Success || len(result.Results) != 1 {
+				t.Fatalf("expected the validation to fail: %s", result.Summary())
+			}
+			r := result.Results[0]
+			if !strings.Contains(r.Error, tt.wantError) || !strings.Contains(r.Output, tt.wantOutput) {
+				t.Errorf("result error = %q, output = %q; want %q, %q", r.Error, r.Output, tt.wantError, tt.wantOutput)
+			}
+		})
+	}
+
+	runner := NewValidationRunner()
+	if err := runner.AddFromDefinitions([]ValidationDefinition{{Type: ValidationTypeCLI, Command: "true", ManagedProcess: &ManagedProcess{}}}); err == nil {
+		t.Error("AddFromDefinitions() without a managed process command succeeded")
+	}
+}
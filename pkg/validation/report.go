@@ -0,0 +1,170 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/logimos/ralph/internal/testreport"
+)
+
+const (
+	// ReportJSONFile is the name of the machine-readable validation report
+	ReportJSONFile = "validation-report.json"
+	// ReportHTMLFile is the name of the HTML validation report
+	ReportHTMLFile = "validation-report.html"
+)
+
+// Report is the outcome of validating a set of features, kept as evidence
+// (e.g., a CI artifact)
+type Report struct {
+	GeneratedAt time.Time             `json:"generated_at"`
+	Success     bool                  `json:"success"`
+	Interrupted bool                  `json:"interrupted,omitempty"` // The run was cancelled before all validations ran
+	TotalCount  int                   `json:"total_count"`
+	PassedCount int                   `json:"passed_count"`
+	FailedCount int                   `json:"failed_count"`
+	Duration    time.Duration         `json:"duration"`
+	Features    []ValidationRunResult `json:"features"`
+}
+
+// NewReport creates a report from the results of each feature's validations
+func NewReport(features []ValidationRunResult, duration time.Duration) *Report {
+	r := &Report{
+		GeneratedAt: time.Now(),
+		Duration:    duration,
+		Features:    features,
+	}
+	for _, f := range features {
+		r.TotalCount += f.TotalCount
+		r.PassedCount += f.PassedCount
+		r.FailedCount += f.FailedCount
+	}
+	r.Success = r.FailedCount == 0
+	return r
+}
+
+// Write writes the JSON report, and the HTML report if html is set, to dir
+// and returns the paths written
+func (r *Report) Write(dir string, html bool) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode validation report: %w", err)
+	}
+	jsonPath := filepath.Join(dir, ReportJSONFile)
+	if err := os.WriteFile(jsonPath, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write validation report: %w", err)
+	}
+	paths := []string{jsonPath}
+
+	if html {
+		var buf bytes.Buffer
+		if err := reportTemplate.Execute(&buf, r); err != nil {
+			return paths, fmt.Errorf("failed to render HTML report: %w", err)
+		}
+		htmlPath := filepath.Join(dir, ReportHTMLFile)
+		if err := os.WriteFile(htmlPath, buf.Bytes(), 0644); err != nil {
+			return paths, fmt.Errorf("failed to write HTML report: %w", err)
+		}
+		paths = append(paths, htmlPath)
+	}
+	return paths, nil
+}
+
+// WriteJUnit writes the report as JUnit XML to path, with a suite per feature
+// and a test case per validation. name names the test run.
+func (r *Report) WriteJUnit(path, name string) error {
+	return r.junit().WriteJUnitXML(path, name)
+}
+
+// junit converts the report to test results with a suite per feature and a
+// test case per validation
+func (r *Report) junit() *testreport.Report {
+	junit := &testreport.Report{Format: "junit"}
+	for _, f := range r.Features {
+		suite := fmt.Sprintf("Feature #%d", f.FeatureID)
+		if f.FeatureName != "" {
+			suite += ": " + f.FeatureName
+		}
+		for _, result := range f.Results {
+			tc := testreport.TestCase{
+				Suite:    suite,
+				Name:     result.Description,
+				Status:   testreport.StatusPassed,
+				Duration: result.Duration,
+			}
+			if tc.Name == "" {
+				tc.Name = result.Message
+			}
+			if !result.Success {
+				tc.Status = testreport.StatusFailed
+				var details []string
+				for _, s := range []string{result.Message, result.Error, result.Report, result.Output} {
+					if s = strings.TrimSpace(s); s != "" {
+						details = append(details, s)
+					}
+				}
+				tc.Message = strings.Join(details, "\n\n")
+			}
+			junit.Tests = append(junit.Tests, tc)
+		}
+	}
+	return junit
+}
+
+// reportTemplate renders a self-contained HTML report, with no external
+// stylesheets or scripts so it can be archived as a single file
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(d time.Duration) string {
+		if d < time.Second {
+			return d.Round(time.Millisecond).String()
+		}
+		return d.Round(100 * time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Validation report - {{if .Success}}passed{{else}}failed{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.5em; }
+.summary { margin-bottom: 1.5em; }
+.pass { color: #1a7f37; }
+.fail { color: #cf222e; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.4em 0.6em; border-bottom: 1px solid #d0d7de; vertical-align: top; }
+th { background: #f6f8fa; }
+pre { background: #f6f8fa; padding: 0.6em; max-height: 20em; overflow: auto; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Validation report: <span class="{{if .Success}}pass">PASSED{{else}}fail">FAILED{{end}}</span></h1>
+<p class="summary">{{.PassedCount}}/{{.TotalCount}} validations passed across {{len .Features}} feature(s) in {{duration .Duration}}{{if .Interrupted}} (interrupted){{end}}.<br>
+Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}.</p>
+{{range .Features}}
+<h2 class="{{if .Success}}pass{{else}}fail{{end}}">Feature #{{.FeatureID}}: {{.FeatureName}}</h2>
+<table>
+<tr><th>Result</th><th>Validation</th><th>Duration</th><th>Details</th></tr>
+{{range .Results}}
+<tr>
+<td class="{{if .Success}}pass">&#10003; pass{{else}}fail">&#10007; fail{{end}}</td>
+<td>{{.Message}}</td>
+<td>{{duration .Duration}}</td>
+<td>{{if .Error}}<div class="fail">{{.Error}}</div>{{end}}{{if .Report}}<pre>{{.Report}}</pre>{{end}}{{if .Output}}<details><summary>Output</summary><pre>{{.Output}}</pre></details>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,95 @@
+package validation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logimos/ralph/internal/testreport"
+)
+
+func TestReportWrite(t *testing.T) {
+	features := []ValidationRunResult{
+		{
+			FeatureID: 1, FeatureName: "Health endpoint", Success: true, TotalCount: 1, PassedCount: 1,
+			Results: []ValidationResult{{Success: true, Message: "GET http://localhost:8080/health returned 200", Duration: 12 * time.Millisecond}},
+		},
+		{
+			FeatureID: 2, FeatureName: "CLI <version>", Success: false, TotalCount: 2, PassedCount: 1, FailedCount: 1,
+			Results: []ValidationResult{
+				{Success: true, Message: "command passed", Duration: 2 * time.Second},
+				{Success: false, Message: "command failed", Error: "exit status 1", Output: "panic: <nil> map", Report: "Security scan failed."},
+			},
+		},
+	}
+	report := NewReport(features, 3*time.Second)
+	if report.Success || report.TotalCount != 3 || report.PassedCount != 2 || report.FailedCount != 1 {
+		t.Errorf("NewReport() = %+v", report)
+	}
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	paths, err := report.Write(dir, false)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("Write() = %v, %v", paths, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ReportJSONFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if len(decoded.Features) != 2 || decoded.Features[1].Results[1].Output != "panic: <nil> map" {
+		t.Errorf("decoded report = %+v", decoded)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ReportHTMLFile)); !os.IsNotExist(err) {
+		t.Error("HTML report written without being requested")
+	}
+
+	paths, err = report.Write(dir, true)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("Write() with HTML = %v, %v", paths, err)
+	}
+	html, err := os.ReadFile(filepath.Join(dir, ReportHTMLFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"FAILED", "2/3 validations passed", "Feature #2: CLI &lt;version&gt;", "exit status 1", "panic: &lt;nil&gt; map", "Security scan failed.", "12ms", "2s"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+	if strings.Contains(string(html), "<link") || strings.Contains(string(html), "<script") {
+		t.Error("HTML report is not self-contained")
+	}
+}
+
+func TestReportJUnit(t *testing.T) {
+	report := NewReport([]ValidationRunResult{{
+		FeatureID: 3, FeatureName: "Signup",
+		Results: []ValidationResult{
+			{Success: true, Message: "returned 201", Description: "POST /signup", Duration: time.Second},
+			{Success: false, Message: "query returned 0 row(s), expected 1", Error: "row count mismatch", Description: "user row exists"},
+			{Success: false, Message: "command failed: not run", Error: "context canceled"},
+		},
+	}}, time.Second)
+
+	junit := report.junit()
+	if len(junit.Tests) != 3 {
+		t.Fatalf("junit() tests = %+v", junit.Tests)
+	}
+	if tc := junit.Tests[0]; tc.Suite != "Feature #3: Signup" || tc.Name != "POST /signup" || tc.Status != testreport.StatusPassed || tc.Duration != time.Second {
+		t.Errorf("passed test = %+v", tc)
+	}
+	if tc := junit.Tests[1]; tc.Status != testreport.StatusFailed || tc.Message != "query returned 0 row(s), expected 1\n\nrow count mismatch" {
+		t.Errorf("failed test = %+v", tc)
+	}
+	// Without a description, the message names the test
+	if tc := junit.Tests[2]; tc.Name != "command failed: not run" {
+		t.Errorf("test without description = %+v", tc)
+	}
+}
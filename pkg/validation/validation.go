@@ -1,11 +1,18 @@
 // Package validation provides outcome-focused validation beyond tests and type checks.
 // It supports validating API endpoints, CLI commands, file existence, and output patterns.
+//
+// Convert the validations of a plan feature with FromPlan, add them to a
+// ValidationRunner with AddFromDefinitions and Run it; processes started by
+// the validators are stopped when the run ends. This package is part of
+// Ralph's public API. Validators run commands and connect to the URLs they
+// are given as is: policy checks are the caller's responsibility.
 package validation
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +21,11 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/logimos/ralph/internal/coverage"
+	"github.com/logimos/ralph/internal/proc"
+	"github.com/logimos/ralph/internal/security"
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 // ValidationType represents the type of validation to perform
@@ -30,6 +42,14 @@ const (
 	ValidationTypeFileExists ValidationType = "file_exists"
 	// ValidationTypeOutputContains validates that output contains a pattern
 	ValidationTypeOutputContains ValidationType = "output_contains"
+	// ValidationTypeCoverage validates that test coverage meets a minimum
+	ValidationTypeCoverage ValidationType = "coverage"
+	// ValidationTypeSecurityScan validates that a security scanner reports no serious findings
+	ValidationTypeSecurityScan ValidationType = "security_scan"
+	// ValidationTypeDBQuery validates the result of a database query
+	ValidationTypeDBQuery ValidationType = "db_query"
+	// ValidationTypeBrowser validates a page loaded in headless Chrome
+	ValidationTypeBrowser ValidationType = "browser"
 )
 
 // DefaultTimeout is the default timeout for validation operations
@@ -38,24 +58,61 @@ const DefaultTimeout = 30 * time.Second
 // DefaultMaxRetries is the default number of retries for validation
 const DefaultMaxRetries = 3
 
+// outputWaitDelay bounds how long a finished command's output is awaited when
+// background processes it started still hold the output pipes open
+const outputWaitDelay = 2 * time.Second
+
 // ValidationDefinition represents a validation rule defined in plan.json
 type ValidationDefinition struct {
-	Type           ValidationType         `json:"type"`
-	URL            string                 `json:"url,omitempty"`             // For HTTP validations
-	Method         string                 `json:"method,omitempty"`          // HTTP method (defaults based on type)
-	Body           string                 `json:"body,omitempty"`            // Request body for POST
-	Headers        map[string]string      `json:"headers,omitempty"`         // HTTP headers
-	ExpectedStatus int                    `json:"expected_status,omitempty"` // Expected HTTP status code
-	ExpectedBody   string                 `json:"expected_body,omitempty"`   // Expected response body pattern (regex)
-	Command        string                 `json:"command,omitempty"`         // For CLI validations
-	Args           []string               `json:"args,omitempty"`            // Command arguments
-	Path           string                 `json:"path,omitempty"`            // For file_exists validation
-	Pattern        string                 `json:"pattern,omitempty"`         // For output_contains validation
-	Input          string                 `json:"input,omitempty"`           // Input to check for pattern
-	Timeout        string                 `json:"timeout,omitempty"`         // Timeout duration (e.g., "30s")
-	Retries        int                    `json:"retries,omitempty"`         // Number of retries
-	Description    string                 `json:"description,omitempty"`     // Human-readable description
-	Options        map[string]interface{} `json:"options,omitempty"`         // Additional options
+	Type            ValidationType         `json:"type"`
+	URL             string                 `json:"url,omitempty"`              // For HTTP validations
+	Method          string                 `json:"method,omitempty"`           // HTTP method (defaults based on type)
+	Body            string                 `json:"body,omitempty"`             // Request body for POST
+	Headers         map[string]string      `json:"headers,omitempty"`          // HTTP headers
+	ExpectedStatus  int                    `json:"expected_status,omitempty"`  // Expected HTTP status code
+	ExpectedBody    string                 `json:"expected_body,omitempty"`    // Expected response body pattern (regex)
+	ExpectedJSON    map[string]interface{} `json:"expected_json,omitempty"`    // JSON path -> expected value or assertions
+	ExpectedHeaders map[string]string      `json:"expected_headers,omitempty"` // Header name -> expected value pattern (regex)
+	Command         string                 `json:"command,omitempty"`          // For CLI validations
+	Args            []string               `json:"args,omitempty"`             // Command arguments
+	Path            string                 `json:"path,omitempty"`             // For file_exists validation
+	Pattern         string                 `json:"pattern,omitempty"`          // For output_contains validation
+	Input           string                 `json:"input,omitempty"`            // Input to check for pattern
+	DSN             string                 `json:"dsn,omitempty"`              // Database to connect to for db_query
+	Query           string                 `json:"query,omitempty"`            // Query to run for db_query
+	Timeout         string                 `json:"timeout,omitempty"`          // Timeout duration (e.g., "30s")
+	Retries         int                    `json:"retries,omitempty"`          // Number of retries
+	Description     string                 `json:"description,omitempty"`      // Human-readable description
+	Options         map[string]interface{} `json:"options,omitempty"`          // Additional options
+	ManagedProcess  *ManagedProcess        `json:"managed_process,omitempty"`  // Process to run while validating (e.g., the app's server)
+}
+
+// FromPlan converts a validation of a plan feature to a definition the
+// validators accept
+func FromPlan(def plan.ValidationDefinition) ValidationDefinition {
+	return ValidationDefinition{
+		Type:            ValidationType(def.Type),
+		URL:             def.URL,
+		Method:          def.Method,
+		Body:            def.Body,
+		Headers:         def.Headers,
+		ExpectedStatus:  def.ExpectedStatus,
+		ExpectedBody:    def.ExpectedBody,
+		ExpectedJSON:    def.ExpectedJSON,
+		ExpectedHeaders: def.ExpectedHeaders,
+		Command:         def.Command,
+		Args:            def.Args,
+		Path:            def.Path,
+		Pattern:         def.Pattern,
+		Input:           def.Input,
+		DSN:             def.DSN,
+		Query:           def.Query,
+		Timeout:         def.Timeout,
+		Retries:         def.Retries,
+		Description:     def.Description,
+		Options:         def.Options,
+		ManagedProcess:  (*ManagedProcess)(def.ManagedProcess),
+	}
 }
 
 // ValidationResult represents the result of a validation
@@ -68,6 +125,8 @@ type ValidationResult struct {
 	StatusCode  int           `json:"status_code,omitempty"` // For HTTP validations
 	Error       string        `json:"error,omitempty"`       // Error message if failed
 	ValidatorID string        `json:"validator_id,omitempty"`
+	Report      string        `json:"report,omitempty"`      // Summary of a failure to pass on to the agent
+	Description string        `json:"description,omitempty"` // Description of the validator that produced the result
 }
 
 // Validator is the interface for all validation types
@@ -96,14 +155,16 @@ func DefaultValidatorConfig() ValidatorConfig {
 
 // EndpointValidator validates HTTP endpoints
 type EndpointValidator struct {
-	URL            string
-	Method         string
-	Body           string
-	Headers        map[string]string
-	ExpectedStatus int
-	ExpectedBody   string // Regex pattern
-	Config         ValidatorConfig
-	Desc           string
+	URL             string
+	Method          string
+	Body            string
+	Headers         map[string]string
+	ExpectedStatus  int
+	ExpectedBody    string                 // Regex pattern
+	ExpectedJSON    map[string]interface{} // JSON path -> expected value or assertions
+	ExpectedHeaders map[string]string      // Header name -> regex
+	Config          ValidatorConfig
+	Desc            string
 }
 
 // NewEndpointValidator creates a new endpoint validator from a definition
@@ -135,12 +196,14 @@ func NewEndpointValidator(def ValidationDefinition) *EndpointValidator {
 	}
 
 	return &EndpointValidator{
-		URL:            def.URL,
-		Method:         strings.ToUpper(method),
-		Body:           def.Body,
-		Headers:        def.Headers,
-		ExpectedStatus: expectedStatus,
-		ExpectedBody:   def.ExpectedBody,
+		URL:             def.URL,
+		Method:          strings.ToUpper(method),
+		Body:            def.Body,
+		Headers:         def.Headers,
+		ExpectedStatus:  expectedStatus,
+		ExpectedBody:    def.ExpectedBody,
+		ExpectedJSON:    def.ExpectedJSON,
+		ExpectedHeaders: def.ExpectedHeaders,
 		Config: ValidatorConfig{
 			Timeout:    timeout,
 			MaxRetries: retries,
@@ -222,6 +285,20 @@ func (v *EndpointValidator) Validate(ctx context.Context) ValidationResult {
 			}
 		}
 
+		// Check headers and JSON fields if specified
+		if len(v.ExpectedHeaders) > 0 {
+			if err := CheckHeaders(resp.Header, v.ExpectedHeaders); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		if len(v.ExpectedJSON) > 0 {
+			if err := CheckJSON(respBody, v.ExpectedJSON); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+
 		// Success
 		result.Success = true
 		result.Message = fmt.Sprintf("%s %s returned %d", v.Method, v.URL, resp.StatusCode)
@@ -263,6 +340,10 @@ type CLIValidator struct {
 	ExpectedExitCode int
 	Config         ValidatorConfig
 	Desc           string
+
+	// Process groups of started commands; background processes they leave
+	// running (e.g., dev servers) are killed by Cleanup
+	groups []int
 }
 
 // NewCLIValidator creates a new CLI validator from a definition
@@ -306,18 +387,35 @@ func (v *CLIValidator) Validate(ctx context.Context) ValidationResult {
 
 	var lastErr error
 	for attempt := 0; attempt <= v.Config.MaxRetries; attempt++ {
+		if ctx.Err() != nil {
+			// Interrupted or past the run deadline; don't start another attempt
+			lastErr = fmt.Errorf("stopped: %w", ctx.Err())
+			break
+		}
 		result.Retries = attempt
 
 		// Create command with context for timeout
 		cmdCtx, cancel := context.WithTimeout(ctx, v.Config.Timeout)
 		cmd := exec.CommandContext(cmdCtx, v.Command, v.Args...)
+		// Run in its own process group so the command and anything it spawns
+		// can be killed together on timeout or cancellation
+		proc.SetGroup(cmd)
+		cmd.WaitDelay = outputWaitDelay
 
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
 
-		err := cmd.Run()
+		err := proc.Run(cmd)
 		cancel()
+		if cmd.Process != nil {
+			v.groups = append(v.groups, cmd.Process.Pid)
+		}
+		if errors.Is(err, exec.ErrWaitDelay) {
+			// The command itself succeeded; only its background processes
+			// were still holding the output open
+			err = nil
+		}
 
 		result.Output = stdout.String()
 		if stderr.Len() > 0 {
@@ -374,6 +472,15 @@ func (v *CLIValidator) Validate(ctx context.Context) ValidationResult {
 	return result
 }
 
+// Cleanup kills any processes still running from the commands this validator
+// started, such as servers launched in the background by a setup command
+func (v *CLIValidator) Cleanup() {
+	for _, pid := range v.groups {
+		proc.KillGroup(pid)
+	}
+	v.groups = nil
+}
+
 // Type returns the validation type
 func (v *CLIValidator) Type() ValidationType {
 	return ValidationTypeCLI
@@ -594,6 +701,193 @@ func (v *OutputValidator) Description() string {
 	return fmt.Sprintf("output contains: %s", v.Pattern)
 }
 
+// CoverageValidator validates that the total test coverage meets a minimum
+type CoverageValidator struct {
+	Command string
+	Min     float64 // Minimum total coverage percentage
+	Config  ValidatorConfig
+	Desc    string
+}
+
+// NewCoverageValidator creates a new coverage validator from a definition.
+// The minimum is taken from the "min" option, as a number or a string such
+// as "80%".
+func NewCoverageValidator(def ValidationDefinition) (*CoverageValidator, error) {
+	command := strings.TrimSpace(def.Command + " " + strings.Join(def.Args, " "))
+
+	var min float64
+	if value, ok := def.Options["min"]; ok {
+		gate, err := coverage.ParseGate(fmt.Sprint(value))
+		if err != nil {
+			return nil, err
+		}
+		min = gate
+	}
+
+	timeout := DefaultTimeout * 10 // Coverage runs the whole test suite
+	if def.Timeout != "" {
+		if d, err := time.ParseDuration(def.Timeout); err == nil {
+			timeout = d
+		}
+	}
+
+	return &CoverageValidator{
+		Command: command,
+		Min:     min,
+		Config:  ValidatorConfig{Timeout: timeout},
+		Desc:    def.Description,
+	}, nil
+}
+
+// Validate runs the coverage command and checks the total against the minimum
+func (v *CoverageValidator) Validate(ctx context.Context) ValidationResult {
+	start := time.Now()
+	result := ValidationResult{
+		ValidatorID: fmt.Sprintf("coverage_%s", sanitizeCommand(v.Command)),
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, v.Config.Timeout)
+	defer cancel()
+	total, output, err := coverage.Measure(cmdCtx, v.Command)
+	result.Output = output
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("failed to measure coverage with %q", v.Command)
+		result.Error = err.Error()
+		return result
+	}
+
+	if err := coverage.NewGate(v.Min).Check(total); err != nil {
+		result.Success = false
+		result.Message = fmt.Sprintf("coverage %.1f%% is below the minimum of %.1f%%", total, v.Min)
+		result.Error = "coverage too low"
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("coverage %.1f%% meets the minimum of %.1f%%", total, v.Min)
+	return result
+}
+
+// Type returns the validation type
+func (v *CoverageValidator) Type() ValidationType {
+	return ValidationTypeCoverage
+}
+
+// Description returns a human-readable description
+func (v *CoverageValidator) Description() string {
+	if v.Desc != "" {
+		return v.Desc
+	}
+	return fmt.Sprintf("coverage >= %.1f%%: %s", v.Min, v.Command)
+}
+
+// maxReportedFindings bounds the findings listed in a security scan report
+const maxReportedFindings = 20
+
+// SecurityScanValidator validates that a security scanner reports no
+// findings at or above a minimum severity
+type SecurityScanValidator struct {
+	Command     string
+	MinSeverity string // Lowest severity that fails: info, low, medium, high or critical
+	Config      ValidatorConfig
+	Desc        string
+}
+
+// NewSecurityScanValidator creates a new security scan validator from a
+// definition. The command is given directly or picked with the "tool" option
+// (gosec, npm, trivy); the "min_severity" option sets the lowest severity
+// that fails the validation (default: high).
+func NewSecurityScanValidator(def ValidationDefinition) (*SecurityScanValidator, error) {
+	command := strings.TrimSpace(def.Command + " " + strings.Join(def.Args, " "))
+	if command == "" {
+		tool, _ := def.Options["tool"].(string)
+		command = security.DefaultCommands[tool]
+		if command == "" {
+			return nil, fmt.Errorf("unknown security scan tool %q: must be one of gosec, npm, trivy", tool)
+		}
+	}
+
+	minSeverity := security.DefaultMinSeverity
+	if s, ok := def.Options["min_severity"].(string); ok {
+		sev, err := security.ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		minSeverity = sev
+	}
+
+	timeout := DefaultTimeout * 10 // Scanners may download vulnerability databases
+	if def.Timeout != "" {
+		if d, err := time.ParseDuration(def.Timeout); err == nil {
+			timeout = d
+		}
+	}
+
+	return &SecurityScanValidator{
+		Command:     command,
+		MinSeverity: minSeverity.String(),
+		Config:      ValidatorConfig{Timeout: timeout},
+		Desc:        def.Description,
+	}, nil
+}
+
+// Validate runs the scanner and fails if it reports findings at or above the
+// minimum severity, with a summary of them as the report
+func (v *SecurityScanValidator) Validate(ctx context.Context) ValidationResult {
+	start := time.Now()
+	result := ValidationResult{
+		ValidatorID: fmt.Sprintf("security_%s", sanitizeCommand(v.Command)),
+	}
+
+	minSeverity, err := security.ParseSeverity(v.MinSeverity)
+	if err != nil {
+		result.Success = false
+		result.Message = "invalid minimum severity"
+		result.Error = err.Error()
+		return result
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, v.Config.Timeout)
+	defer cancel()
+	findings, output, err := security.Scan(cmdCtx, v.Command)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Success = false
+		result.Output = output
+		result.Message = fmt.Sprintf("security scan %q did not complete", v.Command)
+		result.Error = err.Error()
+		return result
+	}
+
+	serious := security.AtLeast(findings, minSeverity)
+	if len(serious) > 0 {
+		result.Success = false
+		result.Message = fmt.Sprintf("security scan found %d issue(s) of %s severity or above", len(serious), v.MinSeverity)
+		result.Error = "security findings"
+		result.Report = fmt.Sprintf("Security scan (%s) failed.\n%s", v.Command, security.Summary(serious, minSeverity, maxReportedFindings))
+		return result
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("security scan found no issues of %s severity or above (%d below)", v.MinSeverity, len(findings))
+	return result
+}
+
+// Type returns the validation type
+func (v *SecurityScanValidator) Type() ValidationType {
+	return ValidationTypeSecurityScan
+}
+
+// Description returns a human-readable description
+func (v *SecurityScanValidator) Description() string {
+	if v.Desc != "" {
+		return v.Desc
+	}
+	return fmt.Sprintf("security scan (%s+): %s", v.MinSeverity, v.Command)
+}
+
 // CreateValidator creates a validator from a validation definition
 func CreateValidator(def ValidationDefinition) (Validator, error) {
 	switch def.Type {
@@ -621,6 +915,27 @@ func CreateValidator(def ValidationDefinition) (Validator, error) {
 		}
 		return NewOutputValidator(def), nil
 
+	case ValidationTypeCoverage:
+		if def.Command == "" {
+			return nil, fmt.Errorf("command is required for coverage validation")
+		}
+		return NewCoverageValidator(def)
+
+	case ValidationTypeSecurityScan:
+		return NewSecurityScanValidator(def)
+
+	case ValidationTypeDBQuery:
+		if def.DSN == "" || def.Query == "" {
+			return nil, fmt.Errorf("dsn and query are required for db_query validation")
+		}
+		return NewDBQueryValidator(def)
+
+	case ValidationTypeBrowser:
+		if def.URL == "" {
+			return nil, fmt.Errorf("url is required for browser validation")
+		}
+		return NewBrowserValidator(def)
+
 	default:
 		return nil, fmt.Errorf("unknown validation type: %s", def.Type)
 	}
@@ -639,8 +954,16 @@ func ParseValidationType(s string) (ValidationType, error) {
 		return ValidationTypeFileExists, nil
 	case "output_contains", "output", "contains":
 		return ValidationTypeOutputContains, nil
+	case "coverage", "cover":
+		return ValidationTypeCoverage, nil
+	case "security_scan", "security", "security-scan":
+		return ValidationTypeSecurityScan, nil
+	case "db_query", "db", "sql", "db-query":
+		return ValidationTypeDBQuery, nil
+	case "browser", "chrome", "page":
+		return ValidationTypeBrowser, nil
 	default:
-		return "", fmt.Errorf("unknown validation type %q: must be one of http_get, http_post, cli_command, file_exists, output_contains", s)
+		return "", fmt.Errorf("unknown validation type %q: must be one of http_get, http_post, cli_command, file_exists, output_contains, coverage, security_scan, db_query, browser", s)
 	}
 }
 
@@ -674,10 +997,17 @@ func sanitizePath(path string) string {
 	return path
 }
 
+// cleaner is implemented by validators that can leave processes running
+type cleaner interface {
+	Cleanup()
+}
+
 // ValidationRunner runs multiple validations and aggregates results
 type ValidationRunner struct {
 	Validators []Validator
 	Timeout    time.Duration
+
+	processes map[string]*processInstance // Managed processes, shared by validations declaring the same one
 }
 
 // NewValidationRunner creates a new validation runner
@@ -699,6 +1029,19 @@ func (r *ValidationRunner) AddFromDefinitions(defs []ValidationDefinition) error
 		if err != nil {
 			return fmt.Errorf("failed to create validator: %w", err)
 		}
+		if def.ManagedProcess != nil {
+			if def.ManagedProcess.Command == "" {
+				return fmt.Errorf("failed to create validator: command is required for managed_process")
+			}
+			key := def.ManagedProcess.key()
+			if r.processes == nil {
+				r.processes = make(map[string]*processInstance)
+			}
+			if r.processes[key] == nil {
+				r.processes[key] = &processInstance{spec: *def.ManagedProcess}
+			}
+			v = &managedValidator{Validator: v, process: r.processes[key]}
+		}
 		r.AddValidator(v)
 	}
 	return nil
@@ -727,8 +1070,25 @@ func (r *ValidationRunner) Run(ctx context.Context) ValidationRunResult {
 	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
 	defer cancel()
 
+	// Processes started by earlier validators (e.g., a server started by a
+	// cli_command) stay up for later ones, and are killed when the run ends,
+	// including on cancellation or when the overall timeout expires
+	defer r.cleanup()
+
 	for _, v := range r.Validators {
+		if ctx.Err() != nil {
+			runResult.Results = append(runResult.Results, ValidationResult{
+				Success:     false,
+				Message:     fmt.Sprintf("%s: not run", v.Description()),
+				Error:       ctx.Err().Error(),
+				Description: v.Description(),
+			})
+			runResult.FailedCount++
+			continue
+		}
+
 		result := v.Validate(ctx)
+		result.Description = v.Description()
 		runResult.Results = append(runResult.Results, result)
 
 		if result.Success {
@@ -743,6 +1103,15 @@ func (r *ValidationRunner) Run(ctx context.Context) ValidationRunResult {
 	return runResult
 }
 
+// cleanup kills processes left running by the runner's validators
+func (r *ValidationRunner) cleanup() {
+	for _, v := range r.Validators {
+		if c, ok := v.(cleaner); ok {
+			c.Cleanup()
+		}
+	}
+}
+
 // Summary returns a human-readable summary of the run results
 func (r *ValidationRunResult) Summary() string {
 	var sb strings.Builder
@@ -6,8 +6,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/logimos/ralph/pkg/plan"
 )
 
 func TestParseValidationType(t *testing.T) {
@@ -31,6 +35,12 @@ func TestParseValidationType(t *testing.T) {
 		{"output_contains", ValidationTypeOutputContains, false},
 		{"output", ValidationTypeOutputContains, false},
 		{"contains", ValidationTypeOutputContains, false},
+		{"coverage", ValidationTypeCoverage, false},
+		{"security_scan", ValidationTypeSecurityScan, false},
+		{"security", ValidationTypeSecurityScan, false},
+		{"db_query", ValidationTypeDBQuery, false},
+		{"sql", ValidationTypeDBQuery, false},
+		{"browser", ValidationTypeBrowser, false},
 		{"invalid", "", true},
 		{"", "", true},
 	}
@@ -126,6 +136,32 @@ func TestCreateValidator(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "coverage with command",
+			def: ValidationDefinition{
+				Type:    ValidationTypeCoverage,
+				Command: "go",
+				Args:    []string{"test", "-coverprofile=c.out", "./..."},
+				Options: map[string]interface{}{"min": 80.0},
+			},
+			wantErr: false,
+		},
+		{
+			name: "coverage without command",
+			def: ValidationDefinition{
+				Type: ValidationTypeCoverage,
+			},
+			wantErr: true,
+		},
+		{
+			name: "coverage with invalid minimum",
+			def: ValidationDefinition{
+				Type:    ValidationTypeCoverage,
+				Command: "go",
+				Options: map[string]interface{}{"min": "lots"},
+			},
+			wantErr: true,
+		},
 		{
 			name: "unknown type",
 			def: ValidationDefinition{
@@ -149,6 +185,24 @@ func TestCreateValidator(t *testing.T) {
 	}
 }
 
+func TestFromPlan(t *testing.T) {
+	def := FromPlan(plan.ValidationDefinition{
+		Type:           "http_get",
+		URL:            "http://localhost:8080/health",
+		ExpectedStatus: 200,
+		ManagedProcess: &plan.ManagedProcess{Command: "./server", ReadyPort: 8080},
+	})
+	if def.Type != ValidationTypeHTTPGet || def.URL != "http://localhost:8080/health" || def.ExpectedStatus != 200 {
+		t.Errorf("FromPlan() = %+v", def)
+	}
+	if def.ManagedProcess == nil || def.ManagedProcess.Command != "./server" || def.ManagedProcess.ReadyPort != 8080 {
+		t.Errorf("FromPlan() managed process = %+v", def.ManagedProcess)
+	}
+	if _, err := CreateValidator(def); err != nil {
+		t.Errorf("CreateValidator(FromPlan()) = %v", err)
+	}
+}
+
 func TestEndpointValidator(t *testing.T) {
 	// Create a test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -556,6 +610,74 @@ func TestValidationRunner(t *testing.T) {
 	}
 }
 
+func TestValidationRunner_KillsBackgroundProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+
+	// A setup command starts a "server" in the background that keeps writing
+	// to a file, and a later validation depends on it running
+	heartbeat := filepath.Join(t.TempDir(), "heartbeat")
+	runner := NewValidationRunner()
+	err := runner.AddFromDefinitions([]ValidationDefinition{
+		{
+			Type:    ValidationTypeCLI,
+			Command: "sh",
+			Args:    []string{"-c", "(while true; do echo x >> " + heartbeat + "; sleep 0.1; done) &"},
+		},
+		{
+			Type:    ValidationTypeCLI,
+			Command: "sh",
+			Args:    []string{"-c", "sleep 0.3; test -s " + heartbeat},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddFromDefinitions() error = %v", err)
+	}
+
+	start := time.Now()
+	result := runner.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected background process to be running during the run: %s", result.Summary())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("setup command blocked the run (took %s)", elapsed)
+	}
+
+	// The background process must not outlive the run
+	before, _ := os.Stat(heartbeat)
+	time.Sleep(500 * time.Millisecond)
+	after, _ := os.Stat(heartbeat)
+	if after.Size() != before.Size() {
+		t.Error("background process still running after the validation run")
+	}
+}
+
+func TestCLIValidator_CancellationKillsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+
+	v := NewCLIValidator(ValidationDefinition{
+		Type:    ValidationTypeCLI,
+		Command: "sh",
+		Args:    []string{"-c", "sleep 30 & sleep 30"},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := v.Validate(ctx)
+	if result.Success {
+		t.Error("expected cancelled validation to fail")
+	}
+	// No further attempts are made once the context is done
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled validation was not stopped promptly (took %s)", elapsed)
+	}
+}
+
 func TestValidationRunnerWithFailures(t *testing.T) {
 	runner := NewValidationRunner()
 
@@ -725,6 +847,92 @@ func TestValidatorDescription(t *testing.T) {
 	}
 }
 
+func TestCoverageValidator(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		min         interface{}
+		wantSuccess bool
+	}{
+		{"meets minimum", []string{"Total coverage: 82.5%"}, 80.0, true},
+		{"minimum as percentage string", []string{"Total coverage: 82.5%"}, "80%", true},
+		{"below minimum", []string{"Total coverage: 61.0%"}, 80.0, false},
+		{"no total in output", []string{"ok"}, 80.0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := CreateValidator(ValidationDefinition{
+				Type:    ValidationTypeCoverage,
+				Command: "echo",
+				Args:    tt.args,
+				Options: map[string]interface{}{"min": tt.min},
+			})
+			if err != nil {
+				t.Fatalf("CreateValidator() error = %v", err)
+			}
+			result := v.Validate(context.Background())
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Validate() success = %v, want %v (%s %s)", result.Success, tt.wantSuccess, result.Message, result.Error)
+			}
+		})
+	}
+}
+
+func TestSecurityScanValidator(t *testing.T) {
+	gosec := `{"Issues":[{"severity":"MEDIUM","rule_id":"G104","details":"Errors_unhandled","file":"main.go","line":"12"},{"severity":"HIGH","rule_id":"G101","details":"Hardcoded_credentials","file":"config.go","line":"3"}]}`
+	tests := []struct {
+		name        string
+		minSeverity interface{}
+		output      string
+		wantSuccess bool
+		wantReport  []string
+	}{
+		{"finding at default severity", nil, gosec, false, []string{"1 security finding(s) of high severity or above", "G101: Hardcoded_credentials (config.go:3)"}},
+		{"findings above lower severity", "medium", gosec, false, []string{"2 security finding(s)", "G104"}},
+		{"findings below severity", "critical", gosec, true, nil},
+		{"no findings", nil, `{"Issues":[]}`, true, nil},
+		{"unparseable output", nil, "scanner_not_configured", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := map[string]interface{}{}
+			if tt.minSeverity != nil {
+				options["min_severity"] = tt.minSeverity
+			}
+			v, err := CreateValidator(ValidationDefinition{
+				Type:    ValidationTypeSecurityScan,
+				Command: "echo",
+				Args:    []string{tt.output},
+				Options: options,
+			})
+			if err != nil {
+				t.Fatalf("CreateValidator() error = %v", err)
+			}
+			result := v.Validate(context.Background())
+			if result.Success != tt.wantSuccess {
+				t.Errorf("Validate() success = %v, want %v (%s %s)", result.Success, tt.wantSuccess, result.Message, result.Error)
+			}
+			for _, want := range tt.wantReport {
+				if !strings.Contains(result.Report, want) {
+					t.Errorf("Report missing %q:\n%s", want, result.Report)
+				}
+			}
+		})
+	}
+
+	for _, def := range []ValidationDefinition{
+		{Type: ValidationTypeSecurityScan},
+		{Type: ValidationTypeSecurityScan, Options: map[string]interface{}{"tool": "snyk"}},
+		{Type: ValidationTypeSecurityScan, Command: "gosec", Options: map[string]interface{}{"min_severity": "severe"}},
+	} {
+		if _, err := CreateValidator(def); err == nil {
+			t.Errorf("CreateValidator(%+v) succeeded", def)
+		}
+	}
+}
+
 func TestValidatorTypes(t *testing.T) {
 	tests := []struct {
 		def          ValidationDefinition
@@ -750,6 +958,14 @@ func TestValidatorTypes(t *testing.T) {
 			def:          ValidationDefinition{Type: ValidationTypeOutputContains, Pattern: "test", Input: "test"},
 			expectedType: ValidationTypeOutputContains,
 		},
+		{
+			def:          ValidationDefinition{Type: ValidationTypeCoverage, Command: "echo"},
+			expectedType: ValidationTypeCoverage,
+		},
+		{
+			def:          ValidationDefinition{Type: ValidationTypeSecurityScan, Options: map[string]interface{}{"tool": "gosec"}},
+			expectedType: ValidationTypeSecurityScan,
+		},
 	}
 
 	for _, tt := range tests {
@@ -2,31 +2,69 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/logimos/ralph/internal/agent"
+	"github.com/logimos/ralph/internal/analytics"
+	"github.com/logimos/ralph/internal/approval"
 	"github.com/logimos/ralph/internal/baseline"
+	"github.com/logimos/ralph/internal/changelog"
+	"github.com/logimos/ralph/internal/checkpoint"
 	"github.com/logimos/ralph/internal/config"
+	"github.com/logimos/ralph/internal/coverage"
 	"github.com/logimos/ralph/internal/detection"
+	"github.com/logimos/ralph/internal/diffs"
 	"github.com/logimos/ralph/internal/environment"
-	"github.com/logimos/ralph/internal/goals"
-	"github.com/logimos/ralph/internal/memory"
-	"github.com/logimos/ralph/internal/milestone"
+	"github.com/logimos/ralph/internal/experiment"
+	"github.com/logimos/ralph/internal/filelock"
+	"github.com/logimos/ralph/internal/flaky"
+	"github.com/logimos/ralph/internal/forecast"
+	"github.com/logimos/ralph/internal/guard"
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/internal/hooks"
+	"github.com/logimos/ralph/internal/llm"
+	"github.com/logimos/ralph/internal/mcp"
 	"github.com/logimos/ralph/internal/multiagent"
+	"github.com/logimos/ralph/internal/notify"
 	"github.com/logimos/ralph/internal/nudge"
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/internal/parallel"
+	"github.com/logimos/ralph/internal/policy"
+	"github.com/logimos/ralph/internal/proc"
+	"github.com/logimos/ralph/internal/progress"
 	"github.com/logimos/ralph/internal/prompt"
 	"github.com/logimos/ralph/internal/recovery"
+	"github.com/logimos/ralph/internal/redact"
 	"github.com/logimos/ralph/internal/replan"
+	"github.com/logimos/ralph/internal/runsummary"
 	"github.com/logimos/ralph/internal/scope"
+	"github.com/logimos/ralph/internal/security"
+	"github.com/logimos/ralph/internal/server"
+	"github.com/logimos/ralph/internal/staleness"
+	"github.com/logimos/ralph/internal/telemetry"
+	"github.com/logimos/ralph/internal/testreport"
+	"github.com/logimos/ralph/internal/tracker"
+	"github.com/logimos/ralph/internal/transcript"
 	"github.com/logimos/ralph/internal/ui"
-	"github.com/logimos/ralph/internal/validation"
+	"github.com/logimos/ralph/internal/worktree"
+	"github.com/logimos/ralph/pkg/goals"
+	"github.com/logimos/ralph/pkg/memory"
+	"github.com/logimos/ralph/pkg/milestone"
+	"github.com/logimos/ralph/pkg/plan"
+	"github.com/logimos/ralph/pkg/validation"
+	"golang.org/x/term"
 )
 
 var (
@@ -47,42 +85,42 @@ func getFlagGroups() []flagGroup {
 		{
 			name:        "Core Options",
 			description: "Essential flags for running Ralph",
-			flags:       []string{"iterations", "agent", "plan", "progress", "config", "build-system", "typecheck", "test", "version"},
+			flags:       []string{"iterations", "agent", "agent-env", "fallback-agents", "rotate-agents", "health-check", "health-check-prompt", "plan", "progress", "C", "config", "profile", "explain-config", "build-system", "typecheck", "test", "lint", "lint-cmd", "version"},
 		},
 		{
 			name:        "Plan Display",
 			description: "View and inspect plan status",
-			flags:       []string{"list-all", "list-tested", "list-untested", "list-deferred"},
+			flags:       []string{"status", "show-feature", "list-all", "list-tested", "list-untested", "list-deferred", "mark-tested", "mark-untested", "defer", "reason", "delete-feature", "add-feature", "category", "feature-milestone", "steps", "merge-plan", "merge-milestone-prefix", "merge-similarity"},
 		},
 		{
 			name:        "Plan Analysis & Refinement",
 			description: "Analyze and refine your plan.json (analyze = preview, refine = apply)",
-			flags:       []string{"analyze-plan", "refine-plan", "dry-run"},
+			flags:       []string{"analyze-plan", "refine-plan", "dry-run", "max-prompt-steps", "progress-tail", "feature-prompt"},
 		},
 		{
 			name:        "Recovery (Per-Feature)",
 			description: "Handle failures during a single feature's implementation. Recovery is the FIRST line of defense - it retries, skips, or rolls back individual features before escalating to replanning.",
-			flags:       []string{"max-retries", "recovery-strategy"},
+			flags:       []string{"max-retries", "recovery-strategy", "retry-backoff", "retry-backoff-multiplier", "retry-backoff-max", "retry-jitter", "retry-budget", "iteration-timeout", "timeout-retry", "min-iteration-interval", "rate-limit-backoff", "rate-limit-retries", "rollback-feature", "rollback-iteration", "flaky-file", "test-report"},
 		},
 		{
 			name:        "Replanning (Plan-Level)",
 			description: "Dynamically adjust the ENTIRE plan when recovery alone isn't enough. Replanning is the SECOND line of defense - triggered after repeated failures across features.",
-			flags:       []string{"auto-replan", "replan", "replan-strategy", "replan-threshold", "list-versions", "restore-version"},
+			flags:       []string{"auto-replan", "replan", "replan-strategy", "replan-threshold", "replan-confirm", "list-versions", "restore-version", "plan-version-dir", "max-plan-versions", "compress-plan-versions"},
 		},
 		{
 			name:        "Scope Control",
 			description: "Limit iterations and set deadlines to prevent over-building",
-			flags:       []string{"scope-limit", "deadline"},
+			flags:       []string{"scope-limit", "deadline", "feature-deadline", "no-scope-calibration", "forecast"},
 		},
 		{
 			name:        "Memory System",
 			description: "Persistent memory for architectural decisions and conventions",
-			flags:       []string{"memory-file", "show-memory", "clear-memory", "add-memory", "memory-retention"},
+			flags:       []string{"memory-file", "show-memory", "clear-memory", "add-memory", "memory-retention", "export-memory", "import-memory", "merge", "delete-memory", "edit-memory", "pin-memory", "unpin-memory", "global", "no-global-memory"},
 		},
 		{
 			name:        "Nudge System",
 			description: "Lightweight mid-run guidance without stopping execution",
-			flags:       []string{"nudge-file", "nudge", "show-nudges", "clear-nudges"},
+			flags:       []string{"nudge-file", "nudge", "nudge-sticky", "nudge-ttl", "nudge-list", "no-nudge-console", "show-nudges", "clear-nudges"},
 		},
 		{
 			name:        "Milestone Tracking",
@@ -92,37 +130,72 @@ func getFlagGroups() []flagGroup {
 		{
 			name:        "Goal-Oriented Planning",
 			description: "Decompose high-level goals into actionable plans",
-			flags:       []string{"goals-file", "goal", "goal-priority", "goals", "decompose-goal", "decompose-all"},
+			flags:       []string{"goals-file", "goal", "goal-priority", "goals", "decompose-goal", "decompose-all", "redecompose-goal", "feedback", "remove-goal", "edit-goal", "set-goal-priority", "complete-goal"},
 		},
 		{
 			name:        "Validation",
 			description: "Verify outcomes beyond tests and type checks",
-			flags:       []string{"validate", "validate-feature"},
+			flags:       []string{"validate", "validate-feature", "validate-on-complete", "ci-gate", "report-dir", "report-html", "coverage-gate", "coverage-cmd"},
 		},
 		{
 			name:        "Multi-Agent Collaboration",
 			description: "Coordinate multiple AI agents working in parallel",
-			flags:       []string{"multi-agent", "agents", "parallel-agents", "list-agents"},
+			flags:       []string{"multi-agent", "agents", "parallel-agents", "list-agents", "review-gate"},
 		},
 		{
 			name:        "Output & UI",
 			description: "Control output format and verbosity",
-			flags:       []string{"verbose", "v", "quiet", "q", "no-color", "json-output", "log-level"},
+			flags:       []string{"verbose", "v", "quiet", "q", "no-color", "json-output", "log-level", "stream", "notify"},
 		},
 		{
 			name:        "Environment",
 			description: "Environment detection and configuration",
-			flags:       []string{"environment"},
+			flags:       []string{"environment", "environment-reports"},
 		},
 		{
 			name:        "Plan Generation",
-			description: "Generate plans from notes files",
-			flags:       []string{"generate-plan", "notes", "output"},
+			description: "Generate plans from notes files or GitHub issues",
+			flags:       []string{"generate-plan", "notes", "from-issue", "no-agent", "output"},
+		},
+		{
+			name:        "Issue Tracker Sync",
+			description: "Import issues from GitHub Issues, Jira or Linear as features and close them once tested",
+			flags:       []string{"sync-tracker"},
 		},
 		{
 			name:        "Codebase Baselining",
 			description: "Analyze and familiarize Ralph with your codebase",
-			flags:       []string{"baseline", "baseline-file", "show-baseline", "use-baseline"},
+			flags:       []string{"baseline", "baseline-file", "show-baseline", "baseline-drift", "use-baseline", "no-impact-analysis", "context-max-age", "context-max-changes", "refresh-stale-context"},
+		},
+		{
+			name:        "Run History & Reports",
+			description: "Record run outcomes and compare runs (ralph report list | ralph report compare <run-a> <run-b>)",
+			flags:       []string{"history-dir", "run-label", "junit-output", "summary-markdown", "progress-query", "analytics", "changelog", "changelog-format", "changelog-file", "diff-dir", "show-iteration-diff", "transcript", "transcript-dir", "show-transcript", "telemetry", "telemetry-file"},
+		},
+		{
+			name:        "Checkpoints",
+			description: "Named snapshots of plan, state and git ref (ralph checkpoint \"<label>\" | list | restore <ref> | delete <ref>)",
+			flags:       []string{"checkpoint-dir"},
+		},
+		{
+			name:        "API Backend",
+			description: "Talk to an OpenAI/Anthropic-compatible HTTP API directly instead of an agent CLI",
+			flags:       []string{"backend", "api-base-url", "api-model", "api-key-env", "api-max-tokens"},
+		},
+		{
+			name:        "A/B Experiments",
+			description: "Alternate between two agents or prompt templates within a run and compare the results",
+			flags:       []string{"experiment", "experiment-agent-b", "experiment-prompt-a", "experiment-prompt-b", "experiment-split"},
+		},
+		{
+			name:        "Parallel Features",
+			description: "Work on independent features at the same time, each in its own git worktree",
+			flags:       []string{"parallel"},
+		},
+		{
+			name:        "Safety",
+			description: "Guard against unwanted changes by the agent or validators",
+			flags:       []string{"no-path-guard", "guard-paths", "isolated-worktree", "approve", "yes", "policy", "force", "no-redact"},
 		},
 	}
 }
@@ -223,6 +296,18 @@ func isBoolFlag(f *flag.Flag) bool {
 
 func main() {
 	cfg := parseFlags()
+	filelock.DefaultOptions.Force = cfg.Force
+
+	// Mask secrets in prompts and in everything Ralph persists
+	if !cfg.NoRedact {
+		r, err := newSecretRedactor(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		secretRedactor = r
+		agent.SetRedactor(r)
+	}
 
 	// Handle version command (exit early)
 	if cfg.ShowVersion {
@@ -230,6 +315,78 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle config explanation (exit early)
+	if cfg.ExplainConfig {
+		if err := handleExplainConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle checkpoint subcommand (e.g., "ralph checkpoint \"before refactor\"")
+	if args := flag.Args(); len(args) > 0 && args[0] == "checkpoint" {
+		if err := handleCheckpointCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle question subcommand (e.g., "ralph question answer 4 \"Use PostgreSQL\"")
+	if args := flag.Args(); len(args) > 0 && args[0] == "question" {
+		if err := handleQuestionCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle telemetry subcommand (e.g., "ralph telemetry export report.json")
+	if args := flag.Args(); len(args) > 0 && args[0] == "telemetry" {
+		if err := handleTelemetryCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle flaky subcommand (e.g., "ralph flaky unmark TestRetry")
+	if args := flag.Args(); len(args) > 0 && args[0] == "flaky" {
+		if err := handleFlakyCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle serve subcommand (e.g., "ralph -agent claude serve 127.0.0.1:7777")
+	if args := flag.Args(); len(args) > 0 && args[0] == "serve" {
+		if err := handleServeCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle mcp subcommand (e.g., "ralph -plan tasks.json mcp")
+	if args := flag.Args(); len(args) > 0 && args[0] == "mcp" {
+		if err := handleMCPCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle report subcommand (e.g., "ralph report compare <run-a> <run-b>")
+	if args := flag.Args(); len(args) > 0 && args[0] == "report" {
+		if err := handleReportCommand(cfg, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle generate-plan command
 	if cfg.GeneratePlan {
 		if err := validateConfig(cfg); err != nil {
@@ -237,6 +394,14 @@ func main() {
 			os.Exit(1)
 		}
 		if err := generatePlanFromNotes(cfg); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	// Handle issue tracker sync (doesn't require iterations; creates the plan if needed)
+	if cfg.SyncTracker {
+		if err := handleTrackerSync(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -244,7 +409,8 @@ func main() {
 	}
 
 	// Handle memory commands (don't require iterations or plan file)
-	if cfg.ShowMemory || cfg.ClearMemory || cfg.AddMemory != "" {
+	if cfg.ShowMemory || cfg.ClearMemory || cfg.AddMemory != "" || cfg.ExportMemory != "" || cfg.ImportMemory != "" ||
+		cfg.DeleteMemory != "" || cfg.EditMemory != "" || cfg.PinMemory != "" || cfg.UnpinMemory != "" {
 		if err := handleMemoryCommands(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -253,7 +419,7 @@ func main() {
 	}
 
 	// Handle nudge commands (don't require iterations or plan file)
-	if cfg.ShowNudges || cfg.ClearNudges || cfg.Nudge != "" {
+	if cfg.ShowNudges || cfg.ClearNudges || cfg.Nudge != "" || cfg.NudgeList {
 		if err := handleNudgeCommands(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -261,6 +427,23 @@ func main() {
 		return
 	}
 
+	// Handle the add-feature command
+	if cfg.AddFeature != "" {
+		if err := addFeature(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.MergePlan != "" {
+		if err := mergePlanFile(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle milestone commands (require plan file but not iterations)
 	if cfg.ListMilestones || cfg.ShowMilestone != "" {
 		if err := validateConfig(cfg); err != nil {
@@ -274,23 +457,80 @@ func main() {
 		return
 	}
 
+	// Handle the forecast (doesn't require iterations)
+	if cfg.Forecast {
+		if err := validateConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := showForecast(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the status dashboard (doesn't require iterations)
+	if cfg.ShowStatus {
+		if err := validateConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		code, err := showStatus(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if code != exitPlanComplete {
+			os.Exit(code)
+		}
+		return
+	}
+
+	// Handle the feature detail command (doesn't require iterations)
+	if cfg.ShowFeature != 0 {
+		if err := validateConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := showFeature(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle feature edits (don't require iterations)
+	if cfg.MarkTested != 0 || cfg.MarkUntested != 0 || cfg.DeferFeature != 0 || cfg.DeleteFeature != 0 {
+		if err := validateConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := editFeature(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle list commands (don't require iterations)
 	if cfg.ListAll || cfg.ListTested || cfg.ListUntested || cfg.ListDeferred {
 		if err := validateConfig(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		list := listPlanStatus
 		if cfg.ListDeferred {
-			if err := listDeferredFeatures(cfg); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
-			}
-			return
+			list = listDeferredFeatures
 		}
-		if err := listPlanStatus(cfg); err != nil {
+		code, err := list(cfg)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		if code != exitPlanComplete {
+			os.Exit(code)
+		}
 		return
 	}
 
@@ -307,6 +547,14 @@ func main() {
 		return
 	}
 
+	if cfg.CIGate {
+		if err := runCIGate(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Handle validation commands
 	if cfg.Validate || cfg.ValidateFeature > 0 {
 		if err := validateConfig(cfg); err != nil {
@@ -321,7 +569,8 @@ func main() {
 	}
 
 	// Handle goal commands
-	if cfg.Goal != "" || cfg.ShowGoals || cfg.GoalStatus || cfg.ListGoals || cfg.DecomposeGoal != "" || cfg.DecomposeAll {
+	if cfg.Goal != "" || cfg.ShowGoals || cfg.GoalStatus || cfg.ListGoals || cfg.DecomposeGoal != "" || cfg.DecomposeAll ||
+		cfg.RedecomposeGoal != "" || cfg.RemoveGoal != "" || cfg.EditGoal != "" || cfg.SetGoalPriority != "" || cfg.CompleteGoal != "" {
 		if err := handleGoalCommands(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -357,7 +606,7 @@ func main() {
 	}
 
 	// Handle baseline commands
-	if cfg.Baseline || cfg.ShowBaseline {
+	if cfg.Baseline || cfg.ShowBaseline || cfg.BaselineDrift {
 		if err := handleBaselineCommands(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -365,68 +614,224 @@ func main() {
 		return
 	}
 
+	// Handle transcript display
+	if cfg.ShowTranscript != "" {
+		if err := handleShowTranscript(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle iteration diff display
+	if cfg.ShowIterationDiff > 0 {
+		if err := handleShowIterationDiff(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle progress log queries
+	if cfg.ProgressQuery != "" {
+		if err := handleProgressQuery(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the analytics report
+	if cfg.Analytics {
+		if err := showAnalytics(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle the changelog
+	if cfg.Changelog {
+		if err := showChangelog(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle rollback to a restore point
+	if cfg.RollbackFeature != 0 || cfg.RollbackIteration != 0 {
+		if err := handleRollbackCommand(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := validateConfig(cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := runIterations(cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if cfg.DryRun {
+		if err := handleDryRun(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := runFunc(cfg)(cfg); err != nil {
+		exitWithError(err)
+	}
+}
+
+// exitInterrupted is the exit status after Ctrl+C interrupted the agent, as
+// shells report for processes killed by SIGINT
+const exitInterrupted = 130
+
+// exitWithError reports an error and exits, with exitInterrupted if the user
+// interrupted the agent. Deferred cleanup has run by the time it is called.
+func exitWithError(err error) {
+	if errors.Is(err, agent.ErrInterrupted) {
+		fmt.Fprintln(os.Stderr, "Interrupted")
+		os.Exit(exitInterrupted)
 	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
 }
 
 func parseFlags() *config.Config {
 	cfg := config.New()
 
+	flag.StringVar(&cfg.WorkDir, "C", "", "Run as if Ralph was started in this directory: the config file, plan, build system and agent are all resolved there")
+
 	// Config file flag (parsed early to load file config before other flags)
 	var configFile string
 	flag.StringVar(&configFile, "config", "", "Path to configuration file (default: auto-discover .ralph.yaml, .ralph.json)")
+	flag.StringVar(&cfg.Profile, "profile", "", "Config file profile to overlay on the other settings (e.g., ci; default: $RALPH_PROFILE)")
+	flag.BoolVar(&cfg.ExplainConfig, "explain-config", false, "Print every effective config value with its source (flag, env, project config, home config, default) and exit")
 
 	flag.StringVar(&cfg.PlanFile, "plan", config.DefaultPlanFile, "Path to the plan file (e.g., plan.json)")
 	flag.StringVar(&cfg.ProgressFile, "progress", config.DefaultProgressFile, "Path to the progress file (e.g., progress.txt)")
 	flag.IntVar(&cfg.Iterations, "iterations", 0, "Number of iterations to run (required)")
 	flag.StringVar(&cfg.AgentCmd, "agent", config.DefaultAgentCmd, "Command name for the AI agent CLI tool")
+	flag.Func("agent-env", "Environment variable for the agent process only, as NAME=VALUE (repeatable; VALUE may be env:NAME or file:PATH)", func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("expected NAME=VALUE, got %q", s)
+		}
+		if err := agent.ValidateEnvName(name); err != nil {
+			return err
+		}
+		if cfg.AgentEnv == nil {
+			cfg.AgentEnv = make(map[string]string)
+		}
+		cfg.AgentEnv[name] = value
+		return nil
+	})
+	flag.Func("fallback-agents", "Comma-separated agent commands tried in order when the agent errors or times out", func(s string) error {
+		cfg.FallbackAgents = nil
+		for _, cmd := range strings.Split(s, ",") {
+			if cmd = strings.TrimSpace(cmd); cmd != "" {
+				cfg.FallbackAgents = append(cfg.FallbackAgents, cmd)
+			}
+		}
+		return nil
+	})
+	flag.BoolVar(&cfg.RotateAgents, "rotate-agents", false, "Start each iteration at the next of the agent and fallback agents, to spread rate limits")
+	flag.BoolVar(&cfg.HealthCheck, "health-check", false, "Send each agent a cheap prompt before the first iteration to verify it is logged in and reachable")
+	flag.StringVar(&cfg.HealthCheckPrompt, "health-check-prompt", config.DefaultHealthCheckPrompt, "Prompt of the agent health check")
 	flag.StringVar(&cfg.BuildSystem, "build-system", "", "Build system preset (pnpm, npm, yarn, gradle, maven, cargo, go, python) or 'auto' for detection")
 	flag.StringVar(&cfg.TypeCheckCmd, "typecheck", "", "Command to run for type checking (overrides build-system preset)")
 	flag.StringVar(&cfg.TestCmd, "test", "", "Command to run for testing (overrides build-system preset)")
+	flag.BoolVar(&cfg.Lint, "lint", false, "Run the build system's lint command after each iteration; issues are retried as lint failures")
+	flag.StringVar(&cfg.LintCmd, "lint-cmd", "", "Command to run for linting (overrides build-system preset; implies -lint)")
 	flag.BoolVar(&cfg.Verbose, "verbose", false, "Enable verbose output")
 	flag.BoolVar(&cfg.Verbose, "v", false, "Enable verbose output (shorthand)")
 	flag.BoolVar(&cfg.ShowVersion, "version", false, "Show version information and exit")
 	flag.BoolVar(&cfg.ListAll, "list-all", false, "List all features (tested and untested)")
-	flag.BoolVar(&cfg.ListStatus, "status", false, "DEPRECATED: Use -list-all instead. List all features.")
+	flag.BoolVar(&cfg.ShowStatus, "status", false, "Show a dashboard of the plan, milestones, goals, last run, nudges and memory")
+	flag.IntVar(&cfg.ShowFeature, "show-feature", 0, "Print the full record of a feature by ID: steps, validations, goal, iterations and last failure")
+	flag.IntVar(&cfg.MarkTested, "mark-tested", 0, "Mark a feature tested by ID (the plan is backed up first)")
+	flag.IntVar(&cfg.MarkUntested, "mark-untested", 0, "Mark a feature untested by ID, putting a deferred feature back in the queue")
+	flag.IntVar(&cfg.DeferFeature, "defer", 0, "Defer a feature by ID (see -reason)")
+	flag.StringVar(&cfg.DeferReason, "reason", "", "Reason recorded with -defer")
+	flag.IntVar(&cfg.DeleteFeature, "delete-feature", 0, "Remove a feature from the plan by ID")
+	flag.StringVar(&cfg.AddFeature, "add-feature", "", "Append a feature with the next free ID (see -category, -feature-milestone and -steps)")
+	flag.StringVar(&cfg.FeatureCategory, "category", "", "Category of the feature added with -add-feature (default: feature)")
+	flag.StringVar(&cfg.FeatureMilestone, "feature-milestone", "", "Milestone of the feature added with -add-feature")
+	flag.StringVar(&cfg.FeatureSteps, "steps", "", "Steps of the feature added with -add-feature, separated by ';'")
+	flag.StringVar(&cfg.MergePlan, "merge-plan", "", "Merge the features of another plan file into the plan, renumbering conflicting IDs")
+	flag.StringVar(&cfg.MergeMilestonePrefix, "merge-milestone-prefix", "", "Prefix added to the milestones of features merged with -merge-plan (e.g., 'mobile/')")
+	flag.Float64Var(&cfg.MergeSimilarity, "merge-similarity", 0, "With -merge-plan, skip features whose description is at least this similar to an existing one (0-1, 0 = merge all)")
 	flag.BoolVar(&cfg.ListTested, "list-tested", false, "List only tested features")
 	flag.BoolVar(&cfg.ListUntested, "list-untested", false, "List only untested features")
 	flag.BoolVar(&cfg.GeneratePlan, "generate-plan", false, "Generate plan.json from notes file")
-	flag.StringVar(&cfg.NotesFile, "notes", "", "Path to notes file (required with -generate-plan)")
+	flag.StringVar(&cfg.NotesFile, "notes", "", "Path to notes file (required with -generate-plan unless -from-issue is set)")
+	flag.BoolVar(&cfg.NoAgent, "no-agent", false, "With -generate-plan, build the plan from the notes' headings and lists without the agent (deterministic, offline)")
+	flag.StringVar(&cfg.FromIssue, "from-issue", "", "With -generate-plan, generate the plan from a GitHub issue or pull request and its comments (owner/repo#123 or URL)")
 	flag.StringVar(&cfg.OutputPlanFile, "output", config.DefaultPlanFile, "Output plan file path (default: plan.json)")
 	flag.IntVar(&cfg.MaxRetries, "max-retries", config.DefaultMaxRetries, "Maximum retries per feature before escalation (default: 3)")
 	flag.StringVar(&cfg.RecoveryStrategy, "recovery-strategy", config.DefaultRecoveryStrategy, "Recovery strategy: retry, skip, rollback (default: retry)")
+	flag.StringVar(&cfg.RetryBackoff, "retry-backoff", "", "Wait this long before the first retry of a failing feature (e.g., '30s'; default: retry immediately)")
+	flag.Float64Var(&cfg.RetryBackoffMultiplier, "retry-backoff-multiplier", config.DefaultRetryBackoffMultiplier, "Factor applied to the retry delay after each retry")
+	flag.StringVar(&cfg.RetryBackoffMax, "retry-backoff-max", config.DefaultRetryBackoffMax, "Upper bound of the retry delay")
+	flag.Float64Var(&cfg.RetryJitter, "retry-jitter", config.DefaultRetryJitter, "Fraction of the retry delay randomly added or removed (0-1)")
+	flag.IntVar(&cfg.RetryBudget, "retry-budget", 0, "Maximum retries per run across all features; failing features are skipped once spent (0 = unlimited)")
+	flag.StringVar(&cfg.IterationTimeout, "iteration-timeout", "", "Kill the agent if a single iteration runs longer than this (e.g., '15m'; default: no limit)")
+	flag.BoolVar(&cfg.TimeoutRetry, "timeout-retry", false, "Re-run a timed-out iteration once, asking the agent to be concise")
+	flag.StringVar(&cfg.MinIterationInterval, "min-iteration-interval", "", "Wait until at least this long after the previous agent run before starting the next (e.g., '30s')")
+	flag.StringVar(&cfg.RateLimitBackoff, "rate-limit-backoff", config.DefaultRateLimitBackoff, "Wait this long before retrying a rate-limited agent, doubling for each retry")
+	flag.IntVar(&cfg.RateLimitRetries, "rate-limit-retries", config.DefaultRateLimitRetries, "Times a rate-limited agent is retried before the iteration fails (0 = treat rate limits as failures)")
+	flag.IntVar(&cfg.RollbackFeature, "rollback-feature", 0, "Restore the working tree to the restore point taken before feature ID was started")
+	flag.IntVar(&cfg.RollbackIteration, "rollback-iteration", 0, "Restore the working tree to the restore point taken before iteration N of the latest run")
+	flag.StringVar(&cfg.FlakyFile, "flaky-file", config.DefaultFlakyFile, "Path of the flaky test store")
+	flag.StringVar(&cfg.TestReport, "test-report", "", "Test report naming failed tests in retry guidance (JUnit XML file or directory, Jest JSON, go test -json output)")
 	flag.StringVar(&cfg.Environment, "environment", "", "Override detected environment (local, github-actions, gitlab-ci, jenkins, circleci, ci)")
+	flag.BoolVar(&cfg.EnvironmentReports, "environment-reports", false, "On GitLab CI and Jenkins, write CI reports (junit.xml, ralph.env or ralph.properties) to -report-dir at the end of a run")
 	// UI-related flags
 	flag.BoolVar(&cfg.NoColor, "no-color", false, "Disable colored output")
 	flag.BoolVar(&cfg.Quiet, "quiet", false, "Minimal output (errors only)")
 	flag.BoolVar(&cfg.Quiet, "q", false, "Minimal output (shorthand for -quiet)")
 	flag.BoolVar(&cfg.JSONOutput, "json-output", false, "Machine-readable JSON output")
 	flag.StringVar(&cfg.LogLevel, "log-level", config.DefaultLogLevel, "Log level: debug, info, warn, error")
+	flag.BoolVar(&cfg.Stream, "stream", false, "Stream agent output live while it runs (instead of printing it after the iteration)")
+	flag.BoolVar(&cfg.Notify, "notify", false, "Show desktop notifications when the run finishes, a milestone completes, or approval is needed (skipped in CI)")
 	// Memory-related flags
 	flag.StringVar(&cfg.MemoryFile, "memory-file", config.DefaultMemoryFile, "Path to memory file")
 	flag.BoolVar(&cfg.ShowMemory, "show-memory", false, "Display stored memories")
 	flag.BoolVar(&cfg.ClearMemory, "clear-memory", false, "Clear all stored memories")
 	flag.StringVar(&cfg.AddMemory, "add-memory", "", "Add a memory entry (format: type:content where type is decision, convention, tradeoff, or context)")
 	flag.IntVar(&cfg.MemoryRetention, "memory-retention", config.DefaultMemoryRetention, "Days to retain memories (default: 90)")
+	flag.StringVar(&cfg.ExportMemory, "export-memory", "", "Write all memories to a file (e.g., to share them with another project)")
+	flag.StringVar(&cfg.ImportMemory, "import-memory", "", "Import memories from a file, replacing the stored ones (see -merge)")
+	flag.BoolVar(&cfg.MergeMemory, "merge", false, "With -import-memory, merge the imported memories with the stored ones")
+	flag.StringVar(&cfg.DeleteMemory, "delete-memory", "", "Delete a memory by ID (IDs are shown by -show-memory)")
+	flag.StringVar(&cfg.EditMemory, "edit-memory", "", "Replace a memory's content (format: id:new content)")
+	flag.StringVar(&cfg.PinMemory, "pin-memory", "", "Exempt a memory from retention pruning by ID")
+	flag.StringVar(&cfg.UnpinMemory, "unpin-memory", "", "Make a pinned memory subject to retention pruning again")
+	flag.BoolVar(&cfg.GlobalMemory, "global", false, "Memory commands target the user-level store ($HOME/.ralph/memory.json)")
+	flag.BoolVar(&cfg.NoGlobalMemory, "no-global-memory", false, "Do not use the user-level memory store underneath the project's")
 	// Milestone-related flags
 	flag.BoolVar(&cfg.ListMilestones, "milestones", false, "List all milestones with progress")
 	flag.StringVar(&cfg.ShowMilestone, "milestone", "", "Show features for a specific milestone")
 	// Nudge-related flags
 	flag.StringVar(&cfg.NudgeFile, "nudge-file", config.DefaultNudgeFile, "Path to nudge file")
-	flag.StringVar(&cfg.Nudge, "nudge", "", "Add one-time nudge (format: type:content where type is focus, skip, constraint, or style)")
+	flag.StringVar(&cfg.Nudge, "nudge", "", "Add one-time nudge (format: type:content where type is focus, skip, constraint, style, or checkpoint; prefix feature:<id>: or milestone:<name>: to scope it)")
 	flag.BoolVar(&cfg.ClearNudges, "clear-nudges", false, "Clear all nudges")
 	flag.BoolVar(&cfg.ShowNudges, "show-nudges", false, "Display current nudges")
+	flag.BoolVar(&cfg.NudgeSticky, "nudge-sticky", false, "With -nudge, keep the nudge active after it is injected (until cleared or its TTL runs out)")
+	flag.StringVar(&cfg.NudgeTTL, "nudge-ttl", "", "With -nudge, keep the nudge active until the TTL runs out: a duration (e.g., 2h) or a number of iterations (e.g., 3)")
+	flag.BoolVar(&cfg.NudgeList, "nudge-list", false, "List active nudges with their remaining lifetime")
+	flag.BoolVar(&cfg.NoNudgeConsole, "no-nudge-console", false, "Do not read nudges typed into the terminal during a run")
 	// Scope control flags
 	flag.IntVar(&cfg.ScopeLimit, "scope-limit", config.DefaultScopeLimit, "Max iterations per feature (0 = unlimited)")
 	flag.StringVar(&cfg.Deadline, "deadline", "", "Deadline duration (e.g., '1h', '30m', '2h30m')")
+	flag.StringVar(&cfg.FeatureDeadline, "feature-deadline", "", "Time budget per feature, after which it is deferred (e.g., '20m'; default: no limit)")
 	flag.BoolVar(&cfg.ListDeferred, "list-deferred", false, "List deferred features")
+	flag.BoolVar(&cfg.NoScopeCalibration, "no-scope-calibration", false, "Use the built-in iteration estimates instead of calibrating them from run history")
+	flag.BoolVar(&cfg.Forecast, "forecast", false, "Estimate the iterations and time left for the plan and each milestone, from run history")
 	// Replanning flags
 	flag.BoolVar(&cfg.AutoReplan, "auto-replan", config.DefaultAutoReplan, "Enable automatic replanning when triggers fire")
 	flag.BoolVar(&cfg.Replan, "replan", false, "Manually trigger replanning")
@@ -434,9 +839,19 @@ func parseFlags() *config.Config {
 	flag.IntVar(&cfg.ReplanThreshold, "replan-threshold", config.DefaultReplanThreshold, "Consecutive failures before replanning (default: 3)")
 	flag.BoolVar(&cfg.ListVersions, "list-versions", false, "List plan backup versions")
 	flag.IntVar(&cfg.RestoreVersion, "restore-version", 0, "Restore a specific plan version")
+	flag.BoolVar(&cfg.ReplanConfirm, "replan-confirm", false, "Show the changes of a replan and ask before it overwrites the plan (skip with -yes)")
+	flag.StringVar(&cfg.PlanVersionDir, "plan-version-dir", config.DefaultPlanVersionDir, "Directory for plan backups (backups next to the plan file are moved there)")
+	flag.IntVar(&cfg.MaxPlanVersions, "max-plan-versions", 0, "Number of plan backups to keep, removing the oldest first (0 = all)")
+	flag.BoolVar(&cfg.CompressPlanVersions, "compress-plan-versions", false, "Gzip plan backups")
 	// Validation flags
 	flag.BoolVar(&cfg.Validate, "validate", false, "Run validations for all completed features")
 	flag.IntVar(&cfg.ValidateFeature, "validate-feature", 0, "Validate a specific feature by ID")
+	flag.BoolVar(&cfg.CIGate, "ci-gate", false, "Exit non-zero if the plan regressed: schema errors, failing validations of tested features, or more untested features than at the last recorded run")
+	flag.BoolVar(&cfg.ValidateOnComplete, "validate-on-complete", false, "Run a feature's validations as soon as the agent marks it tested, unmarking it if they fail")
+	flag.StringVar(&cfg.ReportDir, "report-dir", config.DefaultReportDir, "Directory for validation reports (validation-report.json, validation-report.html)")
+	flag.BoolVar(&cfg.ReportHTML, "report-html", false, "Also write a self-contained HTML validation report")
+	flag.StringVar(&cfg.CoverageGate, "coverage-gate", "", "Minimum test coverage for marking features tested (e.g., 80%); coverage may not drop below its starting value")
+	flag.StringVar(&cfg.CoverageCmd, "coverage-cmd", "", "Command measuring test coverage (overrides the build system's default)")
 	// Goal flags
 	flag.StringVar(&cfg.GoalsFile, "goals-file", config.DefaultGoalsFile, "Path to goals file")
 	flag.StringVar(&cfg.Goal, "goal", "", "Add a high-level goal to decompose into plan items")
@@ -445,21 +860,81 @@ func parseFlags() *config.Config {
 	flag.BoolVar(&cfg.GoalStatus, "goal-status", false, "[Deprecated: use -goals] Show progress toward all goals")
 	flag.BoolVar(&cfg.ListGoals, "list-goals", false, "[Deprecated: use -goals] List all goals")
 	flag.StringVar(&cfg.DecomposeGoal, "decompose-goal", "", "Decompose a specific goal by ID into plan items")
+	flag.StringVar(&cfg.RemoveGoal, "remove-goal", "", "Remove a goal by ID")
+	flag.StringVar(&cfg.EditGoal, "edit-goal", "", "Edit a goal by ID as JSON in $VISUAL or $EDITOR")
+	flag.StringVar(&cfg.SetGoalPriority, "set-goal-priority", "", "Change a goal's priority (format: id:priority)")
+	flag.StringVar(&cfg.CompleteGoal, "complete-goal", "", "Mark a goal by ID complete")
 	flag.BoolVar(&cfg.DecomposeAll, "decompose-all", false, "Decompose all pending goals into plan items")
+	flag.StringVar(&cfg.RedecomposeGoal, "redecompose-goal", "", "Regenerate the untested plan items of a goal by ID (with -feedback)")
+	flag.StringVar(&cfg.Feedback, "feedback", "", "Feedback on a goal's previous plan items (use with -redecompose-goal)")
 	// Multi-agent flags
 	flag.StringVar(&cfg.AgentsFile, "agents", config.DefaultAgentsFile, "Path to multi-agent configuration file")
 	flag.IntVar(&cfg.ParallelAgents, "parallel-agents", config.DefaultParallelAgents, "Maximum number of agents to run in parallel")
 	flag.BoolVar(&cfg.ListAgents, "list-agents", false, "List configured agents")
 	flag.BoolVar(&cfg.EnableMultiAgent, "multi-agent", false, "Enable multi-agent collaboration mode")
+	flag.BoolVar(&cfg.ReviewGate, "review-gate", false, "Only keep features marked tested once the reviewer agents approve the iteration")
 	// Plan analysis flags
 	flag.BoolVar(&cfg.AnalyzePlan, "analyze-plan", false, "Analyze plan and preview refinements (read-only, writes to plan.refined.json for review)")
 	flag.BoolVar(&cfg.RefinePlan, "refine-plan", false, "Apply plan refinements by splitting complex features (writes to plan.json)")
-	flag.BoolVar(&cfg.DryRun, "dry-run", false, "Show what changes would be made without writing (use with -refine-plan)")
+	flag.BoolVar(&cfg.DryRun, "dry-run", false, "Show the configuration, next feature and prompt of a run without invoking the agent (with -refine-plan: preview the refinements)")
+	flag.IntVar(&cfg.MaxPromptSteps, "max-prompt-steps", config.DefaultMaxPromptSteps, "Steps of a feature included in prompts; longer step lists are truncated (0 = no limit)")
+	flag.IntVar(&cfg.ProgressTail, "progress-tail", 0, "Include the last N progress file entries in iteration prompts (0 = none)")
+	flag.BoolVar(&cfg.FeaturePrompt, "feature-prompt", false, "Show the agent only the current feature's details and forbid work on other features")
 	// Baseline flags
 	flag.BoolVar(&cfg.Baseline, "baseline", false, "Analyze the codebase and generate baseline.json for context-aware development")
 	flag.StringVar(&cfg.BaselineFile, "baseline-file", config.DefaultBaselineFile, "Path to baseline file")
 	flag.BoolVar(&cfg.ShowBaseline, "show-baseline", false, "Display the current baseline summary")
+	flag.BoolVar(&cfg.BaselineDrift, "baseline-drift", false, "Compare the saved baseline with the current tree and report files added, removed or changed outside Ralph's iterations")
 	flag.BoolVar(&cfg.UseBaseline, "use-baseline", true, "Use baseline context in agent prompts (default: true when baseline.json exists)")
+	flag.BoolVar(&cfg.NoImpactAnalysis, "no-impact-analysis", false, "Don't list the files the current feature will likely touch (predicted from the baseline) in prompts")
+	flag.IntVar(&cfg.ContextMaxAge, "context-max-age", config.DefaultContextMaxAge, "Warn when the baseline or newest memory is older than this many days (0 = never)")
+	flag.IntVar(&cfg.ContextMaxChanges, "context-max-changes", config.DefaultContextMaxChanges, "Warn when more files than this changed since the baseline or newest memory (0 = never)")
+	flag.BoolVar(&cfg.RefreshStaleContext, "refresh-stale-context", false, "Re-scan a stale baseline at the start of a run instead of only warning")
+	// Run history flags
+	flag.StringVar(&cfg.HistoryDir, "history-dir", config.DefaultHistoryDir, "Directory for run history records")
+	flag.BoolVar(&cfg.Changelog, "changelog", false, "Print the changelog of the completed features, grouped by milestone and category (see -changelog-format and -changelog-file)")
+	flag.StringVar(&cfg.ChangelogFormat, "changelog-format", config.DefaultChangelogFormat, "Changelog format: markdown or keepachangelog")
+	flag.StringVar(&cfg.ChangelogFile, "changelog-file", "", "Write the changelog to this file instead of printing it; during a run, it is rewritten whenever a milestone completes")
+	flag.BoolVar(&cfg.Analytics, "analytics", false, "Print failure rates, iterations, validation pass rates, failure types and deferral causes per feature category across the run history")
+	flag.StringVar(&cfg.RunLabel, "run-label", "", "Label recorded with this run for later comparison (e.g., 'claude-opus')")
+	flag.StringVar(&cfg.JUnitOutput, "junit-output", "", "Write iteration results, or -validate results, as JUnit XML to this file for CI test summaries")
+	flag.StringVar(&cfg.SummaryMarkdown, "summary-markdown", "", "Write the end-of-run summary as Markdown to this file (e.g., for a PR description)")
+	flag.StringVar(&cfg.ProgressQuery, "progress-query", "", "Print structured progress log events matching filters (e.g., 'feature=3 type=failure since=24h', or 'all')")
+	// Iteration diff flags
+	flag.StringVar(&cfg.DiffDir, "diff-dir", config.DefaultDiffDir, "Directory for per-iteration patches")
+	flag.BoolVar(&cfg.Telemetry, "telemetry", false, "Aggregate anonymized usage statistics locally (nothing is sent anywhere)")
+	flag.StringVar(&cfg.TelemetryFile, "telemetry-file", config.DefaultTelemetryFile, "Path of the local telemetry aggregate")
+	flag.IntVar(&cfg.ShowIterationDiff, "show-iteration-diff", 0, "Print the patch recorded for iteration N of the latest run")
+	// Transcript flags
+	flag.BoolVar(&cfg.Transcript, "transcript", false, "Record every prompt and agent response in an append-only transcript for audit")
+	flag.StringVar(&cfg.TranscriptDir, "transcript-dir", config.DefaultTranscriptDir, "Directory for prompt/response transcripts")
+	flag.StringVar(&cfg.ShowTranscript, "show-transcript", "", "Print the transcript of a run ('latest', run ID or ID prefix)")
+	// Checkpoint flags
+	flag.StringVar(&cfg.CheckpointDir, "checkpoint-dir", config.DefaultCheckpointDir, "Directory for named checkpoints")
+	// API backend flags
+	flag.StringVar(&cfg.AgentBackend, "backend", config.DefaultAgentBackend, "Agent backend: cli (shell out to -agent), openai, or anthropic")
+	flag.StringVar(&cfg.APIBaseURL, "api-base-url", "", "Base URL for the API backend (default: provider's public endpoint)")
+	flag.StringVar(&cfg.APIModel, "api-model", "", "Model name for the API backend (e.g., 'gpt-4o', 'claude-sonnet-4-5')")
+	flag.StringVar(&cfg.APIKeyEnv, "api-key-env", "", "Environment variable holding the API key (default: OPENAI_API_KEY or ANTHROPIC_API_KEY)")
+	flag.IntVar(&cfg.APIMaxTokens, "api-max-tokens", 0, "Maximum tokens to generate per API request (default: 8192)")
+	// Experiment flags
+	flag.BoolVar(&cfg.Experiment, "experiment", false, "Enable A/B experiment mode (alternate between two variants and compare results)")
+	flag.StringVar(&cfg.ExperimentAgentB, "experiment-agent-b", "", "Agent command for variant B (variant A uses -agent)")
+	flag.StringVar(&cfg.ExperimentPromptA, "experiment-prompt-a", "", "Prompt template file for variant A ({{prompt}} is replaced with the iteration prompt)")
+	flag.StringVar(&cfg.ExperimentPromptB, "experiment-prompt-b", "", "Prompt template file for variant B ({{prompt}} is replaced with the iteration prompt)")
+	flag.StringVar(&cfg.ExperimentSplit, "experiment-split", config.DefaultExperimentSplit, "How iterations are split between variants: alternate, halves")
+	// Safety flags
+	flag.BoolVar(&cfg.NoPathGuard, "no-path-guard", false, "Disable the guard that reverts iterations modifying files outside the repository")
+	flag.StringVar(&cfg.GuardPaths, "guard-paths", "", "Additional comma-separated paths outside the repository to watch (e.g., '~/.kube,/opt/secrets')")
+	flag.BoolVar(&cfg.IsolatedWorktree, "isolated-worktree", false, "Run in a temporary git worktree and merge changes back only if type check and tests pass")
+	flag.IntVar(&cfg.Parallel, "parallel", 0, "Work on up to N features not blocked by milestone dependencies at the same time, each in its own git worktree")
+	flag.BoolVar(&cfg.Approve, "approve", false, "Show each iteration's changes and wait for approval (y/n/diff/edit); rejected iterations are rolled back")
+	flag.BoolVar(&cfg.Yes, "yes", false, "Answer yes to confirmation prompts (e.g., of -replan-confirm)")
+	flag.StringVar(&cfg.PolicyFile, "policy", "", "Policy file bounding autonomous behavior (default: ralph-policy.yaml if present)")
+	flag.BoolVar(&cfg.Force, "force", false, "Take over locks on the plan and progress files held by another Ralph process")
+	flag.BoolVar(&cfg.NoRedact, "no-redact", false, "Do not mask secrets (API keys, tokens, passwords) in prompts, progress, memories and transcripts")
+	// Issue tracker flags
+	flag.BoolVar(&cfg.SyncTracker, "sync-tracker", false, "Import the tracker's open issues into the plan and comment on and close the issues of tested features, then exit")
 
 	flag.Usage = func() {
 		// Version already includes 'v' prefix from git tags, so don't add another
@@ -468,7 +943,9 @@ func parseFlags() *config.Config {
 			versionDisplay = "v" + Version
 		}
 		fmt.Fprintf(os.Stderr, "Ralph %s - AI-Assisted Development Workflow CLI\n\n", versionDisplay)
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s report <list|compare> [args]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s checkpoint <\"label\"|list|restore|delete> [args]\n\n", os.Args[0])
 		
 		// Print grouped flags
 		printGroupedFlags()
@@ -482,6 +959,11 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "  go      - go build ./... / go test ./...\n")
 		fmt.Fprintf(os.Stderr, "  python  - mypy . / pytest\n")
 		fmt.Fprintf(os.Stderr, "  auto    - Auto-detect from project files\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Lint commands (with -lint):\n")
+		fmt.Fprintf(os.Stderr, "    go: golangci-lint run, npm: npx eslint ., pnpm/yarn: pnpm lint / yarn lint,\n")
+		fmt.Fprintf(os.Stderr, "    python: ruff check ., cargo: cargo clippy -- -D warnings,\n")
+		fmt.Fprintf(os.Stderr, "    gradle: ./gradlew spotlessCheck, maven: mvn checkstyle:check\n")
 		fmt.Fprintf(os.Stderr, "\nConfiguration File:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph automatically discovers config files in this order:\n")
 		fmt.Fprintf(os.Stderr, "    1. Current directory: .ralph.yaml, .ralph.yml, .ralph.json,\n")
@@ -498,7 +980,10 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    iterations: 5\n")
 		fmt.Fprintf(os.Stderr, "    verbose: true\n")
 		fmt.Fprintf(os.Stderr, "  \n")
-		fmt.Fprintf(os.Stderr, "  Priority: CLI flags > config file > defaults\n")
+		fmt.Fprintf(os.Stderr, "  Priority: CLI flags > RALPH_* environment variables > config file > defaults\n")
+		fmt.Fprintf(os.Stderr, "  Every config file key can be set as RALPH_<KEY> (e.g., RALPH_SCOPE_LIMIT=3).\n")
+		fmt.Fprintf(os.Stderr, "  -profile ci overlays the settings under profiles.ci in the config file.\n")
+		fmt.Fprintf(os.Stderr, "  -explain-config shows where each effective value comes from.\n")
 		fmt.Fprintf(os.Stderr, "\nTwo-Tier Failure Handling:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph uses a two-tier system to handle failures gracefully:\n")
 		fmt.Fprintf(os.Stderr, "  \n")
@@ -529,6 +1014,36 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "  retry    - Retry the feature with enhanced guidance (default)\n")
 		fmt.Fprintf(os.Stderr, "  skip     - Skip the feature and move to the next one\n")
 		fmt.Fprintf(os.Stderr, "  rollback - Revert changes via git and retry fresh\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Spacing out retries:\n")
+		fmt.Fprintf(os.Stderr, "    -retry-backoff <duration>      Wait before the first retry of a feature (then x multiplier)\n")
+		fmt.Fprintf(os.Stderr, "    -retry-backoff-max <duration>  Never wait longer than this between retries (default: 5m)\n")
+		fmt.Fprintf(os.Stderr, "    -retry-budget <n>              Skip failing features once the run has used n retries\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Hung agents:\n")
+		fmt.Fprintf(os.Stderr, "    -iteration-timeout <duration>  Kill the agent (and anything it started) after this long\n")
+		fmt.Fprintf(os.Stderr, "    -timeout-retry                 Re-run a timed-out iteration once with \"be concise\" guidance\n")
+		fmt.Fprintf(os.Stderr, "  Timeouts are recorded as 'timeout' failures and handled by the recovery strategy.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Rate limits:\n")
+		fmt.Fprintf(os.Stderr, "    -min-iteration-interval <dur>  Start agent runs at least this far apart\n")
+		fmt.Fprintf(os.Stderr, "    -rate-limit-backoff <duration> Wait before retrying a rate-limited agent (default: 30s, doubled per retry)\n")
+		fmt.Fprintf(os.Stderr, "    -rate-limit-retries <n>        Give up on a rate-limited agent after n waits (default: 5)\n")
+		fmt.Fprintf(os.Stderr, "  Rate limits (HTTP 429, \"too many requests\", ...) are waited out, not recorded as failures.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Restore points (saved in git before each feature and iteration):\n")
+		fmt.Fprintf(os.Stderr, "    -rollback-feature <id>         Restore the tree to before feature <id> was started\n")
+		fmt.Fprintf(os.Stderr, "    -rollback-iteration <n>        Restore the tree to before iteration <n> of the latest run\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Flaky tests (fail, then pass with no related change):\n")
+		fmt.Fprintf(os.Stderr, "    -flaky-file <path>             Where flaky tests are tracked (default: .ralph/flaky.json)\n")
+		fmt.Fprintf(os.Stderr, "    ralph flaky list               Show the quarantined flaky tests\n")
+		fmt.Fprintf(os.Stderr, "    ralph flaky unmark [<test>]    Stop quarantining a test (all tests if none given)\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Failed test details (named in the retry guidance instead of raw logs):\n")
+		fmt.Fprintf(os.Stderr, "    -test-report <path>            JUnit XML file or directory, Jest JSON, or go test -json output\n")
+		fmt.Fprintf(os.Stderr, "  go test -json and jest --json results in the test output are used directly; Gradle and\n")
+		fmt.Fprintf(os.Stderr, "  Maven reports are found in build/test-results/test and target/surefire-reports.\n")
 		fmt.Fprintf(os.Stderr, "\nEnvironment Detection:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph automatically detects the execution environment and adapts:\n")
 		fmt.Fprintf(os.Stderr, "  - CI environments: longer timeouts, verbose output by default\n")
@@ -538,11 +1053,18 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    github-actions, gitlab-ci, jenkins, circleci, travis-ci, azure-devops\n")
 		fmt.Fprintf(os.Stderr, "  \n")
 		fmt.Fprintf(os.Stderr, "  Override with -environment flag or config file.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  CI reports (-environment-reports, written to -report-dir at the end of a run):\n")
+		fmt.Fprintf(os.Stderr, "    gitlab-ci  junit.xml and ralph.env (artifacts:reports:junit and :dotenv)\n")
+		fmt.Fprintf(os.Stderr, "    jenkins    junit.xml, ralph.properties and [ralph] BUILD_DESCRIPTION/BUILD_RESULT lines\n")
 		fmt.Fprintf(os.Stderr, "\nOutput Options:\n")
 		fmt.Fprintf(os.Stderr, "  -no-color      Disable colored output (auto-disabled in non-TTY)\n")
 		fmt.Fprintf(os.Stderr, "  -quiet, -q     Minimal output (errors only)\n")
 		fmt.Fprintf(os.Stderr, "  -json-output   Machine-readable JSON output\n")
 		fmt.Fprintf(os.Stderr, "  -log-level     Log verbosity: debug, info, warn, error (default: info)\n")
+		fmt.Fprintf(os.Stderr, "  -stream        Stream agent output live while the agent runs\n")
+		fmt.Fprintf(os.Stderr, "  -notify        Desktop notification when the run finishes, a milestone completes,\n")
+		fmt.Fprintf(os.Stderr, "                 or approval is needed (osascript, notify-send or toast; not in CI)\n")
 		fmt.Fprintf(os.Stderr, "\nMemory System:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph remembers architectural decisions and conventions across sessions.\n")
 		fmt.Fprintf(os.Stderr, "  Memories are stored in %s (configurable with -memory-file).\n", config.DefaultMemoryFile)
@@ -568,16 +1090,26 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    -milestone <name>    Show features for a specific milestone\n")
 		fmt.Fprintf(os.Stderr, "\nNudge System:\n")
 		fmt.Fprintf(os.Stderr, "  Nudges provide lightweight mid-run guidance without stopping execution.\n")
-		fmt.Fprintf(os.Stderr, "  Create/edit nudges.json during a run to steer Ralph.\n")
+		fmt.Fprintf(os.Stderr, "  Create/edit nudges.json during a run to steer Ralph, or type n and press\n")
+		fmt.Fprintf(os.Stderr, "  Enter in the terminal running Ralph to type a nudge.\n")
 		fmt.Fprintf(os.Stderr, "  \n")
 		fmt.Fprintf(os.Stderr, "  Nudge types:\n")
 		fmt.Fprintf(os.Stderr, "    focus      - Prioritize a specific feature or approach\n")
 		fmt.Fprintf(os.Stderr, "    skip       - Defer a feature or skip certain work\n")
 		fmt.Fprintf(os.Stderr, "    constraint - Add a requirement or limitation\n")
 		fmt.Fprintf(os.Stderr, "    style      - Specify coding style preferences\n")
+		fmt.Fprintf(os.Stderr, "    checkpoint - Create a named checkpoint before the next iteration\n")
 		fmt.Fprintf(os.Stderr, "  \n")
 		fmt.Fprintf(os.Stderr, "  Commands:\n")
 		fmt.Fprintf(os.Stderr, "    -nudge <type:content>  Add a one-time nudge\n")
+		fmt.Fprintf(os.Stderr, "    -nudge <feature:<id>:type:content>\n")
+		fmt.Fprintf(os.Stderr, "    -nudge <milestone:<name>:type:content>\n")
+		fmt.Fprintf(os.Stderr, "                           Add a nudge that applies while the feature or\n")
+		fmt.Fprintf(os.Stderr, "                           milestone is worked on, until it is complete\n")
+		fmt.Fprintf(os.Stderr, "    -nudge-sticky          With -nudge, keep the nudge after it is injected\n")
+		fmt.Fprintf(os.Stderr, "    -nudge-ttl <ttl>       With -nudge, expire the nudge after a duration (2h)\n")
+		fmt.Fprintf(os.Stderr, "                           or a number of iterations (3)\n")
+		fmt.Fprintf(os.Stderr, "    -nudge-list            List active nudges with their remaining lifetime\n")
 		fmt.Fprintf(os.Stderr, "    -show-nudges           Display current nudges\n")
 		fmt.Fprintf(os.Stderr, "    -clear-nudges          Clear all nudges\n")
 		fmt.Fprintf(os.Stderr, "    -nudge-file <path>     Use custom nudge file\n")
@@ -587,7 +1119,9 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "  Options:\n")
 		fmt.Fprintf(os.Stderr, "    -scope-limit <n>       Max iterations per feature (0 = unlimited)\n")
 		fmt.Fprintf(os.Stderr, "    -deadline <duration>   Time limit for the run (e.g., '1h', '30m', '2h30m')\n")
+		fmt.Fprintf(os.Stderr, "    -feature-deadline <duration>  Time budget per feature (e.g., '20m')\n")
 		fmt.Fprintf(os.Stderr, "    -list-deferred         List features that have been deferred\n")
+		fmt.Fprintf(os.Stderr, "    -forecast              Estimate the iterations and time left, per milestone\n")
 		fmt.Fprintf(os.Stderr, "  \n")
 		fmt.Fprintf(os.Stderr, "  When a feature exceeds its iteration limit or the deadline is reached,\n")
 		fmt.Fprintf(os.Stderr, "  Ralph automatically defers the feature and moves to the next one.\n")
@@ -600,6 +1134,8 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    -replan                Manually trigger replanning\n")
 		fmt.Fprintf(os.Stderr, "    -replan-strategy       Strategy: incremental, agent, none (default: incremental)\n")
 		fmt.Fprintf(os.Stderr, "    -replan-threshold <n>  Consecutive failures before replanning (default: 3)\n")
+		fmt.Fprintf(os.Stderr, "    -replan-confirm        Show a replan's changes and ask before applying them\n")
+		fmt.Fprintf(os.Stderr, "    -yes                   Apply replans without asking (with -replan-confirm)\n")
 		fmt.Fprintf(os.Stderr, "    -list-versions         List plan backup versions\n")
 		fmt.Fprintf(os.Stderr, "    -restore-version <n>   Restore a specific plan version\n")
 		fmt.Fprintf(os.Stderr, "  \n")
@@ -618,6 +1154,7 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    cli_command    - Verify CLI command executes successfully\n")
 		fmt.Fprintf(os.Stderr, "    file_exists    - Verify file exists with expected content\n")
 		fmt.Fprintf(os.Stderr, "    output_contains - Verify output contains expected pattern\n")
+		fmt.Fprintf(os.Stderr, "    coverage       - Verify test coverage meets a minimum (options: {\"min\": 80})\n")
 		fmt.Fprintf(os.Stderr, "  \n")
 		fmt.Fprintf(os.Stderr, "  Add validations to plan.json features:\n")
 		fmt.Fprintf(os.Stderr, "    {\n")
@@ -630,6 +1167,18 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "  Commands:\n")
 		fmt.Fprintf(os.Stderr, "    -validate              Run validations for all completed features\n")
 		fmt.Fprintf(os.Stderr, "    -validate-feature <id> Validate a specific feature\n")
+		fmt.Fprintf(os.Stderr, "    -validate-on-complete  During a run, validate features as the agent marks them tested;\n")
+		fmt.Fprintf(os.Stderr, "                           those that fail are unmarked and the agent is told why\n")
+		fmt.Fprintf(os.Stderr, "    -ci-gate               Exit non-zero if the plan file has schema errors, a tested feature's\n")
+		fmt.Fprintf(os.Stderr, "                           validations fail, or more features are untested than at the end\n")
+		fmt.Fprintf(os.Stderr, "                           of the last recorded run (for PR status checks)\n")
+		fmt.Fprintf(os.Stderr, "    -report-dir <dir>      Where validation-report.json is written (default: .ralph/reports)\n")
+		fmt.Fprintf(os.Stderr, "    -report-html           Also write validation-report.html for CI artifacts\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Coverage gate:\n")
+		fmt.Fprintf(os.Stderr, "    -coverage-gate 80%%     Features stay untested while coverage is below 80%%\n")
+		fmt.Fprintf(os.Stderr, "                           (or below its starting value, if that was lower)\n")
+		fmt.Fprintf(os.Stderr, "    -coverage-cmd <cmd>    Coverage command (default depends on the build system)\n")
 		fmt.Fprintf(os.Stderr, "\nGoal-Oriented Planning:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph can decompose high-level goals into actionable plans using AI.\n")
 		fmt.Fprintf(os.Stderr, "  \n")
@@ -651,6 +1200,13 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    -goal-priority <n>        Set priority for the goal (default: 5)\n")
 		fmt.Fprintf(os.Stderr, "    -decompose-goal <id>      Decompose a specific goal into plan items\n")
 		fmt.Fprintf(os.Stderr, "    -decompose-all            Decompose all pending goals\n")
+		fmt.Fprintf(os.Stderr, "    -redecompose-goal <id>    Regenerate a goal's untested plan items\n")
+		fmt.Fprintf(os.Stderr, "      -feedback <text>        What to change about the previous items\n")
+		fmt.Fprintf(os.Stderr, "    -edit-goal <id>           Edit a goal as JSON in $VISUAL or $EDITOR\n")
+		fmt.Fprintf(os.Stderr, "    -set-goal-priority <id:n> Change a goal's priority\n")
+		fmt.Fprintf(os.Stderr, "    -complete-goal <id>       Mark a goal complete\n")
+		fmt.Fprintf(os.Stderr, "    -remove-goal <id>         Remove a goal (its plan items are kept)\n")
+		fmt.Fprintf(os.Stderr, "    -goals -json-output       List goals with progress as JSON, for scripts\n")
 		fmt.Fprintf(os.Stderr, "    -goals-file <path>        Use custom goals file\n")
 		fmt.Fprintf(os.Stderr, "\nMulti-Agent Collaboration:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph supports multi-agent collaboration for parallel AI coordination.\n")
@@ -676,6 +1232,7 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    -agents <path>            Path to agents configuration file\n")
 		fmt.Fprintf(os.Stderr, "    -parallel-agents <n>      Maximum parallel agents (default: 2)\n")
 		fmt.Fprintf(os.Stderr, "    -list-agents              List configured agents\n")
+		fmt.Fprintf(os.Stderr, "    -review-gate              Require reviewer agent approval before features stay tested\n")
 		fmt.Fprintf(os.Stderr, "\nPlan Analysis & Refinement:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph can analyze and refine your plan.json for better organization.\n")
 		fmt.Fprintf(os.Stderr, "  \n")
@@ -691,6 +1248,20 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "    -analyze-plan          Analyze plan, show suggestions, write preview file\n")
 		fmt.Fprintf(os.Stderr, "    -refine-plan           Apply refinements (modifies plan.json)\n")
 		fmt.Fprintf(os.Stderr, "    -refine-plan -dry-run  Preview what -refine-plan would do (no changes)\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Features with more than -max-prompt-steps steps (default: %d) are shown to\n", config.DefaultMaxPromptSteps)
+		fmt.Fprintf(os.Stderr, "  the agent truncated; plan.json keeps every step. -list-untested flags them.\n")
+		fmt.Fprintf(os.Stderr, "  -progress-tail N adds the last N progress entries to each iteration prompt.\n")
+		fmt.Fprintf(os.Stderr, "  -feature-prompt shows the agent only the next untested feature instead of the plan.\n")
+		fmt.Fprintf(os.Stderr, "  -dry-run (without -refine-plan) prints the config, next feature and prompt of a run\n")
+		fmt.Fprintf(os.Stderr, "  without invoking the agent.\n")
+		fmt.Fprintf(os.Stderr, "\nQuestion Features:\n")
+		fmt.Fprintf(os.Stderr, "  Unresolved requirements are plan items with \"type\": \"question\" (emitted by\n")
+		fmt.Fprintf(os.Stderr, "  -generate-plan when the notes are unclear). Runs skip them until they are answered.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Commands:\n")
+		fmt.Fprintf(os.Stderr, "    question list                  List open questions\n")
+		fmt.Fprintf(os.Stderr, "    question answer <id> \"<text>\" Answer a question, turning it into an actionable feature\n")
 		fmt.Fprintf(os.Stderr, "\nCodebase Baselining:\n")
 		fmt.Fprintf(os.Stderr, "  Ralph can analyze your codebase to understand its structure and patterns.\n")
 		fmt.Fprintf(os.Stderr, "  The baseline provides context-aware guidance to AI agents.\n")
@@ -704,37 +1275,177 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "  Commands:\n")
 		fmt.Fprintf(os.Stderr, "    -baseline              Scan codebase and create baseline.json\n")
 		fmt.Fprintf(os.Stderr, "    -show-baseline         Display current baseline summary\n")
+		fmt.Fprintf(os.Stderr, "    -baseline-drift        Report files changed outside Ralph's iterations since the baseline\n")
 		fmt.Fprintf(os.Stderr, "    -baseline-file <path>  Use custom baseline file (default: baseline.json)\n")
 		fmt.Fprintf(os.Stderr, "    -use-baseline=false    Disable baseline context in prompts\n")
+		fmt.Fprintf(os.Stderr, "    -no-impact-analysis    Don't list the files the current feature will likely touch\n")
 		fmt.Fprintf(os.Stderr, "  \n")
 		fmt.Fprintf(os.Stderr, "  The baseline is automatically used in iterations when baseline.json exists.\n")
+		fmt.Fprintf(os.Stderr, "  Files matching the words of the current feature are listed in the prompt, and\n")
+		fmt.Fprintf(os.Stderr, "  after each iteration Ralph reports which of them were actually changed.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Staleness:\n")
+		fmt.Fprintf(os.Stderr, "    -context-max-age <days>     Warn when the baseline or memories are older (default: %d)\n", config.DefaultContextMaxAge)
+		fmt.Fprintf(os.Stderr, "    -context-max-changes <n>    Warn when more files changed since (default: %d)\n", config.DefaultContextMaxChanges)
+		fmt.Fprintf(os.Stderr, "    -refresh-stale-context      Re-scan a stale baseline before the run (or after -baseline-drift)\n")
+		fmt.Fprintf(os.Stderr, "\nRun History & Reports:\n")
+		fmt.Fprintf(os.Stderr, "  Every run is recorded in the history directory (default: .ralph/history)\n")
+		fmt.Fprintf(os.Stderr, "  with features completed, failures, iterations per feature, and cost.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Commands:\n")
+		fmt.Fprintf(os.Stderr, "    report list                    List recorded runs\n")
+		fmt.Fprintf(os.Stderr, "    report compare <run-a> <run-b> Compare two runs side by side\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Runs can be referenced by ID, unique ID prefix, -run-label, 'latest', or 'previous'.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  -analytics aggregates the whole history per feature category: failure rate, average\n")
+		fmt.Fprintf(os.Stderr, "  iterations by complexity, validation pass rate, failure types and deferral causes.\n")
+		fmt.Fprintf(os.Stderr, "  Add -json-output to track the numbers over time.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  -changelog prints the completed features by milestone and category, dated from the\n")
+		fmt.Fprintf(os.Stderr, "  progress log (-changelog-format keepachangelog for Keep a Changelog releases).\n")
+		fmt.Fprintf(os.Stderr, "  With -changelog-file, runs rewrite the file whenever a milestone completes.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  -junit-output <file> writes each iteration (or, with -validate, each validation)\n")
+		fmt.Fprintf(os.Stderr, "  as a JUnit XML test case, for the test summaries of GitHub Actions, GitLab and Jenkins.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  -summary-markdown <file> writes the end-of-run summary (features completed, iterations,\n")
+		fmt.Fprintf(os.Stderr, "  failures, validations, deferred features, milestones) as Markdown for a PR description.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  The changes of every iteration are saved as a patch in the diff directory\n")
+		fmt.Fprintf(os.Stderr, "  (default: .ralph/diffs/<run-id>/iteration-NNN.patch).\n")
+		fmt.Fprintf(os.Stderr, "    -show-iteration-diff N         Print the patch of iteration N of the latest run\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  With -transcript, every prompt sent to the agent and its full response are appended\n")
+		fmt.Fprintf(os.Stderr, "  to .ralph/transcripts/<run-id>.jsonl for compliance review. Matches of the\n")
+		fmt.Fprintf(os.Stderr, "  transcript_redact patterns (config file) are replaced with [REDACTED].\n")
+		fmt.Fprintf(os.Stderr, "    -show-transcript <run>         Print the transcript of a run ('latest', ID or prefix)\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Progress messages are also recorded as JSON lines next to the progress file\n")
+		fmt.Fprintf(os.Stderr, "  (progress.txt -> progress.jsonl) with their type, feature and iteration.\n")
+		fmt.Fprintf(os.Stderr, "    -progress-query \"feature=3 type=failure,coverage since=24h\"\n")
+		fmt.Fprintf(os.Stderr, "                                   Print matching events ('all' for every event,\n")
+		fmt.Fprintf(os.Stderr, "                                   since takes a duration, date or RFC3339 time)\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  With -telemetry, anonymized counts (features per run, failure rates, recovery\n")
+		fmt.Fprintf(os.Stderr, "  strategy usage) are added to a local aggregate (default: .ralph/telemetry.json).\n")
+		fmt.Fprintf(os.Stderr, "  Nothing leaves the machine unless you export and share it.\n")
+		fmt.Fprintf(os.Stderr, "    telemetry show                 Show the aggregated statistics\n")
+		fmt.Fprintf(os.Stderr, "    telemetry export [file]        Export the statistics as a JSON report\n")
+		fmt.Fprintf(os.Stderr, "    telemetry reset                Delete the aggregated statistics\n")
+		fmt.Fprintf(os.Stderr, "\nCheckpoints:\n")
+		fmt.Fprintf(os.Stderr, "  Snapshot the plan, progress, memory, nudges, goals and git state so you can roll back\n")
+		fmt.Fprintf(os.Stderr, "  to a known point. Uncommitted tracked changes are captured without touching the tree.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Commands:\n")
+		fmt.Fprintf(os.Stderr, "    checkpoint \"<label>\"        Create a checkpoint\n")
+		fmt.Fprintf(os.Stderr, "    checkpoint list              List checkpoints\n")
+		fmt.Fprintf(os.Stderr, "    checkpoint restore <ref>     Restore a checkpoint (current state is checkpointed first)\n")
+		fmt.Fprintf(os.Stderr, "    checkpoint delete <ref>      Delete a checkpoint\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  During a run, add a nudge to checkpoint before the next iteration:\n")
+		fmt.Fprintf(os.Stderr, "    %s -nudge \"checkpoint:before refactor\"\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nAPI Server:\n")
+		fmt.Fprintf(os.Stderr, "  ralph serve starts a local HTTP API to start, stop, pause and resume runs, query\n")
+		fmt.Fprintf(os.Stderr, "  their status, add nudges and memories, trigger replans and stream events (SSE).\n")
+		fmt.Fprintf(os.Stderr, "  Flags given before 'serve' apply to the runs it starts. Clients must send\n")
+		fmt.Fprintf(os.Stderr, "  'Authorization: Bearer <token>' with the token it prints, or %s if set.\n", server.TokenEnv)
+		fmt.Fprintf(os.Stderr, "    serve [address]                Listen on address (default: %s)\n", server.DefaultAddr)
+		fmt.Fprintf(os.Stderr, "\nMCP Server:\n")
+		fmt.Fprintf(os.Stderr, "  ralph mcp serves Model Context Protocol tools on stdin/stdout: list_features,\n")
+		fmt.Fprintf(os.Stderr, "  get_next_feature, mark_tested, validate_feature, list_memories, add_memory, add_nudge.\n")
+		fmt.Fprintf(os.Stderr, "    mcp                            Serve the tools (flags before 'mcp' select the files)\n")
+		fmt.Fprintf(os.Stderr, "\nIssue Tracker Sync:\n")
+		fmt.Fprintf(os.Stderr, "  -sync-tracker syncs the plan with the tracker of the config file's tracker section\n")
+		fmt.Fprintf(os.Stderr, "  (github, jira or linear): open issues become features, their labels the category,\n")
+		fmt.Fprintf(os.Stderr, "  and issues whose feature is tested are commented on and closed.\n")
+		fmt.Fprintf(os.Stderr, "\nAPI Backend:\n")
+		fmt.Fprintf(os.Stderr, "  With -backend openai or -backend anthropic, Ralph calls the HTTP API directly\n")
+		fmt.Fprintf(os.Stderr, "  instead of running an agent CLI. Files referenced in the prompt are inlined, and the\n")
+		fmt.Fprintf(os.Stderr, "  model writes changes back as <file path=\"...\"> blocks. Token usage is recorded\n")
+		fmt.Fprintf(os.Stderr, "  in run history; set api_input_cost/api_output_cost in the config file for cost.\n")
+		fmt.Fprintf(os.Stderr, "\nA/B Experiments:\n")
+		fmt.Fprintf(os.Stderr, "  With -experiment, Ralph alternates between variant A (-agent, -experiment-prompt-a)\n")
+		fmt.Fprintf(os.Stderr, "  and variant B (-experiment-agent-b, -experiment-prompt-b) across iterations\n")
+		fmt.Fprintf(os.Stderr, "  (-experiment-split alternate) or plan halves (-experiment-split halves).\n")
+		fmt.Fprintf(os.Stderr, "  Each variant is recorded in run history and compared at the end of the run.\n")
+		fmt.Fprintf(os.Stderr, "\nParallel Features:\n")
+		fmt.Fprintf(os.Stderr, "  With -parallel N, up to N features not blocked by milestone dependencies are worked\n")
+		fmt.Fprintf(os.Stderr, "  on at the same time, each by its own Ralph process in its own git worktree for up to\n")
+		fmt.Fprintf(os.Stderr, "  -iterations iterations. Completed features are applied to your checkout one at a\n")
+		fmt.Fprintf(os.Stderr, "  time and marked tested; worker output is saved under .ralph/parallel.\n")
+		fmt.Fprintf(os.Stderr, "\nSafety:\n")
+		fmt.Fprintf(os.Stderr, "  Before each iteration Ralph snapshots credential and shell startup files outside\n")
+		fmt.Fprintf(os.Stderr, "  the repository (SSH keys, ~/.aws/credentials, ~/.bashrc, ...). If the agent changes\n")
+		fmt.Fprintf(os.Stderr, "  them, or writes through a symlink that resolves outside the repository, the\n")
+		fmt.Fprintf(os.Stderr, "  iteration is aborted, its changes are reverted, and an error is raised. Watch more\n")
+		fmt.Fprintf(os.Stderr, "  locations with -guard-paths; disable with -no-path-guard.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  With -isolated-worktree, the whole run happens in a temporary git worktree copied\n")
+		fmt.Fprintf(os.Stderr, "  from your checkout. Changes are applied back (uncommitted) only after the type check\n")
+		fmt.Fprintf(os.Stderr, "  and tests pass there; otherwise the worktree is kept for inspection.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  With -approve, Ralph shows a diff stat after each iteration and waits for you to\n")
+		fmt.Fprintf(os.Stderr, "  approve (y), reject (n), view the full diff (d) or edit the files (e). Approved\n")
+		fmt.Fprintf(os.Stderr, "  iterations are checked with the type check and test commands; rejected ones are\n")
+		fmt.Fprintf(os.Stderr, "  rolled back and the agent is told why.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  A policy file (ralph-policy.yaml, or -policy) bounds what Ralph may do: maximum\n")
+		fmt.Fprintf(os.Stderr, "  cost per run, allowed commands, protected paths, plan categories that require\n")
+		fmt.Fprintf(os.Stderr, "  review, and forbidden dependencies. Iterations that break it are rolled back.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Writes to the plan and progress files take an advisory lock (<file>.lock), so\n")
+		fmt.Fprintf(os.Stderr, "  concurrent Ralph processes do not clobber each other. Locks of processes that died\n")
+		fmt.Fprintf(os.Stderr, "  are taken over; -force also takes over locks held by running processes.\n")
+		fmt.Fprintf(os.Stderr, "  \n")
+		fmt.Fprintf(os.Stderr, "  Secrets (cloud and API keys, tokens, private keys, passwords in URLs and assignments,\n")
+		fmt.Fprintf(os.Stderr, "  the API backend's key, and -agent-env values from env:/file: references) are masked\n")
+		fmt.Fprintf(os.Stderr, "  as [REDACTED] in prompts, progress entries, memories and transcripts. Add patterns\n")
+		fmt.Fprintf(os.Stderr, "  with redact_patterns in the config file; disable with -no-redact.\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  %s -version                         # Show version information\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -iterations 5                    # Run 5 iterations (auto-detect build system)\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -iterations 5 -build-system gradle  # Use Gradle preset\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -config my-config.yaml           # Use specific config file\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -C ../api -iterations 5          # Run in another project without cd'ing\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -iterations 5 -agent-env ANTHROPIC_API_KEY=env:TEAM_KEY  # Credentials for the agent only\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -status                          # Dashboard of plan, milestones, goals, last run, nudges, memory\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -list-all                        # Show all features (tested and untested)\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -list-tested                     # List tested features\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -list-untested                   # List untested features\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -show-feature 5                  # Everything about feature 5: steps, goal, runs, last failure\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -add-feature \"Password reset\" -category auth -steps \"Email link;Expire link\"  # Append a feature\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -mark-untested 5                 # Reopen feature 5 (the plan is backed up first)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -merge-plan branch.json -merge-similarity 0.8  # Merge another plan, skipping near-duplicates\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -defer 7 -reason \"needs design\" # Defer feature 7\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -milestones                      # Show milestone progress\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -milestone Alpha                 # Show features for 'Alpha' milestone\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -generate-plan -notes notes.md   # Generate plan.json from notes\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -generate-plan -notes notes.md -output my-plan.json  # Custom output file\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -generate-plan -from-issue acme/shop#42  # Generate plan.json from a GitHub issue\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -generate-plan -notes notes.md -no-agent  # Scaffold plan.json from the notes' lists, offline\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -show-memory                     # Display stored memories\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -add-memory \"decision:Use PostgreSQL for persistence\"  # Add a memory\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -clear-memory                    # Clear all memories\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -export-memory team-memory.json  # Export memories to share them\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -import-memory team-memory.json -merge  # Merge shared memories\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -edit-memory \"mem_1712:Use PostgreSQL 16\"  # Edit a memory by ID\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -pin-memory mem_1712              # Never prune a memory\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -add-memory \"convention:Prefer table tests\" -global  # Add a personal memory for all projects\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -nudge \"focus:Work on feature 5 first\"  # Add a one-time nudge\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -show-nudges                     # Display current nudges\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -clear-nudges                    # Clear all nudges\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -iterations 5 -scope-limit 3     # Max 3 iterations per feature\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -iterations 10 -deadline 2h      # 2 hour time limit\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -list-deferred                   # Show deferred features\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -list-all -json-output           # Plan as JSON; exits 0 if all tested, 3 if not, 4 if deferred\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -iterations 5 -auto-replan       # Enable automatic replanning\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -replan -replan-strategy agent   # Manually trigger agent-based replanning\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -list-versions                   # Show plan backup versions\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -restore-version 2               # Restore plan version 2\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -validate                        # Run validations for completed features\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -validate-feature 5              # Validate specific feature\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -ci-gate                         # Fail the build if the plan regressed\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -goal \"Add user authentication with OAuth\"  # Add and decompose goal\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -goals                           # Show all goals with progress\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -decompose-goal auth             # Decompose specific goal\n", os.Args[0])
@@ -746,22 +1457,29 @@ func parseFlags() *config.Config {
 		fmt.Fprintf(os.Stderr, "  %s -baseline                        # Analyze codebase and create baseline.json\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -show-baseline                   # Display baseline summary\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -iterations 5 -use-baseline=false # Run without baseline context\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -iterations 5 -run-label claude  # Label this run for later comparison\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s report list                      # List recorded runs\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s report compare previous latest   # Compare the last two runs\n", os.Args[0])
 	}
 
 	flag.Parse()
 
+	// Change to the -C directory first, so that the config file, the plan and
+	// every other relative path are resolved in it
+	if cfg.WorkDir != "" {
+		if err := os.Chdir(cfg.WorkDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot change to directory %s: %v\n", cfg.WorkDir, err)
+			os.Exit(1)
+		}
+	}
+
 	// Load configuration file (if specified or auto-discovered)
 	cfg.ConfigFile = configFile
 	loadConfigFile(cfg)
 
 	// Apply build system configuration
 	detection.ApplyBuildSystemConfig(cfg)
-
-	// Handle deprecated -status flag
-	if cfg.ListStatus {
-		fmt.Fprintf(os.Stderr, "Warning: -status is deprecated. Use -list-all instead.\n")
-		cfg.ListAll = true
-	}
+	detection.ApplyLintConfig(cfg)
 
 	// Handle deprecated goal-related flags
 	if cfg.ListGoals {
@@ -776,50 +1494,228 @@ func parseFlags() *config.Config {
 	return cfg
 }
 
-// loadConfigFile loads and applies configuration from a file.
-// Priority: CLI flags > config file > defaults
+// loadConfigFile loads and applies configuration from a file and RALPH_*
+// environment variables.
+// Priority: CLI flags > environment variables > config file > defaults
 func loadConfigFile(cfg *config.Config) {
-	var configPath string
-
-	if cfg.ConfigFile != "" {
-		// Explicit config file specified
-		configPath = cfg.ConfigFile
-	} else {
-		// Auto-discover config file
-		configPath = config.DiscoverConfigFile()
-	}
-
-	if configPath == "" {
-		return
-	}
-
 	// Store which flags were explicitly set on command line
 	explicitFlags := make(map[string]bool)
 	flag.Visit(func(f *flag.Flag) {
 		explicitFlags[f.Name] = true
 	})
 
-	fileCfg, err := config.LoadConfigFile(configPath)
-	if err != nil {
-		if cfg.ConfigFile != "" {
-			// Only warn if config file was explicitly specified
-			fmt.Fprintf(os.Stderr, "Warning: failed to load config file %s: %v\n", configPath, err)
+	// -profile, or RALPH_PROFILE, selects a profile of the config file
+	if cfg.Profile == "" {
+		cfg.Profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+	configPath, fileCfg := readConfigFile(cfg, true)
+	if cfg.Profile != "" {
+		profileCfg, err := configProfile(configPath, fileCfg, cfg.Profile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		fileCfg = profileCfg
+	}
+	envCfg, envKeys, err := config.OverlayEnv(fileCfg)
+	if err == nil {
+		err = validateFileConfig(envCfg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s* environment variables: %v\n", config.EnvPrefix, err)
+		os.Exit(1)
+	}
+	if envCfg == nil {
 		return
 	}
 
+	// Apply the config, but only for values not explicitly set via CLI
+	applyFileConfigWithPrecedence(cfg, envCfg, explicitFlags)
+
+	if cfg.Verbose {
+		if fileCfg != nil {
+			fmt.Printf("Loaded configuration from: %s\n", configPath)
+		}
+		if cfg.Profile != "" {
+			fmt.Printf("Using profile: %s\n", cfg.Profile)
+		}
+		for _, key := range envKeys {
+			fmt.Printf("Loaded configuration from: %s\n", config.EnvVarName(key))
+		}
+	}
+}
+
+// configProfile overlays a profile of the config file at configPath on its
+// other settings. A profile that does not exist is an error, so a run does
+// not silently go ahead without the settings it asked for.
+func configProfile(configPath string, fileCfg *config.FileConfig, profile string) (*config.FileConfig, error) {
+	if fileCfg == nil {
+		if configPath == "" {
+			return nil, fmt.Errorf("profile %q: no config file found", profile)
+		}
+		return nil, fmt.Errorf("profile %q: config file %s cannot be used", profile, configPath)
+	}
+	profileCfg, err := fileCfg.WithProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if err := validateFileConfig(profileCfg); err != nil {
+		return nil, fmt.Errorf("%s: invalid profile %q: %w", configPath, profile, err)
+	}
+	return profileCfg, nil
+}
+
+// readConfigFile loads the config file given with -config, or else the one
+// discovered. It returns the path ("" if there is none) and the file's
+// config, or nil if there is no valid config file. Problems are reported if
+// warn is set.
+func readConfigFile(cfg *config.Config, warn bool) (string, *config.FileConfig) {
+	configPath := cfg.ConfigFile
+	if configPath == "" {
+		// Auto-discover config file
+		configPath = config.DiscoverConfigFile()
+	}
+	if configPath == "" {
+		return "", nil
+	}
+
+	fileCfg, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		if warn && cfg.ConfigFile != "" {
+			// Only warn if config file was explicitly specified
+			fmt.Fprintf(os.Stderr, "Warning: failed to load config file %s: %v\n", configPath, err)
+		}
+		return configPath, nil
+	}
+
 	// Validate config file
+	if err := validateFileConfig(fileCfg); err != nil {
+		if warn {
+			fmt.Fprintf(os.Stderr, "Warning: invalid config file %s: %v\n", configPath, err)
+		}
+		return configPath, nil
+	}
+	return configPath, fileCfg
+}
+
+// validateFileConfig validates a config file, including its agents section,
+// which the config package cannot check without importing multiagent
+func validateFileConfig(fileCfg *config.FileConfig) error {
 	if err := config.ValidateFileConfig(fileCfg); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: invalid config file %s: %v\n", configPath, err)
-		return
+		return err
+	}
+	if len(fileCfg.Agents) > 0 {
+		if _, err := multiagent.ParseAgentsSection(fileCfg.Agents, fileCfg.ParallelAgents); err != nil {
+			return fmt.Errorf("agents: %w", err)
+		}
 	}
+	return nil
+}
 
-	// Apply file config, but only for values not explicitly set via CLI
-	applyFileConfigWithPrecedence(cfg, fileCfg, explicitFlags)
+// settingFlags maps the config file keys whose flag is not named after the
+// key to the flag
+var settingFlags = map[string]string{
+	"agents_file":        "agents",
+	"enable_multi_agent": "multi-agent",
+	"policy_file":        "policy",
+}
 
-	if cfg.Verbose {
-		fmt.Printf("Loaded configuration from: %s\n", configPath)
+// handleExplainConfig prints every config file key with its effective value
+// and where the value comes from, following the precedence loadConfigFile
+// applies
+func handleExplainConfig(cfg *config.Config) error {
+	explicitFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+	// -v and -q are short for -verbose and -quiet
+	explicitFlags["verbose"] = explicitFlags["verbose"] || explicitFlags["v"]
+	explicitFlags["quiet"] = explicitFlags["quiet"] || explicitFlags["q"]
+
+	configPath, fileCfg := readConfigFile(cfg, false)
+	fileSource := config.FileConfigSource(configPath)
+	switch {
+	case configPath == "":
+		fmt.Println("Config file: none")
+	case fileCfg == nil:
+		fmt.Printf("Config file: %s (%s, not applied: it cannot be read or is invalid)\n", configPath, fileSource)
+	default:
+		fmt.Printf("Config file: %s (%s)\n", configPath, fileSource)
+	}
+	inProfile := make(map[string]bool)
+	if cfg.Profile != "" {
+		profileCfg, err := configProfile(configPath, fileCfg, cfg.Profile)
+		if err != nil {
+			return err
+		}
+		for _, key := range fileCfg.ProfileKeys(cfg.Profile) {
+			inProfile[key] = true
+		}
+		fileCfg = profileCfg
+		fmt.Printf("Profile: %s\n", cfg.Profile)
+	}
+	mergedCfg, envKeys, err := config.OverlayEnv(fileCfg)
+	if err == nil {
+		err = validateFileConfig(mergedCfg)
+	}
+	if err != nil {
+		fmt.Printf("Environment: %s* variables not applied: %v\n", config.EnvPrefix, err)
+		mergedCfg, envKeys = fileCfg, nil
+	}
+	fmt.Println()
+
+	fileValues, err := config.FileConfigValues(fileCfg)
+	if err != nil {
+		return err
+	}
+	values, err := config.FileConfigValues(mergedCfg)
+	if err != nil {
+		return err
+	}
+	inEnv := make(map[string]bool, len(envKeys))
+	for _, key := range envKeys {
+		inEnv[key] = true
+	}
+
+	var settings []config.Setting
+	for _, key := range config.FileConfigKeys() {
+		name, ok := settingFlags[key]
+		if !ok {
+			name = strings.ReplaceAll(key, "_", "-")
+		}
+		f := flag.Lookup(name)
+		_, inFile := fileValues[key]
+
+		setting := config.Setting{Key: key, Source: config.SourceDefault}
+		switch {
+		case f != nil && explicitFlags[name]:
+			setting.Source = config.SourceFlag
+		case inEnv[key]:
+			setting.Source = config.SourceEnv
+		case inProfile[key]:
+			setting.Source = config.ProfileSource(fileSource, cfg.Profile)
+		case inFile:
+			setting.Source = fileSource
+		case f != nil && f.Value.String() != f.DefValue:
+			setting.Source = config.SourceDerived
+		}
+		switch {
+		case key == "agent_env":
+			// Values may be secrets
+			setting.Value = strings.Join(agent.EnvNames(cfg.AgentEnv), ", ")
+		case key == "tracker" && cfg.Tracker != nil:
+			// The token may be a secret
+			setting.Value = cfg.Tracker.Provider + " " + cfg.Tracker.Project
+		case f != nil:
+			setting.Value = f.Value.String()
+		default:
+			setting.Value = values[key]
+		}
+		setting.Value = secretRedactor.Redact(setting.Value)
+		settings = append(settings, setting)
 	}
+	fmt.Print(config.FormatSettings(settings))
+	return nil
 }
 
 // applyFileConfigWithPrecedence applies file config values only when
@@ -828,6 +1724,20 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.Agent != "" && !explicitFlags["agent"] {
 		cfg.AgentCmd = fileCfg.Agent
 	}
+	// Per-variable merge: -agent-env flags override config file entries with the same name
+	config.MergeAgentEnv(cfg, fileCfg.AgentEnv)
+	if len(fileCfg.FallbackAgents) > 0 && !explicitFlags["fallback-agents"] {
+		cfg.FallbackAgents = fileCfg.FallbackAgents
+	}
+	if fileCfg.RotateAgents && !explicitFlags["rotate-agents"] {
+		cfg.RotateAgents = fileCfg.RotateAgents
+	}
+	if fileCfg.HealthCheck && !explicitFlags["health-check"] {
+		cfg.HealthCheck = fileCfg.HealthCheck
+	}
+	if fileCfg.HealthCheckPrompt != "" && !explicitFlags["health-check-prompt"] {
+		cfg.HealthCheckPrompt = fileCfg.HealthCheckPrompt
+	}
 	if fileCfg.BuildSystem != "" && !explicitFlags["build-system"] {
 		cfg.BuildSystem = fileCfg.BuildSystem
 	}
@@ -837,6 +1747,30 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.Test != "" && !explicitFlags["test"] {
 		cfg.TestCmd = fileCfg.Test
 	}
+	if fileCfg.Lint && !explicitFlags["lint"] {
+		cfg.Lint = fileCfg.Lint
+	}
+	if fileCfg.LintCmd != "" && !explicitFlags["lint-cmd"] {
+		cfg.LintCmd = fileCfg.LintCmd
+	}
+	if fileCfg.TestReport != "" && !explicitFlags["test-report"] {
+		cfg.TestReport = fileCfg.TestReport
+	}
+	if fileCfg.CoverageGate != "" && !explicitFlags["coverage-gate"] {
+		cfg.CoverageGate = fileCfg.CoverageGate
+	}
+	if fileCfg.CoverageCmd != "" && !explicitFlags["coverage-cmd"] {
+		cfg.CoverageCmd = fileCfg.CoverageCmd
+	}
+	if fileCfg.ValidateOnComplete && !explicitFlags["validate-on-complete"] {
+		cfg.ValidateOnComplete = true
+	}
+	if fileCfg.ReportDir != "" && !explicitFlags["report-dir"] {
+		cfg.ReportDir = fileCfg.ReportDir
+	}
+	if fileCfg.ReportHTML && !explicitFlags["report-html"] {
+		cfg.ReportHTML = true
+	}
 	if fileCfg.Plan != "" && !explicitFlags["plan"] {
 		cfg.PlanFile = fileCfg.Plan
 	}
@@ -855,9 +1789,43 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.RecoveryStrategy != "" && !explicitFlags["recovery-strategy"] {
 		cfg.RecoveryStrategy = fileCfg.RecoveryStrategy
 	}
+	if fileCfg.IterationTimeout != "" && !explicitFlags["iteration-timeout"] {
+		cfg.IterationTimeout = fileCfg.IterationTimeout
+	}
+	if fileCfg.TimeoutRetry && !explicitFlags["timeout-retry"] {
+		cfg.TimeoutRetry = fileCfg.TimeoutRetry
+	}
+	if fileCfg.RetryBackoff != "" && !explicitFlags["retry-backoff"] {
+		cfg.RetryBackoff = fileCfg.RetryBackoff
+	}
+	if fileCfg.RetryBackoffMultiplier > 0 && !explicitFlags["retry-backoff-multiplier"] {
+		cfg.RetryBackoffMultiplier = fileCfg.RetryBackoffMultiplier
+	}
+	if fileCfg.RetryBackoffMax != "" && !explicitFlags["retry-backoff-max"] {
+		cfg.RetryBackoffMax = fileCfg.RetryBackoffMax
+	}
+	if fileCfg.RetryJitter != nil && !explicitFlags["retry-jitter"] {
+		cfg.RetryJitter = *fileCfg.RetryJitter
+	}
+	if fileCfg.RetryBudget > 0 && !explicitFlags["retry-budget"] {
+		cfg.RetryBudget = fileCfg.RetryBudget
+	}
+	if fileCfg.MinIterationInterval != "" && !explicitFlags["min-iteration-interval"] {
+		cfg.MinIterationInterval = fileCfg.MinIterationInterval
+	}
+	if fileCfg.RateLimitBackoff != "" && !explicitFlags["rate-limit-backoff"] {
+		cfg.RateLimitBackoff = fileCfg.RateLimitBackoff
+	}
+	if fileCfg.RateLimitRetries != nil && !explicitFlags["rate-limit-retries"] {
+		cfg.RateLimitRetries = *fileCfg.RateLimitRetries
+	}
+	cfg.FailurePatterns = fileCfg.FailurePatterns
 	if fileCfg.Environment != "" && !explicitFlags["environment"] {
 		cfg.Environment = fileCfg.Environment
 	}
+	if fileCfg.EnvironmentReports && !explicitFlags["environment-reports"] {
+		cfg.EnvironmentReports = fileCfg.EnvironmentReports
+	}
 	// UI settings
 	if fileCfg.NoColor && !explicitFlags["no-color"] {
 		cfg.NoColor = fileCfg.NoColor
@@ -871,10 +1839,19 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.LogLevel != "" && !explicitFlags["log-level"] {
 		cfg.LogLevel = fileCfg.LogLevel
 	}
+	if fileCfg.Stream && !explicitFlags["stream"] {
+		cfg.Stream = fileCfg.Stream
+	}
+	if fileCfg.Notify && !explicitFlags["notify"] {
+		cfg.Notify = fileCfg.Notify
+	}
 	// Memory settings
 	if fileCfg.MemoryFile != "" && !explicitFlags["memory-file"] {
 		cfg.MemoryFile = fileCfg.MemoryFile
 	}
+	if fileCfg.NoGlobalMemory && !explicitFlags["no-global-memory"] {
+		cfg.NoGlobalMemory = fileCfg.NoGlobalMemory
+	}
 	if fileCfg.MemoryRetention > 0 && !explicitFlags["memory-retention"] {
 		cfg.MemoryRetention = fileCfg.MemoryRetention
 	}
@@ -882,6 +1859,23 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.NudgeFile != "" && !explicitFlags["nudge-file"] {
 		cfg.NudgeFile = fileCfg.NudgeFile
 	}
+	if fileCfg.NoNudgeConsole && !explicitFlags["no-nudge-console"] {
+		cfg.NoNudgeConsole = fileCfg.NoNudgeConsole
+	}
+	// Prompt settings
+	if fileCfg.MaxPromptSteps != nil && !explicitFlags["max-prompt-steps"] {
+		cfg.MaxPromptSteps = *fileCfg.MaxPromptSteps
+	}
+	if fileCfg.ProgressTail > 0 && !explicitFlags["progress-tail"] {
+		cfg.ProgressTail = fileCfg.ProgressTail
+	}
+	if fileCfg.FeaturePrompt && !explicitFlags["feature-prompt"] {
+		cfg.FeaturePrompt = fileCfg.FeaturePrompt
+	}
+	// Prompt templates can only be set in the config file
+	cfg.IterationPromptTemplate = fileCfg.IterationPromptTemplate
+	cfg.PlanPromptTemplate = fileCfg.PlanPromptTemplate
+	cfg.GoalPromptTemplate = fileCfg.GoalPromptTemplate
 	// Scope control settings
 	if fileCfg.ScopeLimit > 0 && !explicitFlags["scope-limit"] {
 		cfg.ScopeLimit = fileCfg.ScopeLimit
@@ -889,16 +1883,35 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.Deadline != "" && !explicitFlags["deadline"] {
 		cfg.Deadline = fileCfg.Deadline
 	}
+	if fileCfg.FeatureDeadline != "" && !explicitFlags["feature-deadline"] {
+		cfg.FeatureDeadline = fileCfg.FeatureDeadline
+	}
+	if fileCfg.NoScopeCalibration && !explicitFlags["no-scope-calibration"] {
+		cfg.NoScopeCalibration = fileCfg.NoScopeCalibration
+	}
 	// Replan settings
 	if fileCfg.AutoReplan && !explicitFlags["auto-replan"] {
 		cfg.AutoReplan = fileCfg.AutoReplan
 	}
+	if fileCfg.ReplanConfirm && !explicitFlags["replan-confirm"] {
+		cfg.ReplanConfirm = fileCfg.ReplanConfirm
+	}
 	if fileCfg.ReplanStrategy != "" && !explicitFlags["replan-strategy"] {
 		cfg.ReplanStrategy = fileCfg.ReplanStrategy
 	}
 	if fileCfg.ReplanThreshold > 0 && !explicitFlags["replan-threshold"] {
 		cfg.ReplanThreshold = fileCfg.ReplanThreshold
 	}
+	// Plan backup settings
+	if fileCfg.PlanVersionDir != "" && !explicitFlags["plan-version-dir"] {
+		cfg.PlanVersionDir = fileCfg.PlanVersionDir
+	}
+	if fileCfg.MaxPlanVersions > 0 && !explicitFlags["max-plan-versions"] {
+		cfg.MaxPlanVersions = fileCfg.MaxPlanVersions
+	}
+	if fileCfg.CompressPlanVersions && !explicitFlags["compress-plan-versions"] {
+		cfg.CompressPlanVersions = fileCfg.CompressPlanVersions
+	}
 	// Goals settings
 	if fileCfg.GoalsFile != "" && !explicitFlags["goals-file"] {
 		cfg.GoalsFile = fileCfg.GoalsFile
@@ -913,664 +1926,5009 @@ func applyFileConfigWithPrecedence(cfg *config.Config, fileCfg *config.FileConfi
 	if fileCfg.EnableMultiAgent && !explicitFlags["multi-agent"] {
 		cfg.EnableMultiAgent = fileCfg.EnableMultiAgent
 	}
+	if len(fileCfg.Agents) > 0 {
+		cfg.Agents = fileCfg.Agents
+	}
+	if fileCfg.ReviewGate && !explicitFlags["review-gate"] {
+		cfg.ReviewGate = fileCfg.ReviewGate
+	}
+	// Context staleness settings
+	if fileCfg.ContextMaxAge != nil && !explicitFlags["context-max-age"] {
+		cfg.ContextMaxAge = *fileCfg.ContextMaxAge
+	}
+	if fileCfg.ContextMaxChanges != nil && !explicitFlags["context-max-changes"] {
+		cfg.ContextMaxChanges = *fileCfg.ContextMaxChanges
+	}
+	if fileCfg.RefreshStaleContext && !explicitFlags["refresh-stale-context"] {
+		cfg.RefreshStaleContext = fileCfg.RefreshStaleContext
+	}
+	if fileCfg.NoImpactAnalysis && !explicitFlags["no-impact-analysis"] {
+		cfg.NoImpactAnalysis = fileCfg.NoImpactAnalysis
+	}
+	// Run history settings
+	if fileCfg.HistoryDir != "" && !explicitFlags["history-dir"] {
+		cfg.HistoryDir = fileCfg.HistoryDir
+	}
+	if fileCfg.JUnitOutput != "" && !explicitFlags["junit-output"] {
+		cfg.JUnitOutput = fileCfg.JUnitOutput
+	}
+	if fileCfg.SummaryMarkdown != "" && !explicitFlags["summary-markdown"] {
+		cfg.SummaryMarkdown = fileCfg.SummaryMarkdown
+	}
+	// Changelog settings
+	if fileCfg.ChangelogFile != "" && !explicitFlags["changelog-file"] {
+		cfg.ChangelogFile = fileCfg.ChangelogFile
+	}
+	if fileCfg.ChangelogFormat != "" && !explicitFlags["changelog-format"] {
+		cfg.ChangelogFormat = fileCfg.ChangelogFormat
+	}
+	// Iteration diff settings
+	if fileCfg.DiffDir != "" && !explicitFlags["diff-dir"] {
+		cfg.DiffDir = fileCfg.DiffDir
+	}
+	// Transcript settings
+	if fileCfg.Transcript && !explicitFlags["transcript"] {
+		cfg.Transcript = fileCfg.Transcript
+	}
+	if fileCfg.TranscriptDir != "" && !explicitFlags["transcript-dir"] {
+		cfg.TranscriptDir = fileCfg.TranscriptDir
+	}
+	cfg.TranscriptRedact = fileCfg.TranscriptRedact
+	// Telemetry settings
+	if fileCfg.Telemetry && !explicitFlags["telemetry"] {
+		cfg.Telemetry = fileCfg.Telemetry
+	}
+	if fileCfg.TelemetryFile != "" && !explicitFlags["telemetry-file"] {
+		cfg.TelemetryFile = fileCfg.TelemetryFile
+	}
+	// Checkpoint settings
+	if fileCfg.CheckpointDir != "" && !explicitFlags["checkpoint-dir"] {
+		cfg.CheckpointDir = fileCfg.CheckpointDir
+	}
+	// Flaky test settings
+	if fileCfg.FlakyFile != "" && !explicitFlags["flaky-file"] {
+		cfg.FlakyFile = fileCfg.FlakyFile
+	}
+	// API backend settings
+	if fileCfg.Backend != "" && !explicitFlags["backend"] {
+		cfg.AgentBackend = fileCfg.Backend
+	}
+	if fileCfg.APIBaseURL != "" && !explicitFlags["api-base-url"] {
+		cfg.APIBaseURL = fileCfg.APIBaseURL
+	}
+	if fileCfg.APIModel != "" && !explicitFlags["api-model"] {
+		cfg.APIModel = fileCfg.APIModel
+	}
+	if fileCfg.APIKeyEnv != "" && !explicitFlags["api-key-env"] {
+		cfg.APIKeyEnv = fileCfg.APIKeyEnv
+	}
+	if fileCfg.APIMaxTokens > 0 && !explicitFlags["api-max-tokens"] {
+		cfg.APIMaxTokens = fileCfg.APIMaxTokens
+	}
+	if fileCfg.APIInputCost > 0 {
+		cfg.APIInputCost = fileCfg.APIInputCost
+	}
+	if fileCfg.APIOutputCost > 0 {
+		cfg.APIOutputCost = fileCfg.APIOutputCost
+	}
+	// Experiment settings
+	if fileCfg.Experiment && !explicitFlags["experiment"] {
+		cfg.Experiment = fileCfg.Experiment
+	}
+	if fileCfg.ExperimentAgentB != "" && !explicitFlags["experiment-agent-b"] {
+		cfg.ExperimentAgentB = fileCfg.ExperimentAgentB
+	}
+	if fileCfg.ExperimentPromptA != "" && !explicitFlags["experiment-prompt-a"] {
+		cfg.ExperimentPromptA = fileCfg.ExperimentPromptA
+	}
+	if fileCfg.ExperimentPromptB != "" && !explicitFlags["experiment-prompt-b"] {
+		cfg.ExperimentPromptB = fileCfg.ExperimentPromptB
+	}
+	if fileCfg.ExperimentSplit != "" && !explicitFlags["experiment-split"] {
+		cfg.ExperimentSplit = fileCfg.ExperimentSplit
+	}
+	// Safety settings
+	if fileCfg.NoPathGuard && !explicitFlags["no-path-guard"] {
+		cfg.NoPathGuard = fileCfg.NoPathGuard
+	}
+	if fileCfg.IsolatedWorktree && !explicitFlags["isolated-worktree"] {
+		cfg.IsolatedWorktree = fileCfg.IsolatedWorktree
+	}
+	if fileCfg.Parallel > 0 && !explicitFlags["parallel"] {
+		cfg.Parallel = fileCfg.Parallel
+	}
+	if fileCfg.Hooks != nil {
+		cfg.Hooks = *fileCfg.Hooks
+	}
+	if fileCfg.Tracker != nil {
+		cfg.Tracker = fileCfg.Tracker
+	}
+	if fileCfg.Approve && !explicitFlags["approve"] {
+		cfg.Approve = fileCfg.Approve
+	}
+	if fileCfg.PolicyFile != "" && !explicitFlags["policy"] {
+		cfg.PolicyFile = fileCfg.PolicyFile
+	}
+	if len(fileCfg.GuardPaths) > 0 && !explicitFlags["guard-paths"] {
+		cfg.GuardPaths = strings.Join(fileCfg.GuardPaths, ",")
+	}
+	if fileCfg.NoRedact && !explicitFlags["no-redact"] {
+		cfg.NoRedact = fileCfg.NoRedact
+	}
+	cfg.RedactPatterns = fileCfg.RedactPatterns
 }
 
-func validateConfig(cfg *config.Config) error {
-	// Skip validation for generate-plan (handled separately)
-	if cfg.GeneratePlan {
-		if cfg.NotesFile == "" {
-			return fmt.Errorf("notes file is required with -generate-plan (use -notes flag)")
-		}
-		notesPath := strings.TrimSpace(cfg.NotesFile)
-		if notesPath == "" {
-			return fmt.Errorf("notes file path cannot be empty")
-		}
-		if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-			return fmt.Errorf("notes file not found: %s", notesPath)
-		}
-		// Check if agent command exists
-		if _, err := exec.LookPath(cfg.AgentCmd); err != nil {
-			return fmt.Errorf("agent command not found in PATH: %s", cfg.AgentCmd)
+// checkAgentAvailable verifies the configured agent can be reached: the agent
+// command must be in PATH for the CLI backend, while API backends need a model
+// and an API key
+func checkAgentAvailable(cfg *config.Config) error {
+	if _, err := agent.ResolveEnv(cfg.AgentEnv); err != nil {
+		return fmt.Errorf("invalid agent environment: %w", err)
+	}
+	if cfg.UsesAPIBackend() {
+		if !llm.IsProvider(cfg.AgentBackend) {
+			return fmt.Errorf("invalid backend %q: must be one of cli, openai, or anthropic", cfg.AgentBackend)
 		}
-		return nil
+		return agent.CheckAPIBackend(cfg)
+	}
+	if _, err := exec.LookPath(cfg.AgentCmd); err != nil {
+		return fmt.Errorf("agent command not found in PATH: %s", cfg.AgentCmd)
 	}
+	return nil
+}
 
-	// Skip iteration validation if we're just listing status or milestones
-	if cfg.ListAll || cfg.ListTested || cfg.ListUntested || cfg.ListMilestones || cfg.ShowMilestone != "" || cfg.ListDeferred {
-		if _, err := os.Stat(cfg.PlanFile); os.IsNotExist(err) {
-			return fmt.Errorf("plan file not found: %s", cfg.PlanFile)
+// checkFallbackAgents checks that the fallback agent commands are installed
+func checkFallbackAgents(cfg *config.Config) error {
+	for _, cmd := range cfg.FallbackAgents {
+		if _, err := exec.LookPath(cmd); err != nil {
+			return fmt.Errorf("fallback agent command not found in PATH: %s", cmd)
 		}
-		return nil
 	}
+	return nil
+}
 
-	if cfg.Iterations <= 0 {
-		return fmt.Errorf("iterations must be a positive integer (use -iterations flag)")
+// checkAgentHealth sends the health check prompt to each agent, so a run
+// fails fast on an agent that is not logged in or cannot reach its API
+// instead of spending its first iteration finding out
+func checkAgentHealth(cfg *config.Config, output *ui.UI, agents []*config.Config) error {
+	for _, agentCfg := range agents {
+		output.Info("Health check: %s", agentName(agentCfg))
+		if err := agent.HealthCheck(context.Background(), agentCfg, cfg.HealthCheckPrompt); err != nil {
+			return fmt.Errorf("agent %s failed the health check: %w\nCheck that the agent is installed, logged in, and can reach its API", agentName(agentCfg), err)
+		}
 	}
+	output.Success("Agent health check passed")
+	return nil
+}
 
-	if _, err := os.Stat(cfg.PlanFile); os.IsNotExist(err) {
-		return fmt.Errorf("plan file not found: %s", cfg.PlanFile)
+// executeWithRateLimit runs the agents like executeWithFallback, waiting with
+// backoff and trying again while the provider rate limits them, so throttling
+// is not recorded as a failure of the feature
+func executeWithRateLimit(cfg *config.Config, pool *agent.Pool, output *ui.UI, iterPrompt string) (string, *config.Config, error) {
+	backoff := rateLimitBackoff(cfg)
+	for retry := 1; ; retry++ {
+		result, served, err := executeWithFallback(cfg, pool, output, iterPrompt)
+		if retry > cfg.RateLimitRetries || !agent.IsRateLimited(result, err) {
+			return result, served, err
+		}
+		delay := backoff.Delay(retry)
+		output.Warn("Agent %s is rate limited - waiting %s before trying again (%d/%d)", agentName(served), delay.Round(time.Second), retry, cfg.RateLimitRetries)
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("RATE LIMITED: agent %s, waiting %s before trying again", agentName(served), delay.Round(time.Second)))
+		time.Sleep(delay)
 	}
+}
 
-	// Check if agent command exists
-	if _, err := exec.LookPath(cfg.AgentCmd); err != nil {
-		return fmt.Errorf("agent command not found in PATH: %s", cfg.AgentCmd)
+// executeWithFallback runs the agent like executeAgent, moving on to the next
+// agent of the pool when one errors or times out. It returns the agent that
+// produced the result, which is the last agent if all of them failed.
+func executeWithFallback(cfg *config.Config, pool *agent.Pool, output *ui.UI, iterPrompt string) (string, *config.Config, error) {
+	agents := pool.Order(cfg)
+	for j, agentCfg := range agents[:len(agents)-1] {
+		result, err := executeAgent(agentCfg, output, iterPrompt)
+		if err == nil || errors.Is(err, agent.ErrInterrupted) {
+			return result, agentCfg, err
+		}
+		next := agentName(agents[j+1])
+		output.Warn("Agent %s failed, falling back to %s: %v", agentName(agentCfg), next, err)
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("FALLBACK: agent %s failed, falling back to %s", agentName(agentCfg), next))
 	}
+	last := agents[len(agents)-1]
+	result, err := executeAgent(last, output, iterPrompt)
+	return result, last, err
+}
 
-	// Validate recovery strategy
-	if _, err := recovery.ParseStrategyType(cfg.RecoveryStrategy); err != nil {
-		return err
+// executeAgent runs the agent, streaming its output through the UI as it is
+// produced when -stream is enabled
+func executeAgent(cfg *config.Config, output *ui.UI, iterPrompt string) (string, error) {
+	if !cfg.Stream {
+		return agent.Execute(cfg, iterPrompt)
 	}
 
-	// Validate max retries
-	if cfg.MaxRetries < 0 {
-		return fmt.Errorf("max-retries cannot be negative")
-	}
+	stdoutW := output.NewStreamWriter()
+	stderrW := output.NewStreamWriter()
+	result, err := agent.ExecuteStream(cfg, iterPrompt, stdoutW, stderrW)
+	stdoutW.Flush()
+	stderrW.Flush()
+	return result, err
+}
 
-	// Validate scope limit
-	if cfg.ScopeLimit < 0 {
-		return fmt.Errorf("scope-limit cannot be negative")
+// hookOutputLines is the number of lines of a failed hook's output shown
+const hookOutputLines = 20
+
+// newHookRunner creates the runner of the lifecycle hooks, or returns nil if
+// none are configured. Hook commands must be allowed by the policy.
+func newHookRunner(cfg *config.Config, pol *policy.Policy) (*hooks.Runner, error) {
+	runner := hooks.New(map[hooks.Event][]string{
+		hooks.PreIteration:  cfg.Hooks.PreIteration,
+		hooks.PostIteration: cfg.Hooks.PostIteration,
+		hooks.OnFailure:     cfg.Hooks.OnFailure,
+		hooks.OnComplete:    cfg.Hooks.OnComplete,
+
+		hooks.OnMilestoneComplete: cfg.Hooks.OnMilestoneComplete,
+	}, cfg.HookTimeoutDuration())
+	for _, event := range []hooks.Event{hooks.PreIteration, hooks.PostIteration, hooks.OnFailure, hooks.OnComplete, hooks.OnMilestoneComplete} {
+		for _, command := range runner.Commands(event) {
+			if err := pol.CheckCommand(command); err != nil {
+				return nil, fmt.Errorf("%s hook %q: %w", event, command, err)
+			}
+		}
 	}
+	return runner, nil
+}
 
-	// Validate deadline format
-	if cfg.Deadline != "" {
-		if _, err := config.ParseDeadline(cfg.Deadline); err != nil {
-			return fmt.Errorf("invalid deadline format: %w", err)
+// runHooks runs the hooks of an event. Failing hooks are reported but do not
+// stop the run.
+func runHooks(cfg *config.Config, output *ui.UI, runner *hooks.Runner, event hooks.Event, c hooks.Context) {
+	if !runner.Has(event) {
+		return
+	}
+	c.Event = event
+	for _, r := range runner.Run(c) {
+		if r.Err != nil {
+			output.Warn("%s hook %q failed: %v", event, r.Command, r.Err)
+			if out := strings.TrimSpace(r.Output); out != "" {
+				output.Print("%s", lastLines(out, hookOutputLines))
+			}
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("HOOK: %s hook %q failed: %v", event, r.Command, r.Err))
+			continue
+		}
+		output.Debug("%s hook %q finished in %s", event, r.Command, r.Duration.Round(time.Millisecond))
+		if out := strings.TrimSpace(r.Output); out != "" && cfg.Verbose {
+			output.Print("%s", out)
 		}
 	}
+}
 
-	return nil
+// runPostIterationHooks runs the post_iteration hooks with the iteration's result
+func runPostIterationHooks(cfg *config.Config, output *ui.UI, runner *hooks.Runner, c hooks.Context, result, reason string) {
+	c.Result = result
+	c.Error = reason
+	runHooks(cfg, output, runner, hooks.PostIteration, c)
 }
 
-func runIterations(cfg *config.Config) error {
-	// Create UI instance
-	uiCfg := ui.OutputConfig{
-		NoColor:    cfg.NoColor,
-		Quiet:      cfg.Quiet,
-		JSONOutput: cfg.JSONOutput,
-		LogLevel:   ui.ParseLogLevel(cfg.LogLevel),
+// loadPolicy loads the policy file given with -policy, or ralph-policy.yaml in
+// the current directory if present. Returns nil if there is no policy.
+func loadPolicy(cfg *config.Config) (*policy.Policy, error) {
+	path := cfg.PolicyFile
+	if path == "" {
+		if path = policy.Discover("."); path == "" {
+			return nil, nil
+		}
 	}
-	output := ui.New(uiCfg)
-
-	// Start timing for summary
-	startTime := time.Now()
+	return policy.Load(path)
+}
 
-	// Detect environment
-	var envProfile *environment.EnvironmentProfile
-	if cfg.Environment != "" {
-		envType := environment.ParseEnvironmentType(cfg.Environment)
-		envProfile = environment.ForceEnvironment(envType)
-	} else {
-		envProfile = environment.Detect()
+// recordIterationDiff saves the changes an iteration made as a patch and
+// reports a one-line summary of them
+func recordIterationDiff(output *ui.UI, store *diffs.Store, runID string, iteration int, snap *recovery.Snapshot) {
+	patch, err := snap.Patch()
+	if err != nil {
+		output.Debug("Failed to compute diff of iteration %d: %v", iteration, err)
+		return
 	}
-
-	// Apply environment-based recommendations if not explicitly set
-	if !cfg.Verbose && envProfile.RecommendedVerbose {
-		cfg.Verbose = true
+	path, err := store.Save(runID, iteration, patch)
+	if err != nil {
+		output.Debug("Failed to record diff of iteration %d: %v", iteration, err)
+		return
 	}
-
-	// Auto-disable colors in CI if not explicitly set
-	if envProfile.CIEnvironment && !cfg.NoColor {
-		// Keep colors in CI for modern terminals that support it
+	if patch == "" {
+		output.Debug("Iteration %d made no file changes", iteration)
+		return
 	}
+	stat, _ := snap.ShortStat()
+	output.Info("Changes: %s (ralph -show-iteration-diff %d, saved to %s)", strings.TrimSpace(stat), iteration, path)
+}
 
-	// Load memory store
-	memStore := memory.NewStore(cfg.MemoryFile)
-	memStore.SetRetentionDays(cfg.MemoryRetention)
-	if err := memStore.Load(); err != nil {
-		output.Warn("Failed to load memory: %v", err)
+// saveRestorePoints saves the snapshot as the restore point of the iteration
+// and, when the feature is started for the first time, of the feature
+func saveRestorePoints(output *ui.UI, snap *recovery.Snapshot, iteration, featureID int) {
+	label := fmt.Sprintf("before iteration %d", iteration)
+	if featureID > 0 {
+		label += fmt.Sprintf(" (feature #%d)", featureID)
 	}
-
-	// Prune expired memories
-	pruned, _ := memStore.Prune()
-	if pruned > 0 && cfg.Verbose {
-		output.Debug("Pruned %d expired memories", pruned)
+	if err := snap.Save(recovery.IterationRef(iteration), label); err != nil {
+		output.Debug("Failed to save restore point: %v", err)
+		return
 	}
-
-	// Load nudge store
-	nudgeStore := nudge.NewStore(cfg.NudgeFile)
-	if err := nudgeStore.Load(); err != nil {
-		output.Debug("No nudge file loaded: %v", err)
+	if featureID > 0 && !recovery.HasRestorePoint(recovery.FeatureRef(featureID)) {
+		if err := snap.Save(recovery.FeatureRef(featureID), fmt.Sprintf("before feature #%d", featureID)); err != nil {
+			output.Debug("Failed to save restore point: %v", err)
+		}
 	}
+}
 
-	// Load baseline if it exists and use-baseline is enabled
-	var baselineData *baseline.Baseline
-	if cfg.UseBaseline {
-		if data, err := baseline.Load(cfg.BaselineFile); err == nil {
-			baselineData = data
-			if cfg.Verbose {
-				output.Debug("Loaded baseline from %s (%d files, %d lines)",
-					cfg.BaselineFile, baselineData.TotalFiles, baselineData.TotalLines)
-			}
-		}
+// policyViolations checks the changes made since the snapshot against the policy
+func policyViolations(pol *policy.Policy, snap *recovery.Snapshot) []policy.Violation {
+	files, err := snap.ChangedFiles()
+	if err != nil {
+		return []policy.Violation{{Rule: "changes", Detail: fmt.Sprintf("failed to list changed files: %v", err)}}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	diff, err := snap.Diff()
+	if err != nil {
+		return []policy.Violation{{Rule: "changes", Detail: fmt.Sprintf("failed to diff changes: %v", err)}}
 	}
+	return pol.CheckChanges(files, diff)
+}
 
-	output.Header("Ralph - Iterative Development Workflow")
-	output.Info("Plan file: %s", cfg.PlanFile)
-	output.Info("Progress file: %s", cfg.ProgressFile)
-	output.Info("Iterations: %d", cfg.Iterations)
-	output.Info("Agent command: %s", cfg.AgentCmd)
-	output.Info("Recovery strategy: %s (max %d retries)", cfg.RecoveryStrategy, cfg.MaxRetries)
-	if memStore.Count() > 0 {
-		output.Info("Memory: %d entries loaded from %s", memStore.Count(), cfg.MemoryFile)
+// featureCategory returns the plan category of the feature, or empty string if unknown
+func featureCategory(planFile string, id int) string {
+	plans, err := plan.ReadFile(planFile)
+	if err != nil {
+		return ""
 	}
-	if nudgeStore.ActiveCount() > 0 {
-		output.Info("Nudges: %d active nudge(s) from %s", nudgeStore.ActiveCount(), cfg.NudgeFile)
+	if p := plan.GetByID(plans, id); p != nil {
+		return p.Category
+	}
+	return ""
+}
+
+// featureIterationLimit returns the iteration budget the plan gives the
+// feature (max_iterations), or 0 if it has none
+func featureIterationLimit(planFile string, id int) int {
+	plans, err := plan.ReadFile(planFile)
+	if err != nil {
+		return 0
 	}
+	if p := plan.GetByID(plans, id); p != nil {
+		return p.MaxIterations
+	}
+	return 0
+}
+
+// addPromptContext fills in the context sections of an iteration prompt:
+// baseline, memories, recent progress, nudges and blocked features. It
+// returns the nudges and memories it injected.
+func addPromptContext(cfg *config.Config, output *ui.UI, data *prompt.IterationData, baselineData *baseline.Baseline,
+	memStore *memory.Store, nudgeStore *nudge.Store, blocked map[int][]string) ([]nudge.Nudge, []memory.Entry) {
+	// Capture the nudges that apply to this iteration: unscoped ones and
+	// those scoped to the current feature or its milestone
+	activeNudges := nudgeStore.GetActiveFor(data.Feature)
+
+	// Inject baseline context (codebase structure and conventions) and the
+	// files the feature will likely touch
 	if baselineData != nil {
-		output.Info("Baseline: %d files analyzed (%s)", baselineData.TotalFiles, strings.Join(baselineData.TechStack.Languages, ", "))
+		data.Baseline = baselineData.BuildPromptContext()
+		data.Impact = featureImpact(cfg, baselineData, data.Feature).BuildPromptContext()
 	}
-	
-	// Load plans and create milestone manager
-	plans, planErr := plan.ReadFile(cfg.PlanFile)
-	var milestoneMgr *milestone.Manager
-	var completedMilestonesBefore map[string]bool
-	if planErr == nil {
-		milestoneMgr = milestone.NewManager(plans)
-		
-		// Record which milestones are complete before we start
-		completedMilestonesBefore = make(map[string]bool)
-		for _, p := range milestoneMgr.GetCompletedMilestones() {
-			completedMilestonesBefore[p.Milestone.Name] = true
-		}
-		
-		// Show milestone progress in verbose mode
-		if cfg.Verbose && milestoneMgr.HasMilestones() {
-			output.SubHeader("Milestone Progress")
-			for _, p := range milestoneMgr.CalculateAllProgress() {
-				output.Print("  %s", milestone.FormatProgress(p))
-			}
-		}
+
+	// Inject memory context: the memories of the current feature's category
+	// and the global ones, ranked by relevance to the feature
+	memoryCategory, memoryText := "", ""
+	if data.Feature != nil {
+		memoryCategory = data.Feature.Category
+		memoryText = data.Feature.Description + " " + strings.Join(data.Feature.Steps, " ")
 	}
-	
-	if cfg.Verbose {
-		output.Debug("Type check command: %s", cfg.TypeCheckCmd)
-		output.Debug("Test command: %s", cfg.TestCmd)
-		output.Print("")
-		output.Print("%s", envProfile.Summary())
+	injectedMemories := memStore.GetScoped(memoryCategory, memoryText, 10) // Get top 10 relevant memories
+	data.Memories = memory.FormatPromptContext(injectedMemories)
+
+	// Inject what previous iterations did
+	if cfg.ProgressTail > 0 {
+		if entries, err := progress.Tail(cfg.ProgressFile, cfg.ProgressTail); err != nil {
+			output.Debug("Not including recent progress: %v", err)
+		} else {
+			data.Progress = progress.BuildPromptContext(entries)
+		}
 	}
-	output.Print("")
 
-	// Initialize recovery manager
-	strategyType, _ := recovery.ParseStrategyType(cfg.RecoveryStrategy)
-	recoveryMgr := recovery.NewRecoveryManager(cfg.MaxRetries, strategyType)
+	// Inject nudge context
+	data.Nudges = nudge.FormatPromptContext(activeNudges)
 
-	// Initialize replan manager
-	replanMgr := replan.NewReplanManager(cfg.PlanFile, cfg.AgentCmd, cfg.AutoReplan)
-	replanStrategyType, _ := replan.ParseStrategyType(cfg.ReplanStrategy)
-	consecutiveFailures := 0
+	// Tell the agent which features wait for prerequisite milestones
+	data.Blocked = milestone.BuildBlockedPromptContext(blocked)
+	return activeNudges, injectedMemories
+}
 
-	// Initialize scope manager
-	scopeConstraints := &scope.Constraints{
-		MaxIterationsPerFeature: cfg.ScopeLimit,
-		AutoDefer:               true,
+// featureImpact predicts the files a feature will likely touch from the
+// baseline. It returns nil if there is no feature or prediction, or with
+// -no-impact-analysis.
+func featureImpact(cfg *config.Config, baselineData *baseline.Baseline, feature *plan.Plan) *baseline.Impact {
+	if cfg.NoImpactAnalysis || baselineData == nil || feature == nil {
+		return nil
 	}
-	scopeMgr := scope.NewManager(scopeConstraints)
+	text := feature.Description + " " + strings.Join(feature.Steps, " ")
+	return baselineData.PredictImpact(text, baseline.DefaultImpactFiles)
+}
 
-	// Set deadline if specified
-	if cfg.Deadline != "" {
-		deadline, _ := config.ParseDeadline(cfg.Deadline)
-		scopeMgr.SetDeadline(deadline)
+// maxReportedUnpredicted is the number of unpredicted changed files named in
+// impact reports
+const maxReportedUnpredicted = 10
+
+// reportImpact reports which of the predicted files of the feature the
+// iteration changed, leaving out Ralph's state files
+func reportImpact(cfg *config.Config, output *ui.UI, impact *baseline.Impact, snap *recovery.Snapshot, featureID int) {
+	if impact == nil {
+		return
+	}
+	files, err := snap.ChangedFiles()
+	if err != nil {
+		output.Debug("Not reporting impact: %v", err)
+		return
+	}
+	state := make(map[string]bool)
+	for _, f := range contextIgnore(cfg) {
+		state[filepath.Clean(f)] = true
+	}
+	var changed []string
+	for _, f := range files {
+		if !state[filepath.Clean(f)] {
+			changed = append(changed, f)
+		}
+	}
+	if len(changed) == 0 {
+		return
 	}
 
-	// Show scope info if scope control is enabled
-	if cfg.ScopeLimit > 0 || cfg.Deadline != "" {
-		output.Info("Scope control: %s", formatScopeInfo(cfg))
+	result := impact.Compare(changed)
+	msg := fmt.Sprintf("feature #%d: %d/%d predicted file(s) changed", featureID, len(result.Changed), result.Predicted)
+	if len(result.Changed) > 0 {
+		msg += " (" + strings.Join(result.Changed, ", ") + ")"
 	}
-	
-	// Show replan info if enabled
-	if cfg.AutoReplan {
-		output.Info("Auto-replan: enabled (strategy: %s, threshold: %d failures)", cfg.ReplanStrategy, cfg.ReplanThreshold)
+	if n := len(result.Unpredicted); n > 0 {
+		listed := result.Unpredicted
+		if n > maxReportedUnpredicted {
+			listed = listed[:maxReportedUnpredicted]
+		}
+		msg += fmt.Sprintf("; %d unpredicted: %s", n, strings.Join(listed, ", "))
+		if n > len(listed) {
+			msg += fmt.Sprintf(", ... and %d more", n-len(listed))
+		}
 	}
+	output.Info("Impact: %s", msg)
+	appendProgress(cfg.ProgressFile, "IMPACT: "+msg)
+}
 
-	// Track metrics for summary
-	var summary ui.Summary
-	summary.TotalIterations = cfg.Iterations
-	summary.StartTime = startTime
+// checkLint is the name of the lint check, whose failures recovery handles
+// as lint failures
+const checkLint = "Lint"
 
-	// Track the current feature being worked on (extracted from output if possible)
-	currentFeatureID := 0
-	currentFeatureSteps := 0
-	currentFeatureDesc := ""
-	var additionalPromptGuidance string
+// checkError is the failure of one of the checks Ralph runs
+type checkError struct {
+	check string
+	err   error
+}
 
-	for i := 1; i <= cfg.Iterations; i++ {
-		// Check deadline before starting iteration
-		if scopeMgr.IsDeadlineExceeded() {
-			output.Warn("Deadline exceeded - stopping execution")
-			break
-		}
+func (e *checkError) Error() string {
+	return fmt.Sprintf("%s failed: %v", e.check, e.err)
+}
 
-		// Get current feature from plans (first untested, non-deferred)
-		detectedFeatureID, detectedSteps, detectedDesc := extractCurrentFeatureFromPlans(cfg.PlanFile)
-		if detectedFeatureID > 0 && detectedFeatureID != currentFeatureID {
-			// New feature detected - start tracking it
-			currentFeatureID = detectedFeatureID
-			currentFeatureSteps = detectedSteps
-			currentFeatureDesc = detectedDesc
-			scopeMgr.StartFeature(currentFeatureID, currentFeatureSteps, currentFeatureDesc)
-			if cfg.Verbose {
-				complexity := scope.EstimateComplexity(currentFeatureSteps, currentFeatureDesc)
-				output.Debug("Working on feature #%d (%s complexity): %s", 
-					currentFeatureID, complexity, currentFeatureDesc)
-			}
+func (e *checkError) Unwrap() error {
+	return e.err
+}
+
+// isLintFailure reports whether err is a failure of the lint check
+func isLintFailure(err error) bool {
+	var ce *checkError
+	return errors.As(err, &ce) && ce.check == checkLint
+}
+
+// runChecks runs the type check, lint and test commands in the current
+// directory, stopping at the first failure and returning its output
+func runChecks(cfg *config.Config, pol *policy.Policy, output *ui.UI) (string, error) {
+	for _, check := range []struct{ name, command string }{
+		{"Type check", cfg.TypeCheckCmd},
+		{checkLint, cfg.LintCmd},
+		{"Tests", cfg.TestCmd},
+	} {
+		if out, err := runCheck(pol, output, check.name, check.command); err != nil {
+			return out, err
 		}
+	}
+	return "", nil
+}
 
-		output.Header("Iteration %d/%d", i, cfg.Iterations)
-		summary.IterationsRun = i
+// runCheck runs a single check command in the current directory, returning
+// its output if it fails. An empty command passes.
+func runCheck(pol *policy.Policy, output *ui.UI, name, command string) (string, error) {
+	cmd := proc.CommandLine(context.Background(), command)
+	if cmd == nil {
+		return "", nil
+	}
+	if err := pol.CheckCommand(command); err != nil {
+		return "", err
+	}
+	output.Info("%s: %s", name, command)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), &checkError{check: name, err: err}
+	}
+	return "", nil
+}
 
-		// Record iteration for scope tracking
-		scopeMgr.RecordIteration(currentFeatureID)
+// newPathGuard creates the guard against modifying files outside the repository,
+// or returns nil if it is disabled or cannot be set up
+func newPathGuard(cfg *config.Config, output *ui.UI) *guard.Guard {
+	if cfg.NoPathGuard {
+		return nil
+	}
 
-		// Check if current feature should be deferred
-		if shouldDefer, reason := scopeMgr.ShouldDefer(currentFeatureID); shouldDefer && currentFeatureID > 0 {
-			scopeMgr.DeferFeature(currentFeatureID, reason)
-			output.Warn("Feature #%d deferred: %s", currentFeatureID, scope.FormatDeferralReason(reason))
-			
-			// Mark feature as deferred in plan file
-			if err := markFeatureDeferred(cfg.PlanFile, currentFeatureID, string(reason)); err != nil {
-				output.Debug("Failed to update plan file: %v", err)
-			}
-			
-			// Log deferral to progress file
-			deferMsg := fmt.Sprintf("DEFERRED: Feature #%d - %s (iterations used: %d)", 
-				currentFeatureID, scope.FormatDeferralReason(reason), 
-				scopeMgr.GetFeatureScope(currentFeatureID).IterationsUsed)
-			appendProgress(cfg.ProgressFile, deferMsg)
-			
-			summary.FeaturesSkipped++
-			
-			// Reset current feature - agent will move to next
-			currentFeatureID = 0
-		}
+	root, err := os.Getwd()
+	if err != nil {
+		output.Warn("Path guard disabled: %v", err)
+		return nil
+	}
 
-		// Check for simplification suggestion
-		if currentFeatureID > 0 && scopeMgr.ShouldSuggestSimplification(currentFeatureID) && 
-			!scopeMgr.WasSimplificationSuggested(currentFeatureID) {
-			suggestions := scope.SuggestSimplification(currentFeatureSteps, currentFeatureDesc)
-			output.Warn("Feature #%d may be complex. Suggestions:", currentFeatureID)
-			for _, s := range suggestions {
-				output.Print("  - %s", s)
-			}
-			scopeMgr.MarkSimplificationSuggested(currentFeatureID)
+	var extra []string
+	for _, p := range strings.Split(cfg.GuardPaths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			extra = append(extra, p)
 		}
+	}
 
-		if cfg.Verbose {
-			output.Debug("Executing agent command...")
-			if cfg.ScopeLimit > 0 && currentFeatureID > 0 {
-				remaining := scopeMgr.RemainingIterations(currentFeatureID)
-				output.Debug("Scope: %d iterations remaining for current feature", remaining)
-			}
+	g, err := guard.New(root, extra)
+	if err != nil {
+		output.Warn("Path guard disabled: %v", err)
+		return nil
+	}
+	// Ralph's own state written during an iteration is kept when it is reverted
+	g.Ignore(".ralph/", cfg.ProgressFile, progress.JSONLPath(cfg.ProgressFile), cfg.MemoryFile, cfg.NudgeFile)
+	for _, dir := range []string{cfg.HistoryDir, cfg.DiffDir, cfg.TranscriptDir, cfg.ReportDir, cfg.CheckpointDir, cfg.PlanVersionDir} {
+		if dir != "" {
+			g.Ignore(filepath.Clean(dir) + "/")
 		}
+	}
+	if cfg.Verbose {
+		output.Debug("Path guard watching %d location(s) outside %s", len(g.Watched()), g.Root())
+	}
+	return g
+}
 
-		// Show spinner for agent execution if TTY
-		var spinner *ui.Spinner
-		if output.IsTTY() && !cfg.Quiet && !cfg.JSONOutput {
-			spinner = output.NewSpinner("Executing agent...")
-			spinner.Start()
+// reportGuardViolations prominently reports changes made outside the repository
+// and logs them to the progress file
+func reportGuardViolations(output *ui.UI, cfg *config.Config, what string, violations []guard.Violation, revertErr error) {
+	output.Header("SAFETY: Files Modified Outside Repository")
+	output.Error("%s touched %d path(s) outside the repository and was aborted:", what, len(violations))
+	output.Print("%s", guard.FormatViolations(violations))
+	if revertErr != nil {
+		output.Error("Revert incomplete, inspect these paths manually: %v", revertErr)
+	} else {
+		output.Warn("Repository changes from this step were reverted; review any paths not marked (restored).")
+	}
+	for _, v := range violations {
+		if v.Unrecoverable {
+			output.Error("%s was too large to snapshot and cannot be restored; inspect it manually", v.Path)
 		}
+	}
 
-		// Check for nudge file changes (allows user to add nudges mid-run)
-		if reloaded, _ := nudgeStore.Reload(); reloaded && cfg.Verbose {
-			output.Debug("Nudge file updated, reloaded %d nudge(s)", nudgeStore.ActiveCount())
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("SAFETY: %s aborted - modified files outside the repository:\n%s",
+		what, guard.FormatViolations(violations)))
+}
+
+// runFunc returns how a run is carried out: by parallel workers, in an
+// isolated worktree, or in the checkout. Parallel workers each run in their
+// own worktree, so validateConfig rejects -isolated-worktree with -parallel.
+func runFunc(cfg *config.Config) func(*config.Config) error {
+	switch {
+	case cfg.Parallel > 1:
+		return runParallel
+	case cfg.IsolatedWorktree:
+		return runInWorktree
+	default:
+		return runIterations
+	}
+}
+
+// validateExperimentConfig checks that the two experiment variants are usable and differ
+func validateExperimentConfig(cfg *config.Config) error {
+	if _, err := experiment.ParseSplit(cfg.ExperimentSplit); err != nil {
+		return err
+	}
+	if cfg.ExperimentAgentB == "" && cfg.ExperimentPromptA == "" && cfg.ExperimentPromptB == "" {
+		return fmt.Errorf("-experiment requires -experiment-agent-b or a prompt template (-experiment-prompt-a/-experiment-prompt-b)")
+	}
+	if cfg.ExperimentAgentB != "" && cfg.UsesAPIBackend() {
+		// The API backend calls -api-model for both variants, so an agent
+		// command for variant B would not be used
+		return fmt.Errorf("-experiment-agent-b cannot be used with -backend %s; compare prompt templates instead", cfg.AgentBackend)
+	}
+	if cfg.ExperimentAgentB != "" {
+		if _, err := exec.LookPath(cfg.ExperimentAgentB); err != nil {
+			return fmt.Errorf("experiment agent command not found in PATH: %s", cfg.ExperimentAgentB)
+		}
+	}
+	for _, path := range []string{cfg.ExperimentPromptA, cfg.ExperimentPromptB} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("experiment prompt template not found: %s", path)
 		}
+	}
+	return nil
+}
 
-		// Capture active nudges before this iteration
-		activeNudges := nudgeStore.GetActive()
+// agentName returns a short description of the agent for display and run history
+func agentName(cfg *config.Config) string {
+	if cfg.UsesAPIBackend() {
+		return cfg.AgentBackend + ":" + cfg.APIModel
+	}
+	return cfg.AgentCmd
+}
 
-		// Build the prompt for the AI agent, including any recovery guidance
-		iterPrompt := prompt.BuildIterationPrompt(cfg)
+func validateConfig(cfg *config.Config) error {
+	if cfg.MergeMemory && cfg.ImportMemory == "" {
+		return fmt.Errorf("-merge requires -import-memory")
+	}
+	if (cfg.NudgeSticky || cfg.NudgeTTL != "") && cfg.Nudge == "" {
+		return fmt.Errorf("-nudge-sticky and -nudge-ttl require -nudge")
+	}
+	if cfg.Feedback != "" && cfg.RedecomposeGoal == "" {
+		return fmt.Errorf("-feedback requires -redecompose-goal")
+	}
+	if !changelog.ValidFormat(cfg.ChangelogFormat) {
+		return fmt.Errorf("invalid -changelog-format %q (valid: %s)", cfg.ChangelogFormat, strings.Join(changelog.Formats, ", "))
+	}
+
+	// Custom prompt templates must parse, whatever Ralph is asked to do
+	if err := prompt.CheckTemplates(cfg); err != nil {
+		return err
+	}
 
-		// Inject baseline context (codebase structure and conventions)
-		if baselineData != nil {
-			baselineContext := baselineData.BuildPromptContext()
-			if baselineContext != "" {
-				iterPrompt = baselineContext + iterPrompt
+	// Skip validation for generate-plan (handled separately)
+	if cfg.GeneratePlan {
+		if cfg.FromIssue != "" {
+			if cfg.NotesFile != "" {
+				return fmt.Errorf("-notes and -from-issue cannot be used together")
+			}
+			if _, err := tracker.ParseGitHubRef(cfg.FromIssue); err != nil {
+				return err
+			}
+			if cfg.NoAgent {
+				return nil
 			}
+			return checkAgentAvailable(cfg)
 		}
-		
-		// Inject memory context (relevant memories based on current feature category)
-		// Note: category could be extracted from the plan in a future enhancement
-		memoryContext := memStore.BuildPromptContext("", 10) // Get top 10 relevant memories
-		if memoryContext != "" {
-			iterPrompt = memoryContext + iterPrompt
+		if cfg.NotesFile == "" {
+			return fmt.Errorf("notes file is required with -generate-plan (use -notes flag, or -from-issue)")
 		}
-
-		// Inject nudge context
-		nudgeContext := nudgeStore.BuildPromptContext()
-		if nudgeContext != "" {
-			iterPrompt = nudgeContext + iterPrompt
+		notesPath := strings.TrimSpace(cfg.NotesFile)
+		if notesPath == "" {
+			return fmt.Errorf("notes file path cannot be empty")
 		}
-		
-		if additionalPromptGuidance != "" {
-			iterPrompt = additionalPromptGuidance + "\n\n" + iterPrompt
-			additionalPromptGuidance = "" // Clear after use
+		if _, err := os.Stat(notesPath); os.IsNotExist(err) {
+			return fmt.Errorf("notes file not found: %s", notesPath)
 		}
-
-		if cfg.Verbose {
-			output.Debug("Prompt: %s", iterPrompt)
+		if cfg.NoAgent {
+			return nil
+		}
+		return checkAgentAvailable(cfg)
+	}
+	if cfg.NoAgent {
+		return fmt.Errorf("-no-agent requires -generate-plan")
+	}
+	if (cfg.FeatureCategory != "" || cfg.FeatureSteps != "" || cfg.FeatureMilestone != "") && cfg.AddFeature == "" {
+		return fmt.Errorf("-category, -feature-milestone and -steps require -add-feature")
+	}
+	if (cfg.MergeMilestonePrefix != "" || cfg.MergeSimilarity != 0) && cfg.MergePlan == "" {
+		return fmt.Errorf("-merge-milestone-prefix and -merge-similarity require -merge-plan")
+	}
+
+	// Skip iteration validation if we're just listing status or milestones
+	if cfg.ListAll || cfg.ListTested || cfg.ListUntested || cfg.ListMilestones || cfg.ShowMilestone != "" || cfg.ListDeferred ||
+		cfg.ShowStatus || cfg.ShowFeature != 0 || cfg.Forecast || cfg.MarkTested != 0 || cfg.MarkUntested != 0 || cfg.DeferFeature != 0 || cfg.DeleteFeature != 0 {
+		if _, err := os.Stat(cfg.PlanFile); os.IsNotExist(err) {
+			return fmt.Errorf("plan file not found: %s", cfg.PlanFile)
+		}
+		return nil
+	}
+
+	// A dry run shows the first iteration and does not need the agent
+	if cfg.Iterations <= 0 && !cfg.DryRun {
+		return fmt.Errorf("iterations must be a positive integer (use -iterations flag)")
+	}
+
+	if _, err := os.Stat(cfg.PlanFile); os.IsNotExist(err) {
+		return fmt.Errorf("plan file not found: %s", cfg.PlanFile)
+	}
+
+	if !cfg.DryRun {
+		if err := checkAgentAvailable(cfg); err != nil {
+			return err
+		}
+		if err := checkFallbackAgents(cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.Experiment && (len(cfg.FallbackAgents) > 0 || cfg.RotateAgents) {
+		return fmt.Errorf("-fallback-agents and -rotate-agents cannot be combined with -experiment, which compares agents")
+	}
+	if cfg.RotateAgents && len(cfg.FallbackAgents) == 0 {
+		return fmt.Errorf("-rotate-agents requires -fallback-agents")
+	}
+
+	// Validate parallel features
+	if cfg.Parallel < 0 {
+		return fmt.Errorf("parallel cannot be negative")
+	}
+	if cfg.Parallel > 1 {
+		if cfg.IsolatedWorktree {
+			return fmt.Errorf("-parallel already runs every feature in its own worktree; drop -isolated-worktree")
+		}
+		if cfg.Approve {
+			return fmt.Errorf("-parallel cannot be used with -approve, which is interactive")
+		}
+		for _, f := range []string{cfg.PlanFile, cfg.ProgressFile} {
+			if filepath.IsAbs(f) {
+				return fmt.Errorf("-parallel requires plan and progress files inside the working directory, got %s", f)
+			}
+		}
+	}
+
+	// Validate experiment settings
+	if cfg.Experiment {
+		if err := validateExperimentConfig(cfg); err != nil {
+			return err
+		}
+	}
+
+	// Validate recovery strategy
+	if _, err := recovery.ParseStrategyType(cfg.RecoveryStrategy); err != nil {
+		return err
+	}
+
+	// Validate max retries
+	if cfg.MaxRetries < 0 {
+		return fmt.Errorf("max-retries cannot be negative")
+	}
+
+	// Validate retry backoff and budget
+	for name, value := range map[string]string{"retry-backoff": cfg.RetryBackoff, "retry-backoff-max": cfg.RetryBackoffMax} {
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s format: %w", name, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if err := retryBackoff(cfg).Validate(); err != nil {
+		return err
+	}
+	if cfg.RetryBudget < 0 {
+		return fmt.Errorf("retry-budget cannot be negative")
+	}
+
+	// Validate rate limit handling
+	for name, value := range map[string]string{"min-iteration-interval": cfg.MinIterationInterval, "rate-limit-backoff": cfg.RateLimitBackoff} {
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s format: %w", name, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if cfg.RateLimitRetries < 0 {
+		return fmt.Errorf("rate-limit-retries cannot be negative")
+	}
+
+	// Validate iteration timeout
+	if _, err := cfg.IterationTimeoutDuration(); err != nil {
+		return fmt.Errorf("invalid iteration-timeout: %w", err)
+	}
+
+	// The approval gate is interactive
+	if cfg.Approve && cfg.JSONOutput {
+		return fmt.Errorf("-approve cannot be used with -json-output")
+	}
+
+	// Commands Ralph runs must be allowed by the policy
+	pol, err := loadPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.Lint && cfg.LintCmd == "" {
+		return fmt.Errorf("-lint needs -lint-cmd: the build system has no lint command")
+	}
+	commands := []string{cfg.TypeCheckCmd, cfg.LintCmd, cfg.TestCmd}
+	if cfg.CoverageGate != "" {
+		if _, err := coverage.ParseGate(cfg.CoverageGate); err != nil {
+			return err
+		}
+		command := coverageCommand(cfg)
+		if command == "" {
+			return fmt.Errorf("-coverage-gate needs -coverage-cmd: the build system has no default coverage command")
+		}
+		commands = append(commands, command)
+	}
+	if !cfg.UsesAPIBackend() {
+		commands = append(commands, cfg.AgentCmd, cfg.ExperimentAgentB)
+	}
+	for _, command := range commands {
+		if err := pol.CheckCommand(command); err != nil {
+			return err
+		}
+	}
+
+	// Validate scope limit
+	if cfg.ScopeLimit < 0 {
+		return fmt.Errorf("scope-limit cannot be negative")
+	}
+
+	// Validate max prompt steps
+	if cfg.MaxPromptSteps < 0 {
+		return fmt.Errorf("max-prompt-steps cannot be negative")
+	}
+	if cfg.ProgressTail < 0 {
+		return fmt.Errorf("progress-tail cannot be negative")
+	}
+	if cfg.MaxPlanVersions < 0 {
+		return fmt.Errorf("max-plan-versions cannot be negative")
+	}
+
+	// Validate context staleness settings
+	if cfg.ContextMaxAge < 0 {
+		return fmt.Errorf("context-max-age cannot be negative")
+	}
+	if cfg.ContextMaxChanges < 0 {
+		return fmt.Errorf("context-max-changes cannot be negative")
+	}
+
+	// Validate deadline format
+	if cfg.Deadline != "" {
+		if _, err := config.ParseDeadline(cfg.Deadline); err != nil {
+			return fmt.Errorf("invalid deadline format: %w", err)
+		}
+	}
+	if cfg.FeatureDeadline != "" {
+		d, err := time.ParseDuration(cfg.FeatureDeadline)
+		if err != nil {
+			return fmt.Errorf("invalid feature-deadline format: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("feature-deadline must be positive")
+		}
+	}
+
+	return nil
+}
+
+// handleDryRun shows what the first iteration of a run would do: the resolved
+// configuration, the detected environment and build system, the feature that
+// would be selected and the full prompt. The agent is not invoked and the
+// plan is not changed.
+func handleDryRun(cfg *config.Config) error {
+	output := ui.New(ui.OutputConfig{
+		NoColor:    cfg.NoColor,
+		Quiet:      cfg.Quiet,
+		JSONOutput: cfg.JSONOutput,
+		LogLevel:   ui.ParseLogLevel(cfg.LogLevel),
+	})
+	output.Header("Ralph - Dry Run (the agent is not invoked)")
+
+	output.SubHeader("Configuration")
+	configPath := cfg.ConfigFile
+	if configPath == "" {
+		configPath = config.DiscoverConfigFile()
+	}
+	if configPath == "" {
+		configPath = "(none)"
+	}
+	output.Print("Config file: %s", configPath)
+	output.Print("Plan file: %s", cfg.PlanFile)
+	output.Print("Progress file: %s", cfg.ProgressFile)
+	if cfg.Iterations > 0 {
+		output.Print("Iterations: %d", cfg.Iterations)
+	}
+	output.Print("Agent: %s", agentName(cfg))
+	if err := checkAgentAvailable(cfg); err != nil {
+		output.Warn("Agent unavailable: %v", err)
+	}
+	if len(cfg.AgentEnv) > 0 {
+		output.Print("Agent environment: %s", strings.Join(agent.EnvNames(cfg.AgentEnv), ", "))
+	}
+	buildSystem := cfg.BuildSystem
+	if buildSystem == "" || buildSystem == "auto" {
+		buildSystem = detection.DetectBuildSystem() + " (detected)"
+	}
+	output.Print("Build system: %s", buildSystem)
+	output.Print("Type check command: %s", cfg.TypeCheckCmd)
+	output.Print("Test command: %s", cfg.TestCmd)
+	if cfg.LintCmd != "" {
+		output.Print("Lint command: %s", cfg.LintCmd)
+	}
+	output.Print("Recovery strategy: %s (max %d retries)", cfg.RecoveryStrategy, cfg.MaxRetries)
+	output.Print("Scope control: %s", formatScopeInfo(cfg))
+	if cfg.IterationPromptTemplate != "" {
+		output.Print("Iteration prompt template: %s", cfg.IterationPromptTemplate)
+	}
+
+	output.SubHeader("Environment")
+	var envProfile *environment.EnvironmentProfile
+	if cfg.Environment != "" {
+		envProfile = environment.ForceEnvironment(environment.ParseEnvironmentType(cfg.Environment))
+	} else {
+		envProfile = environment.Detect()
+	}
+	output.Print("%s", envProfile.Summary())
+
+	output.SubHeader("Next Feature")
+	blocked := milestoneBlockedFeatures(cfg)
+	featureID, featureSteps, featureDesc := extractCurrentFeatureFromPlans(cfg.PlanFile, blocked)
+	scopeMgr := scope.NewManager(&scope.Constraints{MaxIterationsPerFeature: cfg.ScopeLimit})
+	if !cfg.NoScopeCalibration {
+		scopeMgr.SetCalibration(scopeCalibration(cfg))
+	}
+	if featureID > 0 {
+		scopeMgr.StartFeature(featureID, featureSteps, featureDesc)
+		scopeMgr.SetIterationLimit(featureID, featureIterationLimit(cfg.PlanFile, featureID))
+		output.Print("Feature #%d: %s", featureID, featureDesc)
+		output.Print("Complexity: %s (~%d iterations)", scopeMgr.GetFeatureScope(featureID).EstimatedComplexity, scopeMgr.EstimatedIterations(featureID))
+		if category := featureCategory(cfg.PlanFile, featureID); category != "" {
+			output.Print("Category: %s", category)
+		}
+	} else {
+		output.Print("No actionable feature: the agent would be asked to pick one from the plan")
+	}
+	if len(blocked) > 0 {
+		output.Print("Blocked by prerequisite milestones: %d feature(s)", len(blocked))
+	}
+
+	// Load the context the prompt is built from, as a run would
+	memStore := memory.NewStore(cfg.MemoryFile)
+	memStore.SetRetentionDays(cfg.MemoryRetention)
+	memStore.SetRedactor(secretRedactor.Redact)
+	if err := memStore.Load(); err != nil {
+		output.Warn("Failed to load memory: %v", err)
+	}
+	if globalStore, err := loadGlobalMemory(cfg); err != nil {
+		output.Warn("Failed to load user-level memory: %v", err)
+	} else {
+		memStore.SetGlobal(globalStore)
+	}
+	nudgeStore := nudge.NewStore(cfg.NudgeFile)
+	if err := nudgeStore.Load(); err != nil {
+		output.Debug("No nudge file loaded: %v", err)
+	}
+	var baselineData *baseline.Baseline
+	if cfg.UseBaseline {
+		if data, err := baseline.Load(cfg.BaselineFile); err == nil {
+			baselineData = data
+		}
+	}
+
+	promptData := prompt.NewIterationData(cfg, 1, featureID)
+	activeNudges, injectedMemories := addPromptContext(cfg, output, &promptData, baselineData, memStore, nudgeStore, blocked)
+	promptData.Budget = scope.BuildBudgetPromptContext(featureID, scopeMgr.RemainingIterations(featureID))
+	iterPrompt, err := prompt.RenderIterationPrompt(cfg, promptData)
+	if err != nil {
+		return err
+	}
+	output.SubHeader("Prompt")
+	output.Print("Context: %d memories, %d nudge(s)", len(injectedMemories), len(activeNudges))
+	if baselineData != nil {
+		output.Print("Baseline: %s", cfg.BaselineFile)
+	}
+	output.Print("")
+	output.Print("%s", secretRedactor.Redact(iterPrompt))
+	return nil
+}
+
+// runInWorktree runs the iterations in a temporary git worktree and applies the
+// resulting changes to the main checkout only if the type check and tests pass
+// in the worktree. On failure the worktree is kept for inspection.
+func runInWorktree(cfg *config.Config) error {
+	output := ui.New(ui.OutputConfig{
+		NoColor:    cfg.NoColor,
+		Quiet:      cfg.Quiet,
+		JSONOutput: cfg.JSONOutput,
+		LogLevel:   ui.ParseLogLevel(cfg.LogLevel),
+	})
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if real, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = real
+	}
+	root, err := worktree.RepoRoot(cwd)
+	if err != nil {
+		return fmt.Errorf("-isolated-worktree requires a git repository: %w", err)
+	}
+	rel, _ := filepath.Rel(root, cwd)
+
+	// Files read or written while the run is in progress stay in the main checkout:
+	// nudges can be edited mid-run, and history/checkpoints/plan backups outlive the worktree
+	// The policy also stays in the main checkout, out of the agent's reach
+	if cfg.PolicyFile == "" {
+		cfg.PolicyFile = policy.Discover(cwd)
+	}
+	for _, p := range []*string{&cfg.NudgeFile, &cfg.HistoryDir, &cfg.DiffDir, &cfg.CheckpointDir, &cfg.PlanVersionDir, &cfg.TelemetryFile, &cfg.PolicyFile, &cfg.FlakyFile, &cfg.JUnitOutput, &cfg.SummaryMarkdown, &cfg.TranscriptDir} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(cwd, *p)
+		}
+	}
+	pol, err := loadPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	// State files are carried into the worktree and copied back verbatim on success
+	var stateFiles []string
+	for _, f := range []string{cfg.PlanFile, cfg.ProgressFile, cfg.MemoryFile, cfg.GoalsFile, cfg.BaselineFile} {
+		if f != "" && !filepath.IsAbs(f) {
+			stateFiles = append(stateFiles, filepath.Join(rel, f))
+		}
+	}
+
+	wt, err := worktree.Create(root, worktree.Options{StateFiles: stateFiles, Exclude: []string{".ralph"}})
+	if err != nil {
+		return err
+	}
+	output.Info("Isolated worktree: %s", wt.Path)
+
+	if err := os.Chdir(wt.Translate(cwd)); err != nil {
+		wt.Remove()
+		return fmt.Errorf("failed to enter worktree: %w", err)
+	}
+	runErr := runIterations(cfg)
+	if err := os.Chdir(cwd); err != nil {
+		return fmt.Errorf("failed to return to %s: %w", cwd, err)
+	}
+
+	keep := func(reason string) {
+		output.Warn("%s - changes were NOT applied to %s", reason, root)
+		output.Info("Inspect the worktree at %s", wt.Path)
+		output.Info("Discard it with: git worktree remove --force %s", wt.Path)
+	}
+
+	if runErr != nil {
+		keep("Run failed")
+		return runErr
+	}
+
+	// Seal the result before validating so build and test artifacts are not merged
+	changed, err := wt.Seal()
+	if err != nil {
+		keep("Recording changes failed")
+		return err
+	}
+
+	// Never merge changes the policy forbids
+	if pol != nil {
+		diff, err := wt.Diff()
+		if err != nil {
+			keep("Recording changes failed")
+			return err
+		}
+		if violations := pol.CheckChanges(changed, diff); len(violations) > 0 {
+			output.Error("Changes violate the policy in %s:\n%s", pol.Path, policy.FormatViolations(violations))
+			keep("Policy violation")
+			return fmt.Errorf("changes in isolated worktree violate the policy")
+		}
+	}
+
+	output.SubHeader("Validating Isolated Worktree")
+	for _, check := range []struct{ name, command string }{
+		{"Type check", cfg.TypeCheckCmd},
+		{checkLint, cfg.LintCmd},
+		{"Tests", cfg.TestCmd},
+	} {
+		if check.command == "" {
+			continue
+		}
+		if err := pol.CheckCommand(check.command); err != nil {
+			keep(check.name + " not allowed")
+			return err
+		}
+		output.Info("%s: %s", check.name, check.command)
+		if out, err := wt.Run(check.command); err != nil {
+			output.Error("%s failed: %v", check.name, err)
+			if out = strings.TrimSpace(out); out != "" {
+				output.Print("%s", out)
+			}
+			keep(check.name + " failed")
+			return fmt.Errorf("%s failed in isolated worktree", strings.ToLower(check.name))
+		}
+	}
+
+	files, err := wt.MergeBack()
+	if err != nil {
+		keep("Merge failed")
+		return err
+	}
+	if len(files) == 0 {
+		output.Info("No code changes to apply")
+	} else {
+		output.Success("Applied %d changed file(s) to %s (uncommitted, ready for review)", len(files), root)
+		for _, f := range files {
+			output.Debug("  %s", f)
+		}
+	}
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("ISOLATED WORKTREE: validated and applied %d changed file(s)", len(files)))
+	wt.Remove()
+	return nil
+}
+
+type workerOverride struct{ name, value string }
+
+// workerOverrides are flags parallel workers always run with. Reports,
+// notifications, transcripts and approval prompts belong to the parent run,
+// and workers have no terminal to approve on. Setting them explicitly also
+// overrides the config file.
+var workerOverrides = []workerOverride{
+	{"parallel", "1"},
+	{"no-color", "true"},
+	{"approve", "false"},
+	{"notify", "false"},
+	{"transcript", "false"},
+	{"telemetry", "false"},
+	{"experiment", "false"},
+	{"isolated-worktree", "false"},
+	{"environment-reports", "false"},
+	{"report-html", "false"},
+	{"junit-output", ""},
+	{"summary-markdown", ""},
+}
+
+// workerPathFlags are file flags a worker, started in a worktree, must
+// resolve in the main checkout
+var workerPathFlags = []string{"config", "policy", "flaky-file"}
+
+// workerCommand builds the command a parallel worker runs: the parent's
+// flags without -C and workerOverrides, with workerPathFlags made absolute.
+// Run history, diffs, checkpoints and nudges stay in the main checkout.
+func workerCommand(exe, cwd string, args []string, cfg *config.Config) []string {
+	command := []string{exe}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			rest = args[i:]
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if f := flag.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			value, hasValue = args[i], true
+		}
+		if name == "C" || slices.ContainsFunc(workerOverrides, func(o workerOverride) bool { return o.name == name }) {
+			continue
+		}
+		if !hasValue {
+			command = append(command, "-"+name)
+			continue
+		}
+		if slices.Contains(workerPathFlags, name) && value != "" && !filepath.IsAbs(value) {
+			value = filepath.Join(cwd, value)
+		}
+		command = append(command, fmt.Sprintf("-%s=%s", name, value))
+	}
+	for _, o := range workerOverrides {
+		command = append(command, fmt.Sprintf("-%s=%s", o.name, o.value))
+	}
+	for _, d := range []struct{ name, dir string }{
+		{"history-dir", cfg.HistoryDir},
+		{"diff-dir", cfg.DiffDir},
+		{"checkpoint-dir", cfg.CheckpointDir},
+		{"nudge-file", cfg.NudgeFile},
+	} {
+		if d.dir != "" && !filepath.IsAbs(d.dir) {
+			command = append(command, fmt.Sprintf("-%s=%s", d.name, filepath.Join(cwd, d.dir)))
+		}
+	}
+	return append(command, rest...)
+}
+
+// runParallel works on up to -parallel independent features at a time, each
+// by its own Ralph process in its own worktree, until every feature a worker
+// can start is completed or has failed once
+func runParallel(cfg *config.Config) error {
+	output := ui.New(ui.OutputConfig{
+		NoColor:    cfg.NoColor,
+		Quiet:      cfg.Quiet,
+		JSONOutput: cfg.JSONOutput,
+		LogLevel:   ui.ParseLogLevel(cfg.LogLevel),
+	})
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the ralph executable: %w", err)
+	}
+
+	command := workerCommand(exe, cwd, os.Args[1:], cfg)
+	var stateFiles []string
+	for _, f := range []string{cfg.MemoryFile, cfg.GoalsFile, cfg.BaselineFile} {
+		if f != "" && !filepath.IsAbs(f) {
+			stateFiles = append(stateFiles, f)
+		}
+	}
+	logDir := filepath.Join(cwd, ".ralph", "parallel", time.Now().Format("20060102-150405"))
+	opts := parallel.Options{
+		Dir:         cwd,
+		PlanFile:    cfg.PlanFile,
+		AppendFiles: []string{cfg.ProgressFile, progress.JSONLPath(cfg.ProgressFile)},
+		StateFiles:  stateFiles,
+		LogDir:      logDir,
+		Command:     command,
+	}
+
+	output.Header("Ralph - Parallel Features")
+	output.Info("Workers: up to %d, %d iteration(s) each", cfg.Parallel, cfg.Iterations)
+	output.Info("Worker logs: %s", logDir)
+
+	var completed, failed int
+	attempted := make(map[int]bool)
+	for batch := 1; ; batch++ {
+		plans, err := plan.ReadFile(cfg.PlanFile)
+		if err != nil {
+			return err
+		}
+		features := parallel.Select(plans, milestoneBlockedFeatures(cfg), attempted, cfg.Parallel)
+		if len(features) == 0 {
+			break
+		}
+		output.SubHeader("Batch %d", batch)
+		for _, f := range features {
+			attempted[f.ID] = true
+			output.Info("Feature #%d: %s", f.ID, f.Description)
+		}
+
+		results, err := parallel.Run(features, opts)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if r.Tested {
+				completed++
+				output.Success("Feature #%d completed, applied %d changed file(s)", r.Feature.ID, len(r.Files))
+				appendProgress(cfg.ProgressFile, fmt.Sprintf("PARALLEL: feature #%d completed by a worker, applied %d changed file(s)", r.Feature.ID, len(r.Files)))
+				continue
+			}
+			failed++
+			output.Warn("Feature #%d not completed: %v (log: %s)", r.Feature.ID, r.Err, r.LogFile)
+			if r.Worktree != "" {
+				output.Info("Inspect the worktree at %s", r.Worktree)
+			}
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("PARALLEL: feature #%d not completed: %v", r.Feature.ID, r.Err))
+		}
+	}
+
+	if completed+failed == 0 {
+		output.Info("No features to work on")
+		return nil
+	}
+	output.Info("Parallel features: %d completed, %d not completed", completed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d feature(s) were not completed by their workers", failed)
+	}
+	return nil
+}
+
+func runIterations(cfg *config.Config) error {
+	// Create UI instance
+	uiCfg := ui.OutputConfig{
+		NoColor:    cfg.NoColor,
+		Quiet:      cfg.Quiet,
+		JSONOutput: cfg.JSONOutput,
+		LogLevel:   ui.ParseLogLevel(cfg.LogLevel),
+	}
+	output := ui.New(uiCfg)
+
+	// Start timing for summary
+	startTime := time.Now()
+	defer func() { progressContext = progress.Context{} }()
+
+	// Detect environment
+	var envProfile *environment.EnvironmentProfile
+	if cfg.Environment != "" {
+		envType := environment.ParseEnvironmentType(cfg.Environment)
+		envProfile = environment.ForceEnvironment(envType)
+	} else {
+		envProfile = environment.Detect()
+	}
+
+	// Desktop notifications are for local runs; there is no one to notify in CI
+	notifier := notify.New(cfg.Notify, envProfile.IsCI())
+	if cfg.Notify && notifier == nil {
+		output.Debug("Desktop notifications disabled in CI (%s)", envProfile.Type)
+	}
+
+	// Apply environment-based recommendations if not explicitly set
+	if !cfg.Verbose && envProfile.RecommendedVerbose {
+		cfg.Verbose = true
+	}
+
+	// Auto-disable colors in CI if not explicitly set
+	if envProfile.CIEnvironment && !cfg.NoColor {
+		// Keep colors in CI for modern terminals that support it
+	}
+
+	// Load memory store, with the user-level memories underneath
+	memStore := memory.NewStore(cfg.MemoryFile)
+	memStore.SetRetentionDays(cfg.MemoryRetention)
+	memStore.SetRedactor(secretRedactor.Redact)
+	if err := memStore.Load(); err != nil {
+		output.Warn("Failed to load memory: %v", err)
+	}
+	if globalStore, err := loadGlobalMemory(cfg); err != nil {
+		output.Warn("Failed to load user-level memory: %v", err)
+	} else {
+		memStore.SetGlobal(globalStore)
+	}
+
+	// Prune expired memories
+	pruned, _ := memStore.Prune()
+	if pruned > 0 && cfg.Verbose {
+		output.Debug("Pruned %d expired memories", pruned)
+	}
+
+	// Load nudge store
+	nudgeStore := nudge.NewStore(cfg.NudgeFile)
+	if err := nudgeStore.Load(); err != nil {
+		output.Debug("No nudge file loaded: %v", err)
+	}
+
+	// Load flaky test store; quarantined tests carry over between runs
+	flakyStore, err := flaky.Load(cfg.FlakyFile)
+	if err != nil {
+		output.Warn("Failed to load flaky tests: %v", err)
+		flakyStore = flaky.NewStore(cfg.FlakyFile)
+	}
+	var flakyFound []*flaky.Test
+
+	// Load baseline if it exists and use-baseline is enabled
+	var baselineData *baseline.Baseline
+	if cfg.UseBaseline {
+		if data, err := baseline.Load(cfg.BaselineFile); err == nil {
+			baselineData = data
+			if cfg.Verbose {
+				output.Debug("Loaded baseline from %s (%d files, %d lines)",
+					cfg.BaselineFile, baselineData.TotalFiles, baselineData.TotalLines)
+			}
+		}
+	}
+
+	output.Header("Ralph - Iterative Development Workflow")
+	output.Info("Plan file: %s", cfg.PlanFile)
+	output.Info("Progress file: %s", cfg.ProgressFile)
+	output.Info("Iterations: %d", cfg.Iterations)
+	output.Info("Agent: %s", agentName(cfg))
+	if len(cfg.AgentEnv) > 0 {
+		output.Info("Agent environment: %s", strings.Join(agent.EnvNames(cfg.AgentEnv), ", "))
+	}
+	output.Info("Recovery strategy: %s (max %d retries)", cfg.RecoveryStrategy, cfg.MaxRetries)
+	if backoff := retryBackoff(cfg); backoff.Initial > 0 || cfg.RetryBudget > 0 {
+		budget := "unlimited"
+		if cfg.RetryBudget > 0 {
+			budget = fmt.Sprintf("%d per run", cfg.RetryBudget)
+		}
+		output.Info("Retry backoff: %s, retry budget: %s", backoff, budget)
+	}
+	iterationTimeout, err := cfg.IterationTimeoutDuration()
+	if err != nil {
+		return fmt.Errorf("invalid iteration-timeout: %w", err)
+	}
+	if iterationTimeout > 0 {
+		output.Info("Iteration timeout: %s", iterationTimeout)
+	}
+	if interval := cfg.MinIterationIntervalDuration(); interval > 0 {
+		output.Info("Minimum iteration interval: %s", interval)
+	}
+	if memStore.Count() > 0 {
+		output.Info("Memory: %d entries loaded from %s", memStore.Count(), cfg.MemoryFile)
+	}
+	if nudgeStore.ActiveCount() > 0 {
+		output.Info("Nudges: %d active nudge(s) from %s", nudgeStore.ActiveCount(), cfg.NudgeFile)
+	}
+	if baselineData != nil {
+		output.Info("Baseline: %d files analyzed (%s)", baselineData.TotalFiles, strings.Join(baselineData.TechStack.Languages, ", "))
+	}
+	baselineData = checkContextStaleness(cfg, output, baselineData, memStore)
+	
+	// Load plans and create milestone manager
+	plans, planErr := plan.ReadFile(cfg.PlanFile)
+	var milestoneMgr *milestone.Manager
+	var completedMilestonesBefore map[string]bool
+	goalsFailed := make(map[string]bool) // Goals whose success criteria validations failed
+	if planErr == nil {
+		if questions := plan.FilterQuestions(plans); len(questions) > 0 {
+			output.Warn("%d open question(s) in the plan will be skipped until answered (ralph question list)", len(questions))
+		}
+		for _, p := range prompt.OversizedFeatures(plans, cfg.MaxPromptSteps) {
+			output.Warn("Feature #%d has %d steps; prompts include only the first %d (split it with -analyze-plan)", p.ID, len(p.Steps), cfg.MaxPromptSteps)
+		}
+		if fc := newForecast(cfg, plans); fc.Insufficient(cfg.Iterations) {
+			output.Warn("-iterations %d is likely not enough to finish the plan: %s remain (ralph -forecast)", cfg.Iterations, fc.Total)
+		}
+		var milestoneErr error
+		milestoneMgr, milestoneErr = newMilestoneManager(cfg, plans)
+		if milestoneErr != nil {
+			output.Warn("Failed to load milestones file: %v", milestoneErr)
+		}
+		
+		// Record which milestones are complete before we start
+		completedMilestonesBefore = make(map[string]bool)
+		for _, p := range milestoneMgr.GetCompletedMilestones() {
+			completedMilestonesBefore[p.Milestone.Name] = true
+		}
+		
+		// Show milestone progress in verbose mode
+		if cfg.Verbose && milestoneMgr.HasMilestones() {
+			output.SubHeader("Milestone Progress")
+			for _, p := range milestoneMgr.CalculateAllProgress() {
+				output.Print("  %s", milestone.FormatProgress(p))
+			}
+		}
+	}
+	
+	if cfg.Verbose {
+		output.Debug("Type check command: %s", cfg.TypeCheckCmd)
+		output.Debug("Test command: %s", cfg.TestCmd)
+		output.Print("")
+		output.Print("%s", envProfile.Summary())
+	}
+	output.Print("")
+
+	// Initialize recovery manager
+	strategyType, _ := recovery.ParseStrategyType(cfg.RecoveryStrategy)
+	recoveryMgr := recovery.NewRecoveryManager(cfg.MaxRetries, strategyType)
+	classifier, err := newFailureClassifier(cfg)
+	if err != nil {
+		return err
+	}
+	recoveryMgr.SetClassifier(classifier)
+	recoveryMgr.SetBackoff(retryBackoff(cfg))
+	recoveryMgr.SetRetryBudget(cfg.RetryBudget)
+
+	// Initialize replan manager
+	replanMgr := replan.NewReplanManager(cfg.PlanFile, cfg.AgentCmd, cfg.AutoReplan)
+	replanMgr.SetFailureThreshold(cfg.ReplanThreshold)
+	if err := replanMgr.SetBackupOptions(planBackupOptions(cfg)); err != nil {
+		output.Warn("Plan backups: %v", err)
+	}
+	replanStrategyType, _ := replan.ParseStrategyType(cfg.ReplanStrategy)
+	consecutiveFailures := 0
+	replans := 0
+	// Average time per feature in earlier runs, used for the deadline
+	// pressure trigger until this run completes a feature
+	var historyFeatureDuration time.Duration
+	if cfg.Deadline != "" {
+		if runs, err := history.NewStore(cfg.HistoryDir).List(); err == nil {
+			historyFeatureDuration = history.FeatureDuration(runs, time.Now())
+		}
+	}
+
+	// Initialize scope manager
+	scopeConstraints := &scope.Constraints{
+		MaxIterationsPerFeature: cfg.ScopeLimit,
+		FeatureDeadline:         cfg.FeatureDeadlineDuration(),
+		AutoDefer:               true,
+	}
+	scopeMgr := scope.NewManager(scopeConstraints)
+	if !cfg.NoScopeCalibration {
+		calibration := scopeCalibration(cfg)
+		scopeMgr.SetCalibration(calibration)
+		output.Debug("Scope estimates (iterations): %s", calibration.Summary())
+	}
+
+	// Set deadline if specified
+	if cfg.Deadline != "" {
+		deadline, _ := config.ParseDeadline(cfg.Deadline)
+		scopeMgr.SetDeadline(deadline)
+	}
+
+	// Show scope info if scope control is enabled
+	if cfg.ScopeLimit > 0 || cfg.Deadline != "" || cfg.FeatureDeadline != "" {
+		output.Info("Scope control: %s", formatScopeInfo(cfg))
+	}
+	
+	// Show replan info if enabled
+	if cfg.AutoReplan {
+		output.Info("Auto-replan: enabled (strategy: %s, threshold: %d failures)", cfg.ReplanStrategy, cfg.ReplanThreshold)
+	}
+
+	// Track metrics for summary
+	var summary ui.Summary
+	summary.TotalIterations = cfg.Iterations
+	summary.StartTime = startTime
+
+	// Record this run so it can be compared later (ralph report compare)
+	runRecord := history.NewRun(agentName(cfg), cfg.PlanFile, cfg.RunLabel)
+	agentPool := agent.NewPool(cfg)
+	if agentPool.Size() > 1 {
+		runRecord.IterationAgents = make(map[int]string)
+		summary.Agents = make(map[string]int)
+	}
+	if cfg.HealthCheck {
+		if err := checkAgentHealth(cfg, output, agentPool.Agents(cfg)); err != nil {
+			return err
+		}
+	}
+	if err := history.NewStore(cfg.HistoryDir).Reserve(runRecord); err != nil {
+		output.Debug("Failed to reserve run ID: %v", err)
+	}
+	var transcriptRecorder *transcript.Recorder
+	if cfg.Transcript {
+		if transcriptRecorder = startTranscript(cfg, runRecord.ID); transcriptRecorder != nil {
+			defer agent.SetTranscript(nil)
+			output.Info("Transcript: %s", transcriptRecorder.Path())
+		}
+	}
+
+	// Outcome of each iteration, for -junit-output
+	var iterationTests []testreport.TestCase
+	runRecord.StartTime = startTime
+	runRecord.IterationsLimit = cfg.Iterations
+	diffStore := diffs.NewStore(cfg.DiffDir)
+	// Iteration restore points refer to the latest run only
+	if err := recovery.DeleteRestorePoints(recovery.KindIteration); err != nil {
+		output.Debug("Failed to clear iteration restore points: %v", err)
+	}
+	testedBefore := make(map[int]bool)
+	for _, p := range plans {
+		if p.Tested {
+			testedBefore[p.ID] = true
+		}
+	}
+
+	// Guard against the agent modifying files outside the repository
+	pathGuard := newPathGuard(cfg, output)
+
+	// Load the policy bounding what this run may do
+	pol, err := loadPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if pol != nil {
+		output.Info("Policy: %s (%s)", pol.Path, pol.Summary())
+		replanMgr.SetProtectedCategories(pol.RequireReviewCategories)
+	}
+
+	// Lifecycle hooks run user commands around iterations
+	hookRunner, err := newHookRunner(cfg, pol)
+	if err != nil {
+		return err
+	}
+
+	// Ask a human to approve each iteration (or those the policy requires
+	// review for), and to confirm replans
+	var reviewer *approval.Reviewer
+	if cfg.Approve || (pol != nil && len(pol.RequireReviewCategories) > 0) || confirmReplans(cfg) {
+		reviewer = approval.NewReviewer(os.Stdin, os.Stdout)
+	}
+	if confirmReplans(cfg) {
+		replanMgr.SetConfirm(replanConfirmer(output, reviewer, cfg.PlanFile))
+	}
+
+	// Let the user type nudges into the terminal during the run. The approval
+	// gate and replan confirmation read the same input, so the console is off
+	// when they are used.
+	if reviewer == nil && !cfg.NoNudgeConsole && !cfg.Quiet && !cfg.JSONOutput &&
+		output.IsTTY() && term.IsTerminal(int(os.Stdin.Fd())) {
+		console := nudge.NewConsole(nudgeStore, os.Stdin, os.Stdout)
+		console.Start()
+		defer console.Stop()
+		output.Info("Nudges: type %s and press Enter to add a nudge during the run", nudge.ConsoleKey)
+	}
+
+	// Set up A/B experiment mode if enabled
+	var exp *experiment.Experiment
+	testedSoFar := make(map[int]bool)
+	if cfg.Experiment {
+		var expErr error
+		exp, expErr = newExperiment(cfg, plans)
+		if expErr != nil {
+			return expErr
+		}
+		for id := range testedBefore {
+			testedSoFar[id] = true
+		}
+		runRecord.Tags = map[string]string{"experiment": runRecord.ID}
+		output.Info("Experiment (%s split): %s | %s", exp.Split, exp.A.Describe(), exp.B.Describe())
+	}
+
+	// Measure the coverage the coverage gate starts from
+	var coverageGate *coverage.Gate
+	var coverageTrend coverage.Trend
+	coverageSeen := make(map[int]bool)
+	if cfg.CoverageGate != "" {
+		min, _ := coverage.ParseGate(cfg.CoverageGate) // Checked by validateConfig
+		coverageGate = coverage.NewGate(min)
+		if total, ok := measureCoverage(cfg, output); ok {
+			coverageGate.Baseline = total
+			coverageTrend.Record(0, total, true)
+			output.Info("Coverage gate %.1f%%: coverage is %.1f%%", min, total)
+		}
+		for id := range testedBefore {
+			coverageSeen[id] = true
+		}
+	}
+
+	// Set up the reviewer agents that approve features before they stay tested
+	var reviewGate *multiagent.Orchestrator
+	reviewSeen := make(map[int]bool)
+	if cfg.ReviewGate {
+		agentConfig, source, err := loadAgentsConfig(cfg)
+		if err != nil {
+			return fmt.Errorf("review gate: %w", err)
+		}
+		reviewGate = multiagent.NewOrchestrator(agentConfig, agentConfig.ContextFile)
+		reviewers := len(reviewGate.GetAgentsByRole(multiagent.RoleReviewer))
+		if reviewers == 0 {
+			return fmt.Errorf("review gate: no enabled reviewer agents in %s", source)
+		}
+		output.Info("Review gate: %d reviewer agent(s) must approve features before they stay tested", reviewers)
+		for id := range testedBefore {
+			reviewSeen[id] = true
+		}
+	}
+
+	// Features whose completion was logged to the progress file
+	completedSeen := make(map[int]bool)
+	for id := range testedBefore {
+		completedSeen[id] = true
+	}
+
+	// Validate features as the agent marks them tested
+	validationSeen := make(map[int]bool)
+	// Latest validation result of each feature, for -summary-markdown
+	validationResults := make(map[int]validation.ValidationRunResult)
+	if cfg.ValidateOnComplete {
+		output.Info("Validate on complete: features are validated as they are marked tested")
+		for id := range testedBefore {
+			validationSeen[id] = true
+		}
+	}
+
+	// Track the current feature being worked on (extracted from output if possible)
+	currentFeatureID := 0
+	currentFeatureSteps := 0
+	currentFeatureDesc := ""
+	var additionalPromptGuidance string
+	var planRepairGuidance string // Set when the agent damaged the plan file
+	var lastAgentStart time.Time  // Start of the previous agent run (-min-iteration-interval)
+
+	interrupted := false
+	for i := 1; i <= cfg.Iterations; i++ {
+		// Wait while the run is paused (ralph serve: POST /pause)
+		if server.Paused() {
+			output.Info("Paused before iteration %d - remove %s to resume", i, server.PauseFile)
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("PAUSED: before iteration %d", i))
+			for server.Paused() {
+				time.Sleep(pausePollInterval)
+			}
+			output.Info("Resumed")
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("RESUMED: iteration %d", i))
+		}
+
+		// Check deadline before starting iteration
+		if scopeMgr.IsDeadlineExceeded() {
+			output.Warn("Deadline exceeded - stopping execution")
+			break
+		}
+
+		// Stop once the run has cost as much as the policy allows
+		if err := pol.CheckCost(agent.EstimatedCost(cfg)); err != nil {
+			output.Warn("Policy: %v - stopping execution", err)
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("POLICY: %v", err))
+			break
+		}
+
+		// Get current feature from plans (first untested, non-deferred, not
+		// blocked by an incomplete prerequisite milestone)
+		blockedFeatures := milestoneBlockedFeatures(cfg)
+		detectedFeatureID, detectedSteps, detectedDesc := extractCurrentFeatureFromPlans(cfg.PlanFile, blockedFeatures)
+		if detectedFeatureID > 0 && detectedFeatureID != currentFeatureID {
+			// New feature detected - start tracking it
+			currentFeatureID = detectedFeatureID
+			currentFeatureSteps = detectedSteps
+			currentFeatureDesc = detectedDesc
+			scopeMgr.StartFeature(currentFeatureID, currentFeatureSteps, currentFeatureDesc)
+			scopeMgr.SetIterationLimit(currentFeatureID, featureIterationLimit(cfg.PlanFile, currentFeatureID))
+			if cfg.Verbose {
+				complexity := scope.EstimateComplexity(currentFeatureSteps, currentFeatureDesc)
+				output.Debug("Working on feature #%d (%s complexity, ~%d iterations): %s",
+					currentFeatureID, complexity, scopeMgr.EstimatedIterations(currentFeatureID), currentFeatureDesc)
+			}
+		}
+
+		output.Header("Iteration %d/%d", i, cfg.Iterations)
+		summary.IterationsRun = i
+		progressContext = progress.Context{Iteration: i, FeatureID: currentFeatureID}
+		if transcriptRecorder != nil {
+			transcriptRecorder.SetIteration(i, currentFeatureID)
+		}
+
+		// Record iteration for scope tracking
+		scopeMgr.RecordIteration(currentFeatureID)
+
+		// Check if current feature should be deferred
+		if shouldDefer, reason := scopeMgr.ShouldDefer(currentFeatureID); shouldDefer && currentFeatureID > 0 {
+			scopeMgr.DeferFeature(currentFeatureID, reason)
+			output.Warn("Feature #%d deferred: %s", currentFeatureID, scope.FormatDeferralReason(reason))
+			
+			// Mark feature as deferred in plan file
+			if err := markFeatureDeferred(cfg.PlanFile, currentFeatureID, string(reason)); err != nil {
+				output.Debug("Failed to update plan file: %v", err)
+			}
+			
+			// Log deferral to progress file
+			deferMsg := fmt.Sprintf("DEFERRED: Feature #%d - %s (iterations used: %d)", 
+				currentFeatureID, scope.FormatDeferralReason(reason), 
+				scopeMgr.GetFeatureScope(currentFeatureID).IterationsUsed)
+			appendProgress(cfg.ProgressFile, deferMsg)
+			
+			summary.FeaturesSkipped++
+			
+			// Reset current feature - agent will move to next
+			currentFeatureID = 0
+		}
+
+		// Check for simplification suggestion
+		if currentFeatureID > 0 && scopeMgr.ShouldSuggestSimplification(currentFeatureID) && 
+			!scopeMgr.WasSimplificationSuggested(currentFeatureID) {
+			suggestions := scope.SuggestSimplification(currentFeatureSteps, currentFeatureDesc)
+			output.Warn("Feature #%d may be complex. Suggestions:", currentFeatureID)
+			for _, s := range suggestions {
+				output.Print("  - %s", s)
+			}
+			scopeMgr.MarkSimplificationSuggested(currentFeatureID)
+		}
+
+		// Pick the experiment variant for this iteration
+		var variant *experiment.Variant
+		agentCfg := cfg
+		if exp != nil {
+			variant = exp.Select(i, currentFeatureID)
+			variantCfg := *cfg
+			variantCfg.AgentCmd = variant.AgentCmd
+			agentCfg = &variantCfg
+			output.Info("Experiment %s", variant.Describe())
+		}
+
+		if cfg.Verbose {
+			output.Debug("Executing agent command...")
+			if remaining := scopeMgr.RemainingIterations(currentFeatureID); remaining >= 0 && currentFeatureID > 0 {
+				output.Debug("Scope: %d iterations remaining for current feature", remaining)
+			}
+			if remaining := scopeMgr.RemainingFeatureTime(currentFeatureID); remaining >= 0 && currentFeatureID > 0 {
+				output.Debug("Scope: %s remaining for current feature", remaining.Round(time.Second))
+			}
+		}
+
+		// Space out agent runs to stay under provider rate limits
+		if wait := time.Until(lastAgentStart.Add(cfg.MinIterationIntervalDuration())); !lastAgentStart.IsZero() && wait > 0 {
+			output.Info("Waiting %s before the next iteration (-min-iteration-interval)", wait.Round(time.Second))
+			time.Sleep(wait)
+		}
+
+		hookContext := hooks.Context{RunID: runRecord.ID, Iteration: i, Iterations: cfg.Iterations,
+			FeatureID: currentFeatureID, Feature: currentFeatureDesc, Agent: agentName(agentCfg)}
+		runHooks(cfg, output, hookRunner, hooks.PreIteration, hookContext)
+
+		// Show spinner for agent execution if TTY (streamed output replaces the spinner)
+		var spinner *ui.Spinner
+		if output.IsTTY() && !cfg.Quiet && !cfg.JSONOutput && !cfg.Stream {
+			spinner = output.NewSpinner("Executing agent...")
+			spinner.Start()
+		}
+
+		// Check for nudge file changes (allows user to add nudges mid-run)
+		if reloaded, _ := nudgeStore.Reload(); reloaded && cfg.Verbose {
+			output.Debug("Nudge file updated, reloaded %d nudge(s)", nudgeStore.ActiveCount())
+		}
+
+		// Create checkpoints requested via nudge (e.g., -nudge "checkpoint:before refactor")
+		for _, n := range nudgeStore.GetActiveByType(nudge.NudgeTypeCheckpoint) {
+			if err := nudgeStore.Acknowledge(n.ID); err != nil {
+				output.Debug("Failed to acknowledge checkpoint nudge: %v", err)
+			}
+			cp, err := checkpoint.NewStore(cfg.CheckpointDir).Create(n.Content, checkpointStateFiles(cfg),
+				checkpoint.Options{Source: "nudge", Iteration: i, Exclude: ralphStatePaths(cfg)})
+			if err != nil {
+				output.Warn("Failed to create checkpoint %q: %v", n.Content, err)
+				continue
+			}
+			output.Success("Checkpoint created: %s (%s)", cp.ID, cp.Label)
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("CHECKPOINT: %s %q before iteration %d", cp.ID, cp.Label, i))
+		}
+
+		// Build the prompt for the AI agent, including any recovery guidance
+		promptData := prompt.NewIterationData(cfg, i, currentFeatureID)
+		activeNudges, injectedMemories := addPromptContext(cfg, output, &promptData, baselineData, memStore, nudgeStore, blockedFeatures)
+
+		// Tell the agent how many iterations the feature has left
+		promptData.Budget = scope.BuildBudgetPromptContext(currentFeatureID, scopeMgr.RemainingIterations(currentFeatureID))
+
+		var guidance []string
+		if planRepairGuidance != "" {
+			guidance = append(guidance, planRepairGuidance)
+			planRepairGuidance = ""
+		}
+		if additionalPromptGuidance != "" {
+			guidance = append(guidance, additionalPromptGuidance)
+			additionalPromptGuidance = "" // Clear after use
+		}
+		promptData.Guidance = strings.Join(guidance, "\n\n")
+
+		iterPrompt, err := prompt.RenderIterationPrompt(cfg, promptData)
+		if err != nil {
+			return err
+		}
+
+		if variant != nil {
+			iterPrompt = variant.ApplyPrompt(iterPrompt)
+		}
+
+		var guardSnapshot *guard.Snapshot
+		if pathGuard != nil {
+			guardSnapshot = pathGuard.Snapshot()
+		}
+
+		// Snapshot the working tree so the iteration's changes can be recorded,
+		// and rolled back if they are rejected. Ralph's own state is left out.
+		needsReview := cfg.Approve || pol.RequiresReview(featureCategory(cfg.PlanFile, currentFeatureID))
+		iterSnapshot, snapErr := recovery.TakeSnapshot(ralphStatePaths(cfg)...)
+		if snapErr != nil {
+			if needsReview || pol.ChecksChanges() {
+				return fmt.Errorf("reviewing and policy checks need a git repository: %w", snapErr)
+			}
+			output.Debug("Not recording iteration diff: %v", snapErr)
+			iterSnapshot = nil
+		} else {
+			saveRestorePoints(output, iterSnapshot, i, currentFeatureID)
+		}
+
+		if cfg.Verbose {
+			output.Debug("Prompt: %s", iterPrompt)
+		}
+
+		// Back up the plan, so it can be restored if the agent damages it
+		planBackedUp := true
+		if err := plan.Backup(cfg.PlanFile, plan.BackupFile); err != nil {
+			output.Debug("Not checking plan integrity: %v", err)
+			planBackedUp = false
 		}
 
 		// Execute the AI agent CLI tool
-		result, err := agent.Execute(cfg, iterPrompt)
+		iterStart := time.Now()
+		lastAgentStart = iterStart
+		scopeMgr.StartIteration(currentFeatureID)
+		result, agentCfg, err := executeWithRateLimit(agentCfg, agentPool, output, iterPrompt)
+		timedOut := errors.Is(err, agent.ErrTimeout)
+
+		// Give a hung agent one more chance, asking it to keep the iteration short
+		if timedOut && cfg.TimeoutRetry {
+			output.Warn("Agent timed out after %s - retrying once with guidance to be concise", iterationTimeout)
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("TIMEOUT: iteration %d timed out after %s, retrying", i, iterationTimeout))
+			result, agentCfg, err = executeWithRateLimit(agentCfg, agentPool, output, recovery.TimeoutRetryGuidance+"\n\n"+iterPrompt)
+			timedOut = errors.Is(err, agent.ErrTimeout)
+		}
+		scopeMgr.EndIteration(currentFeatureID)
+		hookContext.Agent = agentName(agentCfg) // A fallback agent may have served the iteration
+		if agentPool.Size() > 1 {
+			runRecord.IterationAgents[i] = agentName(agentCfg)
+			summary.Agents[agentName(agentCfg)]++
+			output.Debug("Iteration %d served by %s", i, agentName(agentCfg))
+		}
+		
+		// Stop spinner
+		if spinner != nil {
+			spinner.Stop()
+		}
+
+		// Ctrl+C killed the agent: stop the run, recording it like a finished one
+		if errors.Is(err, agent.ErrInterrupted) {
+			output.Warn("Interrupted during iteration %d - stopping the run", i)
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("INTERRUPTED: run stopped during iteration %d", i))
+			summary.Errors = append(summary.Errors, fmt.Sprintf("interrupted during iteration %d", i))
+			interrupted = true
+			break
+		}
+
+		// Determine exit code for failure detection
+		exitCode := 0
+		if err != nil {
+			exitCode = 1
+			// Don't return immediately - handle with recovery
+		}
+
+		// Print the agent output (already shown live when streaming)
+		if result != "" && !cfg.Stream {
+			output.Print("%s", result)
+		}
+
+		if iterSnapshot != nil {
+			recordIterationDiff(output, diffStore, runRecord.ID, i, iterSnapshot)
+			reportImpact(cfg, output, featureImpact(cfg, baselineData, promptData.Feature), iterSnapshot, currentFeatureID)
+		}
+
+		// Restore the plan from the backup if the agent damaged it
+		if planBackedUp {
+			planRepairGuidance = checkPlanIntegrity(cfg, output, i)
+		}
+
+		// Abort and revert the iteration if it touched files outside the repository
+		if pathGuard != nil {
+			if violations := pathGuard.Check(guardSnapshot); len(violations) > 0 {
+				revertErr := pathGuard.Revert(guardSnapshot, violations)
+				reportGuardViolations(output, cfg, fmt.Sprintf("Iteration %d", i), violations, revertErr)
+				summary.Errors = append(summary.Errors, fmt.Sprintf("iteration %d modified files outside the repository", i))
+				iterationTests = append(iterationTests, iterationTest(i, currentFeatureID, currentFeatureDesc, iterStart,
+					"Reverted: modified files outside the repository"))
+				additionalPromptGuidance = "IMPORTANT: The previous iteration was reverted because it modified files outside the repository. " +
+					"Only create or modify files inside the repository."
+				if variant != nil {
+					variant.RecordIteration(currentFeatureID, true, nil)
+				}
+				runPostIterationHooks(cfg, output, hookRunner, hookContext, hooks.ResultRolledBack, "modified files outside the repository")
+				output.Print("")
+				continue
+			}
+		}
+
+		// Roll back iterations that change protected paths or add forbidden dependencies
+		if pol.ChecksChanges() {
+			if violations := policyViolations(pol, iterSnapshot); len(violations) > 0 {
+				output.Error("Iteration %d violates the policy in %s:\n%s", i, pol.Path, policy.FormatViolations(violations))
+				if rollbackErr := iterSnapshot.Rollback(); rollbackErr != nil {
+					output.Error("Failed to roll back iteration %d: %v", i, rollbackErr)
+				} else {
+					output.Warn("Iteration %d changes rolled back", i)
+				}
+				appendProgress(cfg.ProgressFile, fmt.Sprintf("POLICY: iteration %d rolled back (%d violation(s))", i, len(violations)))
+				summary.Errors = append(summary.Errors, fmt.Sprintf("iteration %d violated the policy", i))
+				iterationTests = append(iterationTests, iterationTest(i, currentFeatureID, currentFeatureDesc, iterStart,
+					"Rolled back: violated the policy\n"+policy.FormatViolations(violations)))
+				additionalPromptGuidance = "IMPORTANT: The previous iteration was rolled back because it violated the project policy:\n" +
+					policy.FormatViolations(violations) + "\nDo not modify protected paths or add forbidden dependencies."
+				if variant != nil {
+					variant.RecordIteration(currentFeatureID, true, nil)
+				}
+				runPostIterationHooks(cfg, output, hookRunner, hookContext, hooks.ResultRolledBack, "violated the policy")
+				output.Print("")
+				continue
+			}
+		}
+
+		// Wait for the human to approve the iteration before checking it and moving on
+		checksFailed := false
+		lintOutput := "" // Output of a failed lint check
+		if needsReview {
+			notifyDesktop(notifier, output, "Ralph: approval needed", fmt.Sprintf("Iteration %d is waiting for your review", i))
+			review, reviewErr := reviewer.Review(i, iterSnapshot)
+			if reviewErr != nil {
+				return fmt.Errorf("approval of iteration %d: %w", i, reviewErr)
+			}
+
+			if review.Decision == approval.Reject {
+				if rollbackErr := iterSnapshot.Rollback(); rollbackErr != nil {
+					output.Error("Failed to roll back iteration %d: %v", i, rollbackErr)
+				} else {
+					output.Warn("Iteration %d rejected - changes rolled back", i)
+				}
+				appendProgress(cfg.ProgressFile, fmt.Sprintf("REJECTED: iteration %d changes rolled back by reviewer", i))
+				summary.Errors = append(summary.Errors, fmt.Sprintf("iteration %d rejected by reviewer", i))
+				iterationTests = append(iterationTests, iterationTest(i, currentFeatureID, currentFeatureDesc, iterStart,
+					strings.TrimSpace("Rejected by the reviewer\n"+review.Reason)))
+				additionalPromptGuidance = "IMPORTANT: The previous iteration's changes were rejected by the human reviewer and rolled back."
+				if review.Reason != "" {
+					additionalPromptGuidance += " Reviewer feedback: " + review.Reason
+				}
+				if variant != nil {
+					variant.RecordIteration(currentFeatureID, true, nil)
+				}
+				runPostIterationHooks(cfg, output, hookRunner, hookContext, hooks.ResultRolledBack, strings.TrimSpace("rejected by the reviewer "+review.Reason))
+				output.Print("")
+				continue
+			}
+
+			// Approved - verify the changes; failures go through normal recovery
+			if checkOutput, checkErr := runChecks(cfg, pol, output); checkErr != nil {
+				output.Error("%v", checkErr)
+				if checkOutput = strings.TrimSpace(checkOutput); checkOutput != "" {
+					output.Print("%s", checkOutput)
+				}
+				checksFailed = true
+				err = checkErr
+				exitCode = 1
+				result += "\n" + checkOutput
+				if isLintFailure(checkErr) {
+					lintOutput = checkOutput
+				}
+			}
+		}
+
+		// Extract and store any memories from the agent output
+		memoriesStored := extractAndStoreMemories(memStore, result, "",
+			memory.Provenance{FeatureID: currentFeatureID, Iteration: i, Agent: agentName(agentCfg)})
+		if memoriesStored > 0 && cfg.Verbose {
+			output.Debug("Extracted and stored %d new memories from agent output", memoriesStored)
+		}
+
+		// Acknowledge nudges that were injected into this iteration, unless
+		// sticky or expiring, and scoped nudges whose feature or milestone
+		// is now complete
+		if len(activeNudges) > 0 || nudgeStore.ActiveCount() > 0 {
+			acked, err := nudgeStore.AcknowledgeInjected(activeNudges)
+			if err == nil {
+				if updatedPlans, readErr := plan.ReadFile(cfg.PlanFile); readErr == nil {
+					var completed []nudge.Nudge
+					completed, err = nudgeStore.CompleteScoped(updatedPlans)
+					acked = append(acked, completed...)
+				}
+			}
+			if err != nil {
+				output.Debug("Failed to acknowledge nudges: %v", err)
+			}
+			if len(acked) > 0 {
+				// Log nudge acknowledgment to progress file
+				appendProgress(cfg.ProgressFile, nudge.FormatAcknowledgment(acked))
+				if cfg.Verbose {
+					output.Debug("Acknowledged %d nudge(s)", len(acked))
+				}
+			}
+		}
+
+		// Track test results across iterations to find flaky tests
+		testResults := flaky.Parse(result)
+		flakyFound = append(flakyFound, observeFlakyTests(cfg, output, flakyStore, iterSnapshot, testResults)...)
+
+		// Classify the output against the failure patterns; warnings are only reported
+		match := classifier.Classify(result)
+		if match != nil && !match.Failed() {
+			output.Warn("%s: %s", match.Rule, match.Line)
+		}
+
+		// Failures of quarantined flaky tests alone do not count as a failure
+		if match.Failed() {
+			if quarantined := flakyStore.OnlyFlakyFailures(testResults); len(quarantined) > 0 && !classifier.Classify(flaky.Strip(result)).Failed() {
+				output.Warn("Ignoring failures of quarantined flaky test(s): %s", flakyNames(quarantined))
+				match = nil
+			}
+		}
+
+		// Lint the iteration's changes; with approval, the checks already did
+		lintFailed := checksFailed && isLintFailure(err)
+		if !needsReview && cfg.LintCmd != "" && err == nil && !match.Failed() {
+			out, lintErr := runCheck(pol, output, checkLint, cfg.LintCmd)
+			if lintErr != nil {
+				output.Error("%v", lintErr)
+				if lintOutput = strings.TrimSpace(out); lintOutput != "" {
+					output.Print("%s", lintOutput)
+				}
+				checksFailed = true
+				lintFailed = true
+				err = lintErr
+				exitCode = 1
+				result += "\n" + lintOutput
+			}
+		}
+
+		// Features only stay tested if their validations pass
+		var validationErr error
+		if cfg.ValidateOnComplete && err == nil && !match.Failed() && !checksFailed {
+			if tested := newlyTestedFeatures(cfg.PlanFile, validationSeen); len(tested) > 0 {
+				if validationErr = checkFeatureValidations(cfg, output, pol, pathGuard, tested, validationSeen, validationResults); validationErr != nil {
+					checksFailed = true
+					err = validationErr
+					exitCode = 1
+					result += "\n" + validationErr.Error()
+				}
+			}
+		}
+
+		// Features may only be marked tested while coverage passes the gate
+		var coverageErr error
+		if coverageGate != nil && err == nil && !match.Failed() && !checksFailed {
+			if tested := newlyTestedFeatures(cfg.PlanFile, coverageSeen); len(tested) > 0 {
+				if coverageErr = checkCoverageGate(cfg, output, coverageGate, &coverageTrend, i, tested, coverageSeen); coverageErr != nil {
+					checksFailed = true
+					err = coverageErr
+					exitCode = 1
+					result += "\n" + coverageErr.Error()
+					// Unmarked features are validated again when next marked tested
+					for _, id := range tested {
+						delete(validationSeen, id)
+					}
+				}
+			}
+		}
+
+		// Features may only stay tested once the reviewer agents approve
+		var reviewErr error
+		if reviewGate != nil && err == nil && !match.Failed() && !checksFailed {
+			if tested := newlyTestedFeatures(cfg.PlanFile, reviewSeen); len(tested) > 0 {
+				if reviewErr = checkReviewGate(cfg, output, reviewGate, i, currentFeatureID, currentFeatureDesc, iterPrompt, result, tested, reviewSeen); reviewErr != nil {
+					checksFailed = true
+					err = reviewErr
+					exitCode = 1
+					result += "\n" + reviewErr.Error()
+					// Unmarked features are checked again when next marked tested
+					for _, id := range tested {
+						delete(validationSeen, id)
+						delete(coverageSeen, id)
+					}
+				}
+			}
+		}
+
+		// Record when features stay tested, for -changelog
+		for _, id := range newlyTestedFeatures(cfg.PlanFile, completedSeen) {
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("TESTED: feature #%d completed in iteration %d", id, i))
+		}
+
+		// Attribute this iteration's outcome to the experiment variant
+		if variant != nil {
+			failed := err != nil || match.Failed()
+			variant.RecordIteration(currentFeatureID, failed, newlyTestedFeatures(cfg.PlanFile, testedSoFar))
+		}
+
+		// Memories whose advice keeps preceding failures lose confidence
+		if len(injectedMemories) > 0 {
+			ids := make([]string, len(injectedMemories))
+			for j, e := range injectedMemories {
+				ids[j] = e.ID
+			}
+			failed := err != nil || match.Failed()
+			if recordErr := memStore.RecordOutcome(ids, failed); recordErr != nil {
+				output.Debug("Failed to record memory outcomes: %v", recordErr)
+			}
+		}
+
+		// Goals are complete once all their plan items are tested
+		completeGoals(cfg, output, notifier, pol, pathGuard, goalsFailed)
+
+		// Check for newly completed milestones
+		if milestoneMgr != nil && milestoneMgr.HasMilestones() {
+			// Reload plans to get updated tested status
+			updatedPlans, err := plan.ReadFile(cfg.PlanFile)
+			if err == nil {
+				milestoneMgr, _ = newMilestoneManager(cfg, updatedPlans)
+
+				// Check for newly completed milestones
+				for _, p := range milestoneMgr.GetCompletedMilestones() {
+					if !completedMilestonesBefore[p.Milestone.Name] {
+						output.Success("%s", milestone.CelebrationMessage(p.Milestone.Name))
+						notifyDesktop(notifier, output, "Ralph: milestone complete", fmt.Sprintf("%s is complete (%d features)", p.Milestone.Name, p.TotalFeatures))
+						completedMilestonesBefore[p.Milestone.Name] = true
+						milestoneContext := hookContext
+						milestoneContext.Milestone = p.Milestone.Name
+						runHooks(cfg, output, hookRunner, hooks.OnMilestoneComplete, milestoneContext)
+						if cfg.ChangelogFile != "" {
+							if err := writeChangelog(cfg); err != nil {
+								output.Warn("Failed to update the changelog: %v", err)
+							} else {
+								output.Info("Changelog updated: %s", cfg.ChangelogFile)
+							}
+						}
+					}
+				}
+			}
+		}
+
+		// The completion signal only counts if the plan agrees nothing is left to do
+		incompleteGuidance := ""
+		signaled := !checksFailed && strings.Contains(result, prompt.CompleteSignal)
+		if signaled {
+			if remaining, err := unfinishedFeatures(cfg.PlanFile); err != nil {
+				output.Debug("Not verifying the completion signal: %v", err)
+			} else if len(remaining) > 0 {
+				output.Warn("Completion signal ignored: %d feature(s) in %s are still untested", len(remaining), cfg.PlanFile)
+				appendProgress(cfg.ProgressFile, fmt.Sprintf("INCOMPLETE: completion signaled at iteration %d with %d feature(s) untested", i, len(remaining)))
+				incompleteGuidance = incompletePlanGuidance(cfg.PlanFile, remaining)
+				signaled = false
+			}
+		}
+
+		// Check for completion signal (even if there was an error, the output might contain it)
+		if signaled {
+			output.Success("Plan complete! Detected completion signal after %d iteration(s).", i)
+			runPostIterationHooks(cfg, output, hookRunner, hookContext, hooks.ResultSuccess, "")
+			runHooks(cfg, output, hookRunner, hooks.OnComplete, hookContext)
+			iterationTests = append(iterationTests, iterationTest(i, currentFeatureID, currentFeatureDesc, iterStart, ""))
+			summary.FeaturesCompleted++
+			summary.EndTime = time.Now()
+			summary.FailuresRecovered = recoveryMgr.GetRecoveredCount()
+			output.PrintSummary(summary)
+			printRecoverySummaryUI(output, recoveryMgr, cfg.Verbose)
+			printFlakySummary(output, flakyStore, flakyFound)
+			printCoverageSummary(output, &coverageTrend)
+			recordRunHistory(cfg, output, runRecord, testedBefore, scopeMgr, recoveryMgr, validationResults, summary, true)
+			writeRunJUnit(cfg, output, iterationTests)
+			writeRunSummaryMarkdown(cfg, output, runRecord, summary, validationResults)
+			writeEnvironmentReports(cfg, output, envProfile, runRecord, iterationTests)
+			notifyDesktop(notifier, output, "Ralph: plan complete",
+				fmt.Sprintf("%d feature(s) completed in %d iteration(s)", len(runRecord.FeaturesCompleted), i))
+			recordTelemetry(cfg, output, runRecord, recoveryMgr, replans)
+			recordExperimentHistory(cfg, output, exp, runRecord)
+			
+			// Show scope summary if scope control was active
+			if cfg.ScopeLimit > 0 || cfg.Deadline != "" || cfg.FeatureDeadline != "" {
+				printScopeSummary(output, scopeMgr, cfg.Verbose)
+			}
+			
+			// Show final milestone status
+			if milestoneMgr != nil && milestoneMgr.HasMilestones() {
+				output.SubHeader("Final Milestone Status")
+				output.Print("%s", milestoneMgr.Summary())
+			}
+			return nil
+		}
+		
+		// Feed the progress of the run to the proactive replan triggers
+		if current, err := plan.ReadFile(cfg.PlanFile); err == nil {
+			plans = current
+			replanMgr.UpdateProgress(replanProgress(scopeMgr, currentFeatureID, plans, testedBefore, historyFeatureDuration), plans)
+		}
+
+		// Handle failure detection and recovery
+		iterFailure := ""
+		if err != nil || match.Failed() {
+			if exitCode == 0 && match.Failed() {
+				exitCode = 1 // Treat as failure even if command succeeded
+			}
+
+			var failure *recovery.Failure
+			var recoveryResult recovery.RecoveryResult
+			if timedOut {
+				failure, recoveryResult = recoveryMgr.HandleTimeout(result, iterationTimeout, currentFeatureID, i)
+			} else if lintFailed {
+				failure, recoveryResult = recoveryMgr.HandleLintFailure(lintOutput, currentFeatureID, i)
+			} else {
+				failure, recoveryResult = recoveryMgr.HandleFailure(result, exitCode, currentFeatureID, i)
+			}
+			
+			if failure != nil {
+				output.Warn("Failure detected: %s", failure)
+				summary.Errors = append(summary.Errors, failure.String())
+				iterFailure = strings.TrimSpace(failure.String() + "\n\n" + lastLines(failure.Output, junitOutputLines))
+				
+				// Track consecutive failures for replanning
+				consecutiveFailures++
+				
+				// Log failure to progress file
+				logFailureToProgress(cfg.ProgressFile, failure)
+
+				if recoveryResult.ShouldSkip {
+					output.Info("Recovery: %s", recoveryResult.Message)
+					summary.FeaturesSkipped++
+					// Add to blocked features for replan tracking
+					replanMgr.AddBlockedFeature(currentFeatureID)
+					// Reset consecutive failures when skipping
+					consecutiveFailures = 0
+				} else if recoveryResult.ShouldRetry {
+					output.Info("Recovery: %s", recoveryResult.Message)
+					// Set additional guidance for the retry
+					if recoveryResult.ModifiedPrompt != "" {
+						additionalPromptGuidance = recoveryResult.ModifiedPrompt
+					}
+					// Name the failed tests when structured test results are available
+					if failure.Type == recovery.FailureTypeTest {
+						if tests := failedTestGuidance(cfg, output, result, iterStart); tests != "" {
+							additionalPromptGuidance = strings.TrimSpace(additionalPromptGuidance + "\n\n" + tests)
+						}
+					}
+					if validationErr != nil {
+						additionalPromptGuidance = strings.TrimSpace(additionalPromptGuidance + "\n\nIMPORTANT: " + validationErr.Error() +
+							"\nFix the feature so its validations pass before marking it as tested again.")
+					}
+					if coverageErr != nil {
+						additionalPromptGuidance = strings.TrimSpace(additionalPromptGuidance + "\n\nIMPORTANT: Blocked by the " + coverageErr.Error() +
+							". Add tests for the code you wrote before marking the feature as tested again.")
+					}
+					if reviewErr != nil {
+						additionalPromptGuidance = strings.TrimSpace(additionalPromptGuidance + "\n\nIMPORTANT: Blocked by the " + reviewErr.Error() +
+							"\nAddress the review before marking the feature as tested again.")
+					}
+					// Back off before retrying, unless this was the last iteration
+					if recoveryResult.Delay > 0 && i < cfg.Iterations {
+						output.Info("Waiting %s before retrying", recoveryResult.Delay.Round(time.Second))
+						time.Sleep(recoveryResult.Delay)
+					}
+				}
+
+				if !recoveryResult.Success {
+					output.Error("Recovery action failed: %s", recoveryResult.Message)
+					summary.FeaturesFailed++
+				}
+				
+				// Check for replanning triggers
+				replanMgr.UpdateState(currentFeatureID, consecutiveFailures, []string{string(failure.Type)}, plans)
+				replanMgr.IncrementIterations()
+				
+				if shouldReplan, trigger := replanMgr.ShouldReplan(); shouldReplan {
+					if newPlans := executeReplan(cfg, output, replanMgr, replanStrategyType, trigger); newPlans != nil {
+						// Update local plans reference
+						plans = newPlans
+						// Reset consecutive failures after replanning
+						consecutiveFailures = 0
+						replans++
+					}
+				}
+			} else if err != nil {
+				// Agent execution error but no specific failure detected
+				output.Error("Agent execution error: %v", err)
+				summary.Errors = append(summary.Errors, err.Error())
+				iterFailure = err.Error()
+				consecutiveFailures++
+			}
+		} else {
+			// Iteration completed without obvious failures
+			// Reset consecutive failures on success
+			consecutiveFailures = 0
+			replanMgr.ResetState()
+
+			// Replan ahead of failures when the run is falling behind
+			if shouldReplan, trigger := replanMgr.ShouldReplanProactively(); shouldReplan {
+				if newPlans := executeReplan(cfg, output, replanMgr, replanStrategyType, trigger); newPlans != nil {
+					plans = newPlans
+					replans++
+				}
+			}
+		}
+		if iterFailure != "" {
+			failureContext := hookContext
+			failureContext.Error = iterFailure
+			runHooks(cfg, output, hookRunner, hooks.OnFailure, failureContext)
+		}
+		if incompleteGuidance != "" {
+			additionalPromptGuidance = strings.TrimSpace(additionalPromptGuidance + "\n\n" + incompleteGuidance)
+			if iterFailure == "" {
+				iterFailure = "Signaled completion with features still untested"
+			}
+		}
+		if iterFailure != "" {
+			runPostIterationHooks(cfg, output, hookRunner, hookContext, hooks.ResultFailure, iterFailure)
+		} else {
+			runPostIterationHooks(cfg, output, hookRunner, hookContext, hooks.ResultSuccess, "")
+		}
+		iterationTests = append(iterationTests, iterationTest(i, currentFeatureID, currentFeatureDesc, iterStart, iterFailure))
+
+		output.Print("") // Empty line between iterations
+	}
+
+	if !interrupted {
+		output.Info("Completed %d iteration(s) without completion signal.", cfg.Iterations)
+	}
+	summary.EndTime = time.Now()
+	summary.FailuresRecovered = recoveryMgr.GetRecoveredCount()
+	output.PrintSummary(summary)
+	printRecoverySummaryUI(output, recoveryMgr, cfg.Verbose)
+	printFlakySummary(output, flakyStore, flakyFound)
+	printCoverageSummary(output, &coverageTrend)
+	recordRunHistory(cfg, output, runRecord, testedBefore, scopeMgr, recoveryMgr, validationResults, summary, false)
+	writeRunJUnit(cfg, output, iterationTests)
+	writeRunSummaryMarkdown(cfg, output, runRecord, summary, validationResults)
+	writeEnvironmentReports(cfg, output, envProfile, runRecord, iterationTests)
+	notifyDesktop(notifier, output, "Ralph: run finished",
+		fmt.Sprintf("%d iteration(s) run, %d feature(s) completed; the plan is not complete yet", summary.IterationsRun, len(runRecord.FeaturesCompleted)))
+	recordTelemetry(cfg, output, runRecord, recoveryMgr, replans)
+	recordExperimentHistory(cfg, output, exp, runRecord)
+	
+	// Print scope summary if scope control was active
+	if cfg.ScopeLimit > 0 || cfg.Deadline != "" || cfg.FeatureDeadline != "" {
+		printScopeSummary(output, scopeMgr, cfg.Verbose)
+	}
+	
+	// Print memory summary if we have memories
+	if memStore.Count() > 0 && cfg.Verbose {
+		output.SubHeader("Memory Status")
+		output.Print("Total memories: %d (stored in %s)", memStore.Count(), cfg.MemoryFile)
+	}
+	
+	// Print milestone summary if milestones are defined
+	if milestoneMgr != nil && milestoneMgr.HasMilestones() {
+		// Reload plans to get updated tested status
+		updatedPlans, err := plan.ReadFile(cfg.PlanFile)
+		if err == nil {
+			milestoneMgr, _ = newMilestoneManager(cfg, updatedPlans)
+		}
+		output.SubHeader("Milestone Progress")
+		output.Print("%s", milestoneMgr.Summary())
 		
-		// Stop spinner
-		if spinner != nil {
-			spinner.Stop()
+		// Show next milestone to complete
+		next := milestoneMgr.GetNextMilestoneToComplete()
+		if next != nil {
+			output.Info("Next milestone: %s (%s)",
+				next.Milestone.Name,
+				milestone.FormatProgressBar(next, 20))
+		}
+	}
+	
+	if interrupted {
+		return agent.ErrInterrupted
+	}
+	return nil
+}
+
+// checkPlanIntegrity checks the plan file after an iteration and restores it
+// from the backup taken before the iteration if the agent damaged it. It
+// returns guidance telling the agent what went wrong, or "" if nothing did.
+func checkPlanIntegrity(cfg *config.Config, output *ui.UI, iteration int) string {
+	problems, err := plan.CheckIntegrity(cfg.PlanFile, plan.BackupFile)
+	if err != nil {
+		output.Debug("Failed to check plan integrity: %v", err)
+		return ""
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+
+	output.Warn("Iteration %d damaged %s:", iteration, cfg.PlanFile)
+	for _, problem := range problems {
+		output.Print("  - %s", problem)
+	}
+	if _, err := plan.RestoreBackup(cfg.PlanFile, plan.BackupFile); err != nil {
+		output.Error("Failed to restore %s from %s: %v", cfg.PlanFile, plan.BackupFile, err)
+		return ""
+	}
+	output.Info("Restored %s from the backup taken before the iteration", cfg.PlanFile)
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("PLAN: iteration %d damaged %s (%s); restored from backup",
+		iteration, cfg.PlanFile, strings.Join(problems, "; ")))
+
+	return fmt.Sprintf("IMPORTANT: The previous iteration damaged %s, so it was restored from the version before that iteration "+
+		"(features marked tested were kept):\n- %s\n"+
+		"Keep %s a valid JSON array, and keep every feature with its id, description, steps and validations. "+
+		"Only change the \"tested\" field of the feature you completed.",
+		cfg.PlanFile, strings.Join(problems, "\n- "), cfg.PlanFile)
+}
+
+// maxUnfinishedListed is the number of unfinished features named in the
+// guidance after a premature completion signal
+const maxUnfinishedListed = 10
+
+// unfinishedFeatures returns the features of the plan that still have to be
+// done: untested, not deferred and not open questions
+func unfinishedFeatures(planFile string) ([]plan.Plan, error) {
+	plans, err := plan.ReadFile(planFile)
+	if err != nil {
+		return nil, err
+	}
+	var remaining []plan.Plan
+	for _, p := range plans {
+		if p.IsActionable() {
+			remaining = append(remaining, p)
+		}
+	}
+	return remaining, nil
+}
+
+// incompletePlanGuidance tells the agent that it signaled completion while
+// features are still untested, naming them
+func incompletePlanGuidance(planFile string, remaining []plan.Plan) string {
+	var ids []string
+	var lines []string
+	for i, p := range remaining {
+		ids = append(ids, strconv.Itoa(p.ID))
+		if i < maxUnfinishedListed {
+			lines = append(lines, fmt.Sprintf("- #%d: %s", p.ID, p.Description))
+		}
+	}
+	if len(remaining) > maxUnfinishedListed {
+		lines = append(lines, fmt.Sprintf("- ... and %d more", len(remaining)-maxUnfinishedListed))
+	}
+
+	features := "feature " + ids[0] + " is"
+	switch {
+	case len(ids) > maxUnfinishedListed:
+		features = fmt.Sprintf("%d features are", len(ids))
+	case len(ids) > 1:
+		features = "features " + strings.Join(ids[:len(ids)-1], ", ") + " and " + ids[len(ids)-1] + " are"
+	}
+	return fmt.Sprintf("IMPORTANT: You signaled that the plan is complete, but %s still untested in %s:\n%s\n"+
+		"Implement and verify them and mark them \"tested\": true before signaling completion.",
+		features, planFile, strings.Join(lines, "\n"))
+}
+
+// junitOutputLines is the number of lines of agent output kept with a failed
+// iteration in the JUnit report
+const junitOutputLines = 50
+
+// iterationTest returns the outcome of an iteration as a test case of the
+// feature it worked on; failure is empty if the iteration succeeded
+func iterationTest(iteration, featureID int, featureDesc string, start time.Time, failure string) testreport.TestCase {
+	tc := testreport.TestCase{
+		Suite:    "Iterations",
+		Name:     fmt.Sprintf("iteration %d", iteration),
+		Status:   testreport.StatusPassed,
+		Duration: time.Since(start),
+	}
+	if featureID > 0 {
+		tc.Suite = fmt.Sprintf("Feature #%d", featureID)
+		if featureDesc != "" {
+			tc.Suite += ": " + featureDesc
+		}
+	}
+	if failure != "" {
+		tc.Status = testreport.StatusFailed
+		tc.Message = secretRedactor.Redact(failure)
+	}
+	return tc
+}
+
+// lastLines returns the last n lines of s
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeRunJUnit writes the outcome of each iteration, and the features of the
+// plan, as JUnit XML to the -junit-output file. Tested features pass and the
+// others are skipped, with deferred features reported as failed.
+func writeRunJUnit(cfg *config.Config, output *ui.UI, iterations []testreport.TestCase) {
+	if cfg.JUnitOutput == "" {
+		return
+	}
+	if err := runJUnitReport(cfg, iterations).WriteJUnitXML(cfg.JUnitOutput, "ralph"); err != nil {
+		output.Warn("Failed to write JUnit report: %v", err)
+		return
+	}
+	output.Info("JUnit report: %s", cfg.JUnitOutput)
+}
+
+// runJUnitReport returns the features of the plan and the outcome of each
+// iteration as test cases
+func runJUnitReport(cfg *config.Config, iterations []testreport.TestCase) *testreport.Report {
+	report := &testreport.Report{Format: "junit"}
+	if plans, err := plan.ReadFile(cfg.PlanFile); err == nil {
+		for _, p := range plans {
+			tc := testreport.TestCase{Suite: "Features", Name: fmt.Sprintf("#%d %s", p.ID, p.Description), Status: testreport.StatusPassed}
+			switch {
+			case p.Deferred:
+				tc.Status, tc.Message = testreport.StatusFailed, strings.TrimSpace("Deferred: "+p.DeferReason)
+			case !p.Tested:
+				tc.Status, tc.Message = testreport.StatusSkipped, "not tested yet"
+			}
+			report.Tests = append(report.Tests, tc)
+		}
+	}
+	report.Tests = append(report.Tests, iterations...)
+	return report
+}
+
+// writeEnvironmentReports writes the reports of the CI environment to the
+// report directory if -environment-reports is enabled: a JUnit report and a
+// dotenv file on GitLab CI, a JUnit report, a properties file and build
+// markers on Jenkins. The run record must already be finalized.
+func writeEnvironmentReports(cfg *config.Config, output *ui.UI, env *environment.EnvironmentProfile, run *history.Run, iterations []testreport.TestCase) {
+	if !cfg.EnvironmentReports {
+		return
+	}
+	if !env.SupportsReports() {
+		output.Debug("No CI reports for the %s environment", env.Type)
+		return
+	}
+
+	junitPath := filepath.Join(cfg.ReportDir, environment.JUnitReportFile)
+	if err := runJUnitReport(cfg, iterations).WriteJUnitXML(junitPath, "ralph"); err != nil {
+		output.Warn("Failed to write the %s JUnit report: %v", env.Type, err)
+	} else {
+		output.Info("CI report: %s", junitPath)
+	}
+
+	outcome := environment.RunOutcome{
+		RunID:             run.ID,
+		Iterations:        run.IterationsRun,
+		FeaturesCompleted: len(run.FeaturesCompleted),
+		Failures:          run.Failures,
+		PlanComplete:      run.Completed,
+	}
+	if plans, err := plan.ReadFile(cfg.PlanFile); err == nil {
+		s := plan.Summarize(plans)
+		outcome.FeaturesTested, outcome.FeaturesTotal = s.Tested, s.Total
+	}
+	paths, err := env.WriteReports(cfg.ReportDir, outcome)
+	if err != nil {
+		output.Warn("Failed to write the %s reports: %v", env.Type, err)
+	}
+	for _, path := range paths {
+		output.Info("CI report: %s", path)
+	}
+	for _, marker := range env.Markers(outcome) {
+		output.Print("%s", marker)
+	}
+}
+
+// notifyDesktop shows a desktop notification if -notify is enabled. Failures
+// (e.g., no notification daemon) never interrupt the run.
+func notifyDesktop(notifier *notify.Notifier, output *ui.UI, title, message string) {
+	if err := notifier.Notify(title, message); err != nil {
+		output.Debug("Desktop notification: %v", err)
+	}
+}
+
+// writeRunSummaryMarkdown writes the end-of-run summary as Markdown to the
+// -summary-markdown file. The run record must already be finalized.
+func writeRunSummaryMarkdown(cfg *config.Config, output *ui.UI, run *history.Run, summary ui.Summary, validations map[int]validation.ValidationRunResult) {
+	if cfg.SummaryMarkdown == "" {
+		return
+	}
+
+	s := &runsummary.Summary{Run: run}
+	for _, e := range summary.Errors {
+		s.Errors = append(s.Errors, secretRedactor.Redact(e))
+	}
+	for _, v := range validations {
+		s.Validations = append(s.Validations, v)
+	}
+	if plans, err := plan.ReadFile(cfg.PlanFile); err == nil {
+		s.Plans = plans
+		if mgr, _ := newMilestoneManager(cfg, plans); mgr.HasMilestones() {
+			s.Milestones = mgr.CalculateAllProgress()
+		}
+	}
+
+	if err := s.Write(cfg.SummaryMarkdown); err != nil {
+		output.Warn("Failed to write Markdown summary: %v", err)
+		return
+	}
+	output.Info("Markdown summary: %s", cfg.SummaryMarkdown)
+}
+
+// executeReplan runs an automatic replan for trigger and reports the
+// outcome. It returns the new plans if the replan was applied, nil otherwise.
+func executeReplan(cfg *config.Config, output *ui.UI, replanMgr *replan.ReplanManager, strategy replan.StrategyType, trigger replan.TriggerType) []plan.Plan {
+	output.SubHeader("Automatic Replanning Triggered")
+	output.Info("Trigger: %s", trigger)
+
+	replanResult, replanErr := replanMgr.ExecuteReplan(strategy, trigger)
+	if replanErr != nil {
+		output.Error("Replanning failed: %v", replanErr)
+		return nil
+	}
+	if !replanResult.Success {
+		output.Warn("Plan unchanged: %s", replanResult.Message)
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("REPLAN: %s triggered, strategy: %s, %s", trigger, strategy, replanResult.Message))
+		return nil
+	}
+
+	output.Success("Replanning completed: %s", replanResult.Message)
+	if replanResult.OldPlanPath != "" {
+		output.Debug("Backup created: %s", replanResult.OldPlanPath)
+	}
+	if replanResult.Diff != nil && !replanResult.Diff.IsEmpty() && !confirmReplans(cfg) {
+		output.Print("%s", replanResult.Diff.Summary())
+	}
+	// Log replan to progress file
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("REPLAN: %s triggered, strategy: %s", trigger, strategy))
+	return replanResult.NewPlans
+}
+
+// replanProgress describes the progress of the run for the proactive replan
+// triggers. The velocity is measured over the features completed in this run,
+// or taken from earlier runs (historyFeatureDuration) until one is.
+func replanProgress(scopeMgr *scope.Manager, featureID int, plans []plan.Plan, testedBefore map[int]bool, historyFeatureDuration time.Duration) replan.Progress {
+	progress := replan.Progress{
+		FeatureID:       featureID,
+		FeatureDuration: historyFeatureDuration,
+		TimeRemaining:   scopeMgr.RemainingTime(),
+	}
+	if fs := scopeMgr.GetFeatureScope(featureID); fs != nil {
+		progress.FeatureIterations = fs.IterationsUsed
+		progress.EstimatedIterations = scopeMgr.EstimatedIterations(featureID)
+	}
+
+	completed := 0
+	for _, p := range plans {
+		if p.Tested && !testedBefore[p.ID] {
+			completed++
+		}
+	}
+	if completed > 0 {
+		progress.FeatureDuration = scopeMgr.GetElapsedTime() / time.Duration(completed)
+	}
+	return progress
+}
+
+// scopeCalibration builds the scope estimates from the iterations the
+// features completed by earlier runs of the agent took
+func scopeCalibration(cfg *config.Config) *scope.Calibration {
+	calibration := scope.NewCalibration()
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil {
+		return calibration
+	}
+	for complexity, iterations := range history.CompletedFeatureIterations(runs, agentName(cfg)) {
+		for _, n := range iterations {
+			calibration.Add(scope.Complexity(complexity), n)
+		}
+	}
+	return calibration
+}
+
+// recordRunHistory finalizes the run record and saves it to the history directory
+func recordRunHistory(cfg *config.Config, output *ui.UI, run *history.Run, testedBefore map[int]bool, scopeMgr *scope.Manager, recoveryMgr *recovery.RecoveryManager, validationResults map[int]validation.ValidationRunResult, summary ui.Summary, completed bool) {
+	run.EndTime = summary.EndTime
+	run.IterationsRun = summary.IterationsRun
+	run.Completed = completed
+	run.FeaturesSkipped = summary.FeaturesSkipped
+	run.Failures = len(summary.Errors)
+	run.FailuresRecovered = summary.FailuresRecovered
+
+	if cfg.UsesAPIBackend() {
+		usage := agent.TokenUsage()
+		run.InputTokens = usage.InputTokens
+		run.OutputTokens = usage.OutputTokens
+		run.Cost = agent.EstimatedCost(cfg)
+		output.Info("Token usage: %d input, %d output", usage.InputTokens, usage.OutputTokens)
+	}
+
+	for id, count := range scopeMgr.GetStatus().IterationsPerFeature {
+		if id > 0 {
+			run.IterationsPerFeature[id] = count
+			if run.FeatureComplexity == nil {
+				run.FeatureComplexity = make(map[int]string)
+			}
+			run.FeatureComplexity[id] = string(scopeMgr.GetFeatureScope(id).EstimatedComplexity)
+		}
+	}
+
+	recordFeatureOutcomes(run, scopeMgr, recoveryMgr, validationResults)
+
+	// Features completed are those newly marked as tested during this run
+	if plans, err := plan.ReadFile(cfg.PlanFile); err == nil {
+		s := plan.Summarize(plans)
+		run.FeaturesTotal, run.FeaturesTested = s.Total, s.Tested
+		for _, p := range plans {
+			if p.Tested && !testedBefore[p.ID] {
+				run.FeaturesCompleted = append(run.FeaturesCompleted, p.ID)
+			}
+			_, validated := run.Validations[p.ID]
+			_, deferred := run.Deferrals[p.ID]
+			if run.IterationsPerFeature[p.ID] > 0 || run.FeatureFailures[p.ID] > 0 || validated || deferred {
+				run.FeatureCategories[p.ID] = p.Category
+			}
+		}
+	}
+
+	store := history.NewStore(cfg.HistoryDir)
+	if err := store.Save(run); err != nil {
+		output.Debug("Failed to record run history: %v", err)
+		return
+	}
+	output.Debug("Run recorded as %s in %s", run.ID, store.Dir())
+}
+
+// recordFeatureOutcomes adds the failures, validation results and deferrals
+// of the features worked on to the run record
+func recordFeatureOutcomes(run *history.Run, scopeMgr *scope.Manager, recoveryMgr *recovery.RecoveryManager, validationResults map[int]validation.ValidationRunResult) {
+	run.FeatureCategories = make(map[int]string)
+	run.FeatureFailures = recoveryMgr.GetTracker().CountByFeature()
+	run.FailuresByType = make(map[string]int)
+	for t, n := range recoveryMgr.GetTracker().CountByType() {
+		run.FailuresByType[string(t)] = n
+	}
+	run.Validations = make(map[int]history.ValidationCount)
+	for id, result := range validationResults {
+		run.Validations[id] = history.ValidationCount{Passed: result.PassedCount, Failed: result.FailedCount}
+	}
+	run.Deferrals = make(map[int]string)
+	for _, id := range scopeMgr.GetStatus().DeferredFeatureIDs {
+		if fs := scopeMgr.GetFeatureScope(id); fs != nil {
+			run.Deferrals[id] = string(fs.DeferReason)
+		}
+	}
+}
+
+// recordTelemetry adds the anonymized outcome of the run to the local
+// telemetry aggregate, when telemetry is enabled
+func recordTelemetry(cfg *config.Config, output *ui.UI, run *history.Run, recoveryMgr *recovery.RecoveryManager, replans int) {
+	if !cfg.Telemetry {
+		return
+	}
+
+	stats := telemetry.RunStats{
+		Agent:             telemetry.AgentName(agentName(cfg)),
+		BuildSystem:       cfg.BuildSystem,
+		Iterations:        run.IterationsRun,
+		FeaturesCompleted: len(run.FeaturesCompleted),
+		FeaturesSkipped:   run.FeaturesSkipped,
+		Failures:          run.Failures,
+		FailuresRecovered: run.FailuresRecovered,
+		FailuresByType:    make(map[string]int),
+		StrategyUsage:     make(map[string]int),
+		Replans:           replans,
+		Completed:         run.Completed,
+		Duration:          run.Duration(),
+	}
+	if stats.BuildSystem == "" || stats.BuildSystem == "auto" {
+		stats.BuildSystem = detection.DetectBuildSystem()
+	}
+	for t, n := range recoveryMgr.GetTracker().CountByType() {
+		stats.FailuresByType[string(t)] = n
+	}
+	for s, n := range recoveryMgr.StrategyUsage() {
+		stats.StrategyUsage[string(s)] = n
+	}
+
+	if err := telemetry.Record(cfg.TelemetryFile, stats); err != nil {
+		output.Debug("Failed to record telemetry: %v", err)
+		return
+	}
+	output.Debug("Run statistics added to %s", cfg.TelemetryFile)
+}
+
+// newExperiment creates the A/B experiment described by the configuration
+func newExperiment(cfg *config.Config, plans []plan.Plan) (*experiment.Experiment, error) {
+	split, err := experiment.ParseSplit(cfg.ExperimentSplit)
+	if err != nil {
+		return nil, err
+	}
+
+	agentB := cfg.ExperimentAgentB
+	if agentB == "" {
+		agentB = cfg.AgentCmd
+	}
+
+	variantA, err := experiment.NewVariant("A", cfg.AgentCmd, cfg.ExperimentPromptA)
+	if err != nil {
+		return nil, err
+	}
+	variantB, err := experiment.NewVariant("B", agentB, cfg.ExperimentPromptB)
+	if err != nil {
+		return nil, err
+	}
+
+	var featureIDs []int
+	for _, p := range plans {
+		if !p.Tested && !p.IsQuestion() {
+			featureIDs = append(featureIDs, p.ID)
+		}
+	}
+
+	return experiment.New(split, variantA, variantB, cfg.Iterations, featureIDs), nil
+}
+
+// newlyTestedFeatures re-reads the plan and returns features that became tested
+// since the last call, updating seen accordingly
+func newlyTestedFeatures(planFile string, seen map[int]bool) []int {
+	plans, err := plan.ReadFile(planFile)
+	if err != nil {
+		return nil
+	}
+
+	var ids []int
+	for _, p := range plans {
+		if p.Tested && !seen[p.ID] {
+			seen[p.ID] = true
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// recordExperimentHistory saves a history record per experiment variant and
+// prints a side-by-side comparison
+func recordExperimentHistory(cfg *config.Config, output *ui.UI, exp *experiment.Experiment, run *history.Run) {
+	if exp == nil {
+		return
+	}
+
+	runA, runB := exp.VariantRuns(run)
+	store := history.NewStore(cfg.HistoryDir)
+	for _, r := range []*history.Run{runA, runB} {
+		if err := store.Save(r); err != nil {
+			output.Debug("Failed to record experiment variant %s: %v", r.ID, err)
+		}
+	}
+
+	output.SubHeader("Experiment Results")
+	output.Print("%s", history.Compare(runA, runB).Format())
+	output.Info("Compare again later with: %s report compare %s %s", os.Args[0], runA.ID, runB.ID)
+}
+
+// checkpointStateFiles returns the Ralph state files captured by checkpoints
+func checkpointStateFiles(cfg *config.Config) []string {
+	return []string{cfg.PlanFile, cfg.ProgressFile, cfg.MemoryFile, cfg.NudgeFile, cfg.GoalsFile}
+}
+
+// ralphStatePaths returns Ralph's own records, which working tree snapshots,
+// rollbacks and checkpoint restores leave alone
+func ralphStatePaths(cfg *config.Config) []string {
+	return []string{cfg.DiffDir, cfg.HistoryDir, cfg.CheckpointDir, cfg.PlanVersionDir, cfg.TelemetryFile, cfg.FlakyFile, cfg.TranscriptDir, prompt.CondensedPlanFile, plan.BackupFile}
+}
+
+// handleCheckpointCommand handles the "checkpoint" subcommand
+func handleCheckpointCommand(cfg *config.Config, args []string) error {
+	store := checkpoint.NewStore(cfg.CheckpointDir)
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s checkpoint <\"label\"|list|restore <ref>|delete <ref>>", os.Args[0])
+	}
+
+	switch args[0] {
+	case "list":
+		cps, err := store.List()
+		if err != nil {
+			return err
+		}
+		fmt.Println(checkpoint.FormatList(cps))
+		return nil
+
+	case "restore":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s checkpoint restore <ref>", os.Args[0])
+		}
+		cp, err := store.Find(args[1])
+		if err != nil {
+			return err
+		}
+
+		// Checkpoint the current state first so the restore can be undone
+		undo, err := store.Create("before restoring "+cp.ID, checkpointStateFiles(cfg), checkpoint.Options{Source: "restore", Exclude: ralphStatePaths(cfg)})
+		if err != nil {
+			return fmt.Errorf("failed to checkpoint current state before restoring: %w", err)
+		}
+
+		if err := store.Restore(cp); err != nil {
+			return err
+		}
+		fmt.Printf("Restored checkpoint %s (%s)\n", cp.ID, cp.Label)
+		fmt.Printf("Previous state saved as %s; undo with: %s checkpoint restore %s\n", undo.ID, os.Args[0], undo.ID)
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("CHECKPOINT: restored %s %q", cp.ID, cp.Label))
+		return nil
+
+	case "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s checkpoint delete <ref>", os.Args[0])
+		}
+		cp, err := store.Find(args[1])
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(cp); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted checkpoint %s (%s)\n", cp.ID, cp.Label)
+		return nil
+
+	default:
+		label := strings.Join(args, " ")
+		cp, err := store.Create(label, checkpointStateFiles(cfg), checkpoint.Options{Source: "cli", Exclude: ralphStatePaths(cfg)})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Checkpoint created: %s (%s)\n", cp.ID, cp.Label)
+		if cp.HasGit() {
+			fmt.Printf("Git: %s", cp.GitHead)
+			if cp.Uncommitted {
+				fmt.Printf(" + uncommitted changes")
+			}
+			fmt.Println()
+		}
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("CHECKPOINT: %s %q", cp.ID, cp.Label))
+		return nil
+	}
+}
+
+// handleQuestionCommand handles the "question" subcommand, the channel for
+// answering question features
+func handleQuestionCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s question <list|answer <id> \"<answer>\">", os.Args[0])
+	}
+
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		questions := plan.FilterQuestions(plans)
+		if cfg.JSONOutput {
+			if questions == nil {
+				questions = []plan.Plan{}
+			}
+			return printPlanListing(cfg, plan.Summarize(plans), questions)
+		}
+		if len(questions) == 0 {
+			fmt.Printf("No open questions in %s\n", cfg.PlanFile)
+			return nil
+		}
+		printOpenQuestions(cfg.PlanFile, questions)
+		return nil
+
+	case "answer":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: %s question answer <id> \"<answer>\"", os.Args[0])
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid feature ID %q", args[1])
+		}
+		err = plan.Update(cfg.PlanFile, func(current []plan.Plan) ([]plan.Plan, error) {
+			plans = current
+			return plans, plan.AnswerQuestion(plans, id, args[2])
+		})
+		if err != nil {
+			return err
+		}
+		p := plan.GetByID(plans, id)
+		fmt.Printf("Feature #%d is now actionable: %s\n", id, p.Description)
+		fmt.Printf("Answer: %s\n", p.Answer)
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("ANSWER: feature #%d %q answered: %s", id, p.Description, p.Answer))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown question command %q (valid: list, answer)", args[0])
+	}
+}
+
+// printOpenQuestions lists open questions with how to answer them
+func printOpenQuestions(planFile string, questions []plan.Plan) {
+	fmt.Printf("=== Open Questions (from %s) ===\n", planFile)
+	for _, q := range questions {
+		fmt.Printf("  %d. %s\n", q.ID, q.Description)
+	}
+	fmt.Printf("\nThese features are skipped until answered: %s question answer <id> \"<answer>\"\n", os.Args[0])
+}
+
+// showAnalytics prints the statistics per feature category of the run
+// history. Without a plan file, features are categorized by the runs alone.
+func showAnalytics(cfg *config.Config) error {
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil {
+		return err
+	}
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	report := analytics.Build(runs, plans)
+
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode analytics: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(report.Format())
+	return nil
+}
+
+// buildChangelog builds the changelog of the plan, dated from the progress
+// log and the run history
+func buildChangelog(cfg *config.Config) (*changelog.Changelog, error) {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	events, err := progress.Read(progress.JSONLPath(cfg.ProgressFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil {
+		return nil, err
+	}
+	mgr, err := loadMilestoneManager(cfg, plans)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load milestones: %w", err)
+	}
+	return changelog.New(plans, mgr.CalculateAllProgress(), changelog.CompletionDates(events, runs)), nil
+}
+
+// writeChangelog writes the changelog to -changelog-file
+func writeChangelog(cfg *config.Config) error {
+	c, err := buildChangelog(cfg)
+	if err != nil {
+		return err
+	}
+	text, err := c.Render(cfg.ChangelogFormat)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(cfg.ChangelogFile); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create changelog directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(cfg.ChangelogFile, []byte(text), 0644); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return nil
+}
+
+// showChangelog prints the changelog, or writes it to -changelog-file
+func showChangelog(cfg *config.Config) error {
+	if !changelog.ValidFormat(cfg.ChangelogFormat) {
+		return fmt.Errorf("invalid -changelog-format %q (valid: %s)", cfg.ChangelogFormat, strings.Join(changelog.Formats, ", "))
+	}
+	if cfg.ChangelogFile != "" {
+		if err := writeChangelog(cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Changelog written to %s\n", cfg.ChangelogFile)
+		return nil
+	}
+
+	c, err := buildChangelog(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode changelog: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	text, err := c.Render(cfg.ChangelogFormat)
+	if err != nil {
+		return err
+	}
+	fmt.Print(text)
+	return nil
+}
+
+// handleReportCommand handles the "report" subcommand
+func handleReportCommand(cfg *config.Config, args []string) error {
+	store := history.NewStore(cfg.HistoryDir)
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s report <list|compare> [args]", os.Args[0])
+	}
+
+	switch args[0] {
+	case "list":
+		runs, err := store.List()
+		if err != nil {
+			return err
+		}
+		fmt.Println(history.FormatList(runs))
+		return nil
+
+	case "compare":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: %s report compare <run-a> <run-b>", os.Args[0])
+		}
+		runA, err := store.Find(args[1])
+		if err != nil {
+			return err
+		}
+		runB, err := store.Find(args[2])
+		if err != nil {
+			return err
+		}
+		if runA.PlanFile != runB.PlanFile {
+			fmt.Printf("Note: runs used different plan files (%s vs %s)\n\n", runA.PlanFile, runB.PlanFile)
+		}
+		fmt.Print(history.Compare(runA, runB).Format())
+		return nil
+
+	default:
+		return fmt.Errorf("unknown report command %q (valid: list, compare)", args[0])
+	}
+}
+
+// handleServeCommand handles the "serve" subcommand: a local HTTP API that
+// starts runs with the flags given before "serve", and adds nudges and
+// memories and replans like the corresponding flags
+func handleServeCommand(cfg *config.Config, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %s [flags] serve [address]", os.Args[0])
+	}
+	addr := server.DefaultAddr
+	if len(args) == 1 {
+		addr = args[0]
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the ralph executable: %w", err)
+	}
+	flags := os.Args[1 : len(os.Args)-len(flag.Args())]
+
+	token := os.Getenv(server.TokenEnv)
+	generated := token == ""
+	if generated {
+		if token, err = server.GenerateToken(); err != nil {
+			return err
+		}
+	}
+	// Requests addressed to the listen host are accepted besides loopback
+	var hosts []string
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		hosts = append(hosts, host)
+	}
+
+	srv := server.New(server.Options{
+		Command:      append([]string{exe}, flags...),
+		PlanFile:     cfg.PlanFile,
+		ProgressFile: cfg.ProgressFile,
+		Token:        token,
+		Hosts:        hosts,
+		Actions: server.Actions{
+			AddNudge:  func(spec string) (string, error) { return addNudgeSpec(cfg, spec) },
+			AddMemory: func(spec string) (string, error) { return addMemorySpec(cfg, spec) },
+			Replan:    func(strategy string) (string, error) { return serveReplan(cfg, strategy) },
+			Blocked:   func() map[int][]string { return milestoneBlockedFeatures(cfg) },
+		},
+	})
+	fmt.Printf("Ralph API listening on http://%s\n", addr)
+	if generated {
+		fmt.Printf("Token: %s (set %s to choose one)\n", token, server.TokenEnv)
+		fmt.Printf("Send it as: Authorization: Bearer %s\n", token)
+	}
+	return http.ListenAndServe(addr, srv.Handler())
+}
+
+// addNudgeSpec adds a nudge given in the -nudge format (POST /nudges, MCP
+// add_nudge)
+func addNudgeSpec(cfg *config.Config, spec string) (string, error) {
+	parsed, err := nudge.Parse(spec)
+	if err != nil {
+		return "", err
+	}
+	store := nudge.NewStore(cfg.NudgeFile)
+	if err := store.Load(); err != nil {
+		return "", fmt.Errorf("failed to load nudges: %w", err)
+	}
+	n, err := store.Insert(parsed)
+	if err != nil {
+		return "", fmt.Errorf("failed to add nudge: %w", err)
+	}
+	return fmt.Sprintf("Nudge added: [%s] %s", strings.ToUpper(string(n.Type)), n.Content), nil
+}
+
+// addMemorySpec adds a memory given in the -add-memory format (POST /memories,
+// MCP add_memory)
+func addMemorySpec(cfg *config.Config, spec string) (string, error) {
+	typ, content, ok := strings.Cut(spec, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid memory format: expected 'type:content' (e.g., 'decision:Use PostgreSQL')")
+	}
+	entryType, err := memory.ParseEntryType(typ)
+	if err != nil {
+		return "", err
+	}
+	store := memory.NewStore(cfg.MemoryFile)
+	store.SetRetentionDays(cfg.MemoryRetention)
+	store.SetRedactor(secretRedactor.Redact)
+	if err := store.Load(); err != nil {
+		return "", fmt.Errorf("failed to load memory: %w", err)
+	}
+	entry, err := store.Add(entryType, content, "", "user")
+	if err != nil {
+		return "", fmt.Errorf("failed to add memory: %w", err)
+	}
+	return fmt.Sprintf("Memory added: [%s] %s", strings.ToUpper(string(entry.Type)), entry.Content), nil
+}
+
+// serveReplan replans with the given strategy, or -replan-strategy if empty
+// (POST /replan). Replans are not confirmed, as there is no one to ask.
+func serveReplan(cfg *config.Config, strategy string) (string, error) {
+	if strategy == "" {
+		strategy = cfg.ReplanStrategy
+	}
+	replanMgr := replan.NewReplanManager(cfg.PlanFile, cfg.AgentCmd, cfg.AutoReplan)
+	if err := replanMgr.SetBackupOptions(planBackupOptions(cfg)); err != nil {
+		return "", err
+	}
+	result, err := manualReplan(replanMgr, cfg.PlanFile, strategy)
+	if err != nil {
+		return "", err
+	}
+	if result.Diff != nil && !result.Diff.IsEmpty() {
+		return result.Message + "\n" + result.Diff.Summary(), nil
+	}
+	return result.Message, nil
+}
+
+// mcpFeature is a feature as listed by the MCP tools
+type mcpFeature struct {
+	plan.Plan
+	BlockedBy []string `json:"blocked_by,omitempty"` // Incomplete prerequisite milestones
+}
+
+// handleMCPCommand handles the "mcp" subcommand: a Model Context Protocol
+// server on stdin and stdout exposing the plan, memory, nudge and validation
+// operations as tools. Diagnostics go to stderr, as stdout carries the protocol.
+func handleMCPCommand(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: %s [flags] mcp", os.Args[0])
+	}
+	output := ui.New(ui.OutputConfig{NoColor: true, LogLevel: ui.ParseLogLevel(cfg.LogLevel), Writer: os.Stderr})
+	srv := mcp.NewServer("ralph", Version)
+	idSchema := mcp.Schema(map[string]any{"feature_id": mcp.Property("integer", "ID of the feature in the plan")})
+
+	srv.AddTool(mcp.Tool{
+		Name:        "list_features",
+		Description: "List the features of the plan with their status",
+		Handler: func(json.RawMessage) (any, error) {
+			return mcpFeatures(cfg)
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name:        "get_next_feature",
+		Description: "Get the feature to work on next: the first feature that is not tested, deferred or blocked by a milestone",
+		Handler: func(json.RawMessage) (any, error) {
+			features, err := mcpFeatures(cfg)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range features {
+				if f.IsActionable() && len(f.BlockedBy) == 0 {
+					return f, nil
+				}
+			}
+			return "No feature left to work on", nil
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name:        "mark_tested",
+		Description: "Mark a feature tested once it is implemented and its tests pass. The feature's validations, if any, must pass first.",
+		InputSchema: idSchema,
+		Handler: func(args json.RawMessage) (any, error) {
+			var req struct {
+				FeatureID int `json:"feature_id"`
+			}
+			if err := json.Unmarshal(args, &req); err != nil {
+				return nil, err
+			}
+			return mcpMarkTested(cfg, output, req.FeatureID)
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name:        "validate_feature",
+		Description: "Run the validations defined for a feature (HTTP checks, commands, files, coverage, security scans)",
+		InputSchema: idSchema,
+		Handler: func(args json.RawMessage) (any, error) {
+			var req struct {
+				FeatureID int `json:"feature_id"`
+			}
+			if err := json.Unmarshal(args, &req); err != nil {
+				return nil, err
+			}
+			result, err := mcpValidate(cfg, output, req.FeatureID)
+			if err != nil {
+				return nil, err
+			}
+			return result.Summary(), nil
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name:        "list_memories",
+		Description: "List the decisions, conventions, tradeoffs and context remembered for the project",
+		Handler: func(json.RawMessage) (any, error) {
+			store := memory.NewStore(cfg.MemoryFile)
+			if err := store.Load(); err != nil {
+				return nil, fmt.Errorf("failed to load memory: %w", err)
+			}
+			global, err := loadGlobalMemory(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load user-level memory: %w", err)
+			}
+			store.SetGlobal(global)
+			return store.GetAll(), nil
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name:        "add_memory",
+		Description: "Remember something for future iterations",
+		InputSchema: mcp.Schema(map[string]any{
+			"type":    map[string]any{"type": "string", "enum": []string{"decision", "convention", "tradeoff", "context"}},
+			"content": mcp.Property("string", "What to remember"),
+		}),
+		Handler: func(args json.RawMessage) (any, error) {
+			var req struct{ Type, Content string }
+			if err := json.Unmarshal(args, &req); err != nil {
+				return nil, err
+			}
+			return addMemorySpec(cfg, req.Type+":"+req.Content)
+		},
+	})
+	srv.AddTool(mcp.Tool{
+		Name:        "add_nudge",
+		Description: "Steer the next iterations of a running Ralph",
+		InputSchema: mcp.Schema(map[string]any{
+			"type":    map[string]any{"type": "string", "enum": []string{"focus", "skip", "constraint", "style", "checkpoint"}},
+			"content": mcp.Property("string", "The guidance (or checkpoint label)"),
+		}),
+		Handler: func(args json.RawMessage) (any, error) {
+			var req struct{ Type, Content string }
+			if err := json.Unmarshal(args, &req); err != nil {
+				return nil, err
+			}
+			return addNudgeSpec(cfg, req.Type+":"+req.Content)
+		},
+	})
+
+	output.Info("Ralph MCP server ready (plan: %s)", cfg.PlanFile)
+	return srv.Serve(os.Stdin, os.Stdout)
+}
+
+// mcpFeatures reads the plan and the milestones holding features back
+func mcpFeatures(cfg *config.Config) ([]mcpFeature, error) {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	blocked := milestoneBlockedFeatures(cfg)
+	features := make([]mcpFeature, len(plans))
+	for i, p := range plans {
+		features[i] = mcpFeature{Plan: p, BlockedBy: blocked[p.ID]}
+	}
+	return features, nil
+}
+
+// mcpValidate runs the validations of a feature
+func mcpValidate(cfg *config.Config, output *ui.UI, featureID int) (*validation.ValidationRunResult, error) {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	p := plan.GetByID(plans, featureID)
+	if p == nil {
+		return nil, fmt.Errorf("feature #%d not found", featureID)
+	}
+	if len(p.Validations) == 0 {
+		return &validation.ValidationRunResult{FeatureID: p.ID, FeatureName: p.Description, Success: true}, nil
+	}
+	pol, err := loadPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	result := validateFeature(context.Background(), cfg, output, pol, newPathGuard(cfg, output), *p)
+	return &result, nil
+}
+
+// mcpMarkTested marks a feature tested if its validations pass
+func mcpMarkTested(cfg *config.Config, output *ui.UI, featureID int) (string, error) {
+	result, err := mcpValidate(cfg, output, featureID)
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", fmt.Errorf("feature #%d not marked tested: validations failed\n%s", featureID, result.Summary())
+	}
+
+	var description string
+	alreadyTested := false
+	err = plan.Update(cfg.PlanFile, func(plans []plan.Plan) ([]plan.Plan, error) {
+		p := plan.GetByID(plans, featureID)
+		if p == nil {
+			return nil, fmt.Errorf("feature #%d not found", featureID)
+		}
+		if p.Tested {
+			alreadyTested = true
+			return nil, plan.ErrNoChange
+		}
+		p.Tested = true
+		description = p.Description
+		return plans, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if alreadyTested {
+		return fmt.Sprintf("Feature #%d is already tested", featureID), nil
+	}
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("MCP: feature #%d marked tested", featureID))
+	return fmt.Sprintf("Feature #%d marked tested: %s", featureID, description), nil
+}
+
+// handleTrackerSync syncs the plan with the configured issue tracker: tested
+// features close their issues, and open issues without a feature are imported
+func handleTrackerSync(cfg *config.Config) error {
+	if cfg.Tracker == nil {
+		return fmt.Errorf("no issue tracker configured: add a tracker section to the config file")
+	}
+	provider, err := tracker.ParseProvider(cfg.Tracker.Provider)
+	if err != nil {
+		return err
+	}
+	token := cfg.Tracker.Token
+	if token == "" {
+		token = "env:" + tracker.DefaultTokenEnv[provider]
+	}
+	token, err = agent.ResolveValue(token)
+	if err != nil {
+		return fmt.Errorf("tracker token: %w", err)
+	}
+	t, err := tracker.New(tracker.Config{
+		Provider: provider,
+		Project:  cfg.Tracker.Project,
+		URL:      cfg.Tracker.URL,
+		Token:    token,
+		Labels:   cfg.Tracker.Labels,
+	})
+	if err != nil {
+		return err
+	}
+
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		if _, statErr := os.Stat(cfg.PlanFile); !os.IsNotExist(statErr) {
+			return err
 		}
+		plans = nil
+	}
 
-		// Determine exit code for failure detection
-		exitCode := 0
+	ctx := context.Background()
+	issues, err := t.Issues(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Syncing %s with %s %s (%d open issues)\n\n", cfg.PlanFile, provider, cfg.Tracker.Project, len(issues))
+
+	// Push: close the issues of tested features
+	closed, failed := 0, 0
+	for _, p := range tracker.Done(plans, issues, provider) {
+		key, _ := tracker.Key(provider, p.Issue)
+		comment := fmt.Sprintf("Ralph: feature #%d (%s) is implemented and tested.", p.ID, p.Description)
+		if err := t.Comment(ctx, key, comment); err == nil {
+			err = t.Close(ctx, key)
+		}
 		if err != nil {
-			exitCode = 1
-			// Don't return immediately - handle with recovery
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("Closed %s (feature #%d: %s)\n", key, p.ID, p.Description)
+		closed++
+	}
+
+	// Pull: import the issues no feature references yet
+	var added []plan.Plan
+	err = plan.Update(cfg.PlanFile, func(plans []plan.Plan) ([]plan.Plan, error) {
+		plans, added = tracker.Import(plans, issues, provider, cfg.Tracker.Categories)
+		if len(added) == 0 {
+			return nil, plan.ErrNoChange
+		}
+		return plans, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range added {
+		fmt.Printf("Imported %s as feature #%d: %s\n", p.Issue, p.ID, p.Description)
+	}
+
+	fmt.Printf("\n%d issues imported, %d closed\n", len(added), closed)
+	if len(added) > 0 || closed > 0 {
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("TRACKER: %d %s issues imported, %d closed", len(added), provider, closed))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to close %d issues", failed)
+	}
+	return nil
+}
+
+// handleTelemetryCommand handles the "telemetry" subcommand
+func handleTelemetryCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s telemetry <show|export|reset> [file]", os.Args[0])
+	}
+
+	switch args[0] {
+	case "show":
+		agg, err := telemetry.Load(cfg.TelemetryFile)
+		if err != nil {
+			return err
+		}
+		fmt.Print(agg.Report().Format())
+		if !cfg.Telemetry && agg.Runs > 0 {
+			fmt.Println("\nTelemetry is disabled: new runs are not recorded.")
+		}
+		return nil
+
+	case "export":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: %s telemetry export [file]", os.Args[0])
+		}
+		agg, err := telemetry.Load(cfg.TelemetryFile)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(agg.Report(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal telemetry report: %w", err)
+		}
+		if len(args) == 1 {
+			fmt.Println(string(data))
+			return nil
+		}
+		if err := os.WriteFile(args[1], append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write telemetry report: %w", err)
+		}
+		fmt.Printf("Telemetry report for %d run(s) written to %s\n", agg.Runs, args[1])
+		return nil
+
+	case "reset":
+		if err := os.Remove(cfg.TelemetryFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove telemetry file: %w", err)
+		}
+		fmt.Printf("Telemetry statistics in %s deleted.\n", cfg.TelemetryFile)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown telemetry command %q (valid: show, export, reset)", args[0])
+	}
+}
+
+// handleFlakyCommand handles the "flaky" subcommand
+func handleFlakyCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s flaky <list|unmark> [test]", os.Args[0])
+	}
+
+	store, err := flaky.Load(cfg.FlakyFile)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		tests := store.Quarantined()
+		if len(tests) == 0 {
+			fmt.Println("No flaky tests quarantined")
+			return nil
+		}
+		fmt.Printf("=== Quarantined Flaky Tests (from %s) ===\n", cfg.FlakyFile)
+		for _, t := range tests {
+			fmt.Printf("  %s - %d failure(s), flaky since %s\n", t, t.Failures, t.FlakySince.Format("2006-01-02 15:04"))
+		}
+		return nil
+
+	case "unmark":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: %s flaky unmark [test]", os.Args[0])
+		}
+		name := ""
+		if len(args) == 2 {
+			name = args[1]
+		}
+		n := store.Unmark(name)
+		if n == 0 {
+			if name == "" {
+				fmt.Println("No flaky tests quarantined")
+				return nil
+			}
+			return fmt.Errorf("no quarantined flaky test %q", name)
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("%d test(s) no longer quarantined\n", n)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown flaky command %q (valid: list, unmark)", args[0])
+	}
+}
+
+// handleShowIterationDiff prints the patch recorded for an iteration of the
+// latest run, so it can be inspected or piped to git apply
+func handleShowIterationDiff(cfg *config.Config) error {
+	store := diffs.NewStore(cfg.DiffDir)
+	runID, err := store.LatestRun()
+	if err != nil {
+		return err
+	}
+	patch, err := store.Load(runID, cfg.ShowIterationDiff)
+	if err != nil {
+		return err
+	}
+	if patch == "" {
+		fmt.Fprintf(os.Stderr, "Iteration %d of run %s made no file changes.\n", cfg.ShowIterationDiff, runID)
+		return nil
+	}
+	fmt.Print(patch)
+	return nil
+}
+
+// secretRedactor masks secrets in text Ralph persists; nil with -no-redact
+var secretRedactor *redact.Redactor
+
+// newSecretRedactor creates the redactor for the built-in secret formats and
+// redact_patterns. The API backend's key and agent environment values taken
+// from env: or file: references are masked wherever they appear.
+func newSecretRedactor(cfg *config.Config) (*redact.Redactor, error) {
+	r, err := redact.New(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.UsesAPIBackend() {
+		if provider, err := llm.ParseProvider(cfg.AgentBackend); err == nil {
+			r.AddValues(os.Getenv(llm.Config{Provider: provider, APIKeyEnv: cfg.APIKeyEnv}.KeyEnv()))
+		}
+	}
+	if resolved, err := agent.ResolveEnv(cfg.AgentEnv); err == nil {
+		for name, value := range cfg.AgentEnv {
+			if strings.HasPrefix(value, "env:") || strings.HasPrefix(value, "file:") {
+				r.AddValues(resolved[name])
+			}
+		}
+	}
+	return r, nil
+}
+
+// startTranscript records every agent call from now on in the transcript of
+// runID, with the configured redactions. Callers stop recording with
+// agent.SetTranscript(nil) when done.
+func startTranscript(cfg *config.Config, runID string) *transcript.Recorder {
+	r := transcript.NewRecorder(cfg.TranscriptDir, runID)
+	if secretRedactor != nil {
+		r.AddRedactor(secretRedactor.Redact)
+	}
+	if len(cfg.TranscriptRedact) > 0 {
+		redact, err := transcript.PatternRedactor(cfg.TranscriptRedact)
+		if err != nil {
+			// Recording unredacted text could leak what the patterns protect
+			fmt.Fprintf(os.Stderr, "Warning: transcript disabled: %v\n", err)
+			agent.SetTranscript(nil)
+			return nil
+		}
+		r.AddRedactor(redact)
+	}
+	agent.SetTranscript(r)
+	return r
+}
+
+// handleShowTranscript prints the transcript of a run, as JSON lines with
+// -json-output
+func handleShowTranscript(cfg *config.Config) error {
+	store := transcript.NewStore(cfg.TranscriptDir)
+	runID, err := store.Find(cfg.ShowTranscript)
+	if err != nil {
+		return err
+	}
+	entries, err := store.Load(runID)
+	if err != nil {
+		return err
+	}
+
+	if cfg.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fmt.Printf("Transcript of %s: %d agent call(s)\n\n", runID, len(entries))
+	for _, e := range entries {
+		fmt.Println(transcript.Format(e))
+	}
+	return nil
+}
+
+// handleProgressQuery prints the events of the structured progress log that
+// match -progress-query, as JSON lines with -json-output
+func handleProgressQuery(cfg *config.Config) error {
+	q, err := progress.ParseQuery(cfg.ProgressQuery, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid -progress-query: %w", err)
+	}
+	path := progress.JSONLPath(cfg.ProgressFile)
+	events, err := progress.Read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no structured progress log at %s yet (it is written during runs)", path)
+		}
+		return err
+	}
+
+	matched := q.Filter(events)
+	if cfg.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range matched {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, e := range matched {
+		fmt.Println(progress.Format(e))
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d event(s) in %s\n", len(matched), len(events), path)
+	return nil
+}
+
+// handleRollbackCommand restores the working tree to the restore point taken
+// before a feature was started or before an iteration of the latest run. The
+// current state is saved first so the rollback itself can be undone.
+func handleRollbackCommand(cfg *config.Config) error {
+	if cfg.RollbackFeature != 0 && cfg.RollbackIteration != 0 {
+		return fmt.Errorf("-rollback-feature and -rollback-iteration cannot be used together")
+	}
+	kind, key, ref := recovery.KindIteration, cfg.RollbackIteration, recovery.IterationRef(cfg.RollbackIteration)
+	what := fmt.Sprintf("iteration %d", key)
+	if cfg.RollbackFeature != 0 {
+		kind, key, ref = recovery.KindFeature, cfg.RollbackFeature, recovery.FeatureRef(cfg.RollbackFeature)
+		what = fmt.Sprintf("feature #%d", key)
+	}
+	if key < 0 {
+		return fmt.Errorf("invalid %s: must be positive", what)
+	}
+
+	exclude := ralphStatePaths(cfg)
+	target, err := recovery.LoadSnapshot(ref, exclude...)
+	if err != nil {
+		points, listErr := recovery.ListRestorePoints(kind)
+		if listErr != nil || len(points) == 0 {
+			return fmt.Errorf("no restore point for %s (restore points are saved during runs in a git repository)", what)
+		}
+		keys := make([]string, len(points))
+		for i, p := range points {
+			keys[i] = strconv.Itoa(p.Key)
+		}
+		return fmt.Errorf("no restore point for %s (available: %s)", what, strings.Join(keys, ", "))
+	}
+
+	current, err := recovery.TakeSnapshot(exclude...)
+	if err != nil {
+		return err
+	}
+	if err := current.Save(recovery.BeforeRollbackRef, "before rollback to "+what); err != nil {
+		return err
+	}
+	if err := target.Rollback(); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	fmt.Printf("Restored the working tree to its state before %s.\n", what)
+	fmt.Printf("The previous state was saved as %s (git checkout %s -- <path> brings files back).\n", recovery.BeforeRollbackRef, recovery.BeforeRollbackRef)
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("ROLLBACK: restored the working tree to its state before %s", what))
+	return nil
+}
+
+// pausePollInterval is how often a paused run checks whether it was resumed
+const pausePollInterval = time.Second
+
+// rateLimitBackoffMax is the longest wait for a rate-limited agent
+const rateLimitBackoffMax = 10 * time.Minute
+
+// rateLimitBackoff returns the backoff applied while an agent is rate limited
+func rateLimitBackoff(cfg *config.Config) recovery.Backoff {
+	return recovery.Backoff{
+		Initial:    cfg.RateLimitBackoffDuration(),
+		Multiplier: 2,
+		Max:        rateLimitBackoffMax,
+		Jitter:     config.DefaultRetryJitter,
+	}
+}
+
+// retryBackoff returns the backoff applied between retries of a failing feature
+func retryBackoff(cfg *config.Config) recovery.Backoff {
+	return recovery.Backoff{
+		Initial:    cfg.RetryBackoffDuration(),
+		Multiplier: cfg.RetryBackoffMultiplier,
+		Max:        cfg.RetryBackoffMaxDuration(),
+		Jitter:     cfg.RetryJitter,
+	}
+}
+
+// failedTestGuidance describes the tests that failed in an iteration, from
+// go test -json or Jest JSON results in its output, or else from the test
+// report written since the iteration started. Returns "" if neither exists.
+func failedTestGuidance(cfg *config.Config, output *ui.UI, result string, since time.Time) string {
+	report := testreport.Parse(result)
+	if report == nil {
+		path := cfg.TestReport
+		if path == "" {
+			buildSystem := cfg.BuildSystem
+			if buildSystem == "" || buildSystem == "auto" {
+				buildSystem = detection.DetectBuildSystem()
+			}
+			path = testreport.DefaultReportPaths[buildSystem]
+		}
+		if path == "" {
+			return ""
+		}
+		var err error
+		if report, err = testreport.Load(path, since); err != nil {
+			output.Debug("Failed to read test report: %v", err)
+			return ""
+		}
+		if report == nil {
+			return ""
+		}
+	}
+	return report.Guidance(10)
+}
+
+// newFailureClassifier builds the failure classifier from the built-in rules
+// for the project's build system and the config file's failure patterns
+func newFailureClassifier(cfg *config.Config) (*recovery.Classifier, error) {
+	buildSystem := cfg.BuildSystem
+	if buildSystem == "" || buildSystem == "auto" {
+		buildSystem = detection.DetectBuildSystem()
+	}
+
+	custom := make([]recovery.Rule, 0, len(cfg.FailurePatterns))
+	for _, fp := range cfg.FailurePatterns {
+		failureType, err := recovery.ParseFailureType(fp.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failure pattern %q: %w", fp.Pattern, err)
+		}
+		severity, err := recovery.ParseSeverity(fp.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("failure pattern %q: %w", fp.Pattern, err)
+		}
+		custom = append(custom, recovery.Rule{
+			Name:         fp.Name,
+			Pattern:      fp.Pattern,
+			Type:         failureType,
+			Severity:     severity,
+			BuildSystems: fp.BuildSystems,
+		})
+	}
+	return recovery.NewClassifier(buildSystem, custom)
+}
+
+// logFailureToProgress appends failure information to the progress file
+func logFailureToProgress(progressFile string, failure *recovery.Failure) {
+	message := fmt.Sprintf("FAILURE [%s]: %s (feature #%d, retry %d)",
+		failure.Type, failure.Message, failure.FeatureID, failure.RetryCount)
+	
+	if err := appendProgress(progressFile, message); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to log failure to progress file: %v\n", err)
+	}
+}
+
+// printRecoverySummary prints a summary of failures and recovery actions (legacy function)
+func printRecoverySummary(rm *recovery.RecoveryManager, verbose bool) {
+	summary := rm.GetFailureSummary()
+	if summary != "No failures recorded" {
+		fmt.Println()
+		fmt.Printf("=== Recovery Summary ===\n")
+		fmt.Println(summary)
+	} else if verbose {
+		fmt.Println()
+		fmt.Println("No failures encountered during execution.")
+	}
+}
+
+// printRecoverySummaryUI prints a summary using the UI package
+func printRecoverySummaryUI(output *ui.UI, rm *recovery.RecoveryManager, verbose bool) {
+	summary := rm.GetFailureSummary()
+	if summary != "No failures recorded" {
+		output.SubHeader("Recovery Summary")
+		output.Print("%s", summary)
+	} else if verbose {
+		output.Print("")
+		output.Success("No failures encountered during execution.")
+	}
+}
+
+// observeFlakyTests records the test results of an iteration in the flaky
+// test store, warns about the tests found flaky and returns them. Changes made
+// by the iteration decide whether a failure that later passed was fixed.
+func observeFlakyTests(cfg *config.Config, output *ui.UI, store *flaky.Store, snap *recovery.Snapshot, results []flaky.Result) []*flaky.Test {
+	if len(results) == 0 {
+		return nil
+	}
+	var changed []string
+	changedKnown := false
+	if snap != nil {
+		if files, err := snap.ChangedFiles(); err == nil {
+			changed, changedKnown = files, true
+		}
+	}
+
+	found := store.Observe(results, changed, changedKnown)
+	for _, t := range found {
+		output.Warn("Flaky test: %s failed, then passed with no related change - its failures are quarantined", t)
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("FLAKY: %s", t))
+	}
+	if err := store.Save(); err != nil {
+		output.Debug("Failed to save flaky tests: %v", err)
+	}
+	return found
+}
+
+// flakyNames lists the names of failed tests
+func flakyNames(results []flaky.Result) string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Key()
+	}
+	return strings.Join(names, ", ")
+}
+
+// printFlakySummary lists the flaky tests found during the run and how many
+// tests are quarantined in total
+func printFlakySummary(output *ui.UI, store *flaky.Store, found []*flaky.Test) {
+	quarantined := store.Quarantined()
+	if len(found) == 0 && len(quarantined) == 0 {
+		return
+	}
+	output.SubHeader("Flaky Tests")
+	for _, t := range found {
+		output.Print("  New: %s", t)
+	}
+	output.Print("%d test(s) quarantined (ralph flaky list, ralph flaky unmark <test>)", len(quarantined))
+}
+
+// coverageCommand returns the command measuring test coverage: the configured
+// one, or else the build system's default ("" if it has none)
+func coverageCommand(cfg *config.Config) string {
+	if cfg.CoverageCmd != "" {
+		return cfg.CoverageCmd
+	}
+	buildSystem := cfg.BuildSystem
+	if buildSystem == "" || buildSystem == "auto" {
+		buildSystem = detection.DetectBuildSystem()
+	}
+	return coverage.DefaultCommands[buildSystem]
+}
+
+// measureCoverage runs the coverage command and returns the total coverage,
+// or false if it could not be measured
+func measureCoverage(cfg *config.Config, output *ui.UI) (float64, bool) {
+	command := coverageCommand(cfg)
+	output.Info("Coverage: %s", command)
+	total, out, err := coverage.Measure(context.Background(), command)
+	if err != nil {
+		output.Warn("Coverage not measured: %v", err)
+		if out = strings.TrimSpace(out); out != "" {
+			output.Debug("%s", out)
 		}
+		return 0, false
+	}
+	return total, true
+}
+
+// checkCoverageGate measures coverage after features were marked tested in
+// an iteration. If coverage fails the gate, the features are marked untested
+// again and the returned error describes the regression. Coverage that cannot
+// be measured does not block the features.
+func checkCoverageGate(cfg *config.Config, output *ui.UI, gate *coverage.Gate, trend *coverage.Trend, iteration int, tested []int, seen map[int]bool) error {
+	total, ok := measureCoverage(cfg, output)
+	if !ok {
+		return nil
+	}
+	gateErr := gate.Check(total)
+	trend.Record(iteration, total, gateErr == nil)
+	if gateErr == nil {
+		output.Success("Coverage %.1f%% passes the gate", total)
+		return nil
+	}
+
+	if err := markUntested(cfg.PlanFile, tested); err != nil {
+		return fmt.Errorf("coverage gate: %w", err)
+	}
+	var ids []string
+	for _, id := range tested {
+		delete(seen, id)
+		ids = append(ids, fmt.Sprintf("#%d", id))
+	}
+
+	msg := fmt.Sprintf("feature(s) %s not marked tested: %v", strings.Join(ids, ", "), gateErr)
+	output.Warn("Coverage gate: %s", msg)
+	appendProgress(cfg.ProgressFile, "COVERAGE: "+msg)
+	return fmt.Errorf("coverage gate: %s", msg)
+}
+
+// checkReviewGate has the reviewer agents review the iteration that marked
+// features tested. If they do not approve, the features are marked untested
+// again and the returned error carries the review issues for the agent.
+func checkReviewGate(cfg *config.Config, output *ui.UI, gate *multiagent.Orchestrator, iteration, featureID int, featureDesc, task, implementation string, tested []int, seen map[int]bool) error {
+	output.SubHeader("Reviewing iteration %d", iteration)
+	review, reviewErr := gate.Review(context.Background(), featureID, featureDesc, iteration, task, implementation)
+	if reviewErr == nil && review.Approved {
+		output.Success("Review gate: approved by %d reviewer(s)", len(review.Stage.Results))
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("REVIEW: iteration %d approved", iteration))
+		return nil
+	}
+
+	if err := markUntested(cfg.PlanFile, tested); err != nil {
+		return fmt.Errorf("review gate: %w", err)
+	}
+	var ids []string
+	for _, id := range tested {
+		delete(seen, id)
+		ids = append(ids, fmt.Sprintf("#%d", id))
+	}
 
-		// Print the agent output
-		if result != "" {
-			output.Print("%s", result)
+	msg := fmt.Sprintf("feature(s) %s not marked tested: %s", strings.Join(ids, ", "), reviewFeedback(review, reviewErr))
+	output.Warn("Review gate: %s", msg)
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("REVIEW: iteration %d not approved, feature(s) %s unmarked", iteration, strings.Join(ids, ", ")))
+	return fmt.Errorf("review gate: %s", msg)
+}
+
+// markUntested marks features untested again in the plan file
+func markUntested(planFile string, ids []int) error {
+	return plan.Update(planFile, func(plans []plan.Plan) ([]plan.Plan, error) {
+		for _, id := range ids {
+			if p := plan.GetByID(plans, id); p != nil {
+				p.Tested = false
+			}
 		}
+		return plans, nil
+	})
+}
 
-		// Extract and store any memories from the agent output
-		memoriesStored := extractAndStoreMemories(memStore, result, "")
-		if memoriesStored > 0 && cfg.Verbose {
-			output.Debug("Extracted and stored %d new memories from agent output", memoriesStored)
+// reviewFeedback describes why a review did not approve an iteration: the
+// issues the reviewers raised, or the end of their output if no issues could
+// be extracted
+func reviewFeedback(review *multiagent.ReviewResult, reviewErr error) string {
+	if reviewErr != nil {
+		return "the review failed: " + reviewErr.Error()
+	}
+	if review.Resolution != nil && !review.Resolution.Resolved {
+		return "reviewers disagree and the conflict is waiting for a human decision"
+	}
+	if issues := review.Issues(); len(issues) > 0 {
+		return "the reviewers raised these issues:\n- " + strings.Join(issues, "\n- ")
+	}
+	var feedback []string
+	for _, r := range review.Stage.Results {
+		switch {
+		case r.Status != multiagent.StatusComplete:
+			feedback = append(feedback, fmt.Sprintf("reviewer %s failed: %s", r.AgentID, r.Error))
+		case !r.Approved:
+			feedback = append(feedback, fmt.Sprintf("reviewer %s did not approve:\n%s", r.AgentID, lastLines(r.Output, 20)))
 		}
+	}
+	if len(feedback) == 0 {
+		return "the reviewers did not approve"
+	}
+	return strings.Join(feedback, "\n")
+}
 
-		// Acknowledge nudges that were injected into this iteration
-		if len(activeNudges) > 0 {
-			if err := nudgeStore.AcknowledgeAll(); err != nil {
-				output.Debug("Failed to acknowledge nudges: %v", err)
-			} else {
-				// Log nudge acknowledgment to progress file
-				ackMsg := nudge.FormatAcknowledgment(activeNudges)
-				if ackMsg != "" {
-					appendProgress(cfg.ProgressFile, ackMsg)
-				}
-				if cfg.Verbose {
-					output.Debug("Acknowledged %d nudge(s)", len(activeNudges))
-				}
-			}
+// checkFeatureValidations runs the validations of features the agent just
+// marked tested. Features whose validations fail are marked untested again,
+// and the returned error describes the failures for the agent.
+func checkFeatureValidations(cfg *config.Config, output *ui.UI, pol *policy.Policy, pathGuard *guard.Guard, tested []int, seen map[int]bool, results map[int]validation.ValidationRunResult) error {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return fmt.Errorf("validations: %w", err)
+	}
+
+	var failures []string
+	var failed []int
+	var ids []string
+	for _, id := range tested {
+		p := plan.GetByID(plans, id)
+		if p == nil || len(p.Validations) == 0 {
+			continue
+		}
+		output.SubHeader("Validating feature #%d: %s", p.ID, p.Description)
+		result := validateFeature(context.Background(), cfg, output, pol, pathGuard, *p)
+		if results != nil {
+			results[id] = result
+		}
+		if result.Success {
+			output.Success("Feature #%d: %d validation(s) passed", p.ID, result.PassedCount)
+			appendProgress(cfg.ProgressFile, fmt.Sprintf("VALIDATION: feature #%d passed %d validation(s)", p.ID, result.PassedCount))
+			continue
 		}
 
-		// Check for completion signal (even if there was an error, the output might contain it)
-		if strings.Contains(result, prompt.CompleteSignal) {
-			output.Success("Plan complete! Detected completion signal after %d iteration(s).", i)
-			summary.FeaturesCompleted++
-			summary.EndTime = time.Now()
-			summary.FailuresRecovered = recoveryMgr.GetRecoveredCount()
-			output.PrintSummary(summary)
-			printRecoverySummaryUI(output, recoveryMgr, cfg.Verbose)
-			
-			// Show scope summary if scope control was active
-			if cfg.ScopeLimit > 0 || cfg.Deadline != "" {
-				printScopeSummary(output, scopeMgr, cfg.Verbose)
+		for _, vr := range result.Results {
+			if vr.Success {
+				continue
 			}
-			
-			// Show final milestone status
-			if milestoneMgr != nil && milestoneMgr.HasMilestones() {
-				output.SubHeader("Final Milestone Status")
-				output.Print("%s", milestoneMgr.Summary())
+			output.Error("  %s", vr.Message)
+			failure := fmt.Sprintf("- Feature #%d: %s", p.ID, vr.Message)
+			if vr.Error != "" {
+				failure += " (" + vr.Error + ")"
 			}
-			return nil
-		}
-		
-		// Check for newly completed milestones
-		if milestoneMgr != nil && milestoneMgr.HasMilestones() {
-			// Reload plans to get updated tested status
-			updatedPlans, err := plan.ReadFile(cfg.PlanFile)
-			if err == nil {
-				milestoneMgr = milestone.NewManager(updatedPlans)
-				
-				// Check for newly completed milestones
-				for _, p := range milestoneMgr.GetCompletedMilestones() {
-					if !completedMilestonesBefore[p.Milestone.Name] {
-						output.Success("%s", milestone.CelebrationMessage(p.Milestone.Name))
-						completedMilestonesBefore[p.Milestone.Name] = true
-					}
-				}
+			if vr.Report != "" {
+				failure += "\n  " + strings.ReplaceAll(vr.Report, "\n", "\n  ")
 			}
+			failures = append(failures, failure)
 		}
+		failed = append(failed, id)
+		delete(seen, id)
+		ids = append(ids, fmt.Sprintf("#%d", id))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := markUntested(cfg.PlanFile, failed); err != nil {
+		return fmt.Errorf("validations: %w", err)
+	}
 
-		// Handle failure detection and recovery
-		if err != nil || containsFailureIndicators(result) {
-			if exitCode == 0 && containsFailureIndicators(result) {
-				exitCode = 1 // Treat as failure even if command succeeded
+	msg := fmt.Sprintf("feature(s) %s not marked tested: validations failed", strings.Join(ids, ", "))
+	output.Warn("Validate on complete: %s", msg)
+	appendProgress(cfg.ProgressFile, "VALIDATION: "+msg)
+	return fmt.Errorf("%s:\n%s", msg, strings.Join(failures, "\n"))
+}
+
+// printCoverageSummary shows how coverage changed over the run
+func printCoverageSummary(output *ui.UI, trend *coverage.Trend) {
+	summary := trend.Summary()
+	if summary == "" {
+		return
+	}
+	output.SubHeader("Coverage")
+	output.Print("%s", summary)
+}
+
+// listPlanStatus displays plan status (tested/untested features)
+func listPlanStatus(cfg *config.Config) (int, error) {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return 0, err
+	}
+
+	// Determine what to show
+	showTested := cfg.ListAll || cfg.ListTested
+	showUntested := cfg.ListAll || cfg.ListUntested
+	summary := plan.Summarize(plans)
+
+	if cfg.JSONOutput {
+		features := []plan.Plan{}
+		for _, p := range plans {
+			if (p.Tested && showTested) || (!p.Tested && showUntested) {
+				features = append(features, p)
 			}
+		}
+		return planExitCode(summary), printPlanListing(cfg, summary, features)
+	}
 
-			failure, recoveryResult := recoveryMgr.HandleFailure(result, exitCode, currentFeatureID, i)
-			
-			if failure != nil {
-				output.Warn("Failure detected: %s", failure)
-				summary.Errors = append(summary.Errors, failure.String())
-				
-				// Track consecutive failures for replanning
-				consecutiveFailures++
-				
-				// Log failure to progress file
-				logFailureToProgress(cfg.ProgressFile, failure)
+	// Open questions block progress, so they come first
+	questions := plan.FilterQuestions(plans)
+	if showUntested && len(questions) > 0 {
+		printOpenQuestions(cfg.PlanFile, questions)
+		fmt.Println()
+	}
 
-				if recoveryResult.ShouldSkip {
-					output.Info("Recovery: %s", recoveryResult.Message)
-					summary.FeaturesSkipped++
-					// Add to blocked features for replan tracking
-					replanMgr.AddBlockedFeature(currentFeatureID)
-					// Reset consecutive failures when skipping
-					consecutiveFailures = 0
-				} else if recoveryResult.ShouldRetry {
-					output.Info("Recovery: %s", recoveryResult.Message)
-					// Set additional guidance for the retry
-					if recoveryResult.ModifiedPrompt != "" {
-						additionalPromptGuidance = recoveryResult.ModifiedPrompt
-					}
-				}
+	if showTested {
+		fmt.Printf("=== Tested Features (from %s) ===\n", cfg.PlanFile)
+		tested := plan.Filter(plans, true)
+		if len(tested) == 0 {
+			fmt.Println("No tested features found")
+		} else {
+			plan.Print(tested)
+		}
+		fmt.Println()
+	}
 
-				if !recoveryResult.Success {
-					output.Error("Recovery action failed: %s", recoveryResult.Message)
-					summary.FeaturesFailed++
-				}
-				
-				// Check for replanning triggers
-				replanMgr.UpdateState(currentFeatureID, consecutiveFailures, []string{string(failure.Type)}, plans)
-				replanMgr.IncrementIterations()
-				
-				if shouldReplan, trigger := replanMgr.ShouldReplan(); shouldReplan {
-					output.SubHeader("Automatic Replanning Triggered")
-					output.Info("Trigger: %s", trigger)
-					
-					replanResult, replanErr := replanMgr.ExecuteReplan(replanStrategyType, trigger)
-					if replanErr != nil {
-						output.Error("Replanning failed: %v", replanErr)
-					} else if replanResult.Success {
-						output.Success("Replanning completed: %s", replanResult.Message)
-						if replanResult.OldPlanPath != "" {
-							output.Debug("Backup created: %s", replanResult.OldPlanPath)
-						}
-						if replanResult.Diff != nil && !replanResult.Diff.IsEmpty() {
-							output.Print("%s", replanResult.Diff.Summary())
-						}
-						// Update local plans reference
-						plans = replanResult.NewPlans
-						// Log replan to progress file
-						appendProgress(cfg.ProgressFile, fmt.Sprintf("REPLAN: %s triggered, strategy: %s", trigger, replanStrategyType))
-						// Reset consecutive failures after replanning
-						consecutiveFailures = 0
-					}
-				}
-			} else if err != nil {
-				// Agent execution error but no specific failure detected
-				output.Error("Agent execution error: %v", err)
-				summary.Errors = append(summary.Errors, err.Error())
-				consecutiveFailures++
+	if showUntested {
+		fmt.Printf("=== Untested Features (from %s) ===\n", cfg.PlanFile)
+		var untested []plan.Plan
+		for _, p := range plan.Filter(plans, false) {
+			if !p.IsQuestion() {
+				untested = append(untested, p)
 			}
+		}
+		if len(untested) == 0 {
+			fmt.Println("No untested features found")
 		} else {
-			// Iteration completed without obvious failures
-			// Reset consecutive failures on success
-			consecutiveFailures = 0
-			replanMgr.ResetState()
+			plan.Print(untested)
+		}
+
+		if oversized := prompt.OversizedFeatures(plans, cfg.MaxPromptSteps); len(oversized) > 0 {
+			printOversizedFeatures(cfg, oversized)
+		}
+	}
+
+	return planExitCode(summary), nil
+}
+
+// Exit codes of the plan listing commands (-list-all, -list-tested,
+// -list-untested, -list-deferred), so scripts can branch on the plan's state.
+// Errors exit with 1, and invalid flags with 2.
+const (
+	exitPlanComplete = 0 // Every feature is tested
+	exitPlanUntested = 3 // Features remain to be worked on, or questions to be answered
+	exitPlanDeferred = 4 // Features are deferred (takes precedence over exitPlanUntested)
+)
+
+// planExitCode returns the exit code of a plan listing command for the plan
+func planExitCode(s plan.Summary) int {
+	switch {
+	case s.Deferred > 0:
+		return exitPlanDeferred
+	case s.Untested > 0 || s.Questions > 0:
+		return exitPlanUntested
+	default:
+		return exitPlanComplete
+	}
+}
+
+// planListing is the output of the plan listing commands with -json-output
+type planListing struct {
+	PlanFile string       `json:"plan_file"`
+	Summary  plan.Summary `json:"summary"`
+	ExitCode int          `json:"exit_code"`
+	Features []plan.Plan  `json:"features"` // Features selected by the command
+}
+
+// printPlanListing prints the listed features and the plan's summary as JSON
+func printPlanListing(cfg *config.Config, summary plan.Summary, features []plan.Plan) error {
+	data, err := json.MarshalIndent(planListing{
+		PlanFile: cfg.PlanFile,
+		Summary:  summary,
+		ExitCode: planExitCode(summary),
+		Features: features,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode features: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// printOversizedFeatures warns about features whose step lists are truncated
+// in prompts, since they likely need refinement
+func printOversizedFeatures(cfg *config.Config, oversized []plan.Plan) {
+	fmt.Println()
+	fmt.Printf("Warning: %d feature(s) have more than %d steps and likely need refinement:\n", len(oversized), cfg.MaxPromptSteps)
+	for _, p := range oversized {
+		fmt.Printf("  %d. %s (%d steps)\n", p.ID, p.Description, len(p.Steps))
+	}
+	fmt.Printf("Prompts show only their first %d steps. Split them with: %s -analyze-plan\n", cfg.MaxPromptSteps, os.Args[0])
+}
+
+// listDeferredFeatures displays features that have been deferred due to scope constraints
+func listDeferredFeatures(cfg *config.Config) (int, error) {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return 0, err
+	}
+
+	deferred := plan.FilterDeferred(plans, true)
+	summary := plan.Summarize(plans)
+	if cfg.JSONOutput {
+		if deferred == nil {
+			deferred = []plan.Plan{}
+		}
+		return planExitCode(summary), printPlanListing(cfg, summary, deferred)
+	}
+
+	fmt.Printf("=== Deferred Features (from %s) ===\n", cfg.PlanFile)
+	if len(deferred) == 0 {
+		fmt.Println("No deferred features found")
+		fmt.Println()
+		fmt.Println("Features are deferred when they exceed scope constraints:")
+		fmt.Println("  - Iteration limit reached (-scope-limit flag)")
+		fmt.Println("  - Deadline reached (-deadline flag)")
+		fmt.Println("  - Feature time budget used up (-feature-deadline flag)")
+		fmt.Println()
+		fmt.Println("To use scope control, run with:")
+		fmt.Printf("  %s -iterations 10 -scope-limit 3  # Max 3 iterations per feature\n", os.Args[0])
+		fmt.Printf("  %s -iterations 10 -deadline 1h   # 1 hour time limit\n", os.Args[0])
+	} else {
+		for _, p := range deferred {
+			reason := p.DeferReason
+			if reason == "" {
+				reason = "unspecified"
+			}
+			fmt.Printf("  %d. %s [%s] - %s\n", p.ID, p.Category, reason, p.Description)
+		}
+		fmt.Printf("\nTotal deferred: %d features\n", len(deferred))
+	}
+
+	return planExitCode(summary), nil
+}
+
+// newForecast estimates the work left in plans, with the scope calibration
+// and the iteration time of recent runs
+func newForecast(cfg *config.Config, plans []plan.Plan) *forecast.Forecast {
+	var calibration *scope.Calibration
+	if !cfg.NoScopeCalibration {
+		calibration = scopeCalibration(cfg)
+	}
+	var perIteration time.Duration
+	if runs, err := history.NewStore(cfg.HistoryDir).List(); err == nil {
+		perIteration = history.IterationDuration(runs, time.Now())
+	}
+	return forecast.New(plans, calibration, perIteration)
+}
+
+// showForecast prints the estimated iterations and time left for the plan
+// and each milestone
+func showForecast(cfg *config.Config) error {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return err
+	}
+	fc := newForecast(cfg, plans)
+
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(fc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode forecast: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("=== Forecast: %s ===\n", cfg.PlanFile)
+	if len(fc.Features) == 0 {
+		fmt.Println("No features left to work on.")
+		if fc.Questions > 0 {
+			fmt.Printf("%d open question(s) must be answered first (ralph question list)\n", fc.Questions)
+		}
+		return nil
+	}
+	fmt.Printf("Remaining: %s\n", fc.Total)
+	if fc.IterationDuration > 0 {
+		fmt.Printf("Per iteration: ~%s (recent runs)\n", forecast.FormatDuration(fc.IterationDuration))
+	} else {
+		fmt.Println("Per iteration: unknown (no finished runs recorded in the last 14 days)")
+	}
+	if cfg.NoScopeCalibration {
+		fmt.Println("Estimates: built-in defaults (-no-scope-calibration)")
+	} else {
+		fmt.Printf("Estimates: %s\n", scopeCalibration(cfg).Summary())
+	}
+	if fc.Questions > 0 {
+		fmt.Printf("Open questions: %d (not estimated)\n", fc.Questions)
+	}
+
+	if len(fc.Milestones) > 0 {
+		fmt.Println()
+		fmt.Println("Milestones:")
+		for _, m := range fc.Milestones {
+			fmt.Printf("  %-20s %s\n", m.Name, m.Estimate)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Features:")
+	for _, f := range fc.Features {
+		fmt.Printf("  #%-4d %-6s ~%-3d %s\n", f.ID, f.Complexity, f.Iterations, f.Description)
+	}
+	return nil
+}
+
+// statusReport is the project state shown by -status
+type statusReport struct {
+	PlanFile       string             `json:"plan_file"`
+	Summary        plan.Summary       `json:"summary"`
+	ExitCode       int                `json:"exit_code"`
+	NextFeature    *plan.Plan         `json:"next_feature,omitempty"`    // Feature the next iteration works on
+	Milestones     []milestone.Report `json:"milestones"`
+	Goals          []goals.GoalReport `json:"goals"`
+	LastRun        *history.Run       `json:"last_run,omitempty"`
+	Nudges         []nudge.Nudge      `json:"nudges"`                    // Active nudges
+	Memories       int                `json:"memories"`                  // Entries of the project memory
+	GlobalMemories int                `json:"global_memories,omitempty"` // Entries of the user-level memory
+}
+
+// buildStatusReport gathers the state of the plan, milestones, goals, run
+// history, nudges and memory. Only the plan is required; the other parts are
+// empty when their files do not exist.
+func buildStatusReport(cfg *config.Config) (*statusReport, error) {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	summary := plan.Summarize(plans)
+	report := &statusReport{
+		PlanFile: cfg.PlanFile,
+		Summary:  summary,
+		ExitCode: planExitCode(summary),
+		Goals:    []goals.GoalReport{},
+		Nudges:   []nudge.Nudge{},
+	}
+
+	blocked := milestoneBlockedFeatures(cfg)
+	for i := range plans {
+		if plans[i].IsActionable() && len(blocked[plans[i].ID]) == 0 {
+			report.NextFeature = &plans[i]
+			break
 		}
+	}
+
+	mgr, err := newMilestoneManager(cfg, plans)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load milestones: %w", err)
+	}
+	report.Milestones = mgr.Report()
+
+	goalMgr := goals.NewManager(plans)
+	if err := goalMgr.LoadGoals(cfg.GoalsFile); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load goals: %w", err)
+	}
+	report.Goals = goalMgr.Report()
 
-		output.Print("") // Empty line between iterations
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) > 0 {
+		report.LastRun = runs[len(runs)-1]
 	}
 
-	output.Info("Completed %d iteration(s) without completion signal.", cfg.Iterations)
-	summary.EndTime = time.Now()
-	summary.FailuresRecovered = recoveryMgr.GetRecoveredCount()
-	output.PrintSummary(summary)
-	printRecoverySummaryUI(output, recoveryMgr, cfg.Verbose)
-	
-	// Print scope summary if scope control was active
-	if cfg.ScopeLimit > 0 || cfg.Deadline != "" {
-		printScopeSummary(output, scopeMgr, cfg.Verbose)
+	nudgeStore := nudge.NewStore(cfg.NudgeFile)
+	if err := nudgeStore.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load nudges: %w", err)
 	}
-	
-	// Print memory summary if we have memories
-	if memStore.Count() > 0 && cfg.Verbose {
-		output.SubHeader("Memory Status")
-		output.Print("Total memories: %d (stored in %s)", memStore.Count(), cfg.MemoryFile)
+	report.Nudges = append(report.Nudges, nudgeStore.GetActive()...)
+
+	memStore := memory.NewStore(cfg.MemoryFile)
+	memStore.SetRetentionDays(cfg.MemoryRetention)
+	if err := memStore.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load memory: %w", err)
 	}
-	
-	// Print milestone summary if milestones are defined
-	if milestoneMgr != nil && milestoneMgr.HasMilestones() {
-		// Reload plans to get updated tested status
-		updatedPlans, err := plan.ReadFile(cfg.PlanFile)
-		if err == nil {
-			milestoneMgr = milestone.NewManager(updatedPlans)
-		}
-		output.SubHeader("Milestone Progress")
-		output.Print("%s", milestoneMgr.Summary())
-		
-		// Show next milestone to complete
-		next := milestoneMgr.GetNextMilestoneToComplete()
-		if next != nil {
-			output.Info("Next milestone: %s (%s)",
-				next.Milestone.Name,
-				milestone.FormatProgressBar(next, 20))
-		}
+	report.Memories = len(memStore.GetAll())
+	if globalStore, err := loadGlobalMemory(cfg); err == nil && globalStore != nil {
+		report.GlobalMemories = len(globalStore.GetAll())
 	}
-	
-	return nil
+
+	return report, nil
 }
 
-// containsFailureIndicators checks if the output contains signs of failure
-func containsFailureIndicators(output string) bool {
-	outputLower := strings.ToLower(output)
-	indicators := []string{
-		"fail",
-		"error:",
-		"panic:",
-		"cannot compile",
-		"build failed",
-		"test failed",
-		"assertion failed",
+// showStatus prints the -status dashboard and returns the plan's exit code
+func showStatus(cfg *config.Config) (int, error) {
+	report, err := buildStatusReport(cfg)
+	if err != nil {
+		return 0, err
 	}
-	
-	for _, indicator := range indicators {
-		if strings.Contains(outputLower, indicator) {
-			return true
+
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode status: %w", err)
 		}
+		fmt.Println(string(data))
+		return report.ExitCode, nil
+	}
+
+	s := report.Summary
+	fmt.Printf("=== Status: %s ===\n", report.PlanFile)
+	percent := 0.0
+	if s.Total > 0 {
+		percent = float64(s.Tested) * 100 / float64(s.Total)
+	}
+	fmt.Printf("Features:   %s %d/%d tested\n", milestone.FormatProgressBar(&milestone.Progress{Percentage: percent}, 30), s.Tested, s.Total)
+	fmt.Printf("            %d untested, %d deferred, %d open questions\n", s.Untested, s.Deferred, s.Questions)
+	if report.NextFeature != nil {
+		fmt.Printf("Next:       #%d %s\n", report.NextFeature.ID, report.NextFeature.Description)
+	} else if s.Untested > 0 {
+		fmt.Printf("Next:       none (the remaining features are blocked by milestones)\n")
+	} else {
+		fmt.Printf("Next:       none\n")
 	}
-	return false
-}
 
-// logFailureToProgress appends failure information to the progress file
-func logFailureToProgress(progressFile string, failure *recovery.Failure) {
-	message := fmt.Sprintf("FAILURE [%s]: %s (feature #%d, retry %d)",
-		failure.Type, failure.Message, failure.FeatureID, failure.RetryCount)
-	
-	if err := appendProgress(progressFile, message); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to log failure to progress file: %v\n", err)
+	if len(report.Milestones) > 0 {
+		fmt.Println()
+		fmt.Println("Milestones:")
+		for _, m := range report.Milestones {
+			p := &milestone.Progress{Percentage: m.Progress.Percentage}
+			fmt.Printf("  %-20s %s %d/%d\n", m.Name, milestone.FormatProgressBar(p, 20), m.Progress.CompletedFeatures, m.Progress.TotalFeatures)
+		}
 	}
-}
 
-// printRecoverySummary prints a summary of failures and recovery actions (legacy function)
-func printRecoverySummary(rm *recovery.RecoveryManager, verbose bool) {
-	summary := rm.GetFailureSummary()
-	if summary != "No failures recorded" {
+	if len(report.Goals) > 0 {
 		fmt.Println()
-		fmt.Printf("=== Recovery Summary ===\n")
-		fmt.Println(summary)
-	} else if verbose {
+		fmt.Println("Goals:")
+		for _, g := range report.Goals {
+			fmt.Printf("  [%d] %s: %.0f%% (%d/%d items, %s)\n", g.Priority, g.Description,
+				g.Progress.PercentComplete, g.Progress.CompletedItems, g.Progress.TotalItems, g.Progress.Status)
+		}
+	}
+
+	fmt.Println()
+	if r := report.LastRun; r != nil {
+		fmt.Printf("Last run:   %s, %s (%s)\n", r.DisplayName(), r.StartTime.Format("2006-01-02 15:04"), r.Duration().Round(time.Second))
+		fmt.Printf("            %d iterations, %d features completed, %d failures", r.IterationsRun, len(r.FeaturesCompleted), r.Failures)
+		if r.Cost > 0 {
+			fmt.Printf(", $%.2f", r.Cost)
+		}
 		fmt.Println()
-		fmt.Println("No failures encountered during execution.")
+	} else {
+		fmt.Println("Last run:   none recorded")
 	}
-}
 
-// printRecoverySummaryUI prints a summary using the UI package
-func printRecoverySummaryUI(output *ui.UI, rm *recovery.RecoveryManager, verbose bool) {
-	summary := rm.GetFailureSummary()
-	if summary != "No failures recorded" {
-		output.SubHeader("Recovery Summary")
-		output.Print("%s", summary)
-	} else if verbose {
-		output.Print("")
-		output.Success("No failures encountered during execution.")
+	fmt.Printf("Nudges:     %d active\n", len(report.Nudges))
+	for _, n := range report.Nudges {
+		fmt.Printf("            [%s] %s\n", strings.ToUpper(string(n.Type)), n.Content)
+	}
+	if report.GlobalMemories > 0 {
+		fmt.Printf("Memory:     %d entries (%d user-level)\n", report.Memories, report.GlobalMemories)
+	} else {
+		fmt.Printf("Memory:     %d entries\n", report.Memories)
 	}
+
+	return report.ExitCode, nil
 }
 
-// listPlanStatus displays plan status (tested/untested features)
-func listPlanStatus(cfg *config.Config) error {
-	plans, err := plan.ReadFile(cfg.PlanFile)
+// editFeature applies the feature edit given by -mark-tested, -mark-untested,
+// -defer or -delete-feature. The plan is backed up as a version first, so the
+// edit can be undone with -restore-version.
+func editFeature(cfg *config.Config) error {
+	edits := 0
+	for _, id := range []int{cfg.MarkTested, cfg.MarkUntested, cfg.DeferFeature, cfg.DeleteFeature} {
+		if id != 0 {
+			edits++
+		}
+	}
+	if edits > 1 {
+		return fmt.Errorf("only one of -mark-tested, -mark-untested, -defer and -delete-feature can be used at a time")
+	}
+	if cfg.DeferReason != "" && cfg.DeferFeature == 0 {
+		return fmt.Errorf("-reason requires -defer")
+	}
+
+	var id int
+	var action string
+	backupPath, err := editPlan(cfg, func(plans []plan.Plan) ([]plan.Plan, error) {
+		var err error
+		switch {
+		case cfg.MarkTested != 0:
+			id, action = cfg.MarkTested, "marked tested"
+			err = plan.SetTested(plans, id, true)
+		case cfg.MarkUntested != 0:
+			id, action = cfg.MarkUntested, "marked untested"
+			err = plan.SetTested(plans, id, false)
+		case cfg.DeferFeature != 0:
+			reason := strings.TrimSpace(cfg.DeferReason)
+			if reason == "" {
+				reason = "deferred manually"
+			}
+			id, action = cfg.DeferFeature, fmt.Sprintf("deferred (%s)", reason)
+			err = plan.Defer(plans, id, reason)
+		default:
+			id, action = cfg.DeleteFeature, "deleted"
+			if p := plan.GetByID(plans, id); p != nil {
+				action = fmt.Sprintf("deleted (%s)", p.Description)
+			}
+			plans, err = plan.Delete(plans, id)
+		}
+		return plans, err
+	})
 	if err != nil {
 		return err
 	}
 
-	// Determine what to show
-	showTested := cfg.ListAll || cfg.ListTested
-	showUntested := cfg.ListAll || cfg.ListUntested
+	fmt.Printf("Feature #%d %s\n", id, action)
+	fmt.Printf("Previous plan saved to %s (see -list-versions and -restore-version)\n", backupPath)
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("EDIT: feature #%d %s", id, action))
+	return nil
+}
 
-	if showTested {
-		fmt.Printf("=== Tested Features (from %s) ===\n", cfg.PlanFile)
-		tested := plan.Filter(plans, true)
-		if len(tested) == 0 {
-			fmt.Println("No tested features found")
-		} else {
-			plan.Print(tested)
+// editPlan applies an edit made by hand to the plan, holding the plan's lock,
+// and saves the current plan as a version before writing the edited one. It
+// returns the path of the version, or "" if nothing was written or there was
+// no plan to save.
+func editPlan(cfg *config.Config, edit func([]plan.Plan) ([]plan.Plan, error)) (string, error) {
+	backupPath := ""
+	err := plan.Update(cfg.PlanFile, func(plans []plan.Plan) ([]plan.Plan, error) {
+		plans, err := edit(plans)
+		if err != nil {
+			return nil, err
 		}
-		fmt.Println()
+		if _, err := os.Stat(cfg.PlanFile); err == nil {
+			versioner := replan.NewPlanVersioner(cfg.PlanFile)
+			if err := versioner.SetOptions(planBackupOptions(cfg)); err != nil {
+				return nil, err
+			}
+			if backupPath, err = versioner.CreateBackup(replan.TriggerManual); err != nil {
+				return nil, fmt.Errorf("failed to back up plan: %w", err)
+			}
+		}
+		return plans, nil
+	})
+	return backupPath, err
+}
+
+// mergePlanFile merges the features of the plan given by -merge-plan into the
+// plan. The plan is saved as a version first, and created if it does not exist.
+func mergePlanFile(cfg *config.Config) error {
+	if cfg.MergeSimilarity < 0 || cfg.MergeSimilarity > 1 {
+		return fmt.Errorf("-merge-similarity must be between 0 and 1")
+	}
+	other, err := plan.ReadFile(cfg.MergePlan)
+	if err != nil {
+		return err
 	}
+	var m *goals.Merge
+	backupPath, err := editPlan(cfg, func(plans []plan.Plan) ([]plan.Plan, error) {
+		m = goals.MergePlanFile(plans, other, goals.MergeOptions{
+			MilestonePrefix: cfg.MergeMilestonePrefix,
+			Similarity:      cfg.MergeSimilarity,
+		})
+		if len(m.Added) == 0 {
+			return nil, plan.ErrNoChange
+		}
+		return m.Plans, nil
+	})
+	if err != nil {
+		return err
+	}
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("MERGE: %d feature(s) merged from %s, %d skipped as duplicates", len(m.Added), cfg.MergePlan, len(m.Skipped)))
 
-	if showUntested {
-		fmt.Printf("=== Untested Features (from %s) ===\n", cfg.PlanFile)
-		untested := plan.Filter(plans, false)
-		if len(untested) == 0 {
-			fmt.Println("No untested features found")
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode merge: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("Merged %d of %d feature(s) from %s into %s\n", len(m.Added), len(other), cfg.MergePlan, cfg.PlanFile)
+	renumbered := make(map[int]int, len(m.Renumbered))
+	for old, id := range m.Renumbered {
+		renumbered[id] = old
+	}
+	for _, p := range m.Added {
+		if old, ok := renumbered[p.ID]; ok {
+			fmt.Printf("  + #%d %s (was #%d)\n", p.ID, p.Description, old)
 		} else {
-			plan.Print(untested)
+			fmt.Printf("  + #%d %s\n", p.ID, p.Description)
 		}
 	}
+	for _, s := range m.Skipped {
+		fmt.Printf("  = %s (%.0f%% similar to #%d %s)\n", s.Feature.Description, s.Similarity*100, s.Duplicate.ID, s.Duplicate.Description)
+	}
+	if backupPath != "" {
+		fmt.Printf("Previous plan saved to %s (see -list-versions and -restore-version)\n", backupPath)
+	}
+	return nil
+}
+
+// addFeature appends the feature given by -add-feature to the plan, with the
+// next free ID. The plan file is created if it does not exist.
+func addFeature(cfg *config.Config) error {
+	if cfg.ShowMilestone != "" {
+		return fmt.Errorf("-milestone shows a milestone's features; set the milestone of the new feature with -feature-milestone")
+	}
+
+	feature := plan.Plan{
+		Category:    strings.TrimSpace(cfg.FeatureCategory),
+		Description: cfg.AddFeature,
+		Milestone:   strings.TrimSpace(cfg.FeatureMilestone),
+	}
+	if feature.Category == "" {
+		feature.Category = "feature"
+	}
+	if cfg.FeatureSteps != "" {
+		feature.Steps = strings.Split(cfg.FeatureSteps, ";")
+	}
+	var added plan.Plan
+	_, err := editPlan(cfg, func(plans []plan.Plan) ([]plan.Plan, error) {
+		var err error
+		plans, added, err = plan.Add(plans, feature)
+		return plans, err
+	})
+	if err != nil {
+		return err
+	}
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("EDIT: feature #%d added: %s", added.ID, added.Description))
 
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(added, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode feature: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("Added feature #%d to %s: %s\n", added.ID, cfg.PlanFile, added.Description)
 	return nil
 }
 
-// listDeferredFeatures displays features that have been deferred due to scope constraints
-func listDeferredFeatures(cfg *config.Config) error {
+// featureRecordEvents is the number of progress log events -show-feature
+// prints; -json-output includes them all
+const featureRecordEvents = 10
+
+// featureRecord is the full record of a feature shown by -show-feature
+type featureRecord struct {
+	plan.Plan
+	Status      string           `json:"status"`               // tested, deferred, question, blocked or untested
+	BlockedBy   []string         `json:"blocked_by,omitempty"` // Prerequisite milestones that are not complete
+	Goal        *goals.Goal      `json:"goal,omitempty"`       // Goal the feature was generated from
+	Iterations  int              `json:"iterations"`           // Iterations spent on the feature in recorded runs
+	Runs        []featureRun     `json:"runs"`
+	Events      []progress.Event `json:"events"`                 // Events of the structured progress log about the feature
+	LastFailure *progress.Event  `json:"last_failure,omitempty"` // Latest failure event of the feature
+}
+
+// featureRun is a recorded run that worked on a feature
+type featureRun struct {
+	ID         string    `json:"id"`
+	Label      string    `json:"label,omitempty"`
+	Agent      string    `json:"agent"`
+	StartTime  time.Time `json:"start_time"`
+	Iterations int       `json:"iterations"`
+	Completed  bool      `json:"completed"` // Whether the feature was marked tested in the run
+}
+
+// buildFeatureRecord gathers what is known about a feature from the plan, the
+// goals, the run history and the structured progress log
+func buildFeatureRecord(cfg *config.Config, id int) (*featureRecord, error) {
 	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return nil, err
+	}
+	p := plan.GetByID(plans, id)
+	if p == nil {
+		return nil, fmt.Errorf("feature #%d not found in %s", id, cfg.PlanFile)
+	}
+	record := &featureRecord{
+		Plan:      *p,
+		BlockedBy: milestoneBlockedFeatures(cfg)[id],
+		Runs:      []featureRun{},
+		Events:    []progress.Event{},
+	}
+	switch {
+	case p.Tested:
+		record.Status = "tested"
+	case p.Deferred:
+		record.Status = "deferred"
+	case p.IsQuestion():
+		record.Status = "question"
+	case len(record.BlockedBy) > 0:
+		record.Status = "blocked"
+	default:
+		record.Status = "untested"
+	}
+
+	goalMgr := goals.NewManager(plans)
+	if err := goalMgr.LoadGoals(cfg.GoalsFile); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to load goals: %w", err)
+	}
+	record.Goal = goalMgr.GoalForPlan(id)
+
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		completed := false
+		for _, fid := range r.FeaturesCompleted {
+			if fid == id {
+				completed = true
+			}
+		}
+		if r.IterationsPerFeature[id] == 0 && !completed {
+			continue
+		}
+		record.Iterations += r.IterationsPerFeature[id]
+		record.Runs = append(record.Runs, featureRun{
+			ID:         r.ID,
+			Label:      r.Label,
+			Agent:      r.Agent,
+			StartTime:  r.StartTime,
+			Iterations: r.IterationsPerFeature[id],
+			Completed:  completed,
+		})
+	}
+
+	events, err := progress.Read(progress.JSONLPath(cfg.ProgressFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for i, e := range events {
+		if e.FeatureID != id {
+			continue
+		}
+		record.Events = append(record.Events, e)
+		if e.Type == "failure" {
+			record.LastFailure = &events[i]
+		}
+	}
+
+	return record, nil
+}
+
+// showFeature prints the full record of the feature given by -show-feature
+func showFeature(cfg *config.Config) error {
+	record, err := buildFeatureRecord(cfg, cfg.ShowFeature)
 	if err != nil {
 		return err
 	}
 
-	deferred := plan.FilterDeferred(plans, true)
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode feature: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
 
-	fmt.Printf("=== Deferred Features (from %s) ===\n", cfg.PlanFile)
-	if len(deferred) == 0 {
-		fmt.Println("No deferred features found")
-		fmt.Println()
-		fmt.Println("Features are deferred when they exceed scope constraints:")
-		fmt.Println("  - Iteration limit reached (-scope-limit flag)")
-		fmt.Println("  - Deadline reached (-deadline flag)")
-		fmt.Println()
-		fmt.Println("To use scope control, run with:")
-		fmt.Printf("  %s -iterations 10 -scope-limit 3  # Max 3 iterations per feature\n", os.Args[0])
-		fmt.Printf("  %s -iterations 10 -deadline 1h   # 1 hour time limit\n", os.Args[0])
-	} else {
-		for _, p := range deferred {
-			reason := p.DeferReason
-			if reason == "" {
-				reason = "unspecified"
+	p := record.Plan
+	fmt.Printf("=== Feature #%d: %s ===\n", p.ID, p.Description)
+	fmt.Printf("Status: %s\n", record.Status)
+	if p.Category != "" {
+		fmt.Printf("Category: %s\n", p.Category)
+	}
+	if p.Milestone != "" {
+		fmt.Printf("Milestone: %s\n", p.Milestone)
+	}
+	if len(record.BlockedBy) > 0 {
+		fmt.Printf("Blocked by: %s\n", strings.Join(record.BlockedBy, ", "))
+	}
+	if p.Deferred {
+		reason := p.DeferReason
+		if reason == "" {
+			reason = "unspecified"
+		}
+		fmt.Printf("Deferred: %s\n", reason)
+	}
+	if record.Goal != nil {
+		fmt.Printf("Goal: %s (%s)\n", record.Goal.Description, record.Goal.ID)
+	}
+	if p.Issue != "" {
+		fmt.Printf("Issue: %s\n", p.Issue)
+	}
+	if p.MaxIterations > 0 {
+		fmt.Printf("Max iterations: %d\n", p.MaxIterations)
+	}
+	if p.Answer != "" {
+		fmt.Printf("Answer: %s\n", p.Answer)
+	}
+
+	if len(p.Steps) > 0 {
+		fmt.Println("\nSteps:")
+		for i, step := range p.Steps {
+			fmt.Printf("  %d. %s\n", i+1, step)
+		}
+	}
+	if p.ExpectedOutput != "" {
+		fmt.Printf("\nExpected output: %s\n", p.ExpectedOutput)
+	}
+	if len(p.Validations) > 0 {
+		fmt.Println("\nValidations:")
+		for _, v := range p.Validations {
+			desc := v.Description
+			for _, alt := range []string{v.URL, v.Command, v.Path} {
+				if desc == "" {
+					desc = alt
+				}
 			}
-			fmt.Printf("  %d. %s [%s] - %s\n", p.ID, p.Category, reason, p.Description)
+			fmt.Printf("  [%s] %s\n", v.Type, desc)
 		}
-		fmt.Printf("\nTotal deferred: %d features\n", len(deferred))
+	}
+
+	fmt.Printf("\nIterations: %d in %d recorded run(s)\n", record.Iterations, len(record.Runs))
+	for _, r := range record.Runs {
+		outcome := ""
+		if r.Completed {
+			outcome = ", completed"
+		}
+		name := r.ID
+		if r.Label != "" {
+			name = r.Label
+		}
+		fmt.Printf("  %s %s (%s): %d iteration(s)%s\n", r.StartTime.Format("2006-01-02 15:04"), name, r.Agent, r.Iterations, outcome)
+	}
+
+	if len(record.Events) > 0 {
+		fmt.Println("\nProgress log:")
+		events := record.Events
+		if len(events) > featureRecordEvents {
+			fmt.Printf("  (%d earlier events, see -progress-query \"feature=%d\")\n", len(events)-featureRecordEvents, p.ID)
+			events = events[len(events)-featureRecordEvents:]
+		}
+		for _, e := range events {
+			fmt.Printf("  %s\n", progress.Format(e))
+		}
+	}
+	if record.LastFailure != nil {
+		fmt.Printf("\nLast failure (%s):\n  %s\n", record.LastFailure.Time.Local().Format("2006-01-02 15:04:05"), record.LastFailure.Message)
 	}
 
 	return nil
@@ -1578,9 +6936,22 @@ func listDeferredFeatures(cfg *config.Config) error {
 
 // generatePlanFromNotes generates a plan.json file from notes using the AI agent
 func generatePlanFromNotes(cfg *config.Config) error {
+	if cfg.FromIssue != "" {
+		notesPath, err := fetchIssueNotes(cfg)
+		if err != nil {
+			return err
+		}
+		cfg.NotesFile = notesPath
+	}
+	if cfg.NoAgent {
+		return generatePlanWithoutAgent(cfg)
+	}
+	if cfg.Transcript && startTranscript(cfg, history.RunID(time.Now())) != nil {
+		defer agent.SetTranscript(nil)
+	}
 	fmt.Printf("Generating plan from notes file: %s\n", cfg.NotesFile)
 	fmt.Printf("Output plan file: %s\n", cfg.OutputPlanFile)
-	fmt.Printf("Agent command: %s\n\n", cfg.AgentCmd)
+	fmt.Printf("Agent: %s\n\n", agentName(cfg))
 
 	// Resolve absolute paths
 	notesPath, err := filepath.Abs(cfg.NotesFile)
@@ -1594,7 +6965,10 @@ func generatePlanFromNotes(cfg *config.Config) error {
 	}
 
 	// Build the prompt for plan generation
-	genPrompt := prompt.BuildPlanGenerationPrompt(notesPath, outputPath)
+	genPrompt, err := prompt.RenderPlanGenerationPrompt(cfg, notesPath, outputPath)
+	if err != nil {
+		return err
+	}
 
 	if cfg.Verbose {
 		fmt.Printf("Prompt: %s\n\n", genPrompt)
@@ -1619,22 +6993,99 @@ func generatePlanFromNotes(cfg *config.Config) error {
 	return nil
 }
 
-// appendProgress appends a message to the progress file
+// generatePlanWithoutAgent generates a plan file from the headings, checklists
+// and lists of the notes, without running the agent
+func generatePlanWithoutAgent(cfg *config.Config) error {
+	fmt.Printf("Generating plan from notes file (no agent): %s\n", cfg.NotesFile)
+	fmt.Printf("Output plan file: %s\n\n", cfg.OutputPlanFile)
+
+	notes, err := os.ReadFile(cfg.NotesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read notes file: %w", err)
+	}
+	plans := plan.ParseNotes(string(notes))
+	if len(plans) == 0 {
+		return fmt.Errorf("no features found in %s: list them as bullets, checklist items or numbered steps under headings, or generate the plan with the agent", cfg.NotesFile)
+	}
+	if err := plan.WriteFile(cfg.OutputPlanFile, plans); err != nil {
+		return err
+	}
+
+	plan.Print(plans)
+	if questions := plan.FilterQuestions(plans); len(questions) > 0 {
+		fmt.Printf("\n%d open question(s); answer them with: %s question answer <id> \"<answer>\"\n", len(questions), os.Args[0])
+	}
+	fmt.Printf("\n✓ Plan generated successfully: %s (%d features)\n", cfg.OutputPlanFile, len(plans))
+	return nil
+}
+
+// issueNotesDir is where the notes fetched with -from-issue are written, so
+// the agent can read them like a notes file
+var issueNotesDir = filepath.Join(".ralph", "issues")
+
+// fetchIssueNotes fetches the GitHub issue or pull request of -from-issue with
+// its comments, writes it as markdown notes and returns their path. The token
+// of a GitHub tracker is used, or else GITHUB_TOKEN if set.
+func fetchIssueNotes(cfg *config.Config) (string, error) {
+	ref, err := tracker.ParseGitHubRef(cfg.FromIssue)
+	if err != nil {
+		return "", err
+	}
+	token := os.Getenv(tracker.DefaultTokenEnv[tracker.ProviderGitHub])
+	var apiURL string
+	if cfg.Tracker != nil && strings.EqualFold(cfg.Tracker.Provider, string(tracker.ProviderGitHub)) {
+		if cfg.Tracker.Token != "" {
+			if token, err = agent.ResolveValue(cfg.Tracker.Token); err != nil {
+				return "", fmt.Errorf("tracker token: %w", err)
+			}
+		}
+		apiURL = cfg.Tracker.URL
+	}
+
+	fmt.Printf("Fetching %s...\n", ref)
+	thread, err := tracker.FetchGitHubThread(context.Background(), tracker.Config{URL: apiURL, Token: token}, ref)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(issueNotesDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create issue notes directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%d.md", strings.ReplaceAll(ref.Repo, "/", "-"), ref.Number)
+	path := filepath.Join(issueNotesDir, name)
+	if err := os.WriteFile(path, []byte(thread.Markdown()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write issue notes: %w", err)
+	}
+	fmt.Printf("Fetched %q with %d comments\n", thread.Title, len(thread.Comments))
+	return path, nil
+}
+
+// progressContext is the iteration and feature in progress, recorded with
+// progress events whose messages do not name them
+var progressContext progress.Context
+
+// appendProgress appends a message to the progress file, and records it as an
+// event in the structured progress log next to it. Secrets are masked first.
 func appendProgress(path string, message string) error {
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open progress file: %w", err)
+	message = secretRedactor.Redact(message)
+	if err := progress.Append(progress.JSONLPath(path), progress.NewEvent(message, progressContext)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write progress log: %v\n", err)
 	}
-	defer f.Close()
+	return filelock.With(path, func() error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open progress file: %w", err)
+		}
+		defer f.Close()
 
-	timestamp := time.Now().Format(time.RFC3339)
-	entry := fmt.Sprintf("\n[%s] %s\n", timestamp, message)
+		timestamp := time.Now().Format(time.RFC3339)
+		entry := fmt.Sprintf("\n[%s] %s\n", timestamp, message)
 
-	if _, err := f.WriteString(entry); err != nil {
-		return fmt.Errorf("failed to write to progress file: %w", err)
-	}
+		if _, err := f.WriteString(entry); err != nil {
+			return fmt.Errorf("failed to write to progress file: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
 
 // handleNudgeCommands processes nudge-related CLI commands
@@ -1659,22 +7110,27 @@ func handleNudgeCommands(cfg *config.Config) error {
 
 	// Handle add nudge command
 	if cfg.Nudge != "" {
-		parts := strings.SplitN(cfg.Nudge, ":", 2)
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid nudge format: expected 'type:content' (e.g., 'focus:Work on feature 5')")
-		}
-
-		nudgeType, err := nudge.ParseNudgeType(parts[0])
+		parsed, err := nudge.Parse(cfg.Nudge)
 		if err != nil {
 			return err
 		}
 
-		n, err := store.Add(nudgeType, parts[1], 0)
+		parsed.Sticky = cfg.NudgeSticky
+		parsed.TTL = cfg.NudgeTTL
+		n, err := store.Insert(parsed)
 		if err != nil {
 			return fmt.Errorf("failed to add nudge: %w", err)
 		}
 
 		fmt.Printf("Nudge added: [%s] %s\n", strings.ToUpper(string(n.Type)), n.Content)
+		if n.Persistent() {
+			fmt.Printf("Lifetime: %s\n", n.Lifetime(time.Now()))
+		}
+		return nil
+	}
+
+	if cfg.NudgeList {
+		fmt.Print(store.List())
 		return nil
 	}
 
@@ -1687,14 +7143,50 @@ func handleNudgeCommands(cfg *config.Config) error {
 	return nil
 }
 
-// handleMemoryCommands processes memory-related CLI commands
+// loadGlobalMemory loads the user-level memory store, or returns nil if it
+// is disabled
+func loadGlobalMemory(cfg *config.Config) (*memory.Store, error) {
+	path := memory.GlobalMemoryFile()
+	if cfg.NoGlobalMemory || path == "" {
+		return nil, nil
+	}
+	store := memory.NewStore(path)
+	store.SetRetentionDays(cfg.MemoryRetention)
+	store.SetRedactor(secretRedactor.Redact)
+	if err := store.Load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// handleMemoryCommands processes memory-related CLI commands. They target the
+// project store, with the user-level store underneath for showing, editing,
+// deleting and pinning memories, or only the user-level store with -global.
 func handleMemoryCommands(cfg *config.Config) error {
+	if cfg.GlobalMemory {
+		if cfg.NoGlobalMemory || memory.GlobalMemoryFile() == "" {
+			return fmt.Errorf("-global requires the user-level memory store (home directory unknown or -no-global-memory set)")
+		}
+		// The user-level store takes the project store's place
+		global := *cfg
+		global.MemoryFile = memory.GlobalMemoryFile()
+		global.GlobalMemory = false
+		global.NoGlobalMemory = true
+		cfg = &global
+	}
+
 	store := memory.NewStore(cfg.MemoryFile)
 	store.SetRetentionDays(cfg.MemoryRetention)
+	store.SetRedactor(secretRedactor.Redact)
 
 	if err := store.Load(); err != nil {
 		return fmt.Errorf("failed to load memory: %w", err)
 	}
+	globalStore, err := loadGlobalMemory(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load user-level memory: %w", err)
+	}
+	store.SetGlobal(globalStore)
 
 	// Handle clear memory command
 	if cfg.ClearMemory {
@@ -1726,6 +7218,76 @@ func handleMemoryCommands(cfg *config.Config) error {
 		return nil
 	}
 
+	// Handle delete, edit and pin memory commands
+	if cfg.DeleteMemory != "" {
+		entry, err := store.Delete(cfg.DeleteMemory)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Memory deleted: %s [%s] %s\n", entry.ID, strings.ToUpper(string(entry.Type)), entry.Content)
+		return nil
+	}
+	if cfg.EditMemory != "" {
+		id, content, ok := strings.Cut(cfg.EditMemory, ":")
+		if !ok {
+			return fmt.Errorf("invalid edit-memory format: expected 'id:content' (e.g., 'mem_1712:Use PostgreSQL 16')")
+		}
+		entry, err := store.Edit(id, content)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Memory updated: %s [%s] %s\n", entry.ID, strings.ToUpper(string(entry.Type)), entry.Content)
+		return nil
+	}
+	if cfg.PinMemory != "" || cfg.UnpinMemory != "" {
+		id, pinned := cfg.PinMemory, true
+		if id == "" {
+			id, pinned = cfg.UnpinMemory, false
+		}
+		entry, err := store.Pin(id, pinned)
+		if err != nil {
+			return err
+		}
+		if pinned {
+			fmt.Printf("Memory pinned (never pruned): %s %s\n", entry.ID, entry.Content)
+		} else {
+			fmt.Printf("Memory unpinned: %s %s\n", entry.ID, entry.Content)
+		}
+		return nil
+	}
+
+	// Handle export memory command
+	if cfg.ExportMemory != "" {
+		n, err := store.Export(cfg.ExportMemory)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d memories to %s\n", n, cfg.ExportMemory)
+		return nil
+	}
+
+	// Handle import memory command
+	if cfg.ImportMemory != "" {
+		entries, err := memory.ReadExport(cfg.ImportMemory)
+		if err != nil {
+			return err
+		}
+		store.SetGlobal(nil) // Only the target store is counted and replaced
+		before := store.Count()
+		result, err := store.Import(entries, cfg.MergeMemory)
+		if err != nil {
+			return fmt.Errorf("failed to import memory: %w", err)
+		}
+		if cfg.MergeMemory {
+			fmt.Printf("Merged %s into %s: %d added, %d updated, %d already present\n",
+				cfg.ImportMemory, cfg.MemoryFile, result.Added, result.Updated, result.Unchanged)
+		} else {
+			fmt.Printf("Imported %d memories from %s, replacing %d (use -merge to keep them)\n",
+				store.Count(), cfg.ImportMemory, before)
+		}
+		return nil
+	}
+
 	// Handle show memory command (default if no other memory command)
 	if cfg.ShowMemory {
 		// Prune old memories first
@@ -1734,7 +7296,11 @@ func handleMemoryCommands(cfg *config.Config) error {
 			fmt.Printf("Pruned %d expired memories\n\n", pruned)
 		}
 
-		fmt.Println(store.Summary())
+		if cfg.Verbose {
+			fmt.Println(store.Details())
+		} else {
+			fmt.Println(store.Summary())
+		}
 		return nil
 	}
 
@@ -1742,7 +7308,8 @@ func handleMemoryCommands(cfg *config.Config) error {
 }
 
 // extractAndStoreMemories extracts memories from agent output and stores them
-func extractAndStoreMemories(store *memory.Store, output, category string) int {
+// with their provenance
+func extractAndStoreMemories(store *memory.Store, output, category string, prov memory.Provenance) int {
 	entries := memory.ExtractFromOutput(output)
 	if len(entries) == 0 {
 		return 0
@@ -1751,7 +7318,7 @@ func extractAndStoreMemories(store *memory.Store, output, category string) int {
 	stored := 0
 	for _, e := range entries {
 		e.Category = category
-		_, err := store.Add(e.Type, e.Content, category, "agent")
+		_, err := store.AddFromAgent(e.Type, e.Content, category, prov)
 		if err == nil {
 			stored++
 		}
@@ -1769,6 +7336,9 @@ func formatScopeInfo(cfg *config.Config) string {
 	if cfg.Deadline != "" {
 		parts = append(parts, fmt.Sprintf("deadline %s", cfg.Deadline))
 	}
+	if cfg.FeatureDeadline != "" {
+		parts = append(parts, fmt.Sprintf("%s/feature", cfg.FeatureDeadline))
+	}
 	if len(parts) == 0 {
 		return "unlimited"
 	}
@@ -1777,15 +7347,12 @@ func formatScopeInfo(cfg *config.Config) string {
 
 // markFeatureDeferred updates the plan file to mark a feature as deferred
 func markFeatureDeferred(planFile string, featureID int, reason string) error {
-	plans, err := plan.ReadFile(planFile)
-	if err != nil {
-		return err
-	}
-
-	if plan.MarkDeferred(plans, featureID, reason) {
-		return plan.WriteFile(planFile, plans)
-	}
-	return nil
+	return plan.Update(planFile, func(plans []plan.Plan) ([]plan.Plan, error) {
+		if !plan.MarkDeferred(plans, featureID, reason) {
+			return nil, plan.ErrNoChange
+		}
+		return plans, nil
+	})
 }
 
 // printScopeSummary prints a summary of scope control results
@@ -1813,16 +7380,17 @@ func printScopeSummary(output *ui.UI, scopeMgr *scope.Manager, verbose bool) {
 	}
 }
 
-// extractCurrentFeatureFromPlans tries to get the current feature being worked on
-func extractCurrentFeatureFromPlans(planFile string) (int, int, string) {
+// extractCurrentFeatureFromPlans tries to get the current feature being worked on.
+// Blocked features (see milestoneBlockedFeatures) are not started.
+func extractCurrentFeatureFromPlans(planFile string, blocked map[int][]string) (int, int, string) {
 	plans, err := plan.ReadFile(planFile)
 	if err != nil {
 		return 0, 0, ""
 	}
 
-	// Find first untested, non-deferred feature
+	// Find first actionable feature (untested, not deferred, not an open question)
 	for _, p := range plans {
-		if !p.Tested && !p.Deferred {
+		if p.IsActionable() && len(blocked[p.ID]) == 0 {
 			return p.ID, len(p.Steps), p.Description
 		}
 	}
@@ -1833,6 +7401,9 @@ func extractCurrentFeatureFromPlans(planFile string) (int, int, string) {
 func handleReplanCommands(cfg *config.Config) error {
 	// Create replan manager
 	replanMgr := replan.NewReplanManager(cfg.PlanFile, cfg.AgentCmd, cfg.AutoReplan)
+	if err := replanMgr.SetBackupOptions(planBackupOptions(cfg)); err != nil {
+		return err
+	}
 
 	// Handle list versions command
 	if cfg.ListVersions {
@@ -1870,36 +7441,17 @@ func handleReplanCommands(cfg *config.Config) error {
 
 	// Handle manual replan command
 	if cfg.Replan {
-		// Load current plans
-		plans, err := plan.ReadFile(cfg.PlanFile)
-		if err != nil {
-			return fmt.Errorf("failed to load plan file: %w", err)
-		}
-
-		// Find current feature (first untested, non-deferred)
-		currentFeatureID := 0
-		for _, p := range plans {
-			if !p.Tested && !p.Deferred {
-				currentFeatureID = p.ID
-				break
-			}
+		if confirmReplans(cfg) {
+			output := ui.New(ui.OutputConfig{NoColor: cfg.NoColor, Quiet: cfg.Quiet, JSONOutput: cfg.JSONOutput})
+			replanMgr.SetConfirm(replanConfirmer(output, approval.NewReviewer(os.Stdin, os.Stdout), cfg.PlanFile))
 		}
 
-		// Update state
-		replanMgr.UpdateState(currentFeatureID, 0, nil, plans)
-
-		// Parse strategy
-		strategyType, err := replan.ParseStrategyType(cfg.ReplanStrategy)
-		if err != nil {
-			return err
-		}
-
-		fmt.Printf("Manual replanning with strategy: %s\n", strategyType)
+		fmt.Printf("Manual replanning with strategy: %s\n", cfg.ReplanStrategy)
 
 		// Execute replanning
-		result, err := replanMgr.ManualReplan(strategyType)
+		result, err := manualReplan(replanMgr, cfg.PlanFile, cfg.ReplanStrategy)
 		if err != nil {
-			return fmt.Errorf("replanning failed: %w", err)
+			return err
 		}
 
 		// Display results
@@ -1908,7 +7460,7 @@ func handleReplanCommands(cfg *config.Config) error {
 			if result.OldPlanPath != "" {
 				fmt.Printf("Backup created: %s\n", result.OldPlanPath)
 			}
-			if result.Diff != nil && !result.Diff.IsEmpty() {
+			if result.Diff != nil && !result.Diff.IsEmpty() && !confirmReplans(cfg) {
 				fmt.Println()
 				fmt.Println(result.Diff.Summary())
 			}
@@ -1921,6 +7473,384 @@ func handleReplanCommands(cfg *config.Config) error {
 	return nil
 }
 
+// manualReplan replans the plan file with the given strategy, starting from
+// its first actionable feature
+func manualReplan(replanMgr *replan.ReplanManager, planFile, strategy string) (*replan.ReplanResult, error) {
+	plans, err := plan.ReadFile(planFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plan file: %w", err)
+	}
+
+	// Find current feature (first actionable)
+	currentFeatureID := 0
+	for _, p := range plans {
+		if p.IsActionable() {
+			currentFeatureID = p.ID
+			break
+		}
+	}
+	replanMgr.UpdateState(currentFeatureID, 0, nil, plans)
+
+	strategyType, err := replan.ParseStrategyType(strategy)
+	if err != nil {
+		return nil, err
+	}
+	result, err := replanMgr.ManualReplan(strategyType)
+	if err != nil {
+		return nil, fmt.Errorf("replanning failed: %w", err)
+	}
+	return result, nil
+}
+
+// planBackupOptions returns where plan backups are stored and how many are
+// kept
+func planBackupOptions(cfg *config.Config) replan.BackupOptions {
+	return replan.BackupOptions{
+		Dir:         cfg.PlanVersionDir,
+		MaxVersions: cfg.MaxPlanVersions,
+		Compress:    cfg.CompressPlanVersions,
+	}
+}
+
+// confirmReplans reports whether replans must be confirmed before they
+// overwrite the plan (-replan-confirm without -yes)
+func confirmReplans(cfg *config.Config) bool {
+	return cfg.ReplanConfirm && !cfg.Yes
+}
+
+// replanConfirmer shows the changes of a replan and asks whether to apply
+// them. Without an answer (e.g., when input is not a terminal) the replan
+// is not applied.
+func replanConfirmer(output *ui.UI, reviewer *approval.Reviewer, planFile string) replan.ConfirmFunc {
+	return func(result *replan.ReplanResult) bool {
+		output.SubHeader("Replan Preview (%s strategy, trigger: %s)", result.Strategy, result.Trigger)
+		output.Print("%s", strings.TrimRight(result.Diff.Summary(), "\n"))
+		ok, err := reviewer.Confirm(fmt.Sprintf("Apply these changes to %s?", planFile))
+		if err != nil {
+			output.Warn("Replan not confirmed: %v", err)
+			return false
+		}
+		return ok
+	}
+}
+
+// completeGoals marks goals complete once every plan item generated from them
+// is tested. A goal with success criteria is only completed if the
+// validations of its criteria pass; they are generated from the criteria the
+// first time. Goals whose validations failed are recorded in failed and not
+// checked again in this run.
+func completeGoals(cfg *config.Config, output *ui.UI, notifier *notify.Notifier, pol *policy.Policy, pathGuard *guard.Guard, failed map[string]bool) {
+	if _, err := os.Stat(cfg.GoalsFile); err != nil {
+		return
+	}
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return
+	}
+	goalMgr := goals.NewManager(plans)
+	goalMgr.SetGoalsFile(cfg.GoalsFile)
+	if err := goalMgr.LoadGoals(cfg.GoalsFile); err != nil {
+		output.Debug("Not checking goal completion: %v", err)
+		return
+	}
+
+	changed := false
+	for _, goal := range goalMgr.FinishedGoals() {
+		if failed[goal.ID] {
+			continue
+		}
+		if len(goal.Validations) == 0 && len(goal.SuccessCriteria) > 0 {
+			goal.Validations = goals.CriteriaValidations(goal.SuccessCriteria)
+			changed = changed || len(goal.Validations) > 0
+		}
+		if len(goal.Validations) > 0 {
+			output.SubHeader("Validating goal %s: %s", goal.ID, goal.Description)
+			result := validateFeature(context.Background(), cfg, output, pol, pathGuard,
+				plan.Plan{Description: goal.Description, Validations: goal.Validations})
+			if !result.Success {
+				for _, vr := range result.Results {
+					if !vr.Success {
+						output.Error("  %s", vr.Message)
+					}
+				}
+				output.Warn("Goal %s stays open: %d of %d success criteria validation(s) failed (use -complete-goal %s once they pass)",
+					goal.ID, result.FailedCount, result.TotalCount, goal.ID)
+				appendProgress(cfg.ProgressFile, fmt.Sprintf("GOAL: %s has all plan items tested, but %d success criteria validation(s) failed", goal.ID, result.FailedCount))
+				failed[goal.ID] = true
+				continue
+			}
+		}
+
+		if err := goalMgr.MarkGoalComplete(goal.ID); err != nil {
+			output.Debug("Failed to complete goal %s: %v", goal.ID, err)
+			continue
+		}
+		changed = true
+		output.Success("%s", goals.CelebrationMessage(goal))
+		appendProgress(cfg.ProgressFile, fmt.Sprintf("GOAL COMPLETE: %s (%s), %d plan item(s) tested, %d validation(s) passed",
+			goal.ID, goal.Description, len(goal.GeneratedPlanIDs), len(goal.Validations)))
+		notifyDesktop(notifier, output, "Ralph: goal complete", goal.Description)
+	}
+	if changed {
+		if err := goalMgr.SaveGoals(); err != nil {
+			output.Warn("Failed to save goals: %v", err)
+		}
+	}
+}
+
+// validateFeature runs the validations of a feature. Commands the policy
+// forbids are reported as failed validations instead of being run, as are
+// changes the validators make outside the repository.
+func validateFeature(ctx context.Context, cfg *config.Config, output *ui.UI, pol *policy.Policy, pathGuard *guard.Guard, p plan.Plan) validation.ValidationRunResult {
+	// Create validation runner
+	runner := validation.NewValidationRunner()
+
+	// Convert plan.ValidationDefinition to validation.ValidationDefinition
+	var blocked []validation.ValidationResult
+	for _, vdef := range p.Validations {
+		// Coverage is measured with the build system's command unless one is given
+		if vdef.Type == string(validation.ValidationTypeCoverage) && vdef.Command == "" {
+			vdef.Command = coverageCommand(cfg)
+		}
+		// Security scans run the chosen tool's JSON command unless one is given
+		if vdef.Type == string(validation.ValidationTypeSecurityScan) && vdef.Command == "" {
+			if tool, ok := vdef.Options["tool"].(string); ok {
+				vdef.Command = security.DefaultCommands[tool]
+			}
+		}
+		if vdef.Type == string(validation.ValidationTypeCLI) || vdef.Type == string(validation.ValidationTypeCoverage) ||
+			(vdef.Type == string(validation.ValidationTypeSecurityScan) && vdef.Command != "") {
+			if err := pol.CheckCommand(vdef.Command); err != nil {
+				blocked = append(blocked, validation.ValidationResult{
+					ValidatorID: "policy",
+					Success:     false,
+					Message:     fmt.Sprintf("%s %q blocked by policy", vdef.Type, vdef.Command),
+					Error:       err.Error(),
+				})
+				continue
+			}
+		}
+		if mp := vdef.ManagedProcess; mp != nil {
+			if err := checkManagedProcess(pol, mp); err != nil {
+				blocked = append(blocked, validation.ValidationResult{
+					ValidatorID: "policy",
+					Success:     false,
+					Message:     fmt.Sprintf("managed process %q blocked by policy", mp.Command),
+					Error:       err.Error(),
+				})
+				continue
+			}
+		}
+		valDef := validation.FromPlan(vdef)
+		if err := runner.AddFromDefinitions([]validation.ValidationDefinition{valDef}); err != nil {
+			output.Error("Invalid validation: %v", err)
+			continue
+		}
+	}
+
+	// Run validations
+	var guardSnapshot *guard.Snapshot
+	if pathGuard != nil {
+		guardSnapshot = pathGuard.Snapshot()
+	}
+	result := runner.Run(ctx)
+	result.FeatureID = p.ID
+	result.FeatureName = p.Description
+	if len(blocked) > 0 {
+		result.Results = append(result.Results, blocked...)
+		result.Success = false
+		result.FailedCount += len(blocked)
+		result.TotalCount += len(blocked)
+	}
+
+	// Validators must not modify files outside the repository either
+	if pathGuard != nil {
+		if violations := pathGuard.Check(guardSnapshot); len(violations) > 0 {
+			revertErr := pathGuard.Revert(guardSnapshot, violations)
+			reportGuardViolations(output, cfg, fmt.Sprintf("Validations for feature #%d", p.ID), violations, revertErr)
+			result.Results = append(result.Results, validation.ValidationResult{
+				ValidatorID: "path_guard",
+				Success:     false,
+				Message:     "validations modified files outside the repository",
+				Error:       guard.FormatViolations(violations),
+			})
+			result.Success = false
+			result.FailedCount++
+			result.TotalCount++
+		}
+	}
+	return result
+}
+
+// gateCheck is the outcome of a -ci-gate check
+type gateCheck struct {
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Skipped  bool     `json:"skipped,omitempty"` // The check could not be made (e.g., no run recorded yet)
+	Detail   string   `json:"detail,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// runCIGate checks that the plan did not regress: the plan file has no
+// schema errors, the validations of the tested features pass, and no more
+// features are untested than at the end of the last recorded run. It returns
+// an error if a check fails, so that Ralph can gate a build.
+func runCIGate(cfg *config.Config) error {
+	output := ui.New(ui.OutputConfig{
+		NoColor:    cfg.NoColor,
+		Quiet:      cfg.Quiet || cfg.JSONOutput,
+		JSONOutput: cfg.JSONOutput,
+		LogLevel:   ui.ParseLogLevel(cfg.LogLevel),
+	})
+
+	checks := []gateCheck{checkPlanSchema(cfg)}
+	if checks[0].Passed {
+		plans, err := plan.ReadFile(cfg.PlanFile)
+		if err != nil {
+			return err
+		}
+		validations, err := checkTestedValidations(cfg, output, plans)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, validations, checkUntestedRegression(cfg, plans))
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if !c.Passed {
+			failed++
+		}
+	}
+
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(struct {
+			Passed bool        `json:"passed"`
+			Checks []gateCheck `json:"checks"`
+		}{failed == 0, checks}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode CI gate results: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("=== CI gate: %s ===\n", cfg.PlanFile)
+		for _, c := range checks {
+			status := "PASS"
+			switch {
+			case c.Skipped:
+				status = "SKIP"
+			case !c.Passed:
+				status = "FAIL"
+			}
+			if c.Detail != "" {
+				fmt.Printf("[%s] %s: %s\n", status, c.Name, c.Detail)
+			} else {
+				fmt.Printf("[%s] %s\n", status, c.Name)
+			}
+			for _, p := range c.Problems {
+				fmt.Printf("       - %s\n", p)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("CI gate failed: %d of %d check(s) failed", failed, len(checks))
+	}
+	return nil
+}
+
+// checkPlanSchema checks the plan file for schema errors
+func checkPlanSchema(cfg *config.Config) gateCheck {
+	c := gateCheck{Name: "plan schema"}
+	problems, err := plan.CheckSchema(cfg.PlanFile)
+	if err != nil {
+		c.Problems = []string{err.Error()}
+		return c
+	}
+	c.Problems = problems
+	c.Passed = len(problems) == 0
+	if !c.Passed {
+		c.Detail = fmt.Sprintf("%d error(s)", len(problems))
+	}
+	return c
+}
+
+// checkTestedValidations runs the validations of the tested features
+func checkTestedValidations(cfg *config.Config, output *ui.UI, plans []plan.Plan) (gateCheck, error) {
+	c := gateCheck{Name: "validations of tested features"}
+	pol, err := loadPolicy(cfg)
+	if err != nil {
+		return c, err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	pathGuard := newPathGuard(cfg, output)
+
+	features, total := 0, 0
+	for _, p := range plans {
+		if !p.Tested || len(p.Validations) == 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			return c, fmt.Errorf("validation interrupted")
+		}
+		result := validateFeature(ctx, cfg, output, pol, pathGuard, p)
+		features++
+		total += result.TotalCount
+		for _, vr := range result.Results {
+			if !vr.Success {
+				c.Problems = append(c.Problems, fmt.Sprintf("feature #%d (%s): %s", p.ID, p.Description, vr.Message))
+			}
+		}
+	}
+
+	c.Passed = len(c.Problems) == 0
+	switch {
+	case features == 0:
+		c.Skipped = true
+		c.Detail = "no tested features with validations"
+	case c.Passed:
+		c.Detail = fmt.Sprintf("%d validation(s) of %d feature(s) passed", total, features)
+	default:
+		c.Detail = fmt.Sprintf("%d of %d validation(s) failed", len(c.Problems), total)
+	}
+	return c, nil
+}
+
+// checkUntestedRegression compares the number of untested features with the
+// end of the last recorded run of the plan
+func checkUntestedRegression(cfg *config.Config, plans []plan.Plan) gateCheck {
+	c := gateCheck{Name: "untested features", Passed: true}
+	s := plan.Summarize(plans)
+	untested := s.Total - s.Tested
+
+	runs, err := history.NewStore(cfg.HistoryDir).List()
+	if err != nil {
+		c.Skipped = true
+		c.Detail = fmt.Sprintf("%d untested; run history unavailable: %v", untested, err)
+		return c
+	}
+	var last *history.Run
+	for _, r := range runs {
+		if r.FeaturesTotal > 0 && filepath.Clean(r.PlanFile) == filepath.Clean(cfg.PlanFile) {
+			last = r
+		}
+	}
+	if last == nil {
+		c.Skipped = true
+		c.Detail = fmt.Sprintf("%d untested; no run of %s recorded yet", untested, cfg.PlanFile)
+		return c
+	}
+
+	before := last.FeaturesTotal - last.FeaturesTested
+	c.Detail = fmt.Sprintf("%d untested, %d at the end of run %s", untested, before, last.ID)
+	if untested > before {
+		c.Passed = false
+		c.Problems = []string{fmt.Sprintf("%d more feature(s) untested than at the end of run %s", untested-before, last.ID)}
+	}
+	return c
+}
+
 // handleValidationCommands processes validation-related CLI commands
 func handleValidationCommands(cfg *config.Config) error {
 	// Create UI instance
@@ -1981,14 +7911,27 @@ func handleValidationCommands(cfg *config.Config) error {
 	output.Print("")
 
 	// Track overall results
+	validationStart := time.Now()
 	totalValidations := 0
 	totalPassed := 0
 	totalFailed := 0
 	var allResults []validation.ValidationRunResult
-
-	ctx := context.Background()
+	var reports []string // Failure reports to pass on to the next iteration
+
+	// Ctrl-C cancels the running validation and kills any processes it
+	// started, instead of leaving servers behind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	pathGuard := newPathGuard(cfg, output)
+	pol, err := loadPolicy(cfg)
+	if err != nil {
+		return err
+	}
 
 	for _, p := range plansToValidate {
+		if ctx.Err() != nil {
+			break
+		}
 		if len(p.Validations) == 0 {
 			if cfg.ValidateFeature > 0 {
 				output.Warn("Feature #%d has no validations defined", p.ID)
@@ -1997,40 +7940,7 @@ func handleValidationCommands(cfg *config.Config) error {
 		}
 
 		output.SubHeader("Feature #%d: %s", p.ID, p.Description)
-
-		// Create validation runner
-		runner := validation.NewValidationRunner()
-
-		// Convert plan.ValidationDefinition to validation.ValidationDefinition
-		for _, vdef := range p.Validations {
-			valDef := validation.ValidationDefinition{
-				Type:           validation.ValidationType(vdef.Type),
-				URL:            vdef.URL,
-				Method:         vdef.Method,
-				Body:           vdef.Body,
-				Headers:        vdef.Headers,
-				ExpectedStatus: vdef.ExpectedStatus,
-				ExpectedBody:   vdef.ExpectedBody,
-				Command:        vdef.Command,
-				Args:           vdef.Args,
-				Path:           vdef.Path,
-				Pattern:        vdef.Pattern,
-				Input:          vdef.Input,
-				Timeout:        vdef.Timeout,
-				Retries:        vdef.Retries,
-				Description:    vdef.Description,
-				Options:        vdef.Options,
-			}
-			if err := runner.AddFromDefinitions([]validation.ValidationDefinition{valDef}); err != nil {
-				output.Error("Invalid validation: %v", err)
-				continue
-			}
-		}
-
-		// Run validations
-		result := runner.Run(ctx)
-		result.FeatureID = p.ID
-		result.FeatureName = p.Description
+		result := validateFeature(ctx, cfg, output, pol, pathGuard, p)
 
 		allResults = append(allResults, result)
 		totalValidations += result.TotalCount
@@ -2046,10 +7956,50 @@ func handleValidationCommands(cfg *config.Config) error {
 				if vr.Error != "" && cfg.Verbose {
 					output.Debug("    Error: %s", vr.Error)
 				}
+				if vr.Report != "" {
+					output.Print("    %s", strings.ReplaceAll(vr.Report, "\n", "\n    "))
+					reports = append(reports, fmt.Sprintf("Feature #%d: %s", p.ID, vr.Report))
+				}
 			}
 		}
 
-		output.Print("")
+		output.Print("")
+	}
+
+	// Failure reports (e.g., security findings) reach the agent as nudges,
+	// which are injected into the next iteration's prompt
+	if len(reports) > 0 {
+		if err := addReportNudges(cfg, reports); err != nil {
+			output.Warn("Failed to save validation reports for the next iteration: %v", err)
+		} else {
+			output.Info("Validation report(s) will be passed on to the next iteration via %s", cfg.NudgeFile)
+		}
+	}
+
+	// Reports are written for interrupted runs too, as evidence of what ran
+	report := validation.NewReport(allResults, time.Since(validationStart))
+	report.Interrupted = ctx.Err() != nil
+	if cfg.ReportDir != "" {
+		paths, err := report.Write(cfg.ReportDir, cfg.ReportHTML)
+		if err != nil {
+			output.Warn("Failed to write validation report: %v", err)
+		}
+		for _, path := range paths {
+			output.Info("Validation report: %s", path)
+		}
+	}
+	if cfg.JUnitOutput != "" {
+		if err := report.WriteJUnit(cfg.JUnitOutput, "ralph validations"); err != nil {
+			output.Warn("Failed to write JUnit report: %v", err)
+		} else {
+			output.Info("JUnit report: %s", cfg.JUnitOutput)
+		}
+	}
+
+	if ctx.Err() != nil {
+		output.Warn("Validation interrupted - started processes were stopped")
+		appendProgress(cfg.ProgressFile, "VALIDATION: interrupted")
+		return fmt.Errorf("validation interrupted")
 	}
 
 	// Print summary
@@ -2090,6 +8040,75 @@ func handleValidationCommands(cfg *config.Config) error {
 	return nil
 }
 
+// checkManagedProcess checks the commands of a validation's managed process
+// against the policy
+func checkManagedProcess(pol *policy.Policy, mp *plan.ManagedProcess) error {
+	if err := pol.CheckCommand(mp.Command); err != nil {
+		return err
+	}
+	if mp.TeardownCommand != "" {
+		return pol.CheckCommand(mp.TeardownCommand)
+	}
+	return nil
+}
+
+// addReportNudges saves validation failure reports as focus nudges, so the
+// next iteration's prompt asks the agent to fix what they describe
+func addReportNudges(cfg *config.Config, reports []string) error {
+	store := nudge.NewStore(cfg.NudgeFile)
+	if err := store.Load(); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if _, err := store.Add(nudge.NudgeTypeFocus, "Fix these validation failures first. "+report, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newMilestoneManager creates a milestone manager for plans. Milestone
+// definitions are loaded from the plan's milestones file (see
+// loadMilestoneManager), and schedule health is projected from the completion
+// velocity in the run history. The returned manager is usable even if
+// loading the milestones file fails.
+func newMilestoneManager(cfg *config.Config, plans []plan.Plan) (*milestone.Manager, error) {
+	mgr, err := loadMilestoneManager(cfg, plans)
+	if runs, listErr := history.NewStore(cfg.HistoryDir).List(); listErr == nil {
+		mgr.SetVelocity(history.Velocity(runs, time.Now()))
+	}
+	return mgr, err
+}
+
+// loadMilestoneManager creates a milestone manager for plans with the
+// milestone definitions of the plan's milestones file (plan-milestones.json
+// for plan.json), if it exists
+func loadMilestoneManager(cfg *config.Config, plans []plan.Plan) (*milestone.Manager, error) {
+	mgr := milestone.NewManager(plans)
+	milestonesFile := strings.TrimSuffix(cfg.PlanFile, ".json") + "-milestones.json"
+	if _, err := os.Stat(milestonesFile); err == nil {
+		if err := mgr.LoadMilestones(milestonesFile); err != nil {
+			return mgr, err
+		}
+	}
+	return mgr, nil
+}
+
+// milestoneBlockedFeatures returns the untested features whose milestone
+// depends on milestones that are not complete yet, mapped to those
+// milestones. The scheduler does not start them and the agent is told not to.
+func milestoneBlockedFeatures(cfg *config.Config) map[int][]string {
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return nil
+	}
+	mgr, err := loadMilestoneManager(cfg, plans)
+	if err != nil {
+		return nil
+	}
+	return mgr.BlockedFeatures()
+}
+
 // handleMilestoneCommands processes milestone-related CLI commands
 func handleMilestoneCommands(cfg *config.Config) error {
 	// Load plans
@@ -2099,15 +8118,26 @@ func handleMilestoneCommands(cfg *config.Config) error {
 	}
 
 	// Create milestone manager
-	mgr := milestone.NewManager(plans)
+	mgr, err := newMilestoneManager(cfg, plans)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load milestones file: %v\n", err)
+	}
 
-	// Try to load milestones from a separate file if it exists
-	// Check for milestones.json in the same directory as plan file
-	milestonesFile := strings.TrimSuffix(cfg.PlanFile, ".json") + "-milestones.json"
-	if _, err := os.Stat(milestonesFile); err == nil {
-		if err := mgr.LoadMilestones(milestonesFile); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to load milestones file: %v\n", err)
+	if cfg.JSONOutput {
+		var report any = mgr.Report()
+		if cfg.ShowMilestone != "" {
+			progress := mgr.CalculateProgress(cfg.ShowMilestone)
+			if progress.TotalFeatures == 0 {
+				return fmt.Errorf("milestone '%s' not found or has no features", cfg.ShowMilestone)
+			}
+			report = progress.Report()
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode milestones: %w", err)
 		}
+		fmt.Println(string(data))
+		return nil
 	}
 
 	// Handle -milestones flag (list all milestones with progress)
@@ -2129,6 +8159,9 @@ func handleMilestoneCommands(cfg *config.Config) error {
 		}
 
 		fmt.Println(mgr.Summary())
+		if deps := mgr.FormatDependencies(); deps != "" {
+			fmt.Print(deps)
+		}
 
 		// Check for completed milestones and show celebration
 		completed := mgr.GetCompletedMilestones()
@@ -2163,8 +8196,15 @@ func handleMilestoneCommands(cfg *config.Config) error {
 			fmt.Printf("Success Criteria: %s\n", progress.Milestone.Criteria)
 		}
 		fmt.Printf("Progress: %s\n", milestone.FormatProgressBar(progress, 30))
-		fmt.Printf("Status: %s (%d/%d features complete)\n\n",
+		fmt.Printf("Status: %s (%d/%d features complete)\n",
 			progress.Status, progress.CompletedFeatures, progress.TotalFeatures)
+		if progress.Schedule != nil {
+			fmt.Printf("Schedule: %s\n", progress.Schedule)
+		}
+		if len(progress.Blocked) > 0 {
+			fmt.Printf("Blocked by: %s (its features are not started until these are complete)\n", strings.Join(progress.Blocked, ", "))
+		}
+		fmt.Println()
 
 		fmt.Println("Features:")
 		for _, f := range progress.Features {
@@ -2197,6 +8237,12 @@ func handleGoalCommands(cfg *config.Config) error {
 	}
 	output := ui.New(uiCfg)
 
+	// Decomposing goals generates plan items with the agent
+	decomposing := cfg.DecomposeGoal != "" || cfg.DecomposeAll || cfg.RedecomposeGoal != ""
+	if decomposing && cfg.Transcript && startTranscript(cfg, history.RunID(time.Now())) != nil {
+		defer agent.SetTranscript(nil)
+	}
+
 	// Load existing plans (needed for progress tracking and decomposition)
 	var plans []plan.Plan
 	if _, err := os.Stat(cfg.PlanFile); err == nil {
@@ -2212,7 +8258,20 @@ func handleGoalCommands(cfg *config.Config) error {
 		output.Warn("Failed to load goals: %v", err)
 	}
 
+	// Handle goal editing commands
+	if cfg.RemoveGoal != "" || cfg.EditGoal != "" || cfg.SetGoalPriority != "" || cfg.CompleteGoal != "" {
+		return editGoals(cfg, output, goalMgr)
+	}
+
 	// Handle -goals flag (unified view of all goals with progress)
+	if cfg.ShowGoals && cfg.JSONOutput {
+		data, err := json.MarshalIndent(goalMgr.Report(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode goals: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
 	if cfg.ShowGoals {
 		if !goalMgr.HasGoals() {
 			fmt.Println("No goals defined.")
@@ -2313,7 +8372,7 @@ func handleGoalCommands(cfg *config.Config) error {
 		output.Success("Goal added with ID: %s", goal.ID)
 
 		// Decompose the goal if we have an agent
-		if _, err := exec.LookPath(cfg.AgentCmd); err == nil {
+		if err := checkAgentAvailable(cfg); err == nil {
 			output.Print("")
 			output.SubHeader("Decomposing Goal into Plan Items")
 
@@ -2347,6 +8406,22 @@ func handleGoalCommands(cfg *config.Config) error {
 		return nil
 	}
 
+	// Handle -redecompose-goal flag
+	if cfg.RedecomposeGoal != "" {
+		goal := goalMgr.GetGoalByID(cfg.RedecomposeGoal)
+		if goal == nil {
+			return fmt.Errorf("goal with ID %q not found", cfg.RedecomposeGoal)
+		}
+
+		output.Header("Re-decomposing Goal")
+		output.Info("Goal: %s", goal.Description)
+		if cfg.Feedback != "" {
+			output.Info("Feedback: %s", cfg.Feedback)
+		}
+
+		return redecomposeGoal(cfg, output, goalMgr, goal)
+	}
+
 	// Handle -decompose-all flag
 	if cfg.DecomposeAll {
 		pendingGoals := goalMgr.GetPendingGoals()
@@ -2374,6 +8449,101 @@ func handleGoalCommands(cfg *config.Config) error {
 	return nil
 }
 
+// editGoals handles -remove-goal, -edit-goal, -set-goal-priority and
+// -complete-goal, saving the goals file afterwards
+func editGoals(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager) error {
+	switch {
+	case cfg.RemoveGoal != "":
+		goal := goalMgr.GetGoalByID(cfg.RemoveGoal)
+		if goal == nil {
+			return fmt.Errorf("goal %q not found", cfg.RemoveGoal)
+		}
+		items := len(goal.GeneratedPlanIDs)
+		goalMgr.RemoveGoal(cfg.RemoveGoal)
+		if err := goalMgr.SaveGoals(); err != nil {
+			return err
+		}
+		output.Success("Goal removed: %s", cfg.RemoveGoal)
+		if items > 0 {
+			output.Info("Its %d plan item(s) are kept in %s", items, cfg.PlanFile)
+		}
+
+	case cfg.EditGoal != "":
+		data, err := goalMgr.GoalJSON(cfg.EditGoal)
+		if err != nil {
+			return err
+		}
+		edited, err := editInEditor(data, "goal-*.json")
+		if err != nil {
+			return err
+		}
+		if err := goalMgr.ReplaceGoalJSON(cfg.EditGoal, edited); err != nil {
+			return err
+		}
+		if err := goalMgr.SaveGoals(); err != nil {
+			return err
+		}
+		output.Success("Goal updated: %s", cfg.EditGoal)
+
+	case cfg.SetGoalPriority != "":
+		id, value, ok := strings.Cut(cfg.SetGoalPriority, ":")
+		priority, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil {
+			return fmt.Errorf("invalid -set-goal-priority %q: expected id:priority (e.g., auth:10)", cfg.SetGoalPriority)
+		}
+		if err := goalMgr.SetPriority(strings.TrimSpace(id), priority); err != nil {
+			return err
+		}
+		if err := goalMgr.SaveGoals(); err != nil {
+			return err
+		}
+		output.Success("Goal %s priority set to %d", strings.TrimSpace(id), priority)
+
+	case cfg.CompleteGoal != "":
+		if err := goalMgr.MarkGoalComplete(cfg.CompleteGoal); err != nil {
+			return err
+		}
+		if err := goalMgr.SaveGoals(); err != nil {
+			return err
+		}
+		output.Success("Goal marked complete: %s", cfg.CompleteGoal)
+	}
+	return nil
+}
+
+// editInEditor lets the user edit data in $VISUAL or $EDITOR (default: vi)
+// and returns the result. pattern names the temporary file (see os.CreateTemp).
+func editInEditor(data []byte, pattern string) ([]byte, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	f.Close()
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return os.ReadFile(f.Name())
+}
+
 // printGoalProgress prints a single goal with its progress information
 func printGoalProgress(output *ui.UI, p *goals.GoalProgress) {
 	// Status symbol
@@ -2397,6 +8567,102 @@ func printGoalProgress(output *ui.UI, p *goals.GoalProgress) {
 	}
 }
 
+// redecomposeGoal regenerates the plan items of a goal with the agent, taking
+// feedback on the previous items into account. Tested items are kept. The
+// plan is backed up first, so the untested items that are replaced can be
+// restored with -restore-version.
+func redecomposeGoal(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager, goal *goals.Goal) error {
+	existingPlans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		return err
+	}
+
+	// The agent writes the new items to a scratch file next to the plan
+	outputPath, err := filepath.Abs(strings.TrimSuffix(cfg.PlanFile, filepath.Ext(cfg.PlanFile)) + ".redecompose.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(outputPath)
+
+	redecomposePrompt, err := prompt.RenderGoalRedecompositionPrompt(cfg, goal, existingPlans, cfg.Feedback, outputPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Verbose {
+		output.Debug("Prompt: %s", redecomposePrompt)
+	}
+
+	var spinner *ui.Spinner
+	if output.IsTTY() && !cfg.Quiet && !cfg.JSONOutput {
+		spinner = output.NewSpinner("Re-decomposing goal with AI agent...")
+		spinner.Start()
+	}
+	result, err := agent.Execute(cfg, redecomposePrompt)
+	if spinner != nil {
+		spinner.Stop()
+	}
+	if err != nil {
+		return fmt.Errorf("agent execution failed: %w", err)
+	}
+
+	// Prefer the items the agent wrote; fall back to its output
+	generated, readErr := plan.ReadFile(outputPath)
+	if readErr != nil || len(generated) == 0 {
+		decompResult, parseErr := goals.ParseDecompositionResult(result, goal)
+		if parseErr != nil || len(decompResult.GeneratedPlans) == 0 {
+			output.Debug("Raw agent output: %s", result)
+			return fmt.Errorf("re-decomposition produced no plan items")
+		}
+		generated = decompResult.GeneratedPlans
+	}
+	unchecked := goals.AttachCriteriaValidations(goal.SuccessCriteria, generated)
+
+	// Archive the current plan before any item is replaced
+	versioner := replan.NewPlanVersioner(cfg.PlanFile)
+	if err := versioner.SetOptions(planBackupOptions(cfg)); err != nil {
+		return err
+	}
+	backupPath, err := versioner.CreateBackup(replan.TriggerManual)
+	if err != nil {
+		return fmt.Errorf("failed to back up plan: %w", err)
+	}
+
+	r := goals.Redecompose(goal, existingPlans, generated)
+	if err := plan.WriteFile(cfg.PlanFile, r.Plans); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	if err := goalMgr.UpdateGoal(*goal); err != nil {
+		return err
+	}
+	if err := goalMgr.SaveGoals(); err != nil {
+		return fmt.Errorf("failed to save goals: %w", err)
+	}
+	appendProgress(cfg.ProgressFile, fmt.Sprintf("REDECOMPOSE: goal %s kept %d tested item(s), replaced %d and added %d; previous plan archived in %s",
+		goal.ID, len(r.Kept), len(r.Replaced), len(r.Added), backupPath))
+
+	output.Success("Kept %d tested item(s), replaced %d untested item(s), added %d", len(r.Kept), len(r.Replaced), len(r.Added))
+	reportUncheckedCriteria(output, unchecked)
+	output.Print("")
+	output.Print("%s", strings.TrimRight(r.Diff.Summary(), "\n"))
+	if len(r.Replaced) > 0 {
+		output.Print("")
+		output.Info("Replaced items are archived in %s (see -list-versions and -restore-version)", backupPath)
+	}
+	return nil
+}
+
+// reportUncheckedCriteria warns about success criteria that no validation of
+// the generated plan items checks
+func reportUncheckedCriteria(output *ui.UI, unchecked []string) {
+	if len(unchecked) == 0 {
+		return
+	}
+	output.Warn("%d success criteria have no validation and are not checked automatically:", len(unchecked))
+	for _, c := range unchecked {
+		output.Print("  - %s", c)
+	}
+}
+
 // decomposeGoal decomposes a single goal into plan items using the AI agent
 func decomposeGoal(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager, goal *goals.Goal) error {
 	// Load current plans
@@ -2412,7 +8678,10 @@ func decomposeGoal(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager, go
 	}
 
 	// Build the decomposition prompt
-	decomposePrompt := goals.BuildGoalDecompositionPrompt(goal, existingPlans, outputPath)
+	decomposePrompt, err := prompt.RenderGoalDecompositionPrompt(cfg, goal, existingPlans, outputPath)
+	if err != nil {
+		return err
+	}
 
 	if cfg.Verbose {
 		output.Debug("Prompt: %s", decomposePrompt)
@@ -2451,6 +8720,13 @@ func decomposeGoal(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager, go
 				// Plans were written directly
 				newCount := len(updatedPlans) - len(existingPlans)
 				output.Success("Generated %d plan items (written directly by agent)", newCount)
+				if len(goal.SuccessCriteria) > 0 {
+					unchecked := goals.AttachCriteriaValidations(goal.SuccessCriteria, updatedPlans[len(existingPlans):])
+					if err := plan.WriteFile(outputPath, updatedPlans); err != nil {
+						return fmt.Errorf("failed to write plan file: %w", err)
+					}
+					reportUncheckedCriteria(output, unchecked)
+				}
 				
 				// Link new plan IDs to the goal
 				for i := len(existingPlans); i < len(updatedPlans); i++ {
@@ -2470,6 +8746,9 @@ func decomposeGoal(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager, go
 		return fmt.Errorf("decomposition produced no plan items: %s", decompResult.Message)
 	}
 
+	// Make the success criteria checkable on the new plan items
+	unchecked := goals.AttachCriteriaValidations(goal.SuccessCriteria, decompResult.GeneratedPlans)
+
 	// Merge with existing plans
 	mergedPlans := goals.MergePlans(existingPlans, decompResult.GeneratedPlans)
 
@@ -2489,11 +8768,16 @@ func decomposeGoal(cfg *config.Config, output *ui.UI, goalMgr *goals.Manager, go
 	goalMgr.SaveGoals()
 
 	output.Success("Generated %d plan items", len(decompResult.GeneratedPlans))
+	reportUncheckedCriteria(output, unchecked)
 	
 	// Print generated plan items
 	output.Print("")
 	output.Print("Generated plan items:")
 	for _, p := range decompResult.GeneratedPlans {
+		if len(p.Validations) > 0 {
+			output.Print("  %d. [%s] %s (%d validation(s))", p.ID, p.Category, p.Description, len(p.Validations))
+			continue
+		}
 		output.Print("  %d. [%s] %s", p.ID, p.Category, p.Description)
 	}
 
@@ -2626,13 +8910,26 @@ func handleRefinePlanCommand(cfg *config.Config) error {
 	return nil
 }
 
+// loadAgentsConfig loads the multi-agent configuration from the agents
+// section of the config file, or else from the agents file. It returns where
+// the configuration comes from.
+func loadAgentsConfig(cfg *config.Config) (*multiagent.MultiAgentConfig, string, error) {
+	if len(cfg.Agents) > 0 {
+		agentConfig, err := multiagent.ParseAgentsSection(cfg.Agents, cfg.ParallelAgents)
+		return agentConfig, "config file", err
+	}
+	agentConfig, err := multiagent.LoadMultiAgentConfig(cfg.AgentsFile)
+	return agentConfig, cfg.AgentsFile, err
+}
+
 // handleListAgents displays configured agents
 func handleListAgents(cfg *config.Config) error {
-	// Check if agents file exists
-	if _, err := os.Stat(cfg.AgentsFile); os.IsNotExist(err) {
+	// Check if agents are configured
+	if _, err := os.Stat(cfg.AgentsFile); os.IsNotExist(err) && len(cfg.Agents) == 0 {
 		fmt.Println("No agents configuration found.")
 		fmt.Println()
-		fmt.Println("To configure multi-agent collaboration, create agents.json:")
+		fmt.Println("To configure multi-agent collaboration, add an agents section to .ralph.yaml")
+		fmt.Println("or create agents.json:")
 		fmt.Println()
 		fmt.Println(`{
   "agents": [
@@ -2670,14 +8967,29 @@ func handleListAgents(cfg *config.Config) error {
 	}
 
 	// Load agents configuration
-	agentConfig, err := multiagent.LoadMultiAgentConfig(cfg.AgentsFile)
+	agentConfig, source, err := loadAgentsConfig(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to load agents config: %w", err)
 	}
 
-	fmt.Printf("=== Multi-Agent Configuration (from %s) ===\n", cfg.AgentsFile)
+	fmt.Printf("=== Multi-Agent Configuration (from %s) ===\n", source)
 	fmt.Printf("Max parallel agents: %d\n", agentConfig.MaxParallel)
 	fmt.Printf("Conflict resolution: %s\n", agentConfig.ConflictResolution)
+	for _, role := range multiagent.ValidRoles {
+		if path := agentConfig.PromptTemplates[role]; path != "" {
+			fmt.Printf("Prompt template (%s): %s\n", role, path)
+		}
+	}
+	decisionFile := agentConfig.DecisionFile
+	if decisionFile == "" {
+		decisionFile = multiagent.DefaultDecisionFile
+	}
+	if pending, err := multiagent.NewFileDecider(decisionFile).Load(); err == nil && len(pending) > 0 {
+		fmt.Printf("Pending decisions: %d (edit %s)\n", len(pending), decisionFile)
+		for _, p := range pending {
+			fmt.Printf("  %s [%s]\n", p.Description, strings.Join(p.Options, "/"))
+		}
+	}
 	fmt.Println()
 
 	fmt.Println("Configured Agents:")
@@ -2711,8 +9023,182 @@ func handleListAgents(cfg *config.Config) error {
 	return nil
 }
 
+// scanBaseline scans the codebase in the current directory and saves the baseline
+func scanBaseline(cfg *config.Config) (*baseline.Baseline, error) {
+	baselineData, err := baseline.NewScanner(".").Scan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan codebase: %w", err)
+	}
+	if err := baselineData.Save(cfg.BaselineFile); err != nil {
+		return nil, fmt.Errorf("failed to save baseline: %w", err)
+	}
+	return baselineData, nil
+}
+
+// contextPolicy returns when the baseline and memories count as stale
+func contextPolicy(cfg *config.Config) staleness.Policy {
+	return staleness.Policy{
+		MaxAge:     time.Duration(cfg.ContextMaxAge) * 24 * time.Hour,
+		MaxChanges: cfg.ContextMaxChanges,
+	}
+}
+
+// contextIgnore returns Ralph's state files, whose changes do not make the
+// baseline or memories stale
+func contextIgnore(cfg *config.Config) []string {
+	return []string{cfg.PlanFile, cfg.ProgressFile, cfg.MemoryFile, cfg.BaselineFile, cfg.NudgeFile, cfg.GoalsFile}
+}
+
+// checkContextStaleness warns when the baseline or memories injected into
+// prompts are stale, re-scanning a stale baseline with -refresh-stale-context.
+// It returns the baseline to use.
+func checkContextStaleness(cfg *config.Config, output *ui.UI, baselineData *baseline.Baseline, memStore *memory.Store) *baseline.Baseline {
+	policy := contextPolicy(cfg)
+	if !policy.Enabled() {
+		return baselineData
+	}
+
+	if baselineData != nil {
+		report := staleness.Check(cfg.BaselineFile, baselineData.GeneratedAt, policy, contextIgnore(cfg)...)
+		switch {
+		case !report.Stale():
+		case cfg.RefreshStaleContext:
+			refreshed, err := scanBaseline(cfg)
+			if err != nil {
+				output.Warn("%s; refreshing it failed: %v", report, err)
+				break
+			}
+			baselineData = refreshed
+			output.Info("%s; re-scanned it (%d files analyzed)", report, baselineData.TotalFiles)
+		default:
+			output.Warn("%s; refresh it with: %s -baseline", report, os.Args[0])
+		}
+	}
+
+	if memStore.Count() > 0 {
+		name := fmt.Sprintf("Newest memory in %s", cfg.MemoryFile)
+		if report := staleness.Check(name, memStore.LastChanged(), policy, contextIgnore(cfg)...); report.Stale() {
+			output.Warn("%s; review it with: %s -show-memory", report, os.Args[0])
+		}
+	}
+	return baselineData
+}
+
+// baselineDriftReport is the result of -baseline-drift
+type baselineDriftReport struct {
+	BaselineFile string         `json:"baseline_file"`
+	GeneratedAt  time.Time      `json:"generated_at"`
+	ByRalph      []string       `json:"by_ralph"` // Drifted files changed by Ralph's iterations
+	Outside      baseline.Drift `json:"outside"`  // Drifted files changed outside them
+	Refreshed    bool           `json:"refreshed,omitempty"`
+}
+
+// driftIgnore returns the paths whose changes are not baseline drift: Ralph's
+// state files and directories
+func driftIgnore(cfg *config.Config) []string {
+	ignore := append(contextIgnore(cfg), ".ralph/")
+	for _, dir := range []string{cfg.HistoryDir, cfg.DiffDir, cfg.TranscriptDir, cfg.ReportDir} {
+		if dir != "" {
+			ignore = append(ignore, filepath.ToSlash(filepath.Clean(dir))+"/")
+		}
+	}
+	return ignore
+}
+
+// runBaselineDrift compares the saved baseline with the current tree and
+// reports the files added, removed or changed since, separating those
+// changed by Ralph's iterations (per the recorded iteration diffs) from
+// those changed outside them. With -refresh-stale-context, the baseline is
+// replaced by the fresh scan when files changed outside the iterations.
+func runBaselineDrift(cfg *config.Config) error {
+	saved, err := baseline.Load(cfg.BaselineFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no baseline found in %s; create one with: %s -baseline", cfg.BaselineFile, os.Args[0])
+		}
+		return fmt.Errorf("failed to load baseline: %w", err)
+	}
+	current, err := baseline.NewScanner(".").Scan()
+	if err != nil {
+		return fmt.Errorf("failed to scan codebase: %w", err)
+	}
+	ralphFiles, err := diffs.NewStore(cfg.DiffDir).ChangedSince(saved.GeneratedAt)
+	if err != nil {
+		return err
+	}
+
+	drift := saved.Drift(current).Exclude(driftIgnore(cfg))
+	report := baselineDriftReport{
+		BaselineFile: cfg.BaselineFile,
+		GeneratedAt:  saved.GeneratedAt,
+		ByRalph:      []string{},
+		Outside:      drift.Exclude(ralphFiles),
+	}
+	drifted := make(map[string]bool, drift.Total())
+	for _, files := range [][]string{drift.Added, drift.Removed, drift.Changed} {
+		for _, f := range files {
+			drifted[f] = true
+		}
+	}
+	for _, f := range ralphFiles {
+		if drifted[f] {
+			report.ByRalph = append(report.ByRalph, f)
+		}
+	}
+
+	if cfg.RefreshStaleContext && report.Outside.Total() > 0 {
+		if err := current.Save(cfg.BaselineFile); err != nil {
+			return fmt.Errorf("failed to save baseline: %w", err)
+		}
+		report.Refreshed = true
+	}
+
+	if cfg.JSONOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println("=== Baseline Drift ===")
+	fmt.Println()
+	fmt.Printf("Baseline: %s (generated %s, %s old)\n", cfg.BaselineFile,
+		saved.GeneratedAt.Local().Format("2006-01-02 15:04"), staleness.FormatAge(time.Since(saved.GeneratedAt)))
+	fmt.Printf("Files changed since: %d (%d by Ralph's iterations, %d outside them)\n",
+		drift.Total(), len(report.ByRalph), report.Outside.Total())
+	if report.Outside.Total() == 0 {
+		fmt.Println()
+		fmt.Println("No files changed outside Ralph's iterations.")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("Changed outside Ralph's iterations:")
+	for _, group := range []struct {
+		mark  string
+		files []string
+	}{{"+", report.Outside.Added}, {"-", report.Outside.Removed}, {"~", report.Outside.Changed}} {
+		for _, f := range group.files {
+			fmt.Printf("  %s %s\n", group.mark, f)
+		}
+	}
+	fmt.Println()
+	if report.Refreshed {
+		fmt.Printf("Re-scanned the baseline: %s (%d files analyzed)\n", cfg.BaselineFile, current.TotalFiles)
+	} else {
+		fmt.Printf("The baseline context may be stale; refresh it with: %s -baseline\n", os.Args[0])
+	}
+	return nil
+}
+
 // handleBaselineCommands processes baseline-related CLI commands
 func handleBaselineCommands(cfg *config.Config) error {
+	if cfg.BaselineDrift {
+		return runBaselineDrift(cfg)
+	}
+
 	// Handle show-baseline command
 	if cfg.ShowBaseline {
 		baselineData, err := baseline.Load(cfg.BaselineFile)
@@ -2734,6 +9220,10 @@ func handleBaselineCommands(cfg *config.Config) error {
 		}
 
 		fmt.Print(baselineData.Summary())
+		if report := staleness.Check(cfg.BaselineFile, baselineData.GeneratedAt, contextPolicy(cfg), contextIgnore(cfg)...); report.Stale() {
+			fmt.Printf("\nWarning: %s\n", report)
+			fmt.Printf("Refresh it with: %s -baseline\n", os.Args[0])
+		}
 		return nil
 	}
 
@@ -2743,18 +9233,9 @@ func handleBaselineCommands(cfg *config.Config) error {
 		fmt.Println()
 		fmt.Println("Scanning codebase...")
 
-		// Create scanner for current directory
-		scanner := baseline.NewScanner(".")
-
-		// Perform the scan
-		baselineData, err := scanner.Scan()
+		baselineData, err := scanBaseline(cfg)
 		if err != nil {
-			return fmt.Errorf("failed to scan codebase: %w", err)
-		}
-
-		// Save the baseline
-		if err := baselineData.Save(cfg.BaselineFile); err != nil {
-			return fmt.Errorf("failed to save baseline: %w", err)
+			return err
 		}
 
 		fmt.Printf("\nBaseline created: %s\n\n", cfg.BaselineFile)
@@ -1,17 +1,43 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/logimos/ralph/internal/agent"
+	"github.com/logimos/ralph/internal/baseline"
+	"github.com/logimos/ralph/internal/changelog"
 	"github.com/logimos/ralph/internal/config"
 	"github.com/logimos/ralph/internal/detection"
-	"github.com/logimos/ralph/internal/plan"
+	"github.com/logimos/ralph/internal/diffs"
+	"github.com/logimos/ralph/internal/environment"
+	"github.com/logimos/ralph/internal/history"
+	"github.com/logimos/ralph/internal/multiagent"
+	"github.com/logimos/ralph/internal/nudge"
+	"github.com/logimos/ralph/internal/progress"
 	"github.com/logimos/ralph/internal/prompt"
+	"github.com/logimos/ralph/internal/recovery"
+	"github.com/logimos/ralph/internal/replan"
+	"github.com/logimos/ralph/internal/scope"
+	"github.com/logimos/ralph/internal/testreport"
+	"github.com/logimos/ralph/internal/transcript"
+	"github.com/logimos/ralph/internal/ui"
+	"github.com/logimos/ralph/pkg/goals"
+	"github.com/logimos/ralph/pkg/memory"
+	"github.com/logimos/ralph/pkg/plan"
+	"github.com/logimos/ralph/pkg/validation"
 )
 
 // TestDetectBuildSystem tests build system detection based on project files
@@ -641,8 +667,8 @@ func TestConfigStructDefaults(t *testing.T) {
 	if cfg.ShowVersion {
 		t.Error("Default ShowVersion should be false")
 	}
-	if cfg.ListStatus {
-		t.Error("Default ListStatus should be false")
+	if cfg.ShowStatus {
+		t.Error("Default ShowStatus should be false")
 	}
 }
 
@@ -810,11 +836,6 @@ func TestListAllFlagBehavior(t *testing.T) {
 		t.Error("ListAll should be false by default")
 	}
 
-	// Test that ListStatus (deprecated) is also false by default
-	if cfg.ListStatus {
-		t.Error("ListStatus (deprecated) should be false by default")
-	}
-
 	// Test that when ListAll is set, it shows both tested and untested
 	cfg.ListAll = true
 	showTested := cfg.ListAll || cfg.ListTested
@@ -828,33 +849,320 @@ func TestListAllFlagBehavior(t *testing.T) {
 	}
 }
 
-// TestDeprecatedStatusFlagBehavior tests that -status behaves like -list-all
-func TestDeprecatedStatusFlagBehavior(t *testing.T) {
+// TestBuildStatusReport tests that -status gathers the state of the project
+func TestWriteChangelog(t *testing.T) {
+	t.Chdir(t.TempDir())
 	cfg := config.New()
+	plans := []plan.Plan{
+		{ID: 1, Category: "feature", Description: "Login", Milestone: "Alpha", Tested: true},
+		{ID: 2, Category: "fix", Description: "Session timeout", Milestone: "Alpha", Tested: true},
+		{ID: 3, Category: "feature", Description: "Search", Milestone: "Beta"},
+	}
+	if err := plan.WriteFile(cfg.PlanFile, plans); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendProgress(cfg.ProgressFile, "TESTED: feature #1 completed in iteration 2"); err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().Format("2006-01-02")
 
-	// Simulate deprecated -status flag being used
-	cfg.ListStatus = true
+	c, err := buildChangelog(cfg)
+	if err != nil {
+		t.Fatalf("buildChangelog() = %v", err)
+	}
+	if len(c.Sections) != 1 || c.Sections[0].Milestone != "Alpha" || !c.Sections[0].Complete || len(c.Sections[0].Entries) != 2 {
+		t.Fatalf("Sections = %+v, want the completed Alpha milestone", c.Sections)
+	}
 
-	// The deprecation handling in parseFlags() sets ListAll when ListStatus is true
-	// Here we simulate that behavior for testing
-	if cfg.ListStatus {
-		cfg.ListAll = true
+	cfg.ChangelogFile = filepath.Join("docs", "CHANGELOG.md")
+	cfg.ChangelogFormat = changelog.FormatKeepAChangelog
+	if err := writeChangelog(cfg); err != nil {
+		t.Fatalf("writeChangelog() = %v", err)
+	}
+	data, err := os.ReadFile(cfg.ChangelogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## [Alpha] - " + today, "### Added\n\n- Login (#1)", "### Fixed\n\n- Session timeout (#2)"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("changelog is missing %q:\n%s", want, data)
+		}
 	}
 
-	// Verify that ListAll is now true (mimicking the deprecation handling)
-	if !cfg.ListAll {
-		t.Error("ListAll should be true when deprecated ListStatus is used")
+	cfg.ChangelogFormat = "rst"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "-changelog-format") {
+		t.Errorf("validateConfig() = %v, want an invalid -changelog-format", err)
 	}
+}
 
-	// Verify the behavior is the same as using -list-all directly
-	showTested := cfg.ListAll || cfg.ListTested
-	showUntested := cfg.ListAll || cfg.ListUntested
+func TestNewForecast(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	plans := []plan.Plan{
+		{ID: 1, Description: "Login", Milestone: "Alpha", Tested: true},
+		{ID: 2, Description: "Logout", Steps: []string{"a", "b", "c"}, Milestone: "Alpha"},
+		{ID: 3, Description: "Search", Deferred: true},
+		{ID: 4, Description: "Which database?", Type: plan.TypeQuestion},
+	}
+	run := history.NewRun("claude", cfg.PlanFile, "")
+	run.IterationsRun = 4
+	run.EndTime = run.StartTime.Add(40 * time.Minute)
+	if err := history.NewStore(cfg.HistoryDir).Save(run); err != nil {
+		t.Fatal(err)
+	}
 
-	if !showTested {
-		t.Error("Deprecated -status should show tested features")
+	fc := newForecast(cfg, plans)
+	if fc.Total.Features != 1 || fc.Total.Iterations != 5 {
+		t.Errorf("Total = %+v, want 1 feature in 5 iterations", fc.Total)
 	}
-	if !showUntested {
-		t.Error("Deprecated -status should show untested features")
+	if fc.Questions != 1 {
+		t.Errorf("Questions = %d, want 1", fc.Questions)
+	}
+	if fc.IterationDuration != 10*time.Minute {
+		t.Errorf("IterationDuration = %v, want 10m", fc.IterationDuration)
+	}
+	if fc.Total.Duration != 50*time.Minute {
+		t.Errorf("Total.Duration = %v, want 50m", fc.Total.Duration)
+	}
+	if !fc.Insufficient(2) || fc.Insufficient(5) {
+		t.Errorf("Insufficient(2), Insufficient(5) = %v, %v, want true, false", fc.Insufficient(2), fc.Insufficient(5))
+	}
+}
+
+func TestBuildStatusReport(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	cfg.NoGlobalMemory = true
+	plans := []plan.Plan{
+		{ID: 1, Description: "Login", Milestone: "Alpha", Tested: true},
+		{ID: 2, Description: "Logout", Milestone: "Alpha"},
+		{ID: 3, Description: "Search", Deferred: true},
+	}
+	if err := plan.WriteFile(cfg.PlanFile, plans); err != nil {
+		t.Fatal(err)
+	}
+	run := history.NewRun("claude", cfg.PlanFile, "")
+	run.IterationsRun = 4
+	if err := history.NewStore(cfg.HistoryDir).Save(run); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addNudgeSpec(cfg, "focus:Work on logout"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := buildStatusReport(cfg)
+	if err != nil {
+		t.Fatalf("buildStatusReport() = %v", err)
+	}
+	if want := (plan.Summary{Total: 3, Tested: 1, Untested: 1, Deferred: 1}); report.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", report.Summary, want)
+	}
+	if report.ExitCode != exitPlanDeferred {
+		t.Errorf("ExitCode = %d, want %d", report.ExitCode, exitPlanDeferred)
+	}
+	if report.NextFeature == nil || report.NextFeature.ID != 2 {
+		t.Errorf("NextFeature = %+v, want feature 2", report.NextFeature)
+	}
+	if len(report.Milestones) != 1 || report.Milestones[0].Progress.CompletedFeatures != 1 {
+		t.Errorf("Milestones = %+v", report.Milestones)
+	}
+	if report.LastRun == nil || report.LastRun.IterationsRun != 4 {
+		t.Errorf("LastRun = %+v", report.LastRun)
+	}
+	if len(report.Nudges) != 1 || report.Nudges[0].Content != "Work on logout" {
+		t.Errorf("Nudges = %+v", report.Nudges)
+	}
+	if len(report.Goals) != 0 || report.Memories != 0 {
+		t.Errorf("Goals = %+v, Memories = %d, want none", report.Goals, report.Memories)
+	}
+
+	// -status does not need -iterations
+	cfg.ShowStatus = true
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() = %v", err)
+	}
+	if code, err := showStatus(cfg); err != nil || code != exitPlanDeferred {
+		t.Errorf("showStatus() = %d, %v", code, err)
+	}
+}
+
+func TestBuildFeatureRecord(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	plans := []plan.Plan{
+		{ID: 1, Description: "Login", Steps: []string{"Form"}},
+		{ID: 2, Description: "Search", Deferred: true, DeferReason: "scope limit"},
+	}
+	if err := plan.WriteFile(cfg.PlanFile, plans); err != nil {
+		t.Fatal(err)
+	}
+	goalMgr := goals.NewManager(plans)
+	goalMgr.AddGoal(goals.Goal{ID: "auth", Description: "Authentication", GeneratedPlanIDs: []int{1}})
+	if err := goalMgr.SaveGoalsTo(cfg.GoalsFile); err != nil {
+		t.Fatal(err)
+	}
+	run := history.NewRun("claude", cfg.PlanFile, "")
+	run.IterationsPerFeature[1] = 3
+	if err := history.NewStore(cfg.HistoryDir).Save(run); err != nil {
+		t.Fatal(err)
+	}
+	appendProgress(cfg.ProgressFile, "FAILURE [test]: tests failed (feature #1, retry 1)")
+	appendProgress(cfg.ProgressFile, "Working on feature #1")
+	appendProgress(cfg.ProgressFile, "Working on feature #2")
+
+	record, err := buildFeatureRecord(cfg, 1)
+	if err != nil {
+		t.Fatalf("buildFeatureRecord() = %v", err)
+	}
+	if record.Status != "untested" || record.Description != "Login" {
+		t.Errorf("record = %+v", record)
+	}
+	if record.Goal == nil || record.Goal.ID != "auth" {
+		t.Errorf("Goal = %+v, want auth", record.Goal)
+	}
+	if record.Iterations != 3 || len(record.Runs) != 1 {
+		t.Errorf("Iterations = %d, Runs = %+v", record.Iterations, record.Runs)
+	}
+	if len(record.Events) != 2 || record.LastFailure == nil || !strings.Contains(record.LastFailure.Message, "tests failed") {
+		t.Errorf("Events = %+v, LastFailure = %+v", record.Events, record.LastFailure)
+	}
+
+	if record, err := buildFeatureRecord(cfg, 2); err != nil || record.Status != "deferred" || record.Goal != nil || len(record.Runs) != 0 {
+		t.Errorf("buildFeatureRecord(2) = %+v, %v", record, err)
+	}
+	if _, err := buildFeatureRecord(cfg, 9); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("buildFeatureRecord(9) = %v", err)
+	}
+
+	cfg.ShowFeature = 1
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() = %v", err)
+	}
+	if err := showFeature(cfg); err != nil {
+		t.Errorf("showFeature() = %v", err)
+	}
+}
+
+func TestEditFeature(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	if err := plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 1, Description: "Login"}, {ID: 2, Description: "Search"}}); err != nil {
+		t.Fatal(err)
+	}
+	edit := func(mutate func(c *config.Config)) error {
+		c := *cfg
+		mutate(&c)
+		if err := validateConfig(&c); err != nil {
+			return err
+		}
+		return editFeature(&c)
+	}
+
+	if err := edit(func(c *config.Config) { c.MarkTested = 1 }); err != nil {
+		t.Fatalf("-mark-tested = %v", err)
+	}
+	if err := edit(func(c *config.Config) { c.DeferFeature, c.DeferReason = 2, "waiting on design" }); err != nil {
+		t.Fatalf("-defer = %v", err)
+	}
+	plans, _ := plan.ReadFile(cfg.PlanFile)
+	if !plans[0].Tested || !plans[1].Deferred || plans[1].DeferReason != "waiting on design" {
+		t.Errorf("plans = %+v", plans)
+	}
+
+	if err := edit(func(c *config.Config) { c.DeleteFeature = 1 }); err != nil {
+		t.Fatalf("-delete-feature = %v", err)
+	}
+	plans, _ = plan.ReadFile(cfg.PlanFile)
+	if len(plans) != 1 || plans[0].ID != 2 {
+		t.Errorf("plans after delete = %+v", plans)
+	}
+
+	// Every edit was backed up
+	versioner := replan.NewPlanVersioner(cfg.PlanFile)
+	versioner.SetOptions(planBackupOptions(cfg))
+	if versions := versioner.GetVersions(); len(versions) != 3 {
+		t.Errorf("plan versions = %d, want 3", len(versions))
+	}
+
+	if err := edit(func(c *config.Config) { c.MarkTested, c.DeleteFeature = 2, 2 }); err == nil || !strings.Contains(err.Error(), "only one") {
+		t.Errorf("two edits = %v", err)
+	}
+	if err := edit(func(c *config.Config) { c.MarkUntested, c.DeferReason = 2, "x" }); err == nil || !strings.Contains(err.Error(), "-reason requires -defer") {
+		t.Errorf("-reason without -defer = %v", err)
+	}
+	if err := edit(func(c *config.Config) { c.MarkTested = 9 }); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("-mark-tested of a missing feature = %v", err)
+	}
+}
+
+func TestAddFeature(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	cfg.AddFeature = "User login"
+	cfg.FeatureSteps = "Form; Session ;"
+	cfg.FeatureMilestone = "Alpha"
+
+	// The plan is created if it does not exist
+	if err := addFeature(cfg); err != nil {
+		t.Fatalf("addFeature() = %v", err)
+	}
+	cfg.AddFeature, cfg.FeatureCategory, cfg.FeatureSteps, cfg.FeatureMilestone = "Search", "ui", "", ""
+	if err := addFeature(cfg); err != nil {
+		t.Fatalf("addFeature() = %v", err)
+	}
+	// -milestone lists a milestone, it does not set the new feature's
+	cfg.AddFeature, cfg.ShowMilestone = "Logout", "Alpha"
+	if err := addFeature(cfg); err == nil || !strings.Contains(err.Error(), "-feature-milestone") {
+		t.Errorf("addFeature() with -milestone = %v", err)
+	}
+	cfg.ShowMilestone = ""
+
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []plan.Plan{
+		{ID: 1, Category: "feature", Description: "User login", Steps: []string{"Form", "Session"}, Milestone: "Alpha"},
+		{ID: 2, Category: "ui", Description: "Search"},
+	}
+	if !reflect.DeepEqual(plans, want) {
+		t.Errorf("plans = %+v, want %+v", plans, want)
+	}
+
+	cfg.AddFeature = ""
+	cfg.FeatureSteps = "a;b"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "require -add-feature") {
+		t.Errorf("validateConfig() with -steps alone = %v", err)
+	}
+}
+
+func TestMergePlanFileCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 1, Description: "Login"}})
+	plan.WriteFile("branch.json", []plan.Plan{
+		{ID: 1, Description: "login"},
+		{ID: 2, Description: "Search", Milestone: "Beta"},
+	})
+	cfg.MergePlan = "branch.json"
+	cfg.MergeMilestonePrefix = "web/"
+	cfg.MergeSimilarity = 0.9
+
+	if err := mergePlanFile(cfg); err != nil {
+		t.Fatalf("mergePlanFile() = %v", err)
+	}
+	plans, _ := plan.ReadFile(cfg.PlanFile)
+	if len(plans) != 2 || plans[1].ID != 2 || plans[1].Milestone != "web/Beta" {
+		t.Errorf("plans = %+v", plans)
+	}
+
+	cfg.MergeSimilarity = 2
+	if err := mergePlanFile(cfg); err == nil {
+		t.Error("mergePlanFile() with a similarity above 1 should fail")
+	}
+	cfg.MergePlan, cfg.MergeSimilarity = "", 0
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "require -merge-plan") {
+		t.Errorf("validateConfig() with -merge-milestone-prefix alone = %v", err)
 	}
 }
 
@@ -1006,3 +1314,1174 @@ func TestPlanAnalysisPreviewFile(t *testing.T) {
 		}
 	}
 }
+
+// TestValidationWritesReports tests that -validate leaves JSON and HTML reports
+func TestValidationWritesReports(t *testing.T) {
+	dir := t.TempDir()
+	planFile := filepath.Join(dir, "plan.json")
+	planJSON := `[{"id": 1, "description": "Passing feature", "tested": true, "validations": [{"type": "cli_command", "command": "true"}]},
+		{"id": 2, "description": "Failing feature", "tested": true, "validations": [{"type": "cli_command", "command": "false", "retries": 1}]}]`
+	if err := os.WriteFile(planFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.Validate = true
+	cfg.Quiet = true
+	cfg.PlanFile = planFile
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	cfg.NudgeFile = filepath.Join(dir, "nudges.json")
+	cfg.ReportDir = filepath.Join(dir, "reports")
+	cfg.ReportHTML = true
+	cfg.JUnitOutput = filepath.Join(dir, "junit", "validations.xml")
+
+	if err := handleValidationCommands(cfg); err == nil {
+		t.Error("handleValidationCommands() succeeded with a failing validation")
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.ReportDir, validation.ReportJSONFile))
+	if err != nil {
+		t.Fatalf("JSON report not written: %v", err)
+	}
+	var report validation.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Success || len(report.Features) != 2 || report.PassedCount != 1 || report.FailedCount != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.ReportDir, validation.ReportHTMLFile)); err != nil {
+		t.Errorf("HTML report not written: %v", err)
+	}
+
+	junit, err := testreport.Load(cfg.JUnitOutput, time.Time{})
+	if err != nil || junit == nil {
+		t.Fatalf("JUnit report not loaded: %v", err)
+	}
+	if passed, failed, _ := junit.Counts(); passed != 1 || failed != 1 || junit.Failed()[0].Suite != "Feature #2: Failing feature" {
+		t.Errorf("JUnit report = %+v", junit.Tests)
+	}
+}
+
+func TestCheckFeatureValidations(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.Quiet = true
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	planJSON := `[{"id": 1, "description": "Broken", "tested": true, "validations": [{"type": "cli_command", "command": "false", "retries": 1}]},
+		{"id": 2, "description": "Working", "tested": true, "validations": [{"type": "cli_command", "command": "true"}]},
+		{"id": 3, "description": "Unvalidated", "tested": true}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[int]bool{1: true, 2: true, 3: true}
+	results := make(map[int]validation.ValidationRunResult)
+	err := checkFeatureValidations(cfg, ui.New(ui.OutputConfig{Quiet: true}), nil, nil, []int{1, 2, 3}, seen, results)
+	if err == nil || !strings.Contains(err.Error(), "feature(s) #1 not marked tested") || !strings.Contains(err.Error(), "- Feature #1: ") {
+		t.Fatalf("checkFeatureValidations() = %v", err)
+	}
+	if seen[1] || !seen[2] || !seen[3] {
+		t.Errorf("seen = %v, want feature #1 to be validated again", seen)
+	}
+	if len(results) != 2 || results[1].Success || !results[2].Success {
+		t.Errorf("results = %+v, want a failing #1 and a passing #2", results)
+	}
+
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range plans {
+		if want := p.ID != 1; p.Tested != want {
+			t.Errorf("feature #%d tested = %v, want %v", p.ID, p.Tested, want)
+		}
+	}
+
+	// Nothing to unmark when all validations pass
+	if err := checkFeatureValidations(cfg, ui.New(ui.OutputConfig{Quiet: true}), nil, nil, []int{2}, seen, nil); err != nil {
+		t.Errorf("checkFeatureValidations() of passing feature = %v", err)
+	}
+}
+
+func TestCompleteGoals(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.Quiet = true
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	cfg.GoalsFile = filepath.Join(dir, "goals.json")
+	output := ui.New(ui.OutputConfig{Quiet: true})
+	failed := make(map[string]bool)
+
+	// Without a goals file there is nothing to do
+	completeGoals(cfg, output, nil, nil, nil, failed)
+
+	if err := plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 1, Description: "Login", Tested: true}, {ID: 2, Description: "Docs", Tested: true}}); err != nil {
+		t.Fatal(err)
+	}
+	guide := filepath.Join(dir, "guide.md")
+	goalsJSON := `{"goals": [
+		{"id": "auth", "description": "Add auth", "generated_plan_ids": [1]},
+		{"id": "docs", "description": "Write docs", "generated_plan_ids": [2], "success_criteria": ["` + "`" + guide + "`" + ` exists"]}]}`
+	if err := os.WriteFile(cfg.GoalsFile, []byte(goalsJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	load := func() *goals.Manager {
+		t.Helper()
+		mgr := goals.NewManager(nil)
+		if err := mgr.LoadGoals(cfg.GoalsFile); err != nil {
+			t.Fatal(err)
+		}
+		return mgr
+	}
+
+	// The docs goal stays open while its success criterion fails
+	completeGoals(cfg, output, nil, nil, nil, failed)
+	mgr := load()
+	if mgr.GetGoalByID("auth").Status != goals.StatusComplete || mgr.GetGoalByID("auth").CompletedAt == nil {
+		t.Errorf("auth goal = %+v, want complete", mgr.GetGoalByID("auth"))
+	}
+	docs := mgr.GetGoalByID("docs")
+	if docs.Status == goals.StatusComplete || !failed["docs"] {
+		t.Errorf("docs goal = %+v, failed = %v", docs, failed)
+	}
+	if len(docs.Validations) != 1 || docs.Validations[0].Type != "file_exists" || docs.Validations[0].Path != guide {
+		t.Errorf("docs validations = %+v", docs.Validations)
+	}
+	data, _ := os.ReadFile(cfg.ProgressFile)
+	if !strings.Contains(string(data), "GOAL COMPLETE: auth (Add auth)") || !strings.Contains(string(data), "GOAL: docs has all plan items tested, but 1 success criteria validation(s) failed") {
+		t.Errorf("progress = %s", data)
+	}
+
+	// Failed goals are checked again in the next run
+	if err := os.WriteFile(guide, []byte("# Guide"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	completeGoals(cfg, output, nil, nil, nil, failed)
+	if load().GetGoalByID("docs").Status == goals.StatusComplete {
+		t.Error("docs goal checked again in the same run")
+	}
+	completeGoals(cfg, output, nil, nil, nil, make(map[string]bool))
+	if load().GetGoalByID("docs").Status != goals.StatusComplete {
+		t.Error("docs goal not complete once its criterion passes")
+	}
+}
+
+func TestUnfinishedFeatures(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	planJSON := `[{"id": 1, "description": "Done", "tested": true},
+		{"id": 4, "description": "Search"},
+		{"id": 5, "description": "Too big", "deferred": true},
+		{"id": 6, "description": "Which database?", "type": "question"},
+		{"id": 7, "description": "Export"}]`
+	if err := os.WriteFile(planFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := unfinishedFeatures(planFile)
+	if err != nil {
+		t.Fatalf("unfinishedFeatures() failed: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].ID != 4 || remaining[1].ID != 7 {
+		t.Fatalf("unfinishedFeatures() = %+v, want features 4 and 7", remaining)
+	}
+
+	guidance := incompletePlanGuidance(planFile, remaining)
+	for _, want := range []string{"features 4 and 7 are still untested", "- #4: Search", "- #7: Export"} {
+		if !strings.Contains(guidance, want) {
+			t.Errorf("incompletePlanGuidance() missing %q in:\n%s", want, guidance)
+		}
+	}
+	if guidance := incompletePlanGuidance(planFile, remaining[:1]); !strings.Contains(guidance, "feature 4 is still untested") {
+		t.Errorf("incompletePlanGuidance() of one feature = %s", guidance)
+	}
+
+	var many []plan.Plan
+	for id := 1; id <= maxUnfinishedListed+2; id++ {
+		many = append(many, plan.Plan{ID: id, Description: "feature"})
+	}
+	if guidance := incompletePlanGuidance(planFile, many); !strings.Contains(guidance, "12 features are") || !strings.Contains(guidance, "... and 2 more") {
+		t.Errorf("incompletePlanGuidance() of many features = %s", guidance)
+	}
+
+	if _, err := unfinishedFeatures(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("unfinishedFeatures() of a missing plan succeeded")
+	}
+}
+
+func TestCheckPlanIntegrity(t *testing.T) {
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalDir)
+
+	cfg := config.New()
+	cfg.Quiet = true
+	cfg.PlanFile = "plan.json"
+	cfg.ProgressFile = "progress.txt"
+	output := ui.New(ui.OutputConfig{Quiet: true})
+	planJSON := `[{"id": 1, "description": "Login"}, {"id": 2, "description": "Logout"}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Backup(cfg.PlanFile, plan.BackupFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if guidance := checkPlanIntegrity(cfg, output, 1); guidance != "" {
+		t.Errorf("checkPlanIntegrity() of an intact plan = %q", guidance)
+	}
+
+	// The agent marked feature #1 tested and dropped feature #2
+	if err := os.WriteFile(cfg.PlanFile, []byte(`[{"id": 1, "description": "Login", "tested": true}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	guidance := checkPlanIntegrity(cfg, output, 2)
+	if !strings.Contains(guidance, "feature #2 (Logout) was removed") {
+		t.Errorf("checkPlanIntegrity() guidance = %q", guidance)
+	}
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil || len(plans) != 2 || !plans[0].Tested {
+		t.Errorf("restored plan = %+v, %v", plans, err)
+	}
+	if progress, _ := os.ReadFile(cfg.ProgressFile); !strings.Contains(string(progress), "PLAN: iteration 2 damaged plan.json") {
+		t.Errorf("progress = %q", progress)
+	}
+}
+
+func TestWriteRunJUnit(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.Quiet = true
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.JUnitOutput = filepath.Join(dir, "junit.xml")
+	planJSON := `[{"id": 1, "description": "Done", "tested": true},
+		{"id": 2, "description": "Too big", "deferred": true, "defer_reason": "scope_limit"},
+		{"id": 3, "description": "Pending"}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	iterations := []testreport.TestCase{
+		iterationTest(1, 1, "Done", start, ""),
+		iterationTest(2, 0, "", start, "[test] tests failed (feature #0, iteration 2, retries: 0)"),
+	}
+	if iterations[0].Suite != "Feature #1: Done" || iterations[1].Suite != "Iterations" || iterations[1].Name != "iteration 2" {
+		t.Errorf("iterationTest() = %+v", iterations)
+	}
+	writeRunJUnit(cfg, ui.New(ui.OutputConfig{Quiet: true}), iterations)
+
+	junit, err := testreport.Load(cfg.JUnitOutput, time.Time{})
+	if err != nil || junit == nil {
+		t.Fatalf("JUnit report not loaded: %v", err)
+	}
+	var failed []string
+	for _, tc := range junit.Failed() {
+		failed = append(failed, tc.String())
+	}
+	if want := "Features: #2 Too big,Iterations: iteration 2"; strings.Join(failed, ",") != want {
+		t.Errorf("failed = %q, want %q", failed, want)
+	}
+	if passed, _, skipped := junit.Counts(); passed != 2 || skipped != 1 {
+		t.Errorf("Counts() passed = %d, skipped = %d; want 2, 1", passed, skipped)
+	}
+}
+
+func TestWriteEnvironmentReports(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ReportDir = filepath.Join(dir, "reports")
+	planJSON := `[{"id": 1, "description": "Done", "tested": true}, {"id": 2, "description": "Pending"}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := &history.Run{ID: "run-1", IterationsRun: 3, FeaturesCompleted: []int{1}}
+	output := ui.New(ui.OutputConfig{Quiet: true})
+	gitlab := &environment.EnvironmentProfile{Type: environment.EnvGitLabCI}
+
+	// Disabled by default
+	writeEnvironmentReports(cfg, output, gitlab, run, nil)
+	if _, err := os.Stat(cfg.ReportDir); !os.IsNotExist(err) {
+		t.Fatalf("reports were written without -environment-reports")
+	}
+
+	cfg.EnvironmentReports = true
+	writeEnvironmentReports(cfg, output, gitlab, run, nil)
+	if _, err := testreport.Load(filepath.Join(cfg.ReportDir, environment.JUnitReportFile), time.Time{}); err != nil {
+		t.Errorf("JUnit report not loaded: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.ReportDir, environment.DotenvReportFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"RALPH_RUN_ID=run-1\n", "RALPH_STATUS=incomplete\n", "RALPH_FEATURES_TESTED=1\n", "RALPH_FEATURES_TOTAL=2\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s is missing %q:\n%s", environment.DotenvReportFile, want, data)
+		}
+	}
+}
+
+func TestRunCIGate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	cfg.Quiet = true
+	planJSON := `[{"id": 1, "description": "Config file", "tested": true, "validations": [{"type": "file_exists", "path": "app.conf"}]},
+		{"id": 2, "description": "Pending"}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The validation of feature #1 fails, and no run is recorded to compare with
+	if err := runCIGate(cfg); err == nil || !strings.Contains(err.Error(), "1 of 3 check(s) failed") {
+		t.Fatalf("runCIGate() = %v, want the validation check to fail", err)
+	}
+	if err := os.WriteFile("app.conf", []byte("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCIGate(cfg); err != nil {
+		t.Fatalf("runCIGate() = %v, want it to pass", err)
+	}
+
+	// More features are untested than at the end of the last run
+	run := history.NewRun("test", cfg.PlanFile, "")
+	run.FeaturesTotal, run.FeaturesTested = 2, 2
+	if err := history.NewStore(cfg.HistoryDir).Save(run); err != nil {
+		t.Fatal(err)
+	}
+	plans, _ := plan.ReadFile(cfg.PlanFile)
+	if c := checkUntestedRegression(cfg, plans); c.Passed || c.Skipped || len(c.Problems) != 1 {
+		t.Errorf("checkUntestedRegression() = %+v, want one more feature untested", c)
+	}
+	if err := runCIGate(cfg); err == nil {
+		t.Error("runCIGate() passed with more features untested")
+	}
+
+	// Schema errors fail the gate before anything is validated
+	if err := os.WriteFile(cfg.PlanFile, []byte(`[{"id": 1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkPlanSchema(cfg); c.Passed || len(c.Problems) != 1 {
+		t.Errorf("checkPlanSchema() = %+v, want the missing description", c)
+	}
+	if err := runCIGate(cfg); err == nil || !strings.Contains(err.Error(), "1 of 1 check(s) failed") {
+		t.Errorf("runCIGate() = %v, want the schema check to fail", err)
+	}
+}
+
+func TestRunBaselineDrift(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	cfg.RefreshStaleContext = true
+	for _, name := range []string{"main.go", "util.go"} {
+		if err := os.WriteFile(name, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := scanBaseline(cfg); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := baseline.Load(cfg.BaselineFile)
+
+	// Changes made by Ralph's iterations, and to its state files, don't make
+	// the baseline stale
+	store := diffs.NewStore(cfg.DiffDir)
+	if _, err := store.Save("run-20261016-120000", 1, "diff --git a/main.go b/main.go\n"); err != nil {
+		t.Fatal(err)
+	}
+	// File times may be coarser than the baseline's timestamp
+	later := saved.GeneratedAt.Add(time.Second)
+	if err := os.Chtimes(store.Path("run-20261016-120000", 1), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.ProgressFile, []byte("progress\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBaselineDrift(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := baseline.Load(cfg.BaselineFile); !b.GeneratedAt.Equal(saved.GeneratedAt) {
+		t.Error("baseline was re-scanned without changes outside Ralph's iterations")
+	}
+
+	// Other changes do, and the baseline is re-scanned
+	if err := os.Remove("util.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runBaselineDrift(cfg); err != nil {
+		t.Fatal(err)
+	}
+	b, err := baseline.Load(cfg.BaselineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.GeneratedAt.Equal(saved.GeneratedAt) || b.Drift(b).Total() != 0 {
+		t.Error("baseline was not re-scanned after util.go was removed")
+	}
+	for _, f := range b.Files {
+		if f.Path == "util.go" {
+			t.Error("re-scanned baseline still lists util.go")
+		}
+	}
+
+	cfg.BaselineFile = "missing.json"
+	if err := runBaselineDrift(cfg); err == nil || !strings.Contains(err.Error(), "no baseline found") {
+		t.Errorf("runBaselineDrift() without a baseline = %v", err)
+	}
+}
+
+func TestParseFlagsWorkDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("proj", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("proj", ".ralph.yaml"), []byte("plan: tasks.json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, commandLine := os.Args, flag.CommandLine
+	defer func() { os.Args, flag.CommandLine = args, commandLine }()
+	os.Args = []string{"ralph", "-C", "proj", "-progress", "log.txt"}
+	flag.CommandLine = flag.NewFlagSet("ralph", flag.ContinueOnError)
+
+	cfg := parseFlags()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(wd) != "proj" {
+		t.Errorf("working directory = %s, want proj", wd)
+	}
+	// The config file is discovered in the -C directory
+	if cfg.PlanFile != "tasks.json" || cfg.ProgressFile != "log.txt" {
+		t.Errorf("PlanFile, ProgressFile = %q, %q", cfg.PlanFile, cfg.ProgressFile)
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	if got := lastLines("a\nb\nc\n", 2); got != "b\nc" {
+		t.Errorf("lastLines() = %q, want %q", got, "b\nc")
+	}
+	if got := lastLines("a", 2); got != "a" {
+		t.Errorf("lastLines() = %q, want %q", got, "a")
+	}
+}
+
+func TestAppendProgressRecordsEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.txt")
+	progressContext = progress.Context{Iteration: 3, FeatureID: 5}
+	defer func() { progressContext = progress.Context{} }()
+
+	if err := appendProgress(path, "CHECKPOINT: cp-1 \"before refactor\""); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendProgress(path, "FAILURE [test_failure]: tests failed (feature #6, retry 1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	text, _ := os.ReadFile(path)
+	if !strings.Contains(string(text), "CHECKPOINT: cp-1") {
+		t.Errorf("progress file = %q", text)
+	}
+	events, err := progress.Read(progress.JSONLPath(path))
+	if err != nil {
+		t.Fatalf("progress.Read() failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != "checkpoint" || events[0].FeatureID != 5 || events[0].Iteration != 3 ||
+		events[1].Type != "failure" || events[1].FeatureID != 6 || events[1].Payload["detail"] != "test_failure" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestWriteRunSummaryMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.SummaryMarkdown = filepath.Join(dir, "reports", "summary.md")
+	planJSON := `[{"id": 1, "description": "Done", "tested": true, "milestone": "MVP"},
+		{"id": 2, "description": "Too big", "deferred": true, "defer_reason": "scope_exceeded", "milestone": "MVP"}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := history.NewRun("claude", cfg.PlanFile, "")
+	run.FeaturesCompleted = []int{1}
+	run.IterationsRun, run.IterationsLimit = 3, 5
+	validations := map[int]validation.ValidationRunResult{1: {FeatureID: 1, Success: true, TotalCount: 1, PassedCount: 1}}
+	writeRunSummaryMarkdown(cfg, ui.New(ui.OutputConfig{Quiet: true}), run, ui.Summary{Errors: []string{"iteration 2 violated the policy"}}, validations)
+
+	data, err := os.ReadFile(cfg.SummaryMarkdown)
+	if err != nil {
+		t.Fatalf("summary not written: %v", err)
+	}
+	for _, want := range []string{"- [x] #1 Done", "- ✅ #1 Done: 1/1 passed", "- iteration 2 violated the policy", "- #2 Too big (scope_exceeded)", "MVP: 1/2 features"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("summary missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestStartTranscript(t *testing.T) {
+	cfg := config.New()
+	cfg.TranscriptDir = t.TempDir()
+	cfg.TranscriptRedact = []string{`token-\w+`}
+	defer agent.SetTranscript(nil)
+
+	r := startTranscript(cfg, "run-1")
+	if r == nil {
+		t.Fatal("startTranscript() returned nil")
+	}
+	if err := r.Record(transcript.Entry{Prompt: "use token-abc", Response: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := transcript.NewStore(cfg.TranscriptDir).Load("run-1")
+	if err != nil || len(entries) != 1 || entries[0].Prompt != "use [REDACTED]" {
+		t.Errorf("transcript = %+v, %v", entries, err)
+	}
+
+	// An invalid pattern disables the transcript rather than recording unredacted text
+	cfg.TranscriptRedact = []string{"("}
+	if r := startTranscript(cfg, "run-2"); r != nil {
+		t.Error("startTranscript() with an invalid pattern returned a recorder")
+	}
+}
+
+func TestNewSecretRedactor(t *testing.T) {
+	t.Setenv("RALPH_TEST_TEAM_KEY", "team-key-0123456789")
+	cfg := config.New()
+	cfg.AgentEnv = map[string]string{"ANTHROPIC_API_KEY": "env:RALPH_TEST_TEAM_KEY", "APP_ENV": "production"}
+	cfg.RedactPatterns = []string{`INTERNAL-\d+`}
+
+	r, err := newSecretRedactor(cfg)
+	if err != nil {
+		t.Fatalf("newSecretRedactor() failed: %v", err)
+	}
+	got := r.Redact("key team-key-0123456789 for INTERNAL-42 in production")
+	if want := "key [REDACTED] for [REDACTED] in production"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+
+	// Progress entries are masked before they are written
+	secretRedactor = r
+	defer func() { secretRedactor = nil }()
+	path := filepath.Join(t.TempDir(), "progress.txt")
+	if err := appendProgress(path, "FAILURE [test]: login failed with team-key-0123456789"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, progress.JSONLPath(path)} {
+		data, _ := os.ReadFile(p)
+		if strings.Contains(string(data), "team-key") || !strings.Contains(string(data), "[REDACTED]") {
+			t.Errorf("%s = %q, want the key masked", p, data)
+		}
+	}
+}
+
+func TestReplanProgress(t *testing.T) {
+	scopeMgr := scope.NewManager(&scope.Constraints{})
+	scopeMgr.SetDeadlineDuration(time.Hour)
+	scopeMgr.StartFeature(2, 2, "Add a button")
+	scopeMgr.RecordIteration(2)
+	plans := []plan.Plan{
+		{ID: 1, Tested: true},
+		{ID: 2},
+		{ID: 3},
+	}
+
+	// Until a feature is completed in this run, the velocity of earlier runs is used
+	got := replanProgress(scopeMgr, 2, plans, map[int]bool{1: true}, 20*time.Minute)
+	if got.FeatureID != 2 || got.FeatureIterations != 1 || got.EstimatedIterations != scope.ComplexityToIterations(scope.ComplexityLow) {
+		t.Errorf("replanProgress() = %+v", got)
+	}
+	if got.FeatureDuration != 20*time.Minute || got.TimeRemaining <= 0 || got.TimeRemaining > time.Hour {
+		t.Errorf("replanProgress() timing = %+v", got)
+	}
+
+	got = replanProgress(scopeMgr, 2, plans, map[int]bool{}, 20*time.Minute)
+	if got.FeatureDuration == 20*time.Minute || got.FeatureDuration > time.Minute {
+		t.Errorf("replanProgress() with a feature completed in this run = %v, want the run's elapsed time", got.FeatureDuration)
+	}
+}
+
+func TestRecordFeatureOutcomes(t *testing.T) {
+	scopeMgr := scope.NewManager(&scope.Constraints{})
+	scopeMgr.StartFeature(2, 2, "Add a button")
+	scopeMgr.DeferFeature(2, scope.DeferReasonIterationLimit)
+	recoveryMgr := recovery.NewRecoveryManager(3, recovery.StrategyRetry)
+	recoveryMgr.GetTracker().RecordFailure(&recovery.Failure{FeatureID: 1, Type: recovery.FailureTypeTest})
+	recoveryMgr.GetTracker().RecordFailure(&recovery.Failure{FeatureID: 1, Type: recovery.FailureTypeLint})
+	results := map[int]validation.ValidationRunResult{1: {PassedCount: 2, FailedCount: 1}}
+
+	run := history.NewRun("claude", "plan.json", "")
+	recordFeatureOutcomes(run, scopeMgr, recoveryMgr, results)
+	if want := map[int]int{1: 2}; !reflect.DeepEqual(run.FeatureFailures, want) {
+		t.Errorf("FeatureFailures = %v, want %v", run.FeatureFailures, want)
+	}
+	if want := map[string]int{"test_failure": 1, "lint_failure": 1}; !reflect.DeepEqual(run.FailuresByType, want) {
+		t.Errorf("FailuresByType = %v, want %v", run.FailuresByType, want)
+	}
+	if want := map[int]history.ValidationCount{1: {Passed: 2, Failed: 1}}; !reflect.DeepEqual(run.Validations, want) {
+		t.Errorf("Validations = %v, want %v", run.Validations, want)
+	}
+	if want := map[int]string{2: "iteration_limit"}; !reflect.DeepEqual(run.Deferrals, want) {
+		t.Errorf("Deferrals = %v, want %v", run.Deferrals, want)
+	}
+}
+
+func TestScopeCalibration(t *testing.T) {
+	cfg := config.New()
+	cfg.HistoryDir = t.TempDir()
+	cfg.AgentCmd = "claude"
+	store := history.NewStore(cfg.HistoryDir)
+	for i, agentCmd := range []string{"claude", "claude", "claude", "cursor-agent"} {
+		run := history.NewRun(agentCmd, cfg.PlanFile, "")
+		run.ID = fmt.Sprintf("run-%d", i)
+		run.FeaturesCompleted = []int{1}
+		run.IterationsPerFeature[1] = 7
+		run.FeatureComplexity = map[int]string{1: string(scope.ComplexityLow)}
+		if err := store.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calibration := scopeCalibration(cfg)
+	if calibration.Samples(scope.ComplexityLow) != 3 || calibration.Iterations(scope.ComplexityLow) != 7 {
+		t.Errorf("calibration = %s", calibration.Summary())
+	}
+
+	cfg.HistoryDir = filepath.Join(t.TempDir(), "missing")
+	if calibration := scopeCalibration(cfg); calibration.Calibrated(scope.ComplexityLow) {
+		t.Error("calibration without history should use the defaults")
+	}
+}
+
+func TestAddPromptContext(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	if err := plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 1, Category: "ui", Description: "Login form"}}); err != nil {
+		t.Fatal(err)
+	}
+	memStore := memory.NewStore(filepath.Join(dir, "memory.json"))
+	if _, err := memStore.Add(memory.EntryTypeConvention, "Use tabs", "", "user"); err != nil {
+		t.Fatal(err)
+	}
+	nudgeStore := nudge.NewStore(filepath.Join(dir, "nudges.json"))
+	if _, err := nudgeStore.Add(nudge.NudgeTypeFocus, "Keep it simple", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	data := prompt.NewIterationData(cfg, 1, 1)
+	nudges, memories := addPromptContext(cfg, ui.New(ui.OutputConfig{Quiet: true}), &data, nil, memStore, nudgeStore,
+		map[int][]string{2: {"beta"}})
+	if len(nudges) != 1 || len(memories) != 1 {
+		t.Fatalf("injected %d nudge(s), %d memories, want 1 and 1", len(nudges), len(memories))
+	}
+	for name, section := range map[string]string{"nudges": data.Nudges, "memories": data.Memories, "blocked": data.Blocked} {
+		if section == "" {
+			t.Errorf("%s section is empty", name)
+		}
+	}
+	if !strings.Contains(data.Nudges, "Keep it simple") || !strings.Contains(data.Memories, "Use tabs") {
+		t.Errorf("context = %q, %q", data.Nudges, data.Memories)
+	}
+
+	// With a baseline, the files the feature will likely touch are listed
+	base := &baseline.Baseline{Files: []baseline.FileInfo{
+		{Path: "web/login_form.tsx", Type: baseline.FileTypeSource},
+		{Path: "web/cart.tsx", Type: baseline.FileTypeSource},
+	}}
+	data = prompt.NewIterationData(cfg, 1, 1)
+	addPromptContext(cfg, ui.New(ui.OutputConfig{Quiet: true}), &data, base, memStore, nudgeStore, nil)
+	if !strings.Contains(data.Impact, "- web/login_form.tsx\n") || strings.Contains(data.Impact, "cart") {
+		t.Errorf("impact section = %q", data.Impact)
+	}
+	if !strings.Contains(data.Default(), data.Impact) {
+		t.Error("the built-in prompt does not include the impact section")
+	}
+	cfg.NoImpactAnalysis = true
+	data = prompt.NewIterationData(cfg, 1, 1)
+	addPromptContext(cfg, ui.New(ui.OutputConfig{Quiet: true}), &data, base, memStore, nudgeStore, nil)
+	if data.Impact != "" || data.Baseline == "" {
+		t.Errorf("with -no-impact-analysis, impact = %q, baseline = %q", data.Impact, data.Baseline)
+	}
+}
+
+func TestValidateConfigDryRun(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.AgentCmd = "ralph-missing-agent"
+	if err := plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 1, Description: "Login"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() accepted a run without iterations")
+	}
+	// A dry run needs neither iterations nor the agent
+	cfg.DryRun = true
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() of a dry run = %v", err)
+	}
+}
+
+func TestRunFuncParallelAndWorktree(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	cfg := config.New()
+	cfg.PlanFile = "plan.json"
+	cfg.DryRun = true // Validates the flags without needing the agent
+	if err := plan.WriteFile(cfg.PlanFile, []plan.Plan{{ID: 1, Description: "Login"}}); err != nil {
+		t.Fatal(err)
+	}
+	same := func(a, b func(*config.Config) error) bool {
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+
+	if !same(runFunc(cfg), runIterations) {
+		t.Error("runFunc() without -parallel or -isolated-worktree should run in the checkout")
+	}
+	cfg.IsolatedWorktree = true
+	if !same(runFunc(cfg), runInWorktree) {
+		t.Error("runFunc() with -isolated-worktree should run in a worktree")
+	}
+	// -parallel 1 works on one feature at a time, so it keeps -isolated-worktree
+	cfg.Parallel = 1
+	if err := validateConfig(cfg); err != nil || !same(runFunc(cfg), runInWorktree) {
+		t.Errorf("-parallel 1 -isolated-worktree: validateConfig() = %v", err)
+	}
+	// Parallel workers already run in worktrees of their own
+	cfg.Parallel = 3
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "-isolated-worktree") {
+		t.Errorf("validateConfig() accepted -parallel 3 with -isolated-worktree: %v", err)
+	}
+	cfg.IsolatedWorktree = false
+	if err := validateConfig(cfg); err != nil || !same(runFunc(cfg), runParallel) {
+		t.Errorf("-parallel 3: validateConfig() = %v", err)
+	}
+}
+
+func TestWorkerCommand(t *testing.T) {
+	commandLine := flag.CommandLine
+	defer func() { flag.CommandLine = commandLine }()
+	flag.CommandLine = flag.NewFlagSet("ralph", flag.ContinueOnError)
+	flag.String("C", "", "")
+	flag.String("config", "", "")
+	flag.String("junit-output", "", "")
+	flag.Int("iterations", 0, "")
+	flag.Int("parallel", 0, "")
+	flag.Bool("approve", false, "")
+	flag.Bool("verbose", false, "")
+
+	cfg := config.New()
+	cfg.HistoryDir = ".ralph/history"
+	args := []string{"-C", "proj", "-iterations", "5", "-parallel=3", "-approve", "--junit-output", "out.xml", "-verbose", "-config", "ci.yaml"}
+	got := workerCommand("/bin/ralph", "/repo", args, cfg)
+	joined := strings.Join(got, " ")
+
+	if got[0] != "/bin/ralph" {
+		t.Errorf("workerCommand()[0] = %q, want the executable", got[0])
+	}
+	for _, want := range []string{"-iterations=5", "-verbose", "-config=" + filepath.Join("/repo", "ci.yaml"), "-parallel=1", "-approve=false", "-junit-output=", "-history-dir=" + filepath.Join("/repo", ".ralph/history")} {
+		if !slices.Contains(got, want) {
+			t.Errorf("workerCommand() = %s, missing %s", joined, want)
+		}
+	}
+	for _, unwanted := range []string{"-C", "-parallel=3", "-approve"} {
+		if slices.Contains(got, unwanted) {
+			t.Errorf("workerCommand() = %s, should drop %s", joined, unwanted)
+		}
+	}
+	if strings.Contains(joined, "proj") || strings.Contains(joined, "out.xml") {
+		t.Errorf("workerCommand() = %s, should drop the values of -C and -junit-output", joined)
+	}
+}
+
+func TestValidateExperimentConfigAPIBackend(t *testing.T) {
+	template := filepath.Join(t.TempDir(), "b.md")
+	if err := os.WriteFile(template, []byte("Be brief.\n{{prompt}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.AgentBackend = "anthropic"
+	cfg.APIModel = "claude-sonnet"
+	cfg.ExperimentAgentB = "claude"
+	// The API backend runs -api-model for both variants
+	if err := validateExperimentConfig(cfg); err == nil || !strings.Contains(err.Error(), "-experiment-agent-b") {
+		t.Errorf("validateExperimentConfig() with an API backend and an agent B = %v", err)
+	}
+	cfg.ExperimentAgentB = ""
+	cfg.ExperimentPromptB = template
+	if err := validateExperimentConfig(cfg); err != nil {
+		t.Errorf("validateExperimentConfig() of a prompt experiment with an API backend = %v", err)
+	}
+}
+
+func TestLoadAgentsConfigFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ralph.yaml")
+	content := `
+parallel_agents: 3
+agents:
+  - id: impl-1
+    role: implementer
+    command: claude
+    enabled: true
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileCfg, err := config.LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateFileConfig(fileCfg); err != nil {
+		t.Fatalf("validateFileConfig() = %v", err)
+	}
+
+	cfg := config.New()
+	applyFileConfigWithPrecedence(cfg, fileCfg, map[string]bool{})
+	agentConfig, source, err := loadAgentsConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != "config file" || len(agentConfig.Agents) != 1 || agentConfig.MaxParallel != 3 {
+		t.Errorf("loadAgentsConfig() = %+v from %s", agentConfig, source)
+	}
+
+	fileCfg.Agents[0]["role"] = "designer"
+	if err := validateFileConfig(fileCfg); err == nil || !strings.HasPrefix(err.Error(), "agents: ") {
+		t.Errorf("validateFileConfig() of an invalid agent = %v", err)
+	}
+}
+
+// reviewExecutor answers every reviewer with the same output
+type reviewExecutor struct {
+	output string
+}
+
+func (e reviewExecutor) Execute(ctx context.Context, agentConfig *multiagent.AgentConfig, prompt string) (string, error) {
+	return e.output, nil
+}
+
+func TestCheckReviewGate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.New()
+	cfg.Quiet = true
+	cfg.PlanFile = filepath.Join(dir, "plan.json")
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	planJSON := `[{"id": 1, "description": "Login", "tested": true}, {"id": 2, "description": "Logout", "tested": true}]`
+	if err := os.WriteFile(cfg.PlanFile, []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := ui.New(ui.OutputConfig{Quiet: true})
+	gate := multiagent.NewOrchestrator(&multiagent.MultiAgentConfig{Agents: []multiagent.AgentConfig{
+		{ID: "review-1", Role: multiagent.RoleReviewer, Command: "cmd", Enabled: true},
+	}}, filepath.Join(dir, "context.json"))
+
+	gate.SetExecutor(reviewExecutor{output: "Needs work.\nIssues:\n- Passwords are logged"})
+	seen := map[int]bool{1: true, 2: true}
+	err := checkReviewGate(cfg, output, gate, 4, 1, "Login", "Implement login", "done", []int{1}, seen)
+	if err == nil || !strings.Contains(err.Error(), "feature(s) #1 not marked tested") || !strings.Contains(err.Error(), "- Passwords are logged") {
+		t.Fatalf("checkReviewGate() = %v", err)
+	}
+	if seen[1] || !seen[2] {
+		t.Errorf("seen = %v, want feature #1 to be reviewed again", seen)
+	}
+	plans, err := plan.ReadFile(cfg.PlanFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plans[0].Tested || !plans[1].Tested {
+		t.Errorf("plans = %+v, want only #1 unmarked", plans)
+	}
+
+	gate.SetExecutor(reviewExecutor{output: "LGTM"})
+	if err := checkReviewGate(cfg, output, gate, 5, 2, "Logout", "Implement logout", "done", []int{2}, seen); err != nil {
+		t.Errorf("checkReviewGate() of an approved iteration = %v", err)
+	}
+}
+
+func TestExecuteWithFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts not supported")
+	}
+	dir := t.TempDir()
+	for name, body := range map[string]string{"failing-agent": "exit 1", "working-agent": "echo done"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.New()
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	cfg.AgentCmd = filepath.Join(dir, "failing-agent")
+	cfg.FallbackAgents = []string{filepath.Join(dir, "working-agent")}
+	output := ui.New(ui.OutputConfig{Quiet: true})
+
+	result, served, err := executeWithFallback(cfg, agent.NewPool(cfg), output, "prompt")
+	if err != nil || result != "done" || served.AgentCmd != cfg.FallbackAgents[0] {
+		t.Errorf("executeWithFallback() = %q, %s, %v; want the fallback's output", result, served.AgentCmd, err)
+	}
+	if data, _ := os.ReadFile(cfg.ProgressFile); !strings.Contains(string(data), "FALLBACK: agent "+cfg.AgentCmd+" failed") {
+		t.Errorf("progress = %q, want the fallback logged", data)
+	}
+
+	// The last agent's error is returned when all of them fail
+	cfg.FallbackAgents = []string{cfg.AgentCmd}
+	if _, served, err := executeWithFallback(cfg, agent.NewPool(cfg), output, "prompt"); err == nil || served == cfg {
+		t.Errorf("executeWithFallback() = %v from %s, want the last agent's error", err, served.AgentCmd)
+	}
+}
+
+func TestExecuteWithRateLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts not supported")
+	}
+	dir := t.TempDir()
+	// The agent is rate limited on its first run only
+	script := "#!/bin/sh\nif [ -f " + filepath.Join(dir, "ran") + " ]; then echo done; exit 0; fi\n" +
+		"touch " + filepath.Join(dir, "ran") + "\necho 'Error: 429 Too Many Requests' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "agent"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.ProgressFile = filepath.Join(dir, "progress.txt")
+	cfg.AgentCmd = filepath.Join(dir, "agent")
+	cfg.RateLimitBackoff = "1ms"
+	output := ui.New(ui.OutputConfig{Quiet: true})
+
+	result, _, err := executeWithRateLimit(cfg, agent.NewPool(cfg), output, "prompt")
+	if err != nil || result != "done" {
+		t.Errorf("executeWithRateLimit() = %q, %v; want the retried run's output", result, err)
+	}
+	if data, _ := os.ReadFile(cfg.ProgressFile); !strings.Contains(string(data), "RATE LIMITED: agent "+cfg.AgentCmd) {
+		t.Errorf("progress = %q, want the rate limit logged", data)
+	}
+
+	// Without retries, a rate limit is an ordinary failure
+	os.Remove(filepath.Join(dir, "ran"))
+	cfg.RateLimitRetries = 0
+	if _, _, err := executeWithRateLimit(cfg, agent.NewPool(cfg), output, "prompt"); err == nil {
+		t.Error("executeWithRateLimit() without retries waited out the rate limit")
+	}
+}
+
+func TestCheckAgentHealth(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts not supported")
+	}
+	dir := t.TempDir()
+	for name, body := range map[string]string{"failing-agent": "exit 1", "working-agent": "echo OK"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := config.New()
+	cfg.AgentCmd = filepath.Join(dir, "working-agent")
+	output := ui.New(ui.OutputConfig{Quiet: true})
+
+	if err := checkAgentHealth(cfg, output, agent.NewPool(cfg).Agents(cfg)); err != nil {
+		t.Errorf("checkAgentHealth() = %v", err)
+	}
+
+	// A failing fallback agent fails the check too
+	cfg.FallbackAgents = []string{filepath.Join(dir, "failing-agent")}
+	err := checkAgentHealth(cfg, output, agent.NewPool(cfg).Agents(cfg))
+	if err == nil || !strings.Contains(err.Error(), "failing-agent failed the health check") {
+		t.Errorf("checkAgentHealth() = %v, want the fallback agent's failure", err)
+	}
+}
+
+func TestMCPMarkTested(t *testing.T) {
+	t.Chdir(t.TempDir())
+	plans := []plan.Plan{
+		{ID: 1, Description: "Login"},
+		{ID: 2, Description: "Docs", Validations: []plan.ValidationDefinition{{Type: "file_exists", Path: "README.md"}}},
+	}
+	if err := plan.WriteFile("plan.json", plans); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.PlanFile = "plan.json"
+	cfg.ProgressFile = "progress.txt"
+	cfg.NoPathGuard = true
+	output := ui.New(ui.OutputConfig{Quiet: true})
+
+	if _, err := mcpMarkTested(cfg, output, 1); err != nil {
+		t.Fatalf("mcpMarkTested(1) = %v", err)
+	}
+	// Feature 2 is only marked tested once its validation passes
+	if _, err := mcpMarkTested(cfg, output, 2); err == nil || !strings.Contains(err.Error(), "validations failed") {
+		t.Errorf("mcpMarkTested(2) without README.md = %v", err)
+	}
+	os.WriteFile("README.md", []byte("# Docs\n"), 0644)
+	if _, err := mcpMarkTested(cfg, output, 2); err != nil {
+		t.Errorf("mcpMarkTested(2) = %v", err)
+	}
+	if _, err := mcpMarkTested(cfg, output, 9); err == nil {
+		t.Error("mcpMarkTested() accepted an unknown feature")
+	}
+
+	plans, _ = plan.ReadFile("plan.json")
+	if !plans[0].Tested || !plans[1].Tested {
+		t.Errorf("plans = %+v, want both tested", plans)
+	}
+	features, err := mcpFeatures(cfg)
+	if err != nil || len(features) != 2 {
+		t.Errorf("mcpFeatures() = %v, %v", features, err)
+	}
+}
+
+func TestHandleTrackerSync(t *testing.T) {
+	t.Chdir(t.TempDir())
+	var closed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[{"number": 1, "title": "Login"}, {"number": 2, "title": "Search", "labels": [{"name": "ui"}]}]`))
+		case http.MethodPatch:
+			closed = append(closed, r.URL.Path)
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+
+	if err := plan.WriteFile("plan.json", []plan.Plan{{ID: 1, Description: "Login", Tested: true, Issue: "github:1"}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHOP_TOKEN", "secret")
+	cfg := config.New()
+	cfg.PlanFile = "plan.json"
+	cfg.ProgressFile = "progress.txt"
+	cfg.Tracker = &config.Tracker{Provider: "github", Project: "acme/shop", URL: srv.URL, Token: "env:SHOP_TOKEN"}
+
+	if err := handleTrackerSync(cfg); err != nil {
+		t.Fatalf("handleTrackerSync() = %v", err)
+	}
+	if len(closed) != 1 || closed[0] != "/repos/acme/shop/issues/1" {
+		t.Errorf("closed = %v, want issue 1 of the tested feature", closed)
+	}
+	plans, _ := plan.ReadFile("plan.json")
+	if len(plans) != 2 || plans[1].Issue != "github:2" || plans[1].Category != "ui" {
+		t.Errorf("plans = %+v, want issue 2 imported", plans)
+	}
+	if data, _ := os.ReadFile("progress.txt"); !strings.Contains(string(data), "TRACKER: 1 github issues imported, 1 closed") {
+		t.Errorf("progress = %q", data)
+	}
+
+	cfg.Tracker.Token = "env:RALPH_TEST_UNSET_TOKEN"
+	if err := handleTrackerSync(cfg); err == nil {
+		t.Error("handleTrackerSync() without a token succeeded")
+	}
+}
+
+func TestFetchIssueNotes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the tracker token", r.Header.Get("Authorization"))
+		}
+		if strings.HasSuffix(r.URL.Path, "/comments") {
+			w.Write([]byte(`[{"body": "Sort by price too", "user": {"login": "sam"}}]`))
+			return
+		}
+		w.Write([]byte(`{"number": 5, "title": "Product search", "body": "Search by name."}`))
+	}))
+	defer srv.Close()
+
+	cfg := config.New()
+	cfg.GeneratePlan = true
+	cfg.FromIssue = "acme/shop#5"
+	cfg.Tracker = &config.Tracker{Provider: "github", Project: "acme/shop", URL: srv.URL, Token: "secret"}
+
+	path, err := fetchIssueNotes(cfg)
+	if err != nil {
+		t.Fatalf("fetchIssueNotes() = %v", err)
+	}
+	if path != filepath.Join(".ralph", "issues", "acme-shop-5.md") {
+		t.Errorf("path = %q", path)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# Product search") || !strings.Contains(string(data), "Sort by price too") {
+		t.Errorf("notes = %q", data)
+	}
+
+	cfg.NotesFile = "notes.md"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "cannot be used together") {
+		t.Errorf("validateConfig() with -notes and -from-issue = %v", err)
+	}
+	cfg.NotesFile, cfg.FromIssue = "", "shop#5"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "invalid issue") {
+		t.Errorf("validateConfig() with a bad issue = %v", err)
+	}
+}
+
+func TestGeneratePlanWithoutAgent(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("notes.md", []byte("# App\n\n## Features\n- Login\n  - Form\n- Logout\n"), 0644)
+	cfg := config.New()
+	cfg.GeneratePlan = true
+	cfg.NoAgent = true
+	cfg.NotesFile = "notes.md"
+	cfg.AgentCmd = "no-such-agent-installed"
+
+	// The agent is neither checked nor run
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() = %v", err)
+	}
+	if err := generatePlanFromNotes(cfg); err != nil {
+		t.Fatalf("generatePlanFromNotes() = %v", err)
+	}
+	plans, err := plan.ReadFile(cfg.OutputPlanFile)
+	if err != nil || len(plans) != 2 || plans[0].Description != "Login" || len(plans[0].Steps) != 1 {
+		t.Errorf("plans = %+v, %v", plans, err)
+	}
+
+	os.WriteFile("notes.md", []byte("Nothing structured here.\n"), 0644)
+	if err := generatePlanFromNotes(cfg); err == nil || !strings.Contains(err.Error(), "no features found") {
+		t.Errorf("generatePlanFromNotes() of prose = %v", err)
+	}
+}
+
+func TestListPlanStatusExitCode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := config.New()
+	cfg.ListAll = true
+	cfg.JSONOutput = true
+
+	tests := []struct {
+		name  string
+		plans []plan.Plan
+		want  int
+	}{
+		{"all tested", []plan.Plan{{ID: 1, Tested: true}}, exitPlanComplete},
+		{"untested", []plan.Plan{{ID: 1, Tested: true}, {ID: 2}}, exitPlanUntested},
+		{"question", []plan.Plan{{ID: 1, Tested: true}, {ID: 2, Type: plan.TypeQuestion}}, exitPlanUntested},
+		{"deferred", []plan.Plan{{ID: 1}, {ID: 2, Deferred: true}}, exitPlanDeferred},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := plan.WriteFile(cfg.PlanFile, tt.plans); err != nil {
+				t.Fatal(err)
+			}
+			if code, err := listPlanStatus(cfg); err != nil || code != tt.want {
+				t.Errorf("listPlanStatus() = %d, %v, want %d", code, err, tt.want)
+			}
+			if code, err := listDeferredFeatures(cfg); err != nil || code != tt.want {
+				t.Errorf("listDeferredFeatures() = %d, %v, want %d", code, err, tt.want)
+			}
+		})
+	}
+}